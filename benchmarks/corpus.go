@@ -0,0 +1,72 @@
+// Package benchmarks measures tokenize/detokenize latency and allocations
+// across input sizes, entity densities, and local vs cloud mode.
+//
+// Run from the repository root:
+//
+//	go test -run '^$' -bench . -benchmem ./benchmarks
+//
+// Cloud-mode cases run only when BLINDFOLD_API_KEY is set; they measure
+// network round trips and count against your quota.
+package benchmarks
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/genpii"
+)
+
+// fillerWords make up the PII-free part of generated documents.
+var fillerWords = strings.Fields(`the customer reported that their order arrived late and
+the package was damaged so we issued a partial refund and asked them to keep
+the item while billing reviews the charge and support follows up next week`)
+
+// entityTypes are pattern-detectable, so local and cloud mode see the same
+// entities. Values come from the genpii generator.
+var entityTypes = []string{genpii.Email, genpii.Phone, genpii.CreditCard, genpii.IPAddress}
+
+// Density is the share of words that are PII.
+type Density struct {
+	Name  string
+	Ratio float64
+}
+
+// Densities covers mostly-prose text up to entity-heavy records.
+var Densities = []Density{
+	{Name: "none", Ratio: 0},
+	{Name: "low", Ratio: 0.01},
+	{Name: "medium", Ratio: 0.05},
+	{Name: "high", Ratio: 0.20},
+}
+
+// Sizes are the approximate input lengths in bytes.
+var Sizes = []int{256, 4 << 10, 64 << 10}
+
+// Document returns deterministic text of roughly size bytes in which about
+// density.Ratio of the words are PII.
+func Document(size int, density Density) string {
+	seed := int64(size) + int64(density.Ratio*1000)
+	r := rand.New(rand.NewSource(seed))
+	gen := genpii.New(seed)
+	var b strings.Builder
+	for b.Len() < size {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		if r.Float64() < density.Ratio {
+			b.WriteString(gen.Value(entityTypes[r.Intn(len(entityTypes))]))
+		} else {
+			b.WriteString(fillerWords[r.Intn(len(fillerWords))])
+		}
+	}
+	return b.String()
+}
+
+// SizeName formats a byte count for benchmark names (256B, 4KB, 64KB).
+func SizeName(size int) string {
+	if size >= 1<<10 {
+		return fmt.Sprintf("%dKB", size>>10)
+	}
+	return fmt.Sprintf("%dB", size)
+}
@@ -0,0 +1,72 @@
+package benchmarks
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+type mode struct {
+	name   string
+	client func(b *testing.B) *blindfold.Client
+}
+
+var modes = []mode{
+	{name: "local", client: func(*testing.B) *blindfold.Client {
+		return blindfold.New(blindfold.WithMode("local"))
+	}},
+	{name: "cloud", client: func(b *testing.B) *blindfold.Client {
+		key := os.Getenv("BLINDFOLD_API_KEY")
+		if key == "" {
+			b.Skip("BLINDFOLD_API_KEY not set")
+		}
+		return blindfold.New(blindfold.WithAPIKey(key))
+	}},
+}
+
+func BenchmarkTokenize(b *testing.B) {
+	ctx := context.Background()
+	for _, m := range modes {
+		for _, size := range Sizes {
+			for _, d := range Densities {
+				text := Document(size, d)
+				b.Run(m.name+"/"+SizeName(size)+"/"+d.Name, func(b *testing.B) {
+					bf := m.client(b)
+					b.SetBytes(int64(len(text)))
+					b.ReportAllocs()
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						if _, err := bf.Tokenize(ctx, text); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// BenchmarkDetokenize is client-side in both modes, so it runs locally only.
+func BenchmarkDetokenize(b *testing.B) {
+	ctx := context.Background()
+	bf := blindfold.New(blindfold.WithMode("local"))
+	for _, size := range Sizes {
+		for _, d := range Densities {
+			tokenized, err := bf.Tokenize(ctx, Document(size, d))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Run(SizeName(size)+"/"+d.Name, func(b *testing.B) {
+				b.SetBytes(int64(len(tokenized.Text)))
+				b.ReportAllocs()
+				b.ReportMetric(float64(len(tokenized.Mapping)), "tokens")
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					bf.Detokenize(tokenized.Text, tokenized.Mapping)
+				}
+			})
+		}
+	}
+}
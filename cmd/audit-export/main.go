@@ -0,0 +1,261 @@
+// audit-export turns the audit log written by pkg/audit into signed,
+// timestamped reports per time window (requests processed, entity types,
+// policies, destinations, outcomes) to hand to auditors.
+//
+//	go run ./cmd/audit-export -genkey auditor            # auditor.key (keep), auditor.pub (share)
+//	go run ./cmd/audit-export -audit audit.jsonl -key auditor.key -from 2026-10-01 -to 2026-10-08
+//	go run ./cmd/audit-export -verify audit-reports/audit-2026-10-01.json -pub auditor.pub
+//
+// Each report is written with a detached Ed25519 signature next to it
+// (<report>.sig) covering the file's bytes and the signing time. The
+// audit log is a JSON lines file or a postgres:// URL, as in pkg/audit.
+// Reports hold counts only, never values.
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/audit"
+)
+
+// parseTime accepts RFC 3339 or a date, taken as midnight UTC.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+func reportName(w audit.Window, unit string) string {
+	layout := map[string]string{"hour": "2006-01-02T15", "month": "2006-01"}[unit]
+	if layout == "" {
+		layout = "2006-01-02"
+	}
+	return "audit-" + w.Start.Format(layout)
+}
+
+func generateKey(prefix string) error {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(prefix+".key", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return err
+	}
+	der, err = x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(prefix+".pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s.key (keep private) and %s.pub (key ID %s)\n", prefix, prefix, audit.KeyID(pub))
+	return nil
+}
+
+func readPEM(path, typ string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != typ {
+		return nil, fmt.Errorf("%s: no %s PEM block", path, typ)
+	}
+	return block.Bytes, nil
+}
+
+func loadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 key", path)
+	}
+	return priv, nil
+}
+
+func loadPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 key", path)
+	}
+	return pub, nil
+}
+
+func verify(reportPath, pubPath string) error {
+	pub, err := loadPublicKey(pubPath)
+	if err != nil {
+		return err
+	}
+	report, err := os.ReadFile(reportPath)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(reportPath + ".sig")
+	if err != nil {
+		return err
+	}
+	var sig audit.Signature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return fmt.Errorf("%s.sig: %w", reportPath, err)
+	}
+	if err := audit.Verify(report, sig, pub); err != nil {
+		return err
+	}
+	fmt.Printf("OK: %s signed by key %s at %s\n", reportPath, sig.KeyID, sig.SignedAt.Format(time.RFC3339))
+	return nil
+}
+
+func main() {
+	_ = godotenv.Load()
+	log.SetFlags(0)
+	target := flag.String("audit", os.Getenv("AUDIT_TARGET"), "audit log: JSON lines file or postgres:// URL")
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	fromFlag := flag.String("from", today.AddDate(0, 0, -1).Format("2006-01-02"), "start of the range (date or RFC 3339)")
+	toFlag := flag.String("to", today.Format("2006-01-02"), "end of the range, exclusive (date or RFC 3339)")
+	unit := flag.String("window", "day", "report window: hour, day, week or month")
+	format := flag.String("format", "json", "report format: json or csv")
+	outDir := flag.String("out", "audit-reports", "directory for reports and signatures")
+	keyPath := flag.String("key", os.Getenv("AUDIT_SIGNING_KEY"), "Ed25519 private key (PEM) to sign reports with")
+	genkey := flag.String("genkey", "", "write a new key pair to <prefix>.key and <prefix>.pub and exit")
+	verifyPath := flag.String("verify", "", "verify this report against its .sig and exit")
+	pubPath := flag.String("pub", "", "public key (PEM) for -verify")
+	flag.Parse()
+
+	switch {
+	case *genkey != "":
+		if err := generateKey(*genkey); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case *verifyPath != "":
+		if *pubPath == "" {
+			log.Fatal("-verify needs -pub")
+		}
+		if err := verify(*verifyPath, *pubPath); err != nil {
+			log.Fatalf("%s: %v", *verifyPath, err)
+		}
+		return
+	}
+
+	if *target == "" {
+		log.Fatal("-audit (or AUDIT_TARGET) is required")
+	}
+	if *keyPath == "" {
+		log.Fatal("-key (or AUDIT_SIGNING_KEY) is required: unsigned reports can't be handed to auditors")
+	}
+	if *format != "json" && *format != "csv" {
+		log.Fatalf("-format %q: want json or csv", *format)
+	}
+	key, err := loadPrivateKey(*keyPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	from, err := parseTime(*fromFlag)
+	if err != nil {
+		log.Fatalf("-from: %v", err)
+	}
+	to, err := parseTime(*toFlag)
+	if err != nil {
+		log.Fatalf("-to: %v", err)
+	}
+	if !from.Before(to) {
+		log.Fatal("-from must be before -to")
+	}
+	windows, err := audit.Windows(from, to, *unit)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Read whole windows, so the first and last reports aren't partial
+	events, err := audit.Events(context.Background(), *target, windows[0].Start, windows[len(windows)-1].End)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	now := time.Now()
+	for _, w := range windows {
+		if w.End.After(now) {
+			log.Printf("skipping %s: window not over yet", reportName(w, *unit))
+			continue
+		}
+		r := audit.Summarize(events, w, now)
+		var b strings.Builder
+		if *format == "csv" {
+			err = r.WriteCSV(&b)
+		} else {
+			enc := json.NewEncoder(&b)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(r)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		path := filepath.Join(*outDir, reportName(w, *unit)+"."+*format)
+		if err := writeSigned(path, []byte(b.String()), key, now); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s  %5d requests  %5d with PII  → %s (+ .sig)\n", w.Start.Format(time.RFC3339), r.Requests, r.RequestsWithPII, path)
+	}
+}
+
+// writeSigned writes report to path and its signature to path.sig. An
+// existing report is never overwritten: one already handed out must stay
+// verifiable.
+func writeSigned(path string, report []byte, key ed25519.PrivateKey, at time.Time) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s exists; move it aside to re-export", path)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(report); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	sig, err := json.MarshalIndent(audit.Sign(report, key, at), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+".sig", append(sig, '\n'), 0o644)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// addedFile holds the lines a commit adds to one file.
+type addedFile struct {
+	path  string
+	lines []addedLine
+}
+
+type addedLine struct {
+	n    int // 1-based line number in the new file
+	text string
+}
+
+// git runs git in dir and returns its standard output.
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// stagedLines returns the lines added by the staged changes in the
+// repository at root. Deleted lines can't leak anything new and aren't
+// returned, and neither are binary files.
+func stagedLines(root string) ([]addedFile, error) {
+	out, err := git(root, "diff", "--cached", "--unified=0", "--no-color", "--no-ext-diff", "--no-renames", "--diff-filter=ACM")
+	if err != nil {
+		return nil, err
+	}
+	return parseDiff(out)
+}
+
+// parseDiff reads a unified diff with zero context lines.
+func parseDiff(diff []byte) ([]addedFile, error) {
+	var files []addedFile
+	var cur *addedFile
+	n := 0          // next line number in the new file
+	header := false // between "diff --git" and the first hunk
+	sc := bufio.NewScanner(bytes.NewReader(diff))
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			cur, header = nil, true
+		case header && strings.HasPrefix(line, "+++ "):
+			// A path with spaces is followed by a tab, for patch(1)
+			path := strings.TrimSuffix(strings.TrimPrefix(line, "+++ "), "\t")
+			if path == "/dev/null" {
+				cur = nil
+				continue
+			}
+			if strings.HasPrefix(path, `"`) {
+				// git C-quotes paths with unusual characters
+				if p, err := strconv.Unquote(path); err == nil {
+					path = p
+				}
+			}
+			files = append(files, addedFile{path: strings.TrimPrefix(path, "b/")})
+			cur = &files[len(files)-1]
+		case strings.HasPrefix(line, "@@ "):
+			start, err := hunkStart(line)
+			if err != nil {
+				return nil, err
+			}
+			n, header = start, false
+		case header:
+			// index, mode and "--- a/path" lines
+		case cur != nil && strings.HasPrefix(line, "+"):
+			cur.lines = append(cur.lines, addedLine{n, line[1:]})
+			n++
+		}
+	}
+	return files, sc.Err()
+}
+
+// hunkStart returns the first new-file line of a hunk header such as
+// "@@ -12,0 +13,2 @@".
+func hunkStart(header string) (int, error) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return 0, fmt.Errorf("malformed hunk header %q", header)
+	}
+	spec, _, _ := strings.Cut(fields[2][1:], ",")
+	return strconv.Atoi(spec)
+}
@@ -0,0 +1,252 @@
+// blindfold-precommit is a Git pre-commit hook that blocks commits adding
+// PII: customer emails pasted into fixtures, a real card number in a test,
+// a phone number in a comment.
+//
+// It scans only the lines the commit adds (git diff --cached), in local
+// mode, so nothing leaves the machine and no API key is needed. Findings
+// are printed with file, line and column, values masked:
+//
+//	go install ./cmd/blindfold-precommit
+//	blindfold-precommit -install        # writes .git/hooks/pre-commit
+//
+// Detection follows the repository's policy file, .blindfold.yaml at the
+// top of the work tree (see pkg/policyconf): its default policy applies,
+// and its allowlist is how a repository declares values that are fine to
+// commit, such as a public support address. A line containing
+// "blindfold:allow" is skipped. Paths matching -exclude are not scanned.
+//
+// The exit status is 1 when findings block the commit and 2 when the scan
+// itself fails.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/masking"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+// allowMarker on a line exempts it from scanning.
+const allowMarker = "blindfold:allow"
+
+const hook = `#!/bin/sh
+# Installed by blindfold-precommit -install. Bypass once with git commit --no-verify.
+exec blindfold-precommit "$@"
+`
+
+// Finding is one detected entity on an added line.
+type Finding struct {
+	Path   string
+	Line   int
+	Column int // 1-based, in bytes
+	Type   string
+	Score  float64
+	Value  string
+	Text   string // the whole line
+}
+
+// patterns holds repeated -exclude flags.
+type patterns []string
+
+func (p *patterns) String() string     { return strings.Join(*p, ",") }
+func (p *patterns) Set(v string) error { *p = append(*p, v); return nil }
+
+// excluded reports whether file matches one of the patterns, as a whole
+// path or by base name. A pattern ending in / excludes a directory tree.
+func (p patterns) excluded(file string) bool {
+	for _, pat := range p {
+		if dir, ok := strings.CutSuffix(pat, "/"); ok {
+			if file == dir || strings.HasPrefix(file, dir+"/") {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(pat, file); ok {
+			return true
+		}
+		if ok, _ := path.Match(pat, path.Base(file)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// scan detects entities in the added lines of each file. The lines of a
+// file are scanned together, so an entity broken across consecutive lines
+// is still seen, and each finding is mapped back to its line.
+func scan(ctx context.Context, bf *policyconf.Client, files []addedFile, exclude patterns, minScore float64) ([]Finding, error) {
+	var findings []Finding
+	for _, f := range files {
+		if exclude.excluded(f.path) {
+			continue
+		}
+		var text strings.Builder
+		var starts []int // offset of each line in text
+		var lines []addedLine
+		for _, l := range f.lines {
+			if strings.Contains(l.text, allowMarker) {
+				continue
+			}
+			starts = append(starts, text.Len())
+			lines = append(lines, l)
+			text.WriteString(l.text)
+			text.WriteByte('\n')
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		res, err := bf.Detect(ctx, text.String())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.path, err)
+		}
+		for _, e := range res.DetectedEntities {
+			if e.Score < minScore {
+				continue
+			}
+			i := 0
+			for i+1 < len(starts) && starts[i+1] <= e.Start {
+				i++
+			}
+			findings = append(findings, Finding{
+				Path:   f.path,
+				Line:   lines[i].n,
+				Column: e.Start - starts[i] + 1,
+				Type:   e.Type,
+				Score:  e.Score,
+				Value:  e.Text,
+				Text:   lines[i].text,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// report prints each finding with its line, the value masked unless
+// showValues, and a caret under it.
+func report(w io.Writer, findings []Finding, showValues bool) {
+	masks := masking.Defaults()
+	for _, f := range findings {
+		shown := f.Value
+		if !showValues {
+			shown = masks.Mask(f.Type, f.Value)
+		}
+		line := f.Text
+		start := f.Column - 1
+		end := min(start+len(f.Value), len(line))
+		fmt.Fprintf(w, "%s:%d:%d: %s (score %.2f)\n", f.Path, f.Line, f.Column, f.Type, f.Score)
+		prefix := fmt.Sprintf("%6d | ", f.Line)
+		fmt.Fprintf(w, "%s%s\n", prefix, strings.TrimRight(line[:start]+shown+line[end:], "\r"))
+		fmt.Fprintf(w, "%s%s%s\n\n", strings.Repeat(" ", len(prefix)), indent(line[:start]), strings.Repeat("^", max(1, utf8.RuneCountInString(shown))))
+	}
+}
+
+// indent returns blanks as wide as s, keeping its tabs, so a caret below
+// lines up with the line above however the terminal expands tabs.
+func indent(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+// installHook writes the pre-commit hook into the repository at root.
+func installHook(root string, force bool) (string, error) {
+	out, err := git(root, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	p := filepath.Join(dir, "pre-commit")
+	if _, err := os.Stat(p); err == nil && !force {
+		return "", fmt.Errorf("%s already exists; add `blindfold-precommit` to it, or pass -force to replace it", p)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return p, os.WriteFile(p, []byte(hook), 0o755)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("blindfold-precommit: ")
+	config := flag.String("config", ".blindfold.yaml", "policy file, relative to the top of the work tree; a missing file means -policy")
+	policy := flag.String("policy", "strict", "built-in policy when there is no policy file")
+	var exclude patterns
+	flag.Var(&exclude, "exclude", "path or base-name glob not to scan, or dir/ (repeatable)")
+	minScore := flag.Float64("min-score", 0, "ignore findings below this score")
+	showValues := flag.Bool("show-values", false, "print detected values instead of masking them")
+	install := flag.Bool("install", false, "install the pre-commit hook in this repository and exit")
+	force := flag.Bool("force", false, "with -install, replace an existing hook")
+	flag.Parse()
+
+	out, err := git(".", "rev-parse", "--show-toplevel")
+	if err != nil {
+		log.Print(err)
+		os.Exit(2)
+	}
+	root := strings.TrimSpace(string(out))
+
+	if *install {
+		p, err := installHook(root, *force)
+		if err != nil {
+			log.Print(err)
+			os.Exit(2)
+		}
+		fmt.Printf("Installed %s\n", p)
+		return
+	}
+
+	cfgPath := filepath.Join(root, *config)
+	name := *policy
+	if _, err := os.Stat(cfgPath); errors.Is(err, fs.ErrNotExist) {
+		cfgPath = ""
+	} else {
+		name = "" // the file's default
+	}
+	pol, err := policyconf.Resolve(cfgPath, name)
+	if err != nil {
+		log.Print(err)
+		os.Exit(2)
+	}
+	// Always local: staged code never leaves the machine
+	bf := pol.Wrap(blindfold.New(append(pol.ClientOptions(), blindfold.WithMode("local"))...))
+
+	files, err := stagedLines(root)
+	if err != nil {
+		log.Print(err)
+		os.Exit(2)
+	}
+	findings, err := scan(context.Background(), bf, files, exclude, *minScore)
+	if err != nil {
+		log.Print(err)
+		os.Exit(2)
+	}
+	if len(findings) == 0 {
+		return
+	}
+	report(os.Stderr, findings, *showValues)
+	fmt.Fprintf(os.Stderr, "Commit blocked: %d possible PII finding(s) in staged lines (policy %q).\n", len(findings), pol.Name)
+	fmt.Fprintf(os.Stderr, "Remove them, add the value to the allowlist in %s, mark the line with %q,\nor, if you are sure, commit with --no-verify.\n", *config, allowMarker)
+	os.Exit(1)
+}
@@ -0,0 +1,345 @@
+// blindfold-scan fails a CI job when a repository or a build artifact
+// holds PII: fixtures copied from production, a customer export left in a
+// test directory, log files shipped inside an image.
+//
+// It walks a directory, runs detection over every text file, prints each
+// finding with its value masked, and exits 1 when findings remain after
+// the -min-score threshold and -max-findings budget. With -sarif it also
+// writes a SARIF 2.1.0 log, which code-scanning services show as
+// annotations on the lines involved:
+//
+//	blindfold-scan -sarif pii.sarif .
+//	blindfold-scan -min-score 0.9 -max-findings 5 dist/
+//
+// In GitHub Actions, upload the log with github/codeql-action/upload-sarif
+// (run it with if: always(), so findings are uploaded when the scan fails
+// the job).
+//
+// Detection follows .blindfold.yaml at the scanned root when there is one
+// (see pkg/policyconf): its allowlist declares values that are fine to
+// commit. Lines containing "blindfold:allow" are skipped, as in
+// cmd/blindfold-precommit, and -exclude skips paths. Binary files, files
+// over -max-bytes, and .git are never read. The exit status is 2 when the
+// scan itself fails.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/masking"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+// allowMarker on a line exempts it from scanning.
+const allowMarker = "blindfold:allow"
+
+// Finding is one detected entity.
+type Finding struct {
+	Path   string // slash-separated, relative to the scanned root
+	Line   int
+	Column int // 1-based, in bytes
+	Type   string
+	Score  float64
+	Value  string
+	Mask   string // Value masked by pkg/masking
+	Text   string // the whole line
+}
+
+// patterns holds repeated -exclude flags.
+type patterns []string
+
+func (p *patterns) String() string     { return strings.Join(*p, ",") }
+func (p *patterns) Set(v string) error { *p = append(*p, v); return nil }
+
+// excluded reports whether rel matches one of the patterns, as a whole
+// path or by base name. A pattern ending in / excludes a directory tree.
+func (p patterns) excluded(rel string) bool {
+	for _, pat := range p {
+		if dir, ok := strings.CutSuffix(pat, "/"); ok {
+			if rel == dir || strings.HasPrefix(rel, dir+"/") {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(pat, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pat, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// scanner holds the settings of one run.
+type scanner struct {
+	bf       bfclient.Client
+	root     string
+	exclude  patterns
+	exts     map[string]bool // empty: every text file
+	maxBytes int64
+	minScore float64
+	masks    masking.Rules
+
+	files, skipped int
+}
+
+// walk scans every file under s.root and returns the findings in path
+// order.
+func (s *scanner) walk(ctx context.Context) ([]Finding, error) {
+	var findings []Finding
+	err := filepath.WalkDir(s.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if d.Name() == ".git" || (rel != "." && s.exclude.excluded(rel)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || s.exclude.excluded(rel) {
+			return nil
+		}
+		if len(s.exts) > 0 && !s.exts[strings.ToLower(path.Ext(rel))] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() > s.maxBytes {
+			s.skipped++
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if bytes.IndexByte(data[:min(len(data), 8<<10)], 0) >= 0 || !utf8.Valid(data) {
+			s.skipped++
+			return nil
+		}
+		found, err := s.scan(ctx, rel, string(data))
+		if err != nil {
+			return fmt.Errorf("%s: %w", rel, err)
+		}
+		s.files++
+		findings = append(findings, found...)
+		return nil
+	})
+	return findings, err
+}
+
+// scan detects entities in text, the content of the file rel, skipping
+// lines that carry the allow marker.
+func (s *scanner) scan(ctx context.Context, rel, text string) ([]Finding, error) {
+	lines := strings.SplitAfter(text, "\n")
+	// Blank out allowed lines so offsets still match the file
+	var scanned strings.Builder
+	for _, l := range lines {
+		if strings.Contains(l, allowMarker) {
+			scanned.WriteString(strings.Repeat(" ", len(strings.TrimSuffix(l, "\n"))))
+			if strings.HasSuffix(l, "\n") {
+				scanned.WriteByte('\n')
+			}
+			continue
+		}
+		scanned.WriteString(l)
+	}
+	if strings.TrimSpace(scanned.String()) == "" {
+		return nil, nil
+	}
+	res, err := s.bf.Detect(ctx, scanned.String())
+	if err != nil {
+		return nil, err
+	}
+	var out []Finding
+	i, start := 0, 0 // line index and its offset
+	for _, e := range res.DetectedEntities {
+		if e.Score < s.minScore {
+			continue
+		}
+		for i+1 < len(lines) && start+len(lines[i]) <= e.Start {
+			start += len(lines[i])
+			i++
+		}
+		line := strings.TrimRight(lines[i], "\r\n")
+		if e.End-start > len(line) {
+			// The entity runs onto the next line; report its first part
+			e.Text = line[e.Start-start:]
+		}
+		out = append(out, Finding{
+			Path:   rel,
+			Line:   i + 1,
+			Column: e.Start - start + 1,
+			Type:   e.Type,
+			Score:  e.Score,
+			Value:  e.Text,
+			Mask:   s.masks.Mask(e.Type, e.Text),
+			Text:   line,
+		})
+	}
+	return out, nil
+}
+
+// report prints each finding with its line, the value masked unless
+// showValues.
+func report(w io.Writer, findings []Finding, showValues bool) {
+	for _, f := range findings {
+		shown := f.Mask
+		if showValues {
+			shown = f.Value
+		}
+		start := f.Column - 1
+		fmt.Fprintf(w, "%s:%d:%d: %s (score %.2f)\n", f.Path, f.Line, f.Column, f.Type, f.Score)
+		fmt.Fprintf(w, "%6d | %s\n\n", f.Line, f.Text[:start]+shown+f.Text[start+len(f.Value):])
+	}
+}
+
+// summarize prints counts per entity type.
+func summarize(w io.Writer, findings []Finding) {
+	counts := make(map[string]int)
+	for _, f := range findings {
+		counts[f.Type]++
+	}
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		fmt.Fprintf(w, "  %-24s %d\n", t, counts[t])
+	}
+}
+
+// saveSARIF writes the SARIF log to path, or to stdout for "-".
+func saveSARIF(path, root string, findings []Finding) error {
+	if path == "-" {
+		return writeSARIF(os.Stdout, root, findings)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeSARIF(f, root, findings); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// fileURI is the file: URI of dir, with a trailing slash as SARIF wants
+// for base URIs.
+func fileURI(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	u := url.URL{Scheme: "file", Path: strings.TrimSuffix(filepath.ToSlash(abs), "/") + "/"}
+	return u.String()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("blindfold-scan: ")
+	config := flag.String("config", ".blindfold.yaml", "policy file, relative to the scanned root; a missing file means -policy")
+	policy := flag.String("policy", "strict", "built-in policy when there is no policy file")
+	exts := flag.String("ext", "", "comma-separated file extensions to scan (default: every text file)")
+	var exclude patterns
+	flag.Var(&exclude, "exclude", "path or base-name glob not to scan, or dir/ (repeatable)")
+	maxBytes := flag.Int64("max-bytes", 4<<20, "skip files larger than this")
+	minScore := flag.Float64("min-score", 0.5, "ignore findings below this score")
+	maxFindings := flag.Int("max-findings", 0, "findings allowed before the scan fails")
+	sarif := flag.String("sarif", "", `write a SARIF log to this file ("-" for stdout)`)
+	showValues := flag.Bool("show-values", false, "print detected values instead of masking them (never in SARIF)")
+	quiet := flag.Bool("q", false, "print the summary only")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: blindfold-scan [flags] [DIR]\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	root := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		root = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfgPath := filepath.Join(root, *config)
+	name := *policy
+	if _, err := os.Stat(cfgPath); errors.Is(err, fs.ErrNotExist) {
+		cfgPath = ""
+	} else {
+		name = "" // the file's default
+	}
+	pol, err := policyconf.Resolve(cfgPath, name)
+	if err != nil {
+		log.Print(err)
+		os.Exit(2)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	s := &scanner{
+		bf:       pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...)),
+		root:     root,
+		exclude:  exclude,
+		exts:     make(map[string]bool),
+		maxBytes: *maxBytes,
+		minScore: *minScore,
+		masks:    masking.Defaults(),
+	}
+	for _, e := range strings.Split(*exts, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			s.exts[strings.ToLower(e)] = true
+		}
+	}
+	findings, err := s.walk(context.Background())
+	if err != nil {
+		log.Print(err)
+		os.Exit(2)
+	}
+
+	if *sarif != "" {
+		if err := saveSARIF(*sarif, root, findings); err != nil {
+			log.Print(err)
+			os.Exit(2)
+		}
+	}
+
+	if !*quiet {
+		report(os.Stderr, findings, *showValues)
+	}
+	fmt.Fprintf(os.Stderr, "Scanned %d files (%d skipped) with policy %q: %d findings\n", s.files, s.skipped, pol.Name, len(findings))
+	summarize(os.Stderr, findings)
+	if len(findings) > *maxFindings {
+		fmt.Fprintf(os.Stderr, "FAIL: %d findings, %d allowed\n", len(findings), *maxFindings)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// SARIF 2.1.0, the subset code-scanning services read.
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                   `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLoc `json:"originalUriBaseIds,omitempty"`
+	ColumnKind         string                      `json:"columnKind"`
+	Results            []sarifResult               `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string          `json:"id"`
+	Name             string          `json:"name"`
+	ShortDescription sarifMessage    `json:"shortDescription"`
+	FullDescription  sarifMessage    `json:"fullDescription"`
+	Help             sarifMessage    `json:"help"`
+	DefaultConfig    sarifRuleConfig `json:"defaultConfiguration"`
+	Properties       map[string]any  `json:"properties,omitempty"`
+}
+
+type sarifRuleConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]any    `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysical `json:"physicalLocation"`
+}
+
+type sarifPhysical struct {
+	ArtifactLocation sarifArtifactLoc `json:"artifactLocation"`
+	Region           sarifRegion      `json:"region"`
+}
+
+type sarifArtifactLoc struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// ruleID names the rule for an entity type: "pii/email-address".
+func ruleID(entityType string) string {
+	return "pii/" + strings.ReplaceAll(strings.ToLower(entityType), " ", "-")
+}
+
+// utf16Col converts a 1-based byte column in line to the 1-based UTF-16
+// column SARIF counts in by default.
+func utf16Col(line string, col int) int {
+	return len(utf16.Encode([]rune(line[:col-1]))) + 1
+}
+
+// fingerprint identifies a finding across runs without the value itself:
+// the path, the type, and the line with the value masked. Moving the line
+// keeps its alerts; editing it opens new ones.
+func fingerprint(f Finding) string {
+	line := f.Text[:f.Column-1] + f.Mask + f.Text[f.Column-1+len(f.Value):]
+	h := sha256.Sum256([]byte(f.Path + "\x00" + f.Type + "\x00" + strings.TrimSpace(line)))
+	return hex.EncodeToString(h[:16])
+}
+
+// writeSARIF writes findings as one SARIF run, with a rule per entity type
+// found. Messages carry masked values only, since code-scanning alerts are
+// visible to everyone with read access to the repository.
+func writeSARIF(w io.Writer, root string, findings []Finding) error {
+	types := make(map[string]bool)
+	for _, f := range findings {
+		types[f.Type] = true
+	}
+	names := make([]string, 0, len(types))
+	for t := range types {
+		names = append(names, t)
+	}
+	sort.Strings(names)
+	index := make(map[string]int, len(names))
+	rules := make([]sarifRule, len(names))
+	for i, t := range names {
+		index[t] = i
+		rules[i] = sarifRule{
+			ID:               ruleID(t),
+			Name:             strings.ReplaceAll(t, " ", ""),
+			ShortDescription: sarifMessage{t + " in source"},
+			FullDescription:  sarifMessage{"A value that looks like a real " + strings.ToLower(t) + " is committed to the repository."},
+			Help:             sarifMessage{"Replace it with synthetic data (cmd/genpii), add it to the allowlist of the repository's .blindfold.yaml if it is public, or mark the line with blindfold:allow."},
+			DefaultConfig:    sarifRuleConfig{Level: "error"},
+			Properties:       map[string]any{"tags": []string{"privacy", "pii"}},
+		}
+	}
+
+	results := make([]sarifResult, 0, len(findings))
+	for _, f := range findings {
+		start := utf16Col(f.Text, f.Column)
+		end := utf16Col(f.Text, f.Column+len(f.Value))
+		results = append(results, sarifResult{
+			RuleID:    ruleID(f.Type),
+			RuleIndex: index[f.Type],
+			Level:     "error",
+			Message:   sarifMessage{f.Type + " detected: " + f.Mask},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysical{
+				ArtifactLocation: sarifArtifactLoc{URI: (&url.URL{Path: f.Path}).String(), URIBaseID: "%SRCROOT%"},
+				Region:           sarifRegion{StartLine: f.Line, StartColumn: start, EndColumn: end},
+			}}},
+			PartialFingerprints: map[string]string{"piiLineHash/v1": fingerprint(f)},
+			Properties:          map[string]any{"score": f.Score},
+		})
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "blindfold-scan",
+				InformationURI: "https://github.com/blindfold-dev/blindfold-cookbook/tree/main/cmd/blindfold-scan",
+				Rules:          rules,
+			}},
+			OriginalURIBaseIDs: map[string]sarifArtifactLoc{"%SRCROOT%": {URI: fileURI(root)}},
+			ColumnKind:         "utf16CodeUnits",
+			Results:            results,
+		}},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
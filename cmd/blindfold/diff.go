@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+const diffUsage = `usage: blindfold diff [flags] FILE|-
+       blindfold diff [flags] -response FILE -mapping FILE
+
+The first form tokenizes FILE (or stdin) and shows the original next to
+the tokenized text, with each entity type in its own color. The second
+shows a raw LLM response next to its detokenized form, flagging tokens
+the mapping can't restore.
+
+`
+
+// looseToken matches placeholder-like strings a model may have mangled:
+// <Email_Address_1>, [Person 2], <person_1 >.
+var looseToken = regexp.MustCompile(`[<\[]\s*([A-Za-z][A-Za-z _-]*?)[\s_-]*(\d+)\s*[>\]]`)
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), diffUsage)
+		fs.PrintDefaults()
+	}
+	file := fs.String("policies", "", "policy file (YAML or JSON); empty = built-in policies only")
+	name := fs.String("policy", "basic", "policy to apply, by name")
+	width := fs.Int("width", 60, "width of each column")
+	colorMode := fs.String("color", "auto", "color output: auto, always or never")
+	value := fs.String("value", "", "explain whether this value was caught")
+	response := fs.String("response", "", "raw LLM response to detokenize")
+	mappingPath := fs.String("mapping", "", "mapping JSON (token → value) for -response")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	color, err := useColor(*colorMode)
+	if err != nil {
+		return err
+	}
+	if *width < 20 {
+		return fmt.Errorf("-width %d: want at least 20", *width)
+	}
+
+	if *response != "" {
+		if *mappingPath == "" {
+			return errors.New("-response needs -mapping")
+		}
+		return diffResponse(os.Stdout, *response, *mappingPath, *width, color)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("want one input file, or - for stdin")
+	}
+	text, err := readInput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		return err
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+	return diffTokenized(context.Background(), os.Stdout, bf, text, *value, *width, color)
+}
+
+func readInput(path string) (string, error) {
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		return string(data), err
+	}
+	data, err := os.ReadFile(path)
+	return string(data), err
+}
+
+// locate returns where e is in text: at its offsets when they hold its
+// text, otherwise at its first occurrence from `from`.
+func locate(text string, e blindfold.DetectedEntity, from int) (int, int, bool) {
+	if e.Start >= 0 && e.Start <= e.End && e.End <= len(text) && text[e.Start:e.End] == e.Text {
+		return e.Start, e.End, true
+	}
+	if e.Text == "" || from > len(text) {
+		return 0, 0, false
+	}
+	i := strings.Index(text[from:], e.Text)
+	if i < 0 {
+		return 0, 0, false
+	}
+	return from + i, from + i + len(e.Text), true
+}
+
+func diffTokenized(ctx context.Context, w io.Writer, bf bfclient.Client, text, value string, width int, color bool) error {
+	det, err := bf.Detect(ctx, text)
+	if err != nil {
+		return fmt.Errorf("detect: %w", err)
+	}
+	tok, err := bf.Tokenize(ctx, text)
+	if err != nil {
+		return fmt.Errorf("tokenize: %w", err)
+	}
+
+	var types []string
+	for _, e := range det.DetectedEntities {
+		types = append(types, e.Type)
+	}
+	for token := range tok.Mapping {
+		typ, _, _ := mapping.ParseToken(token)
+		types = append(types, typ)
+	}
+	c := newColors(types)
+
+	var left []span
+	next := make(map[string]int)
+	for _, e := range det.DetectedEntities {
+		if start, end, ok := locate(text, e, next[e.Text]); ok {
+			next[e.Text] = end
+			left = append(left, span{start, end, c[e.Type]})
+		}
+	}
+	var right []span
+	for _, loc := range mapping.TokenPattern.FindAllStringIndex(tok.Text, -1) {
+		token := tok.Text[loc[0]:loc[1]]
+		if _, ok := tok.Mapping[token]; ok {
+			typ, _, _ := mapping.ParseToken(token)
+			right = append(right, span{loc[0], loc[1], c[typ]})
+		}
+	}
+
+	sideBySide(w, "ORIGINAL", "TOKENIZED", cells(text, left), cells(tok.Text, right), width, color)
+	fmt.Fprintln(w)
+	legend(w, c, color)
+
+	byValue := make(map[string]string)
+	for token, v := range tok.Mapping {
+		byValue[v] = token
+	}
+	fmt.Fprintf(w, "\n%d entities detected, %d tokens:\n", len(det.DetectedEntities), len(tok.Mapping))
+	for _, e := range det.DetectedEntities {
+		fmt.Fprintf(w, "  %-24s %-28q score %.2f  → %s\n", truncate(e.Type, 24), truncate(e.Text, 26), e.Score, orDash(byValue[e.Text]))
+	}
+	if value != "" {
+		fmt.Fprintf(w, "\n%s\n", explain(text, value, det.DetectedEntities, byValue))
+	}
+	return nil
+}
+
+// explain says whether value was caught whole, in part, or not at all.
+func explain(text, value string, entities []blindfold.DetectedEntity, byValue map[string]string) string {
+	at := strings.Index(text, value)
+	if at < 0 {
+		return fmt.Sprintf("%q does not occur in the input.", value)
+	}
+	end := at + len(value)
+	var partial []string
+	for _, e := range entities {
+		if e.Text == value || strings.TrimSpace(e.Text) == value {
+			return fmt.Sprintf("%q was caught as %s (score %.2f) → %s.", value, e.Type, e.Score, orDash(byValue[e.Text]))
+		}
+		start, stop, ok := locate(text, e, 0)
+		if ok && start < end && at < stop {
+			partial = append(partial, fmt.Sprintf("%s %q", e.Type, e.Text))
+		}
+	}
+	if len(partial) > 0 {
+		return fmt.Sprintf("%q was caught only in part, by %s. A custom pattern can match the whole value.", value, strings.Join(partial, ", "))
+	}
+	return fmt.Sprintf("%q was not caught. No detector in this policy matched it; check the policy's entity types and locales, or add a pattern or deny-list entry.", value)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// normalizeToken folds a token-like string to letters and its number, so
+// <Email_Address_1> and <Email Address_1> compare equal.
+func normalizeToken(typ, n string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(typ) {
+		if 'a' <= r && r <= 'z' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String() + "#" + n
+}
+
+func diffResponse(w io.Writer, responsePath, mappingPath string, width int, color bool) error {
+	raw, err := readInput(responsePath)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(mappingPath)
+	if err != nil {
+		return err
+	}
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("%s: %w", mappingPath, err)
+	}
+
+	var types []string
+	known := make(map[string]string) // normalized → token
+	for token := range m {
+		if typ, n, ok := mapping.ParseToken(token); ok {
+			types = append(types, typ)
+			known[normalizeToken(typ, fmt.Sprint(n))] = token
+		}
+	}
+	c := newColors(types)
+
+	// Rebuild the detokenized text token by token to know where each
+	// restored value lands
+	var left, right []span
+	var b strings.Builder
+	last := 0
+	for _, loc := range mapping.TokenPattern.FindAllStringIndex(raw, -1) {
+		token := raw[loc[0]:loc[1]]
+		b.WriteString(raw[last:loc[0]])
+		value, ok := m[token]
+		if !ok {
+			left = append(left, span{loc[0], loc[1], unresolved})
+			right = append(right, span{b.Len(), b.Len() + len(token), unresolved})
+			b.WriteString(token)
+		} else {
+			typ, _, _ := mapping.ParseToken(token)
+			left = append(left, span{loc[0], loc[1], c[typ]})
+			right = append(right, span{b.Len(), b.Len() + len(value), c[typ]})
+			b.WriteString(value)
+		}
+		last = loc[1]
+	}
+	b.WriteString(raw[last:])
+	restored := b.String()
+
+	// Mangled placeholders that TokenPattern doesn't see at all
+	type suspect struct{ text, guess string }
+	var suspects []suspect
+	for _, loc := range looseToken.FindAllStringSubmatchIndex(raw, -1) {
+		s := raw[loc[0]:loc[1]]
+		if _, ok := m[s]; ok {
+			continue
+		}
+		g := known[normalizeToken(raw[loc[2]:loc[3]], raw[loc[4]:loc[5]])]
+		suspects = append(suspects, suspect{s, g})
+		left = append(left, span{loc[0], loc[1], unresolved})
+	}
+
+	sideBySide(w, "RAW RESPONSE", "DETOKENIZED", cells(raw, left), cells(restored, right), width, color)
+	fmt.Fprintln(w)
+	legend(w, c, color)
+
+	used := make(map[string]bool)
+	for _, token := range mapping.TokenPattern.FindAllString(raw, -1) {
+		used[token] = true
+	}
+	var missing []string
+	for token := range m {
+		if !used[token] {
+			missing = append(missing, token)
+		}
+	}
+	sort.Strings(missing)
+
+	restoredCount := 0
+	for token := range used {
+		if _, ok := m[token]; ok {
+			restoredCount++
+		}
+	}
+	fmt.Fprintf(w, "\n%d token(s) restored", restoredCount)
+	if len(missing) > 0 {
+		fmt.Fprintf(w, "; not in the response: %s", strings.Join(missing, ", "))
+	}
+	fmt.Fprintln(w)
+	for _, s := range suspects {
+		if s.guess != "" {
+			fmt.Fprintf(w, "  %q is not restored: the model changed the token; did it mean %s?\n", s.text, s.guess)
+		} else {
+			fmt.Fprintf(w, "  %q is not restored: no such token in the mapping\n", s.text)
+		}
+	}
+	return nil
+}
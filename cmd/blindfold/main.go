@@ -0,0 +1,62 @@
+// blindfold is a command-line companion for debugging Blindfold
+// protection in this repo's recipes.
+//
+// Usage:
+//
+//	blindfold <command> [flags]
+//
+// Commands:
+//
+//	diff    original vs tokenized text, or raw vs detokenized response, side by side
+//	report  entity counts per type and per document across a corpus
+//	mapping export, import and inspect encrypted mapping files
+//
+// Run "blindfold <command> -h" for a command's flags.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"diff", "original vs tokenized text, or raw vs detokenized response, side by side", runDiff},
+	{"report", "entity counts per type and per document across a corpus", runReport},
+	{"mapping", "export, import and inspect encrypted mapping files", runMapping},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: blindfold <command> [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun \"blindfold <command> -h\" for a command's flags.\n")
+}
+
+func main() {
+	_ = godotenv.Load()
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "help" {
+		usage()
+		os.Exit(2)
+	}
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "blindfold %s: %v\n", c.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "blindfold: unknown command %q\n\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
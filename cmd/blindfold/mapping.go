@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/cache"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapstore"
+)
+
+const mappingUsage = `usage: blindfold mapping <command> [flags]
+
+Moves token mappings between tools as encrypted mapping files
+(pkg/mapstore). Keys come from -keys or BLINDFOLD_MAPPING_KEYS, as
+comma-separated id:base64 pairs; the first one encrypts.
+
+Commands:
+  keygen   print a new key
+  export   encrypt a mapping JSON file (token → value)
+  import   decrypt a mapping file back to mapping JSON
+  inspect  print the metadata of mapping files, without a key
+  rotate   re-encrypt mapping files or a Redis store under the first key
+
+`
+
+var mappingCommands = []command{
+	{"keygen", "print a new key", runKeygen},
+	{"export", "encrypt a mapping JSON file", runExport},
+	{"import", "decrypt a mapping file", runImport},
+	{"inspect", "print the metadata of mapping files", runInspect},
+	{"rotate", "re-encrypt mapping files or a Redis store under the first key", runRotate},
+}
+
+func runMapping(args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprint(os.Stderr, mappingUsage)
+		return nil
+	}
+	for _, c := range mappingCommands {
+		if c.name == args[0] {
+			return c.run(args[1:])
+		}
+	}
+	fmt.Fprint(os.Stderr, mappingUsage)
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+// mappingFlags returns a flag set for a mapping command, with -keys.
+func mappingFlags(name, usage string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet("mapping "+name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	keys := fs.String("keys", "", "id:base64 keys, comma-separated, first one encrypts (default: $BLINDFOLD_MAPPING_KEYS)")
+	return fs, keys
+}
+
+func parseKeys(flagValue string) ([]mapstore.Key, error) {
+	if flagValue == "" {
+		flagValue = os.Getenv("BLINDFOLD_MAPPING_KEYS")
+	}
+	if flagValue == "" {
+		return nil, errors.New("no keys: set -keys or BLINDFOLD_MAPPING_KEYS (see blindfold mapping keygen)")
+	}
+	return mapstore.ParseKeys(flagValue)
+}
+
+// labels is a repeatable -label key=value flag.
+type labels map[string]string
+
+func (l labels) String() string { return "" }
+
+func (l labels) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("%q: want key=value", s)
+	}
+	l[k] = v
+	return nil
+}
+
+func runKeygen(args []string) error {
+	fs := flag.NewFlagSet("mapping keygen", flag.ContinueOnError)
+	id := fs.String("id", time.Now().UTC().Format("2006-01"), "key ID recorded in the files it encrypts")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	k, err := mapstore.NewKey(*id)
+	if err != nil {
+		return err
+	}
+	fmt.Println(k.Encode())
+	return nil
+}
+
+const exportUsage = `usage: blindfold mapping export [flags] MAPPING.json|-
+
+Encrypts a mapping JSON object (token → value), as written by the recipes
+and read by verify-mapping, into a mapping file on stdout or -o.
+
+`
+
+func runExport(args []string) error {
+	fs, keysFlag := mappingFlags("export", exportUsage)
+	session := fs.String("session", "", "session, document or case the mapping belongs to")
+	policy := fs.String("policy", "", "policy the mapping was tokenized under")
+	out := fs.String("o", "", "output file (default: stdout)")
+	lbls := labels{}
+	fs.Var(lbls, "label", "key=value annotation, readable without the key (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("want one mapping file, or - for stdin")
+	}
+	keys, err := parseKeys(*keysFlag)
+	if err != nil {
+		return err
+	}
+	text, err := readInput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var m map[string]string
+	if err := json.Unmarshal([]byte(text), &m); err != nil {
+		return fmt.Errorf("%s: want a JSON object of token → value: %v", fs.Arg(0), err)
+	}
+	meta := mapstore.Metadata{Session: *session, Policy: *policy, Source: "blindfold mapping export"}
+	if len(lbls) > 0 {
+		meta.Labels = lbls
+	}
+	data, err := mapstore.Marshal(m, meta, keys[0])
+	if err != nil {
+		return err
+	}
+	return writeOutput(*out, append(data, '\n'))
+}
+
+const importUsage = `usage: blindfold mapping import [flags] FILE|-
+
+Decrypts a mapping file and prints its mapping JSON (token → value) on
+stdout or -o, and its metadata on stderr. The output holds the values in
+the clear: -o writes it readable by you only.
+
+`
+
+func runImport(args []string) error {
+	fs, keysFlag := mappingFlags("import", importUsage)
+	out := fs.String("o", "", "output file (default: stdout)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("want one mapping file, or - for stdin")
+	}
+	keys, err := parseKeys(*keysFlag)
+	if err != nil {
+		return err
+	}
+	text, err := readInput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	m, meta, err := mapstore.Unmarshal([]byte(text), keys...)
+	if err != nil {
+		return err
+	}
+	printMetadata(os.Stderr, fs.Arg(0), meta, "")
+	var data bytes.Buffer
+	enc := json.NewEncoder(&data)
+	enc.SetEscapeHTML(false) // tokens are <Type_N>
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return err
+	}
+	return writeOutput(*out, data.Bytes())
+}
+
+func runInspect(args []string) error {
+	fs := flag.NewFlagSet("mapping inspect", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "usage: blindfold mapping inspect FILE...\n\nPrints what each mapping file says about itself. No key is needed, and\nnothing is verified: only import checks a file.\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("want at least one FILE")
+	}
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		meta, keyID, err := mapstore.Inspect(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		printMetadata(os.Stdout, path, meta, keyID)
+	}
+	return nil
+}
+
+func printMetadata(w io.Writer, name string, meta mapstore.Metadata, keyID string) {
+	fmt.Fprintf(w, "%s: %d tokens", name, meta.Tokens())
+	if keyID != "" {
+		fmt.Fprintf(w, ", key %s", keyID)
+	}
+	fmt.Fprintln(w)
+	for _, f := range [][2]string{{"session", meta.Session}, {"policy", meta.Policy}, {"source", meta.Source}} {
+		if f[1] != "" {
+			fmt.Fprintf(w, "  %-9s %s\n", f[0], f[1])
+		}
+	}
+	fmt.Fprintf(w, "  %-9s %s\n  %-9s %s\n", "created", meta.Created.Format(time.RFC3339), "updated", meta.Updated.Format(time.RFC3339))
+	for _, k := range sortedKeys(meta.Entities) {
+		fmt.Fprintf(w, "  %-9s %s ×%d\n", "entity", k, meta.Entities[k])
+	}
+	for _, k := range sortedKeys(meta.Labels) {
+		fmt.Fprintf(w, "  %-9s %s=%s\n", "label", k, meta.Labels[k])
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+const rotateUsage = `usage: blindfold mapping rotate [flags] FILE...
+       blindfold mapping rotate [flags] -redis ADDR
+
+Re-encrypts mapping files in place, or the entries of a mapstore.Store in
+Redis while services use it, under the first of -keys. Files and entries
+already under it are left alone, so a run can be repeated. Rotate a
+store only once every service writes with the new key (see pkg/mapstore).
+
+`
+
+func runRotate(args []string) error {
+	fs, keysFlag := mappingFlags("rotate", rotateUsage)
+	redisAddr := fs.String("redis", "", "Redis address of a mapping store to rotate instead of files")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if (fs.NArg() == 0) == (*redisAddr == "") {
+		fs.Usage()
+		return errors.New("want mapping files or -redis, not both")
+	}
+	keys, err := parseKeys(*keysFlag)
+	if err != nil {
+		return err
+	}
+
+	if *redisAddr != "" {
+		store, err := mapstore.NewStore(cache.NewRedis(redis.NewClient(&redis.Options{Addr: *redisAddr}), 0), keys...)
+		if err != nil {
+			return err
+		}
+		stats, err := store.Rotate(context.Background())
+		fmt.Printf("%d entries: %d rotated to key %s, %d already under it, %d failed\n",
+			stats.Entries, stats.Rotated, keys[0].ID, stats.Current, len(stats.Failed))
+		for _, session := range sortedKeys(stats.Failed) {
+			fmt.Printf("  %s: %v\n", session, stats.Failed[session])
+		}
+		if err == nil && len(stats.Failed) > 0 {
+			err = fmt.Errorf("%d entries not rotated", len(stats.Failed))
+		}
+		return err
+	}
+
+	failed := 0
+	for _, path := range fs.Args() {
+		rotated, err := rotateFile(path, keys)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Printf("%s: %v\n", path, err)
+		case rotated:
+			fmt.Printf("%s: rotated to key %s\n", path, keys[0].ID)
+		default:
+			fmt.Printf("%s: already under key %s\n", path, keys[0].ID)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d files not rotated", failed)
+	}
+	return nil
+}
+
+// rotateFile rewraps the file at path and replaces it with a rename, so a
+// reader sees the old file or the new one, never half of either.
+func rotateFile(path string, keys []mapstore.Key) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	out, changed, err := mapstore.Rewrap(data, keys...)
+	if err != nil || !changed {
+		return false, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".rotate-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(out, '\n')); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err := tmp.Close(); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp.Name(), path)
+}
+
+// writeOutput writes data to path, owner-only, or to stdout if path is
+// empty.
+func writeOutput(path string, data []byte) error {
+	if path == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
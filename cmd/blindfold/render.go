@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// palette holds the ANSI styles entity types cycle through.
+var palette = []string{"1;33", "1;36", "1;35", "1;32", "1;34", "1;31;4", "33;4", "36;4"}
+
+// unresolved styles tokens a mapping can't restore.
+const unresolved = "1;37;41"
+
+// span is a highlighted byte range of a text.
+type span struct {
+	start, end int
+	style      string
+}
+
+// cell is one rune and its style ("" for plain).
+type cell struct {
+	r     rune
+	style string
+}
+
+// colors assigns palette styles to entity types in sorted order, so a type
+// has the same color on both sides.
+type colors map[string]string
+
+func newColors(types []string) colors {
+	sorted := append([]string(nil), types...)
+	sort.Strings(sorted)
+	c := make(colors, len(sorted))
+	for _, typ := range sorted {
+		if _, ok := c[typ]; !ok {
+			c[typ] = palette[len(c)%len(palette)]
+		}
+	}
+	return c
+}
+
+// useColor decides whether to write ANSI codes: "always", "never", or
+// "auto" for a terminal without NO_COLOR set.
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		fi, err := os.Stdout.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("-color %q: want auto, always or never", mode)
+}
+
+// cells styles text with spans. Overlapping spans after the first are
+// ignored.
+func cells(text string, spans []span) []cell {
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	out := make([]cell, 0, len(text))
+	k := 0
+	for i, r := range text {
+		for k < len(spans) && spans[k].end <= i {
+			k++
+		}
+		style := ""
+		if k < len(spans) && spans[k].start <= i {
+			style = spans[k].style
+		}
+		out = append(out, cell{r, style})
+	}
+	return out
+}
+
+// wrap splits cs into lines at newlines and at width runes, preferring to
+// break after a space.
+func wrap(cs []cell, width int) [][]cell {
+	var lines [][]cell
+	var line []cell
+	for _, c := range cs {
+		if c.r == '\n' {
+			lines = append(lines, line)
+			line = nil
+			continue
+		}
+		line = append(line, c)
+		if len(line) > width {
+			cut := width
+			for i := width; i > width/2; i-- {
+				if line[i-1].r == ' ' {
+					cut = i
+					break
+				}
+			}
+			lines = append(lines, line[:cut:cut])
+			line = append([]cell(nil), line[cut:]...)
+		}
+	}
+	return append(lines, line)
+}
+
+// paint renders a line padded to width.
+func paint(line []cell, width int, color bool) string {
+	var b strings.Builder
+	style := ""
+	for _, c := range line {
+		if color && c.style != style {
+			if style != "" {
+				b.WriteString("\x1b[0m")
+			}
+			if c.style != "" {
+				b.WriteString("\x1b[" + c.style + "m")
+			}
+			style = c.style
+		}
+		b.WriteRune(c.r)
+	}
+	if color && style != "" {
+		b.WriteString("\x1b[0m")
+	}
+	if n := len(line); n < width {
+		b.WriteString(strings.Repeat(" ", width-n))
+	}
+	return b.String()
+}
+
+// sideBySide writes left and right in two columns of width runes.
+func sideBySide(w io.Writer, leftTitle, rightTitle string, left, right []cell, width int, color bool) {
+	l, r := wrap(left, width), wrap(right, width)
+	// A trailing newline on both sides adds no line worth showing
+	for len(l) > 1 && len(r) > 1 && len(l[len(l)-1]) == 0 && len(r[len(r)-1]) == 0 {
+		l, r = l[:len(l)-1], r[:len(r)-1]
+	}
+	fmt.Fprintf(w, "%-*s │ %s\n%s─┼─%s\n", width, leftTitle, rightTitle, strings.Repeat("─", width), strings.Repeat("─", width))
+	for i := 0; i < len(l) || i < len(r); i++ {
+		var a, b []cell
+		if i < len(l) {
+			a = l[i]
+		}
+		if i < len(r) {
+			b = r[i]
+		}
+		fmt.Fprintf(w, "%s │ %s\n", paint(a, width, color), strings.TrimRight(paint(b, width, color), " "))
+	}
+}
+
+// legend writes each entity type in its color.
+func legend(w io.Writer, c colors, color bool) {
+	types := make([]string, 0, len(c))
+	for typ := range c {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	parts := make([]string, len(types))
+	for i, typ := range types {
+		cs := make([]cell, 0, len(typ))
+		for _, r := range typ {
+			cs = append(cs, cell{r, c[typ]})
+		}
+		parts[i] = paint(cs, 0, color)
+	}
+	if len(parts) > 0 {
+		fmt.Fprintf(w, "Legend: %s\n", strings.Join(parts, "  "))
+	}
+}
+
+// truncate shortens s to n runes for table columns.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+const reportUsage = `usage: blindfold report [flags] PATH...
+
+Runs detection over every text file under PATH and reports which entity
+types occur, how often, and where: per-type totals, per-document counts,
+and the files with the most PII. With -baseline, each type's count is
+compared to an earlier JSON report. Reports hold counts only, never values.
+
+`
+
+// Report is the result of a corpus scan.
+type Report struct {
+	GeneratedAt  time.Time      `json:"generated_at"`
+	Policy       string         `json:"policy"`
+	Files        int            `json:"files"`
+	FilesWithPII int            `json:"files_with_pii"`
+	Skipped      int            `json:"skipped"`
+	Totals       map[string]int `json:"totals"`
+	Documents    []Document     `json:"documents"`
+	Trend        []Trend        `json:"trend,omitempty"`
+}
+
+// Document is one file's counts.
+type Document struct {
+	Path     string         `json:"path"`
+	Bytes    int            `json:"bytes"`
+	Total    int            `json:"total"`
+	Entities map[string]int `json:"entities"`
+}
+
+// Trend compares one type's count with a baseline report.
+type Trend struct {
+	Type     string `json:"type"`
+	Previous int    `json:"previous"`
+	Current  int    `json:"current"`
+	Change   int    `json:"change"`
+}
+
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), reportUsage)
+		fs.PrintDefaults()
+	}
+	file := fs.String("policies", "", "policy file (YAML or JSON); empty = built-in policies only")
+	name := fs.String("policy", "strict", "policy to apply, by name")
+	format := fs.String("format", "text", "output format: text, json, csv or html")
+	out := fs.String("out", "", "output file (default: stdout)")
+	top := fs.Int("top", 10, "number of top files to list in text and HTML output")
+	baseline := fs.String("baseline", "", "earlier JSON report to compare totals with")
+	exts := fs.String("ext", ".txt,.md,.log,.csv,.json,.jsonl,.eml", "comma-separated file extensions to scan")
+	maxBytes := fs.Int("max-bytes", 1<<20, "skip files larger than this")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("want at least one PATH")
+	}
+	write, ok := reportWriters[*format]
+	if !ok {
+		return fmt.Errorf("-format %q: want text, json, csv or html", *format)
+	}
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		return err
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+
+	allowed := make(map[string]bool)
+	for _, e := range strings.Split(*exts, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			allowed[strings.ToLower(e)] = true
+		}
+	}
+	r, err := scan(context.Background(), bf, fs.Args(), allowed, *maxBytes)
+	if err != nil {
+		return err
+	}
+	r.Policy = pol.Name
+	if *baseline != "" {
+		if err := r.compare(*baseline); err != nil {
+			return err
+		}
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return write(w, r, *top)
+}
+
+// scan detects entities in every matching file under roots.
+func scan(ctx context.Context, bf bfclient.Client, roots []string, exts map[string]bool, maxBytes int) (*Report, error) {
+	r := &Report{GeneratedAt: time.Now().UTC(), Totals: make(map[string]int)}
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !exts[strings.ToLower(filepath.Ext(path))] {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if len(data) > maxBytes || bytes.IndexByte(data, 0) >= 0 {
+				r.Skipped++
+				return nil
+			}
+			res, err := bf.Detect(ctx, string(data))
+			if err != nil {
+				return fmt.Errorf("%s: detect: %w", path, err)
+			}
+			doc := Document{Path: path, Bytes: len(data), Entities: make(map[string]int)}
+			for _, e := range res.DetectedEntities {
+				doc.Entities[e.Type]++
+				r.Totals[e.Type]++
+				doc.Total++
+			}
+			r.Files++
+			if doc.Total > 0 {
+				r.FilesWithPII++
+			}
+			r.Documents = append(r.Documents, doc)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(r.Documents, func(i, j int) bool {
+		a, b := r.Documents[i], r.Documents[j]
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.Path < b.Path
+	})
+	return r, nil
+}
+
+// compare fills in the trend against the JSON report at path.
+func (r *Report) compare(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var prev Report
+	if err := json.Unmarshal(data, &prev); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, typ := range sortedTypes(r.Totals, prev.Totals) {
+		r.Trend = append(r.Trend, Trend{Type: typ, Previous: prev.Totals[typ], Current: r.Totals[typ], Change: r.Totals[typ] - prev.Totals[typ]})
+	}
+	return nil
+}
+
+// sortedTypes returns the keys of every map, most frequent in the first
+// map first.
+func sortedTypes(counts ...map[string]int) []string {
+	seen := make(map[string]bool)
+	var types []string
+	for _, m := range counts {
+		for typ := range m {
+			if !seen[typ] {
+				seen[typ] = true
+				types = append(types, typ)
+			}
+		}
+	}
+	sort.Slice(types, func(i, j int) bool {
+		a, b := counts[0][types[i]], counts[0][types[j]]
+		if a != b {
+			return a > b
+		}
+		return types[i] < types[j]
+	})
+	return types
+}
+
+var reportWriters = map[string]func(io.Writer, *Report, int) error{
+	"text": writeReportText,
+	"json": writeReportJSON,
+	"csv":  writeReportCSV,
+	"html": writeReportHTML,
+}
+
+func topDocuments(r *Report, n int) []Document {
+	var docs []Document
+	for _, d := range r.Documents {
+		if d.Total == 0 || len(docs) == n {
+			break
+		}
+		docs = append(docs, d)
+	}
+	return docs
+}
+
+func writeReportText(w io.Writer, r *Report, top int) error {
+	fmt.Fprintf(w, "Scanned %d files (%d skipped) with policy %s: %d contain PII\n\n", r.Files, r.Skipped, r.Policy, r.FilesWithPII)
+	fmt.Fprintf(w, "%-28s %8s %8s\n", "ENTITY TYPE", "COUNT", "FILES")
+	for _, typ := range sortedTypes(r.Totals) {
+		files := 0
+		for _, d := range r.Documents {
+			if d.Entities[typ] > 0 {
+				files++
+			}
+		}
+		fmt.Fprintf(w, "%-28s %8d %8d\n", truncate(typ, 28), r.Totals[typ], files)
+	}
+	if docs := topDocuments(r, top); len(docs) > 0 {
+		fmt.Fprintf(w, "\nTop files:\n")
+		for _, d := range docs {
+			fmt.Fprintf(w, "  %5d  %s  (%s)\n", d.Total, d.Path, summarizeCounts(d.Entities))
+		}
+	}
+	if len(r.Trend) > 0 {
+		fmt.Fprintf(w, "\nTrend vs baseline:\n")
+		for _, t := range r.Trend {
+			fmt.Fprintf(w, "  %-28s %6d → %-6d %+d\n", truncate(t.Type, 28), t.Previous, t.Current, t.Change)
+		}
+	}
+	return nil
+}
+
+func summarizeCounts(m map[string]int) string {
+	parts := make([]string, 0, len(m))
+	for _, typ := range sortedTypes(m) {
+		parts = append(parts, fmt.Sprintf("%s ×%d", typ, m[typ]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func writeReportJSON(w io.Writer, r *Report, _ int) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// writeReportCSV writes one row per document and a column per entity type.
+func writeReportCSV(w io.Writer, r *Report, _ int) error {
+	types := sortedTypes(r.Totals)
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"path", "bytes", "total"}, types...)); err != nil {
+		return err
+	}
+	for _, d := range r.Documents {
+		row := []string{d.Path, strconv.Itoa(d.Bytes), strconv.Itoa(d.Total)}
+		for _, typ := range types {
+			row = append(row, strconv.Itoa(d.Entities[typ]))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var reportHTML = template.Must(template.New("report").Funcs(template.FuncMap{
+	"summarize": summarizeCounts,
+	"signed":    func(n int) string { return fmt.Sprintf("%+d", n) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>PII report — {{.R.Policy}}</title>
+<style>
+body { font: 14px system-ui, sans-serif; margin: 2rem; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2rem; }
+th, td { border-bottom: 1px solid #ddd; padding: .3rem .8rem; text-align: left; }
+td.n { text-align: right; font-variant-numeric: tabular-nums; }
+.bar { background: #d9534f; height: .7rem; }
+.up { color: #c9302c; } .down { color: #449d44; }
+</style>
+</head>
+<body>
+<h1>PII report</h1>
+<p>{{.R.Files}} files scanned ({{.R.Skipped}} skipped) with policy <code>{{.R.Policy}}</code> on {{.R.GeneratedAt.Format "2006-01-02 15:04 UTC"}}: <b>{{.R.FilesWithPII}}</b> contain PII.</p>
+<h2>Entity types</h2>
+<table>
+<tr><th>Type</th><th>Count</th><th></th></tr>
+{{range .Types}}<tr><td>{{.Type}}</td><td class="n">{{.Count}}</td><td><div class="bar" style="width: {{.Width}}px"></div></td></tr>
+{{end}}</table>
+<h2>Top files</h2>
+<table>
+<tr><th>File</th><th>Entities</th><th>Breakdown</th></tr>
+{{range .Top}}<tr><td><code>{{.Path}}</code></td><td class="n">{{.Total}}</td><td>{{summarize .Entities}}</td></tr>
+{{end}}</table>
+{{if .R.Trend}}<h2>Trend vs baseline</h2>
+<table>
+<tr><th>Type</th><th>Previous</th><th>Current</th><th>Change</th></tr>
+{{range .R.Trend}}<tr><td>{{.Type}}</td><td class="n">{{.Previous}}</td><td class="n">{{.Current}}</td><td class="n {{if gt .Change 0}}up{{else if lt .Change 0}}down{{end}}">{{signed .Change}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+func writeReportHTML(w io.Writer, r *Report, top int) error {
+	type row struct {
+		Type         string
+		Count, Width int
+	}
+	types := sortedTypes(r.Totals)
+	rows := make([]row, len(types))
+	for i, typ := range types {
+		rows[i] = row{Type: typ, Count: r.Totals[typ], Width: 300 * r.Totals[typ] / r.Totals[types[0]]}
+	}
+	return reportHTML.Execute(w, struct {
+		R     *Report
+		Types []row
+		Top   []Document
+	}{r, rows, topDocuments(r, top)})
+}
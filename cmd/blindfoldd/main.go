@@ -0,0 +1,316 @@
+// blindfoldd is a local daemon that tokenizes, restores and scans text
+// for every tool on the machine — editor plugins, shell scripts, desktop
+// apps — so they share one warm client per policy, one cache, and session
+// mappings that outlive the tool that made them (see pkg/daemon).
+//
+// Usage:
+//
+//	blindfoldd [serve] [flags]            run the daemon
+//	blindfoldd <command> [flags] < text   talk to it
+//
+// Commands:
+//
+//	serve       run the daemon (the default)
+//	tokenize    tokenize stdin
+//	detokenize  restore the tokens in stdin
+//	scan        list the PII in stdin, masked; exits 1 if there is any
+//	forget      drop a session's mapping
+//	status      report on the daemon
+//
+// The daemon serves on a Unix domain socket, $XDG_RUNTIME_DIR/blindfoldd.sock
+// by default, or the named pipe \\.\pipe\blindfoldd-<user> on Windows.
+// Only the user running it can connect.
+//
+//	blindfoldd -policies policies.yaml &
+//	git diff | blindfoldd tokenize -session review-42 > safe.diff
+//	ask-model < safe.diff | blindfoldd detokenize -session review-42
+//
+// Sessions live in memory and end with the daemon. With -redis, the cache
+// and the sessions go to Redis, the sessions encrypted with the keys in
+// BLINDFOLD_MAPPING_KEYS (see blindfold mapping keygen), so they survive a
+// restart.
+//
+// Run "blindfoldd <command> -h" for a command's flags.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/cache"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/daemon"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapstore"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"serve", "run the daemon (the default)", runServe},
+	{"tokenize", "tokenize stdin", runTokenize},
+	{"detokenize", "restore the tokens in stdin", runDetokenize},
+	{"scan", "list the PII in stdin, masked; exits 1 if there is any", runScan},
+	{"forget", "drop a session's mapping", runForget},
+	{"status", "report on the daemon", runStatus},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: blindfoldd [<command>] [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-11s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun \"blindfoldd <command> -h\" for a command's flags.\n")
+}
+
+// errFound makes scan exit 1 without printing an error.
+var errFound = errors.New("PII found")
+
+func main() {
+	_ = godotenv.Load()
+	args := os.Args[1:]
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "help") {
+		usage()
+		os.Exit(2)
+	}
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	for _, c := range commands {
+		if c.name == name {
+			if err := c.run(args); err != nil {
+				if !errors.Is(err, errFound) && !errors.Is(err, flag.ErrHelp) {
+					fmt.Fprintf(os.Stderr, "blindfoldd %s: %v\n", c.name, err)
+				}
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "blindfoldd: unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	socket := fs.String("socket", "", "socket path, or pipe name on Windows (default: "+daemon.DefaultAddress()+")")
+	policies := fs.String("policies", "", "policy file (YAML or JSON); without one, requests can name the built-in policies")
+	policy := fs.String("policy", "", "policy for requests that don't name one (default: the file's default, or "+daemon.DefaultPolicy+")")
+	cacheSize := fs.Int("cache", 10000, "cached Detect and Tokenize results kept in memory; 0 turns the cache off")
+	redisAddr := fs.String("redis", "", "Redis address for the cache and encrypted sessions, instead of memory")
+	ttl := fs.Duration("ttl", 24*time.Hour, "with -redis, how long cache entries and sessions are kept")
+	keysFlag := fs.String("keys", "", "with -redis, id:base64 keys encrypting sessions, comma-separated (default: $BLINDFOLD_MAPPING_KEYS)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := daemon.Config{Policies: &policyconf.Config{}, Policy: *policy}
+	if *policies != "" {
+		pc, err := policyconf.Load(*policies)
+		if err != nil {
+			return err
+		}
+		cfg.Policies = pc
+	}
+	if cfg.Policy == "" {
+		cfg.Policy = cfg.Policies.Default
+	}
+	if cfg.Policy == "" {
+		cfg.Policy = daemon.DefaultPolicy
+	}
+	// Fail now on a default policy that doesn't resolve, not on the first
+	// request
+	if _, err := cfg.Policies.Policy(cfg.Policy); err != nil {
+		return err
+	}
+	if *redisAddr != "" {
+		if *keysFlag == "" {
+			*keysFlag = os.Getenv("BLINDFOLD_MAPPING_KEYS")
+		}
+		if *keysFlag == "" {
+			return errors.New("no keys: set -keys or BLINDFOLD_MAPPING_KEYS (see blindfold mapping keygen)")
+		}
+		keys, err := mapstore.ParseKeys(*keysFlag)
+		if err != nil {
+			return err
+		}
+		rdb := redis.NewClient(&redis.Options{Addr: *redisAddr})
+		store, err := mapstore.NewStore(cache.NewRedis(rdb, *ttl), keys...)
+		if err != nil {
+			return err
+		}
+		cfg.Store = store
+		cfg.Cache = cache.NewRedis(rdb, *ttl)
+	} else {
+		cfg.Store = protect.NewMemoryStore()
+		if *cacheSize > 0 {
+			cfg.Cache = cache.NewLRU(*cacheSize)
+		}
+	}
+	s := daemon.NewServer(cfg)
+
+	l, err := daemon.Listen(*socket)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = s.Shutdown(shutdown)
+	}()
+	log.Printf("blindfoldd: serving on %s (policy %s)", l.Addr(), cfg.Policy)
+	if err := s.Serve(l); !errors.Is(err, daemon.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// clientFlags returns a flag set for a client command, with -socket and
+// -json.
+func clientFlags(name, usage string) (*flag.FlagSet, *string, *bool) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	socket := fs.String("socket", "", "daemon socket, or pipe name on Windows (default: "+daemon.DefaultAddress()+")")
+	asJSON := fs.Bool("json", false, "print the daemon's whole response as JSON")
+	return fs, socket, asJSON
+}
+
+// call sends one request with stdin as its text, and prints the response
+// as JSON with -json.
+func call(socket string, asJSON bool, req daemon.Request) (*daemon.Response, error) {
+	if req.Op != daemon.OpForget && req.Op != daemon.OpStatus {
+		text, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		req.Text = string(text)
+	}
+	ctx := context.Background()
+	c, err := daemon.Dial(ctx, socket)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	res, err := c.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return res, enc.Encode(res)
+	}
+	return res, nil
+}
+
+func runTokenize(args []string) error {
+	fs, socket, asJSON := clientFlags("tokenize", "usage: blindfoldd tokenize [flags] < text\n\nPrints stdin tokenized. With -session, the same value keeps its token across calls and detokenize can restore it.\n\n")
+	session := fs.String("session", "", "session to keep the mapping in")
+	policy := fs.String("policy", "", "policy to tokenize with (default: the daemon's)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	res, err := call(*socket, *asJSON, daemon.Request{Op: daemon.OpTokenize, Session: *session, Policy: *policy})
+	if err != nil || *asJSON {
+		return err
+	}
+	fmt.Print(res.Text)
+	return nil
+}
+
+func runDetokenize(args []string) error {
+	fs, socket, asJSON := clientFlags("detokenize", "usage: blindfoldd detokenize -session id [flags] < text\n\nPrints stdin with the session's tokens restored.\n\n")
+	session := fs.String("session", "", "session whose mapping restores the tokens (required)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *session == "" {
+		fs.Usage()
+		return errors.New("-session is required")
+	}
+	res, err := call(*socket, *asJSON, daemon.Request{Op: daemon.OpDetokenize, Session: *session})
+	if err != nil || *asJSON {
+		return err
+	}
+	fmt.Print(res.Text)
+	if len(res.Unresolved) > 0 {
+		fmt.Fprintf(os.Stderr, "blindfoldd detokenize: no value for %s\n", strings.Join(res.Unresolved, ", "))
+	}
+	return nil
+}
+
+func runScan(args []string) error {
+	fs, socket, asJSON := clientFlags("scan", "usage: blindfoldd scan [flags] < text\n\nLists the PII in stdin with values masked, one finding per line, and exits 1 if there is any.\n\n")
+	policy := fs.String("policy", "", "policy to scan with (default: the daemon's)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	res, err := call(*socket, *asJSON, daemon.Request{Op: daemon.OpScan, Policy: *policy})
+	if err != nil {
+		return err
+	}
+	if !*asJSON {
+		for _, f := range res.Findings {
+			fmt.Printf("%d-%d\t%s\t%s\t%.2f\n", f.Start, f.End, f.Type, f.Masked, f.Score)
+		}
+	}
+	if len(res.Findings) > 0 {
+		return errFound
+	}
+	return nil
+}
+
+func runForget(args []string) error {
+	fs, socket, asJSON := clientFlags("forget", "usage: blindfoldd forget -session id [flags]\n\nDrops the session's mapping: its tokens can no longer be restored.\n\n")
+	session := fs.String("session", "", "session to drop (required)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *session == "" {
+		fs.Usage()
+		return errors.New("-session is required")
+	}
+	_, err := call(*socket, *asJSON, daemon.Request{Op: daemon.OpForget, Session: *session})
+	return err
+}
+
+func runStatus(args []string) error {
+	fs, socket, asJSON := clientFlags("status", "usage: blindfoldd status [flags]\n\n")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	res, err := call(*socket, *asJSON, daemon.Request{Op: daemon.OpStatus})
+	if err != nil || *asJSON {
+		return err
+	}
+	st := res.Status
+	fmt.Printf("mode:      %s\npolicy:    %s (of %s)\nrequests:  %d\ncache:     %d hits, %d misses\nup since:  %s\n",
+		st.Mode, st.Policy, strings.Join(st.Policies, ", "), st.Requests, st.CacheHits, st.CacheMisses, st.Started.Format(time.RFC3339))
+	return nil
+}
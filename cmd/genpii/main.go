@@ -0,0 +1,62 @@
+// genpii writes synthetic PII documents with ground-truth annotations.
+//
+// The output is JSONL in the labeled-corpus format read by the eval harness,
+// so a corpus of any size can be produced on demand:
+//
+//	go run ./cmd/genpii -n 1000 -domain medical -out medical.jsonl
+//	go run ./eval -corpus medical.jsonl -match overlap
+//
+// Use -text to emit plain text (one document per line) for feeding other
+// tools. Every value is fake; see package genpii for how each is built.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/genpii"
+)
+
+func main() {
+	n := flag.Int("n", 100, "number of documents")
+	seed := flag.Int64("seed", 1, "random seed; the same seed produces the same corpus")
+	domain := flag.String("domain", "", "template domain: "+strings.Join(genpii.Domains(), ", ")+" (default: mixed)")
+	out := flag.String("out", "", "output file (default: stdout)")
+	plain := flag.Bool("text", false, "write plain text without annotations")
+	flag.Parse()
+
+	if _, ok := genpii.Templates[*domain]; *domain != "" && !ok {
+		log.Fatalf("unknown -domain %q (want one of %s)", *domain, strings.Join(genpii.Domains(), ", "))
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	for _, doc := range genpii.New(*seed).Documents(*n, *domain) {
+		var err error
+		if *plain {
+			_, err = fmt.Fprintln(bw, doc.Text)
+		} else {
+			err = enc.Encode(doc)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
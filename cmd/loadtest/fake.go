@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// serveFake serves a chat completions API that answers after latency
+// with the placeholders of the last message, whole or, for stream: true,
+// as SSE deltas of 8 bytes 5 ms apart.
+func serveFake(addr string, latency time.Duration) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Stream   bool      `json:"stream"`
+			Messages []message `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) == 0 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		tokens := mapping.TokenPattern.FindAllString(req.Messages[len(req.Messages)-1].Content, -1)
+		reply := "Thanks, noted: " + strings.Join(tokens, ", ") + "."
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+		if !req.Stream {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{
+				"id": "fake", "object": "chat.completion",
+				"choices": []any{map[string]any{"index": 0, "message": map[string]any{"role": "assistant", "content": reply}, "finish_reason": "stop"}},
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher, _ := w.(http.Flusher)
+		chunk := func(delta map[string]any, finish any) {
+			data, _ := json.Marshal(map[string]any{
+				"id": "fake", "object": "chat.completion.chunk",
+				"choices": []any{map[string]any{"index": 0, "delta": delta, "finish_reason": finish}},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		for i := 0; i < len(reply); i += 8 {
+			chunk(map[string]any{"content": reply[i:min(i+8, len(reply))]}, nil)
+			time.Sleep(5 * time.Millisecond)
+		}
+		chunk(map[string]any{}, "stop")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+	log.Printf("loadtest: fake upstream on http://%s/v1 (%s to first byte)", addr, latency)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Fatalf("fake upstream: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,239 @@
+// loadtest replays synthetic conversations through the protection gateway
+// at a fixed request rate and reports latency percentiles, end to end and
+// for the gateway's tokenize, upstream and detokenize stages separately.
+//
+//	go run ./cmd/loadtest -fake-upstream 127.0.0.1:9090 -rps 50 -duration 30s &
+//	go run ./examples/gateway-go -upstream http://127.0.0.1:9090/v1 -server-timing
+//
+// With -fake-upstream, the load test also plays the model, so a run costs
+// nothing and the upstream's latency is known; it waits for the gateway
+// to come up before it starts. With -target "", it only plays the model.
+//
+// Requests are sent open loop: one every 1/rps whether or not earlier ones
+// have finished, with at most -concurrency in flight. A request due while
+// all slots are taken is skipped and counted, so a saturated gateway shows
+// up as skips rather than as a lower rate that looks healthy.
+//
+// Conversations come from pkg/genpii: each request is the next turn of one
+// of them, so later requests carry the earlier turns and grow the way chat
+// traffic does. The stage times come from the Server-Timing trailer the
+// gateway sends with -server-timing; without it, only the end-to-end and
+// time-to-first-byte figures are reported.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/genpii"
+)
+
+// sample is one request's outcome.
+type sample struct {
+	status int // 0 for a transport error
+	err    error
+	total  time.Duration
+	ttfb   time.Duration
+	stages map[string]time.Duration // from Server-Timing
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// conversation is a system prompt and alternating user and assistant
+// turns; request n of it sends the first n user turns.
+type conversation []message
+
+func newConversations(n, turns int, seed int64) []conversation {
+	g := genpii.New(seed)
+	convs := make([]conversation, n)
+	for i := range convs {
+		c := conversation{{Role: "system", Content: "You are a support agent. Keep placeholders like <Person_1> exactly as they are."}}
+		for t := 0; t < turns; t++ {
+			c = append(c, message{Role: "user", Content: g.Document("", "").Text})
+			c = append(c, message{Role: "assistant", Content: "Thanks, I've noted that. Is there anything else?"})
+		}
+		convs[i] = c
+	}
+	return convs
+}
+
+// turn returns the messages of request t (from 0): the system prompt and
+// turns up to and including user turn t.
+func (c conversation) turn(t int) []message { return c[:2+2*t] }
+
+func main() {
+	target := flag.String("target", "http://127.0.0.1:8080/v1", "gateway base URL")
+	rps := flag.Float64("rps", 20, "requests per second")
+	concurrency := flag.Int("concurrency", 32, "most requests in flight")
+	duration := flag.Duration("duration", 30*time.Second, "how long to send requests")
+	turns := flag.Int("turns", 4, "user turns per conversation")
+	streamShare := flag.Float64("stream", 0.5, "share of requests sent with stream: true (0 to 1)")
+	model := flag.String("model", "gpt-4o-mini", "model named in requests")
+	seed := flag.Int64("seed", 1, "random seed for the conversations")
+	timeout := flag.Duration("timeout", 30*time.Second, "per-request timeout")
+	fakeAddr := flag.String("fake-upstream", "", "also serve a fake OpenAI API on this address, for the gateway's -upstream (empty = off)")
+	fakeLatency := flag.Duration("upstream-latency", 300*time.Millisecond, "with -fake-upstream, time before the first byte of each reply")
+	flag.Parse()
+	if *rps <= 0 || *concurrency < 1 || *turns < 1 || *streamShare < 0 || *streamShare > 1 {
+		log.Fatal("-rps must be positive, -concurrency and -turns at least 1, -stream between 0 and 1")
+	}
+
+	log.SetFlags(0)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *fakeAddr != "" {
+		serveFake(*fakeAddr, *fakeLatency)
+		if *target == "" {
+			<-ctx.Done()
+			return
+		}
+	}
+	if err := waitFor(ctx, *target); err != nil {
+		log.Fatal(err)
+	}
+
+	convs := newConversations(max(1, int(*rps*duration.Seconds())/(*turns)+1), *turns, *seed)
+	client := &http.Client{Timeout: *timeout, Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency}}
+	rnd := rand.New(rand.NewSource(*seed))
+
+	var mu sync.Mutex
+	var samples []sample
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, *concurrency)
+	skipped := 0
+
+	log.Printf("loadtest: %s at %g rps for %s, at most %d in flight", *target, *rps, *duration, *concurrency)
+	start := time.Now()
+	tick := time.NewTicker(time.Duration(float64(time.Second) / *rps))
+	defer tick.Stop()
+	deadline := time.After(*duration)
+	progress := time.NewTicker(5 * time.Second)
+	defer progress.Stop()
+send:
+	for n := 0; ; {
+		select {
+		case <-ctx.Done():
+			break send
+		case <-deadline:
+			break send
+		case <-progress.C:
+			mu.Lock()
+			log.Printf("loadtest: %s: %d done, %d in flight, %d skipped", time.Since(start).Round(time.Second), len(samples), len(slots), skipped)
+			mu.Unlock()
+		case <-tick.C:
+			select {
+			case slots <- struct{}{}:
+			default:
+				mu.Lock()
+				skipped++
+				mu.Unlock()
+				continue
+			}
+			conv, turn := convs[n / *turns % len(convs)], n%*turns
+			body := map[string]any{"model": *model, "messages": conv.turn(turn), "stream": rnd.Float64() < *streamShare}
+			n++
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s := send(ctx, client, *target, body)
+				<-slots
+				mu.Lock()
+				samples = append(samples, s)
+				mu.Unlock()
+			}()
+		}
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report(os.Stdout, samples, skipped, elapsed)
+}
+
+// send posts one chat completion and reads the reply to the end.
+func send(ctx context.Context, client *http.Client, target string, body map[string]any) sample {
+	payload, _ := json.Marshal(body)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(target, "/")+"/chat/completions", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer loadtest")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return sample{err: err, total: time.Since(start)}
+	}
+	defer resp.Body.Close()
+	s := sample{status: resp.StatusCode}
+	// A streamed reply's first byte is its first chunk
+	first := make([]byte, 1)
+	if n, _ := resp.Body.Read(first); n > 0 {
+		s.ttfb = time.Since(start)
+	}
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		s.err = err
+	}
+	s.total = time.Since(start)
+	// Trailers are only there once the body has been read to the end
+	s.stages = parseServerTiming(resp.Trailer.Get("Server-Timing") + ", " + resp.Header.Get("Server-Timing"))
+	return s
+}
+
+// parseServerTiming reads "name;dur=1.5, name;dur=0.2" with durations in
+// milliseconds. Entries without a duration are ignored.
+func parseServerTiming(v string) map[string]time.Duration {
+	out := make(map[string]time.Duration)
+	for _, entry := range strings.Split(v, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(entry), ";")
+		for _, p := range strings.Split(params, ";") {
+			if ms, ok := strings.CutPrefix(strings.TrimSpace(p), "dur="); ok {
+				if f, err := strconv.ParseFloat(ms, 64); err == nil && name != "" {
+					out[name] += time.Duration(f * float64(time.Millisecond))
+				}
+			}
+		}
+	}
+	return out
+}
+
+// waitFor blocks until target's host accepts connections, for a gateway
+// started at the same time as the load test.
+func waitFor(ctx context.Context, target string) error {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("-target %q: want a URL such as http://127.0.0.1:8080/v1", target)
+	}
+	for i := 0; ; i++ {
+		conn, err := net.DialTimeout("tcp", u.Host, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if i == 0 {
+			log.Printf("loadtest: waiting for %s", u.Host)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// stages are the Server-Timing entries reported, in the gateway's order.
+// detokenize is the part of respond spent restoring tokens.
+var stages = []string{"decode", "tokenize", "audit", "upstream", "respond", "detokenize"}
+
+func report(w io.Writer, samples []sample, skipped int, elapsed time.Duration) {
+	ok, failed := 0, map[string]int{}
+	var total, ttfb []time.Duration
+	byStage := map[string][]time.Duration{}
+	for _, s := range samples {
+		switch {
+		case s.err != nil:
+			failed[s.err.Error()]++
+		case s.status != 200:
+			failed[fmt.Sprintf("HTTP %d", s.status)]++
+		default:
+			ok++
+			total, ttfb = append(total, s.total), append(ttfb, s.ttfb)
+			for name, d := range s.stages {
+				byStage[name] = append(byStage[name], d)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d requests in %s (%.1f/s): %d ok, %d failed, %d skipped at the concurrency limit\n",
+		len(samples), elapsed.Round(time.Millisecond), float64(len(samples))/elapsed.Seconds(), ok, len(samples)-ok, skipped)
+	for reason, n := range failed {
+		fmt.Fprintf(w, "  %5d × %s\n", n, reason)
+	}
+	if ok == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n%-12s %9s %9s %9s %9s %9s\n", "", "p50", "p90", "p99", "max", "n")
+	row := func(name string, ds []time.Duration) {
+		if len(ds) == 0 {
+			return
+		}
+		sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+		fmt.Fprintf(w, "%-12s %9s %9s %9s %9s %9d\n", name,
+			round(percentile(ds, 50)), round(percentile(ds, 90)), round(percentile(ds, 99)), round(ds[len(ds)-1]), len(ds))
+	}
+	row("total", total)
+	row("first byte", ttfb)
+	for _, name := range stages {
+		row("  "+name, byStage[name])
+	}
+	if len(byStage) == 0 {
+		fmt.Fprintln(w, "\nNo Server-Timing from the gateway; start it with -server-timing for the stage rows.")
+	}
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted ds.
+func percentile(ds []time.Duration, p float64) time.Duration {
+	i := int(p/100*float64(len(ds))+0.5) - 1
+	return ds[max(0, min(i, len(ds)-1))]
+}
+
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= 100*time.Millisecond:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
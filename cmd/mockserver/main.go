@@ -0,0 +1,53 @@
+// mockserver runs the Blindfold API mock as a standalone process, for CI
+// pipelines and non-Go test suites.
+//
+//	go run ./cmd/mockserver -addr :8089 -latency 20ms -fail-every 10
+//	export BLINDFOLD_BASE_URL=http://localhost:8089 BLINDFOLD_API_KEY=mock-api-key
+//
+// Point any SDK at the address with its base-URL option. Detection is
+// deterministic (local regex scanner); see package testing/mockserver.
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/blindfold-dev/blindfold-cookbook/testing/mockserver"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8089", "listen address")
+	key := flag.String("api-key", mockserver.APIKey, "accepted X-API-Key")
+	latency := flag.Duration("latency", 0, "delay added to every response")
+	locales := flag.String("locales", "us", "comma-separated detector locales")
+	failFirst := flag.Int("fail-first", 0, "fail the first N requests")
+	failEvery := flag.Int("fail-every", 0, "fail every Nth request")
+	failStatus := flag.Int("fail-status", http.StatusServiceUnavailable, "status code for injected failures")
+	flag.Parse()
+
+	opts := []mockserver.Option{
+		mockserver.WithAPIKey(*key),
+		mockserver.WithLatency(*latency),
+		mockserver.WithLocales(strings.Split(*locales, ",")...),
+	}
+	if *failFirst > 0 {
+		opts = append(opts, mockserver.WithFailFirst(*failFirst, *failStatus))
+	}
+	if *failEvery > 0 {
+		opts = append(opts, mockserver.WithFailEvery(*failEvery, *failStatus))
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	srv := mockserver.NewUnstarted(opts...)
+	srv.Listener.Close()
+	srv.Listener = ln
+	srv.Start()
+	log.Printf("Blindfold mock API listening on %s (X-API-Key: %s)", srv.URL, *key)
+	select {}
+}
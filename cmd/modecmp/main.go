@@ -0,0 +1,395 @@
+// modecmp runs local and cloud detection over the same corpus and reports
+// what cloud mode finds that local mode doesn't, to help decide whether an
+// API key is worth it for your data.
+//
+// Local mode is regex-based: emails, cards, phone numbers, national IDs.
+// Cloud mode adds NLP detection of names, organizations and addresses,
+// which no pattern catches reliably. How much that matters depends on the
+// data: support tickets are full of names, log lines mostly aren't. Run
+// modecmp over a sample of your own:
+//
+//	BLINDFOLD_API_KEY=... go run ./cmd/modecmp tickets/
+//	BLINDFOLD_API_KEY=... go run ./cmd/modecmp -format json -out modecmp.json logs/ exports/
+//
+// Each entity is matched across the modes by overlapping span. The report
+// counts, per type, entities both modes found, entities only cloud mode
+// found, and entities only local mode found (cloud mode also runs
+// patterns, so these are rare and worth a look), and lists the cloud-only
+// findings with their values masked. A value found by both modes with
+// different types counts as found by both; the report lists it apart.
+//
+// An API key is required: without one there is nothing to compare. The
+// exit status is 2 when the comparison can't run.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/joho/godotenv"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/masking"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+// Finding is an entity one mode found and the other didn't, or found with
+// another type.
+type Finding struct {
+	Path      string  `json:"path"`
+	Line      int     `json:"line"`
+	Column    int     `json:"column"` // 1-based, in bytes
+	Type      string  `json:"type"`
+	OtherType string  `json:"other_type,omitempty"` // the other mode's type, for relabeled entities
+	Score     float64 `json:"score"`
+	Mask      string  `json:"mask"`
+	value     string
+	context   string // the line, value masked
+}
+
+// Counts compares one entity type across the modes.
+type Counts struct {
+	Local     int `json:"local"`
+	Cloud     int `json:"cloud"`
+	Both      int `json:"both"`
+	CloudOnly int `json:"cloud_only"`
+	LocalOnly int `json:"local_only"`
+}
+
+// Report is the result of a comparison. It holds masked values only.
+type Report struct {
+	Policy      string             `json:"policy"`
+	Files       int                `json:"files"`
+	FilesGained int                `json:"files_gained"` // files where cloud mode found something local mode didn't
+	Skipped     int                `json:"skipped"`
+	Local       int                `json:"local"` // entities found, per mode
+	Cloud       int                `json:"cloud"`
+	Types       map[string]*Counts `json:"types"`
+	CloudOnly   []Finding          `json:"cloud_only"`
+	LocalOnly   []Finding          `json:"local_only"`
+	Relabeled   []Finding          `json:"relabeled"`
+}
+
+func (r *Report) counts(typ string) *Counts {
+	c, ok := r.Types[typ]
+	if !ok {
+		c = &Counts{}
+		r.Types[typ] = c
+	}
+	return c
+}
+
+type comparer struct {
+	local, cloud bfclient.Client
+	exts         map[string]bool
+	maxBytes     int64
+	minScore     float64
+	masks        masking.Rules
+}
+
+// run compares both modes over every matching file under roots.
+func (c *comparer) run(ctx context.Context, roots []string) (*Report, error) {
+	r := &Report{Types: make(map[string]*Counts)}
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !c.exts[strings.ToLower(filepath.Ext(path))] {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if info.Size() > c.maxBytes {
+				r.Skipped++
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+				r.Skipped++
+				return nil
+			}
+			if err := c.compare(ctx, r, filepath.ToSlash(path), string(data)); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			r.Files++
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// compare detects text in both modes and adds the differences to r.
+func (c *comparer) compare(ctx context.Context, r *Report, path, text string) error {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	local, err := c.detect(ctx, c.local, text)
+	if err != nil {
+		return fmt.Errorf("local: %w", err)
+	}
+	cloud, err := c.detect(ctx, c.cloud, text)
+	if err != nil {
+		return fmt.Errorf("cloud: %w", err)
+	}
+	r.Local += len(local)
+	r.Cloud += len(cloud)
+	for _, e := range local {
+		r.counts(e.Type).Local++
+	}
+
+	matched := make([]bool, len(local))
+	gained := false
+	for _, e := range cloud {
+		n := r.counts(e.Type)
+		n.Cloud++
+		i := overlapping(local, e)
+		if i < 0 {
+			n.CloudOnly++
+			r.CloudOnly = append(r.CloudOnly, c.finding(path, text, e, ""))
+			gained = true
+			continue
+		}
+		n.Both++
+		matched[i] = true
+		if local[i].Type != e.Type {
+			r.Relabeled = append(r.Relabeled, c.finding(path, text, e, local[i].Type))
+		}
+	}
+	for i, e := range local {
+		if !matched[i] && overlapping(cloud, e) < 0 {
+			r.counts(e.Type).LocalOnly++
+			r.LocalOnly = append(r.LocalOnly, c.finding(path, text, e, ""))
+		}
+	}
+	if gained {
+		r.FilesGained++
+	}
+	return nil
+}
+
+func (c *comparer) detect(ctx context.Context, bf bfclient.Client, text string) ([]blindfold.DetectedEntity, error) {
+	res, err := bf.Detect(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	var out []blindfold.DetectedEntity
+	for _, e := range res.DetectedEntities {
+		if e.Score >= c.minScore {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// overlapping returns the index of the first entity in es whose span
+// overlaps e's, or -1.
+func overlapping(es []blindfold.DetectedEntity, e blindfold.DetectedEntity) int {
+	for i, o := range es {
+		if o.Start < e.End && e.Start < o.End {
+			return i
+		}
+	}
+	return -1
+}
+
+// finding locates e in text and masks it.
+func (c *comparer) finding(path, text string, e blindfold.DetectedEntity, other string) Finding {
+	lineStart := strings.LastIndexByte(text[:e.Start], '\n') + 1
+	lineEnd := len(text)
+	if i := strings.IndexByte(text[e.Start:], '\n'); i >= 0 {
+		lineEnd = e.Start + i
+	}
+	mask := c.masks.Mask(e.Type, e.Text)
+	end := min(e.End, lineEnd)
+	return Finding{
+		Path:      path,
+		Line:      strings.Count(text[:e.Start], "\n") + 1,
+		Column:    e.Start - lineStart + 1,
+		Type:      e.Type,
+		OtherType: other,
+		Score:     e.Score,
+		Mask:      mask,
+		value:     e.Text,
+		context:   strings.TrimRight(text[lineStart:e.Start]+mask+text[end:lineEnd], "\r"),
+	}
+}
+
+func sortedTypes(types map[string]*Counts) []string {
+	out := make([]string, 0, len(types))
+	for t := range types {
+		out = append(out, t)
+	}
+	// Most cloud-only first: that's what the report is for
+	sort.Slice(out, func(i, j int) bool {
+		a, b := types[out[i]], types[out[j]]
+		if a.CloudOnly != b.CloudOnly {
+			return a.CloudOnly > b.CloudOnly
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+// writeText prints the per-type table, a verdict, and up to top findings
+// of each kind.
+func writeText(w io.Writer, r *Report, top int, showValues bool) {
+	fmt.Fprintf(w, "Compared %d files (%d skipped) with policy %q: local mode found %d entities, cloud mode %d\n\n", r.Files, r.Skipped, r.Policy, r.Local, r.Cloud)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "entity type\tlocal\tcloud\tboth\tcloud only\tlocal only\t")
+	for _, t := range sortedTypes(r.Types) {
+		n := r.Types[t]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t\n", t, n.Local, n.Cloud, n.Both, n.CloudOnly, n.LocalOnly)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	if len(r.CloudOnly) == 0 {
+		fmt.Fprintln(w, "Cloud mode found nothing local mode missed: local mode covers this corpus.")
+	} else {
+		var parts []string
+		for _, t := range sortedTypes(r.Types) {
+			if n := r.Types[t].CloudOnly; n > 0 {
+				parts = append(parts, fmt.Sprintf("%s %d", t, n))
+			}
+		}
+		fmt.Fprintf(w, "Cloud mode found %d entities local mode missed (%.0f%% of its findings), in %d of %d files: %s\n",
+			len(r.CloudOnly), 100*float64(len(r.CloudOnly))/float64(r.Cloud), r.FilesGained, r.Files, strings.Join(parts, ", "))
+	}
+
+	list := func(title string, fs []Finding) {
+		if len(fs) == 0 || top == 0 {
+			return
+		}
+		fmt.Fprintf(w, "\n%s:\n", title)
+		for i, f := range fs {
+			if i == top {
+				fmt.Fprintf(w, "  ... and %d more\n", len(fs)-top)
+				break
+			}
+			shown, line := f.Mask, f.context
+			if showValues {
+				shown = f.value
+				line = strings.Replace(line, f.Mask, f.value, 1)
+			}
+			kind := f.Type
+			if f.OtherType != "" {
+				kind = fmt.Sprintf("%s (local: %s)", f.Type, f.OtherType)
+			}
+			fmt.Fprintf(w, "  %s:%d:%d: %s %s (score %.2f)\n      | %s\n", f.Path, f.Line, f.Column, kind, shown, f.Score, strings.TrimSpace(line))
+		}
+	}
+	list("Only cloud mode found", r.CloudOnly)
+	list("Only local mode found", r.LocalOnly)
+	list("Found by both with different types", r.Relabeled)
+}
+
+func writeJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func main() {
+	_ = godotenv.Load()
+	log.SetFlags(0)
+	log.SetPrefix("modecmp: ")
+	file := flag.String("policies", "", "policy file (YAML or JSON); empty = built-in policies only")
+	name := flag.String("policy", "strict", "policy to apply in both modes, by name")
+	exts := flag.String("ext", ".txt,.md,.log,.csv,.json,.jsonl,.eml", "comma-separated file extensions to compare")
+	maxBytes := flag.Int64("max-bytes", 1<<20, "skip files larger than this")
+	minScore := flag.Float64("min-score", 0.5, "ignore entities below this score in either mode")
+	format := flag.String("format", "text", "output format: text or json")
+	out := flag.String("out", "", "output file (default: stdout)")
+	top := flag.Int("top", 20, "findings of each kind to list in text output")
+	showValues := flag.Bool("show-values", false, "print detected values instead of masking them (text output only)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: modecmp [flags] PATH...\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *format != "text" && *format != "json" {
+		log.Printf("-format %q: want text or json", *format)
+		os.Exit(2)
+	}
+	if os.Getenv("BLINDFOLD_API_KEY") == "" {
+		log.Print("BLINDFOLD_API_KEY is required: cloud mode is half of the comparison")
+		os.Exit(2)
+	}
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Print(err)
+		os.Exit(2)
+	}
+	c := &comparer{
+		local:    pol.Wrap(blindfold.New(append(pol.ClientOptions(), blindfold.WithMode("local"))...)),
+		cloud:    pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...)),
+		exts:     make(map[string]bool),
+		maxBytes: *maxBytes,
+		minScore: *minScore,
+		masks:    masking.Defaults(),
+	}
+	for _, e := range strings.Split(*exts, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			c.exts[strings.ToLower(e)] = true
+		}
+	}
+	r, err := c.run(context.Background(), flag.Args())
+	if err != nil {
+		log.Print(err)
+		os.Exit(2)
+	}
+	r.Policy = pol.Name
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Print(err)
+			os.Exit(2)
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		err = writeJSON(w, r)
+	} else {
+		writeText(w, r, *top, *showValues)
+	}
+	if err != nil {
+		log.Print(err)
+		os.Exit(2)
+	}
+}
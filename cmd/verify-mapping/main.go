@@ -0,0 +1,87 @@
+// verify-mapping checks a set of token mappings before they are used to
+// detokenize, and reports problems that would silently corrupt output:
+//
+//   - duplicate: a mapping file lists the same token twice; decoding keeps
+//     only the last value
+//   - collision: one token stands for different values in different files,
+//     so text from one session restores with another session's values
+//   - drift: one value has different tokens across sessions (or within
+//     one), so tokens stop being stable identifiers
+//   - empty: a token maps to an empty value, so detokenizing deletes it
+//   - orphan: a token in a text file has no entry in any mapping and will
+//     be left in the output
+//   - unused: a mapping token is in none of the text files, so a text lost
+//     it, or the texts belong to another mapping; only checked with -text
+//
+// Mappings are JSON objects of token → value, as written by Tokenize
+// callers in this repo; pass them in session order. Texts are checked
+// against all mappings together:
+//
+//	go run ./cmd/verify-mapping -text 'redacted/*.txt' redacted/*.mapping.json
+//
+// Reports name tokens and files only, never values. The exit status is 1
+// when problems are found and 2 when the input can't be read.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	log.SetFlags(0)
+	textGlob := flag.String("text", "", "glob of tokenized texts to check for orphan and unused tokens")
+	asJSON := flag.Bool("json", false, "write problems as JSON lines")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: verify-mapping [-text glob] [-json] mapping.json...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var texts []string
+	if *textGlob != "" {
+		var err error
+		if texts, err = filepath.Glob(*textGlob); err != nil {
+			log.Print(err)
+			os.Exit(2)
+		}
+		if len(texts) == 0 {
+			log.Printf("no texts match %s", *textGlob)
+			os.Exit(2)
+		}
+	}
+	problems, err := verify(flag.Args(), texts)
+	if err != nil {
+		log.Print(err)
+		os.Exit(2)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	for _, p := range problems {
+		if *asJSON {
+			if err := enc.Encode(p); err != nil {
+				log.Fatal(err)
+			}
+			continue
+		}
+		fmt.Printf("%-9s %s: %s %s\n", p.Kind, p.File, p.Token, p.Detail)
+	}
+	if len(problems) > 0 {
+		if !*asJSON {
+			fmt.Printf("\n%d problem(s) in %d mapping(s), %d text(s)\n", len(problems), flag.NArg(), len(texts))
+		}
+		os.Exit(1)
+	}
+	if !*asJSON {
+		fmt.Printf("OK: %d mapping(s), %d text(s)\n", flag.NArg(), len(texts))
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// Problem is one finding.
+type Problem struct {
+	Kind   string `json:"kind"`
+	File   string `json:"file"`
+	Token  string `json:"token"`
+	Detail string `json:"detail"`
+}
+
+// load reads a mapping file and reports tokens listed more than once.
+func load(path string) (map[string]string, []Problem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, nil, fmt.Errorf("%s: not a JSON object", path)
+	}
+	m := make(map[string]string)
+	var problems []Problem
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		token := t.(string) // object keys are always strings
+		var value string
+		if err := dec.Decode(&value); err != nil {
+			return nil, nil, fmt.Errorf("%s: %s: %w", path, token, err)
+		}
+		if prev, ok := m[token]; ok {
+			detail := "listed again with the same value"
+			if prev != value {
+				detail = "listed again with a different value; only the last is kept"
+			}
+			problems = append(problems, Problem{"duplicate", path, token, detail})
+		}
+		m[token] = value
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, nil, fmt.Errorf("%s: trailing data after the mapping", path)
+	}
+	return m, problems, nil
+}
+
+// verify reads the mappings at paths, in session order, and the texts at
+// textPaths, and checks them.
+func verify(paths, textPaths []string) ([]Problem, error) {
+	var problems []Problem
+	mappings := make([]map[string]string, len(paths))
+	for i, path := range paths {
+		m, dups, err := load(path)
+		if err != nil {
+			return nil, err
+		}
+		mappings[i] = m
+		problems = append(problems, dups...)
+	}
+	texts := make(map[string]string, len(textPaths))
+	for _, path := range textPaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		texts[path] = string(data)
+	}
+	return append(problems, check(paths, mappings, texts)...), nil
+}
+
+// check checks mappings, in session order and named by paths, and texts,
+// by file name, against all of them.
+func check(paths []string, mappings []map[string]string, texts map[string]string) []Problem {
+	var problems []Problem
+	for i, m := range mappings {
+		for _, token := range sortedKeys(m) {
+			if m[token] == "" {
+				problems = append(problems, Problem{"empty", paths[i], token, "maps to an empty value"})
+			}
+		}
+	}
+
+	merged := mapping.Merge(mappings...)
+	// Where each token was first bound, for the reports
+	firstSeen := make(map[string]int)
+	for i := len(mappings) - 1; i >= 0; i-- {
+		for token := range mappings[i] {
+			firstSeen[token] = i
+		}
+	}
+	for _, c := range merged.Conflicts {
+		switch c.Kind {
+		case mapping.SameToken:
+			problems = append(problems, Problem{"collision", paths[c.Source], c.Token,
+				"stands for a different value than in " + paths[firstSeen[c.Token]]})
+		case mapping.SameValue:
+			detail := "same value as " + c.Resolved
+			if i, ok := firstSeen[c.Resolved]; ok {
+				detail += " in " + paths[i]
+			}
+			problems = append(problems, Problem{"drift", paths[c.Source], c.Token, detail})
+		}
+	}
+	if len(texts) == 0 {
+		return problems
+	}
+
+	all := make(map[string]string)
+	for _, m := range mappings {
+		for token, value := range m {
+			all[token] = value
+		}
+	}
+	used := make(map[string]bool)
+	for _, path := range sortedKeys(texts) {
+		seen := make(map[string]bool)
+		for _, token := range mapping.TokenPattern.FindAllString(texts[path], -1) {
+			used[token] = true
+			if _, ok := all[token]; !ok && !seen[token] {
+				seen[token] = true
+				problems = append(problems, Problem{"orphan", path, token, "not in any mapping"})
+			}
+		}
+	}
+	for i, m := range mappings {
+		for _, token := range sortedKeys(m) {
+			if !used[token] {
+				problems = append(problems, Problem{"unused", paths[i], token, "in none of the texts"})
+			}
+		}
+	}
+	return problems
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
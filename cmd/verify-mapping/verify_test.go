@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+func TestCheck(t *testing.T) {
+	const text = "Mail <Email Address_1> or call <Phone Number_1>."
+	full := map[string]string{"<Email Address_1>": "jane@example.com", "<Phone Number_1>": "+1 415 555 0134"}
+	for _, c := range []struct {
+		name string
+		m    map[string]string
+		want []Problem
+	}{
+		{"missing token", map[string]string{"<Email Address_1>": "jane@example.com"},
+			[]Problem{{"orphan", "reply.txt", "<Phone Number_1>", "not in any mapping"}}},
+		{"extra token", map[string]string{"<Email Address_1>": "jane@example.com", "<Phone Number_1>": "+1 415 555 0134", "<Person_1>": "Jane Doe"},
+			[]Problem{{"unused", "a.json", "<Person_1>", "in none of the texts"}}},
+		{"empty value", map[string]string{"<Email Address_1>": "jane@example.com", "<Phone Number_1>": ""},
+			[]Problem{{"empty", "a.json", "<Phone Number_1>", "maps to an empty value"}}},
+		{"clean", full, nil},
+	} {
+		got := check([]string{"a.json"}, []map[string]string{c.m}, map[string]string{"reply.txt": text})
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestCheckAcrossSessions(t *testing.T) {
+	got := check([]string{"a.json", "b.json"}, []map[string]string{
+		{"<Person_1>": "Jane Doe", "<Email Address_1>": "jane@example.com"},
+		{"<Person_1>": "John Roe", "<Email Address_2>": "jane@example.com"},
+	}, nil)
+	want := []Problem{
+		{"drift", "b.json", "<Email Address_2>", "same value as <Email Address_1> in a.json"},
+		{"collision", "b.json", "<Person_1>", "stands for a different value than in a.json"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+// A mapping from Tokenize checks clean against its own text, read back
+// from the files a caller would write.
+func TestVerifyRoundTrip(t *testing.T) {
+	bf := blindfold.New(blindfold.WithMode("local"))
+	input := "Mail jane@example.com, card 4111 1111 1111 1111."
+	res, err := bf.Tokenize(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	mapPath, textPath := filepath.Join(dir, "a.mapping.json"), filepath.Join(dir, "a.redacted.txt")
+	data, err := json.Marshal(res.Mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(mapPath, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(textPath, []byte(res.Text), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := verify([]string{mapPath}, []string{textPath})
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("problems = %+v, err = %v", problems, err)
+	}
+	if got := bf.Detokenize(res.Text, res.Mapping).Text; got != input {
+		t.Errorf("restored %q, want %q", got, input)
+	}
+}
+
+func TestVerifyDuplicate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.json")
+	data := `{"<Person_1>": "Jane Doe", "<Person_1>": "John Roe"}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := verify([]string{path}, nil)
+	want := []Problem{{"duplicate", path, "<Person_1>", "listed again with a different value; only the last is kept"}}
+	if err != nil || !reflect.DeepEqual(problems, want) {
+		t.Errorf("problems = %+v, err = %v", problems, err)
+	}
+}
@@ -0,0 +1,307 @@
+// A/B answer quality with and without tokenization.
+//
+// Sends every prompt in a prompt set to a model twice, once with its
+// values in the clear and once with them replaced by placeholders, scores
+// both answers with the prompt's checks (after restoring the tokenized
+// one), and reports per task how much placeholder substitution costs.
+// Extraction and routing usually lose nothing; a task whose answer
+// depends on what a value is, such as an age from a date of birth, loses
+// everything, and that is the point of measuring.
+//
+// Usage:
+//
+//	go run ./eval/quality -n 3
+//	go run ./eval/quality -tokenize blindfold -judge gpt-4o -v
+//
+// The raw arm sends values in the clear, so it only ever runs on
+// synthetic data: prompts are pkg/genpii templates, filled here, and a
+// prompt whose fixed text has PII of its own outside the slots is
+// refused. There is no way to feed it real records.
+//
+// Requires OPENAI_API_KEY. With -tokenize blindfold, placeholders come
+// from Tokenize, in local mode unless BLINDFOLD_API_KEY is set.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/fewshot"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/genpii"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+// placeholderRule is added to the system prompt of the tokenized arm, as
+// the recipes in this repo do.
+const placeholderRule = " Values like <Person_1> and <Email Address_1> are placeholders for real data: " +
+	"copy them exactly as written where the answer needs them."
+
+func readPrompts(path string) ([]*Prompt, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var prompts []*Prompt
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		p := new(Prompt)
+		if err := json.Unmarshal(sc.Bytes(), p); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if p.ID == "" || p.Text == "" {
+			return nil, fmt.Errorf("%s:%d: id and prompt are required", path, line)
+		}
+		if p.Task == "" {
+			p.Task = p.ID
+		}
+		prompts = append(prompts, p)
+	}
+	return prompts, sc.Err()
+}
+
+// synthetic refuses a case whose text has PII outside its filled slots,
+// which would go to the model as is in the raw arm.
+func synthetic(ctx context.Context, bf bfclient.Client, c *Case) error {
+	res, err := bf.Detect(ctx, c.Text)
+	if err != nil {
+		return err
+	}
+	for _, e := range res.DetectedEntities {
+		inSlot := false
+		for _, s := range c.Spans {
+			if e.Start < s.End && s.Start < e.End {
+				inSlot = true
+				break
+			}
+		}
+		if !inSlot {
+			return fmt.Errorf("prompt %s: %s outside the template slots; the raw arm only sends synthetic values", c.Prompt.ID, e.Type)
+		}
+	}
+	return nil
+}
+
+// tokenizer produces the tokenized arm's prompt and its mapping.
+type tokenizer func(ctx context.Context, c *Case) (string, map[string]string, error)
+
+// bySpans replaces exactly the synthetic values with placeholders, from
+// their annotations. It measures the substitution alone, independent of
+// what detection would find.
+func bySpans(_ context.Context, c *Case) (string, map[string]string, error) {
+	ex := fewshot.Example{Input: c.Text, InputEntities: c.Spans}.Placeholders()
+	m := make(map[string]string, len(c.Spans))
+	for i, s := range ex.InputEntities {
+		m[s.Text] = c.Spans[i].Text
+	}
+	return ex.Input, m, nil
+}
+
+// byBlindfold tokenizes with bf, as a real pipeline would, and folds
+// repeats of a value into one token.
+func byBlindfold(bf bfclient.Client) tokenizer {
+	return func(ctx context.Context, c *Case) (string, map[string]string, error) {
+		res, err := bf.Tokenize(ctx, c.Text)
+		if err != nil {
+			return "", nil, err
+		}
+		tokenized, m := mapping.Fold(res.Text, res.Mapping)
+		return tokenized, m, nil
+	}
+}
+
+type runner struct {
+	oa     *openai.Client
+	model  string
+	judge  string
+	system string
+}
+
+func (r *runner) ask(ctx context.Context, model, system, prompt string, format *openai.ChatCompletionResponseFormat) (string, error) {
+	res, err := r.oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:          model,
+		Temperature:    0,
+		ResponseFormat: format,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: system},
+			{Role: openai.ChatMessageRoleUser, Content: prompt},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(res.Choices) == 0 {
+		return "", fmt.Errorf("empty response")
+	}
+	return strings.TrimSpace(res.Choices[0].Message.Content), nil
+}
+
+const judgePrompt = "You grade answers to a task. Rate each answer from 1 (wrong or useless) to 5 (correct and complete), " +
+	`judging only how well it does the task. Reply with a JSON object: {"a": <rating>, "b": <rating>}.`
+
+// rate asks the judge model to grade the raw and restored answers, shown
+// as A and B in an order that alternates between cases, against position
+// bias. It returns the raw rating first.
+func (r *runner) rate(ctx context.Context, c *Case, raw, restored string) ([2]int, error) {
+	a, b := raw, restored
+	flip := c.Variant%2 == 1
+	if flip {
+		a, b = b, a
+	}
+	prompt := fmt.Sprintf("Task:\n%s\n\nAnswer A:\n%s\n\nAnswer B:\n%s", c.Text, a, b)
+	out, err := r.ask(ctx, r.judge, judgePrompt, prompt, &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject})
+	if err != nil {
+		return [2]int{}, err
+	}
+	var v struct{ A, B int }
+	if err := json.Unmarshal([]byte(stripFence(out)), &v); err != nil {
+		return [2]int{}, fmt.Errorf("judge reply %q: %w", out, err)
+	}
+	if flip {
+		v.A, v.B = v.B, v.A
+	}
+	return [2]int{v.A, v.B}, nil
+}
+
+func printReport(w io.Writer, r *Report, verbose bool) {
+	fmt.Fprintf(w, "Evaluated %d cases with %s (placeholders from %s)\n\n", r.Cases, r.Model, r.Tokenize)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := "task\tcases\traw\ttokenized\tdelta\tperfect raw\tperfect tok\tunresolved\t"
+	if r.Judge != "" {
+		header += "judge raw\tjudge tok\t"
+	}
+	fmt.Fprintln(tw, header)
+	row := func(t *TaskResult) {
+		fmt.Fprintf(tw, "%s\t%d\t%.2f\t%.2f\t%+.2f\t%d\t%d\t%d\t", t.Task, t.Cases, t.Raw.Score, t.Tokenized.Score, t.Delta(), t.Raw.Perfect, t.Tokenized.Perfect, t.Unresolved)
+		if r.Judge != "" {
+			fmt.Fprintf(tw, "%.2f\t%.2f\t", t.Raw.Judge, t.Tokenized.Judge)
+		}
+		fmt.Fprintln(tw)
+	}
+	for _, t := range r.Tasks {
+		row(t)
+	}
+	row(&r.Overall)
+	tw.Flush()
+
+	if !verbose || len(r.Failures) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nFailed checks:")
+	for _, f := range r.Failures {
+		fmt.Fprintf(w, "  %s (%s): %s\n      %s\n", f.Case, f.Arm, strings.Join(f.Failed, "; "), strings.ReplaceAll(f.Answer, "\n", " "))
+	}
+}
+
+func main() {
+	_ = godotenv.Load()
+
+	promptsPath := flag.String("prompts", "eval/quality/prompts.jsonl", "prompt set (JSONL with pkg/genpii slots)")
+	n := flag.Int("n", 3, "variants of each prompt, each with fresh synthetic values")
+	seed := flag.Int64("seed", 1, "seed for the synthetic values")
+	model := flag.String("model", openai.GPT4oMini, "model under test")
+	mode := flag.String("tokenize", "spans", "placeholders from the values' annotations (spans) or from Tokenize (blindfold)")
+	file := flag.String("policies", "", "policy file for -tokenize blindfold (YAML or JSON); empty = built-in policies only")
+	name := flag.String("policy", "strict", "policy for -tokenize blindfold, by name")
+	judge := flag.String("judge", "", "model that also rates both answers 1–5 (default: checks only)")
+	system := flag.String("system", "You are a helpful customer support assistant. Follow the instructions exactly.", "system prompt for both arms")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	verbose := flag.Bool("v", false, "list every failed check with its answer")
+	flag.Parse()
+
+	if os.Getenv("OPENAI_API_KEY") == "" {
+		log.Fatal("OPENAI_API_KEY is required")
+	}
+	prompts, err := readPrompts(*promptsPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// The synthetic-only check runs offline, whatever -tokenize says
+	local := pol.Wrap(blindfold.New(append(pol.ClientOptions(), blindfold.WithMode("local"))...))
+	var tokenize tokenizer
+	switch *mode {
+	case "spans":
+		tokenize = bySpans
+	case "blindfold":
+		// API key is optional — omit it to run in local mode (regex-based, offline)
+		tokenize = byBlindfold(pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...)))
+	default:
+		log.Fatalf("unknown -tokenize %q (want spans or blindfold)", *mode)
+	}
+
+	ctx := context.Background()
+	run := &runner{oa: openai.NewClient(os.Getenv("OPENAI_API_KEY")), model: *model, judge: *judge, system: *system}
+	report := &Report{Model: *model, Tokenize: *mode, Judge: *judge}
+	g := genpii.New(*seed)
+	for _, p := range prompts {
+		for v := 0; v < *n; v++ {
+			c := fill(g, p, v)
+			id := fmt.Sprintf("%s#%d", p.ID, v+1)
+			if err := synthetic(ctx, local, c); err != nil {
+				log.Fatal(err)
+			}
+			rawAnswer, err := run.ask(ctx, *model, *system, c.Text, nil)
+			if err != nil {
+				log.Fatalf("%s raw: %v", id, err)
+			}
+			tokText, m, err := tokenize(ctx, c)
+			if err != nil {
+				log.Fatalf("%s tokenize: %v", id, err)
+			}
+			tokAnswer, err := run.ask(ctx, *model, *system+placeholderRule, tokText, nil)
+			if err != nil {
+				log.Fatalf("%s tokenized: %v", id, err)
+			}
+			unresolved := len(mapping.Unresolved(tokAnswer, m))
+			restored := mapping.Detokenize(tokAnswer, m)
+
+			raw, tok := c.score(rawAnswer), c.score(restored)
+			var ratings [2]int
+			if *judge != "" {
+				if ratings, err = run.rate(ctx, c, rawAnswer, restored); err != nil {
+					log.Fatalf("%s judge: %v", id, err)
+				}
+			}
+			report.add(c, raw, tok, ratings, unresolved)
+			if len(raw.Failed) > 0 {
+				report.Failures = append(report.Failures, Failure{Case: id, Arm: "raw", Failed: raw.Failed, Answer: rawAnswer})
+			}
+			if len(tok.Failed) > 0 {
+				report.Failures = append(report.Failures, Failure{Case: id, Arm: "tokenized", Failed: tok.Failed, Answer: tokAnswer})
+			}
+		}
+	}
+	report.finish()
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	printReport(os.Stdout, report, *verbose)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/genpii"
+)
+
+// Prompt is one line of the prompt set: a task written with pkg/genpii
+// slots ({Person:1}, {Email Address:1}) and the checks its answer must
+// pass. Checks use the same slots and get the same values as the prompt.
+type Prompt struct {
+	ID     string            `json:"id"`
+	Task   string            `json:"task"`
+	Text   string            `json:"prompt"`
+	Expect []string          `json:"expect"` // the answer contains each, ignoring case
+	Reject []string          `json:"reject"` // the answer contains none
+	JSON   bool              `json:"json"`   // the answer is a JSON object
+	Fields map[string]string `json:"fields"` // fields the JSON answer has, ignoring case
+}
+
+// Case is a prompt filled with one set of synthetic values.
+type Case struct {
+	Prompt  *Prompt
+	Variant int
+	Text    string
+	Spans   []genpii.Span // every synthetic value in Text
+	expect  []string
+	reject  []string
+	fields  [][2]string // key, value, sorted by key
+}
+
+// sep joins the prompt and its checks for filling, so they share slot
+// groups.
+const sep = "\x1f"
+
+// fill renders p with fresh values from g.
+func fill(g *genpii.Generator, p *Prompt, variant int) *Case {
+	keys := make([]string, 0, len(p.Fields))
+	for k := range p.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{p.Text}
+	parts = append(parts, p.Expect...)
+	parts = append(parts, p.Reject...)
+	for _, k := range keys {
+		parts = append(parts, p.Fields[k])
+	}
+	text, spans := g.Fill(strings.Join(parts, sep))
+	filled := strings.Split(text, sep)
+
+	c := &Case{Prompt: p, Variant: variant, Text: filled[0]}
+	for _, s := range spans {
+		if s.End <= len(c.Text) {
+			c.Spans = append(c.Spans, s)
+		}
+	}
+	rest := filled[1:]
+	c.expect, rest = rest[:len(p.Expect)], rest[len(p.Expect):]
+	c.reject, rest = rest[:len(p.Reject)], rest[len(p.Reject):]
+	for i, k := range keys {
+		c.fields = append(c.fields, [2]string{k, rest[i]})
+	}
+	return c
+}
+
+// Score is how an answer did on its case's checks.
+type Score struct {
+	Passed int
+	Total  int
+	Failed []string
+}
+
+// Fraction is the share of checks passed.
+func (s Score) Fraction() float64 {
+	if s.Total == 0 {
+		return 1
+	}
+	return float64(s.Passed) / float64(s.Total)
+}
+
+func (s *Score) check(ok bool, what string) {
+	s.Total++
+	if ok {
+		s.Passed++
+	} else {
+		s.Failed = append(s.Failed, what)
+	}
+}
+
+// normalize lowercases s and collapses whitespace, so line breaks and
+// capitalization don't fail a check.
+func normalize(s string) string { return strings.Join(strings.Fields(strings.ToLower(s)), " ") }
+
+// score runs c's checks over answer, the model's reply with any tokens
+// already restored.
+func (c *Case) score(answer string) Score {
+	var s Score
+	norm := normalize(answer)
+	for _, e := range c.expect {
+		s.check(strings.Contains(norm, normalize(e)), fmt.Sprintf("missing %q", e))
+	}
+	for _, r := range c.reject {
+		s.check(!strings.Contains(norm, normalize(r)), fmt.Sprintf("contains %q", r))
+	}
+	if !c.Prompt.JSON && len(c.fields) == 0 {
+		return s
+	}
+	var obj map[string]any
+	err := json.Unmarshal([]byte(stripFence(answer)), &obj)
+	s.check(err == nil, "not a JSON object")
+	for _, f := range c.fields {
+		got, ok := obj[f[0]]
+		s.check(ok && normalize(fmt.Sprint(got)) == normalize(f[1]), fmt.Sprintf("field %s is not %q", f[0], f[1]))
+	}
+	return s
+}
+
+// stripFence removes a Markdown code fence around a JSON answer.
+func stripFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	s = strings.TrimPrefix(s, "json")
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
+
+// Arm sums one arm's results for a task.
+type Arm struct {
+	Score   float64 `json:"score"`   // mean share of checks passed
+	Perfect int     `json:"perfect"` // answers that passed every check
+	Judge   float64 `json:"judge,omitempty"`
+}
+
+// TaskResult compares the arms on one task.
+type TaskResult struct {
+	Task      string `json:"task"`
+	Cases     int    `json:"cases"`
+	Raw       Arm    `json:"raw"`
+	Tokenized Arm    `json:"tokenized"`
+	// Unresolved counts tokens in tokenized answers that the mapping
+	// doesn't have: placeholders the model invented or mangled.
+	Unresolved int `json:"unresolved"`
+}
+
+// Delta is the tokenized score minus the raw score.
+func (t *TaskResult) Delta() float64 { return t.Tokenized.Score - t.Raw.Score }
+
+// Failure is a check an answer failed, for -v.
+type Failure struct {
+	Case   string   `json:"case"`
+	Arm    string   `json:"arm"`
+	Failed []string `json:"failed"`
+	Answer string   `json:"answer"`
+}
+
+// Report is the result of a run. Every value in it is synthetic.
+type Report struct {
+	Model    string        `json:"model"`
+	Tokenize string        `json:"tokenize"`
+	Judge    string        `json:"judge,omitempty"`
+	Cases    int           `json:"cases"`
+	Tasks    []*TaskResult `json:"tasks"`
+	Overall  TaskResult    `json:"overall"`
+	Failures []Failure     `json:"failures,omitempty"`
+
+	byTask map[string]*sums
+}
+
+type sums struct {
+	cases, unresolved        int
+	raw, tok, judgeR, judgeT float64
+	perfectR, perfectT       int
+}
+
+// add records one case's results.
+func (r *Report) add(c *Case, raw, tok Score, judge [2]int, unresolved int) {
+	if r.byTask == nil {
+		r.byTask = make(map[string]*sums)
+	}
+	for _, key := range []string{c.Prompt.Task, ""} {
+		s, ok := r.byTask[key]
+		if !ok {
+			s = &sums{}
+			r.byTask[key] = s
+		}
+		s.cases++
+		s.unresolved += unresolved
+		s.raw += raw.Fraction()
+		s.tok += tok.Fraction()
+		s.judgeR += float64(judge[0])
+		s.judgeT += float64(judge[1])
+		if len(raw.Failed) == 0 {
+			s.perfectR++
+		}
+		if len(tok.Failed) == 0 {
+			s.perfectT++
+		}
+	}
+	r.Cases++
+}
+
+// finish computes the per-task means.
+func (r *Report) finish() {
+	result := func(task string, s *sums) TaskResult {
+		n := float64(s.cases)
+		return TaskResult{
+			Task:       task,
+			Cases:      s.cases,
+			Raw:        Arm{Score: s.raw / n, Perfect: s.perfectR, Judge: s.judgeR / n},
+			Tokenized:  Arm{Score: s.tok / n, Perfect: s.perfectT, Judge: s.judgeT / n},
+			Unresolved: s.unresolved,
+		}
+	}
+	tasks := make([]string, 0, len(r.byTask))
+	for t := range r.byTask {
+		if t != "" {
+			tasks = append(tasks, t)
+		}
+	}
+	sort.Strings(tasks)
+	r.Tasks = nil
+	for _, t := range tasks {
+		tr := result(t, r.byTask[t])
+		r.Tasks = append(r.Tasks, &tr)
+	}
+	if s, ok := r.byTask[""]; ok {
+		r.Overall = result("ALL", s)
+	}
+}
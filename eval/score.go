@@ -0,0 +1,151 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/genpii"
+)
+
+// Counts are the confusion counts for one entity type.
+type Counts struct {
+	TP int `json:"tp"`
+	FP int `json:"fp"`
+	FN int `json:"fn"`
+}
+
+func (c Counts) Precision() float64 { return ratio(c.TP, c.TP+c.FP) }
+func (c Counts) Recall() float64    { return ratio(c.TP, c.TP+c.FN) }
+
+func (c Counts) F1() float64 {
+	p, r := c.Precision(), c.Recall()
+	if p+r == 0 {
+		return 0
+	}
+	return 2 * p * r / (p + r)
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// Report holds per-type counts for one detection mode.
+type Report struct {
+	Mode   string             `json:"mode"`
+	ByType map[string]*Counts `json:"by_type"`
+}
+
+func newReport(mode string) *Report {
+	return &Report{Mode: mode, ByType: make(map[string]*Counts)}
+}
+
+func (r *Report) counts(typ string) *Counts {
+	c, ok := r.ByType[typ]
+	if !ok {
+		c = &Counts{}
+		r.ByType[typ] = c
+	}
+	return c
+}
+
+// Total sums counts over all types (micro-average).
+func (r *Report) Total() Counts {
+	var t Counts
+	for _, c := range r.ByType {
+		t.TP += c.TP
+		t.FP += c.FP
+		t.FN += c.FN
+	}
+	return t
+}
+
+// Types returns the entity types in alphabetical order.
+func (r *Report) Types() []string {
+	types := make([]string, 0, len(r.ByType))
+	for t := range r.ByType {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// normalizeType makes "Email Address", "email address" and "EMAIL_ADDRESS"
+// compare equal — label sets and API responses don't always agree on case.
+func normalizeType(t string) string {
+	return strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(t, "_", " "))), " ")
+}
+
+// Score adds one document's detections to the report. A detection is a true
+// positive if its type matches an unclaimed label and the spans are equal
+// (exact) or intersect (overlap). Each label can be claimed once.
+func (r *Report) Score(doc genpii.Document, detected []blindfold.DetectedEntity, overlap bool) {
+	claimed := make([]bool, len(doc.Entities))
+	for _, d := range detected {
+		start, end := byteSpan(doc.Text, d)
+		typ := normalizeType(d.Type)
+		hit := false
+		for i, label := range doc.Entities {
+			if claimed[i] || normalizeType(label.Type) != typ {
+				continue
+			}
+			if (overlap && start < label.End && label.Start < end) || (start == label.Start && end == label.End) {
+				claimed[i] = true
+				hit = true
+				break
+			}
+		}
+		if hit {
+			r.counts(typ).TP++
+		} else {
+			r.counts(typ).FP++
+		}
+	}
+	for i, label := range doc.Entities {
+		if !claimed[i] {
+			r.counts(normalizeType(label.Type)).FN++
+		}
+	}
+}
+
+// byteSpan returns the byte offsets of a detection. Local mode reports byte
+// offsets; the API may report character offsets, which differ once the text
+// contains non-ASCII characters. The reported text decides which one it is.
+func byteSpan(text string, d blindfold.DetectedEntity) (int, int) {
+	if d.Start >= 0 && d.End <= len(text) && d.Start <= d.End && text[d.Start:d.End] == d.Text {
+		return d.Start, d.End
+	}
+	if start, end, ok := runeToByte(text, d.Start, d.End); ok && text[start:end] == d.Text {
+		return start, end
+	}
+	if i := strings.Index(text, d.Text); i >= 0 && d.Text != "" {
+		return i, i + len(d.Text)
+	}
+	return d.Start, d.End
+}
+
+func runeToByte(text string, startRune, endRune int) (int, int, bool) {
+	start, end := -1, -1
+	n := 0
+	for i := range text {
+		if n == startRune {
+			start = i
+		}
+		if n == endRune {
+			end = i
+		}
+		n++
+	}
+	if n == endRune {
+		end = len(text)
+	}
+	if n == startRune {
+		start = len(text)
+	}
+	return start, end, start >= 0 && end >= start && utf8.ValidString(text[start:end])
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/genpii"
+)
+
+func TestScore(t *testing.T) {
+	// "Jane Doe" is bytes 5-13, "jane@example.com" 17-33
+	doc := genpii.Document{
+		Text: "Call Jane Doe at jane@example.com today.",
+		Entities: []genpii.Span{
+			{Type: "Person", Start: 5, End: 13},
+			{Type: "Email Address", Start: 17, End: 33},
+		},
+	}
+	email := blindfold.DetectedEntity{Type: "Email Address", Text: "jane@example.com", Start: 17, End: 33}
+	jane := blindfold.DetectedEntity{Type: "Person", Text: "Jane", Start: 5, End: 9}
+	doe := blindfold.DetectedEntity{Type: "Person", Text: "Doe", Start: 10, End: 13}
+
+	for _, c := range []struct {
+		name     string
+		detected []blindfold.DetectedEntity
+		overlap  bool
+		want     map[string]Counts
+		p, r, f1 float64 // of the total
+	}{
+		{
+			name:     "partial overlap, exact",
+			detected: []blindfold.DetectedEntity{jane, email},
+			want:     map[string]Counts{"person": {FP: 1, FN: 1}, "email address": {TP: 1}},
+			p:        0.5, r: 0.5, f1: 0.5,
+		},
+		{
+			name:     "partial overlap, overlap",
+			detected: []blindfold.DetectedEntity{jane, email},
+			overlap:  true,
+			want:     map[string]Counts{"person": {TP: 1}, "email address": {TP: 1}},
+			p:        1, r: 1, f1: 1,
+		},
+		{
+			// A label is claimed once: the second half of the name is a
+			// false positive
+			name:     "two detections on one label",
+			detected: []blindfold.DetectedEntity{jane, doe},
+			overlap:  true,
+			want:     map[string]Counts{"person": {TP: 1, FP: 1}, "email address": {FN: 1}},
+			p:        0.5, r: 0.5, f1: 0.5,
+		},
+		{
+			// Types compare after normalizing, but a different type never
+			// matches, even on the exact span
+			name: "type mismatch",
+			detected: []blindfold.DetectedEntity{
+				{Type: "Organization", Text: "Jane Doe", Start: 5, End: 13},
+				{Type: "EMAIL_ADDRESS", Text: "jane@example.com", Start: 17, End: 33},
+			},
+			overlap: true,
+			want:    map[string]Counts{"organization": {FP: 1}, "person": {FN: 1}, "email address": {TP: 1}},
+			p:       0.5, r: 0.5, f1: 0.5,
+		},
+		{
+			name: "no detections",
+			want: map[string]Counts{"person": {FN: 1}, "email address": {FN: 1}},
+			p:    0, r: 0, f1: 0,
+		},
+	} {
+		r := newReport("test")
+		r.Score(doc, c.detected, c.overlap)
+		got := make(map[string]Counts, len(r.ByType))
+		for typ, counts := range r.ByType {
+			got[typ] = *counts
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: counts = %+v, want %+v", c.name, got, c.want)
+		}
+		total := r.Total()
+		for _, m := range []struct {
+			name      string
+			got, want float64
+		}{{"precision", total.Precision(), c.p}, {"recall", total.Recall(), c.r}, {"F1", total.F1(), c.f1}} {
+			if math.IsNaN(m.got) || math.Abs(m.got-m.want) > 1e-9 {
+				t.Errorf("%s: %s = %v, want %v", c.name, m.name, m.got, m.want)
+			}
+		}
+	}
+}
+
+func TestEmptyCounts(t *testing.T) {
+	// Nothing labeled and nothing detected: zeros, not NaN
+	var c Counts
+	if c.Precision() != 0 || c.Recall() != 0 || c.F1() != 0 {
+		t.Errorf("P/R/F1 = %v/%v/%v, want 0/0/0", c.Precision(), c.Recall(), c.F1())
+	}
+}
+
+func TestByteSpan(t *testing.T) {
+	// "ë" is two bytes, so rune and byte offsets of the email differ by one
+	text := "Zoë at zoe@example.com"
+	for _, d := range []blindfold.DetectedEntity{
+		{Text: "zoe@example.com", Start: 8, End: 23}, // bytes
+		{Text: "zoe@example.com", Start: 7, End: 22}, // runes
+		{Text: "zoe@example.com", Start: 0, End: 0},  // wrong: found by text
+	} {
+		if start, end := byteSpan(text, d); start != 8 || end != 23 {
+			t.Errorf("byteSpan(%d, %d) = %d, %d, want 8, 23", d.Start, d.End, start, end)
+		}
+	}
+}
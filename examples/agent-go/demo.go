@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+var emailToken = regexp.MustCompile(`<Email Address_\d+>`)
+
+// fakeModel answers chat completions as a scripted agent: plan, four
+// tool calls, then an answer. Like a real model it only knows what is in
+// the request, so every token it uses comes from an earlier message or
+// tool result.
+func fakeModel(req openai.ChatCompletionRequest) openai.ChatCompletionMessage {
+	var user string
+	results := map[string]map[string]any{} // tool name → last result
+	calls := 0
+	names := map[string]string{} // tool call ID → tool name
+	for _, m := range req.Messages {
+		switch m.Role {
+		case openai.ChatMessageRoleUser:
+			user = m.Content
+		case openai.ChatMessageRoleAssistant:
+			for _, tc := range m.ToolCalls {
+				names[tc.ID] = tc.Function.Name
+				calls++
+			}
+		case openai.ChatMessageRoleTool:
+			var v any
+			_ = json.Unmarshal([]byte(m.Content), &v)
+			switch v := v.(type) {
+			case map[string]any:
+				results[names[m.ToolCallID]] = v
+			case []any:
+				if len(v) > 0 {
+					results[names[m.ToolCallID]] = map[string]any{"orders": v}
+				}
+			}
+		}
+	}
+
+	msg := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+	call := func(name string, args map[string]string) {
+		msg.ToolCalls = []openai.ToolCall{{ID: fmt.Sprintf("call_%d", calls+1), Type: openai.ToolTypeFunction,
+			Function: openai.FunctionCall{Name: name, Arguments: result(args)}}}
+	}
+	cust, ords := results["find_customer"], results["list_orders"]
+	damaged := map[string]any{}
+	if ords != nil {
+		for _, o := range ords["orders"].([]any) {
+			if o := o.(map[string]any); strings.Contains(fmt.Sprint(o["status"]), "damaged") {
+				damaged = o
+			}
+		}
+	}
+	switch {
+	case req.ToolChoice == "none":
+		msg.Content = fmt.Sprintf("1. Find the customer with %s.\n2. List their orders and find the damaged one.\n3. Refund it.\n4. Email the customer a confirmation.\n5. Report back.", emailToken.FindString(user))
+	case calls == 0:
+		call("find_customer", map[string]string{"email": emailToken.FindString(user)})
+	case calls == 1:
+		call("list_orders", map[string]string{"customer_id": fmt.Sprint(cust["customer_id"])})
+	case calls == 2:
+		call("refund_order", map[string]string{"order_id": fmt.Sprint(damaged["order_id"]), "reason": "arrived damaged"})
+	case calls == 3:
+		call("send_email", map[string]string{
+			"to":      fmt.Sprint(cust["email"]),
+			"subject": "Your refund for order " + fmt.Sprint(damaged["order_id"]),
+			"body": fmt.Sprintf("Hi %s,\n\nWe've refunded $%v for the %s delivered to %s. It will reach your card in 5-10 business days.\nIf you'd like a call, we'll use %s.",
+				cust["name"], damaged["total"], damaged["item"], damaged["ship_to"], cust["phone"]),
+		})
+	default:
+		msg.Content = fmt.Sprintf("Refunded order %s ($%v) for %s and sent a confirmation to %s.", damaged["order_id"], damaged["total"], cust["name"], cust["email"])
+	}
+	return msg
+}
@@ -0,0 +1,139 @@
+// Tool-using agents + Blindfold: Keep placeholders consistent across a
+// plan, any number of tool calls, and the final answer.
+//
+// An agent makes many model calls per request, and PII enters at every
+// step: the user's message, then each tool result. One mapping (a
+// Session) is threaded through the whole run. The user's message and
+// every tool result are tokenized into it, tool-call arguments are
+// restored from it just before the tool runs, and the final answer is
+// restored from it once. A value that shows up again in a later tool
+// result gets the token it already had, so the model can keep referring
+// to <Person_1> from the first step to the last.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+const systemPrompt = "You are a support agent for a home-appliance store. Use the tools to act on the request. " +
+	"Values like <Person_1> and <Email Address_1> are placeholders for real customer data: pass them to tools " +
+	"and use them in emails exactly as written, never guess what they stand for."
+
+const planPrompt = "Before doing anything, write a short numbered plan for this request. Don't call any tools yet."
+
+// run executes one agent request: a plan, then tool calls until the model
+// answers or maxSteps is reached. trace prints what the model sees
+// (tokens) and what the tools do (real values).
+func run(ctx context.Context, oa *openai.Client, model string, s *Session, request string, maxSteps int) (string, error) {
+	protected, err := s.Protect(ctx, request)
+	if err != nil {
+		return "", fmt.Errorf("tokenize request: %w", err)
+	}
+	fmt.Printf("User (as the model sees it):\n  %s\n\n", protected)
+	msgs := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+		{Role: openai.ChatMessageRoleUser, Content: protected},
+	}
+
+	plan, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:      model,
+		Messages:   append(msgs, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: planPrompt}),
+		Tools:      tools,
+		ToolChoice: "none",
+	})
+	if err != nil {
+		return "", fmt.Errorf("plan: %w", err)
+	}
+	if len(plan.Choices) == 0 {
+		return "", errors.New("plan: empty response")
+	}
+	msgs = append(msgs, plan.Choices[0].Message)
+	fmt.Printf("Plan:\n  %s\n\n", strings.ReplaceAll(plan.Choices[0].Message.Content, "\n", "\n  "))
+
+	for step := 1; step <= maxSteps; step++ {
+		res, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{Model: model, Messages: msgs, Tools: tools})
+		if err != nil {
+			return "", fmt.Errorf("step %d: %w", step, err)
+		}
+		if len(res.Choices) == 0 {
+			return "", fmt.Errorf("step %d: empty response", step)
+		}
+		msg := res.Choices[0].Message
+		msgs = append(msgs, msg)
+		if len(msg.ToolCalls) == 0 {
+			if bad := s.Unresolved(msg.Content); len(bad) > 0 {
+				fmt.Printf("Warning: answer uses tokens the session never issued: %s\n", strings.Join(bad, ", "))
+			}
+			fmt.Printf("Answer (as the model wrote it):\n  %s\n\n", msg.Content)
+			return s.Restore(msg.Content), nil
+		}
+		for _, tc := range msg.ToolCalls {
+			fmt.Printf("Step %d  model → %s %s\n", step, tc.Function.Name, tc.Function.Arguments)
+			out, err := runTool(ctx, s, tc.Function.Name, s.RestoreJSON(tc.Function.Arguments), func(line string) {
+				fmt.Printf("        tool   %s\n", line)
+			})
+			if err != nil {
+				// Tokenizing a result failed or the call was malformed:
+				// tell the model instead of sending anything unprotected
+				out = result(map[string]string{"error": err.Error()})
+			}
+			fmt.Printf("        model ← %s\n", out)
+			msgs = append(msgs, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleTool, ToolCallID: tc.ID, Content: out})
+		}
+		fmt.Println()
+	}
+	return "", fmt.Errorf("no answer after %d steps", maxSteps)
+}
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "", "policy file (YAML or JSON); empty = built-in policies only")
+	name := flag.String("policy", "strict", "policy to apply, by name")
+	request := flag.String("request", "Customer jane.doe@example.com says her blender arrived broken. Refund the damaged order and email her a confirmation.", "request for the agent")
+	model := flag.String("model", openai.GPT4oMini, "chat model")
+	maxSteps := flag.Int("max-steps", 8, "most model round trips after the plan")
+	demo := flag.Bool("demo", false, "use a scripted in-process model instead of OpenAI")
+	flag.Parse()
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+
+	cfg := openai.DefaultConfig(os.Getenv("OPENAI_API_KEY"))
+	if *demo {
+		srv := fakeopenai.MessageServer(fakeModel)
+		defer srv.Close()
+		cfg = fakeopenai.Config(srv)
+	} else if os.Getenv("OPENAI_API_KEY") == "" {
+		log.Fatal("OPENAI_API_KEY is required (or run with -demo)")
+	}
+	oa := openai.NewClientWithConfig(cfg)
+
+	s := newSession(bf)
+	answer, err := run(ctx, oa, *model, s, *request, *maxSteps)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Answer (restored for the user):\n  %s\n\n%d values tokenized in one mapping across the run\n", answer, s.Len())
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// Session holds the one mapping an agent run uses, from the user's first
+// message to the final answer. Everything that goes to the model passes
+// through Protect or ProtectAs, and everything that comes back passes
+// through Restore, so a value keeps its token across every round trip
+// and tool call.
+type Session struct {
+	bf      bfclient.Client
+	mapping map[string]string
+	byValue map[string]string // entity type + value → token
+	highest map[string]int
+}
+
+func newSession(bf bfclient.Client) *Session {
+	return &Session{bf: bf, mapping: map[string]string{}, byValue: map[string]string{}, highest: map[string]int{}}
+}
+
+// Protect tokenizes text and merges its mapping into the session's. A
+// value already seen gets its existing token; a new value gets the next
+// free number for its type, so earlier tokens never change meaning.
+func (s *Session) Protect(ctx context.Context, text string) (string, error) {
+	res, err := s.bf.Tokenize(ctx, text)
+	if err != nil {
+		return "", err
+	}
+	if len(res.Mapping) == 0 {
+		return res.Text, nil
+	}
+	m := mapping.Merge(s.mapping, res.Mapping)
+	s.adopt(m.Mapping)
+	return m.Rewrite(1, res.Text), nil
+}
+
+// ProtectAs tokenizes a whole value as one entity of type typ, without
+// detection. Tool results are structured, so a name field is known to be
+// a name even where detection would miss it.
+func (s *Session) ProtectAs(typ, value string) string {
+	if value == "" {
+		return ""
+	}
+	if token, ok := s.byValue[typ+"\x00"+value]; ok {
+		return token
+	}
+	s.highest[typ]++
+	token := mapping.FormatToken(typ, s.highest[typ])
+	s.mapping[token] = value
+	s.byValue[typ+"\x00"+value] = token
+	return token
+}
+
+// Restore puts the session's values back into text.
+func (s *Session) Restore(text string) string {
+	return mapping.Detokenize(text, s.mapping)
+}
+
+// RestoreJSON restores tool-call arguments. They are decoded first, so a
+// token written as \u003cPerson_1\u003e is restored too, and re-encoded,
+// so a restored quote can't break them. Arguments that aren't JSON are
+// returned as they are, for the tool to reject.
+func (s *Session) RestoreJSON(args string) string {
+	dec := json.NewDecoder(strings.NewReader(args))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return args
+	}
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s.restoreValue(v)); err != nil {
+		return args
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (s *Session) restoreValue(v any) any {
+	switch v := v.(type) {
+	case string:
+		return s.Restore(v)
+	case map[string]any:
+		for k, e := range v {
+			v[k] = s.restoreValue(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = s.restoreValue(e)
+		}
+	}
+	return v
+}
+
+// Unresolved lists tokens in text the session never issued: a model that
+// invents <Person_7> gets it reported, not restored.
+func (s *Session) Unresolved(text string) []string {
+	return mapping.Unresolved(text, s.mapping)
+}
+
+// Len returns how many values the session has tokenized.
+func (s *Session) Len() int { return len(s.mapping) }
+
+func (s *Session) adopt(m map[string]string) {
+	s.mapping = m
+	for token, value := range m {
+		if typ, n, ok := mapping.ParseToken(token); ok {
+			s.byValue[typ+"\x00"+value] = token
+			if n > s.highest[typ] {
+				s.highest[typ] = n
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// The CRM the agent's tools run against. It holds real values; only the
+// tools see it.
+type customer struct {
+	ID, Name, Email, Phone, Notes string
+}
+
+type order struct {
+	ID, CustomerID, Item, Status, ShipTo string
+	Total                                float64
+}
+
+var customers = []customer{
+	{"C-1042", "Jane Doe", "jane.doe@example.com", "415-555-0134", "Prefers a callback on 415-555-0199 after 5pm."},
+	{"C-2210", "Omar Haddad", "omar.haddad@example.org", "312-555-0178", ""},
+}
+
+var orders = []order{
+	{"O-5531", "C-1042", "Blender BX-200", "delivered, reported damaged", "12 Market St, San Francisco, CA 94105", 89.00},
+	{"O-5602", "C-1042", "Toaster T-4", "delivered", "12 Market St, San Francisco, CA 94105", 45.00},
+	{"O-5710", "C-2210", "Kettle K-1", "shipped", "400 Lake Shore Dr, Chicago, IL 60611", 39.00},
+}
+
+func object(props map[string]jsonschema.Definition, required ...string) jsonschema.Definition {
+	return jsonschema.Definition{Type: jsonschema.Object, Properties: props, Required: required}
+}
+
+var str = jsonschema.Definition{Type: jsonschema.String}
+
+var tools = []openai.Tool{
+	{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{
+		Name: "find_customer", Description: "Look up a customer by email address.",
+		Parameters: object(map[string]jsonschema.Definition{"email": str}, "email")}},
+	{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{
+		Name: "list_orders", Description: "List a customer's orders.",
+		Parameters: object(map[string]jsonschema.Definition{"customer_id": str}, "customer_id")}},
+	{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{
+		Name: "refund_order", Description: "Refund an order in full.",
+		Parameters: object(map[string]jsonschema.Definition{"order_id": str, "reason": str}, "order_id", "reason")}},
+	{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{
+		Name: "send_email", Description: "Send an email to a customer.",
+		Parameters: object(map[string]jsonschema.Definition{"to": str, "subject": str, "body": str}, "to", "subject", "body")}},
+}
+
+// runTool executes a tool call. args arrive restored, with real values;
+// the result goes back tokenized through s, field by field. log records
+// what the tool did, in real values, for the operator.
+func runTool(ctx context.Context, s *Session, name, args string, log func(string)) (string, error) {
+	var in map[string]string
+	if err := json.Unmarshal([]byte(args), &in); err != nil {
+		return "", fmt.Errorf("%s: arguments: %w", name, err)
+	}
+	switch name {
+	case "find_customer":
+		for _, c := range customers {
+			if strings.EqualFold(c.Email, in["email"]) {
+				log(fmt.Sprintf("find_customer(%s) → %s", in["email"], c.ID))
+				notes, err := s.Protect(ctx, c.Notes)
+				if err != nil {
+					return "", err
+				}
+				return result(map[string]string{
+					"customer_id": c.ID,
+					"name":        s.ProtectAs("Person", c.Name),
+					"email":       s.ProtectAs("Email Address", c.Email),
+					"phone":       s.ProtectAs("Phone Number", c.Phone),
+					"notes":       notes,
+				}), nil
+			}
+		}
+		log(fmt.Sprintf("find_customer(%s) → not found", in["email"]))
+		return result(map[string]string{"error": "no customer with that email"}), nil
+	case "list_orders":
+		var out []map[string]any
+		for _, o := range orders {
+			if o.CustomerID == in["customer_id"] {
+				out = append(out, map[string]any{"order_id": o.ID, "item": o.Item, "status": o.Status, "total": o.Total,
+					"ship_to": s.ProtectAs("Address", o.ShipTo)})
+			}
+		}
+		log(fmt.Sprintf("list_orders(%s) → %d orders", in["customer_id"], len(out)))
+		return result(out), nil
+	case "refund_order":
+		for _, o := range orders {
+			if o.ID == in["order_id"] {
+				log(fmt.Sprintf("refund_order(%s, %q) → $%.2f", o.ID, in["reason"], o.Total))
+				return result(map[string]any{"refund_id": "R-" + strings.TrimPrefix(o.ID, "O-"), "amount": o.Total, "status": "issued"}), nil
+			}
+		}
+		return result(map[string]string{"error": "no such order"}), nil
+	case "send_email":
+		log(fmt.Sprintf("send_email(to %s)\n      Subject: %s\n      %s", in["to"], in["subject"], strings.ReplaceAll(in["body"], "\n", "\n      ")))
+		return result(map[string]string{"status": "sent"}), nil
+	}
+	return "", fmt.Errorf("unknown tool %q", name)
+}
+
+// result encodes a tool result. HTML escaping is off, so tokens reach
+// the model as <Person_1>, not \u003cPerson_1\u003e.
+func result(v any) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,72 @@
+// Allowlists and denylists + Blindfold: Tune detection to your organization.
+//
+// Some values look like PII but are public — a support mailbox, a hotline,
+// the office address — and tokenizing them only confuses the model. Others
+// are not PII to any detector but must never leave — project codenames, key
+// accounts. policies.yaml lists both, and pkg/policyconf applies them on top
+// of the built-in detectors.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/joho/godotenv"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+const sample = "Hi jane.doe@example.com, thanks for reaching support@mycompany.com or +1 800-555-0100. " +
+	"The nightingale rollout for Globex Corporation slips a week; Bluebird is unaffected. " +
+	"Visit us at 500 Market Street, San Francisco, or call me directly on +1 212-555-0187."
+
+func tokenize(ctx context.Context, pol *policyconf.Policy) error {
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+
+	tokenized, err := bf.Tokenize(ctx, sample)
+	if err != nil {
+		return fmt.Errorf("tokenize: %w", err)
+	}
+	fmt.Printf("Tokenized: %s\n", tokenized.Text)
+	tokens := make([]string, 0, len(tokenized.Mapping))
+	for token := range tokenized.Mapping {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	for _, token := range tokens {
+		fmt.Printf("  %-22s %s\n", token, tokenized.Mapping[token])
+	}
+	return nil
+}
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply (default: the file's default)")
+	flag.Parse()
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Input: %s\n", sample)
+	fmt.Println("\n== basic (no lists) ==")
+	if err := tokenize(ctx, policyconf.Builtin(pol.Base)); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("\n== %s (allow %d values, deny %d types) ==\n", pol.Name, len(pol.Allow), len(pol.Deny))
+	if err := tokenize(ctx, pol); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,155 @@
+// One-way redaction + Blindfold: Export support tickets for analytics with
+// PII removed for good.
+//
+// The export must hold nothing reversible, so tickets go through
+// pkg/redact instead of Tokenize: only Detect is called, no mapping ever
+// exists, and each entity is replaced by its type ("[Email Address]").
+// Rows keep the ticket's metadata and how many entities of each type were
+// removed, which is what the analytics side needs.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/redact"
+)
+
+// Row is one exported ticket. Body is a redact.Text rather than a string:
+// only Redactor.Redact makes one, so tokenized text, which has a mapping
+// somewhere, can't be assigned here by mistake.
+type Row struct {
+	ID        string         `json:"id"`
+	CreatedAt string         `json:"created_at"`
+	Channel   string         `json:"channel"`
+	Rating    int            `json:"rating"`
+	Body      redact.Text    `json:"body"`
+	Entities  map[string]int `json:"entities,omitempty"`
+}
+
+// export redacts every ticket in the CSV at path and writes one JSON line
+// per ticket to w. It returns the entity counts over all tickets.
+func export(ctx context.Context, r *redact.Redactor, path string, w io.Writer) (int, map[string]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+	rd := csv.NewReader(f)
+	header, err := rd.Read()
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[name] = i
+	}
+	for _, name := range []string{"id", "created_at", "channel", "rating", "body"} {
+		if _, ok := col[name]; !ok {
+			return 0, nil, fmt.Errorf("%s: no %q column", path, name)
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	total := make(map[string]int)
+	n := 0
+	for {
+		rec, err := rd.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, total, fmt.Errorf("%s: %w", path, err)
+		}
+		rating, err := strconv.Atoi(rec[col["rating"]])
+		if err != nil {
+			return n, total, fmt.Errorf("%s: ticket %s: rating: %w", path, rec[col["id"]], err)
+		}
+		body, err := r.Redact(ctx, rec[col["body"]])
+		if err != nil {
+			// Fail closed: a ticket that can't be redacted isn't exported
+			return n, total, fmt.Errorf("ticket %s: %w", rec[col["id"]], err)
+		}
+		row := Row{
+			ID:        rec[col["id"]],
+			CreatedAt: rec[col["created_at"]],
+			Channel:   rec[col["channel"]],
+			Rating:    rating,
+			Body:      body,
+			Entities:  body.Counts(),
+		}
+		if err := enc.Encode(row); err != nil {
+			return n, total, err
+		}
+		for typ, c := range row.Entities {
+			total[typ] += c
+		}
+		n++
+	}
+	return n, total, nil
+}
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply (default: the file's default)")
+	in := flag.String("in", "tickets.csv", "CSV of tickets (id, created_at, channel, rating, body)")
+	out := flag.String("out", "export/tickets.jsonl", `JSON lines export ("-" for stdout)`)
+	flag.Parse()
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	r := redact.New(pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...)))
+
+	w := io.Writer(os.Stdout)
+	if *out != "-" {
+		if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+			log.Fatal(err)
+		}
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = io.MultiWriter(f, os.Stdout)
+	}
+	n, total, err := export(ctx, r, *in, w)
+	if err != nil {
+		// Don't leave a partial export behind
+		if *out != "-" {
+			os.Remove(*out)
+		}
+		log.Fatal(err)
+	}
+
+	types := make([]string, 0, len(total))
+	for typ, c := range total {
+		types = append(types, fmt.Sprintf("%s ×%d", typ, c))
+	}
+	sort.Strings(types)
+	fmt.Printf("\nExported %d tickets to %s\nRemoved: %s\n", n, *out, strings.Join(types, ", "))
+	fmt.Println("No mapping was created: the export can't be restored.")
+}
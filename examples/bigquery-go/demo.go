@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+)
+
+// demo starts the service in-process and calls it the way BigQuery does:
+// one JSON request per batch, naming the function in userDefinedContext.
+// The last request runs against a Blindfold API that is overloaded, to
+// show the status BigQuery retries.
+func demo(h *handler) error {
+	log.SetFlags(0)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	n := 0
+	call := func(fn string, calls [][]any) (*response, error) {
+		n++
+		var body bytes.Buffer
+		enc := json.NewEncoder(&body)
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(struct {
+			RequestID   string            `json:"requestId"`
+			Caller      string            `json:"caller"`
+			SessionUser string            `json:"sessionUser"`
+			Context     map[string]string `json:"userDefinedContext"`
+			Calls       [][]any           `json:"calls"`
+		}{fmt.Sprintf("demo-%d", n), "//bigquery.googleapis.com/projects/acme/jobs/acme:US.bquxjob_1", "analyst@example.com",
+			map[string]string{"function": fn}, calls})
+		fmt.Printf("%s\n  → %s\n", fn, bytes.TrimSpace(body.Bytes()))
+		resp, err := http.Post(srv.URL, "application/json", &body)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		fmt.Printf("  ← %d %s\n\n", resp.StatusCode, bytes.TrimSpace(data))
+		var out response
+		_ = json.Unmarshal(data, &out)
+		return &out, nil
+	}
+
+	// SELECT support.blindfold_tokenize(body) FROM support.tickets
+	if _, err := call("tokenize", [][]any{
+		{"Refund order 5531 to card 4111 1111 1111 1111, confirm to jane.doe@example.com"},
+		{nil},
+		{"Call me back on 415-555-0199 after 5pm"},
+	}); err != nil {
+		return err
+	}
+
+	// RETURNS JSON: the reply is an object with text and mapping
+	res, err := call("tokenize_with_mapping", [][]any{{"Ticket from omar.haddad@example.com about IP 203.0.113.42"}})
+	if err != nil {
+		return err
+	}
+	if len(res.Replies) != 1 {
+		return fmt.Errorf("tokenize_with_mapping: no reply")
+	}
+	v := res.Replies[0].(map[string]any)
+	if _, err := call("detokenize", [][]any{{v["text"], v["mapping"]}}); err != nil {
+		return err
+	}
+
+	// A bad argument fails the query: BigQuery doesn't retry 400
+	if _, err := call("detokenize", [][]any{{"<Person_1>", []int{1}}}); err != nil {
+		return err
+	}
+
+	// An overloaded Blindfold API returns 429, which BigQuery retries
+	h.bf = overloaded{h.bf}
+	_, err = call("tokenize", [][]any{{"jane.doe@example.com"}})
+	return err
+}
+
+// overloaded answers every Tokenize call the way the Blindfold API does
+// when it is rate limiting.
+type overloaded struct{ bfclient.Client }
+
+func (overloaded) Tokenize(context.Context, string, ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	return nil, &blindfold.APIError{BlindfoldError: blindfold.BlindfoldError{Message: "API error: 429 rate limit exceeded", StatusCode: 429}}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/sqlfn"
+)
+
+// BigQuery remote functions POST a batch of calls as
+// {"requestId", "caller", "sessionUser", "userDefinedContext", "calls":
+// [[arg1, arg2, ...], ...]}, and expect {"replies": [...]} with exactly one
+// reply per call, in the same order. A failure is {"errorMessage": ...}
+// with a 4xx or 5xx status: BigQuery retries 408, 429, 500, 503 and 504,
+// and fails the query on anything else.
+type request struct {
+	RequestID   string              `json:"requestId"`
+	Caller      string              `json:"caller"`
+	SessionUser string              `json:"sessionUser"`
+	Context     map[string]string   `json:"userDefinedContext"`
+	Calls       [][]json.RawMessage `json:"calls"`
+}
+
+type response struct {
+	Replies []any `json:"replies"`
+}
+
+type failure struct {
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// handler serves the pkg/sqlfn functions. One endpoint can carry them
+// all: the function is named by the "function" key of the remote
+// function's user_defined_context (see setup.sql), or else by the path.
+// If any call fails, the whole request fails and no reply is returned:
+// BigQuery never gets a row back untokenized.
+type handler struct {
+	bf      bfclient.Client
+	workers int
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.reply(w, http.StatusMethodNotAllowed, failure{ErrorMessage: "remote functions are called with POST"})
+		return
+	}
+	var in request
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		h.reply(w, http.StatusBadRequest, failure{ErrorMessage: "body is not a remote function request: " + err.Error()})
+		return
+	}
+	name := in.Context["function"]
+	if name == "" {
+		name = strings.Trim(r.URL.Path, "/")
+	}
+	fn, ok := sqlfn.Funcs[name]
+	if !ok {
+		h.reply(w, http.StatusBadRequest, failure{ErrorMessage: fmt.Sprintf("unknown function %q: set user_defined_context function to one of tokenize, tokenize_with_mapping, detokenize", name)})
+		return
+	}
+	rows := make([][]*string, len(in.Calls))
+	for i, call := range in.Calls {
+		rows[i] = sqlfn.Args(call)
+	}
+	results, err := sqlfn.Run(r.Context(), h.bf, fn, rows, h.workers)
+	// The caller is the job that ran the query; it identifies the query and
+	// holds no row data
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, sqlfn.ErrBadArgs):
+			status = http.StatusBadRequest
+		case bferrors.IsRetryable(err):
+			status = http.StatusTooManyRequests
+		}
+		log.Printf("%s request=%s caller=%s calls=%d kind=%s: %v", name, in.RequestID, in.Caller, len(rows), bferrors.KindOf(err), err)
+		h.reply(w, status, failure{ErrorMessage: fmt.Sprintf("%s: %v", name, err)})
+		return
+	}
+	// Counts only: the log never holds a value
+	if fn.Tokenizes {
+		log.Printf("%s request=%s caller=%s calls=%d entities=%s", name, in.RequestID, in.Caller, len(rows), sqlfn.Entities(results))
+	} else {
+		log.Printf("%s request=%s caller=%s calls=%d", name, in.RequestID, in.Caller, len(rows))
+	}
+	h.reply(w, http.StatusOK, response{Replies: results})
+}
+
+func (h *handler) reply(w http.ResponseWriter, status int, res any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(res)
+}
@@ -0,0 +1,82 @@
+// BigQuery remote function + Blindfold: Tokenize warehouse columns from
+// SQL.
+//
+// Runs an HTTP service that speaks BigQuery's remote-function contract,
+// the Cloud Functions and Cloud Run HTTP interface, so once the function
+// is created (see setup.sql),
+//
+//	SELECT support.blindfold_tokenize(body) FROM support.tickets;
+//
+// scrubs a column in place, before the rows are exported to an LLM
+// workflow. BigQuery sends calls in batches; each batch is tokenized in
+// parallel and answered in call order. A batch that can't be tokenized
+// fails as a whole, so no row ever comes back raw, and transient failures
+// return a status BigQuery retries. The BigQuery counterpart of the
+// Snowflake recipe: both serve the functions in pkg/sqlfn.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/config"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/health"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+func main() {
+	// Every flag can also be set as BIGQUERY_FN_<FLAG> or in the -config file
+	cfg := config.New(flag.CommandLine, "BIGQUERY_FN")
+	// Cloud Run and Cloud Functions set PORT and expect the service on it
+	defaultAddr := "127.0.0.1:8083"
+	if port := os.Getenv("PORT"); port != "" {
+		defaultAddr = ":" + port
+	}
+	addr := flag.String("addr", defaultAddr, "listen address (default :$PORT when PORT is set)")
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply, by name (default: the file's default)")
+	workers := flag.Int("workers", 8, "calls of a batch tokenized in parallel")
+	runDemo := flag.Bool("demo", false, "send sample requests the way BigQuery does to an in-process service and exit")
+	cfg.File("config")
+	cfg.Check("workers", func() error {
+		if *workers < 1 {
+			return errors.New("must be at least 1")
+		}
+		return nil
+	})
+	if err := cfg.Parse(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	h := &handler{
+		// API key is optional — omit it to run in local mode (regex-based, offline)
+		bf:      pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...)),
+		workers: *workers,
+	}
+
+	if *runDemo {
+		if err := demo(h); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", h)
+	health.NewService(flag.CommandLine, pol, h.bf).Register(mux)
+	log.Printf("remote functions listening on http://%s (policy %s)", *addr, pol.Name)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		log.Print(err)
+	}
+}
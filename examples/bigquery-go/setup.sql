@@ -0,0 +1,63 @@
+-- Blindfold remote functions for BigQuery.
+--
+-- Deploy the service to Cloud Run (or Cloud Functions, 2nd gen) first:
+--
+--   gcloud run deploy blindfold-fn --source . --region us-central1 --no-allow-unauthenticated
+--
+-- then create a connection, and let its service account invoke the service:
+--
+--   bq mk --connection --location=US --connection_type=CLOUD_RESOURCE blindfold
+--   bq show --connection US.blindfold   # prints the connection's serviceAccountId
+--   gcloud run services add-iam-policy-binding blindfold-fn --region us-central1 \
+--     --member=serviceAccount:<serviceAccountId> --role=roles/run.invoker
+--
+-- BigQuery signs each request with that service account, so only it can
+-- call the service.
+
+-- One endpoint serves every function; user_defined_context names which.
+-- max_batching_rows bounds the work per request.
+CREATE OR REPLACE FUNCTION support.blindfold_tokenize(text STRING) RETURNS STRING
+REMOTE WITH CONNECTION `acme.US.blindfold`
+OPTIONS (
+  endpoint = 'https://blindfold-fn-abc123-uc.a.run.app',
+  user_defined_context = [("function", "tokenize")],
+  max_batching_rows = 200
+);
+
+CREATE OR REPLACE FUNCTION support.blindfold_tokenize_with_mapping(text STRING) RETURNS JSON
+REMOTE WITH CONNECTION `acme.US.blindfold`
+OPTIONS (
+  endpoint = 'https://blindfold-fn-abc123-uc.a.run.app',
+  user_defined_context = [("function", "tokenize_with_mapping")],
+  max_batching_rows = 200
+);
+
+CREATE OR REPLACE FUNCTION support.blindfold_detokenize(text STRING, mapping JSON) RETURNS STRING
+REMOTE WITH CONNECTION `acme.US.blindfold`
+OPTIONS (
+  endpoint = 'https://blindfold-fn-abc123-uc.a.run.app',
+  user_defined_context = [("function", "detokenize")],
+  max_batching_rows = 200
+);
+
+-- Scrub in place before an export: the values are gone for good.
+CREATE OR REPLACE TABLE support.tickets_for_llm AS
+SELECT id, created_at, support.blindfold_tokenize(body) AS body
+FROM support.tickets;
+
+-- Keep a way back: text for the LLM workflow, mappings in a dataset only a
+-- few principals can read.
+CREATE TEMP TABLE tokenized AS
+SELECT id, support.blindfold_tokenize_with_mapping(body) AS t
+FROM support.tickets;
+
+CREATE OR REPLACE TABLE support.tickets_protected AS
+SELECT id, JSON_VALUE(t, '$.text') AS body FROM tokenized;
+
+CREATE OR REPLACE TABLE restricted.ticket_mappings AS
+SELECT id, JSON_QUERY(t, '$.mapping') AS mapping FROM tokenized;
+
+-- Restore model output for a ticket, for principals that may see the values.
+SELECT s.id, support.blindfold_detokenize(s.summary, m.mapping) AS summary
+FROM support.ticket_summaries s
+JOIN restricted.ticket_mappings m USING (id);
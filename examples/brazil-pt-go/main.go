@@ -0,0 +1,80 @@
+// Brazilian Portuguese pipeline + Blindfold: Answer customers without
+// sending CPF, CNPJ or phone numbers to the LLM.
+//
+// Customer messages in Portuguese are tokenized with the Brazil pack from
+// pkg/packs — CPF and CNPJ numbers are only tokenized when their check
+// digits are valid, including the alphanumeric CNPJ format — then the LLM
+// classifies each message and drafts a reply in Portuguese, and the reply
+// is restored before it goes back to the customer.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/packs"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+)
+
+const systemPrompt = "Você é um atendente de suporte de uma loja online brasileira. " +
+	"Para cada mensagem, responda em português com duas linhas: " +
+	"\"Categoria:\" (cobrança, entrega, cadastro ou outro) e \"Resposta:\" (uma resposta curta e cordial ao cliente). " +
+	"Mantenha marcadores como <Brazilian CPF_1> exatamente como estão."
+
+var messages = []string{
+	"Olá, sou a Ana Souza. Fui cobrada duas vezes no cartão. Meu CPF é 529.982.247-25, podem verificar? Meu celular é (11) 98765-4321.",
+	"Somos a empresa de CNPJ 12.ABC.345/01DE-35 e a nota fiscal saiu com o CNPJ antigo 11.222.333/0001-81. Como corrigir?",
+	"Meu pedido 2024-0042 ainda não chegou. Endereço: Av. Paulista, 1578, CEP 01310-200. Telefone fixo +55 11 3456-7890.",
+}
+
+func handle(ctx context.Context, p *protect.Protector, oa *openai.Client, message string, dryRun bool) error {
+	fmt.Printf("Mensagem:   %s\n", message)
+	chat := protect.Chat(ctx, oa, openai.GPT4oMini, systemPrompt)
+	reply, err := p.Do(ctx, message, func(safe string) (string, error) {
+		fmt.Printf("Tokenizada: %s\n", safe)
+		if dryRun {
+			return "", nil
+		}
+		return chat(safe)
+	})
+	if err != nil || dryRun {
+		return err
+	}
+	fmt.Printf("\n%s\n", reply)
+	return nil
+}
+
+func main() {
+	_ = godotenv.Load()
+	dryRun := flag.Bool("dry-run", false, "tokenize only; skip OpenAI calls")
+	flag.Parse()
+	ctx := context.Background()
+
+	pol, err := packs.Policy("br")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	p := protect.New(bfclient.FromEnv(pol.ClientOptions()...))
+	p.Policy = pol
+	oa := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
+
+	for i, msg := range messages {
+		fmt.Printf("== Mensagem %d ==\n", i+1)
+		if err := handle(ctx, p, oa, msg, *dryRun); err != nil {
+			log.Printf("mensagem %d: %v", i+1, err)
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,182 @@
+// Call-center batch pipeline + Blindfold: Summarize a night's calls and
+// write one report.
+//
+// Every transcript in a folder is tokenized, summarized by the LLM with its
+// action items extracted, and restored; a pool of workers handles the calls
+// concurrently under shared rate limits. Each call's result is written as
+// soon as it is done and doubles as a checkpoint, so a run that is
+// interrupted or hits errors picks up where it left off. Once every call is
+// in, the results are rolled up into a single Markdown report.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/resilience"
+)
+
+type job struct {
+	call, sum string
+	data      []byte
+}
+
+type outcome struct {
+	call string
+	res  *CallResult
+	err  error
+}
+
+func main() {
+	_ = godotenv.Load()
+	dir := flag.String("dir", "transcripts", "directory of .txt call transcripts")
+	outDir := flag.String("out", "output", "directory for per-call results and the report")
+	policy := flag.String("policy", "strict", "built-in Blindfold policy")
+	workers := flag.Int("workers", 4, "calls processed concurrently")
+	rps := flag.Float64("rps", 5, "request rate limit, per service (0 = unlimited)")
+	date := flag.String("date", time.Now().Format("2006-01-02"), "report date")
+	force := flag.Bool("force", false, "reprocess calls that already have a result")
+	dryRun := flag.Bool("dry-run", false, "tokenize only; skip OpenAI calls and write nothing")
+	flag.Parse()
+
+	// Ctrl-C stops handing out calls; finished results are kept for the
+	// next run
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	// A rejected key or a used-up quota fails every call the same way, so
+	// the first such error stops the batch instead of running through it
+	ctx, abort := context.WithCancel(ctx)
+	defer abort()
+	var fatal error
+
+	pol, err := policyconf.Resolve("", *policy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := bfclient.FromEnv(append(pol.ClientOptions(), blindfold.WithMaxRetries(0))...)
+	protector := protect.New(resilience.Wrap(bf, resilience.DefaultPolicy(*rps)))
+	protector.Policy = pol
+	p := &pipeline{
+		protector: protector,
+		llm:       resilience.WrapChat(openai.NewClient(os.Getenv("OPENAI_API_KEY")), resilience.DefaultPolicy(*rps)),
+		dir:       filepath.Join(*outDir, "calls"),
+		dryRun:    *dryRun,
+	}
+	if !*dryRun {
+		if err := os.MkdirAll(p.dir, 0o700); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(*dir, "*.txt"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(files) == 0 {
+		log.Fatalf("no transcripts in %s", *dir)
+	}
+	var calls []string
+	var pending []job
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		call := strings.TrimSuffix(filepath.Base(file), ".txt")
+		calls = append(calls, call)
+		sum := hashTranscript(data)
+		if !*force && !*dryRun && p.done(call, sum) {
+			continue
+		}
+		pending = append(pending, job{call: call, sum: sum, data: data})
+	}
+	fmt.Printf("%d calls, %d already done, %d to process\n", len(calls), len(calls)-len(pending), len(pending))
+
+	jobs := make(chan job)
+	outcomes := make(chan outcome)
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				res, err := p.process(ctx, j.call, j.data, j.sum)
+				outcomes <- outcome{call: j.call, res: res, err: err}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, j := range pending {
+			select {
+			case jobs <- j:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	failed := make(map[string]error)
+	for o := range outcomes {
+		if o.err != nil {
+			if fatal != nil && bferrors.KindOf(o.err) == bferrors.Canceled {
+				continue // stopped by the abort
+			}
+			failed[o.call] = o.err
+			log.Printf("%s: %v", o.call, o.err)
+			if fatal == nil && bferrors.IsFatal(o.err) {
+				fatal = o.err
+				abort()
+			}
+			continue
+		}
+		fmt.Printf("  %s: %d entities protected, %d action items\n", o.call, o.res.Entities, len(o.res.ActionItems))
+	}
+	if fatal != nil {
+		log.Fatalf("stopped after a %s error; fix it and rerun to resume", bferrors.KindOf(fatal))
+	}
+	if ctx.Err() != nil {
+		log.Fatal("interrupted; rerun to resume")
+	}
+	if *dryRun {
+		return
+	}
+
+	results, err := loadResults(p.dir, calls)
+	if err != nil {
+		log.Fatal(err)
+	}
+	path := filepath.Join(*outDir, "report-"+*date+".md")
+	if err := os.WriteFile(path, []byte(report(*date, results, failed)), 0o600); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Wrote %s (%d calls)\n", path, len(results))
+	if len(failed) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+)
+
+const systemPrompt = "You summarize customer-service calls for the operations team. " +
+	"Reply with a JSON object: " +
+	`{"summary": "two sentences", "action_items": [{"owner": "agent, team or customer", "task": "...", "due": "when, if stated"}]}. ` +
+	"Keep placeholders like <Person_1> exactly as they are."
+
+// ActionItem is a follow-up task extracted from a call.
+type ActionItem struct {
+	Owner string `json:"owner"`
+	Task  string `json:"task"`
+	Due   string `json:"due,omitempty"`
+}
+
+// CallResult is the restored outcome of one call. It doubles as the
+// checkpoint: a call whose result exists for the same transcript hash is
+// not processed again.
+type CallResult struct {
+	Call        string       `json:"call"`
+	SHA256      string       `json:"sha256"`
+	Summary     string       `json:"summary"`
+	ActionItems []ActionItem `json:"action_items"`
+	Entities    int          `json:"entities"`
+	ProcessedAt time.Time    `json:"processed_at"`
+}
+
+// pipeline processes transcripts into results under dir.
+type pipeline struct {
+	protector *protect.Protector
+	llm       bfclient.ChatCompleter
+	dir       string // one <call>.json per processed call
+	dryRun    bool
+}
+
+func (p *pipeline) resultPath(call string) string {
+	return filepath.Join(p.dir, call+".json")
+}
+
+// done reports whether call already has a result for this transcript.
+func (p *pipeline) done(call, sum string) bool {
+	r, err := readResult(p.resultPath(call))
+	return err == nil && r.SHA256 == sum
+}
+
+// process tokenizes one transcript, has the LLM summarize it and extract
+// action items, restores both, and writes the result.
+func (p *pipeline) process(ctx context.Context, call string, transcript []byte, sum string) (*CallResult, error) {
+	var out struct {
+		Summary     string       `json:"summary"`
+		ActionItems []ActionItem `json:"action_items"`
+	}
+	res, err := p.protector.Run(ctx, string(transcript), func(safe string) (string, error) {
+		if p.dryRun {
+			return "", nil
+		}
+		completion, err := p.llm.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+			Model: openai.GPT4oMini,
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+				{Role: openai.ChatMessageRoleUser, Content: safe},
+			},
+			ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+		})
+		if err != nil {
+			return "", bferrors.Wrap("openai", err)
+		}
+		if len(completion.Choices) == 0 {
+			return "", errors.New("openai: empty response")
+		}
+		reply := completion.Choices[0].Message.Content
+		if err := json.Unmarshal([]byte(reply), &out); err != nil {
+			return "", fmt.Errorf("parse reply: %w", err)
+		}
+		return reply, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	r := &CallResult{Call: call, SHA256: sum, Entities: len(res.Entities)}
+	if p.dryRun {
+		return r, nil
+	}
+
+	restore := func(s string) string { return mapping.Detokenize(s, res.Mapping) }
+	r.Summary = restore(out.Summary)
+	for _, a := range out.ActionItems {
+		r.ActionItems = append(r.ActionItems, ActionItem{Owner: restore(a.Owner), Task: restore(a.Task), Due: restore(a.Due)})
+	}
+	r.ProcessedAt = time.Now().UTC()
+	if err := writeResult(p.resultPath(call), r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func hashTranscript(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func readResult(path string) (*CallResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r CallResult
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// writeResult writes r through a temporary file and a rename, so an
+// interrupted run never leaves a half-written checkpoint behind.
+func writeResult(path string, r *CallResult) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// Results hold restored PII: readable by the owner only
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadResults reads every result in dir for the given calls, in order.
+// Calls without a result are skipped.
+func loadResults(dir string, calls []string) ([]*CallResult, error) {
+	var out []*CallResult
+	for _, call := range calls {
+		r, err := readResult(filepath.Join(dir, call+".json"))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// report renders the consolidated Markdown report.
+func report(date string, results []*CallResult, failed map[string]error) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Call report — %s\n\n", date)
+	items := 0
+	for _, r := range results {
+		items += len(r.ActionItems)
+	}
+	fmt.Fprintf(&b, "%d calls summarized, %d action items", len(results), items)
+	if len(failed) > 0 {
+		kinds := make(map[string]int)
+		for _, err := range failed {
+			kinds[bferrors.KindOf(err).String()]++
+		}
+		var byKind []string
+		for k, n := range kinds {
+			byKind = append(byKind, fmt.Sprintf("%d %s", n, k))
+		}
+		sort.Strings(byKind)
+		fmt.Fprintf(&b, ", %d failed: %s (rerun to retry)", len(failed), strings.Join(byKind, ", "))
+	}
+	b.WriteString(".\n")
+
+	b.WriteString("\n## Action items\n\n| Call | Owner | Task | Due |\n|---|---|---|---|\n")
+	for _, r := range results {
+		for _, a := range r.ActionItems {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", r.Call, cell(a.Owner), cell(a.Task), cell(a.Due))
+		}
+	}
+
+	b.WriteString("\n## Calls\n")
+	for _, r := range results {
+		fmt.Fprintf(&b, "\n### %s\n\n%s\n", r.Call, r.Summary)
+	}
+	return b.String()
+}
+
+// cell escapes a value for a Markdown table cell.
+func cell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+const systemPrompt = "You route customer support tickets. Classify the ticket's intent and priority, and say whether it needs a human. " +
+	"Values like <Email Address_1> are placeholders for customer data; they never change the label."
+
+// schema is the structured output the model must return.
+var schema = jsonschema.Definition{
+	Type: jsonschema.Object,
+	Properties: map[string]jsonschema.Definition{
+		"intent":      {Type: jsonschema.String, Enum: intents},
+		"priority":    {Type: jsonschema.String, Enum: priorities},
+		"needs_human": {Type: jsonschema.Boolean},
+	},
+	Required:             []string{"intent", "priority", "needs_human"},
+	AdditionalProperties: false,
+}
+
+type classifier struct {
+	oa    *openai.Client
+	model string
+}
+
+// classify labels one ticket's text, tokenized or, with -compare, raw.
+func (c *classifier) classify(ctx context.Context, text string) (Labels, error) {
+	res, err := c.oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:       c.model,
+		Temperature: 0,
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type:       openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{Name: "ticket_labels", Schema: &schema, Strict: true},
+		},
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: text},
+		},
+	})
+	if err != nil {
+		return Labels{}, err
+	}
+	if len(res.Choices) == 0 {
+		return Labels{}, fmt.Errorf("empty response")
+	}
+	var l Labels
+	if err := json.Unmarshal([]byte(strings.TrimSpace(res.Choices[0].Message.Content)), &l); err != nil {
+		return Labels{}, fmt.Errorf("labels: %w", err)
+	}
+	return l, l.valid()
+}
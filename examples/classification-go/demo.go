@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+// rules are tried in order; the first match wins.
+var rules = []struct {
+	words  []string
+	labels Labels
+}{
+	{[]string{"login alert", "never placed", "wasn't me"}, Labels{"security", "urgent", true}},
+	{[]string{"cancel", "close my account"}, Labels{"cancellation", "normal", true}},
+	{[]string{"refund", "money back"}, Labels{"refund", "high", false}},
+	{[]string{"charged twice", "invoice"}, Labels{"billing", "high", false}},
+	{[]string{"package", "deliver"}, Labels{"shipping", "normal", false}},
+	{[]string{"email on my account", "log in"}, Labels{"account", "normal", false}},
+}
+
+// fakeModel answers chat completions with a keyword classifier. Like a
+// real model it routes on the words of a ticket, so it gives the same
+// labels for raw and tokenized text.
+func fakeModel(req openai.ChatCompletionRequest) string {
+	text := strings.ToLower(fakeopenai.Prompt(req))
+	labels := Labels{"account", "low", true}
+match:
+	for _, rule := range rules {
+		for _, word := range rule.words {
+			if strings.Contains(text, word) {
+				labels = rule.labels
+				break match
+			}
+		}
+	}
+	out, _ := json.Marshal(labels)
+	return string(out)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+)
+
+// The label sets. The response schema restricts the model to them, and
+// every label is checked against them again before it is stored.
+var (
+	intents    = []string{"billing", "refund", "shipping", "account", "security", "cancellation"}
+	priorities = []string{"low", "normal", "high", "urgent"}
+)
+
+// Labels is the model's structured output. It has no free-text field on
+// purpose: a "reason" would echo the ticket and put content in the store.
+type Labels struct {
+	Intent     string `json:"intent"`
+	Priority   string `json:"priority"`
+	NeedsHuman bool   `json:"needs_human"`
+}
+
+func oneOf(v string, set []string) bool {
+	for _, s := range set {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// valid reports whether l only holds values from the label sets.
+func (l Labels) valid() error {
+	if !oneOf(l.Intent, intents) {
+		return fmt.Errorf("intent %q is not one of %s", l.Intent, strings.Join(intents, ", "))
+	}
+	if !oneOf(l.Priority, priorities) {
+		return fmt.Errorf("priority %q is not one of %s", l.Priority, strings.Join(priorities, ", "))
+	}
+	return nil
+}
+
+// Record is one line of the label store: the ticket's ID and its labels.
+// Neither the ticket's text, nor its tokenized text, nor the mapping is
+// ever written.
+type Record struct {
+	ID           string    `json:"id"`
+	Labels                 // embedded, so the fields sit at the top level
+	Model        string    `json:"model"`
+	ClassifiedAt time.Time `json:"classified_at"`
+}
+
+// store writes records as JSON lines, then scans what it wrote: a label
+// store that holds PII is a bug, and this is where it would show.
+func store(ctx context.Context, bf bfclient.Client, path string, records []Record) error {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	for _, r := range records {
+		if err := r.valid(); err != nil {
+			return fmt.Errorf("%s: %w", r.ID, err)
+		}
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	res, err := bf.Detect(ctx, b.String())
+	if err != nil {
+		return fmt.Errorf("scan labels: %w", err)
+	}
+	if len(res.DetectedEntities) > 0 {
+		return fmt.Errorf("label store would hold PII (%s); nothing written", res.DetectedEntities[0].Type)
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// fakeDesktop is a desktop the demo and tests script: whatever they set is
+// on the clipboard and in front.
+type fakeDesktop struct {
+	clip, front string
+}
+
+func (f *fakeDesktop) readClipboard() (string, error)   { return f.clip, nil }
+func (f *fakeDesktop) writeClipboard(text string) error { f.clip = text; return nil }
+func (f *fakeDesktop) foreground() (string, error)      { return f.front, nil }
+
+// demo runs a support agent's session against a fake desktop and a
+// mapping in a temporary directory: two copies from the CRM into ChatGPT
+// and the answer back into an email.
+func demo(ctx context.Context, g *guard) error {
+	dir, err := os.MkdirTemp("", "clipboard-guard-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	d := &fakeDesktop{}
+	g.d, g.store = d, store{path: filepath.Join(dir, "mapping.json")}
+	log.SetOutput(os.Stdout)
+	log.SetFlags(0)
+	log.SetPrefix("  ")
+
+	steps := []struct {
+		say, front, copy string
+	}{
+		{say: "copy a case note in the CRM", front: "Google Chrome — Case 00412 | Salesforce",
+			copy: "Customer jane.doe@example.com (+1 415-555-0142) disputes a charge on card 4111 1111 1111 1111."},
+		{say: "switch to ChatGPT", front: "ChatGPT — Refund dispute help"},
+		{say: "copy a follow-up in the CRM", front: "Google Chrome — Case 00412 | Salesforce",
+			copy: "She wrote again from jane.doe@example.com; her manager is ravi.patel@example.com."},
+		{say: "switch to ChatGPT", front: "ChatGPT — Refund dispute help"},
+		{say: "copy the model's answer", front: "ChatGPT — Refund dispute help",
+			copy: "Dear customer, we've reversed the charge on <Credit Card Number_1>. A confirmation goes to <Email Address_1>, cc <Email Address_2>."},
+		{say: "switch to the mail client", front: "Mail — Re: Case 00412"},
+	}
+	for _, s := range steps {
+		fmt.Printf("\n▸ %s\n", s.say)
+		d.front = s.front
+		if s.copy != "" {
+			d.clip = s.copy
+		}
+		if err := g.check(ctx); err != nil {
+			return err
+		}
+		fmt.Printf("  clipboard: %s\n", d.clip)
+	}
+	data, err := os.ReadFile(g.store.path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nlocal mapping (%s):\n%s", filepath.Base(g.store.path), data)
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// desktop is what the guard needs from the machine it runs on: the
+// clipboard, and the window that would receive a paste.
+type desktop interface {
+	readClipboard() (string, error)
+	writeClipboard(text string) error
+	// foreground describes the focused window as "app — title", the title
+	// being what tells one browser tab from another.
+	foreground() (string, error)
+}
+
+// errNoForeground is returned when the platform offers no way to see the
+// focused window, as on Wayland.
+var errNoForeground = errors.New("can't tell the focused window on this desktop")
+
+// system drives the real desktop through the tools every platform ships or
+// packages, so the guard needs no cgo: pbcopy and osascript on macOS,
+// PowerShell on Windows, wl-clipboard, xclip or xsel and xdotool on Linux.
+type system struct {
+	paste, copy, front []string // commands; front is nil where unsupported
+}
+
+func newSystem() (*system, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return &system{
+			paste: []string{"pbpaste"},
+			copy:  []string{"pbcopy"},
+			front: []string{"osascript", "-e", macFront},
+		}, nil
+	case "windows":
+		ps := []string{"powershell", "-NoProfile", "-NonInteractive", "-Command"}
+		return &system{
+			paste: append(ps, utf8Out+"[Console]::Out.Write((Get-Clipboard -Raw))"), // Write: no newline added
+			copy:  append(ps, "[Console]::InputEncoding = [Text.Encoding]::UTF8; Set-Clipboard -Value ([Console]::In.ReadToEnd())"),
+			front: append(ps, utf8Out+winFront),
+		}, nil
+	}
+	s := &system{}
+	switch {
+	case os.Getenv("WAYLAND_DISPLAY") != "" && found("wl-paste"):
+		s.paste, s.copy = []string{"wl-paste", "--no-newline"}, []string{"wl-copy"}
+	case found("xclip"):
+		s.paste, s.copy = []string{"xclip", "-selection", "clipboard", "-o"}, []string{"xclip", "-selection", "clipboard", "-i"}
+	case found("xsel"):
+		s.paste, s.copy = []string{"xsel", "--clipboard", "--output"}, []string{"xsel", "--clipboard", "--input"}
+	default:
+		return nil, errors.New("no clipboard tool found: install wl-clipboard (Wayland), xclip or xsel")
+	}
+	// Wayland keeps the focused window from other clients; xdotool only
+	// sees X11 and XWayland windows
+	if os.Getenv("WAYLAND_DISPLAY") == "" && found("xdotool") {
+		s.front = []string{"sh", "-c", `printf '%s — %s' "$(xdotool getactivewindow getwindowclassname)" "$(xdotool getactivewindow getwindowname)"`}
+	}
+	return s, nil
+}
+
+func found(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+const (
+	// Window titles need the accessibility permission for the terminal
+	// running the guard; without it only the app name comes back
+	macFront = `tell application "System Events"
+	set p to first application process whose frontmost is true
+	set t to ""
+	try
+		set t to name of front window of p
+	end try
+	return (name of p) & " — " & t
+end tell`
+
+	utf8Out = "[Console]::OutputEncoding = [Text.Encoding]::UTF8; "
+
+	winFront = `Add-Type @'
+using System; using System.Text; using System.Runtime.InteropServices;
+public static class Fg {
+	[DllImport("user32.dll")] public static extern IntPtr GetForegroundWindow();
+	[DllImport("user32.dll", CharSet = CharSet.Unicode)] public static extern int GetWindowText(IntPtr h, StringBuilder s, int n);
+	[DllImport("user32.dll")] public static extern uint GetWindowThreadProcessId(IntPtr h, out uint pid);
+}
+'@
+$h = [Fg]::GetForegroundWindow(); $t = New-Object Text.StringBuilder 512; [void][Fg]::GetWindowText($h, $t, 512)
+$id = 0; [void][Fg]::GetWindowThreadProcessId($h, [ref]$id)
+(Get-Process -Id $id).ProcessName + " — " + $t`
+)
+
+func (s *system) readClipboard() (string, error) {
+	return capture(s.paste)
+}
+
+func (s *system) writeClipboard(text string) error {
+	return feed(s.copy, text)
+}
+
+func (s *system) foreground() (string, error) {
+	if s.front == nil {
+		return "", errNoForeground
+	}
+	out, err := capture(s.front)
+	return strings.TrimSpace(out), err
+}
+
+// capture runs argv and returns what it printed.
+func capture(argv []string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %v %s", argv[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// feed runs argv with input on stdin. Its output isn't connected: xclip,
+// xsel and wl-copy fork a child that keeps serving the clipboard after
+// they exit, and waiting on its pipes would hang.
+func feed(argv []string, input string) error {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = strings.NewReader(input)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v", argv[0], err)
+	}
+	return nil
+}
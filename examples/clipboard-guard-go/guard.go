@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// guard keeps the clipboard right for the focused window: tokenized while
+// an AI app would receive the paste, restored for everything else.
+type guard struct {
+	bf    bfclient.Client
+	d     desktop
+	store store
+	ai    []string // lower-case names of AI apps and sites, matched in window titles
+
+	// What the last check saw; the clipboard is only looked at again when
+	// its content or the kind of window in front changes
+	lastText string
+	lastAI   bool
+}
+
+// isAI reports whether the focused window belongs to an AI app.
+func (g *guard) isAI(front string) bool {
+	front = strings.ToLower(front)
+	for _, name := range g.ai {
+		if strings.Contains(front, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// check runs once per poll. When an AI app is in front and the clipboard
+// holds PII, the clipboard is swapped for its tokenized text; anywhere
+// else, tokens the guard issued are swapped back for their values. The
+// text is tokenized the moment the AI app comes to the front, whenever it
+// was copied, so the paste that follows is already safe.
+func (g *guard) check(ctx context.Context) error {
+	front, err := g.d.foreground()
+	if err != nil {
+		return err
+	}
+	text, err := g.d.readClipboard()
+	if err != nil {
+		return err
+	}
+	toAI := g.isAI(front)
+	if text == g.lastText && toAI == g.lastAI {
+		return nil
+	}
+
+	var out, done string
+	if toAI {
+		out, done, err = g.protect(ctx, text)
+	} else {
+		out, done, err = g.restore(text)
+	}
+	if err != nil {
+		return err // not recorded as seen, so the next poll tries again
+	}
+	if out != text {
+		if err := g.d.writeClipboard(out); err != nil {
+			return err
+		}
+		log.Printf("%s, for %s", done, front)
+	}
+	g.lastText, g.lastAI = out, toAI
+	return nil
+}
+
+// protect tokenizes text and records its mapping. It returns text as it
+// is when there is nothing to protect.
+func (g *guard) protect(ctx context.Context, text string) (out, done string, err error) {
+	if strings.TrimSpace(text) == "" {
+		return text, "", nil
+	}
+	res, err := g.bf.Tokenize(ctx, text)
+	if err != nil {
+		return "", "", fmt.Errorf("clipboard left as it is, PII not checked: %w", err)
+	}
+	if len(res.Mapping) == 0 {
+		return text, "", nil
+	}
+	if out, err = g.store.add(res.Text, res.Mapping); err != nil {
+		return "", "", err
+	}
+	types := map[string]bool{}
+	for _, e := range res.DetectedEntities {
+		types[e.Type] = true
+	}
+	return out, fmt.Sprintf("protected %d value%s (%s)", len(res.Mapping), plural(len(res.Mapping)), strings.Join(sortedKeys(types), ", ")), nil
+}
+
+// restore replaces the tokens in text the guard issued with their values.
+// Tokens it didn't issue, or has since forgotten, stay as they are.
+func (g *guard) restore(text string) (out, done string, err error) {
+	if !mapping.TokenPattern.MatchString(text) {
+		return text, "", nil
+	}
+	stored, err := g.store.load()
+	if err != nil {
+		return "", "", err
+	}
+	n := 0
+	for _, token := range mapping.TokenPattern.FindAllString(text, -1) {
+		if _, ok := stored[token]; ok {
+			n++
+		}
+	}
+	if n == 0 {
+		return text, "", nil
+	}
+	return mapping.Detokenize(text, stored), fmt.Sprintf("restored %d value%s", n, plural(n)), nil
+}
+
+// watch polls until ctx is done. Errors are logged once each, not every
+// poll: a clipboard holding an image, say, fails every read until the next
+// copy.
+func (g *guard) watch(ctx context.Context, interval time.Duration) error {
+	if _, err := g.d.foreground(); errors.Is(err, errNoForeground) {
+		return fmt.Errorf("%w: bind -protect and -restore to hotkeys instead", err)
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	var lastErr string
+	for {
+		err := g.check(ctx)
+		switch {
+		case err == nil:
+			lastErr = ""
+		case err.Error() != lastErr:
+			lastErr = err.Error()
+			log.Print(err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick.C:
+		}
+	}
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
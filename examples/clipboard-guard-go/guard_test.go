@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+var local = blindfold.New(blindfold.WithMode("local"))
+
+func newTestGuard(t *testing.T) (*guard, *fakeDesktop) {
+	d := &fakeDesktop{}
+	return &guard{
+		bf:    local,
+		d:     d,
+		store: store{path: filepath.Join(t.TempDir(), "guard", "mapping.json")},
+		ai:    []string{"chatgpt", "claude"},
+	}, d
+}
+
+// step sets the desktop, runs one check and returns the clipboard.
+func step(t *testing.T, g *guard, d *fakeDesktop, front, clip string) string {
+	t.Helper()
+	d.front = front
+	if clip != "" {
+		d.clip = clip
+	}
+	if err := g.check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return d.clip
+}
+
+func TestGuardProtectsForAIAndRestoresElsewhere(t *testing.T) {
+	g, d := newTestGuard(t)
+	note := "Refund jane@example.com, card 4111 1111 1111 1111"
+
+	if got := step(t, g, d, "Google Chrome — Salesforce", note); got != note {
+		t.Errorf("copied in the CRM: clipboard = %q, want it untouched", got)
+	}
+	want := "Refund <Email Address_1>, card <Credit Card Number_1>"
+	if got := step(t, g, d, "ChatGPT — New chat", ""); got != want {
+		t.Errorf("ChatGPT in front: clipboard = %q, want %q", got, want)
+	}
+	// Pasting around inside the AI app leaves the tokens alone
+	if got := step(t, g, d, "Claude — Refunds", ""); got != want {
+		t.Errorf("Claude in front: clipboard = %q", got)
+	}
+	if got := step(t, g, d, "Mail — Re: refund", ""); got != note {
+		t.Errorf("back in Mail: clipboard = %q, want %q", got, note)
+	}
+	if info, err := os.Stat(g.store.path); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Errorf("mapping file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestGuardReusesTokensAcrossCopies(t *testing.T) {
+	g, d := newTestGuard(t)
+	step(t, g, d, "ChatGPT", "From jane@example.com")
+	second := "Manager omar@example.com, customer jane@example.com"
+	got := step(t, g, d, "ChatGPT", second)
+	if !strings.HasSuffix(got, ", customer <Email Address_1>") {
+		t.Errorf("second copy = %q, want jane@example.com as <Email Address_1> again", got)
+	}
+	m, err := g.store.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 2 || m["<Email Address_1>"] != "jane@example.com" {
+		t.Errorf("mapping = %v", m)
+	}
+	if back := step(t, g, d, "Mail", ""); back != second {
+		t.Errorf("restored = %q, want %q", back, second)
+	}
+}
+
+func TestGuardLeavesUnknownTokens(t *testing.T) {
+	g, d := newTestGuard(t)
+	step(t, g, d, "ChatGPT", "mail jane@example.com")
+	reply := "Wrote to <Email Address_1> and <Person_4>"
+	if got, want := step(t, g, d, "Mail", reply), "Wrote to jane@example.com and <Person_4>"; got != want {
+		t.Errorf("clipboard = %q, want %q", got, want)
+	}
+	if err := g.store.forget(); err != nil {
+		t.Fatal(err)
+	}
+	if got := step(t, g, d, "Notes", reply); got != reply {
+		t.Errorf("after -forget: clipboard = %q, want it untouched", got)
+	}
+}
+
+type noFront struct{ fakeDesktop }
+
+func (noFront) foreground() (string, error) { return "", errNoForeground }
+
+func TestWatchNeedsForeground(t *testing.T) {
+	g, _ := newTestGuard(t)
+	g.d = &noFront{}
+	if err := g.watch(context.Background(), 0); !errors.Is(err, errNoForeground) {
+		t.Errorf("watch = %v, want errNoForeground", err)
+	}
+}
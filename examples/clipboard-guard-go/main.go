@@ -0,0 +1,128 @@
+// Clipboard guard + Blindfold: Keep customer data out of AI chats.
+//
+// Watches the clipboard and the focused window. When an AI app comes to
+// the front (ChatGPT, Claude, Gemini, Copilot… matched by window title)
+// with PII on the clipboard, the clipboard is swapped for its tokenized
+// text before the paste lands. Copy the model's answer and switch to any
+// other app, and the tokens are swapped back for the real values from a
+// mapping kept on this machine only. Where the focused window can't be
+// seen (Wayland), or for explicit control, bind -protect and -restore to
+// hotkeys instead.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+// defaultAI names the AI apps and sites recognized out of the box, as they
+// appear in app names and window titles.
+const defaultAI = "ChatGPT,OpenAI,Claude,Gemini,Copilot,Perplexity,Mistral,DeepSeek,Poe"
+
+func main() {
+	_ = godotenv.Load()
+	policiesFile := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	policy := flag.String("policy", "", "policy to apply, by name (default: the file's default)")
+	mappingFile := flag.String("mapping", "", "local mapping file (default: clipboard-guard/mapping.json in the user config directory)")
+	aiApps := flag.String("ai", defaultAI, "comma-separated AI apps and sites, matched case-insensitively in the focused window's app name and title")
+	interval := flag.Duration("interval", 500*time.Millisecond, "how often to check the clipboard and the focused window")
+	protect := flag.Bool("protect", false, "tokenize the clipboard once, whatever is in front, and exit (bind to a hotkey)")
+	restore := flag.Bool("restore", false, "restore the tokens on the clipboard once and exit (bind to a hotkey)")
+	forget := flag.Bool("forget", false, "delete the local mapping and exit; tokens already pasted can't be restored after")
+	runDemo := flag.Bool("demo", false, "run a scripted copy-and-paste session against a simulated desktop and exit")
+	flag.Parse()
+	log.SetFlags(log.Ltime)
+
+	if *mappingFile == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			log.Fatal(err)
+		}
+		*mappingFile = filepath.Join(dir, "clipboard-guard", "mapping.json")
+	}
+	st := store{path: *mappingFile}
+	if *forget {
+		if err := st.forget(); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("forgot", st.path)
+		return
+	}
+
+	pol, err := policyconf.Resolve(*policiesFile, *policy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	g := &guard{bf: pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...)), store: st}
+	for _, name := range strings.Split(*aiApps, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			g.ai = append(g.ai, name)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *runDemo {
+		if err := demo(ctx, g); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	sys, err := newSystem()
+	if err != nil {
+		log.Fatal(err)
+	}
+	g.d = sys
+	switch {
+	case *protect:
+		err = once(ctx, g, g.protect)
+	case *restore:
+		err = once(ctx, g, func(_ context.Context, text string) (string, string, error) { return g.restore(text) })
+	default:
+		log.Printf("guarding the clipboard for %s; mapping in %s", strings.Join(g.ai, ", "), st.path)
+		err = g.watch(ctx, *interval)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// once applies fn to the clipboard: the hotkey commands.
+func once(ctx context.Context, g *guard, fn func(context.Context, string) (string, string, error)) error {
+	text, err := g.d.readClipboard()
+	if err != nil {
+		return err
+	}
+	out, done, err := fn(ctx, text)
+	if err != nil {
+		return err
+	}
+	if out == text {
+		log.Print("nothing to change on the clipboard")
+		return nil
+	}
+	if err := g.d.writeClipboard(out); err != nil {
+		return err
+	}
+	log.Print(done)
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// store is the local mapping: every token the guard has put on the
+// clipboard and the value it stands for. It lives in a JSON file only the
+// user can read and is read and written whole on every change, so
+// -protect and -restore run from a hotkey share it with a running
+// watcher.
+type store struct {
+	path string
+}
+
+func (s store) load() (map[string]string, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]string{}
+	return m, json.Unmarshal(data, &m)
+}
+
+// add merges the mapping of a Tokenize call into the store and returns its
+// text rewritten to the stored tokens: a value copied before keeps the
+// token it had, so a conversation with the model sees one placeholder per
+// customer, however many times their details are pasted.
+func (s store) add(text string, m map[string]string) (string, error) {
+	stored, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	merged := mapping.Merge(stored, m)
+	text = merged.Rewrite(1, text)
+	if len(merged.Mapping) == len(stored) {
+		return text, nil
+	}
+	var data bytes.Buffer
+	enc := json.NewEncoder(&data)
+	enc.SetEscapeHTML(false) // tokens are <Type_N>
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(merged.Mapping); err != nil {
+		return "", err
+	}
+	// Written next to the store and renamed over it, so a reader never
+	// sees half a file. CreateTemp makes it owner-only (0600)
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".mapping-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data.Bytes()); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return text, os.Rename(tmp.Name(), s.path)
+}
+
+func (s store) forget() error {
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
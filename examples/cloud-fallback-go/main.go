@@ -0,0 +1,118 @@
+// Circuit breaker + Blindfold: Fall back to local mode when cloud detection fails.
+//
+// Cloud-mode tokenization goes through a circuit breaker. When the Blindfold
+// API keeps failing, the breaker opens and requests are tokenized locally
+// with the built-in regex patterns instead, so users still get answers —
+// with a logged downgrade — rather than errors. After a cooldown one probe
+// request checks whether the cloud is back.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	bfotel "github.com/blindfold-dev/blindfold-cookbook/pkg/otel"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/resilience"
+)
+
+func newProtectedClient(simulateOutage bool) *resilience.FallbackClient {
+	// Fail fast: the breaker, not the SDK, decides what happens on errors
+	opts := []blindfold.Option{blindfold.WithMaxRetries(0), blindfold.WithTimeout(2 * time.Second)}
+	if simulateOutage {
+		// Point cloud mode at an address nothing listens on
+		opts = append(opts, blindfold.WithBaseURL("http://127.0.0.1:9"))
+		if os.Getenv("BLINDFOLD_API_KEY") == "" {
+			opts = append(opts, blindfold.WithAPIKey("simulated-outage"))
+		}
+	}
+
+	bf := resilience.NewLocalFallback(bfclient.FromEnv(opts...), 3, 10*time.Second)
+	bf.Breaker.OnStateChange = func(from, to resilience.State) {
+		log.Printf("circuit %s -> %s", from, to)
+	}
+	bf.OnFallback = func(op string, err error) {
+		log.Printf("DOWNGRADE: %s served by local mode (regex only, no names/addresses): %v", op, err)
+	}
+	return bf
+}
+
+func protectedChat(ctx context.Context, bf *bfotel.Client, oa bfclient.ChatCompleter, userMessage, policy, model string) (string, error) {
+	ctx, span := bfotel.Start(ctx, "protected_chat", bfotel.Policy.String(policy))
+	defer span.End()
+
+	// 1. Tokenize — cloud mode, or local mode while the circuit is open
+	tokenized, err := bf.Tokenize(ctx, userMessage, blindfold.WithCallPolicy(policy))
+	if err != nil {
+		bfotel.RecordError(span, err)
+		return "", fmt.Errorf("tokenize: %w", err)
+	}
+	fmt.Printf("Tokenized: %s\n", tokenized.Text)
+
+	// 2. Send tokenized text to OpenAI
+	completion, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "You are a helpful assistant."},
+			{Role: openai.ChatMessageRoleUser, Content: tokenized.Text},
+		},
+	})
+	if err != nil {
+		bfotel.RecordError(span, err)
+		return "", fmt.Errorf("openai: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", errors.New("openai: empty response")
+	}
+
+	// 3. Detokenize — identical in both modes
+	return bf.DetokenizeContext(ctx, completion.Choices[0].Message.Content, tokenized.Mapping).Text, nil
+}
+
+func main() {
+	_ = godotenv.Load()
+	simulateOutage := flag.Bool("simulate-outage", false, "send cloud-mode calls to an unreachable endpoint")
+	flag.Parse()
+
+	// Tracing is off unless OTEL_* variables are set (see examples/otel-tracing-go)
+	shutdown, err := bfotel.Setup(context.Background(), "cloud-fallback-go")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdown(context.Background())
+
+	fallback := newProtectedClient(*simulateOutage)
+	bf := bfotel.Wrap(fallback)
+	oa := bfotel.WrapChat(openai.NewClient(os.Getenv("OPENAI_API_KEY")))
+
+	messages := []string{
+		"Can you draft a reply to jane.doe@example.com about her late delivery?",
+		"Call Tom Walker back on +1 212-555-0187 regarding the refund.",
+		"My card 4111 1111 1111 1111 was charged twice, email me at sam@example.org.",
+		"Update the shipping address for maria.lopez@example.com.",
+		"Summarize the complaint from Priya Patel (priya.patel@example.net).",
+	}
+	for _, msg := range messages {
+		fmt.Printf("\nUser: %s\n", msg)
+		response, err := protectedChat(context.Background(), bf, oa, msg, "strict", "gpt-4o-mini")
+		if err != nil {
+			log.Printf("request failed: %v", err)
+			continue
+		}
+		fmt.Printf("Assistant: %s\n", response)
+	}
+	fmt.Printf("\nCircuit state at exit: %s\n", fallback.Breaker.State())
+}
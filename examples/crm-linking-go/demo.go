@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+var (
+	demoToken = map[string]*regexp.Regexp{
+		"person": regexp.MustCompile(`<Person_\d+>`),
+		"email":  regexp.MustCompile(`<Email Address_\d+>`),
+		"phone":  regexp.MustCompile(`<Phone Number_\d+>`),
+	}
+	demoPlan      = regexp.MustCompile(`, (\w+) plan, ([^,]+),`)
+	demoLastOrder = regexp.MustCompile(`last order (\d+), ([^\n]+)`)
+	demoTickets   = regexp.MustCompile(`(\d+) open ticket`)
+)
+
+// fakeModel answers chat completions with replies scripted by topic.
+// Like a real model it uses the account context when there is one, and
+// only tokens from its input.
+func fakeModel(req openai.ChatCompletionRequest) string {
+	_, account, _ := strings.Cut(req.Messages[0].Content, "Account context:\n")
+	in := fakeopenai.Prompt(req)
+	tok := func(kind string) string { return demoToken[kind].FindString(in) }
+	plan := demoPlan.FindStringSubmatch(account)
+
+	var out string
+	switch {
+	case strings.Contains(in, "order") && demoLastOrder.MatchString(account):
+		o := demoLastOrder.FindStringSubmatch(account)
+		out = fmt.Sprintf("Hi %s, your order %s was %s, so it should be with you within two business days. "+
+			"I've added this to your open ticket, and we'll email %s if it hasn't arrived by Monday.", tok("person"), o[1], o[2], tok("email"))
+	case strings.Contains(in, "invoice") && plan != nil:
+		out = fmt.Sprintf("Thanks, I've flagged the amount on your last %s plan invoice for our billing team. "+
+			"They'll call you back on %s within one business day.", plan[1], tok("phone"))
+	case strings.Contains(in, "plan") && plan != nil:
+		t := demoTickets.FindStringSubmatch(account)
+		out = fmt.Sprintf("Happy to help: your account is on the %s plan (%s), and moving up keeps everything you have today. "+
+			"I'll send the options to %s, and your %s open ticket stays with the same agent.", plan[1], plan[2], tok("email"), t[1])
+	case strings.Contains(in, "password"):
+		out = fmt.Sprintf("Welcome! Use \"Forgot password\" on the sign-in page, and we'll send a reset link to %s. It expires in 30 minutes.", tok("email"))
+	default:
+		out = "Thanks for reaching out; an agent will follow up shortly."
+	}
+	return out
+}
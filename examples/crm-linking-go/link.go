@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// Contact is a CRM record. Name, Email and Phone identify the person; the
+// other fields describe the account and are safe to put in a prompt.
+type Contact struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	Phone         string `json:"phone"`
+	Plan          string `json:"plan"`
+	Status        string `json:"status"`
+	CustomerSince string `json:"customer_since"`
+	OpenTickets   int    `json:"open_tickets"`
+	LastOrder     string `json:"last_order,omitempty"`
+}
+
+// Context describes the account without identifying the person, for a
+// prompt.
+func (c *Contact) Context() string {
+	s := fmt.Sprintf("contact %s, %s plan, %s, customer since %s, %d open ticket(s)", c.ID, c.Plan, c.Status, c.CustomerSince, c.OpenTickets)
+	if c.LastOrder != "" {
+		s += ", last order " + c.LastOrder
+	}
+	return s
+}
+
+// CRM looks contacts up by the values Blindfold detects.
+type CRM struct {
+	byID    map[string]*Contact
+	byEmail map[string]*Contact
+	byPhone map[string]*Contact
+	byName  map[string][]*Contact
+}
+
+func loadCRM(path string) (*CRM, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var contacts []*Contact
+	if err := json.Unmarshal(data, &contacts); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	crm := &CRM{byID: make(map[string]*Contact), byEmail: make(map[string]*Contact), byPhone: make(map[string]*Contact), byName: make(map[string][]*Contact)}
+	for _, c := range contacts {
+		crm.byID[c.ID] = c
+		if c.Email != "" {
+			crm.byEmail[strings.ToLower(c.Email)] = c
+		}
+		if p := phoneKey(c.Phone); p != "" {
+			crm.byPhone[p] = c
+		}
+		n := nameKey(c.Name)
+		crm.byName[n] = append(crm.byName[n], c)
+	}
+	return crm, nil
+}
+
+// phoneKey keeps the last ten digits, so "+1 415 555 0142" and
+// "415-555-0142" are one number.
+func phoneKey(s string) string {
+	var digits []rune
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) > 10 {
+		digits = digits[len(digits)-10:]
+	}
+	return string(digits)
+}
+
+func nameKey(s string) string { return strings.Join(strings.Fields(strings.ToLower(s)), " ") }
+
+// How a token was linked, strongest first.
+const (
+	ByEmail = "email"
+	ByPhone = "phone"
+	ByName  = "name"            // the only contact with this name
+	ByBoth  = "name, confirmed" // the name of a contact linked by email or phone in the same text
+)
+
+// Link ties a token to a CRM contact.
+type Link struct {
+	Token   string `json:"token"`
+	Contact string `json:"contact"`
+	By      string `json:"by"`
+}
+
+// Linked is a mapping enriched with CRM links: each token still has its
+// value, and linked tokens also have a contact.
+type Linked struct {
+	Mapping map[string]string
+	Links   map[string]Link // by token
+	crm     *CRM
+}
+
+// link looks every token of m up in the CRM. Emails and phone numbers are
+// exact keys. A name is linked only if it belongs to a contact already
+// linked in the same text, or to exactly one contact: names are not
+// unique, and a wrong link would put someone else's account in the
+// prompt.
+func (crm *CRM) link(m map[string]string) *Linked {
+	l := &Linked{Mapping: m, Links: make(map[string]Link), crm: crm}
+	tokens := make([]string, 0, len(m))
+	for t := range m {
+		tokens = append(tokens, t)
+	}
+	sort.Strings(tokens)
+	found := make(map[string]bool) // contacts linked by email or phone
+	var names []string
+	for _, token := range tokens {
+		typ, _, _ := mapping.ParseToken(token)
+		var c *Contact
+		var by string
+		switch typ {
+		case "Email Address":
+			c, by = crm.byEmail[strings.ToLower(m[token])], ByEmail
+		case "Phone Number":
+			c, by = crm.byPhone[phoneKey(m[token])], ByPhone
+		case "Person":
+			names = append(names, token)
+		}
+		if c != nil {
+			l.Links[token] = Link{Token: token, Contact: c.ID, By: by}
+			found[c.ID] = true
+		}
+	}
+	for _, token := range names {
+		candidates := crm.byName[nameKey(m[token])]
+		for _, c := range candidates {
+			if found[c.ID] {
+				l.Links[token] = Link{Token: token, Contact: c.ID, By: ByBoth}
+			}
+		}
+		if _, ok := l.Links[token]; !ok && len(candidates) == 1 {
+			l.Links[token] = Link{Token: token, Contact: candidates[0].ID, By: ByName}
+		}
+	}
+	return l
+}
+
+// Context is the customer context for a prompt: one line per linked
+// contact, naming the tokens that stand for it and describing the
+// account, with no name, email or phone number in it.
+func (l *Linked) Context() string {
+	byContact := make(map[string][]string)
+	var ids []string
+	for _, link := range l.Links {
+		if byContact[link.Contact] == nil {
+			ids = append(ids, link.Contact)
+		}
+		byContact[link.Contact] = append(byContact[link.Contact], link.Token)
+	}
+	sort.Strings(ids)
+	var lines []string
+	for _, id := range ids {
+		tokens := byContact[id]
+		sort.Strings(tokens)
+		lines = append(lines, fmt.Sprintf("- %s: %s", strings.Join(tokens, ", "), l.crm.byID[id].Context()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Resolution modes for a reply's tokens.
+const (
+	ResolveText   = "text"   // the original values: Detokenize
+	ResolveID     = "id"     // linked tokens become [contact C-1042], others their values
+	ResolveRecord = "record" // as id, plus the linked CRM records
+)
+
+// Resolved is a reply resolved to CRM records rather than raw text.
+type Resolved struct {
+	Text     string              `json:"text"`
+	Contacts map[string]*Contact `json:"contacts,omitempty"`
+}
+
+// Resolve restores the tokens of a reply. In id and record mode a linked
+// token resolves to its contact instead of its value, so a ticket system
+// gets a reference it can join on and the reply text holds no contact
+// details for a linked customer.
+func (l *Linked) Resolve(text, mode string) (*Resolved, error) {
+	switch mode {
+	case ResolveText:
+		return &Resolved{Text: mapping.Detokenize(text, l.Mapping)}, nil
+	case ResolveID, ResolveRecord:
+	default:
+		return nil, fmt.Errorf("unknown resolve mode %q (want text, id or record)", mode)
+	}
+	r := &Resolved{}
+	if mode == ResolveRecord {
+		r.Contacts = make(map[string]*Contact)
+	}
+	r.Text = mapping.ReplaceTokens(text, func(token string) string {
+		if link, ok := l.Links[token]; ok {
+			if r.Contacts != nil {
+				r.Contacts[link.Contact] = l.crm.byID[link.Contact]
+			}
+			return "[contact " + link.Contact + "]"
+		}
+		if v, ok := l.Mapping[token]; ok {
+			return v
+		}
+		return token
+	})
+	return r, nil
+}
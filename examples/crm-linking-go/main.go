@@ -0,0 +1,168 @@
+// CRM entity linking + Blindfold: Link tokens to CRM records, so the LLM
+// gets customer context without customer identity.
+//
+// A tokenized message tells the model that <Email Address_1> wrote in,
+// but nothing about their account. Looking the mapping's values up in the
+// CRM (email → contact ID) enriches it with links, and a linked token
+// can come with the account facts that are safe to share: plan, status,
+// open tickets, last order. The model sees "<Email Address_1>: contact
+// C-1042, Pro plan, 2 open tickets" and never the name, email or phone
+// number. On the way back, a linked token can resolve to the contact
+// record instead of its raw value, for systems that want a reference to
+// join on rather than an email address.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+const systemPrompt = "You are a customer support assistant. Reply to the customer in two or three sentences, " +
+	"using the account context when it helps. Values like <Email Address_1> are placeholders for customer data: " +
+	"copy them exactly as written."
+
+func ask(ctx context.Context, oa *openai.Client, model, accountContext, message string) (string, error) {
+	system := systemPrompt
+	if accountContext != "" {
+		system += "\n\nAccount context:\n" + accountContext
+	}
+	res, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: system},
+			{Role: openai.ChatMessageRoleUser, Content: message},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(res.Choices) == 0 {
+		return "", fmt.Errorf("empty response")
+	}
+	return strings.TrimSpace(res.Choices[0].Message.Content), nil
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, sc.Err()
+}
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply, by name (default: the file's default)")
+	crmPath := flag.String("crm", "crm.json", "CRM contacts export (JSON array)")
+	messages := flag.String("messages", "messages.txt", "incoming messages, one per line")
+	resolve := flag.String("resolve", ResolveText, "how reply tokens are restored: text, id or record")
+	model := flag.String("model", openai.GPT4oMini, "OpenAI model")
+	demo := flag.Bool("demo", false, "use a scripted in-process model instead of OpenAI (no OpenAI key needed)")
+	flag.Parse()
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+
+	cfg := openai.DefaultConfig(os.Getenv("OPENAI_API_KEY"))
+	if *demo {
+		srv := fakeopenai.Server(fakeModel)
+		defer srv.Close()
+		cfg = fakeopenai.Config(srv)
+	} else if os.Getenv("OPENAI_API_KEY") == "" {
+		log.Fatal("OPENAI_API_KEY is required (or run with -demo)")
+	}
+	oa := openai.NewClientWithConfig(cfg)
+
+	crm, err := loadCRM(*crmPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	lines, err := readLines(*messages)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i, line := range lines {
+		fmt.Printf("── Message %d: %s\n", i+1, line)
+		res, err := bf.Tokenize(ctx, line)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tokenized, m := mapping.Fold(res.Text, res.Mapping)
+		fmt.Printf("   tokenized: %s\n", tokenized)
+		linked := crm.link(m)
+		tokens := make([]string, 0, len(m))
+		for t := range m {
+			tokens = append(tokens, t)
+		}
+		sort.Strings(tokens)
+		for _, t := range tokens {
+			if link, ok := linked.Links[t]; ok {
+				fmt.Printf("   link:      %s → %s (by %s)\n", t, link.Contact, link.By)
+			} else {
+				fmt.Printf("   link:      %s → not in the CRM\n", t)
+			}
+		}
+		accountContext := linked.Context()
+		if accountContext != "" {
+			fmt.Printf("   context:   %s\n", strings.ReplaceAll(accountContext, "\n", "\n              "))
+		}
+
+		answer, err := ask(ctx, oa, *model, accountContext, tokenized)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("   answer:    %s\n", answer)
+		if bad := mapping.Unresolved(answer, m); len(bad) > 0 {
+			log.Fatalf("answer has tokens the message never had: %s", strings.Join(bad, ", "))
+		}
+		r, err := linked.Resolve(answer, *resolve)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *resolve == ResolveRecord {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("   ", "  ")
+			fmt.Print("   → ")
+			if err := enc.Encode(r); err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			fmt.Printf("   → %s\n", r.Text)
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,86 @@
+// Custom patterns + Blindfold: Protect organization-specific identifiers.
+//
+// Built-in detectors know emails, phone numbers and cards, but not your
+// employee IDs, order numbers or internal hostnames. This example registers
+// regex patterns for them next to the built-ins, so they are tokenized with
+// their own entity types (<Employee ID_1>, <Order Number_1>,
+// <Internal Hostname_1>) and restored like any other token.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/joho/godotenv"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+const sample = "Ticket from EMP-204518: order ORD-00482137 failed on billing-db-02.corp.acme.com. " +
+	"Customer jane.doe@example.com was charged twice; see ORD-00482137 in the logs of api.acme.internal."
+
+// acmePolicy keeps the built-in "basic" policy and adds three internal
+// identifier formats. The same patterns can live in a policy file — see
+// ../policyconf-go.
+func acmePolicy() (*policyconf.Policy, error) {
+	pol := &policyconf.Policy{
+		Name: "acme",
+		Base: "basic",
+		Patterns: []policyconf.Pattern{
+			{Entity: "Employee ID", Regex: `\bEMP-\d{6}\b`},
+			{Entity: "Order Number", Regex: `\bORD-\d{8}\b`},
+			{
+				Entity: "Internal Hostname",
+				Regex:  `\b[a-z0-9][a-z0-9-]*(?:\.[a-z0-9-]+)*\.(?:corp\.acme\.com|acme\.internal)\b`,
+				Score:  0.8,
+			},
+		},
+	}
+	if err := pol.Compile(); err != nil {
+		return nil, err
+	}
+	return pol, nil
+}
+
+func newClient(pol *policyconf.Policy) bfclient.Client {
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	return pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+}
+
+func main() {
+	_ = godotenv.Load()
+	ctx := context.Background()
+
+	pol, err := acmePolicy()
+	if err != nil {
+		log.Fatal(err)
+	}
+	bf := newClient(pol)
+
+	tokenized, err := bf.Tokenize(ctx, sample)
+	if err != nil {
+		log.Fatalf("tokenize: %v", err)
+	}
+	fmt.Printf("Input:     %s\n", sample)
+	fmt.Printf("Tokenized: %s\n\n", tokenized.Text)
+
+	tokens := make([]string, 0, len(tokenized.Mapping))
+	for token := range tokenized.Mapping {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	fmt.Println("Mapping:")
+	for _, token := range tokens {
+		fmt.Printf("  %-24s %s\n", token, tokenized.Mapping[token])
+	}
+
+	restored := bf.Detokenize(tokenized.Text, tokenized.Mapping)
+	fmt.Printf("\nRestored:  %s\n", restored.Text)
+}
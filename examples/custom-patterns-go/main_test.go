@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+func tokenize(t *testing.T, text string) *blindfold.TokenizeResponse {
+	t.Helper()
+	pol, err := acmePolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf := pol.Wrap(blindfold.New(append(pol.ClientOptions(), blindfold.WithMode("local"))...))
+	res, err := bf.Tokenize(context.Background(), text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestCustomEntitiesInMapping(t *testing.T) {
+	res := tokenize(t, sample)
+
+	want := map[string]string{
+		"<Employee ID_1>":       "EMP-204518",
+		"<Order Number_1>":      "ORD-00482137",
+		"<Internal Hostname_1>": "billing-db-02.corp.acme.com",
+		"<Internal Hostname_2>": "api.acme.internal",
+		"<Email Address_1>":     "jane.doe@example.com",
+	}
+	for token, value := range want {
+		if got, ok := res.Mapping[token]; !ok || got != value {
+			t.Errorf("mapping[%q] = %q, %v; want %q", token, got, ok, value)
+		}
+	}
+	if len(res.Mapping) != len(want) {
+		t.Errorf("mapping has %d entries, want %d: %v", len(res.Mapping), len(want), res.Mapping)
+	}
+}
+
+func TestCustomEntityTypes(t *testing.T) {
+	res := tokenize(t, sample)
+
+	counts := make(map[string]int)
+	for _, e := range res.DetectedEntities {
+		counts[e.Type]++
+	}
+	// The order number appears twice; both occurrences are entities but
+	// share one token.
+	for typ, n := range map[string]int{"Employee ID": 1, "Order Number": 2, "Internal Hostname": 2, "Email Address": 1} {
+		if counts[typ] != n {
+			t.Errorf("%d %s entities, want %d (detected %v)", counts[typ], typ, n, counts)
+		}
+	}
+}
+
+func TestCustomTokensRoundTrip(t *testing.T) {
+	res := tokenize(t, sample)
+
+	for _, value := range []string{"EMP-204518", "ORD-00482137", "corp.acme.com", "acme.internal"} {
+		if strings.Contains(res.Text, value) {
+			t.Errorf("tokenized text still contains %q: %s", value, res.Text)
+		}
+	}
+	pol, _ := acmePolicy()
+	bf := pol.Wrap(blindfold.New(blindfold.WithMode("local")))
+	if got := bf.Detokenize(res.Text, res.Mapping).Text; got != sample {
+		t.Errorf("round trip:\n got %q\nwant %q", got, sample)
+	}
+}
+
+func TestLookalikesNotMatched(t *testing.T) {
+	text := "EMP-12345 is too short, ORD-123 too, and www.acme.com is public."
+	res := tokenize(t, text)
+	if len(res.Mapping) != 0 {
+		t.Errorf("unexpected tokens %v in %q", res.Mapping, res.Text)
+	}
+}
+
+func TestPatternValidation(t *testing.T) {
+	for _, p := range []policyconf.Pattern{
+		{Entity: "Badge", Regex: `[`},
+		{Entity: "Badge", Regex: `\d*`},
+		{Entity: "Badge_ID", Regex: `\bB\d{4}\b`},
+	} {
+		pol := &policyconf.Policy{Base: "basic", Patterns: []policyconf.Pattern{p}}
+		if err := pol.Compile(); err == nil {
+			t.Errorf("Compile accepted %+v", p)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// Doc is one tokenized document of the corpus.
+type Doc struct {
+	ID      string
+	Text    string            // tokenized
+	Mapping map[string]string // this document's own numbering
+	vec     []float32
+	raw     []float32 // embedding of the raw text, with -compare
+}
+
+// shape replaces every token with its bare type, "<Person>". Token
+// numbers only say which person within one document, since each document
+// is tokenized on its own, so for similarity across documents they are
+// noise.
+func shape(text string) string {
+	return mapping.ReplaceTokens(text, func(token string) string {
+		typ, _, _ := mapping.ParseToken(token)
+		return "<" + typ + ">"
+	})
+}
+
+// Kinds of near-duplicate pair. The text is alike in all three; the
+// values behind the tokens decide which it is.
+const (
+	Duplicate = "duplicate" // same values: one document twice, merged
+	Related   = "related"   // some values shared: reported, not merged
+	Template  = "template"  // no values shared: same wording about someone else
+)
+
+// Pair is two documents whose tokenized text is alike.
+type Pair struct {
+	A, B       int
+	Similarity float64
+	Raw        float64 // similarity of the raw texts, with -compare
+	Shared     int     // values both documents hold
+	Smaller    int     // values in the document with fewer
+	Kind       string
+}
+
+// values returns the set of a document's values, by type.
+func values(m map[string]string) map[string]bool {
+	set := make(map[string]bool, len(m))
+	for token, v := range m {
+		typ, _, _ := mapping.ParseToken(token)
+		set[typ+"\x00"+strings.ToLower(strings.TrimSpace(v))] = true
+	}
+	return set
+}
+
+// classify says what a similar pair is from the values behind its tokens:
+// a duplicate holds at least half the values of the smaller document.
+// The tokens alone can't tell; <Person_1> is the first person in every
+// document.
+func classify(p *Pair, a, b *Doc) {
+	va, vb := values(a.Mapping), values(b.Mapping)
+	p.Smaller = len(va)
+	if len(vb) < p.Smaller {
+		p.Smaller = len(vb)
+	}
+	for v := range va {
+		if vb[v] {
+			p.Shared++
+		}
+	}
+	switch {
+	case p.Shared > 0 && 2*p.Shared >= p.Smaller:
+		p.Kind = Duplicate
+	case p.Shared > 0:
+		p.Kind = Related
+	default:
+		p.Kind = Template
+	}
+}
+
+// pairs compares every two documents and returns those at or above the
+// threshold, most similar first.
+func pairs(docs []*Doc, threshold float64) []*Pair {
+	var out []*Pair
+	for i := range docs {
+		for j := i + 1; j < len(docs); j++ {
+			sim := cosine(docs[i].vec, docs[j].vec)
+			if sim < threshold {
+				continue
+			}
+			p := &Pair{A: i, B: j, Similarity: sim}
+			if docs[i].raw != nil {
+				p.Raw = cosine(docs[i].raw, docs[j].raw)
+			}
+			classify(p, docs[i], docs[j])
+			out = append(out, p)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Similarity > out[j].Similarity })
+	return out
+}
+
+// Cluster is a kept document and its duplicates, with one mapping for
+// all of them.
+type Cluster struct {
+	Keep    *Doc
+	Dups    []*Doc
+	Merged  *mapping.Merged // inputs: Keep, then Dups in order
+	Added   []string        // tokens whose values only duplicates had
+	Renamed int             // duplicate tokens renumbered into Keep's numbering
+}
+
+// clusters groups documents joined by duplicate pairs. The first
+// document of a group, in corpus order, is kept; the mappings are merged
+// with its mapping first, so its tokens never change and each
+// duplicate's tokens are renamed to match it.
+func clusters(docs []*Doc, ps []*Pair) []*Cluster {
+	parent := make([]int, len(docs))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for _, p := range ps {
+		if p.Kind != Duplicate {
+			continue
+		}
+		a, b := find(p.A), find(p.B)
+		if a > b {
+			a, b = b, a
+		}
+		parent[b] = a
+	}
+
+	groups := make(map[int][]*Doc)
+	var roots []int
+	for i, d := range docs {
+		r := find(i)
+		if groups[r] == nil {
+			roots = append(roots, r)
+		}
+		groups[r] = append(groups[r], d)
+	}
+	out := make([]*Cluster, 0, len(roots))
+	for _, r := range roots {
+		g := groups[r]
+		c := &Cluster{Keep: g[0], Dups: g[1:]}
+		maps := make([]map[string]string, len(g))
+		for i, d := range g {
+			maps[i] = d.Mapping
+		}
+		c.Merged = mapping.Merge(maps...)
+		for token := range c.Merged.Mapping {
+			if _, ok := c.Keep.Mapping[token]; !ok {
+				c.Added = append(c.Added, token)
+			}
+		}
+		sort.Strings(c.Added)
+		for _, r := range c.Merged.Renames[1:] {
+			c.Renamed += len(r)
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
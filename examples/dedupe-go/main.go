@@ -0,0 +1,195 @@
+// Embedding dedupe + Blindfold: Find near-duplicate documents with
+// embeddings of tokenized text, and merge duplicates without losing a
+// value.
+//
+// Each document is tokenized on its own and only its tokenized text is
+// embedded, with tokens reduced to their type, so "charged twice on
+// <Credit Card Number>" embeds the same whoever's card it was. Similarity
+// search works as well as on raw text, and the embeddings API never sees
+// a value. But it also means a form letter from two different customers
+// looks like a duplicate. Every similar pair is therefore checked against
+// the values behind its tokens: only pairs that share them are merged.
+// Merging reconciles the mappings with mapping.Merge, so the kept
+// document's tokens stay as they are, each duplicate's tokens are
+// renumbered to match, and values only a duplicate had are kept.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/semcache"
+)
+
+// Record is one document of the deduplicated corpus. Duplicates keep
+// their text, rewritten into the record's numbering, so a value only a
+// duplicate had is not lost.
+type Record struct {
+	ID         string `json:"id"`
+	Text       string `json:"text"` // tokenized
+	Duplicates []Copy `json:"duplicates,omitempty"`
+}
+
+// Copy is a duplicate merged into a record.
+type Copy struct {
+	ID      string            `json:"id"`
+	Text    string            `json:"text"`              // tokenized, in the record's numbering
+	Renames map[string]string `json:"renames,omitempty"` // its own token → the record's
+}
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply, by name (default: the file's default)")
+	dir := flag.String("dir", "corpus", "directory of .txt documents")
+	out := flag.String("out", "output", "directory for the deduplicated corpus and its mappings")
+	threshold := flag.Float64("threshold", 0.85, "cosine similarity at which two documents are compared")
+	model := flag.String("embedding-model", string(openai.SmallEmbedding3), "OpenAI embedding model")
+	compare := flag.Bool("compare", false, "also embed the raw text and show both similarities (synthetic data only)")
+	demo := flag.Bool("demo", false, "use an offline word-hashing embedder instead of OpenAI (no OpenAI key needed)")
+	flag.Parse()
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+
+	var embedder semcache.Embedder = semcache.Words(512)
+	if !*demo {
+		if os.Getenv("OPENAI_API_KEY") == "" {
+			log.Fatal("OPENAI_API_KEY is required (or run with -demo)")
+		}
+		embedder = semcache.OpenAI(openai.NewClient(os.Getenv("OPENAI_API_KEY")), openai.EmbeddingModel(*model))
+	}
+
+	files, err := filepath.Glob(filepath.Join(*dir, "*.txt"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(files) < 2 {
+		log.Fatalf("need at least two documents in %s", *dir)
+	}
+	sort.Strings(files)
+	var docs []*Doc
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			log.Fatal(err)
+		}
+		res, err := bf.Tokenize(ctx, string(data))
+		if err != nil {
+			log.Fatalf("%s: tokenize: %v", f, err)
+		}
+		d := &Doc{ID: strings.TrimSuffix(filepath.Base(f), ".txt"), Text: res.Text, Mapping: res.Mapping}
+		if d.vec, err = embedder.Embed(ctx, shape(d.Text)); err != nil {
+			log.Fatalf("%s: embed: %v", f, err)
+		}
+		if *compare {
+			// The one place raw text goes out: -compare is for synthetic data
+			if d.raw, err = embedder.Embed(ctx, string(data)); err != nil {
+				log.Fatalf("%s: embed: %v", f, err)
+			}
+		}
+		docs = append(docs, d)
+	}
+
+	ps := pairs(docs, *threshold)
+	fmt.Printf("%d documents, %d similar pair(s) at ≥ %.2f\n\n", len(docs), len(ps), *threshold)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "SIMILARITY\tKIND\tSHARED VALUES\tPAIR"
+	if *compare {
+		header = "SIMILARITY\tRAW\tKIND\tSHARED VALUES\tPAIR"
+	}
+	fmt.Fprintln(tw, header)
+	for _, p := range ps {
+		raw := ""
+		if *compare {
+			raw = fmt.Sprintf("\t%.2f", p.Raw)
+		}
+		fmt.Fprintf(tw, "%.2f%s\t%s\t%d/%d\t%s ~ %s\n", p.Similarity, raw, p.Kind, p.Shared, p.Smaller, docs[p.A].ID, docs[p.B].ID)
+	}
+	tw.Flush()
+
+	cs := clusters(docs, ps)
+	var records []Record
+	mappings := make(map[string]map[string]string)
+	merged := 0
+	fmt.Println()
+	for _, c := range cs {
+		r := Record{ID: c.Keep.ID, Text: c.Keep.Text}
+		for i, d := range c.Dups {
+			r.Duplicates = append(r.Duplicates, Copy{ID: d.ID, Text: c.Merged.Rewrite(i+1, d.Text), Renames: c.Merged.Renames[i+1]})
+		}
+		if len(c.Dups) > 0 {
+			ids := make([]string, len(c.Dups))
+			for i, d := range c.Dups {
+				ids[i] = d.ID
+			}
+			added := "no new values"
+			if len(c.Added) > 0 {
+				added = "adds " + strings.Join(c.Added, ", ")
+			}
+			fmt.Printf("%s kept, merged %s: %d token(s) renumbered, %s\n", c.Keep.ID, strings.Join(ids, ", "), c.Renamed, added)
+			merged += len(c.Dups)
+		}
+		records = append(records, r)
+		mappings[r.ID] = c.Merged.Mapping
+	}
+
+	if err := os.MkdirAll(*out, 0o700); err != nil {
+		log.Fatal(err)
+	}
+	corpus := filepath.Join(*out, "corpus.jsonl")
+	if err := writeJSONL(corpus, records); err != nil {
+		log.Fatal(err)
+	}
+	// The mappings restore every record; keep them apart from the corpus
+	mapPath := filepath.Join(*out, "mappings.json")
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(mappings); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(mapPath, []byte(b.String()), 0o600); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("\nWrote %s (%d documents, %d duplicate(s) merged) and %s\n", corpus, len(records), merged, mapPath)
+}
+
+func writeJSONL(path string, records []Record) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetEscapeHTML(false)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// audience is the aud claim this service accepts.
+const audience = "detokenize-approval"
+
+// Role is the role claim in a caller's JWT.
+type Role string
+
+// Roles the service knows.
+const (
+	Caller   Role = "caller"   // tokenizes, and asks for text to be restored
+	Reviewer Role = "reviewer" // approves or denies restoring sensitive values
+)
+
+// Claims are the JWT claims the service reads.
+type Claims struct {
+	Role Role `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// authenticate verifies the bearer token in an Authorization header. Only
+// HS256 is accepted, and the token must carry an expiry and this
+// service's audience.
+func authenticate(secret []byte, header string) (*Claims, error) {
+	raw, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || raw == "" {
+		return nil, errors.New("missing bearer token")
+	}
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(*jwt.Token) (any, error) { return secret, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return claims, nil
+}
+
+// issue signs a token for subject with role, valid for ttl. In production
+// tokens come from your identity provider; this is for the demo.
+func issue(secret []byte, subject string, role Role, ttl time.Duration) (string, error) {
+	now := time.Now()
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}).SignedString(secret)
+}
@@ -0,0 +1,205 @@
+// Detokenization approval + Blindfold: Hold back restored text that
+// reveals sensitive values until a reviewer approves it.
+//
+// A small HTTP service tokenizes text for callers and keeps the mapping
+// server-side. Restoring text whose tokens are all of ordinary types is
+// immediate. When a token is of a sensitive type (SSNs, medical record
+// numbers, diagnoses), the request is queued with the caller's reason
+// instead, and the caller gets a preview with those tokens left in. A
+// reviewer approves or denies it on a small web page or through the API,
+// and only an approved request is released, once, to the caller that
+// made it.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/config"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+const ticket = "Member jane.doe@example.com (SSN 123-45-6789, MRN 00482913) asks whether her type 2 diabetes " +
+	"supplies are covered. Call back on 415-555-0134."
+
+// call sends body (nil for a GET) to the service as the holder of token
+// and returns the status and response body.
+func call(method, url, token string, body any) (int, string, error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, "", err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, r)
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	out, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(bytes.TrimSpace(out)), err
+}
+
+// demo runs the service in-process: a support bot asks for two replies to
+// be restored, one is approved and collected while the bot waits, the
+// other denied.
+func demo(handler http.Handler, secret []byte) error {
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	token := func(sub string, role Role) string {
+		t, err := issue(secret, sub, role, 5*time.Minute)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return t
+	}
+	// The bot's own subject with the reviewer role: approving its own
+	// request is still refused
+	bot, dana, self := token("support-bot", Caller), token("dana@example.com", Reviewer), token("support-bot", Reviewer)
+	must := func(method, path, tok string, body any) string {
+		status, out, err := call(method, srv.URL+path, tok, body)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("  %s %s → %d\n    %s\n", method, path, status, out)
+		return out
+	}
+
+	fmt.Println("── 1. The bot tokenizes a ticket")
+	var tok tokenizeResponse
+	json.Unmarshal([]byte(must("POST", "/v1/tokenize", bot, tokenizeRequest{Text: ticket})), &tok)
+
+	// Tokenized text stands in for the LLM's replies, which kept the tokens
+	fmt.Println("\n── 2. A reply with ordinary tokens only is restored at once")
+	must("POST", "/v1/detokenize", bot, detokenizeRequest{Session: tok.Session, Text: "I've emailed <Email Address_1> the coverage details."})
+
+	fmt.Println("\n── 3. A reply with the SSN and the diagnosis waits for a reviewer")
+	reply := "Confirmed for SSN <Social Security Number_1>: <Medical Condition_1> supplies are covered. We'll call <Phone Number_1>."
+	var queued restoreResponse
+	json.Unmarshal([]byte(must("POST", "/v1/detokenize", bot, detokenizeRequest{Session: tok.Session, Text: reply})), &queued)
+	json.Unmarshal([]byte(must("POST", "/v1/detokenize", bot, detokenizeRequest{
+		Session: tok.Session, Text: reply, Reason: "member asked on a verified call, ticket #8812",
+	})), &queued)
+
+	// The bot long-polls while the reviewer decides
+	released := make(chan string)
+	go func() {
+		status, out, _ := call("GET", srv.URL+queued.Poll+"?wait=10s", bot, nil)
+		released <- fmt.Sprintf("  [bot, waiting since 3] GET %s?wait=10s → %d\n    %s\n", queued.Poll, status, out)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	fmt.Println("\n── 4. Reviewers see tokens, never values")
+	must("GET", "/v1/review", dana, nil)
+	must("GET", "/v1/review", bot, nil)
+	must("POST", "/v1/review/"+queued.Request, self, decision{Decision: "approve"})
+	must("POST", "/v1/review/"+queued.Request, dana, decision{Decision: "approve", Note: "verified caller"})
+	fmt.Print(<-released)
+
+	fmt.Println("\n── 5. The text is released once")
+	must("GET", queued.Poll, bot, nil)
+
+	fmt.Println("\n── 6. A denied request")
+	json.Unmarshal([]byte(must("POST", "/v1/detokenize", bot, detokenizeRequest{
+		Session: tok.Session, Text: "Your MRN is <Medical Record Number_1>.", Reason: "member forgot it",
+	})), &queued)
+	must("POST", "/v1/review/"+queued.Request, dana, decision{Decision: "deny", Note: "read MRNs only from the portal"})
+	must("GET", queued.Poll, bot, nil)
+	return nil
+}
+
+func main() {
+	// Every flag can also be set as APPROVAL_<FLAG> or in the -config file
+	cfg := config.New(flag.CommandLine, "APPROVAL")
+	addr := flag.String("addr", "127.0.0.1:8080", "listen address")
+	policiesFile := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	policy := flag.String("policy", "", "policy to apply, by name (default: the file's default)")
+	sensitive := flag.String("approve", "Social Security Number,Medical Record Number,Medical Condition", "entity types whose values are restored only after approval, comma-separated")
+	ttl := flag.Duration("ttl", 15*time.Minute, "how long a request waits for a reviewer, and an approval for its caller")
+	runDemo := flag.Bool("demo", false, "run the service in-process and call it as the bot and reviewers")
+	issueRole := flag.String("issue", "", "print a demo JWT for this role (caller or reviewer) and exit")
+	subject := flag.String("sub", "demo@example.com", "subject for -issue")
+	cfg.File("config")
+	cfg.Check("ttl", func() error {
+		if *ttl <= 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	})
+	if err := cfg.Parse(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	secret := []byte(os.Getenv("JWT_SECRET"))
+	if len(secret) == 0 {
+		if !*runDemo {
+			log.Fatal("JWT_SECRET is required (see .env.example)")
+		}
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *issueRole != "" {
+		t, err := issue(secret, *subject, Role(*issueRole), time.Hour)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(t)
+		return
+	}
+
+	pol, err := policyconf.Resolve(*policiesFile, *policy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var types []string
+	for _, typ := range strings.Split(*sensitive, ",") {
+		if typ = strings.TrimSpace(typ); typ != "" {
+			types = append(types, typ)
+		}
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	s := newServer(pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...)), secret, types, *ttl)
+
+	if *runDemo {
+		log.SetFlags(0)
+		log.SetPrefix("  audit: ")
+		log.SetOutput(os.Stdout)
+		if err := demo(s.routes(), secret); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	log.Printf("listening on http://%s (reviewers: http://%s/review); approval needed for %s", *addr, *addr, strings.Join(types, ", "))
+	if err := http.ListenAndServe(*addr, s.routes()); err != nil {
+		log.Print(err)
+	}
+}
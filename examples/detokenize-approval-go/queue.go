@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status is where an approval request is in its life.
+type Status string
+
+const (
+	Pending  Status = "pending"  // waiting for a reviewer
+	Approved Status = "approved" // may be released to its caller, once
+	Denied   Status = "denied"
+	Expired  Status = "expired"  // not decided, or not collected, in time
+	Released Status = "released" // the restored text went to the caller
+)
+
+var (
+	errNotFound   = errors.New("no such request")
+	errDecided    = errors.New("request is no longer pending")
+	errOwnRequest = errors.New("a reviewer can't approve their own request")
+)
+
+// approval is a request to restore text whose tokens include sensitive
+// types. It holds the tokenized text only: the values stay in the session
+// store until the request is released.
+type approval struct {
+	ID      string    `json:"id"`
+	Session string    `json:"session"`
+	Caller  string    `json:"caller"`
+	Reason  string    `json:"reason"`
+	Text    string    `json:"text"`  // tokenized
+	Needs   []string  `json:"needs"` // the sensitive entity types in Text
+	Status  Status    `json:"status"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+
+	Reviewer string     `json:"reviewer,omitempty"`
+	Note     string     `json:"note,omitempty"`
+	Decided  *time.Time `json:"decided,omitempty"`
+
+	done chan struct{} // closed when it leaves Pending
+}
+
+// queue holds approval requests in memory. A request not decided within
+// ttl expires, and so does an approval not collected within ttl of the
+// decision.
+type queue struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	items map[string]*approval
+}
+
+func newQueue(ttl time.Duration) *queue {
+	return &queue{ttl: ttl, items: make(map[string]*approval)}
+}
+
+// submit queues a as pending.
+func (q *queue) submit(a *approval) {
+	now := time.Now()
+	a.Status, a.Created, a.Expires, a.done = Pending, now, now.Add(q.ttl), make(chan struct{})
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.items[a.ID] = a
+}
+
+// expire moves a to Expired if its time is up. q.mu must be held.
+func (q *queue) expire(a *approval, now time.Time) {
+	if (a.Status == Pending || a.Status == Approved) && now.After(a.Expires) {
+		if a.Status == Pending {
+			close(a.done)
+		}
+		a.Status = Expired
+	}
+}
+
+// get returns a copy of the request id.
+func (q *queue) get(id string) (approval, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	a, ok := q.items[id]
+	if !ok {
+		return approval{}, errNotFound
+	}
+	q.expire(a, time.Now())
+	return *a, nil
+}
+
+// wait returns the request id once it has left Pending, or as it is when
+// ctx is done.
+func (q *queue) wait(ctx context.Context, id string) (approval, error) {
+	a, err := q.get(id)
+	if err != nil || a.Status != Pending {
+		return a, err
+	}
+	timer := time.NewTimer(time.Until(a.Expires))
+	defer timer.Stop()
+	select {
+	case <-a.done:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return q.get(id)
+}
+
+// decide records reviewer's decision on the pending request id.
+func (q *queue) decide(id, reviewer string, approve bool, note string) (approval, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	a, ok := q.items[id]
+	if !ok {
+		return approval{}, errNotFound
+	}
+	now := time.Now()
+	q.expire(a, now)
+	if a.Status != Pending {
+		return *a, errDecided
+	}
+	if a.Caller == reviewer {
+		return *a, errOwnRequest
+	}
+	a.Status, a.Reviewer, a.Note, a.Decided = Denied, reviewer, note, &now
+	if approve {
+		// The caller has ttl from now to collect it
+		a.Status, a.Expires = Approved, now.Add(q.ttl)
+	}
+	close(a.done)
+	return *a, nil
+}
+
+// release marks the approved request id Released and returns it. It
+// returns the request unchanged if it is in any other state, so a text is
+// released at most once.
+func (q *queue) release(id string) (approval, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	a, ok := q.items[id]
+	if !ok {
+		return approval{}, errNotFound
+	}
+	q.expire(a, time.Now())
+	if a.Status != Approved {
+		return *a, nil
+	}
+	released := *a
+	a.Status = Released
+	return released, nil
+}
+
+// pending returns the requests waiting for a reviewer, oldest first, and
+// forgets those closed for longer than ttl.
+func (q *queue) pending() []approval {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	var out []approval
+	for _, a := range q.items {
+		q.expire(a, now)
+		switch {
+		case a.Status == Pending:
+			out = append(out, *a)
+		case a.Status != Approved && now.After(a.Expires.Add(q.ttl)):
+			delete(q.items, a.ID)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.Before(out[j].Created) })
+	return out
+}
@@ -0,0 +1,80 @@
+package main
+
+import "net/http"
+
+// reviewPage serves the reviewer's page. It holds no data: the page asks
+// for the reviewer's token and calls /v1/review with it, so it is served
+// without one.
+func reviewPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+	w.Write([]byte(reviewHTML))
+}
+
+const reviewHTML = `<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Detokenization approvals</title>
+<style>
+body { font: 14px system-ui, sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+td, th { border-bottom: 1px solid #ddd; padding: .5em; text-align: left; vertical-align: top; }
+code { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>Detokenization approvals</h1>
+<p><input id="token" type="password" placeholder="reviewer token" size="60"> <button id="load">Load</button> <span id="msg"></span></p>
+<table>
+<thead><tr><th>Requested</th><th>Caller</th><th>Reason</th><th>Needs</th><th>Text</th><th>Decision</th></tr></thead>
+<tbody id="rows"></tbody>
+</table>
+<script>
+const $ = id => document.getElementById(id);
+const call = (method, path, body) => fetch(path, {
+  method, body: body && JSON.stringify(body),
+  headers: {"Authorization": "Bearer " + $("token").value, "Content-Type": "application/json"},
+}).then(async r => { const j = await r.json(); if (!r.ok) throw new Error(j.error ? j.error.message : r.status); return j; });
+
+function cell(tr, text, tag) {
+  const td = tr.insertCell(), el = document.createElement(tag || "span");
+  el.textContent = text;
+  td.appendChild(el);
+  return td;
+}
+
+async function load() {
+  try {
+    const {pending} = await call("GET", "/v1/review");
+    const rows = $("rows");
+    rows.replaceChildren();
+    for (const a of pending || []) {
+      const tr = rows.insertRow();
+      cell(tr, new Date(a.created).toLocaleTimeString());
+      cell(tr, a.caller);
+      cell(tr, a.reason);
+      cell(tr, a.needs.join(", "));
+      cell(tr, a.text, "code");
+      const td = tr.insertCell(), note = document.createElement("input");
+      note.placeholder = "note";
+      td.appendChild(note);
+      for (const d of ["approve", "deny"]) {
+        const b = document.createElement("button");
+        b.textContent = d;
+        b.onclick = () => call("POST", "/v1/review/" + a.id, {decision: d, note: note.value})
+          .then(load, e => $("msg").textContent = e.message);
+        td.appendChild(b);
+      }
+    }
+    $("msg").textContent = (pending || []).length + " pending";
+  } catch (e) {
+    $("msg").textContent = e.message;
+  }
+}
+$("load").onclick = load;
+setInterval(() => $("token").value && load(), 5000);
+</script>
+</body>
+</html>
+`
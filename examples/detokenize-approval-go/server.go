@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// maxWait caps how long GET /v1/requests/{id}?wait= holds a request open.
+const maxWait = 60 * time.Second
+
+// server tokenizes text for callers and keeps the mappings to itself.
+// Restoring text is immediate unless it holds a token of a sensitive type;
+// then it waits in the queue for a reviewer.
+type server struct {
+	bf        bfclient.Client
+	secret    []byte
+	sensitive map[string]bool // entity types that need approval
+	q         *queue
+
+	mu       sync.Mutex
+	sessions map[string]session // by session ID
+}
+
+// session is a tokenization's mapping and the subject who created it,
+// the only one who may restore its tokens.
+type session struct {
+	owner string
+	m     map[string]string
+}
+
+func newServer(bf bfclient.Client, secret []byte, sensitive []string, ttl time.Duration) *server {
+	s := &server{bf: bf, secret: secret, sensitive: make(map[string]bool), q: newQueue(ttl), sessions: make(map[string]session)}
+	for _, typ := range sensitive {
+		s.sensitive[typ] = true
+	}
+	return s
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/tokenize", s.authed(Caller, http.MethodPost, s.tokenize))
+	mux.HandleFunc("/v1/detokenize", s.authed(Caller, http.MethodPost, s.detokenize))
+	mux.HandleFunc("/v1/requests/", s.authed(Caller, http.MethodGet, s.poll))
+	mux.HandleFunc("/v1/review", s.authed(Reviewer, http.MethodGet, s.listPending))
+	mux.HandleFunc("/v1/review/", s.authed(Reviewer, http.MethodPost, s.decide))
+	mux.HandleFunc("/review", reviewPage)
+	return mux
+}
+
+// authed rejects requests with another method, without a valid JWT or
+// from another role.
+func (s *server) authed(role Role, method string, next func(http.ResponseWriter, *http.Request, *Claims)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use "+method)
+			return
+		}
+		claims, err := authenticate(s.secret, r.Header.Get("Authorization"))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="detokenize-approval"`)
+			writeError(w, http.StatusUnauthorized, "unauthorized", err.Error())
+			return
+		}
+		if claims.Role != role {
+			writeError(w, http.StatusForbidden, "forbidden", "needs role "+string(role))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+		next(w, r, claims)
+	}
+}
+
+type tokenizeRequest struct {
+	Text string `json:"text"`
+}
+
+type tokenizeResponse struct {
+	Session string `json:"session"`
+	Text    string `json:"text"`
+}
+
+func (s *server) tokenize(w http.ResponseWriter, r *http.Request, c *Claims) {
+	var req tokenizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	res, err := s.bf.Tokenize(r.Context(), req.Text)
+	if err != nil {
+		// Fail closed: nothing is returned unprotected
+		writeError(w, http.StatusBadGateway, "blindfold_error", "tokenization failed")
+		return
+	}
+	id := newID()
+	s.mu.Lock()
+	s.sessions[id] = session{owner: c.Subject, m: res.Mapping}
+	s.mu.Unlock()
+	log.Printf("tokenize session=%s sub=%s tokens=%d", id, c.Subject, len(res.Mapping))
+	writeJSON(w, http.StatusOK, tokenizeResponse{Session: id, Text: res.Text})
+}
+
+type detokenizeRequest struct {
+	Session string `json:"session"`
+	Text    string `json:"text"`
+	Reason  string `json:"reason"` // shown to the reviewer
+}
+
+// restoreResponse is the answer to a restore, now or after review.
+type restoreResponse struct {
+	Request string   `json:"request,omitempty"`
+	Status  Status   `json:"status"`
+	Text    string   `json:"text,omitempty"`    // the restored text, once released
+	Preview string   `json:"preview,omitempty"` // restored except for the sensitive tokens, while pending
+	Needs   []string `json:"needs,omitempty"`
+	Poll    string   `json:"poll,omitempty"`
+
+	Reviewer string `json:"reviewer,omitempty"`
+	Note     string `json:"note,omitempty"`
+}
+
+// detokenize restores the session's tokens in text, unless one of them is
+// of a sensitive type: then the request is queued for review and the
+// caller gets its ID to poll, and a preview with only the other tokens
+// restored.
+func (s *server) detokenize(w http.ResponseWriter, r *http.Request, c *Claims) {
+	var req detokenizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	m, ok := s.mapping(req.Session, c.Subject)
+	if !ok {
+		// Someone else's session doesn't exist, as far as this caller knows
+		writeError(w, http.StatusNotFound, "not_found", "unknown session")
+		return
+	}
+	needs, preview := s.split(req.Text, m)
+	if len(needs) == 0 {
+		log.Printf("detokenize session=%s sub=%s released", req.Session, c.Subject)
+		writeJSON(w, http.StatusOK, restoreResponse{Status: Released, Text: mapping.Detokenize(req.Text, m)})
+		return
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		writeError(w, http.StatusBadRequest, "reason_required", "restoring "+strings.Join(needs, ", ")+" needs a reason for the reviewer")
+		return
+	}
+
+	a := &approval{ID: newID(), Session: req.Session, Caller: c.Subject, Reason: req.Reason, Text: req.Text, Needs: needs}
+	s.q.submit(a)
+	log.Printf("approval requested id=%s session=%s sub=%s needs=%q", a.ID, a.Session, c.Subject, needs)
+	w.Header().Set("Location", "/v1/requests/"+a.ID)
+	writeJSON(w, http.StatusAccepted, restoreResponse{Request: a.ID, Status: Pending, Preview: preview, Needs: needs, Poll: "/v1/requests/" + a.ID})
+}
+
+// mapping returns the mapping of session if owner created it.
+func (s *server) mapping(id, owner string) (map[string]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok || sess.owner != owner {
+		return nil, false
+	}
+	return sess.m, true
+}
+
+// split returns the sensitive entity types among the tokens of text that
+// m resolves, and text with every other token restored.
+func (s *server) split(text string, m map[string]string) ([]string, string) {
+	needs := make(map[string]bool)
+	open := make(map[string]string, len(m))
+	for token, value := range m {
+		if !strings.Contains(text, token) {
+			continue
+		}
+		typ, _, _ := mapping.ParseToken(token)
+		if s.sensitive[typ] {
+			needs[typ] = true
+			continue
+		}
+		open[token] = value
+	}
+	types := make([]string, 0, len(needs))
+	for typ := range needs {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types, mapping.Detokenize(text, open)
+}
+
+// poll returns the caller's request; with ?wait=30s it waits up to that
+// long for a decision first. An approved request is released here, once:
+// the restored text is made at that moment and never kept.
+func (s *server) poll(w http.ResponseWriter, r *http.Request, c *Claims) {
+	id := strings.TrimPrefix(r.URL.Path, "/v1/requests/")
+	a, err := s.q.get(id)
+	if err != nil || a.Caller != c.Subject {
+		// Someone else's request doesn't exist, as far as this caller knows
+		writeError(w, http.StatusNotFound, "not_found", "unknown request")
+		return
+	}
+	if wait, err := time.ParseDuration(r.URL.Query().Get("wait")); err == nil && wait > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), min(wait, maxWait))
+		defer cancel()
+		a, _ = s.q.wait(ctx, id)
+	}
+
+	resp := restoreResponse{Request: id, Status: a.Status, Needs: a.Needs, Reviewer: a.Reviewer, Note: a.Note}
+	switch a.Status {
+	case Pending:
+		writeJSON(w, http.StatusAccepted, resp)
+	case Approved:
+		m, _ := s.mapping(a.Session, a.Caller)
+		if a, _ = s.q.release(id); a.Status != Approved {
+			writeJSON(w, http.StatusGone, restoreResponse{Request: id, Status: a.Status})
+			return
+		}
+		log.Printf("released id=%s session=%s sub=%s approved_by=%s", id, a.Session, c.Subject, a.Reviewer)
+		resp.Status, resp.Text = Released, mapping.Detokenize(a.Text, m)
+		writeJSON(w, http.StatusOK, resp)
+	case Denied:
+		writeJSON(w, http.StatusForbidden, resp)
+	default: // Expired, or already Released
+		writeJSON(w, http.StatusGone, resp)
+	}
+}
+
+// listPending returns the requests waiting for a reviewer. They hold
+// tokenized text only: a reviewer decides on the reason and the entity
+// types, without seeing a value.
+func (s *server) listPending(w http.ResponseWriter, r *http.Request, c *Claims) {
+	writeJSON(w, http.StatusOK, map[string][]approval{"pending": s.q.pending()})
+}
+
+type decision struct {
+	Decision string `json:"decision"` // "approve" or "deny"
+	Note     string `json:"note"`
+}
+
+func (s *server) decide(w http.ResponseWriter, r *http.Request, c *Claims) {
+	id := strings.TrimPrefix(r.URL.Path, "/v1/review/")
+	var d decision
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil || (d.Decision != "approve" && d.Decision != "deny") {
+		writeError(w, http.StatusBadRequest, "invalid_request", `want {"decision": "approve" or "deny", "note": "..."}`)
+		return
+	}
+	a, err := s.q.decide(id, c.Subject, d.Decision == "approve", d.Note)
+	switch {
+	case errors.Is(err, errNotFound):
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+		return
+	case errors.Is(err, errOwnRequest):
+		log.Printf("approval REFUSED id=%s reviewer=%s: own request", id, c.Subject)
+		writeError(w, http.StatusForbidden, "forbidden", err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusConflict, "conflict", err.Error()+": "+string(a.Status))
+		return
+	}
+	log.Printf("approval %s id=%s reviewer=%s", a.Status, id, c.Subject)
+	writeJSON(w, http.StatusOK, restoreResponse{Request: id, Status: a.Status, Needs: a.Needs, Reviewer: a.Reviewer, Note: a.Note})
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, typ, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"message": message, "type": typ},
+	})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+var secret = []byte("test-secret-test-secret-test-sec")
+
+func newTestServer(t *testing.T, ttl time.Duration) (*httptest.Server, string) {
+	t.Helper()
+	s := newServer(blindfold.New(blindfold.WithMode("local")), secret, []string{"Social Security Number"}, ttl)
+	srv := httptest.NewServer(s.routes())
+	t.Cleanup(srv.Close)
+	bot := token(t, "bot", Caller)
+	var tok tokenizeResponse
+	status, out, _ := call("POST", srv.URL+"/v1/tokenize", bot, tokenizeRequest{Text: "SSN 123-45-6789, mail jane@example.com"})
+	if status != http.StatusOK || json.Unmarshal([]byte(out), &tok) != nil {
+		t.Fatalf("tokenize: %d %s", status, out)
+	}
+	return srv, tok.Session
+}
+
+func token(t *testing.T, sub string, role Role) string {
+	t.Helper()
+	tok, err := issue(secret, sub, role, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+// submit asks for the SSN to be restored and returns the request.
+func submit(t *testing.T, srv *httptest.Server, session string) restoreResponse {
+	t.Helper()
+	status, out, _ := call("POST", srv.URL+"/v1/detokenize", token(t, "bot", Caller),
+		detokenizeRequest{Session: session, Text: "SSN <Social Security Number_1> for <Email Address_1>", Reason: "test"})
+	var resp restoreResponse
+	json.Unmarshal([]byte(out), &resp)
+	if status != http.StatusAccepted || resp.Preview != "SSN <Social Security Number_1> for jane@example.com" {
+		t.Fatalf("detokenize: %d %s", status, out)
+	}
+	return resp
+}
+
+func TestApprovedReleasedOnce(t *testing.T) {
+	srv, session := newTestServer(t, time.Minute)
+	req := submit(t, srv, session)
+	bot := token(t, "bot", Caller)
+
+	if status, _, _ := call("GET", srv.URL+req.Poll, token(t, "mallory", Caller), nil); status != http.StatusNotFound {
+		t.Errorf("another caller polled: %d", status)
+	}
+	if status, _, _ := call("GET", srv.URL+req.Poll, bot, nil); status != http.StatusAccepted {
+		t.Errorf("pending poll: %d", status)
+	}
+	if status, _, _ := call("POST", srv.URL+"/v1/review/"+req.Request, token(t, "bot", Reviewer), decision{Decision: "approve"}); status != http.StatusForbidden {
+		t.Errorf("own approval: %d", status)
+	}
+	if status, out, _ := call("POST", srv.URL+"/v1/review/"+req.Request, token(t, "dana", Reviewer), decision{Decision: "approve"}); status != http.StatusOK {
+		t.Fatalf("approve: %d %s", status, out)
+	}
+	if status, _, _ := call("POST", srv.URL+"/v1/review/"+req.Request, token(t, "dana", Reviewer), decision{Decision: "deny"}); status != http.StatusConflict {
+		t.Errorf("second decision: %d", status)
+	}
+
+	status, out, _ := call("GET", srv.URL+req.Poll, bot, nil)
+	if status != http.StatusOK || !strings.Contains(out, "SSN 123-45-6789 for jane@example.com") {
+		t.Errorf("release: %d %s", status, out)
+	}
+	if status, out, _ := call("GET", srv.URL+req.Poll, bot, nil); status != http.StatusGone || strings.Contains(out, "123-45-6789") {
+		t.Errorf("second release: %d %s", status, out)
+	}
+}
+
+func TestSessionBelongsToItsCreator(t *testing.T) {
+	srv, session := newTestServer(t, time.Minute)
+	mallory := token(t, "mallory", Caller)
+	for _, req := range []detokenizeRequest{
+		{Session: session, Text: "mail <Email Address_1>"},
+		{Session: session, Text: "SSN <Social Security Number_1>", Reason: "test"},
+	} {
+		status, out, _ := call("POST", srv.URL+"/v1/detokenize", mallory, req)
+		if status != http.StatusNotFound || strings.Contains(out, "jane@example.com") {
+			t.Errorf("another caller restored %q: %d %s", req.Text, status, out)
+		}
+	}
+	status, out, _ := call("GET", srv.URL+"/v1/review", token(t, "dana", Reviewer), nil)
+	if status != http.StatusOK || strings.Contains(out, "mallory") {
+		t.Errorf("another caller queued a review: %d %s", status, out)
+	}
+}
+
+func TestUndecidedExpires(t *testing.T) {
+	srv, session := newTestServer(t, 50*time.Millisecond)
+	req := submit(t, srv, session)
+	status, out, _ := call("GET", srv.URL+req.Poll+"?wait=5s", token(t, "bot", Caller), nil)
+	if status != http.StatusGone || !strings.Contains(out, `"expired"`) {
+		t.Errorf("after ttl: %d %s", status, out)
+	}
+	if status, _, _ := call("POST", srv.URL+"/v1/review/"+req.Request, token(t, "dana", Reviewer), decision{Decision: "approve"}); status != http.StatusConflict {
+		t.Errorf("approved after expiry: %d", status)
+	}
+}
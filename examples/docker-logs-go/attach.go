@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// attacher follows the logs of every matching container: those running
+// at startup and those started later.
+type attacher struct {
+	eng    *engine
+	labels []string
+	tail   int
+	self   string // this container's ID prefix, never followed
+	lines  chan<- logLine
+
+	mu     sync.Mutex
+	active map[string]bool
+	last   map[string]time.Time // last line seen per container, for resuming
+	wg     sync.WaitGroup
+}
+
+// run attaches until ctx is done, then waits for the followers to stop.
+// Events are subscribed to before the running containers are listed, so a
+// container that starts in between isn't missed.
+func (a *attacher) run(ctx context.Context) error {
+	subscribed := make(chan error, 1)
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		for first := true; ctx.Err() == nil; first = false {
+			err := a.eng.starts(ctx, a.labels, func(id string) { a.attach(ctx, id) })
+			if first {
+				subscribed <- err
+			}
+			if ctx.Err() == nil {
+				log.Printf("events: %v; reconnecting", err)
+				sleep(ctx, time.Second)
+			}
+		}
+	}()
+	// Give the events request a moment; an immediate failure, such as no
+	// Docker socket, is reported right away
+	select {
+	case err := <-subscribed:
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	ids, err := a.eng.running(ctx, a.labels)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		a.attach(ctx, id)
+	}
+	<-ctx.Done()
+	a.wg.Wait()
+	return nil
+}
+
+// attach starts following id unless it is already followed or is the
+// sidecar itself, whose scrubbed output would loop back in.
+func (a *attacher) attach(ctx context.Context, id string) {
+	if a.self != "" && strings.HasPrefix(id, a.self) {
+		return
+	}
+	a.mu.Lock()
+	if a.active[id] {
+		a.mu.Unlock()
+		return
+	}
+	a.active[id] = true
+	a.mu.Unlock()
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		defer func() {
+			a.mu.Lock()
+			delete(a.active, id)
+			a.mu.Unlock()
+		}()
+		a.followLoop(ctx, id)
+	}()
+}
+
+// followLoop follows one container until it stops, reconnecting after
+// errors from where it left off.
+func (a *attacher) followLoop(ctx context.Context, id string) {
+	for ctx.Err() == nil {
+		c, err := a.eng.inspect(ctx, id)
+		if err != nil {
+			// Gone, most likely; a restart brings a new start event
+			if ctx.Err() == nil {
+				log.Printf("%.12s: %v", id, err)
+			}
+			return
+		}
+		a.mu.Lock()
+		since := a.last[c.ID]
+		a.mu.Unlock()
+		if since.IsZero() {
+			log.Printf("following %s (%s)", c.Name, c.short())
+		}
+		err = a.eng.follow(ctx, c, since, a.tail, func(l logLine) {
+			a.mu.Lock()
+			a.last[c.ID] = l.Time
+			a.mu.Unlock()
+			a.lines <- l
+		})
+		if err == nil {
+			// The stream ends when the container stops
+			log.Printf("%s (%s) stopped", c.Name, c.short())
+			return
+		}
+		if ctx.Err() == nil {
+			log.Printf("%s (%s): %v; reconnecting", c.Name, c.short(), err)
+			sleep(ctx, time.Second)
+		}
+	}
+}
+
+// selfID is the sidecar's own container ID prefix when it runs in Docker,
+// which sets the hostname to it, or "".
+func selfID() string {
+	if _, err := os.Stat("/.dockerenv"); err != nil {
+		return ""
+	}
+	h, _ := os.Hostname()
+	return h
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
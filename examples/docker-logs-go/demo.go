@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// demoLogs is what the fake containers write. The multiplexed frames cut
+// lines, and one email address, in the middle.
+var demoLogs = map[string][]string{
+	"checkout-api": {
+		`INFO order 8812 placed by jane.doe@example.com from 203.0.113.42`,
+		`WARN payment retry card=4111 1111 1111 1111 customer=jane.doe@example.com`,
+		`ERROR refund failed for ssn 123-45-6789, contact billing@example.com`,
+	},
+	"worker": {
+		`{"level":"info","msg":"welcome email sent","to":"marcus.webb@example.org"}`,
+		`{"level":"info","msg":"sms sent","to":"+1 415-555-0134"}`,
+	},
+}
+
+// fakeEngine stands in for the Docker Engine API on a Unix socket. It
+// knows two containers: checkout-api, running from the start with
+// multiplexed logs, and worker, a TTY container that starts a moment
+// later and is announced on /events.
+func fakeEngine() (sock string, stop func(), err error) {
+	dir, err := os.MkdirTemp("", "docker-logs-demo")
+	if err != nil {
+		return "", nil, err
+	}
+	sock = filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+	ids := map[string]string{"checkout-api": strings.Repeat("a1", 32), "worker": strings.Repeat("b2", 32)}
+	names := map[string]string{ids["checkout-api"]: "checkout-api", ids["worker"]: "worker"}
+	writeJSON := func(w http.ResponseWriter, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+engineVersion+"/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []map[string]string{{"Id": ids["checkout-api"]}})
+	})
+	mux.HandleFunc("/"+engineVersion+"/events", func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		sleep(r.Context(), 500*time.Millisecond)
+		writeJSON(w, map[string]any{"Type": "container", "Action": "start", "Actor": map[string]string{"ID": ids["worker"]}})
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	mux.HandleFunc("/"+engineVersion+"/containers/", func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, "/"+engineVersion+"/containers/")
+		id, what, _ := strings.Cut(rest, "/")
+		name, ok := names[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			writeJSON(w, map[string]string{"message": "No such container: " + id})
+			return
+		}
+		tty := name == "worker"
+		switch what {
+		case "json":
+			writeJSON(w, map[string]any{"Id": id, "Name": "/" + name, "Config": map[string]bool{"Tty": tty}})
+		case "logs":
+			var stream []byte
+			for i, line := range demoLogs[name] {
+				ts := time.Date(2026, 10, 14, 9, 12, i+1, 0, time.UTC).Format(time.RFC3339Nano)
+				stream = append(stream, ts+" "+line+"\n"...)
+			}
+			if tty {
+				_, _ = w.Write(stream)
+				return
+			}
+			// Multiplexed, in 37-byte frames on stdout
+			for i := 0; i < len(stream); i += 37 {
+				part := stream[i:min(i+37, len(stream))]
+				var hdr [8]byte
+				hdr[0] = 1
+				binary.BigEndian.PutUint32(hdr[4:], uint32(len(part)))
+				_, _ = w.Write(append(hdr[:], part...))
+				w.(http.Flusher).Flush()
+			}
+			// Still running: hold the stream open, as follow=1 does
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	srv := &http.Server{Handler: mux}
+	go func() { _ = srv.Serve(l) }()
+	return sock, func() { srv.Close(); os.RemoveAll(dir) }, nil
+}
+
+// demoHost runs the fake engine and returns its DOCKER_HOST.
+func demoHost() (string, func(), error) {
+	sock, stop, err := fakeEngine()
+	if err != nil {
+		return "", nil, fmt.Errorf("demo: %w", err)
+	}
+	return "unix://" + sock, stop, nil
+}
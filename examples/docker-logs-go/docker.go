@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// engineVersion is the Engine API version requested. 1.41 is Docker 20.10;
+// later engines serve it too.
+const engineVersion = "v1.41"
+
+// engine is a minimal Docker Engine API client: the four endpoints the
+// sidecar needs, over the Unix socket or TCP.
+type engine struct {
+	http *http.Client
+	base string // "http://docker/v1.41" for a socket
+}
+
+// newEngine connects to host, a DOCKER_HOST value: unix:///path or
+// tcp://host:port. Empty means the default socket.
+func newEngine(host string) (*engine, error) {
+	if host == "" {
+		host = "unix:///var/run/docker.sock"
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("docker host %q: %w", host, err)
+	}
+	switch u.Scheme {
+	case "unix":
+		sock := u.Path
+		tr := &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+		}}
+		return &engine{http: &http.Client{Transport: tr}, base: "http://docker/" + engineVersion}, nil
+	case "tcp", "http":
+		return &engine{http: &http.Client{}, base: "http://" + u.Host + "/" + engineVersion}, nil
+	default:
+		return nil, fmt.Errorf("docker host %q: want unix:// or tcp:// (TLS hosts aren't supported here)", host)
+	}
+}
+
+func (e *engine) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := e.base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := e.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("docker: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		resp.Body.Close()
+		var msg struct{ Message string }
+		if json.Unmarshal(body, &msg) == nil && msg.Message != "" {
+			return nil, fmt.Errorf("docker: %s: %s", path, msg.Message)
+		}
+		return nil, fmt.Errorf("docker: %s: %s", path, resp.Status)
+	}
+	return resp, nil
+}
+
+func (e *engine) getJSON(ctx context.Context, path string, query url.Values, v any) error {
+	resp, err := e.get(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// container is what the sidecar needs to know about a container.
+type container struct {
+	ID   string
+	Name string
+	TTY  bool // logs of a TTY container aren't multiplexed
+}
+
+func (c container) short() string { return c.ID[:min(12, len(c.ID))] }
+
+// labelFilters encodes label selectors ("app=web", "scrub") as the filters
+// parameter of /containers/json and /events.
+func labelFilters(labels []string, extra map[string][]string) url.Values {
+	f := map[string][]string{}
+	for k, v := range extra {
+		f[k] = v
+	}
+	if len(labels) > 0 {
+		f["label"] = labels
+	}
+	q := url.Values{}
+	if len(f) > 0 {
+		b, _ := json.Marshal(f)
+		q.Set("filters", string(b))
+	}
+	return q
+}
+
+// running lists the running containers that carry every label.
+func (e *engine) running(ctx context.Context, labels []string) ([]string, error) {
+	var list []struct{ ID string }
+	if err := e.getJSON(ctx, "/containers/json", labelFilters(labels, nil), &list); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(list))
+	for i, c := range list {
+		ids[i] = c.ID
+	}
+	return ids, nil
+}
+
+// inspect looks up a container's name and TTY setting.
+func (e *engine) inspect(ctx context.Context, id string) (container, error) {
+	var info struct {
+		ID     string
+		Name   string
+		Config struct{ Tty bool }
+	}
+	if err := e.getJSON(ctx, "/containers/"+url.PathEscape(id)+"/json", nil, &info); err != nil {
+		return container{}, err
+	}
+	return container{ID: info.ID, Name: strings.TrimPrefix(info.Name, "/"), TTY: info.Config.Tty}, nil
+}
+
+// starts streams the IDs of containers that start from now on and carry
+// every label, until ctx is done or the connection drops.
+func (e *engine) starts(ctx context.Context, labels []string, fn func(id string)) error {
+	q := labelFilters(labels, map[string][]string{"type": {"container"}, "event": {"start"}})
+	resp, err := e.get(ctx, "/events", q)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev struct {
+			Actor struct{ ID string }
+		}
+		if err := dec.Decode(&ev); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("docker: events: %w", err)
+		}
+		fn(ev.Actor.ID)
+	}
+}
+
+// logLine is one line a container wrote.
+type logLine struct {
+	Time      time.Time
+	Container container
+	Stream    string // "stdout" or "stderr"
+	Text      string // without the trailing newline
+}
+
+// follow streams c's log lines to fn, starting after since (or with the
+// last tail lines when since is zero), until the container stops or ctx is
+// done. Timestamps are requested so a caller can resume after a dropped
+// connection without repeating lines.
+func (e *engine) follow(ctx context.Context, c container, since time.Time, tail int, fn func(logLine)) error {
+	q := url.Values{"follow": {"1"}, "stdout": {"1"}, "stderr": {"1"}, "timestamps": {"1"}}
+	if since.IsZero() {
+		q.Set("tail", fmt.Sprint(tail))
+	} else {
+		// The API takes fractional seconds; since is inclusive, so step past
+		// the last line seen
+		t := since.Add(time.Nanosecond)
+		q.Set("since", fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond()))
+	}
+	resp, err := e.get(ctx, "/containers/"+url.PathEscape(c.ID)+"/logs", q)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	emit := func(stream, raw string) {
+		ts, text, _ := strings.Cut(raw, " ")
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			t, text = time.Now().UTC(), raw
+		}
+		fn(logLine{Time: t, Container: c, Stream: stream, Text: strings.TrimSuffix(text, "\r")})
+	}
+	if c.TTY {
+		return scanLines(resp.Body, func(s string) { emit("stdout", s) })
+	}
+	return demux(resp.Body, emit)
+}
+
+// scanLines calls fn for each line of r. Long lines are split at 1 MiB
+// rather than failing the stream.
+func scanLines(r io.Reader, fn func(string)) error {
+	br := bufio.NewReaderSize(r, 64<<10)
+	var long []byte
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			long = append(long, chunk...)
+			if len(long) >= 1<<20 {
+				fn(string(long))
+				long = long[:0]
+			}
+			continue
+		}
+		line := append(long, chunk...)
+		long = long[:0]
+		if len(line) > 0 {
+			fn(strings.TrimSuffix(string(line), "\n"))
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// demux splits the multiplexed log stream of a non-TTY container into
+// lines per stream. Each frame has an 8-byte header: the stream (1 stdout,
+// 2 stderr), three zero bytes, and the payload length, big-endian. A line
+// may span frames, so partial lines are kept per stream.
+func demux(r io.Reader, fn func(stream, line string)) error {
+	var hdr [8]byte
+	partial := map[string]*strings.Builder{"stdout": {}, "stderr": {}}
+	flush := func() {
+		for stream, b := range partial {
+			if b.Len() > 0 {
+				fn(stream, b.String())
+				b.Reset()
+			}
+		}
+	}
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			flush()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		stream := "stdout"
+		if hdr[0] == 2 {
+			stream = "stderr"
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(hdr[4:]))
+		if _, err := io.ReadFull(r, payload); err != nil {
+			flush()
+			return err
+		}
+		b := partial[stream]
+		for len(payload) > 0 {
+			i := bytes.IndexByte(payload, '\n')
+			if i < 0 {
+				b.Write(payload)
+				break
+			}
+			b.Write(payload[:i])
+			fn(stream, b.String())
+			b.Reset()
+			payload = payload[i+1:]
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The json-file logging driver writes one JSON object per line to
+// /var/lib/docker/containers/<id>/<id>-json.log; a line longer than 16 KiB
+// is split over several entries, only the last ending in a newline.
+type jsonFileEntry struct {
+	Log    string    `json:"log"`
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+}
+
+// tailJSONFiles follows every file matching pattern, picking up new files
+// as they appear, until ctx is done. Files present at startup are read
+// from the end (or from the start, with fromStart); later ones from the
+// start.
+func tailJSONFiles(ctx context.Context, pattern string, fromStart bool, lines chan<- logLine) error {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("-json-file %q: %w", pattern, err)
+	}
+	followed := make(map[string]bool)
+	done := make(chan string)
+	first := true
+	for {
+		matches, _ := filepath.Glob(pattern)
+		for _, p := range matches {
+			if followed[p] {
+				continue
+			}
+			followed[p] = true
+			c := containerFromPath(p)
+			log.Printf("following %s (%s)", c.Name, p)
+			j := &jsonFileJoiner{c: c, lines: lines, partial: make(map[string]string)}
+			go func(p string, atEnd bool) {
+				if err := tailFile(ctx, p, atEnd, j.entry); err != nil && ctx.Err() == nil {
+					log.Printf("%s: %v", p, err)
+				}
+				done <- p
+			}(p, first && !fromStart)
+		}
+		first = false
+		select {
+		case <-ctx.Done():
+			// Wait for the tails to stop sending
+			for n := len(followed); n > 0; n-- {
+				delete(followed, <-done)
+			}
+			return nil
+		case p := <-done:
+			delete(followed, p)
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// containerFromPath names the container a json-file log belongs to, from
+// the config.v2.json Docker keeps next to it.
+func containerFromPath(p string) container {
+	id := strings.TrimSuffix(filepath.Base(p), "-json.log")
+	c := container{ID: id, Name: id[:min(12, len(id))]}
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(p), "config.v2.json"))
+	if err != nil {
+		return c
+	}
+	var cfg struct{ Name string }
+	if json.Unmarshal(data, &cfg) == nil && cfg.Name != "" {
+		c.Name = strings.TrimPrefix(cfg.Name, "/")
+	}
+	return c
+}
+
+// jsonFileJoiner turns a file's entries into lines, joining the entries
+// the driver split at 16 KiB.
+type jsonFileJoiner struct {
+	c       container
+	lines   chan<- logLine
+	partial map[string]string // per stream
+}
+
+func (j *jsonFileJoiner) entry(raw []byte) {
+	var e jsonFileEntry
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return
+	}
+	text := j.partial[e.Stream] + e.Log
+	if !strings.HasSuffix(e.Log, "\n") {
+		j.partial[e.Stream] = text
+		return
+	}
+	delete(j.partial, e.Stream)
+	j.lines <- logLine{Time: e.Time, Container: j.c, Stream: e.Stream, Text: strings.TrimRight(text, "\r\n")}
+}
+
+// tailFile calls fn with each complete line appended to path until ctx is
+// done, starting at the end when atEnd. It reopens the file when the
+// driver rotates it (a new file at the path) or truncates it.
+func tailFile(ctx context.Context, path string, atEnd bool, fn func([]byte)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+	if atEnd {
+		if _, err := f.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+	}
+	br := bufio.NewReaderSize(f, 64<<10)
+	var partial []byte
+	missing := 0
+	for {
+		chunk, err := br.ReadSlice('\n')
+		partial = append(partial, chunk...)
+		if err == nil {
+			fn(partial)
+			partial = partial[:0]
+			continue
+		}
+		if !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+			return err
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		// At the end: wait for more, or for a rotation
+		sleep(ctx, 250*time.Millisecond)
+		if ctx.Err() != nil {
+			return nil
+		}
+		cur, err := os.Stat(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) && missing < 40 {
+				missing++ // mid-rotation, or the container was removed
+				continue
+			}
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		open, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		missing = 0
+		pos, _ := f.Seek(0, io.SeekCurrent)
+		if os.SameFile(cur, open) && cur.Size() >= pos {
+			continue
+		}
+		// Rotated or truncated: finish nothing half-read, start the new file
+		f.Close()
+		if f, err = os.Open(path); err != nil {
+			return err
+		}
+		br.Reset(f)
+		partial = partial[:0]
+	}
+}
+
+// readJournal reads `journalctl -o json` output from r, as written for the
+// journald logging driver, until r ends. Docker logs stdout at priority 6
+// and stderr at 3.
+func readJournal(r io.Reader, lines chan<- logLine) error {
+	dec := json.NewDecoder(r)
+	for {
+		var e map[string]json.RawMessage
+		if err := dec.Decode(&e); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("journal: %w", err)
+		}
+		id, name := journalString(e["CONTAINER_ID_FULL"]), journalString(e["CONTAINER_NAME"])
+		if id == "" {
+			id = journalString(e["CONTAINER_ID"])
+		}
+		if id == "" && name == "" {
+			continue // not a container's entry
+		}
+		l := logLine{Container: container{ID: id, Name: name}, Stream: "stdout", Text: strings.TrimRight(journalString(e["MESSAGE"]), "\r\n")}
+		if journalString(e["PRIORITY"]) == "3" {
+			l.Stream = "stderr"
+		}
+		if us, err := strconv.ParseInt(journalString(e["__REALTIME_TIMESTAMP"]), 10, 64); err == nil {
+			l.Time = time.UnixMicro(us).UTC()
+		}
+		lines <- l
+	}
+}
+
+// journalString decodes a journal field: a string, or an array of bytes
+// for values that aren't valid UTF-8.
+func journalString(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var b []byte
+	var ints []int
+	if json.Unmarshal(raw, &ints) == nil {
+		for _, n := range ints {
+			b = append(b, byte(n))
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,123 @@
+// Docker log scrubbing + Blindfold: Strip PII from container logs before
+// they reach the log platform, without touching the applications.
+//
+// A sidecar follows the logs of every container on the host (or those
+// with given labels) through the Docker Engine API, including containers
+// started later. Lines are redacted in small batches with pkg/redact, so
+// nothing reversible is kept, and forwarded as JSON lines for Vector,
+// Fluent Bit or any shipper that reads stdin. Hosts that log to files can
+// read the json-file driver's files instead, and journald hosts can pipe
+// in journalctl. A batch that fails to scrub is replaced by markers, never
+// forwarded as it was.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/redact"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/resilience"
+)
+
+// labels holds repeated -label flags.
+type labels []string
+
+func (l *labels) String() string     { return strings.Join(*l, ",") }
+func (l *labels) Set(v string) error { *l = append(*l, v); return nil }
+
+func main() {
+	_ = godotenv.Load()
+	log.SetFlags(0)
+	log.SetOutput(os.Stderr)
+	host := flag.String("docker-host", os.Getenv("DOCKER_HOST"), "Docker Engine address, unix:// or tcp:// (default: the local socket)")
+	var sel labels
+	flag.Var(&sel, "label", `follow only containers with this label, "key" or "key=value" (repeatable)`)
+	tail := flag.Int("tail", 0, "lines of existing logs to read from each container when attaching")
+	jsonFiles := flag.String("json-file", "", `read json-file driver logs matching this glob instead of the API, e.g. "/var/lib/docker/containers/*/*-json.log"`)
+	fromStart := flag.Bool("from-start", false, "with -json-file, read files present at startup from the start")
+	journal := flag.Bool("journal", false, "read `journalctl -o json` output from stdin instead of the API")
+	out := flag.String("out", "-", `where to write scrubbed JSON lines ("-" for stdout)`)
+	batch := flag.Int("batch", 200, "most lines per Detect call")
+	flushEvery := flag.Duration("flush", 250*time.Millisecond, "longest a line waits for its batch")
+	file := flag.String("policies", "", "policy file (YAML or JSON); empty = built-in policies only")
+	name := flag.String("policy", "strict", "policy to apply, by name")
+	rps := flag.Float64("rps", 10, "Detect calls per second (cloud mode) (0 = unlimited)")
+	runDemo := flag.Bool("demo", false, "follow two fake containers for a few seconds and exit")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *runDemo {
+		h, stopEngine, err := demoHost()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer stopEngine()
+		*host = h
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+	}
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := bfclient.FromEnv(pol.ClientOptions()...)
+	r := redact.New(pol.Wrap(resilience.Wrap(bf, resilience.DefaultPolicy(*rps))))
+
+	w := io.Writer(os.Stdout)
+	if *out != "-" {
+		f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	s := newScrubber(r, w, *batch, *flushEvery)
+	lines := make(chan logLine, *batch)
+	scrubbed := make(chan error, 1)
+	// Detect calls outlive the signal, so lines already read are flushed
+	go func() { scrubbed <- s.run(context.WithoutCancel(ctx), lines) }()
+
+	var readErr error
+	switch {
+	case *journal:
+		readErr = readJournal(os.Stdin, lines)
+	case *jsonFiles != "":
+		readErr = tailJSONFiles(ctx, *jsonFiles, *fromStart, lines)
+	default:
+		eng, err := newEngine(*host)
+		if err != nil {
+			log.Fatal(err)
+		}
+		a := &attacher{eng: eng, labels: sel, tail: *tail, self: selfID(), lines: lines,
+			active: make(map[string]bool), last: make(map[string]time.Time)}
+		readErr = a.run(ctx)
+	}
+	close(lines)
+	if err := <-scrubbed; err != nil {
+		log.Fatal(err)
+	}
+	s.summary(os.Stderr)
+	if readErr != nil {
+		log.Fatal(readErr)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/redact"
+)
+
+// droppedText replaces a line that couldn't be scrubbed. Forwarding it as
+// it is would defeat the sidecar, and dropping it silently would hide
+// the gap.
+const droppedText = "[dropped: PII scrubbing failed]"
+
+// Record is one forwarded log line, as a JSON line.
+type Record struct {
+	Time        time.Time      `json:"time"`
+	Container   string         `json:"container"`
+	ContainerID string         `json:"container_id"`
+	Stream      string         `json:"stream"`
+	Log         redact.Text    `json:"log"`
+	Redacted    map[string]int `json:"redacted,omitempty"`
+	Dropped     bool           `json:"dropped,omitempty"`
+}
+
+// droppedRecord is written in place of a line whose scrubbing failed.
+type droppedRecord struct {
+	Record
+	Log string `json:"log"`
+}
+
+// scrubber redacts lines in batches: it waits at most flushEvery for up
+// to batchSize lines and redacts them with one Detect call, which keeps
+// cloud mode to a request per batch rather than per line.
+type scrubber struct {
+	r          *redact.Redactor
+	enc        *json.Encoder
+	batchSize  int
+	flushEvery time.Duration
+
+	lines, dropped int
+	totals         map[string]int
+}
+
+func newScrubber(r *redact.Redactor, w io.Writer, batchSize int, flushEvery time.Duration) *scrubber {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &scrubber{r: r, enc: enc, batchSize: batchSize, flushEvery: flushEvery, totals: make(map[string]int)}
+}
+
+// run scrubs and writes lines from in until it is closed. Lines still
+// buffered when in closes are flushed, so a shutdown loses nothing already
+// read. ctx only bounds the Detect calls.
+func (s *scrubber) run(ctx context.Context, in <-chan logLine) error {
+	batch := make([]logLine, 0, s.batchSize)
+	timer := time.NewTimer(s.flushEvery)
+	timer.Stop()
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := s.write(ctx, batch)
+		batch = batch[:0]
+		return err
+	}
+	for {
+		select {
+		case l, ok := <-in:
+			if !ok {
+				return flush()
+			}
+			if len(batch) == 0 {
+				timer.Reset(s.flushEvery)
+			}
+			batch = append(batch, l)
+			if len(batch) < s.batchSize {
+				continue
+			}
+			timer.Stop()
+		case <-timer.C:
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// write redacts a batch and writes its records in order.
+func (s *scrubber) write(ctx context.Context, batch []logLine) error {
+	texts := s.redact(ctx, batch)
+	for i, l := range batch {
+		base := Record{Time: l.Time, Container: l.Container.Name, ContainerID: l.Container.short(), Stream: l.Stream}
+		var err error
+		if texts == nil {
+			base.Dropped = true
+			err = s.enc.Encode(droppedRecord{Record: base, Log: droppedText})
+			s.dropped++
+		} else {
+			base.Log = texts[i]
+			base.Redacted = texts[i].Counts()
+			err = s.enc.Encode(base)
+			for typ, n := range base.Redacted {
+				s.totals[typ] += n
+			}
+		}
+		if err != nil {
+			return err
+		}
+		s.lines++
+	}
+	return nil
+}
+
+// redact redacts the batch's lines with a single Detect call. It returns
+// nil if that fails, and the whole batch is dropped.
+func (s *scrubber) redact(ctx context.Context, batch []logLine) []redact.Text {
+	texts := make([]string, len(batch))
+	for i, l := range batch {
+		texts[i] = l.Text
+	}
+	out, err := s.r.RedactEach(ctx, texts)
+	if err != nil {
+		log.Printf("scrub: %d lines dropped: %v", len(batch), err)
+		return nil
+	}
+	return out
+}
+
+// summary prints line counts and redactions by type.
+func (s *scrubber) summary(w io.Writer) {
+	types := make([]string, 0, len(s.totals))
+	for typ, n := range s.totals {
+		types = append(types, fmt.Sprintf("%s ×%d", typ, n))
+	}
+	sort.Strings(types)
+	if len(types) == 0 {
+		types = []string{"nothing"}
+	}
+	fmt.Fprintf(w, "Forwarded %d lines (%d dropped). Redacted: %s\n", s.lines, s.dropped, strings.Join(types, ", "))
+}
@@ -0,0 +1,102 @@
+// Per-entity-type strategies + Blindfold: Tokenize names, mask SSNs, drop
+// card numbers and keep dates, in one pass driven by the policy file.
+//
+// Real policies are never uniform: the LLM needs to address the customer,
+// an agent needs the last four of an SSN, nobody needs the card number,
+// and the date of birth decides eligibility. Each entity type's action in
+// policies.yaml is applied to the tokenized text; only tokenized values
+// stay in the mapping and come back in the reply.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/masking"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+const ticket = "Ticket from Ms. Dana Whitfield (dana.whitfield@example.com, 415-555-0199). " +
+	"Date of birth: 1984-03-12. She asks whether her plan covers her two dependants. " +
+	"For verification she gave SSN 123-45-6789 and paid with card 4111 1111 1111 1111. " +
+	"Please reply to Ms. Dana Whitfield by email."
+
+const systemPrompt = "You are a benefits support agent. Draft a short reply to the ticket. " +
+	"Keep placeholders like <Person_1> and values like HASH_1a2b3c4d5e6f exactly as they are."
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply (default: the file's default)")
+	dryRun := flag.Bool("dry-run", false, "apply strategies only; skip OpenAI calls")
+	flag.Parse()
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// With the same key, a value gets the same hash in every run
+	hashKey := []byte(os.Getenv("HASH_KEY"))
+	if len(hashKey) == 0 {
+		hashKey = make([]byte, 32)
+		if _, err := rand.Read(hashKey); err != nil {
+			log.Fatal(err)
+		}
+	}
+	s := &Strategies{policy: pol, masks: masking.Defaults(), hashKey: hashKey}
+
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+	oa := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
+
+	res, err := bf.Tokenize(ctx, ticket)
+	if err != nil {
+		log.Fatal(err)
+	}
+	applied := s.Apply(res)
+
+	types := make([]string, 0, len(applied.Actions))
+	for typ := range applied.Actions {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	fmt.Println("Actions:")
+	for _, typ := range types {
+		fmt.Printf("  %-24s %s\n", typ, applied.Actions[typ])
+	}
+	fmt.Printf("\nOriginal: %s\n\nSent:     %s\n", ticket, applied.Text)
+	fmt.Printf("\nRestorable: %d token(s); masked, hashed, redacted and dropped values are gone\n", len(applied.Mapping))
+	if *dryRun {
+		return
+	}
+
+	completion, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: openai.GPT4oMini,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: applied.Text},
+		},
+	})
+	if err != nil {
+		log.Fatalf("openai: %v", err)
+	}
+	if len(completion.Choices) == 0 {
+		log.Fatal("openai: empty response")
+	}
+	reply := completion.Choices[0].Message.Content
+	fmt.Printf("\nReply:\n%s\n", bf.Detokenize(reply, applied.Mapping).Text)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/masking"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/redact"
+)
+
+// dropped marks where a dropped value was, so the space before it can go
+// too.
+const dropped = "\x00"
+
+var droppedRun = regexp.MustCompile(`[ \t]*` + dropped)
+
+// Strategies applies a policy's action per entity type to tokenized text.
+type Strategies struct {
+	policy  *policyconf.Policy
+	masks   masking.Rules
+	hashKey []byte
+}
+
+// Applied is text with every strategy applied. Mapping holds the tokenized
+// values only: nothing else can be restored.
+type Applied struct {
+	Text    string
+	Mapping map[string]string
+	Actions map[string]policyconf.Action // entity type → action applied
+}
+
+// hash returns a stable, keyed hash of value: the same email in two
+// tickets links them, but without the key it can't be guessed back.
+func (s *Strategies) hash(value string) string {
+	mac := hmac.New(sha256.New, s.hashKey)
+	mac.Write([]byte(value))
+	return "HASH_" + hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// Apply rewrites every token in res in a single pass, by the action the
+// policy gives its entity type.
+func (s *Strategies) Apply(res *blindfold.TokenizeResponse) *Applied {
+	out := &Applied{Mapping: make(map[string]string), Actions: make(map[string]policyconf.Action)}
+	text := mapping.ReplaceTokens(res.Text, func(token string) string {
+		value, ok := res.Mapping[token]
+		if !ok {
+			return token
+		}
+		typ, _, _ := mapping.ParseToken(token)
+		action := s.policy.Action(typ)
+		out.Actions[typ] = action
+		switch action {
+		case policyconf.Mask:
+			return s.masks.Mask(typ, value)
+		case policyconf.Redact:
+			return redact.Label(typ)
+		case policyconf.Hash:
+			return s.hash(value)
+		case policyconf.Drop:
+			return dropped
+		case policyconf.Keep:
+			return value
+		}
+		out.Mapping[token] = value
+		return token
+	})
+	out.Text = droppedRun.ReplaceAllString(text, "")
+	return out
+}
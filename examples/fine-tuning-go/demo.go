@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+// fakeFineTuning stands in for the OpenAI files, fine-tuning and chat
+// endpoints. A job runs through a few statuses on successive polls, and
+// the "fine-tuned model" answers in the style of the training data,
+// carrying the placeholders of the question into its reply. It records
+// the training file it was sent.
+type fakeFineTuning struct {
+	mu       sync.Mutex
+	training []byte
+	polls    int
+	job      openai.FineTuningJob
+}
+
+func newFakeFineTuning() (*httptest.Server, *fakeFineTuning) {
+	f := &fakeFineTuning{}
+	return httptest.NewServer(f), f
+}
+
+func (f *fakeFineTuning) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	reply := func(v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	switch path := strings.TrimPrefix(r.URL.Path, "/v1"); {
+	case r.Method == http.MethodPost && path == "/files":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		if r.FormValue("purpose") == "fine-tune" && f.training == nil {
+			f.training = data
+		}
+		reply(map[string]any{"id": fmt.Sprintf("file-demo%d", len(data)), "object": "file", "bytes": len(data), "purpose": r.FormValue("purpose")})
+	case r.Method == http.MethodPost && path == "/fine_tuning/jobs":
+		var req openai.FineTuningJobRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.job = openai.FineTuningJob{ID: "ftjob-demo1", Object: "fine_tuning.job", Model: req.Model, Status: "validating_files",
+			TrainingFile: req.TrainingFile, ValidationFile: req.ValidationFile, CreatedAt: time.Now().Unix()}
+		reply(f.job)
+	case r.Method == http.MethodGet && path == "/fine_tuning/jobs/"+f.job.ID:
+		f.polls++
+		switch f.polls {
+		case 1:
+			f.job.Status = "queued"
+		case 2:
+			f.job.Status = "running"
+		default:
+			f.job.Status = "succeeded"
+			f.job.FineTunedModel = "ft:" + f.job.Model + ":acme::demo1234"
+			f.job.TrainedTokens = 3 * len(f.training) / 4
+		}
+		reply(f.job)
+	case r.Method == http.MethodPost && path == "/chat/completions":
+		fakeopenai.Handler(func(req openai.ChatCompletionRequest) string {
+			return answer(fakeopenai.Prompt(req))
+		}).ServeHTTP(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// answer writes the reply a model trained on the sample conversations
+// might give: each placeholder in the question comes back, unchanged, in
+// a sentence for its type.
+func answer(question string) string {
+	parts := []string{"Thanks for reaching out."}
+	for _, tok := range dedupe(mapping.TokenPattern.FindAllString(question, -1)) {
+		typ, _, _ := mapping.ParseToken(tok)
+		switch typ {
+		case "Email Address":
+			parts = append(parts, "The receipt is on its way to "+tok+".")
+		case "Phone Number":
+			parts = append(parts, "We'll text "+tok+" as soon as it ships.")
+		case "Credit Card Number":
+			parts = append(parts, "I can see the charge on "+tok+".")
+		default:
+			parts = append(parts, "I've noted "+tok+" on your account.")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func dedupe(tokens []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, t := range tokens {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
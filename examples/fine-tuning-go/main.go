@@ -0,0 +1,254 @@
+// Fine-tuning + Blindfold: Fine-tune a support model on logged
+// conversations without training it on any customer's data.
+//
+// Logged conversations are scrubbed, turned into the chat fine-tuning
+// format and checked for leftovers, then uploaded and trained on. The
+// model learns the placeholders, not the values: trained on
+// "receipt sent to <Email Address_1>", it answers a new customer with
+// that customer's token, which the tokenize call for that request can
+// restore. The last step shows exactly that, with a fresh mapping.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+// prepare turns the logged conversations into training (and optionally
+// validation) files.
+func prepare(ctx context.Context, bf bfclient.Client, in, trainPath, valPath string, valFraction float64) (train, val []byte, err error) {
+	convs, err := loadConversations(in)
+	if err != nil {
+		return nil, nil, err
+	}
+	var examples []Example
+	counts := make(map[string]int)
+	skipped := 0
+	for _, c := range convs {
+		c, ok := trim(c)
+		if !ok {
+			skipped++
+			continue
+		}
+		ex, n, err := scrub(ctx, bf, c)
+		if err != nil {
+			// Fail closed: no file is written
+			return nil, nil, err
+		}
+		for typ, k := range n {
+			counts[typ] += k
+		}
+		examples = append(examples, ex)
+	}
+	fmt.Printf("1. Prepared %d examples from %d conversations (%d without a reply skipped)\n", len(examples), len(convs), skipped)
+	fmt.Printf("   Tokenized: %s\n", summarize(counts))
+
+	leaks, err := verify(ctx, bf, examples)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(leaks) > 0 {
+		return nil, nil, fmt.Errorf("detection still finds PII in the scrubbed examples:\n  %s", strings.Join(leaks, "\n  "))
+	}
+	fmt.Println("2. Verified: a second detection pass finds nothing outside placeholders")
+
+	nVal := int(float64(len(examples)) * valFraction)
+	if len(examples)-nVal < minExamples {
+		return nil, nil, fmt.Errorf("%d training examples; fine-tuning needs at least %d", len(examples)-nVal, minExamples)
+	}
+	if train, err = writeExamples(trainPath, examples[:len(examples)-nVal]); err != nil {
+		return nil, nil, err
+	}
+	if nVal > 0 {
+		if val, err = writeExamples(valPath, examples[len(examples)-nVal:]); err != nil {
+			return nil, nil, err
+		}
+	}
+	fmt.Printf("3. Wrote %s (%d examples)", trainPath, len(examples)-nVal)
+	if nVal > 0 {
+		fmt.Printf(" and %s (%d)", valPath, nVal)
+	}
+	fmt.Println()
+	return train, val, nil
+}
+
+// train uploads the files, starts a job and waits for its model.
+func train(ctx context.Context, oa *openai.Client, trainData, valData []byte, model, suffix string, poll time.Duration) (string, error) {
+	file, err := oa.CreateFileBytes(ctx, openai.FileBytesRequest{Name: "train.jsonl", Bytes: trainData, Purpose: openai.PurposeFineTune})
+	if err != nil {
+		return "", fmt.Errorf("upload: %w", err)
+	}
+	req := openai.FineTuningJobRequest{TrainingFile: file.ID, Model: model, Suffix: suffix}
+	if len(valData) > 0 {
+		vf, err := oa.CreateFileBytes(ctx, openai.FileBytesRequest{Name: "validation.jsonl", Bytes: valData, Purpose: openai.PurposeFineTune})
+		if err != nil {
+			return "", fmt.Errorf("upload: %w", err)
+		}
+		req.ValidationFile = vf.ID
+	}
+	job, err := oa.CreateFineTuningJob(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("create job: %w", err)
+	}
+	fmt.Printf("4. Uploaded %s; started %s on %s\n", file.ID, job.ID, model)
+	return waitJob(ctx, oa, job.ID, poll)
+}
+
+// waitJob polls a fine-tuning job until it ends and returns its model.
+func waitJob(ctx context.Context, oa *openai.Client, id string, poll time.Duration) (string, error) {
+	last := ""
+	for {
+		job, err := oa.RetrieveFineTuningJob(ctx, id)
+		if err != nil {
+			return "", fmt.Errorf("job %s: %w", id, err)
+		}
+		if job.Status != last {
+			fmt.Printf("   %s\n", job.Status)
+			last = job.Status
+		}
+		switch job.Status {
+		case "succeeded":
+			fmt.Printf("5. Trained %s (%d tokens)\n", job.FineTunedModel, job.TrainedTokens)
+			return job.FineTunedModel, nil
+		case "failed", "cancelled":
+			return "", fmt.Errorf("job %s %s", id, job.Status)
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("stopped waiting; resume with -job %s: %w", id, ctx.Err())
+		case <-time.After(poll):
+		}
+	}
+}
+
+// infer asks the fine-tuned model about a new customer's message. The
+// message gets its own mapping, unrelated to anything in training, and
+// the reply restores with it.
+func infer(ctx context.Context, bf bfclient.Client, oa *openai.Client, model, prompt string) error {
+	tok, err := bf.Tokenize(ctx, prompt)
+	if err != nil {
+		return fmt.Errorf("tokenize: %w", err)
+	}
+	resp, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: tok.Text},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("chat: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return fmt.Errorf("chat: no choices")
+	}
+	raw := resp.Choices[0].Message.Content
+	fmt.Printf("\n6. Inference with %s\n", model)
+	fmt.Printf("   Customer:  %s\n", prompt)
+	fmt.Printf("   Sent:      %s\n", tok.Text)
+	fmt.Printf("   Model:     %s\n", raw)
+	fmt.Printf("   Restored:  %s\n", bf.Detokenize(raw, tok.Mapping).Text)
+	if missing := mapping.Unresolved(raw, tok.Mapping); len(missing) > 0 {
+		fmt.Printf("   Unresolved placeholders: %s\n", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func summarize(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "no entities"
+	}
+	parts := make([]string, 0, len(counts))
+	for typ, n := range counts {
+		parts = append(parts, fmt.Sprintf("%s ×%d", typ, n))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+func main() {
+	_ = godotenv.Load()
+	in := flag.String("in", "conversations.jsonl", "logged conversations (JSONL)")
+	trainPath := flag.String("train", "train.jsonl", "training file to write")
+	valPath := flag.String("validation", "validation.jsonl", "validation file to write, with -val")
+	valFraction := flag.Float64("val", 0, "fraction of examples held out for validation")
+	base := flag.String("model", "gpt-4o-mini-2024-07-18", "base model to fine-tune")
+	suffix := flag.String("suffix", "support", "suffix for the fine-tuned model's name")
+	poll := flag.Duration("poll", 30*time.Second, "how often to check the job")
+	prepareOnly := flag.Bool("prepare-only", false, "write the training files and stop")
+	jobID := flag.String("job", "", "wait for an existing job instead of starting one")
+	tuned := flag.String("use-model", "", "skip training and run inference with this fine-tuned model")
+	prompt := flag.String("prompt", "Hi, please send the receipt for order 7731 to sam.lee@example.com and text me at 628-555-0147 when it ships.", "new customer message for the inference step")
+	file := flag.String("policies", "", "policy file (YAML or JSON); empty = built-in policies only")
+	name := flag.String("policy", "strict", "policy to apply, by name")
+	runDemo := flag.Bool("demo", false, "run every step against an in-process fake API")
+	flag.Parse()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+
+	key := os.Getenv("OPENAI_API_KEY")
+	cfg := openai.DefaultConfig(key)
+	var fake *fakeFineTuning
+	if *runDemo {
+		srv, f := newFakeFineTuning()
+		defer srv.Close()
+		cfg.BaseURL, fake = srv.URL+"/v1", f
+		*poll = 100 * time.Millisecond
+	} else if key == "" && !*prepareOnly {
+		log.Fatal("OPENAI_API_KEY is not set (use -prepare-only or -demo to try it without one)")
+	}
+	oa := openai.NewClientWithConfig(cfg)
+
+	model := *tuned
+	switch {
+	case model != "":
+	case *jobID != "":
+		if model, err = waitJob(ctx, oa, *jobID, *poll); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		trainData, valData, err := prepare(ctx, bf, *in, *trainPath, *valPath, *valFraction)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *prepareOnly {
+			return
+		}
+		if model, err = train(ctx, oa, trainData, valData, *base, *suffix, *poll); err != nil {
+			log.Fatal(err)
+		}
+		if fake != nil {
+			first, _, _ := bytes.Cut(fake.training, []byte("\n"))
+			fmt.Printf("   The API received, for example:\n   %s\n", first)
+		}
+	}
+	if err := infer(ctx, bf, oa, model, *prompt); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// systemPrompt opens every training example and every inference request,
+// so the model learns to treat placeholders as opaque values.
+const systemPrompt = "You are Acme's support agent. Reply briefly and concretely. " +
+	"Customer details appear as placeholders like <Email Address_1>; " +
+	"use them exactly as written and never invent details."
+
+// minExamples is the fine-tuning API's minimum training set.
+const minExamples = 10
+
+// Conversation is one logged support conversation.
+type Conversation struct {
+	ID        string `json:"id"`
+	Channel   string `json:"channel"`
+	StartedAt string `json:"started_at"`
+	Turns     []Turn `json:"turns"`
+}
+
+// Turn is one message of a conversation.
+type Turn struct {
+	Speaker string `json:"speaker"` // "customer" or "agent"
+	Text    string `json:"text"`
+}
+
+// Example is one line of a chat fine-tuning file.
+type Example struct {
+	Messages []Message `json:"messages"`
+}
+
+// Message is a message of an Example. openai.ChatCompletionMessage would
+// do, but its JSON escapes the < and > of every placeholder.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// loadConversations reads conversation logs, one JSON object per line.
+func loadConversations(path string) ([]Conversation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var out []Conversation
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	for n := 1; sc.Scan(); n++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var c Conversation
+		if err := json.Unmarshal(sc.Bytes(), &c); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		out = append(out, c)
+	}
+	return out, sc.Err()
+}
+
+// trim drops trailing customer turns, which have no reply to learn from,
+// and reports whether an agent reply is left.
+func trim(c Conversation) (Conversation, bool) {
+	for len(c.Turns) > 0 && c.Turns[len(c.Turns)-1].Speaker != "agent" {
+		c.Turns = c.Turns[:len(c.Turns)-1]
+	}
+	for _, t := range c.Turns {
+		if t.Speaker == "customer" {
+			return c, true
+		}
+	}
+	return c, false
+}
+
+// scrub tokenizes a conversation into a training example. All turns share
+// one mapping (mapping.Merge), so the reply that repeats a customer's
+// email uses the customer's token: the model learns to carry placeholders
+// from question to answer. The mapping is then thrown away; training data
+// never needs restoring.
+func scrub(ctx context.Context, bf bfclient.Client, c Conversation) (Example, map[string]int, error) {
+	tokenized := make([]string, len(c.Turns))
+	mappings := make([]map[string]string, len(c.Turns))
+	counts := make(map[string]int)
+	for i, t := range c.Turns {
+		res, err := bf.Tokenize(ctx, t.Text)
+		if err != nil {
+			return Example{}, nil, fmt.Errorf("%s: %w", c.ID, err)
+		}
+		tokenized[i], mappings[i] = res.Text, res.Mapping
+		for _, e := range res.DetectedEntities {
+			counts[e.Type]++
+		}
+	}
+	merged := mapping.Merge(mappings...)
+	ex := Example{Messages: []Message{{Role: openai.ChatMessageRoleSystem, Content: systemPrompt}}}
+	for i, t := range c.Turns {
+		role := openai.ChatMessageRoleUser
+		if t.Speaker == "agent" {
+			role = openai.ChatMessageRoleAssistant
+		}
+		ex.Messages = append(ex.Messages, Message{Role: role, Content: merged.Rewrite(i, tokenized[i])})
+	}
+	return ex, counts, nil
+}
+
+// verify runs detection over the scrubbed examples once more, with the
+// placeholders blanked out, and returns what it still finds. Training
+// data can't be recalled from a model, so the files are checked before
+// they leave.
+func verify(ctx context.Context, bf bfclient.Client, examples []Example) ([]string, error) {
+	var leaks []string
+	for i, ex := range examples {
+		for _, m := range ex.Messages[1:] {
+			text := mapping.TokenPattern.ReplaceAllStringFunc(m.Content, func(tok string) string {
+				return strings.Repeat(" ", len(tok))
+			})
+			res, err := bf.Detect(ctx, text)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range res.DetectedEntities {
+				leaks = append(leaks, fmt.Sprintf("example %d (%s): %s", i+1, m.Role, e.Type))
+			}
+		}
+	}
+	return leaks, nil
+}
+
+// writeExamples writes examples as a JSONL fine-tuning file and returns
+// its bytes.
+func writeExamples(path string, examples []Example) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	for _, ex := range examples {
+		if err := enc.Encode(ex); err != nil {
+			return nil, err
+		}
+	}
+	return b.Bytes(), os.WriteFile(path, b.Bytes(), 0o644)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// Client replaces Blindfold tokens with fake values of the same shape: a
+// fake email for an email, a Luhn-valid fake card for a card. The mapping
+// it returns is keyed by the fakes, so Detokenize restores them like
+// tokens.
+//
+// Fakes are derived from the value with an HMAC under the client's key,
+// so a value gets the same fake on every call and from every client with
+// the same key. A fake is never a value the client has seen, and never
+// text that already appears in the input.
+type Client struct {
+	next bfclient.Client
+	key  []byte
+
+	mu      sync.Mutex
+	byValue map[string]string // entity type + value → fake
+	byFake  map[string]string // fake → value
+	values  map[string]bool   // every value faked so far
+}
+
+var _ bfclient.Client = (*Client)(nil)
+
+// Wrap returns next with format-preserving tokens derived under key.
+func Wrap(next bfclient.Client, key []byte) *Client {
+	return &Client{
+		next:    next,
+		key:     key,
+		byValue: make(map[string]string),
+		byFake:  make(map[string]string),
+		values:  make(map[string]bool),
+	}
+}
+
+// Detect passes through to the wrapped client.
+func (c *Client) Detect(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.DetectResponse, error) {
+	return c.next.Detect(ctx, text, opts...)
+}
+
+// Tokenize tokenizes with the wrapped client, then swaps every token for a
+// fake of the same entity type and shape.
+func (c *Client) Tokenize(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	res, err := c.next.Tokenize(ctx, text, opts...)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]string, 0, len(res.Mapping))
+	for token := range res.Mapping {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+
+	toFake := make(map[string]string, len(tokens))
+	out := &blindfold.TokenizeResponse{
+		Mapping:          make(map[string]string, len(tokens)),
+		DetectedEntities: res.DetectedEntities,
+		EntitiesCount:    res.EntitiesCount,
+	}
+	c.mu.Lock()
+	for _, token := range tokens {
+		typ, _, _ := mapping.ParseToken(token)
+		value := res.Mapping[token]
+		fake := c.fake(typ, value, text)
+		toFake[token] = fake
+		out.Mapping[fake] = value
+	}
+	c.mu.Unlock()
+	out.Text = mapping.Detokenize(res.Text, toFake)
+	return out, nil
+}
+
+// Detokenize restores the fakes in text.
+func (c *Client) Detokenize(text string, m map[string]string) *blindfold.DetokenizeResponse {
+	n := 0
+	for fake := range m {
+		n += strings.Count(text, fake)
+	}
+	return &blindfold.DetokenizeResponse{Text: mapping.Detokenize(text, m), ReplacementsMade: n}
+}
+
+// fake returns the fake for value, deriving a new one if needed. c.mu
+// must be held.
+func (c *Client) fake(entityType, value, text string) string {
+	key := entityType + "\x00" + value
+	if f, ok := c.byValue[key]; ok {
+		return f
+	}
+	for attempt := 0; ; attempt++ {
+		f := synthesize(entityType, value, c.rng(key, attempt))
+		if _, taken := c.byFake[f]; taken || f == value || c.values[f] || strings.Contains(text, f) {
+			continue
+		}
+		c.byValue[key] = f
+		c.byFake[f] = value
+		c.values[value] = true
+		return f
+	}
+}
+
+// rng seeds a generator from the HMAC of key and attempt.
+func (c *Client) rng(key string, attempt int) *rand.Rand {
+	mac := hmac.New(sha256.New, c.key)
+	fmt.Fprintf(mac, "%s\x00%d", key, attempt)
+	seed := binary.BigEndian.Uint64(mac.Sum(nil))
+	return rand.New(rand.NewSource(int64(seed)))
+}
+
+// synthesize builds a fake of the same shape as value.
+func synthesize(entityType, value string, r *rand.Rand) string {
+	switch entityType {
+	case blindfold.EntityEmailAddress:
+		return fakeEmail(value, r)
+	case blindfold.EntityCreditCard:
+		return fakeCard(value, r)
+	case blindfold.EntityIPAddress:
+		// A documentation range (RFC 5737)
+		return fmt.Sprintf("203.0.113.%d", 1+r.Intn(254))
+	case blindfold.EntityPhoneNumber:
+		return fakePhone(value, r)
+	default:
+		return reshape(value, 0, r)
+	}
+}
+
+// reshape replaces the letters and digits of s after the first keep with
+// random ones of the same class and case.
+func reshape(s string, keep int, r *rand.Rand) string {
+	var b strings.Builder
+	i := 0
+	for _, ch := range s {
+		if unicode.IsLetter(ch) || unicode.IsDigit(ch) {
+			if i >= keep {
+				switch {
+				case unicode.IsDigit(ch):
+					ch = rune('0' + r.Intn(10))
+				case unicode.IsUpper(ch):
+					ch = rune('A' + r.Intn(26))
+				default:
+					ch = rune('a' + r.Intn(26))
+				}
+			}
+			i++
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
+
+// fakeEmail keeps the shape of the local part and moves the address to
+// example.com, which is reserved and never receives mail.
+func fakeEmail(value string, r *rand.Rand) string {
+	local, _, ok := strings.Cut(value, "@")
+	if !ok {
+		return reshape(value, 0, r)
+	}
+	return strings.ToLower(reshape(local, 0, r)) + "@example.com"
+}
+
+// fakeCard keeps the first digit, so the card network stays the same,
+// randomizes the rest, and sets a valid Luhn check digit.
+func fakeCard(value string, r *rand.Rand) string {
+	s := []byte(reshape(value, 1, r))
+	var pos []int
+	for i, ch := range s {
+		if '0' <= ch && ch <= '9' {
+			pos = append(pos, i)
+		}
+	}
+	if len(pos) < 2 {
+		return string(s)
+	}
+	sum := 0
+	for k := 0; k < len(pos)-1; k++ {
+		d := int(s[pos[len(pos)-2-k]] - '0')
+		if k%2 == 0 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	s[pos[len(pos)-1]] = byte('0' + (10-sum%10)%10)
+	return string(s)
+}
+
+// fakePhone keeps an international prefix (+1, +44) and the formatting,
+// and randomizes the rest. The first random digit is 2-9: area codes and
+// national numbers don't start with 0 or 1.
+func fakePhone(value string, r *rand.Rand) string {
+	keep := 0
+	if strings.HasPrefix(value, "+") {
+		cc, _, _ := strings.Cut(value[1:], " ")
+		if len(cc) <= 3 {
+			keep = len(cc)
+		}
+	}
+	s := []byte(reshape(value, keep, r))
+	n := 0
+	for i, ch := range s {
+		if '0' <= ch && ch <= '9' {
+			if n == keep {
+				s[i] = byte('2' + r.Intn(8))
+				break
+			}
+			n++
+		}
+	}
+	return string(s)
+}
@@ -0,0 +1,91 @@
+// Format-preserving tokens + Blindfold: Placeholders that look like the
+// real thing.
+//
+// <Email Address_1> fails an email validator, and models sometimes treat
+// angle-bracket tokens as markup to fix or drop. This example swaps every
+// Blindfold token for a fake of the same shape — a fake address at
+// example.com, a Luhn-valid card number, a phone number with the same
+// formatting — so downstream validators pass and the LLM reads the text
+// naturally. The mapping is keyed by the fakes, so the reply is restored as
+// usual.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+)
+
+const sample = "Customer john.smith@acme.com (+1 415-555-0134) says card 4111 1111 1111 1111 " +
+	"was charged twice; SSN on file 123-45-6789, last login from 192.168.20.14."
+
+const systemPrompt = "You are a support agent. Write a two-sentence reply to the customer " +
+	"that repeats their contact email and the card it concerns, so they can confirm."
+
+func main() {
+	_ = godotenv.Load()
+	dryRun := flag.Bool("dry-run", false, "tokenize only; skip the OpenAI call")
+	flag.Parse()
+	ctx := context.Background()
+
+	// The key decides every fake. Keep it fixed (FPE_KEY) for fakes that are
+	// stable across runs; a random one is stable for this process only
+	key := []byte(os.Getenv("FPE_KEY"))
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Fatal(err)
+		}
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := Wrap(bfclient.FromEnv(), key)
+
+	tokenized, err := bf.Tokenize(ctx, sample)
+	if err != nil {
+		log.Fatalf("tokenize: %v", err)
+	}
+	fmt.Printf("Original:  %s\n", sample)
+	fmt.Printf("Protected: %s\n\n", tokenized.Text)
+	fakes := make([]string, 0, len(tokenized.Mapping))
+	for fake := range tokenized.Mapping {
+		fakes = append(fakes, fake)
+	}
+	sort.Strings(fakes)
+	for _, fake := range fakes {
+		fmt.Printf("  %-28s → %s\n", fake, tokenized.Mapping[fake])
+	}
+	if *dryRun {
+		return
+	}
+
+	oa := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
+	completion, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: openai.GPT4oMini,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: tokenized.Text},
+		},
+	})
+	if err != nil {
+		log.Fatalf("openai: %v", err)
+	}
+	if len(completion.Choices) == 0 {
+		log.Fatal("openai: empty response")
+	}
+	reply := completion.Choices[0].Message.Content
+	fmt.Printf("\nLLM reply: %s\n", reply)
+	fmt.Printf("Restored:  %s\n", bf.Detokenize(reply, tokenized.Mapping).Text)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+var testKey = []byte("format-preserving-test-key")
+
+func newClient() *Client {
+	return Wrap(blindfold.New(blindfold.WithMode("local")), testKey)
+}
+
+func tokenize(t *testing.T, c *Client, text string) *blindfold.TokenizeResponse {
+	t.Helper()
+	res, err := c.Tokenize(context.Background(), text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// fakeFor returns the fake standing in for value.
+func fakeFor(t *testing.T, res *blindfold.TokenizeResponse, value string) string {
+	t.Helper()
+	for fake, v := range res.Mapping {
+		if v == value {
+			return fake
+		}
+	}
+	t.Fatalf("no fake for %q in %v", value, res.Mapping)
+	return ""
+}
+
+func luhn(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		d := int(s[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func TestRoundTrip(t *testing.T) {
+	c := newClient()
+	res := tokenize(t, c, sample)
+
+	if len(res.Mapping) == 0 {
+		t.Fatal("nothing tokenized")
+	}
+	for fake, value := range res.Mapping {
+		if strings.Contains(res.Text, value) {
+			t.Errorf("protected text still contains %q", value)
+		}
+		if !strings.Contains(res.Text, fake) {
+			t.Errorf("protected text lacks fake %q", fake)
+		}
+	}
+	if tokens := mapping.TokenPattern.FindAllString(res.Text, -1); len(tokens) > 0 {
+		t.Errorf("protected text has tokens left: %v", tokens)
+	}
+	if got := c.Detokenize(res.Text, res.Mapping).Text; got != sample {
+		t.Errorf("round trip:\n got %q\nwant %q", got, sample)
+	}
+}
+
+func TestShapes(t *testing.T) {
+	res := tokenize(t, newClient(), sample)
+
+	shapes := map[string]*regexp.Regexp{
+		"john.smith@acme.com": regexp.MustCompile(`^[a-z]{4}\.[a-z]{5}@example\.com$`),
+		"+1 415-555-0134":     regexp.MustCompile(`^\+1 [2-9]\d{2}-\d{3}-\d{4}$`),
+		"4111 1111 1111 1111": regexp.MustCompile(`^4\d{3} \d{4} \d{4} \d{4}$`),
+		"123-45-6789":         regexp.MustCompile(`^\d{3}-\d{2}-\d{4}$`),
+		"192.168.20.14":       regexp.MustCompile(`^203\.0\.113\.\d{1,3}$`),
+	}
+	for value, shape := range shapes {
+		if fake := fakeFor(t, res, value); !shape.MatchString(fake) {
+			t.Errorf("fake for %q = %q, want shape %s", value, fake, shape)
+		}
+	}
+	if card := fakeFor(t, res, "4111 1111 1111 1111"); !luhn(card) {
+		t.Errorf("fake card %q fails the Luhn check", card)
+	}
+}
+
+func TestStableFakes(t *testing.T) {
+	c := newClient()
+	first := tokenize(t, c, "Email john.smith@acme.com today.")
+	second := tokenize(t, c, "Reply to john.smith@acme.com and jane@acme.com.")
+	other := tokenize(t, newClient(), "Email john.smith@acme.com today.")
+
+	want := fakeFor(t, first, "john.smith@acme.com")
+	if got := fakeFor(t, second, "john.smith@acme.com"); got != want {
+		t.Errorf("second call: fake %q, want %q", got, want)
+	}
+	if got := fakeFor(t, other, "john.smith@acme.com"); got != want {
+		t.Errorf("same key, new client: fake %q, want %q", got, want)
+	}
+	if fakeFor(t, second, "jane@acme.com") == want {
+		t.Error("two values share a fake")
+	}
+}
+
+func TestFakesAvoidInputText(t *testing.T) {
+	c := newClient()
+	// The fake for the email is known; put it in the input next to the
+	// real address, and a new client must derive another one
+	fake := fakeFor(t, tokenize(t, c, "john.smith@acme.com"), "john.smith@acme.com")
+	text := "Real: john.smith@acme.com, lookalike: " + fake
+	res := tokenize(t, newClient(), text)
+
+	if got := fakeFor(t, res, "john.smith@acme.com"); got == fake {
+		t.Errorf("fake %q collides with text already in the input", got)
+	}
+	if got := c.Detokenize(res.Text, res.Mapping).Text; got != text {
+		t.Errorf("round trip:\n got %q\nwant %q", got, text)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/gateway"
+)
+
+// serveDebug serves pprof and expvar on their own listener, apart from
+// the gateway's. Profiles and goroutine dumps describe the process, not
+// the requests, but they are for operators only: keep addr on loopback or
+// a management network.
+func serveDebug(addr string) {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			log.Printf("debug: %s is not a loopback address; make sure only operators can reach it", addr)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	log.Printf("debug: pprof and expvar on http://%s/debug/", addr)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("debug: %v", err)
+		}
+	}()
+}
+
+// logRuntime logs a summary of the last interval every interval: requests
+// served, bytes allocated in total and per request, heap in use, garbage
+// collections and their pauses, and goroutines. Allocation per request
+// rising with no change in traffic points at the payloads; GC pauses or
+// goroutines rising point at the process.
+func logRuntime(interval time.Duration, stats *gateway.Stats) {
+	var prev runtime.MemStats
+	runtime.ReadMemStats(&prev)
+	prevRequests := stats.Requests()
+	for range time.Tick(interval) {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		requests := stats.Requests()
+
+		n := requests - prevRequests
+		alloc := m.TotalAlloc - prev.TotalAlloc
+		perRequest := uint64(0)
+		if n > 0 {
+			perRequest = alloc / uint64(n)
+		}
+		gcs := m.NumGC - prev.NumGC
+		// PauseNs is a ring of the last 256 pauses
+		var maxPause uint64
+		for i := uint32(0); i < min(gcs, 256); i++ {
+			maxPause = max(maxPause, m.PauseNs[(m.NumGC-i+255)%256])
+		}
+		log.Printf("runtime: %d requests (%d in flight), alloc %s (%s/request), heap %s, %d GCs (pause %s total, %s max), %d goroutines",
+			n, stats.InFlight(), bytesize(alloc), bytesize(perRequest), bytesize(m.HeapAlloc),
+			gcs, time.Duration(m.PauseTotalNs-prev.PauseTotalNs), time.Duration(maxPause), runtime.NumGoroutine())
+		prev, prevRequests = m, requests
+	}
+}
+
+func bytesize(n uint64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,295 @@
+// OpenAI-compatible gateway + Blindfold: Protect every LLM call without changing app code.
+//
+// Runs an HTTP proxy that speaks the OpenAI API. Point any OpenAI SDK's
+// base URL at it: message contents are tokenized before they leave, and
+// responses — streamed or not — are detokenized on the way back.
+// Embeddings, moderations and image-generation requests are tokenized too. Prometheus
+// metrics for detection and detokenization are served at /metrics, and an
+// optional audit log records what each request contained — types and
+// tokens, never values. A live dashboard of both is served at
+// /dashboard/, and a spike in the PII per request of a route or tenant,
+// or a drop to none, is sent to a webhook or Slack. /healthz, /readyz and /configz serve load
+// balancers and operators. With -endpoints, cloud calls are spread over
+// several regions with health checks and failover, and chat requests are
+// hedged to a second region when the first is slow. With -debug-addr,
+// pprof profiles and expvar counters of requests and their stages are
+// served on a separate listener for diagnosing latency. On SIGTERM it
+// drains: it turns unready, lets requests in flight and streams finish
+// within -shutdown-timeout, and closes the audit log behind them.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/anomaly"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/audit"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/config"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/dashboard"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/gateway"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/health"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/metrics"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/resilience"
+)
+
+func main() {
+	// Every flag can also be set as GATEWAY_<FLAG> or in the -config file
+	cfg := config.New(flag.CommandLine, "GATEWAY")
+	addr := flag.String("addr", "127.0.0.1:8080", "listen address")
+	upstream := flag.String("upstream", gateway.DefaultUpstream, "OpenAI-compatible upstream base URL")
+	policiesFile := flag.String("policies", "", "policy file (YAML or JSON); empty = built-in policies only")
+	policy := flag.String("policy", "basic", "policy to apply, by name")
+	auditTarget := flag.String("audit", "", "audit sink: file path, \"-\" for stdout, or postgres:// URL (empty = off)")
+	endpointList := flag.String("endpoints", "", "cloud endpoints in order of preference, as name=url,name=url (empty = BLINDFOLD_BASE_URL or the default)")
+	hedgeAfter := flag.Duration("hedge-after", 300*time.Millisecond, "with -endpoints, send a chat request's tokenize call to the next region too if the first hasn't answered by then (0 = never)")
+	debugAddr := flag.String("debug-addr", "", "serve pprof and expvar (/debug/pprof/, /debug/vars) on this address (empty = off)")
+	runtimeEvery := flag.Duration("runtime-summary", 0, "log an allocation, GC and goroutine summary this often (0 = off)")
+	serverTiming := flag.Bool("server-timing", false, "send each request's stage times in a Server-Timing trailer, for cmd/loadtest")
+	tenantHeader := flag.String("tenant-header", "", "request header naming the caller's tenant, for audit events and alerts, such as X-Tenant-Id (empty = none)")
+	alertWindow := flag.Duration("alert-window", anomaly.DefaultWindow, "compare PII per request with the baseline of each route and tenant this often (0 = no alerts)")
+	drainDelay := flag.Duration("drain-delay", 0, "on SIGTERM, keep serving this long while /readyz reports draining, for load balancers to notice")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "on SIGTERM, how long requests in flight and streams get to finish")
+	cfg.Env("upstream", "OPENAI_BASE_URL")
+	cfg.Env("audit", "AUDIT_TARGET")
+	cfg.Env("endpoints", "BLINDFOLD_ENDPOINTS")
+	cfg.File("config")
+	cfg.Check("upstream", func() error {
+		if u, err := url.Parse(*upstream); err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.New("want an absolute URL, such as https://api.openai.com/v1")
+		}
+		return nil
+	})
+	cfg.Check("hedge-after", func() error {
+		if *hedgeAfter < 0 {
+			return errors.New("must not be negative")
+		}
+		return nil
+	})
+	cfg.Check("runtime-summary", func() error {
+		if *runtimeEvery < 0 {
+			return errors.New("must not be negative")
+		}
+		return nil
+	})
+	cfg.Check("alert-window", func() error {
+		if *alertWindow < 0 {
+			return errors.New("must not be negative")
+		}
+		return nil
+	})
+	cfg.Check("drain-delay", func() error {
+		if *drainDelay < 0 {
+			return errors.New("must not be negative")
+		}
+		return nil
+	})
+	cfg.Check("shutdown-timeout", func() error {
+		if *shutdownTimeout <= 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	})
+	if err := cfg.Parse(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	pol, err := policyconf.Resolve(*policiesFile, *policy)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// SIGTERM, as from a container runtime, starts the drain in serve; a
+	// second signal exits at once
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sink, err := audit.Open(ctx, *auditTarget)
+	if err != nil {
+		log.Fatalf("audit: %v", err)
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	m := metrics.New(reg)
+	// The dashboard and the anomaly detector see every audit event, after
+	// the audit log has it
+	dash := dashboard.New(reg)
+	var det *anomaly.Detector
+	if *alertWindow > 0 {
+		det = anomaly.New(notifiers()...)
+		det.Window = *alertWindow
+		go det.Run(ctx)
+	}
+
+	// API key is optional — omit it to run in local mode (regex-based, offline).
+	// In cloud mode, a failing API downgrades to local mode and counts a
+	// fallback event instead of failing requests.
+	var primary bfclient.Client = bfclient.FromEnv(append(pol.ClientOptions(), blindfold.WithMaxRetries(0))...)
+	var regions *resilience.Failover
+	if *endpointList != "" {
+		endpoints, err := parseEndpoints(*endpointList, append(pol.ClientOptions(), blindfold.WithMaxRetries(0))...)
+		if err != nil {
+			log.Fatalf("-endpoints: %v", err)
+		}
+		// Regions fail over to each other first; local mode is the last resort
+		regions = resilience.NewFailover(3, 30*time.Second, endpoints...)
+		regions.HedgeAfter = *hedgeAfter
+		regions.OnFailover, regions.OnHedge = m.ObserveFailover, m.ObserveHedge
+		go regions.Run(context.Background(), 15*time.Second)
+		primary = regions
+	}
+	bf := resilience.NewLocalFallback(primary, 3, 10*time.Second, pol.ClientOptions()...)
+	bf.OnFallback = m.ObserveFallback
+
+	// Request counts and time per stage, at /debug/vars with -debug-addr
+	stats := gateway.NewStats()
+	expvar.Publish("gateway", stats)
+	if *debugAddr != "" {
+		serveDebug(*debugAddr)
+	}
+	if *runtimeEvery > 0 {
+		go logRuntime(*runtimeEvery, stats)
+	}
+
+	gw := gateway.New(gateway.Config{
+		Blindfold: metrics.Wrap(pol.Wrap(bf), m),
+		Upstream:  *upstream,
+		APIKey:    os.Getenv("OPENAI_API_KEY"),
+		Policy:    pol.Name,
+		Metrics:   m,
+		Audit:     audit.Tee(sink, dash, sinkOf(det)),
+		Stats:     stats,
+
+		TenantHeader: *tenantHeader,
+		ServerTiming: *serverTiming,
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/v1/", gw)
+	mux.Handle("/metrics", metrics.Handler(reg))
+	mux.Handle("/dashboard/", http.StripPrefix("/dashboard", dash.Handler()))
+	if regions != nil {
+		mux.HandleFunc("/endpoints", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(regions.Status())
+		})
+	}
+
+	// /healthz, /readyz and /configz for the load balancer. Tokenizing
+	// works for as long as local mode does, so the cloud API and the
+	// regions are reported without making the gateway unready. The audit
+	// database is required: without it every request is refused
+	hc := health.NewService(flag.CommandLine, pol, pol.Wrap(bf))
+	cfgz := health.StandardConfig(flag.CommandLine, pol)
+	cfgz["sources"] = cfg.Sources()
+	hc.Config = cfgz
+	if os.Getenv("BLINDFOLD_API_KEY") != "" {
+		hc.AddInfo("cloud", func(context.Context) error {
+			if s := bf.Breaker.State(); s != resilience.Closed {
+				return fmt.Errorf("circuit %s: tokenizing in local mode", s)
+			}
+			return nil
+		})
+	}
+	if regions != nil {
+		hc.AddInfo("regions", func(context.Context) error {
+			var down []string
+			for _, s := range regions.Status() {
+				if !s.Healthy {
+					down = append(down, s.Name)
+				}
+			}
+			if len(down) > 0 {
+				return fmt.Errorf("unhealthy: %s", strings.Join(down, ", "))
+			}
+			return nil
+		})
+	}
+	if db, ok := sink.(interface{ Ping(context.Context) error }); ok {
+		hc.AddCheck("audit", db.Ping)
+	}
+	hc.Register(mux)
+
+	log.Printf("gateway listening on http://%s/v1 (upstream %s, policy %s)", *addr, *upstream, pol.Name)
+	srv := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	srv.RegisterOnShutdown(func() { dash.Close() })
+	serve(ctx, stop, srv, hc, stats, *drainDelay, *shutdownTimeout)
+
+	// Every handler has returned: flush the audit log to disk.
+	// Prometheus metrics are pulled, so the last scrape during the drain
+	// has them all
+	if sink != nil {
+		if err := sink.Close(); err != nil {
+			log.Printf("audit: %v", err)
+		}
+	}
+	log.Printf("gateway stopped after %d requests", stats.Requests())
+}
+
+// notifiers returns where alerts go besides the log: a webhook at
+// ALERT_WEBHOOK_URL, signed with ALERT_WEBHOOK_SECRET when set, and a
+// Slack incoming webhook at SLACK_WEBHOOK_URL. They are secrets, so they
+// aren't flags.
+func notifiers() []anomaly.Notifier {
+	var out []anomaly.Notifier
+	if u := os.Getenv("ALERT_WEBHOOK_URL"); u != "" {
+		out = append(out, anomaly.Webhook{URL: u, Secret: os.Getenv("ALERT_WEBHOOK_SECRET")})
+	}
+	if u := os.Getenv("SLACK_WEBHOOK_URL"); u != "" {
+		out = append(out, anomaly.Slack{URL: u})
+	}
+	return out
+}
+
+// sinkOf returns det as a sink, or nil without one: a nil *Detector in
+// an audit.Sink would not be skipped by audit.Tee.
+func sinkOf(det *anomaly.Detector) audit.Sink {
+	if det == nil {
+		return nil
+	}
+	return det
+}
+
+// parseEndpoints builds a cloud client per name=url pair. They need an API
+// key: without one the clients would run in local mode and never fail
+// over.
+func parseEndpoints(list string, opts ...blindfold.Option) ([]resilience.Endpoint, error) {
+	key := os.Getenv("BLINDFOLD_API_KEY")
+	if key == "" {
+		return nil, errors.New("cloud endpoints need BLINDFOLD_API_KEY")
+	}
+	var endpoints []resilience.Endpoint
+	for _, pair := range strings.Split(list, ",") {
+		name, base, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" || base == "" {
+			return nil, fmt.Errorf("%q: want name=url", pair)
+		}
+		client := blindfold.New(append([]blindfold.Option{blindfold.WithAPIKey(key), blindfold.WithBaseURL(base)}, opts...)...)
+		endpoints = append(endpoints, resilience.Endpoint{Name: name, Client: client})
+	}
+	return endpoints, nil
+}
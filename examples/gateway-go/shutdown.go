@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/gateway"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/health"
+)
+
+// serve runs srv until it fails or ctx is done, and then drains it:
+//
+//  1. /readyz answers 503 at once, and for delay the gateway keeps
+//     taking requests, so load balancers see it and route elsewhere
+//     before the listener closes;
+//  2. the listener closes, and requests in flight, streams included, get
+//     until timeout to finish, so a response already streaming is
+//     restored to its end instead of being cut off with tokens in it;
+//  3. whatever is still running after that is cut off.
+//
+// serve returns once no handler is left running, so the audit sink can be
+// closed behind it.
+func serve(ctx context.Context, stop context.CancelFunc, srv *http.Server, hc *health.Handler, stats *gateway.Stats, delay, timeout time.Duration) {
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	select {
+	case err := <-errc:
+		log.Print(err)
+		return
+	case <-ctx.Done():
+		stop()
+	}
+
+	hc.Drain()
+	log.Printf("shutting down: draining %d requests in flight", stats.InFlight())
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := srv.Shutdown(shutdown)
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Printf("shutdown: %d requests still running after %s; cutting them off", stats.InFlight(), timeout)
+		srv.Close()
+		// Closing the connections cancels the requests' contexts; give
+		// their handlers a moment to return and record what they did
+		for deadline := time.Now().Add(2 * time.Second); stats.InFlight() > 0 && time.Now().Before(deadline); {
+			time.Sleep(20 * time.Millisecond)
+		}
+	} else if err != nil {
+		log.Printf("shutdown: %v", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+const (
+	demoFolder  = "1DemoFolderQ3Ops"
+	demoRefresh = "1//demo-refresh-token"
+	// demoTokenUses is how many API calls an access token lasts, so the
+	// demo shows the client refreshing a token the API turned down
+	demoTokenUses = 8
+)
+
+// fakeDrive stands in for Google's OAuth token endpoint and the Drive
+// API: folder listing in pages, the changes feed, export and download,
+// comments, and uploads. Every edit is appended to a change log, and a
+// page token is a position in it.
+type fakeDrive struct {
+	mu       sync.Mutex
+	files    map[string]*fakeFile
+	log      []string // file IDs, one per change
+	token    string
+	uses     int
+	issued   int
+	comments int
+	docs     int
+}
+
+type fakeFile struct {
+	File
+	content  string
+	comments map[string]string
+}
+
+// newFakeDrive starts the fake and returns a client for it that holds a
+// refresh token, as a user's OAuth grant would.
+func newFakeDrive() (*fakeDrive, *drive, func()) {
+	fd := &fakeDrive{files: make(map[string]*fakeFile)}
+	at := time.Date(2026, 10, 13, 9, 0, 0, 0, time.UTC)
+	add := func(id, name, mimeType, content string, parent string) {
+		at = at.Add(47 * time.Minute)
+		fd.files[id] = &fakeFile{File: File{ID: id, Name: name, MimeType: mimeType, ModifiedTime: at.Format(time.RFC3339),
+			Parents: []string{parent}}, content: content, comments: make(map[string]string)}
+		fd.log = append(fd.log, id)
+	}
+	add("1DocEscalations", "Q3 customer escalations", docType,
+		"Q3 customer escalations\r\n\r\n"+
+			"1. Double charge, order 5531. Jane Doe (jane.doe@example.com) was charged twice on card 4111 1111 1111 1111. "+
+			"Refund approved by billing on 12 Sept; Jane wants written confirmation.\r\n\r\n"+
+			"2. Locked account. Omar Haddad could not sign in after a password reset for three days. Unlocked by support; "+
+			"root cause is the lockout rule, ticket open with the identity team.\r\n\r\n"+
+			"3. Damaged delivery, order 7710. Li Wei sent photos of a dented kettle. Replacement shipped; "+
+			"courier claim still open. Call back on 617-555-0123 when the claim is settled.\r\n", demoFolder)
+	add("1SheetRenewals", "Renewals tracker", sheetType,
+		"Customer,Contact,Email,Phone,Renewal date,ARR\r\n"+
+			"Northwind,Priya Patel,priya@northwind.example,212-555-0111,2026-11-01,48000\r\n"+
+			"Contoso,Aisha Khan,aisha.khan@contoso.example,(415) 555-0199,2026-11-15,120000\r\n"+
+			"Fabrikam,Omar Haddad,omar.haddad@example.com,+1 415 555 0142,2026-12-01,36000\r\n", demoFolder)
+	add("1TxtOnboarding", "onboarding-call-notes.txt", "text/plain",
+		"Onboarding call with Northwind, 9 Oct. Priya Patel wants SSO before rollout and asked for the admin guide "+
+			"at priya@northwind.example. Next call 16 Oct.\n", demoFolder)
+	add("1PdfContract", "Signed contract.pdf", "application/pdf", "%PDF-1.7", demoFolder)
+	add("1FolderArchive", "Archive", folderType, "", demoFolder)
+	add("1DocElsewhere", "Team offsite plan", docType, "Offsite in Lisbon, 3-5 Nov.", "1OtherFolder")
+
+	srv := httptest.NewServer(fd)
+	hc := srv.Client()
+	return fd, &drive{base: srv.URL, http: hc,
+		tokens: refreshToken(hc, srv.URL+"/token", "demo-client.apps.googleusercontent.com", "demo-secret", demoRefresh)}, srv.Close
+}
+
+// edit changes the folder between the demo's two runs, and describes
+// what it did.
+func (fd *fakeDrive) edit() string {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	touch := func(id string) *fakeFile {
+		f := fd.files[id]
+		f.ModifiedTime = time.Date(2026, 10, 14, 8, 30, len(fd.log), 0, time.UTC).Format(time.RFC3339)
+		fd.log = append(fd.log, id)
+		return f
+	}
+	f := touch("1DocEscalations")
+	f.content += "\r\n4. Wrong invoice address. Priya Patel (priya@northwind.example) asked for invoice 2291 to be reissued " +
+		"to the Boston office. Reissued today.\r\n"
+	touch("1TxtOnboarding").Trashed = true
+	touch("1DocElsewhere").content += " Bring laptops."
+	return "adds an escalation to Q3 customer escalations, moves onboarding-call-notes.txt to the trash " +
+		"and edits a doc in another folder"
+}
+
+func (fd *fakeDrive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	if r.URL.Path == "/token" {
+		if r.FormValue("grant_type") != "refresh_token" || r.FormValue("refresh_token") != demoRefresh {
+			fd.json(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "Bad Request"})
+			return
+		}
+		fd.issued++
+		fd.token, fd.uses = fmt.Sprintf("ya29.demo-%d", fd.issued), 0
+		fd.json(w, http.StatusOK, map[string]any{"access_token": fd.token, "expires_in": 3599, "token_type": "Bearer", "scope": driveScope})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+fd.token || fd.uses >= demoTokenUses {
+		fd.fail(w, http.StatusUnauthorized, "authError", "Request had invalid authentication credentials.")
+		return
+	}
+	fd.uses++
+
+	q := r.URL.Query()
+	path := strings.TrimPrefix(r.URL.Path, "/drive/v3")
+	switch {
+	case r.Method == http.MethodGet && path == "/files":
+		fd.list(w, q)
+	case r.Method == http.MethodGet && path == "/changes/startPageToken":
+		fd.json(w, http.StatusOK, map[string]string{"kind": "drive#startPageToken", "startPageToken": strconv.Itoa(len(fd.log) + 1)})
+	case r.Method == http.MethodGet && path == "/changes":
+		fd.changes(w, q)
+	case r.URL.Path == "/upload/drive/v3/files" && r.Method == http.MethodPost:
+		fd.upload(w, r)
+	case strings.HasPrefix(r.URL.Path, "/upload/drive/v3/files/") && r.Method == http.MethodPatch:
+		f := fd.files[strings.TrimPrefix(r.URL.Path, "/upload/drive/v3/files/")]
+		if f == nil {
+			fd.fail(w, http.StatusNotFound, "notFound", "File not found.")
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		f.content, f.ModifiedTime = string(body), time.Now().UTC().Format(time.RFC3339)
+		fd.log = append(fd.log, f.ID)
+		fd.json(w, http.StatusOK, map[string]string{"id": f.ID})
+	case strings.HasPrefix(path, "/files/"):
+		parts := strings.Split(strings.TrimPrefix(path, "/files/"), "/")
+		f := fd.files[parts[0]]
+		if f == nil || f.MimeType == folderType {
+			fd.fail(w, http.StatusNotFound, "notFound", "File not found: "+parts[0]+".")
+			return
+		}
+		fd.file(w, r, f, parts[1:])
+	default:
+		fd.fail(w, http.StatusNotFound, "notFound", "Not found.")
+	}
+}
+
+var parentsQuery = regexp.MustCompile(`^'([^']+)' in parents and trashed = false$`)
+
+// list answers a folder query, two files a page.
+func (fd *fakeDrive) list(w http.ResponseWriter, q map[string][]string) {
+	m := parentsQuery.FindStringSubmatch(first(q["q"]))
+	if m == nil {
+		fd.fail(w, http.StatusBadRequest, "invalid", "Invalid Value")
+		return
+	}
+	var files []File
+	for _, f := range fd.files {
+		if f.in(m[1]) && !f.Trashed {
+			files = append(files, f.File)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	from, _ := strconv.Atoi(first(q["pageToken"]))
+	to := min(from+2, len(files))
+	page := map[string]any{"files": files[from:to]}
+	if to < len(files) {
+		page["nextPageToken"] = strconv.Itoa(to)
+	}
+	fd.json(w, http.StatusOK, page)
+}
+
+// changes answers the changes feed from a page token, two changes a page.
+func (fd *fakeDrive) changes(w http.ResponseWriter, q map[string][]string) {
+	from, err := strconv.Atoi(first(q["pageToken"]))
+	if err != nil || from < 1 || from > len(fd.log)+1 {
+		fd.fail(w, http.StatusBadRequest, "invalid", "Invalid Value")
+		return
+	}
+	to := min(from+2, len(fd.log)+1)
+	var changes []map[string]any
+	for _, id := range fd.log[from-1 : to-1] {
+		changes = append(changes, map[string]any{"kind": "drive#change", "fileId": id, "removed": false, "file": fd.files[id].File})
+	}
+	page := map[string]any{"changes": changes}
+	if to <= len(fd.log) {
+		page["nextPageToken"] = strconv.Itoa(to)
+	} else {
+		page["newStartPageToken"] = strconv.Itoa(to)
+	}
+	fd.json(w, http.StatusOK, page)
+}
+
+// file answers export, download and comments on one file.
+func (fd *fakeDrive) file(w http.ResponseWriter, r *http.Request, f *fakeFile, rest []string) {
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet && r.URL.Query().Get("alt") == "media":
+		if strings.HasPrefix(f.MimeType, "application/vnd.google-apps.") {
+			fd.fail(w, http.StatusForbidden, "fileNotDownloadable", "Only files with binary content can be downloaded. Use Export with Docs Editors files.")
+			return
+		}
+		io.WriteString(w, f.content)
+	case len(rest) == 1 && rest[0] == "export" && r.Method == http.MethodGet:
+		if exportAs[f.MimeType] != r.URL.Query().Get("mimeType") {
+			fd.fail(w, http.StatusBadRequest, "badRequest", "The requested conversion is not supported.")
+			return
+		}
+		// Docs export as UTF-8 with a byte order mark
+		io.WriteString(w, "\ufeff"+f.content)
+	case len(rest) >= 1 && rest[0] == "comments":
+		var c struct {
+			Content string `json:"content"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil || c.Content == "" {
+			fd.fail(w, http.StatusBadRequest, "required", "Required: content")
+			return
+		}
+		id := ""
+		switch {
+		case len(rest) == 1 && r.Method == http.MethodPost:
+			fd.comments++
+			id = fmt.Sprintf("AAABc%06dDemo", fd.comments)
+		case len(rest) == 2 && r.Method == http.MethodPatch && f.comments[rest[1]] != "":
+			id = rest[1]
+		default:
+			fd.fail(w, http.StatusNotFound, "notFound", "Comment not found.")
+			return
+		}
+		f.comments[id] = c.Content
+		fd.json(w, http.StatusOK, map[string]string{"id": id})
+	default:
+		fd.fail(w, http.StatusBadRequest, "badRequest", "Not supported by the demo Drive.")
+	}
+}
+
+// upload creates a Google Doc from a multipart upload: JSON metadata,
+// then the text.
+func (fd *fakeDrive) upload(w http.ResponseWriter, r *http.Request) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || r.URL.Query().Get("uploadType") != "multipart" {
+		fd.fail(w, http.StatusBadRequest, "badContent", "Expected a multipart upload.")
+		return
+	}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	var parts [][]byte
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		b, _ := io.ReadAll(p)
+		parts = append(parts, b)
+	}
+	var meta File
+	if len(parts) != 2 || json.Unmarshal(parts[0], &meta) != nil || meta.MimeType != docType {
+		fd.fail(w, http.StatusBadRequest, "badContent", "Expected metadata and a text part.")
+		return
+	}
+	fd.docs++
+	meta.ID = fmt.Sprintf("1DocSummary%d", fd.docs)
+	meta.ModifiedTime = time.Now().UTC().Format(time.RFC3339)
+	fd.files[meta.ID] = &fakeFile{File: meta, content: string(parts[1]), comments: make(map[string]string)}
+	fd.log = append(fd.log, meta.ID)
+	fd.json(w, http.StatusOK, map[string]string{"id": meta.ID})
+}
+
+func first(v []string) string {
+	if len(v) == 0 {
+		return ""
+	}
+	return v[0]
+}
+
+func (fd *fakeDrive) fail(w http.ResponseWriter, status int, reason, msg string) {
+	fd.json(w, status, map[string]any{"error": map[string]any{"code": status, "message": msg,
+		"errors": []map[string]string{{"domain": "global", "reason": reason, "message": msg}}}})
+}
+
+func (fd *fakeDrive) json(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+var demoToken = regexp.MustCompile(`<(Person|Email Address|Phone Number|Credit Card Number)_\d+>`)
+
+// fakeModel answers chat completions with summaries scripted by file
+// name. Like a real model it only uses tokens from its input.
+func fakeModel(req openai.ChatCompletionRequest) string {
+	in := fakeopenai.Prompt(req)
+	// tok returns the n-th distinct token of a type, in order of
+	// appearance
+	tok := func(typ string, n int) string {
+		seen := map[string]bool{}
+		for _, t := range demoToken.FindAllString(in, -1) {
+			if strings.HasPrefix(t, "<"+typ+"_") && !seen[t] {
+				seen[t] = true
+				if len(seen) == n {
+					return t
+				}
+			}
+		}
+		return "someone"
+	}
+
+	var out string
+	switch {
+	case strings.Contains(in, "File: Q3 customer escalations"):
+		out = fmt.Sprintf("Open and resolved customer escalations for Q3. %s's double charge on order 5531 is refunded but still needs "+
+			"written confirmation to %s; %s's lockout is fixed, with the root cause open with the identity team; "+
+			"%s's replacement kettle has shipped and the courier claim is open, with a callback due on %s.",
+			tok("Person", 1), tok("Email Address", 1), tok("Person", 2), tok("Person", 3), tok("Phone Number", 1))
+		if strings.Contains(in, "invoice 2291") {
+			out += fmt.Sprintf(" Invoice 2291 was reissued to the Boston office for %s.", tok("Person", 4))
+		}
+	case strings.Contains(in, "File: Renewals tracker"):
+		out = fmt.Sprintf("Three renewals are due by 1 December, worth 204,000 in ARR. Contoso (%s, 120,000) on 15 November is the largest; "+
+			"Northwind (%s) renews first, on 1 November, and Fabrikam (%s) last.", tok("Person", 2), tok("Person", 1), tok("Person", 3))
+	case strings.Contains(in, "File: onboarding-call-notes.txt"):
+		out = fmt.Sprintf("Notes from Northwind's onboarding call on 9 October: %s needs SSO before rollout. "+
+			"Follow-up: send the admin guide to %s before the next call on 16 October.", tok("Person", 1), tok("Email Address", 1))
+	default:
+		out = "A file in the folder; nothing needs follow-up."
+	}
+	return out
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Google Workspace types the recipe can read as text, and what it exports
+// them as. Other text/* files are downloaded as they are.
+const (
+	docType    = "application/vnd.google-apps.document"
+	sheetType  = "application/vnd.google-apps.spreadsheet"
+	folderType = "application/vnd.google-apps.folder"
+)
+
+var exportAs = map[string]string{
+	docType:   "text/plain",
+	sheetType: "text/csv", // the first sheet only
+}
+
+// maxExport is the most text read from one file. Drive refuses to export
+// more than 10 MB anyway.
+const maxExport = 10 << 20
+
+// summaryOf is the appProperties key on companion docs, holding the ID of
+// the file summarized, so a sync never summarizes its own output.
+const summaryOf = "blindfoldSummaryOf"
+
+const fileFields = "id,name,mimeType,modifiedTime,parents,trashed,appProperties"
+
+// File is a Drive file with the fields the recipe reads.
+type File struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	MimeType      string            `json:"mimeType"`
+	ModifiedTime  string            `json:"modifiedTime"`
+	Parents       []string          `json:"parents"`
+	Trashed       bool              `json:"trashed"`
+	AppProperties map[string]string `json:"appProperties"`
+}
+
+// readable reports whether f can be read as text.
+func (f File) readable() bool {
+	return exportAs[f.MimeType] != "" || strings.HasPrefix(f.MimeType, "text/")
+}
+
+func (f File) in(folder string) bool {
+	for _, p := range f.Parents {
+		if p == folder {
+			return true
+		}
+	}
+	return false
+}
+
+// Change is one entry of the changes feed: a file that was added, edited,
+// moved, trashed or deleted since a page token.
+type Change struct {
+	FileID  string `json:"fileId"`
+	Removed bool   `json:"removed"`
+	File    *File  `json:"file"`
+}
+
+// drive is a minimal Drive API v3 client: folder listing, the changes
+// feed, export and download, comments, and plain-text uploads as Google
+// Docs, which is all the recipe needs.
+type drive struct {
+	base   string // https://www.googleapis.com
+	tokens *tokenSource
+	http   *http.Client
+}
+
+// driveError is an error response from the Drive API.
+type driveError struct {
+	Status int
+	Reason string
+	Msg    string
+}
+
+func (e *driveError) Error() string {
+	return fmt.Sprintf("drive: %d %s: %s", e.Status, e.Reason, e.Msg)
+}
+
+func isNotFound(err error) bool {
+	var de *driveError
+	return errors.As(err, &de) && de.Status == http.StatusNotFound
+}
+
+// connect builds a client from the environment, in order of preference:
+//
+//   - GOOGLE_ACCESS_TOKEN: an access token you already have, such as from
+//     `gcloud auth print-access-token`; it expires within the hour.
+//   - GOOGLE_CLIENT_ID, GOOGLE_CLIENT_SECRET and GOOGLE_REFRESH_TOKEN: an
+//     OAuth client and a refresh token a user granted it.
+//   - GOOGLE_APPLICATION_CREDENTIALS: a service account's JSON key. The
+//     service account sees the folders shared with it, or, with
+//     GOOGLE_IMPERSONATE_USER and domain-wide delegation, that user's.
+func connect(ctx context.Context) (*drive, error) {
+	d := &drive{base: "https://www.googleapis.com", http: &http.Client{Timeout: 60 * time.Second}}
+	switch {
+	case os.Getenv("GOOGLE_ACCESS_TOKEN") != "":
+		d.tokens = &tokenSource{token: os.Getenv("GOOGLE_ACCESS_TOKEN")}
+	case os.Getenv("GOOGLE_REFRESH_TOKEN") != "":
+		id, secret := os.Getenv("GOOGLE_CLIENT_ID"), os.Getenv("GOOGLE_CLIENT_SECRET")
+		if id == "" || secret == "" {
+			return nil, fmt.Errorf("GOOGLE_REFRESH_TOKEN needs GOOGLE_CLIENT_ID and GOOGLE_CLIENT_SECRET")
+		}
+		d.tokens = refreshToken(d.http, googleTokenURL, id, secret, os.Getenv("GOOGLE_REFRESH_TOKEN"))
+	case os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "":
+		ts, err := serviceAccount(d.http, os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"), os.Getenv("GOOGLE_IMPERSONATE_USER"))
+		if err != nil {
+			return nil, err
+		}
+		d.tokens = ts
+	default:
+		return nil, fmt.Errorf("set GOOGLE_ACCESS_TOKEN, GOOGLE_REFRESH_TOKEN with GOOGLE_CLIENT_ID and GOOGLE_CLIENT_SECRET, " +
+			"or GOOGLE_APPLICATION_CREDENTIALS (or run with -demo)")
+	}
+	// Fail on bad credentials now, not halfway through a folder
+	if _, err := d.tokens.get(ctx, false); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+const (
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+	// driveScope covers reading the folder's files and writing comments
+	// and companion docs. drive.file would only see files the recipe
+	// created.
+	driveScope = "https://www.googleapis.com/auth/drive"
+)
+
+// tokenSource hands out an access token and fetches a new one shortly
+// before it expires, or when the API turns the current one down.
+type tokenSource struct {
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+	// fetch gets a new token and its lifetime. Nil for a fixed token.
+	fetch func(ctx context.Context) (string, time.Duration, error)
+}
+
+func (ts *tokenSource) get(ctx context.Context, force bool) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.fetch == nil {
+		if force {
+			return "", fmt.Errorf("drive: access token rejected; GOOGLE_ACCESS_TOKEN has likely expired")
+		}
+		return ts.token, nil
+	}
+	if ts.token != "" && !force && time.Until(ts.expiry) > time.Minute {
+		return ts.token, nil
+	}
+	token, ttl, err := ts.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	ts.token, ts.expiry = token, time.Now().Add(ttl)
+	return token, nil
+}
+
+// refreshToken exchanges a refresh token for access tokens.
+func refreshToken(hc *http.Client, tokenURL, clientID, clientSecret, refresh string) *tokenSource {
+	return &tokenSource{fetch: func(ctx context.Context) (string, time.Duration, error) {
+		return exchange(ctx, hc, tokenURL, url.Values{"grant_type": {"refresh_token"}, "client_id": {clientID},
+			"client_secret": {clientSecret}, "refresh_token": {refresh}})
+	}}
+}
+
+// serviceAccount signs a JWT with the service account's key for each
+// access token (the OAuth 2.0 JWT bearer grant).
+func serviceAccount(hc *http.Client, keyFile, subject string) (*tokenSource, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	var key struct {
+		Type        string `json:"type"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("%s: %w", keyFile, err)
+	}
+	if key.Type != "service_account" {
+		return nil, fmt.Errorf("%s: not a service account key (type %q)", keyFile, key.Type)
+	}
+	signer, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(key.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", keyFile, err)
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = googleTokenURL
+	}
+	return &tokenSource{fetch: func(ctx context.Context) (string, time.Duration, error) {
+		now := time.Now()
+		claims := jwt.MapClaims{"iss": key.ClientEmail, "scope": driveScope, "aud": key.TokenURI,
+			"iat": now.Unix(), "exp": now.Add(time.Hour).Unix()}
+		if subject != "" {
+			claims["sub"] = subject
+		}
+		assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(signer)
+		if err != nil {
+			return "", 0, err
+		}
+		return exchange(ctx, hc, key.TokenURI, url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}})
+	}}, nil
+}
+
+// exchange posts a grant to the token endpoint.
+func exchange(ctx context.Context, hc *http.Client, tokenURL string, form url.Values) (string, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := hc.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", 0, fmt.Errorf("google oauth: %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || tok.AccessToken == "" {
+		return "", 0, fmt.Errorf("google oauth: %d %s: %s", resp.StatusCode, tok.Error, tok.Description)
+	}
+	return tok.AccessToken, time.Duration(tok.ExpiresIn) * time.Second, nil
+}
+
+// send makes one request, and makes it again with a new token if the
+// API turns the current one down. It returns the response body, at most
+// maxExport bytes of it.
+func (d *drive) send(ctx context.Context, method, path, contentType string, body []byte) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		token, err := d.tokens.get(ctx, attempt > 0)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, d.base+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := d.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxExport+1))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			var e struct {
+				Error struct {
+					Message string `json:"message"`
+					Errors  []struct {
+						Reason string `json:"reason"`
+					} `json:"errors"`
+				} `json:"error"`
+			}
+			de := &driveError{Status: resp.StatusCode, Msg: strings.TrimSpace(string(data))}
+			if json.Unmarshal(data, &e) == nil && e.Error.Message != "" {
+				de.Msg = e.Error.Message
+				if len(e.Error.Errors) > 0 {
+					de.Reason = e.Error.Errors[0].Reason
+				}
+			}
+			return nil, de
+		}
+		if len(data) > maxExport {
+			return nil, fmt.Errorf("drive: response larger than %d MB", maxExport>>20)
+		}
+		return data, nil
+	}
+}
+
+func (d *drive) do(ctx context.Context, method, path string, body, out any) error {
+	var b []byte
+	var contentType string
+	if body != nil {
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	}
+	data, err := d.send(ctx, method, path, contentType, b)
+	if err != nil || out == nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// list returns the files directly in a folder, following nextPageToken
+// until every page is read.
+func (d *drive) list(ctx context.Context, folder string) ([]File, error) {
+	q := url.Values{
+		"q":                         {fmt.Sprintf("'%s' in parents and trashed = false", strings.ReplaceAll(folder, "'", `\'`))},
+		"fields":                    {"nextPageToken,files(" + fileFields + ")"},
+		"pageSize":                  {"100"},
+		"orderBy":                   {"name"},
+		"supportsAllDrives":         {"true"},
+		"includeItemsFromAllDrives": {"true"},
+	}
+	var out []File
+	for {
+		var page struct {
+			Files []File `json:"files"`
+			Next  string `json:"nextPageToken"`
+		}
+		if err := d.do(ctx, http.MethodGet, "/drive/v3/files?"+q.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		out = append(out, page.Files...)
+		if page.Next == "" {
+			return out, nil
+		}
+		q.Set("pageToken", page.Next)
+	}
+}
+
+// startPageToken returns the token for changes from now on.
+func (d *drive) startPageToken(ctx context.Context) (string, error) {
+	var res struct {
+		Token string `json:"startPageToken"`
+	}
+	err := d.do(ctx, http.MethodGet, "/drive/v3/changes/startPageToken?supportsAllDrives=true", nil, &res)
+	return res.Token, err
+}
+
+// changes returns every change since token, following nextPageToken, and
+// the token to pass next time.
+func (d *drive) changes(ctx context.Context, token string) ([]Change, string, error) {
+	q := url.Values{
+		"fields":                    {"nextPageToken,newStartPageToken,changes(fileId,removed,file(" + fileFields + "))"},
+		"pageSize":                  {"100"},
+		"supportsAllDrives":         {"true"},
+		"includeItemsFromAllDrives": {"true"},
+	}
+	var out []Change
+	for {
+		q.Set("pageToken", token)
+		var page struct {
+			Changes  []Change `json:"changes"`
+			Next     string   `json:"nextPageToken"`
+			NewStart string   `json:"newStartPageToken"`
+		}
+		if err := d.do(ctx, http.MethodGet, "/drive/v3/changes?"+q.Encode(), nil, &page); err != nil {
+			return nil, "", err
+		}
+		out = append(out, page.Changes...)
+		if page.NewStart != "" {
+			return out, page.NewStart, nil
+		}
+		token = page.Next
+	}
+}
+
+// text exports a Google Doc or Sheet as text, or downloads a text file.
+func (d *drive) text(ctx context.Context, f File) (string, error) {
+	path := "/drive/v3/files/" + url.PathEscape(f.ID)
+	if as := exportAs[f.MimeType]; as != "" {
+		path += "/export?mimeType=" + url.QueryEscape(as)
+	} else {
+		path += "?alt=media&supportsAllDrives=true"
+	}
+	data, err := d.send(ctx, http.MethodGet, path, "", nil)
+	return string(bytes.TrimPrefix(data, []byte("\ufeff"))), err
+}
+
+// comment adds a comment to a file, or replaces the text of one added
+// before, and returns its ID.
+func (d *drive) comment(ctx context.Context, fileID, commentID, content string) (string, error) {
+	var res struct {
+		ID string `json:"id"`
+	}
+	path := "/drive/v3/files/" + url.PathEscape(fileID) + "/comments"
+	if commentID != "" {
+		err := d.do(ctx, http.MethodPatch, path+"/"+url.PathEscape(commentID)+"?fields=id", map[string]string{"content": content}, &res)
+		if !isNotFound(err) {
+			return res.ID, err
+		}
+		// Someone deleted it; add a new one
+	}
+	err := d.do(ctx, http.MethodPost, path+"?fields=id", map[string]string{"content": content}, &res)
+	return res.ID, err
+}
+
+// createDoc uploads text as a new Google Doc in folder, marked as the
+// summary of the file source, and returns its ID.
+func (d *drive) createDoc(ctx context.Context, folder, name, source, text string) (string, error) {
+	meta, err := json.Marshal(map[string]any{"name": name, "mimeType": docType, "parents": []string{folder},
+		"appProperties": map[string]string{summaryOf: source}})
+	if err != nil {
+		return "", err
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		data        []byte
+	}{{"application/json; charset=UTF-8", meta}, {"text/plain; charset=UTF-8", []byte(text)}} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return "", err
+		}
+		w.Write(part.data)
+	}
+	mw.Close()
+	data, err := d.send(ctx, http.MethodPost, "/upload/drive/v3/files?uploadType=multipart&supportsAllDrives=true&fields=id",
+		"multipart/related; boundary="+mw.Boundary(), body.Bytes())
+	if err != nil {
+		return "", err
+	}
+	var res struct {
+		ID string `json:"id"`
+	}
+	return res.ID, json.Unmarshal(data, &res)
+}
+
+// updateDoc replaces the text of a companion doc.
+func (d *drive) updateDoc(ctx context.Context, id, text string) error {
+	_, err := d.send(ctx, http.MethodPatch, "/upload/drive/v3/files/"+url.PathEscape(id)+"?uploadType=media&supportsAllDrives=true&fields=id",
+		"text/plain; charset=UTF-8", []byte(text))
+	return err
+}
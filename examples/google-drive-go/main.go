@@ -0,0 +1,341 @@
+// Google Drive + Blindfold: Summarize the Docs and Sheets in a Drive
+// folder without the LLM seeing the personal data in them.
+//
+// Each Google Doc is exported as plain text, each Sheet as CSV, and text
+// files are downloaded as they are. pkg/docsummary tokenizes the text in
+// overlapping windows, asks the LLM for a summary, checks it for tokens
+// the file never had and restores it. The summary goes back to Drive as a comment on
+// the file, or as a companion Google Doc next to it.
+//
+// The first run lists the folder; later runs read the Drive changes feed
+// from the page token the last run saved, so only files edited, added or
+// removed since then are fetched and summarized again.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/docsummary"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+type config struct {
+	folder, statePath, write string
+	full, dryRun             bool
+}
+
+func main() {
+	_ = godotenv.Load()
+	var cfg config
+	flag.StringVar(&cfg.folder, "folder", os.Getenv("DRIVE_FOLDER_ID"), "ID of the Drive folder to summarize (default: $DRIVE_FOLDER_ID)")
+	flag.StringVar(&cfg.statePath, "state", "drive-state.json", "state file: the sync page token and what was summarized")
+	flag.StringVar(&cfg.write, "write", "comment", "where summaries go: comment (on the file) or doc (a companion Google Doc in the folder)")
+	flag.BoolVar(&cfg.full, "full", false, "list the whole folder instead of reading changes since the last run")
+	flag.BoolVar(&cfg.dryRun, "dry-run", false, "summarize and print, but write nothing to Drive or the state file")
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply, by name (default: the file's default)")
+	model := flag.String("model", openai.GPT4oMini, "OpenAI model")
+	maxChars := flag.Int("max-chars", docsummary.DefaultMaxChars, "summarize tokenized files longer than this section by section")
+	demo := flag.Bool("demo", false, "use an in-process Drive and scripted model (no Google or OpenAI account needed)")
+	flag.Parse()
+	if cfg.write != "comment" && cfg.write != "doc" {
+		log.Fatalf("-write: want comment or doc, got %q", cfg.write)
+	}
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+
+	oaCfg := openai.DefaultConfig(os.Getenv("OPENAI_API_KEY"))
+	var d *drive
+	var fake *fakeDrive
+	if *demo {
+		llm := fakeopenai.Server(fakeModel)
+		defer llm.Close()
+		oaCfg = fakeopenai.Config(llm)
+		var srv func()
+		fake, d, srv = newFakeDrive()
+		defer srv()
+		// A fresh state, so the demo always starts with a full sync
+		dir, err := os.MkdirTemp("", "drive-demo-")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		cfg.folder, cfg.statePath = demoFolder, filepath.Join(dir, "drive-state.json")
+	} else {
+		if os.Getenv("OPENAI_API_KEY") == "" {
+			log.Fatal("OPENAI_API_KEY is required (or run with -demo)")
+		}
+		if cfg.folder == "" {
+			log.Fatal("-folder or DRIVE_FOLDER_ID is required: the ID at the end of the folder's URL")
+		}
+		if d, err = connect(ctx); err != nil {
+			log.Fatal(err)
+		}
+	}
+	s := docsummary.New(bf, openai.NewClientWithConfig(oaCfg), *model)
+	s.MaxChars = *maxChars
+
+	if err := run(ctx, d, s, cfg); err != nil {
+		log.Fatal(err)
+	}
+	if fake != nil {
+		fmt.Printf("\n(demo) Meanwhile, someone %s\n\n", fake.edit())
+		if err := run(ctx, d, s, cfg); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// run is one sync: it summarizes the files that are new or changed since
+// the last, and saves the page token to pick up from next time. If any
+// file fails, the token is kept where it was, so the next run sees those
+// changes again; files summarized in the meantime are recorded and not
+// summarized twice.
+func run(ctx context.Context, d *drive, s *docsummary.Summarizer, cfg config) error {
+	st, err := loadState(cfg.statePath, cfg.folder)
+	if err != nil {
+		return err
+	}
+	files, next, err := pending(ctx, d, st, cfg)
+	if err != nil {
+		return err
+	}
+	summarized, unchanged, failed := 0, 0, 0
+	for _, f := range files {
+		switch {
+		case f.MimeType == folderType:
+			fmt.Printf("── %s: skipped, subfolders aren't synced\n\n", f.Name)
+			continue
+		case !f.readable():
+			fmt.Printf("── %s: skipped, can't read %s as text\n\n", f.Name, f.MimeType)
+			continue
+		}
+		e := st.Files[f.ID]
+		if e != nil && e.ModifiedTime == f.ModifiedTime {
+			unchanged++
+			continue
+		}
+		fmt.Printf("── %s (%s, edited %s)\n", f.Name, kind(f), when(f.ModifiedTime))
+		if e == nil {
+			e = &entry{}
+		}
+		if err := summarizeFile(ctx, d, s, cfg, f, e); err != nil {
+			fmt.Printf("   failed:    %v\n\n", err)
+			failed++
+			continue
+		}
+		st.Files[f.ID] = e
+		summarized++
+		fmt.Println()
+	}
+	fmt.Printf("%d summarized, %d unchanged, %d failed\n", summarized, unchanged, failed)
+	if cfg.dryRun {
+		return nil
+	}
+	if failed == 0 {
+		st.PageToken = next
+	}
+	if err := st.save(cfg.statePath); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d file(s) failed; the next run tries them again", failed)
+	}
+	fmt.Printf("Saved page token %s to %s\n", st.PageToken, cfg.statePath)
+	return nil
+}
+
+// pending returns the folder's files to look at, and the page token to
+// save after them. With no token saved, or -full, that is every file in
+// the folder; otherwise it is the files the changes feed reports in the
+// folder, and the state of files removed from it is dropped.
+func pending(ctx context.Context, d *drive, st *state, cfg config) ([]File, string, error) {
+	if st.PageToken == "" || cfg.full {
+		// The token comes first: a file edited while the folder is read
+		// shows up in the next run's changes rather than being missed
+		token, err := d.startPageToken(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		files, err := d.list(ctx, cfg.folder)
+		if err != nil {
+			return nil, "", err
+		}
+		files = withoutSummaries(files)
+		keep := make(map[string]bool, len(files))
+		for _, f := range files {
+			keep[f.ID] = true
+		}
+		for id := range st.Files {
+			if !keep[id] {
+				delete(st.Files, id)
+			}
+		}
+		fmt.Printf("Folder %s: full sync, %d file(s)\n\n", cfg.folder, len(files))
+		return files, token, nil
+	}
+
+	changes, token, err := d.changes(ctx, st.PageToken)
+	if err != nil {
+		return nil, "", err
+	}
+	// A file changed twice appears twice; its last change wins
+	latest := make(map[string]Change, len(changes))
+	var order []string
+	for _, c := range changes {
+		if _, ok := latest[c.FileID]; !ok {
+			order = append(order, c.FileID)
+		}
+		latest[c.FileID] = c
+	}
+	var files []File
+	var gone []string
+	for _, id := range order {
+		c := latest[id]
+		if c.Removed || c.File == nil || c.File.Trashed || !c.File.in(cfg.folder) {
+			// The changes feed covers the whole drive; only files that
+			// were in this folder matter
+			if e := st.Files[id]; e != nil {
+				gone = append(gone, e.Name)
+				delete(st.Files, id)
+			}
+			continue
+		}
+		files = append(files, *c.File)
+	}
+	files = withoutSummaries(files)
+	fmt.Printf("Folder %s: %d change(s) in the drive since page token %s, %d in the folder\n\n", cfg.folder, len(changes), st.PageToken, len(files)+len(gone))
+	for _, name := range gone {
+		fmt.Printf("── %s: removed from the folder, forgotten\n\n", name)
+	}
+	return files, token, nil
+}
+
+// withoutSummaries drops the companion docs a sync wrote, so it never
+// summarizes its own output.
+func withoutSummaries(files []File) []File {
+	out := files[:0]
+	for _, f := range files {
+		if f.AppProperties[summaryOf] == "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// summarizeFile reads f, summarizes it, and unless -dry-run writes the
+// summary back and records where it went in e.
+func summarizeFile(ctx context.Context, d *drive, s *docsummary.Summarizer, cfg config, f File, e *entry) error {
+	text, err := d.text(ctx, f)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("no text")
+	}
+	sum, err := s.Summarize(ctx, f.Name, text)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("   tokenized: %d chars, %d section(s), %s\n", len(text), sum.Sections, entities(sum.Entities))
+	fmt.Printf("   summary:   %s\n", sum.Tokenized)
+	fmt.Printf("   → %s\n", sum.Text)
+	if cfg.dryRun {
+		fmt.Printf("   not written (-dry-run)\n")
+		return nil
+	}
+
+	switch cfg.write {
+	case "comment":
+		if e.CommentID, err = d.comment(ctx, f.ID, e.CommentID, commentHeader+"\n\n"+sum.Text); err != nil {
+			return err
+		}
+		fmt.Printf("   written:   comment %s\n", e.CommentID)
+	case "doc":
+		body := "Summary of " + f.Name + "\n\n" + sum.Text + "\n\n" + docFooter + "\n"
+		updated := false
+		if e.DocID != "" {
+			err := d.updateDoc(ctx, e.DocID, body)
+			if err != nil && !isNotFound(err) {
+				return err
+			}
+			updated = err == nil
+		}
+		if !updated {
+			if e.DocID, err = d.createDoc(ctx, cfg.folder, f.Name+" (summary)", f.ID, body); err != nil {
+				return err
+			}
+		}
+		verb := "created"
+		if updated {
+			verb = "updated"
+		}
+		fmt.Printf("   written:   %s companion doc %s\n", verb, e.DocID)
+	}
+	e.Name, e.ModifiedTime, e.Entities, e.SummarizedAt = f.Name, f.ModifiedTime, sum.Entities, time.Now().UTC()
+	return nil
+}
+
+const (
+	commentHeader = "Summary (written by an LLM from a copy of this file with personal data replaced by placeholders):"
+	docFooter     = "Written by an LLM from a copy of the file with personal data replaced by placeholders. It is updated when the file changes."
+)
+
+func kind(f File) string {
+	switch f.MimeType {
+	case docType:
+		return "Google Doc"
+	case sheetType:
+		return "Google Sheet"
+	}
+	return f.MimeType
+}
+
+// when shows an RFC 3339 time from Drive in local time.
+func when(rfc3339 string) string {
+	t, err := time.Parse(time.RFC3339, rfc3339)
+	if err != nil {
+		return rfc3339
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func entities(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "no entities"
+	}
+	types := make([]string, 0, len(counts))
+	n := 0
+	for t, c := range counts {
+		types = append(types, t)
+		n += c
+	}
+	sort.Strings(types)
+	for i, t := range types {
+		types[i] = fmt.Sprintf("%s %d", t, counts[t])
+	}
+	return fmt.Sprintf("%d entities protected (%s)", n, strings.Join(types, ", "))
+}
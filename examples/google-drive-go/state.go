@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// state is what a sync keeps between runs: where the changes feed left
+// off, and per file the version summarized and where its summary went.
+// It holds no mappings and no text, but file names can be personal, so
+// it is written owner-only.
+type state struct {
+	Folder    string            `json:"folder"`
+	PageToken string            `json:"page_token"`
+	Files     map[string]*entry `json:"files"`
+}
+
+type entry struct {
+	Name         string         `json:"name"`
+	ModifiedTime string         `json:"modified_time"` // of the version summarized
+	CommentID    string         `json:"comment_id,omitempty"`
+	DocID        string         `json:"doc_id,omitempty"` // companion doc
+	Entities     map[string]int `json:"entities,omitempty"`
+	SummarizedAt time.Time      `json:"summarized_at"`
+}
+
+// loadState reads the state for folder. A missing file is a first sync.
+func loadState(path, folder string) (*state, error) {
+	st := &state{Folder: folder, Files: make(map[string]*entry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if st.Folder != folder {
+		return nil, fmt.Errorf("%s is the state for folder %s; pass another -state for %s", path, st.Folder, folder)
+	}
+	if st.Files == nil {
+		st.Files = make(map[string]*entry)
+	}
+	return st, nil
+}
+
+// save writes the state through a temporary file, so a crash leaves the
+// previous state rather than half of this one.
+func (st *state) save(path string) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".drive-state-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,110 @@
+// HIPAA clinical notes + Blindfold: Summarize notes without sending PHI to
+// the LLM.
+//
+// Clinical notes carry identifiers no generic detector knows about: medical
+// record numbers, provider NPI numbers, dates of birth written as "DOB
+// 04/17/1958", and diagnoses in free text. policies.yaml layers patterns and
+// a diagnosis denylist on the built-in hipaa_us policy; each note in notes/
+// is tokenized with it, summarized by the LLM for the care team, and the
+// summary is restored.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+)
+
+const systemPrompt = "You summarize clinical notes for the care team. " +
+	"Reply with four short lines: Patient, Problems, Plan, Follow-up. " +
+	"Keep placeholders like <Medical Record Number_1> exactly as they are."
+
+// npi validates a National Provider Identifier: a Luhn check digit over
+// the number prefixed with 80840, the US health industry issuer code.
+func npi(s string) bool {
+	sum := 0
+	d := "80840" + s
+	for i := range d {
+		x := int(d[len(d)-1-i] - '0')
+		if i%2 == 1 {
+			if x *= 2; x > 9 {
+				x -= 9
+			}
+		}
+		sum += x
+	}
+	return sum%10 == 0
+}
+
+func summarize(ctx context.Context, p *protect.Protector, oa *openai.Client, note string, dryRun bool) error {
+	call := protect.Chat(ctx, oa, openai.GPT4oMini, systemPrompt)
+	if dryRun {
+		call = func(string) (string, error) { return "", nil }
+	}
+	res, err := p.Run(ctx, note, call)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Protected: %s\n", res.Summary())
+	if dryRun {
+		fmt.Printf("\n%s\n", strings.TrimSpace(res.Safe))
+		return nil
+	}
+	fmt.Printf("\n%s\n", res.Text)
+	return nil
+}
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply (default: the file's default)")
+	dir := flag.String("dir", "notes", "directory of .txt clinical notes")
+	dryRun := flag.Bool("dry-run", false, "print tokenized notes; skip OpenAI calls")
+	flag.Parse()
+	ctx := context.Background()
+
+	// Registered before the policy file that uses it is loaded
+	policyconf.RegisterValidator("npi", npi)
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	p := protect.New(bfclient.FromEnv(pol.ClientOptions()...))
+	p.Policy = pol
+	oa := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
+
+	files, err := filepath.Glob(filepath.Join(*dir, "*.txt"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(files) == 0 {
+		log.Fatalf("no notes in %s", *dir)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("== %s %s\n", filepath.Base(file), strings.Repeat("=", 50-len(filepath.Base(file))))
+		if err := summarize(ctx, p, oa, string(data), *dryRun); err != nil {
+			log.Printf("%s: %v", file, err)
+		}
+		fmt.Println()
+	}
+}
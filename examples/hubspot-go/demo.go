@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/casedraft"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+// fakePortal stands in for a HubSpot portal: ticket search in pages of
+// two, v4 associations, batch reads and note creation. Notes are kept in
+// memory with their ticket associations.
+type fakePortal struct {
+	mu       sync.Mutex
+	tickets  []map[string]string // id, subject, content, hs_pipeline_stage
+	contacts map[string]map[string]string
+	notes    map[string]map[string]string
+	assoc    map[string]map[string][]string // ticket → type → IDs
+	nextNote int
+}
+
+const demoToken = "pat-demo-0000"
+
+func newFakePortal() *httptest.Server {
+	p := &fakePortal{
+		tickets: []map[string]string{
+			{"id": "18234001", "hs_pipeline_stage": "1", "subject": "Refund for duplicate charge",
+				"content": "Hi, card 5555 5555 5555 4444 was billed twice for invoice INV-20931. Please refund one and email me at jane.doe@example.com."},
+			{"id": "18234002", "hs_pipeline_stage": "1", "subject": "Change my login email",
+				"content": "Please change my login email from omar.haddad@example.com to omar@haddad.dev, I'm leaving my old provider."},
+			{"id": "18234003", "hs_pipeline_stage": "1", "subject": "Cancel subscription",
+				"content": "Chat transcript: This is Priya Patel, please cancel at the end of my trial. You can call me on 212-555-0111."},
+			{"id": "18234004", "hs_pipeline_stage": "1", "subject": "Order 7710 arrived damaged",
+				"content": "The box for order 7710 was crushed and the kettle inside is dented."},
+			{"id": "18234005", "hs_pipeline_stage": "2", "subject": "Invoice copy",
+				"content": "Waiting on customer: asked bob.lee@example.org which invoice they need."},
+		},
+		contacts: map[string]map[string]string{
+			"501": {"firstname": "Jane", "lastname": "Doe", "email": "jane.doe@example.com", "phone": "+1 415 555 0142"},
+			"502": {"firstname": "Omar", "lastname": "Haddad", "email": "omar.haddad@example.com", "phone": ""},
+			"504": {"firstname": "Li", "lastname": "Wei", "email": "li.wei@example.com", "phone": "617-555-0123"},
+		},
+		notes: map[string]map[string]string{
+			"9001": {"hs_note_body": "<p>" + casedraft.Marker + "<br><br>Hi Li Wei, sorry to hear order 7710 arrived damaged...</p>"},
+		},
+		assoc: map[string]map[string][]string{
+			"18234001": {"contacts": {"501"}},
+			"18234002": {"contacts": {"502"}},
+			"18234004": {"contacts": {"504"}, "notes": {"9001"}},
+		},
+		nextNote: 9002,
+	}
+	return httptest.NewServer(p)
+}
+
+func (p *fakePortal) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+demoToken {
+		p.json(w, http.StatusUnauthorized, map[string]string{"status": "error", "category": "INVALID_AUTHENTICATION", "message": "Authentication credentials not found."})
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodPost && path == "/crm/v3/objects/tickets/search":
+		var req struct {
+			FilterGroups []struct {
+				Filters []struct {
+					PropertyName, Value string
+				}
+			}
+			Limit int
+			After string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			p.json(w, http.StatusBadRequest, map[string]string{"category": "VALIDATION_ERROR", "message": err.Error()})
+			return
+		}
+		stage := ""
+		if len(req.FilterGroups) > 0 && len(req.FilterGroups[0].Filters) > 0 {
+			stage = req.FilterGroups[0].Filters[0].Value
+		}
+		var matched []map[string]string
+		for _, t := range p.tickets {
+			if stage == "" || t["hs_pipeline_stage"] == stage {
+				matched = append(matched, t)
+			}
+		}
+		from, _ := strconv.Atoi(req.After)
+		to := min(from+min(req.Limit, 2), len(matched))
+		res := map[string]any{"total": len(matched), "results": p.objects(matched[from:to])}
+		if to < len(matched) {
+			res["paging"] = map[string]any{"next": map[string]string{"after": strconv.Itoa(to)}}
+		}
+		p.json(w, http.StatusOK, res)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/crm/v4/objects/tickets/"):
+		parts := strings.Split(strings.TrimPrefix(path, "/crm/v4/objects/tickets/"), "/") // id, associations, type
+		if len(parts) != 3 || parts[1] != "associations" {
+			p.notFound(w)
+			return
+		}
+		results := []map[string]any{}
+		for _, id := range p.assoc[parts[0]][parts[2]] {
+			results = append(results, map[string]any{"toObjectId": json.Number(id), "associationTypes": []any{}})
+		}
+		p.json(w, http.StatusOK, map[string]any{"results": results})
+	case r.Method == http.MethodPost && (path == "/crm/v3/objects/contacts/batch/read" || path == "/crm/v3/objects/notes/batch/read"):
+		store := p.contacts
+		if strings.Contains(path, "/notes/") {
+			store = p.notes
+		}
+		var req struct {
+			Inputs []struct{ ID string }
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		var found []map[string]string
+		for _, in := range req.Inputs {
+			if props, ok := store[in.ID]; ok {
+				o := map[string]string{"id": in.ID}
+				for k, v := range props {
+					o[k] = v
+				}
+				found = append(found, o)
+			}
+		}
+		p.json(w, http.StatusOK, map[string]any{"status": "COMPLETE", "results": p.objects(found)})
+	case r.Method == http.MethodPost && path == "/crm/v3/objects/notes":
+		var req struct {
+			Properties   map[string]string
+			Associations []struct {
+				To struct{ ID string }
+			}
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Associations) == 0 {
+			p.json(w, http.StatusBadRequest, map[string]string{"category": "VALIDATION_ERROR", "message": "a note needs a ticket association"})
+			return
+		}
+		id := strconv.Itoa(p.nextNote)
+		p.nextNote++
+		p.notes[id] = req.Properties
+		ticket := req.Associations[0].To.ID
+		if p.assoc[ticket] == nil {
+			p.assoc[ticket] = make(map[string][]string)
+		}
+		p.assoc[ticket]["notes"] = append(p.assoc[ticket]["notes"], id)
+		p.json(w, http.StatusCreated, map[string]any{"id": id, "properties": req.Properties})
+	default:
+		p.notFound(w)
+	}
+}
+
+// objects renders records as CRM objects: id plus properties.
+func (p *fakePortal) objects(records []map[string]string) []map[string]any {
+	out := []map[string]any{}
+	for _, rec := range records {
+		props := make(map[string]string)
+		for k, v := range rec {
+			if k != "id" {
+				props[k] = v
+			}
+		}
+		out = append(out, map[string]any{"id": rec["id"], "properties": props})
+	}
+	return out
+}
+
+func (p *fakePortal) notFound(w http.ResponseWriter) {
+	p.json(w, http.StatusNotFound, map[string]string{"status": "error", "category": "OBJECT_NOT_FOUND", "message": "resource not found"})
+}
+
+func (p *fakePortal) json(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+var demoTokens = map[string]*regexp.Regexp{
+	"person": regexp.MustCompile(`<Person_\d+>`),
+	"email":  regexp.MustCompile(`<Email Address_\d+>`),
+	"phone":  regexp.MustCompile(`<Phone Number_\d+>`),
+	"card":   regexp.MustCompile(`<Credit Card Number_\d+>`),
+}
+
+// fakeModel answers chat completions with replies scripted by the ticket
+// subject. Like a real model it only uses tokens from its input.
+func fakeModel(req openai.ChatCompletionRequest) string {
+	in := fakeopenai.Prompt(req)
+	tok := func(kind string) string { return demoTokens[kind].FindString(in) }
+	greeting := "Hi there,"
+	if p := tok("person"); p != "" {
+		greeting = "Hi " + p + ","
+	}
+
+	var out string
+	switch {
+	case strings.Contains(in, "billed twice"):
+		out = fmt.Sprintf("%s sorry about the double charge on %s for invoice INV-20931. "+
+			"I've asked our billing team to refund the duplicate, and we'll email %s once it's on its way.", greeting, tok("card"), tok("email"))
+	case strings.Contains(in, "login email"):
+		emails := demoTokens["email"].FindAllString(in, -1)
+		out = fmt.Sprintf("%s happy to help. I've sent a confirmation link to %s; until you click it, keep signing in with %s.",
+			greeting, emails[len(emails)-1], emails[0])
+	case strings.Contains(in, "cancel"):
+		out = fmt.Sprintf("%s I've noted your request to cancel at the end of your trial, so you won't be charged. "+
+			"If we need anything else we'll call you on %s.", greeting, tok("phone"))
+	default:
+		out = greeting + " thanks for getting in touch; we're looking into your ticket and will update you shortly."
+	}
+	return out
+}
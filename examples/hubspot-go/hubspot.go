@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// noteToTicket is HubSpot's association type ID for a note on a ticket.
+const noteToTicket = 228
+
+// Ticket is a HubSpot ticket with its first associated contact and the
+// bodies of the notes already on it.
+type Ticket struct {
+	ID      string
+	Subject string
+	Content string
+	Contact *Contact
+	Notes   []string
+}
+
+// Contact is a HubSpot contact. Every field identifies the customer.
+type Contact struct {
+	FirstName string `json:"firstname"`
+	LastName  string `json:"lastname"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+}
+
+// Name is the contact's full name, as far as HubSpot has it.
+func (c *Contact) Name() string { return strings.TrimSpace(c.FirstName + " " + c.LastName) }
+
+// hubspot is a minimal CRM API client for a private app: ticket search,
+// associations, batch reads and note creation.
+type hubspot struct {
+	base  string // https://api.hubapi.com
+	token string
+	http  *http.Client
+}
+
+func newHubSpot(base, token string) *hubspot {
+	return &hubspot{base: strings.TrimSuffix(base, "/"), token: token, http: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// hsError is an error response from the CRM API.
+type hsError struct {
+	Status   int
+	Category string
+	Msg      string
+}
+
+func (e *hsError) Error() string {
+	return fmt.Sprintf("hubspot: %d %s: %s", e.Status, e.Category, e.Msg)
+}
+
+func (hs *hubspot) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, hs.base+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+hs.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := hs.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Category string `json:"category"`
+			Message  string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return &hsError{Status: resp.StatusCode, Category: e.Category, Msg: e.Message}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type object struct {
+	ID         string            `json:"id"`
+	Properties map[string]string `json:"properties"`
+}
+
+// search returns every ticket in a pipeline stage, following the paging
+// cursor.
+func (hs *hubspot) search(ctx context.Context, stage string, limit int) ([]object, error) {
+	var out []object
+	after := ""
+	for {
+		req := map[string]any{
+			"filterGroups": []any{map[string]any{"filters": []any{
+				map[string]string{"propertyName": "hs_pipeline_stage", "operator": "EQ", "value": stage},
+			}}},
+			"properties": []string{"subject", "content", "hs_pipeline_stage"},
+			"sorts":      []any{map[string]string{"propertyName": "createdate", "direction": "ASCENDING"}},
+			"limit":      min(limit-len(out), 100),
+		}
+		if after != "" {
+			req["after"] = after
+		}
+		var page struct {
+			Results []object `json:"results"`
+			Paging  *struct {
+				Next struct {
+					After string `json:"after"`
+				} `json:"next"`
+			} `json:"paging"`
+		}
+		if err := hs.do(ctx, http.MethodPost, "/crm/v3/objects/tickets/search", req, &page); err != nil {
+			return nil, err
+		}
+		out = append(out, page.Results...)
+		if page.Paging == nil || page.Paging.Next.After == "" || len(out) >= limit {
+			return out, nil
+		}
+		after = page.Paging.Next.After
+	}
+}
+
+// associated returns the IDs of the objects of a type associated with a
+// ticket.
+func (hs *hubspot) associated(ctx context.Context, ticketID, toType string) ([]string, error) {
+	var res struct {
+		Results []struct {
+			ToObjectID json.Number `json:"toObjectId"`
+		} `json:"results"`
+	}
+	if err := hs.do(ctx, http.MethodGet, "/crm/v4/objects/tickets/"+ticketID+"/associations/"+toType, nil, &res); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(res.Results))
+	for i, r := range res.Results {
+		ids[i] = r.ToObjectID.String()
+	}
+	return ids, nil
+}
+
+// read batch-reads objects of a type by ID.
+func (hs *hubspot) read(ctx context.Context, objectType string, ids []string, properties ...string) (map[string]map[string]string, error) {
+	out := make(map[string]map[string]string, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+	inputs := make([]map[string]string, len(ids))
+	for i, id := range ids {
+		inputs[i] = map[string]string{"id": id}
+	}
+	var res struct {
+		Results []object `json:"results"`
+	}
+	err := hs.do(ctx, http.MethodPost, "/crm/v3/objects/"+objectType+"/batch/read",
+		map[string]any{"properties": properties, "inputs": inputs}, &res)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range res.Results {
+		out[o.ID] = o.Properties
+	}
+	return out, nil
+}
+
+// tickets searches a pipeline stage and fills in each ticket's contact and
+// notes.
+func (hs *hubspot) tickets(ctx context.Context, stage string, limit int) ([]Ticket, error) {
+	found, err := hs.search(ctx, stage, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Ticket, len(found))
+	for i, o := range found {
+		t := Ticket{ID: o.ID, Subject: o.Properties["subject"], Content: o.Properties["content"]}
+		contacts, err := hs.associated(ctx, o.ID, "contacts")
+		if err != nil {
+			return nil, fmt.Errorf("ticket %s: %w", o.ID, err)
+		}
+		if len(contacts) > 0 {
+			props, err := hs.read(ctx, "contacts", contacts[:1], "firstname", "lastname", "email", "phone")
+			if err != nil {
+				return nil, fmt.Errorf("ticket %s: %w", o.ID, err)
+			}
+			if p, ok := props[contacts[0]]; ok {
+				t.Contact = &Contact{FirstName: p["firstname"], LastName: p["lastname"], Email: p["email"], Phone: p["phone"]}
+			}
+		}
+		notes, err := hs.associated(ctx, o.ID, "notes")
+		if err != nil {
+			return nil, fmt.Errorf("ticket %s: %w", o.ID, err)
+		}
+		bodies, err := hs.read(ctx, "notes", notes, "hs_note_body")
+		if err != nil {
+			return nil, fmt.Errorf("ticket %s: %w", o.ID, err)
+		}
+		for _, id := range notes {
+			t.Notes = append(t.Notes, bodies[id]["hs_note_body"])
+		}
+		out[i] = t
+	}
+	return out, nil
+}
+
+// note adds a note to a ticket and returns its ID. Notes are internal:
+// they show on the ticket's timeline, not to the customer. The body is
+// HTML, so text is escaped and line breaks become <br>.
+func (hs *hubspot) note(ctx context.Context, ticketID, text string) (string, error) {
+	body := strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+	var res object
+	err := hs.do(ctx, http.MethodPost, "/crm/v3/objects/notes", map[string]any{
+		"properties": map[string]string{"hs_note_body": body, "hs_timestamp": time.Now().UTC().Format(time.RFC3339)},
+		"associations": []any{map[string]any{
+			"to":    map[string]string{"id": ticketID},
+			"types": []any{map[string]any{"associationCategory": "HUBSPOT_DEFINED", "associationTypeId": noteToTicket}},
+		}},
+	}, &res)
+	if err != nil {
+		return "", err
+	}
+	return res.ID, nil
+}
@@ -0,0 +1,129 @@
+// HubSpot + Blindfold: Draft replies to HubSpot tickets without the LLM
+// seeing who the customer is.
+//
+// The HubSpot variant of the Salesforce recipe. Tickets in a pipeline
+// stage are searched through the CRM API, with their associated contact
+// and notes, and handed to pkg/casedraft: the same protect-call-restore
+// core, which tokenizes each field value into one mapping, asks the LLM
+// for a draft, checks it for tokens the ticket never had and restores it.
+// The draft is written back as a note on the ticket for an agent to
+// review and send. Tickets that already have a draft note are skipped, so
+// the recipe can run on a schedule.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/casedraft"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+func drafted(t Ticket) bool {
+	for _, n := range t.Notes {
+		if casedraft.IsDraft(n) {
+			return true
+		}
+	}
+	return false
+}
+
+func indent(s string) string {
+	lines := strings.Split(s, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = "              " + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply, by name (default: the file's default)")
+	stage := flag.String("stage", "1", "draft replies for tickets in this pipeline stage (ID; 1 is \"New\" in the default pipeline)")
+	limit := flag.Int("limit", 20, "at most this many tickets per run")
+	dryRun := flag.Bool("dry-run", false, "print drafts without writing them to HubSpot")
+	model := flag.String("model", openai.GPT4oMini, "OpenAI model")
+	demo := flag.Bool("demo", false, "use an in-process HubSpot portal and scripted model (no HubSpot or OpenAI account needed)")
+	flag.Parse()
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+
+	cfg := openai.DefaultConfig(os.Getenv("OPENAI_API_KEY"))
+	hs := newHubSpot("https://api.hubapi.com", os.Getenv("HUBSPOT_ACCESS_TOKEN"))
+	if *demo {
+		llm, portal := fakeopenai.Server(fakeModel), newFakePortal()
+		defer llm.Close()
+		defer portal.Close()
+		cfg = fakeopenai.Config(llm)
+		hs = newHubSpot(portal.URL, demoToken)
+	} else if os.Getenv("OPENAI_API_KEY") == "" || os.Getenv("HUBSPOT_ACCESS_TOKEN") == "" {
+		log.Fatal("OPENAI_API_KEY and HUBSPOT_ACCESS_TOKEN are required (or run with -demo)")
+	}
+	drafter := casedraft.New(bf, openai.NewClientWithConfig(cfg), *model)
+
+	tickets, err := hs.tickets(ctx, *stage, *limit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	written, skipped := 0, 0
+	for _, t := range tickets {
+		fmt.Printf("── Ticket %s: %s\n", t.ID, t.Subject)
+		if drafted(t) {
+			fmt.Printf("   skipped:   already has a draft\n\n")
+			skipped++
+			continue
+		}
+		c := casedraft.Case{Number: t.ID, Subject: t.Subject, Body: t.Content}
+		if t.Contact != nil {
+			c.Name, c.Email, c.Phone = t.Contact.Name(), t.Contact.Email, t.Contact.Phone
+		}
+		d, err := drafter.Draft(ctx, c)
+		if d != nil {
+			fmt.Printf("   tokenized: %s\n", indent(d.Prompt.Text))
+			fmt.Printf("   draft:     %s\n", d.Reply)
+		}
+		if errors.Is(err, casedraft.ErrUnresolved) {
+			fmt.Printf("   skipped:   %v\n\n", err)
+			skipped++
+			continue
+		}
+		if err != nil {
+			log.Fatalf("ticket %s: %v", t.ID, err)
+		}
+		fmt.Printf("   → %s\n", d.Text)
+		if *dryRun {
+			fmt.Printf("   not written (-dry-run)\n\n")
+			continue
+		}
+		id, err := hs.note(ctx, t.ID, d.Comment())
+		if err != nil {
+			log.Fatalf("ticket %s: %v", t.ID, err)
+		}
+		fmt.Printf("   written:   note %s\n\n", id)
+		written++
+	}
+	fmt.Printf("%d ticket(s): %d draft(s) written, %d skipped\n", len(tickets), written, skipped)
+}
@@ -0,0 +1,89 @@
+// India customer-support transcripts + Blindfold: Summarize calls without
+// exposing Aadhaar, PAN or phone numbers.
+//
+// Support calls in India routinely include Aadhaar and PAN numbers read out
+// for KYC, often without the word "Aadhaar" anywhere near them. Each
+// transcript in transcripts/ is tokenized with the India pack from
+// pkg/packs — Aadhaar numbers are recognised by their Verhoeff check digit
+// alone — summarized by the LLM, and the summary is restored for the
+// support team.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/packs"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+)
+
+const systemPrompt = "You summarize customer-support call transcripts for the support team. " +
+	"Reply with three short lines: Issue, Action taken, Follow-up. " +
+	"Keep placeholders like <Indian Aadhaar_1> exactly as they are."
+
+func summarize(ctx context.Context, p *protect.Protector, oa *openai.Client, transcript string, dryRun bool) error {
+	call := protect.Chat(ctx, oa, openai.GPT4oMini, systemPrompt)
+	if dryRun {
+		call = func(string) (string, error) { return "", nil }
+	}
+	res, err := p.Run(ctx, transcript, call)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Protected: %s\n", res.Summary())
+	if dryRun {
+		fmt.Printf("\n%s\n", strings.TrimSpace(res.Safe))
+		return nil
+	}
+	fmt.Printf("\n%s\n", res.Text)
+	return nil
+}
+
+func main() {
+	_ = godotenv.Load()
+	dir := flag.String("dir", "transcripts", "directory of .txt transcripts")
+	dryRun := flag.Bool("dry-run", false, "print tokenized transcripts; skip OpenAI calls")
+	flag.Parse()
+	ctx := context.Background()
+
+	pol, err := packs.Policy("in")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	p := protect.New(bfclient.FromEnv(pol.ClientOptions()...))
+	p.Policy = pol
+	oa := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
+
+	files, err := filepath.Glob(filepath.Join(*dir, "*.txt"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(files) == 0 {
+		log.Fatalf("no transcripts in %s", *dir)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("== %s %s\n", filepath.Base(file), strings.Repeat("=", 50-len(filepath.Base(file))))
+		if err := summarize(ctx, p, oa, string(data), *dryRun); err != nil {
+			log.Printf("%s: %v", file, err)
+		}
+		fmt.Println()
+	}
+}
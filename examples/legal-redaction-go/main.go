@@ -0,0 +1,188 @@
+// Legal document redaction + Blindfold: Summarize contracts and pleadings,
+// and hand out a redacted copy with a restoration mapping for counsel.
+//
+// Each document in documents/ is tokenized with a policy that matches
+// parties by their legal suffix, US street addresses and federal case
+// numbers. The LLM summarizes the clauses from the tokenized text only.
+// The redacted document is written next to its mapping, so the redacted
+// copy can be shared widely while counsel, who also holds the mapping, can
+// restore it with -restore.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+const systemPrompt = "You are a paralegal. Summarize the legal document for counsel: " +
+	"one line naming the document type and the parties, then one bullet per clause or count " +
+	"with its practical effect. Keep placeholders like <Party_1> exactly as they are."
+
+// tokenized is a redacted document and where it was written.
+type tokenized struct {
+	Text        string
+	Mapping     map[string]string
+	RedactedOut string
+	MappingOut  string
+}
+
+// redact tokenizes the document at path and writes <name>.redacted.txt and
+// <name>.mapping.json to outDir.
+func redact(ctx context.Context, bf bfclient.Client, path, outDir string) (*tokenized, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	res, err := bf.Tokenize(ctx, string(data))
+	if err != nil {
+		return nil, fmt.Errorf("tokenize: %w", err)
+	}
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	doc := &tokenized{
+		Text:        res.Text,
+		Mapping:     res.Mapping,
+		RedactedOut: filepath.Join(outDir, base+".redacted.txt"),
+		MappingOut:  filepath.Join(outDir, base+".mapping.json"),
+	}
+	if err := os.WriteFile(doc.RedactedOut, []byte(res.Text), 0o644); err != nil {
+		return nil, err
+	}
+	m, err := json.MarshalIndent(res.Mapping, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	// The mapping restores every redacted value: counsel only
+	if err := os.WriteFile(doc.MappingOut, append(m, '\n'), 0o600); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func summarize(ctx context.Context, oa *openai.Client, doc *tokenized) (string, error) {
+	completion, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: openai.GPT4oMini,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: doc.Text},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("openai: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", errors.New("openai: empty response")
+	}
+	return completion.Choices[0].Message.Content, nil
+}
+
+// restore writes the original of a redacted document to stdout, as counsel
+// would with the mapping file.
+func restore(redactedPath, mappingPath string) error {
+	text, err := os.ReadFile(redactedPath)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(mappingPath)
+	if err != nil {
+		return err
+	}
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("%s: %w", mappingPath, err)
+	}
+	restored := mapping.Detokenize(string(text), m)
+	if left := mapping.Unresolved(restored, m); len(left) > 0 {
+		return fmt.Errorf("%d token(s) not in %s: %s", len(left), mappingPath, strings.Join(left, ", "))
+	}
+	_, err = fmt.Print(restored)
+	return err
+}
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply (default: the file's default)")
+	dir := flag.String("dir", "documents", "directory of .txt documents")
+	outDir := flag.String("out", "redacted", "directory for redacted documents and mappings")
+	dryRun := flag.Bool("dry-run", false, "redact only; skip OpenAI calls")
+	restorePath := flag.String("restore", "", "restore this redacted document with its mapping and print it")
+	flag.Parse()
+	ctx := context.Background()
+
+	if *restorePath != "" {
+		mappingPath := strings.TrimSuffix(*restorePath, ".redacted.txt") + ".mapping.json"
+		if err := restore(*restorePath, mappingPath); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+	oa := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
+
+	files, err := filepath.Glob(filepath.Join(*dir, "*.txt"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(files) == 0 {
+		log.Fatalf("no documents in %s", *dir)
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	for _, file := range files {
+		fmt.Printf("== %s %s\n", filepath.Base(file), strings.Repeat("=", 50-len(filepath.Base(file))))
+		doc, err := redact(ctx, bf, file, *outDir)
+		if err != nil {
+			log.Printf("%s: %v", file, err)
+			continue
+		}
+		counts := make(map[string]int)
+		for token := range doc.Mapping {
+			typ, _, _ := mapping.ParseToken(token)
+			counts[typ]++
+		}
+		types := make([]string, 0, len(counts))
+		for typ, n := range counts {
+			types = append(types, fmt.Sprintf("%s ×%d", typ, n))
+		}
+		sort.Strings(types)
+		fmt.Printf("Redacted: %s\n", strings.Join(types, ", "))
+		fmt.Printf("Wrote %s and %s\n", doc.RedactedOut, doc.MappingOut)
+
+		if *dryRun {
+			fmt.Println()
+			continue
+		}
+		summary, err := summarize(ctx, oa, doc)
+		if err != nil {
+			log.Printf("%s: %v", file, err)
+			continue
+		}
+		fmt.Printf("\n%s\n\n", bf.Detokenize(summary, doc.Mapping).Text)
+	}
+}
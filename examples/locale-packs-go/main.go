@@ -0,0 +1,70 @@
+// Regional identifier packs + Blindfold: Protect non-US identifiers.
+//
+// The default detectors are US-centric. pkg/packs bundles policy files for
+// other regions — locale detectors with check-digit validation, plus
+// patterns for the formats they miss — and this example applies one to a
+// sample text from that region.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/packs"
+)
+
+// samples holds one typical message per pack.
+var samples = map[string]string{
+	"eu": "Hallo, bitte überweisen Sie die Erstattung an DE89 3704 0044 0532 0130 00 (USt-IdNr. DE123456789). " +
+		"Mein Reisepass: C01X00T47. Erreichbar unter +49 30 12345678 oder, in Paris, appelez le 06 12 34 56 78.",
+	"uk": "Patient Jane Doe, NHS 943-476-5919, NI number ab 12 34 56 c, lives at 10 Downing Street, SW1A 2AA. " +
+		"GP surgery: 020 7946 0958, mobile 07700 900123.",
+	"in": "Customer Rahul Verma called about KYC. Aadhaar 7260 1815 9082, PAN ABCPE1234F. " +
+		"Call back on 98765 43210 or the office landline 080 41234567.",
+	"br": "Cliente com CPF 529.982.247-25, empresa CNPJ 12.ABC.345/01DE-35. " +
+		"Celular (11) 98765-4321, entrega na Rua Augusta, 500, CEP 01305-000.",
+}
+
+func protect(ctx context.Context, pack string) error {
+	pol, err := packs.Policy(pack)
+	if err != nil {
+		return err
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+
+	text := samples[pack]
+	tokenized, err := bf.Tokenize(ctx, text)
+	if err != nil {
+		return fmt.Errorf("tokenize: %w", err)
+	}
+	fmt.Printf("Input:     %s\n", text)
+	fmt.Printf("Tokenized: %s\n", tokenized.Text)
+	for _, e := range tokenized.DetectedEntities {
+		fmt.Printf("  %-24s %s\n", e.Type, e.Text)
+	}
+	return nil
+}
+
+func main() {
+	_ = godotenv.Load()
+	pack := flag.String("pack", "eu", "pack to apply ("+strings.Join(packs.Names(), ", ")+")")
+	flag.Parse()
+
+	if _, ok := samples[*pack]; !ok {
+		log.Fatalf("no sample for pack %q (have %s)", *pack, strings.Join(packs.Names(), ", "))
+	}
+	if err := protect(context.Background(), *pack); err != nil {
+		log.Fatal(err)
+	}
+}
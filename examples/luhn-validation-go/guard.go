@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"sync"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// cardLike matches 13 to 19 digits, optionally grouped by spaces or dashes:
+// the shapes a card detector considers.
+var cardLike = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+
+// luhn reports whether the digits of s pass the Luhn check.
+func luhn(s string) bool {
+	var d []int
+	for _, c := range s {
+		if '0' <= c && c <= '9' {
+			d = append(d, int(c-'0'))
+		}
+	}
+	if len(d) < 13 || len(d) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range d {
+		x := d[len(d)-1-i]
+		if i%2 == 1 {
+			if x *= 2; x > 9 {
+				x -= 9
+			}
+		}
+		sum += x
+	}
+	return sum%10 == 0
+}
+
+// Suppressed is a card-like number that failed the Luhn check.
+type Suppressed struct {
+	Value string
+	// Restored is set if the wrapped client had tokenized it and the guard
+	// put it back; otherwise the detector had already let it through.
+	Restored bool
+}
+
+// Stats counts what the guard saw across calls.
+type Stats struct {
+	Candidates int // card-like numbers in the input
+	Cards      int // candidates that passed the Luhn check
+	Suppressed int // candidates that failed it
+	Restored   int // failed candidates the wrapped client had tokenized
+}
+
+// Guard runs a Luhn check on every card-like number before it stays
+// tokenized as a Credit Card Number. Local-mode detection already checks
+// Luhn; cloud-mode NLP detection and custom patterns may not, and a 16-digit
+// trace ID tokenized as a card is a placeholder the model can't reason
+// about.
+type Guard struct {
+	next bfclient.Client
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+var _ bfclient.Client = (*Guard)(nil)
+
+// Wrap returns next with the Luhn guard applied.
+func Wrap(next bfclient.Client) *Guard {
+	return &Guard{next: next}
+}
+
+// Stats returns the counts so far.
+func (g *Guard) Stats() Stats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stats
+}
+
+// Detect drops Credit Card Number entities that fail the Luhn check.
+func (g *Guard) Detect(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.DetectResponse, error) {
+	res, err := g.next.Detect(ctx, text, opts...)
+	if err != nil {
+		return nil, err
+	}
+	out := &blindfold.DetectResponse{}
+	for _, e := range res.DetectedEntities {
+		if e.Type == blindfold.EntityCreditCard && !luhn(e.Text) {
+			continue
+		}
+		out.DetectedEntities = append(out.DetectedEntities, e)
+	}
+	out.EntitiesCount = len(out.DetectedEntities)
+	return out, nil
+}
+
+// Tokenize tokenizes with the wrapped client and restores Credit Card
+// Number tokens whose value fails the Luhn check.
+func (g *Guard) Tokenize(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	res, _, err := g.TokenizeReport(ctx, text, opts...)
+	return res, err
+}
+
+// TokenizeReport is Tokenize, also returning the card-like numbers of text
+// that failed the Luhn check.
+func (g *Guard) TokenizeReport(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, []Suppressed, error) {
+	res, err := g.next.Tokenize(ctx, text, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	restore := make(map[string]string)
+	restoredValues := make(map[string]bool)
+	for token, value := range res.Mapping {
+		if typ, _, _ := mapping.ParseToken(token); typ == blindfold.EntityCreditCard && !luhn(value) {
+			restore[token] = value
+			restoredValues[value] = true
+		}
+	}
+
+	var st Stats
+	var report []Suppressed
+	for _, c := range cardLike.FindAllString(text, -1) {
+		st.Candidates++
+		if luhn(c) {
+			st.Cards++
+			continue
+		}
+		st.Suppressed++
+		s := Suppressed{Value: c, Restored: restoredValues[c]}
+		if s.Restored {
+			st.Restored++
+		}
+		report = append(report, s)
+	}
+	g.mu.Lock()
+	g.stats.Candidates += st.Candidates
+	g.stats.Cards += st.Cards
+	g.stats.Suppressed += st.Suppressed
+	g.stats.Restored += st.Restored
+	g.mu.Unlock()
+
+	if len(restore) == 0 {
+		return res, report, nil
+	}
+	out := &blindfold.TokenizeResponse{
+		Text:    mapping.Detokenize(res.Text, restore),
+		Mapping: make(map[string]string, len(res.Mapping)),
+	}
+	for token, value := range res.Mapping {
+		if _, ok := restore[token]; !ok {
+			out.Mapping[token] = value
+		}
+	}
+	for _, e := range res.DetectedEntities {
+		if !(e.Type == blindfold.EntityCreditCard && restoredValues[e.Text]) {
+			out.DetectedEntities = append(out.DetectedEntities, e)
+		}
+	}
+	out.EntitiesCount = len(out.DetectedEntities)
+	return out, report, nil
+}
+
+// Detokenize passes through to the wrapped client.
+func (g *Guard) Detokenize(text string, m map[string]string) *blindfold.DetokenizeResponse {
+	return g.next.Detokenize(text, m)
+}
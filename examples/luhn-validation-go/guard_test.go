@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fake"
+)
+
+func TestLuhn(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want bool
+	}{
+		{"4111111111111111", true},
+		{"4111 1111 1111 1111", true},
+		{"4111-1111-1111-1111", true},
+		{"378282246310005", true}, // 15-digit Amex
+		{"4222222222222", true},   // 13 digits, the shortest
+		{"4111111111111112", false},
+		{"411111111117", false},         // checksum fits, but 12 digits
+		{"41111111111111111115", false}, // checksum fits, but 20 digits
+		{"", false},
+	} {
+		if got := luhn(c.in); got != c.want {
+			t.Errorf("luhn(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestTokenizeReport(t *testing.T) {
+	const (
+		card  = "4111 1111 1111 1111"
+		trace = "4111 1111 1111 1112" // 16 digits, fails Luhn
+	)
+	text := "Card " + card + ", trace " + trace
+	for _, c := range []struct {
+		name      string
+		detected  []string // values the wrapped client tokenizes as cards
+		wantText  string
+		wantMap   map[string]string
+		wantRep   []Suppressed
+		wantStats Stats
+	}{
+		{
+			name:      "tokenized trace ID restored",
+			detected:  []string{card, trace},
+			wantText:  "Card <Credit Card Number_1>, trace " + trace,
+			wantMap:   map[string]string{"<Credit Card Number_1>": card},
+			wantRep:   []Suppressed{{Value: trace, Restored: true}},
+			wantStats: Stats{Candidates: 2, Cards: 1, Suppressed: 1, Restored: 1},
+		},
+		{
+			name:      "trace ID already left alone",
+			detected:  []string{card},
+			wantText:  "Card <Credit Card Number_1>, trace " + trace,
+			wantMap:   map[string]string{"<Credit Card Number_1>": card},
+			wantRep:   []Suppressed{{Value: trace}},
+			wantStats: Stats{Candidates: 2, Cards: 1, Suppressed: 1},
+		},
+	} {
+		g := Wrap(fake.New(fake.WithEntity(blindfold.EntityCreditCard, c.detected...)))
+		res, rep, err := g.TokenizeReport(context.Background(), text)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if res.Text != c.wantText {
+			t.Errorf("%s: text = %q, want %q", c.name, res.Text, c.wantText)
+		}
+		if !reflect.DeepEqual(res.Mapping, c.wantMap) {
+			t.Errorf("%s: mapping = %v, want %v", c.name, res.Mapping, c.wantMap)
+		}
+		if len(res.DetectedEntities) != 1 || res.DetectedEntities[0].Text != card || res.EntitiesCount != 1 {
+			t.Errorf("%s: entities = %+v, want only the card", c.name, res.DetectedEntities)
+		}
+		if !reflect.DeepEqual(rep, c.wantRep) {
+			t.Errorf("%s: report = %+v, want %+v", c.name, rep, c.wantRep)
+		}
+		if g.Stats() != c.wantStats {
+			t.Errorf("%s: stats = %+v, want %+v", c.name, g.Stats(), c.wantStats)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Luhn validation + Blindfold: Keep 16-digit IDs out of the card bucket.
+//
+// Application logs are full of 13–19 digit identifiers — trace IDs, order
+// numbers, account references — that look exactly like card numbers. A card
+// number always passes the Luhn check; a random identifier passes one time
+// in ten. This example wraps the Blindfold client with a guard that
+// Luhn-checks every card-like number, puts back Credit Card Number tokens
+// whose value fails, and reports the suppressed false positives.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/joho/godotenv"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fake"
+)
+
+const sample = `10:04:11 INFO  checkout: order 4000123456789012 paid with 4111 1111 1111 1111
+10:04:12 DEBUG trace=7392018465529301 span=3 upstream=payments
+10:04:15 INFO  refund issued to 5500 0000 0000 0004 for order 4000123456789029
+10:04:19 WARN  account ref 1234-5678-9012-3456 flagged for review`
+
+// mask keeps the last four digits of a card-like value.
+func mask(s string) string {
+	n := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			n++
+		}
+	}
+	var b strings.Builder
+	seen := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			if seen++; seen <= n-4 {
+				c = '•'
+			}
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func main() {
+	_ = godotenv.Load()
+	simulate := flag.Bool("simulate", false, "use a detector that tags every card-like number, as an NLP model might")
+	flag.Parse()
+	ctx := context.Background()
+
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	var bf bfclient.Client = bfclient.FromEnv()
+	if *simulate {
+		bf = fake.New(fake.WithEntity(blindfold.EntityCreditCard, cardLike.FindAllString(sample, -1)...))
+	}
+	guard := Wrap(bf)
+
+	raw, err := bf.Tokenize(ctx, sample)
+	if err != nil {
+		log.Fatalf("tokenize: %v", err)
+	}
+	tokenized, suppressed, err := guard.TokenizeReport(ctx, sample)
+	if err != nil {
+		log.Fatalf("tokenize: %v", err)
+	}
+
+	fmt.Printf("Detector only:\n%s\n\n", raw.Text)
+	fmt.Printf("Luhn-validated:\n%s\n\n", tokenized.Text)
+
+	fmt.Printf("Suppressed %d false positive(s):\n", len(suppressed))
+	for _, s := range suppressed {
+		note := "not tokenized"
+		if s.Restored {
+			note = "token restored"
+		}
+		fmt.Printf("  %-22s %s\n", mask(s.Value), note)
+	}
+
+	st := guard.Stats()
+	fmt.Printf("\nCard-like numbers: %d, valid cards: %d, suppressed: %d, restored: %d\n",
+		st.Candidates, st.Cards, st.Suppressed, st.Restored)
+
+	restored := guard.Detokenize(tokenized.Text, tokenized.Mapping)
+	fmt.Printf("\nRestored:\n%s\n", restored.Text)
+}
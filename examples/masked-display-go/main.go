@@ -0,0 +1,64 @@
+// Partial masking + Blindfold: Show values people can recognise but not
+// read.
+//
+// Tokens are right for text going to an LLM, but wrong for a support
+// dashboard: an agent needs to see "the card ending 3456" or "an email at
+// acme.com", not <Credit Card Number_1>. This example detects PII once and
+// renders the same records three ways — tokenized, masked uniformly with
+// the SDK's Mask, and masked per entity type with pkg/masking.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/joho/godotenv"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/masking"
+)
+
+var records = []string{
+	"Refund requested by john.smith@acme.com for card 4111-1111-1110-3456.",
+	"Callback to +1 415-555-0134; SSN on file 123-45-6789.",
+	"Payout to IBAN DE89 3704 0044 0532 0130 00 confirmed, receipt sent to ap@globex.example.",
+}
+
+func main() {
+	_ = godotenv.Load()
+	ctx := context.Background()
+
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := bfclient.FromEnv(blindfold.WithLocales([]string{"us", "eu"}))
+
+	// Per-type rules: the defaults, with phone numbers showing only the last
+	// two digits on this screen
+	rules := masking.Defaults()
+	rules[blindfold.EntityPhoneNumber] = masking.KeepLast(2)
+
+	for _, text := range records {
+		detected, err := bf.Detect(ctx, text)
+		if err != nil {
+			log.Fatalf("detect: %v", err)
+		}
+		tokenized, err := bf.Tokenize(ctx, text)
+		if err != nil {
+			log.Fatalf("tokenize: %v", err)
+		}
+		uniform, err := bf.Mask(ctx, text, blindfold.WithCharsToShow(4), blindfold.WithFromEnd(true))
+		if err != nil {
+			log.Fatalf("mask: %v", err)
+		}
+
+		fmt.Printf("Original:  %s\n", text)
+		fmt.Printf("Tokenized: %s\n", tokenized.Text)
+		fmt.Printf("Uniform:   %s\n", uniform.Text)
+		fmt.Printf("Per type:  %s\n\n", rules.Apply(text, detected.DetectedEntities))
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+var demoToken = regexp.MustCompile(`<[A-Za-z ]+_\d+>`)
+
+// fakeModel answers chat completions with minutes scripted for the
+// sample transcripts, by meeting title. Like a real model it only uses
+// tokens from its input.
+func fakeModel(req openai.ChatCompletionRequest) string {
+	in := fakeopenai.Prompt(req)
+	// tok returns the n-th distinct token of a type, in order of
+	// appearance; participants come first, in the header
+	tok := func(typ string, n int) string {
+		seen := map[string]bool{}
+		for _, t := range demoToken.FindAllString(in, -1) {
+			if strings.HasPrefix(t, "<"+typ+"_") && !seen[t] {
+				seen[t] = true
+				if len(seen) == n {
+					return t
+				}
+			}
+		}
+		return "someone"
+	}
+	p := func(n int) string { return tok("Participant", n) }
+
+	var out Minutes
+	switch {
+	case strings.HasPrefix(in, "Meeting: renewal sync"):
+		out = Minutes{
+			Summary: "Pre-renewal check-in for " + tok("Customer", 1) + ", whose contract ends on 31 October. " +
+				tok("Person", 1) + " wants a discount after the September outage, which took their account (" + tok("Account Number", 1) + ")" +
+				" down for about six hours. Finance can approve up to ten percent off the first year on a two-year term.",
+			Decisions: []string{"Offer " + tok("Customer", 1) + " ten percent off the first year if they sign for two years."},
+			ActionItems: []ActionItem{
+				{Owner: p(2), Task: "Send the September incident report to " + tok("Person", 1) + " (" + tok("Email Address", 1) + "), copying " + p(1), Due: "Friday"},
+				{Owner: p(3), Task: "Prepare the revised quote", Due: "20 October"},
+				{Owner: p(1), Task: "Book a call with " + tok("Person", 1) + " on " + tok("Phone Number", 1), Due: "next week"},
+			},
+		}
+	case strings.HasPrefix(in, "Meeting: support standup"):
+		out = Minutes{
+			Summary: "Standup on the " + tok("Customer", 1) + " chargeback. A caller read out a full card number and the agent " +
+				"pasted it into ticket 88412. The customer is waiting for a callback once the refund clears.",
+			Decisions: []string{"Agents paste only the last four digits of a card into tickets."},
+			ActionItems: []ActionItem{
+				{Owner: p(2), Task: "Scrub the card number from ticket 88412 and the call recording", Due: "today"},
+				{Owner: p(3), Task: "Call the customer back on " + tok("Phone Number", 1) + " once billing confirms the refund"},
+				{Owner: p(2), Task: "Remind the team to paste only the last four digits", Due: "end of day"},
+			},
+		}
+	case strings.HasPrefix(in, "Meeting: Q4 roadmap planning"):
+		out = Minutes{
+			Summary: "Review of customer asks for Q4. " + tok("Customer", 1) + " needs SSO before rolling out to 4,000 seats, as " +
+				tok("Person", 1) + " asked. Shipping SSO in November means moving the reporting work to Q1, which also " +
+				"slips the reporting beta for two smaller customers.",
+			Decisions: []string{"Ship SSO in November and move the reporting work to Q1."},
+			ActionItems: []ActionItem{
+				{Owner: p(2), Task: "Write up the SSO plan", Due: "24 October"},
+				{Owner: p(3), Task: "Email " + tok("Person", 1) + " (" + tok("Email Address", 1) + ") the November date", Due: "once the SSO plan is done"},
+			},
+		}
+	default:
+		out = Minutes{Summary: "A meeting with no decisions or follow-ups recorded."}
+	}
+	content, _ := json.Marshal(out)
+	return string(content)
+}
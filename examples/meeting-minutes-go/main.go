@@ -0,0 +1,162 @@
+// Meeting transcripts + Blindfold: Turn a Zoom, Meet or Teams transcript
+// into minutes and action items without the LLM learning who was in the
+// meeting or which customers came up.
+//
+// The transcript export is parsed into speaker turns (transcript.go).
+// Participant names are taken from the speaker labels and attendee list
+// and added to the policy's denylist, first names included, so they are
+// tokenized even in local mode and each participant keeps one token
+// however they are addressed. Emails, phone numbers, cards, account
+// numbers and the customers on the policy's denylist are matched by the
+// policy. The LLM writes the minutes from tokens only. The internal
+// distribution copy is detokenized; the shared copy keeps neutral labels
+// like "Participant 1" and "Customer 2".
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply (default: the file's default)")
+	format := flag.String("format", "auto", "transcript format: auto, vtt (Zoom or Teams .vtt), zoom (Zoom .txt) or meet (Google Meet transcript as .txt)")
+	outDir := flag.String("out", "minutes", "directory for the internal and shared copies")
+	model := flag.String("model", openai.GPT4oMini, "OpenAI model")
+	dryRun := flag.Bool("dry-run", false, "print the tokenized transcripts; skip OpenAI calls and write nothing")
+	demo := flag.Bool("demo", false, "use a scripted model instead of OpenAI (no OpenAI account needed)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: meeting-minutes-go [flags] [transcript ...]\n\nWith no transcripts, every file in transcripts/ is read.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	ctx := context.Background()
+
+	files := flag.Args()
+	if len(files) == 0 {
+		var err error
+		if files, err = filepath.Glob(filepath.Join("transcripts", "*")); err != nil || len(files) == 0 {
+			log.Fatal("no transcripts given and none in transcripts/")
+		}
+	}
+
+	oaCfg := openai.DefaultConfig(os.Getenv("OPENAI_API_KEY"))
+	if *demo {
+		llm := fakeopenai.Server(fakeModel)
+		defer llm.Close()
+		oaCfg = fakeopenai.Config(llm)
+	} else if os.Getenv("OPENAI_API_KEY") == "" && !*dryRun {
+		log.Fatal("OPENAI_API_KEY is required (or run with -demo or -dry-run)")
+	}
+	oa := openai.NewClientWithConfig(oaCfg)
+
+	failed := 0
+	for _, path := range files {
+		if err := minutes(ctx, oa, path, *file, *name, *format, *model, *outDir, *dryRun); err != nil {
+			fmt.Printf("── %s: failed: %v\n\n", path, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// minutes writes the internal and shared minutes for one transcript.
+func minutes(ctx context.Context, oa *openai.Client, path, policyFile, policyName, format, model, outDir string, dryRun bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	t, err := parseTranscript(path, data, format)
+	if err != nil {
+		return err
+	}
+
+	// A fresh policy per meeting, so one meeting's participants aren't
+	// on the next one's denylist
+	pol, err := policyconf.Resolve(policyFile, policyName)
+	if err != nil {
+		return err
+	}
+	terms := participantTerms(t.Participants)
+	if err := addParticipants(pol, terms); err != nil {
+		return err
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+	p := protect.New(participants{Client: bf, terms: terms})
+	p.Strict = true
+
+	var mins *Minutes
+	res, err := p.Run(ctx, t.String(), func(safe string) (string, error) {
+		if dryRun {
+			return "", nil
+		}
+		var err error
+		if mins, err = draftMinutes(ctx, oa, model, safe); err != nil {
+			return "", err
+		}
+		return mins.markdown(internalNote), nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("── %s (%s transcript, %d turns, %d participants, %d entities protected)\n",
+		path, t.Format, len(t.Turns), len(t.Participants), len(res.Entities))
+	if dryRun {
+		fmt.Printf("\n%s\n\n", strings.TrimSpace(res.Safe))
+		return nil
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return err
+	}
+	base := filepath.Join(outDir, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	internal, shared := base+".internal.md", base+".shared.md"
+	// Only the internal copy gets real names and customer details back
+	if err := os.WriteFile(internal, []byte(res.Text), 0o600); err != nil {
+		return err
+	}
+	sharedCopy := mapping.ReplaceTokens(mins.markdown(sharedNote), label)
+	if err := os.WriteFile(shared, []byte(sharedCopy), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("\n%s\nWrote %s (internal) and %s (shared)\n\n", sharedCopy, internal, shared)
+	return nil
+}
+
+const (
+	internalNote = "Internal distribution only: real names and customer details. Don't forward outside the company."
+	sharedNote   = "Shared copy: participants and customer details are replaced with labels."
+)
+
+// label turns a token into a neutral label for the shared copy:
+// <Participant_2> becomes "Participant 2".
+func label(token string) string {
+	typ, n, ok := mapping.ParseToken(token)
+	if !ok {
+		return token
+	}
+	return fmt.Sprintf("%s %d", typ, n)
+}
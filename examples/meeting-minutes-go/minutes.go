@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+const systemPrompt = "You take minutes for internal meetings. From the transcript, reply with a JSON object: " +
+	`{"summary": "one paragraph", "decisions": ["..."], "action_items": [{"owner": "who", "task": "...", "due": "when, if stated"}]}. ` +
+	"Only record decisions and action items someone agreed to in the meeting. " +
+	"Keep placeholders like <Participant_1> and <Customer_1> exactly as they are."
+
+// ActionItem is a follow-up someone took on in the meeting.
+type ActionItem struct {
+	Owner string `json:"owner"`
+	Task  string `json:"task"`
+	Due   string `json:"due,omitempty"`
+}
+
+// Minutes are the LLM's notes on a meeting, still tokenized.
+type Minutes struct {
+	Title        string       `json:"-"`
+	Participants string       `json:"-"`
+	Summary      string       `json:"summary"`
+	Decisions    []string     `json:"decisions"`
+	ActionItems  []ActionItem `json:"action_items"`
+}
+
+// participantTerms returns the names to tokenize for a meeting's
+// participants, each mapped to the participant it names: every full name,
+// and the first name of a participant whose first name nobody else in the
+// meeting shares.
+func participantTerms(participants []string) map[string]string {
+	terms := make(map[string]string)
+	firsts := make(map[string][]string)
+	for _, p := range participants {
+		terms[p] = p
+		if f := strings.Fields(p); len(f) > 1 && unicode.IsUpper([]rune(f[0])[0]) {
+			firsts[f[0]] = append(firsts[f[0]], p)
+		}
+	}
+	for first, names := range firsts {
+		if _, ok := terms[first]; !ok && len(names) == 1 {
+			terms[first] = names[0]
+		}
+	}
+	return terms
+}
+
+// addParticipants puts a meeting's participants on pol's denylist as
+// Participant. Full names match ignoring case, like any denylist term;
+// first names only match as written, so Will's name doesn't tokenize
+// every "will" in the meeting.
+func addParticipants(pol *policyconf.Policy, terms map[string]string) error {
+	if pol.Deny == nil {
+		pol.Deny = make(map[string][]string)
+	}
+	for term, p := range terms {
+		if term == p {
+			pol.Deny["Participant"] = append(pol.Deny["Participant"], term)
+		} else {
+			pol.Patterns = append(pol.Patterns, policyconf.Pattern{Entity: "Participant", Regex: `\b` + regexp.QuoteMeta(term) + `\b`, Score: 1})
+		}
+	}
+	return pol.Compile()
+}
+
+// fold gives each participant a single token. The denylist tokenizes a
+// full name and a first name separately, and a name written in another
+// case gets a token of its own; fold rewrites all of them to the token of
+// the name in the participant list, which the transcript header always
+// has, so "Jane" and "JANE DOE" restore as "Jane Doe".
+func fold(text string, m map[string]string, terms map[string]string) (string, map[string]string) {
+	byName := make(map[string]string)
+	for token, value := range m {
+		if terms[value] == value {
+			byName[value] = token
+		}
+	}
+	lower := make(map[string]string, len(terms))
+	for term, p := range terms {
+		lower[strings.ToLower(strings.Join(strings.Fields(term), " "))] = p
+	}
+	rewrite := make(map[string]string)
+	for token, value := range m {
+		p, ok := lower[strings.ToLower(strings.Join(strings.Fields(value), " "))]
+		if canonical := byName[p]; ok && canonical != "" && canonical != token {
+			rewrite[token] = canonical
+		}
+	}
+	if len(rewrite) == 0 {
+		return text, m
+	}
+	out := make(map[string]string, len(m)-len(rewrite))
+	for token, value := range m {
+		if _, ok := rewrite[token]; !ok {
+			out[token] = value
+		}
+	}
+	return mapping.ReplaceTokens(text, func(token string) string {
+		if canonical, ok := rewrite[token]; ok {
+			return canonical
+		}
+		return token
+	}), out
+}
+
+// participants is a Client whose Tokenize folds each participant into one
+// token, so a Protector sees the folded text and mapping.
+type participants struct {
+	bfclient.Client
+	terms map[string]string
+}
+
+func (c participants) Tokenize(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	res, err := c.Client.Tokenize(ctx, text, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res.Text, res.Mapping = fold(res.Text, res.Mapping, c.terms)
+	return res, nil
+}
+
+// draftMinutes has the LLM write minutes from a tokenized transcript, as
+// rendered by Transcript.String.
+func draftMinutes(ctx context.Context, oa *openai.Client, model, tokenized string) (*Minutes, error) {
+	completion, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: tokenized},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("openai: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, errors.New("openai: empty response")
+	}
+	mins := &Minutes{}
+	if err := json.Unmarshal([]byte(completion.Choices[0].Message.Content), mins); err != nil {
+		return nil, fmt.Errorf("parse reply: %w", err)
+	}
+	// The header lines name the meeting and everyone in it, tokenized
+	head := strings.SplitN(tokenized, "\n", 3)
+	mins.Title = strings.TrimPrefix(head[0], "Meeting: ")
+	if len(head) > 1 {
+		mins.Participants = strings.TrimPrefix(head[1], "Participants: ")
+	}
+	return mins, nil
+}
+
+// markdown renders the minutes, with a note under the title on who the
+// copy is for.
+func (mins *Minutes) markdown(note string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Minutes: %s\n\n_%s_\n\n**Participants:** %s\n\n## Summary\n\n%s\n", mins.Title, note, mins.Participants, mins.Summary)
+	if len(mins.Decisions) > 0 {
+		b.WriteString("\n## Decisions\n\n")
+		for _, d := range mins.Decisions {
+			fmt.Fprintf(&b, "- %s\n", d)
+		}
+	}
+	b.WriteString("\n## Action items\n\n")
+	if len(mins.ActionItems) == 0 {
+		b.WriteString("None.\n")
+		return b.String()
+	}
+	b.WriteString("| Owner | Task | Due |\n|---|---|---|\n")
+	for _, a := range mins.ActionItems {
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", cell(a.Owner), cell(a.Task), cell(a.Due))
+	}
+	return b.String()
+}
+
+// cell escapes a value for a Markdown table cell.
+func cell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
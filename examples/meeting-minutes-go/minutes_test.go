@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+func TestParticipantTerms(t *testing.T) {
+	got := participantTerms([]string{"Jane Doe", "Jane Smith", "Li Wei", "Cher", "+1 415 555 0142"})
+	want := map[string]string{
+		"Jane Doe":        "Jane Doe",
+		"Jane Smith":      "Jane Smith", // two Janes: neither first name is theirs alone
+		"Li":              "Li Wei",
+		"Li Wei":          "Li Wei",
+		"Cher":            "Cher",
+		"+1 415 555 0142": "+1 415 555 0142",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("participantTerms = %q, want %q", got, want)
+	}
+}
+
+func TestFold(t *testing.T) {
+	tr := &Transcript{Title: "sync", Participants: []string{"Jane Doe", "Will Hart"}, Turns: []Turn{
+		{"Jane Doe", "00:00:01", "Will, you will send it?"},
+		{"Will Hart", "00:00:05", "Yes. And JANE DOE gets a copy, Jane."},
+	}}
+	terms := participantTerms(tr.Participants)
+	pol := &policyconf.Policy{}
+	if err := addParticipants(pol, terms); err != nil {
+		t.Fatal(err)
+	}
+	bf := pol.Wrap(blindfold.New(blindfold.WithMode("local")))
+	res, err := bf.Tokenize(context.Background(), tr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, m := fold(res.Text, res.Mapping, terms)
+	want := "Meeting: sync\nParticipants: <Participant_1>, <Participant_2>\n\n" +
+		"[00:00:01] <Participant_1>: <Participant_2>, you will send it?\n" +
+		"[00:00:05] <Participant_2>: Yes. And <Participant_1> gets a copy, <Participant_1>.\n"
+	if text != want {
+		t.Errorf("folded text\n%s\nwant\n%s", text, want)
+	}
+	if want := map[string]string{"<Participant_1>": "Jane Doe", "<Participant_2>": "Will Hart"}; !reflect.DeepEqual(m, want) {
+		t.Errorf("folded mapping %q, want %q", m, want)
+	}
+	if got := bf.Detokenize(text, m).Text; !strings.Contains(got, "Will Hart, you will send it?") {
+		t.Errorf("restored %q", got)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Transcript is a meeting as said, speaker by speaker.
+type Transcript struct {
+	Title  string
+	Format string
+	// Participants are the attendees listed in the export, then every
+	// other speaker, in order of first appearance.
+	Participants []string
+	Turns        []Turn
+}
+
+// Turn is what one speaker said before someone else spoke.
+type Turn struct {
+	Speaker string
+	At      string // hh:mm:ss into the meeting, or the clock time Zoom wrote
+	Text    string
+}
+
+var (
+	vttTiming = regexp.MustCompile(`^((?:\d+:)?\d{2}:\d{2})[.,]\d{3}\s+-->`)
+	vttVoice  = regexp.MustCompile(`^<v(?:\.[\w.-]+)?\s+([^>]+)>(.*?)(?:</v>)?$`)
+	vttTag    = regexp.MustCompile(`</?[a-z](?:[^>]*)>`)
+	zoomLabel = regexp.MustCompile(`^\[(.+)\]\s+(\d{1,2}:\d{2}:\d{2})$`)
+	clock     = regexp.MustCompile(`^\d{1,2}:\d{2}:\d{2}$`)
+)
+
+// parseTranscript reads a transcript export. format is "vtt" (Zoom, and
+// Teams, which marks speakers with <v> tags), "zoom" (Zoom's .txt with
+// "[Name] 10:02:11" headers) or "meet" (a Google Meet transcript doc
+// downloaded as text); "auto" tells them apart by their first line.
+func parseTranscript(file string, data []byte, format string) (*Transcript, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if format == "auto" {
+		format = detect(lines)
+	}
+	t := &Transcript{Format: format}
+	switch format {
+	case "vtt":
+		t.parseVTT(lines)
+	case "zoom":
+		t.parseZoom(lines)
+	case "meet":
+		t.parseMeet(lines)
+	default:
+		return nil, fmt.Errorf("unknown format %q (want auto, vtt, zoom or meet)", format)
+	}
+	if t.Title == "" {
+		base := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		t.Title = strings.Join(strings.FieldsFunc(base, func(r rune) bool { return r == '-' || r == '_' }), " ")
+	}
+	if len(t.Turns) == 0 {
+		return nil, fmt.Errorf("%s: no speaker turns found in this %s transcript", file, format)
+	}
+	return t, nil
+}
+
+func detect(lines []string) string {
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		switch {
+		case l == "":
+			continue
+		case strings.HasPrefix(l, "WEBVTT"):
+			return "vtt"
+		case zoomLabel.MatchString(l):
+			return "zoom"
+		}
+		return "meet"
+	}
+	return "meet"
+}
+
+// parseVTT reads WebVTT cues. A cue's speaker comes from a <v> tag, or a
+// "Name:" prefix as Zoom writes it; a cue with neither continues the
+// last speaker's turn.
+func (t *Transcript) parseVTT(lines []string) {
+	var at string
+	var text []string
+	flush := func() {
+		if at != "" && len(text) > 0 {
+			line := strings.Join(text, " ")
+			speaker, said := "", line
+			if m := vttVoice.FindStringSubmatch(line); m != nil {
+				speaker, said = strings.TrimSpace(m[1]), m[2]
+			} else if name, rest, ok := speakerLabel(line, nil); ok {
+				speaker, said = name, rest
+			}
+			t.add(speaker, at, strings.TrimSpace(vttTag.ReplaceAllString(said, "")))
+		}
+		at, text = "", nil
+	}
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		switch {
+		case l == "":
+			flush()
+		case at == "" && vttTiming.MatchString(l):
+			at = hms(vttTiming.FindStringSubmatch(l)[1])
+		case at != "":
+			text = append(text, l)
+		}
+		// Anything else is the header, a cue ID or a NOTE or STYLE block
+	}
+	flush()
+}
+
+// parseZoom reads Zoom's text transcript: a "[Name] hh:mm:ss" line, then
+// what they said.
+func (t *Transcript) parseZoom(lines []string) {
+	var speaker, at string
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		if m := zoomLabel.FindStringSubmatch(l); m != nil {
+			speaker, at = strings.TrimSpace(m[1]), hms(m[2])
+			continue
+		}
+		if l != "" && speaker != "" {
+			t.add(speaker, at, l)
+		}
+	}
+}
+
+// parseMeet reads a Google Meet transcript: a title line, an Attendees
+// list, then "Name: text" paragraphs under a timestamp every few minutes.
+// With an attendee list, only attendees start a turn, so a line such as
+// "Note: ..." stays part of the turn it is in.
+func (t *Transcript) parseMeet(lines []string) {
+	var attendees map[string]bool
+	var at string
+	started := false
+	for i := 0; i < len(lines); i++ {
+		l := strings.TrimSpace(lines[i])
+		switch {
+		case l == "":
+		case t.Title == "" && !started:
+			t.Title = strings.TrimSuffix(l, " - Transcript")
+		case l == "Attendees" && !started && i+1 < len(lines):
+			i++
+			attendees = make(map[string]bool)
+			for _, name := range strings.Split(lines[i], ",") {
+				if name = strings.TrimSpace(name); name != "" && !attendees[name] {
+					attendees[name] = true
+					t.Participants = append(t.Participants, name)
+				}
+			}
+		case l == "Transcript" && !started:
+			started = true
+		case clock.MatchString(l):
+			started, at = true, hms(l)
+		case strings.HasPrefix(l, "Transcription ended after "), strings.HasPrefix(l, "This editable transcript was computer generated"):
+		default:
+			if at == "" {
+				at = "00:00:00"
+			}
+			if name, rest, ok := speakerLabel(l, attendees); ok {
+				started = true
+				t.add(name, at, rest)
+			} else if started && len(t.Turns) > 0 {
+				t.add("", at, l)
+			}
+		}
+	}
+}
+
+// speakerLabel splits a "Name: text" line. Without a list of known names, a
+// name is up to five words starting with a capital, digit or +, which
+// is how the exports write display names and dial-in numbers.
+func speakerLabel(line string, known map[string]bool) (name, rest string, ok bool) {
+	name, rest, ok = strings.Cut(line, ": ")
+	if !ok {
+		return "", "", false
+	}
+	name = strings.TrimSpace(name)
+	if known != nil {
+		return name, strings.TrimSpace(rest), known[name]
+	}
+	first := []rune(name)
+	if len(first) == 0 || len(strings.Fields(name)) > 5 || !(unicode.IsUpper(first[0]) || unicode.IsDigit(first[0]) || first[0] == '+') {
+		return "", "", false
+	}
+	return name, strings.TrimSpace(rest), true
+}
+
+// add appends what a speaker said, to their last turn if nobody spoke in
+// between. An empty speaker continues the last turn.
+func (t *Transcript) add(speaker, at, text string) {
+	if text == "" {
+		return
+	}
+	if n := len(t.Turns); n > 0 && (speaker == "" || t.Turns[n-1].Speaker == speaker) {
+		t.Turns[n-1].Text += " " + text
+		return
+	}
+	if speaker == "" {
+		t.Turns = append(t.Turns, Turn{Speaker: "Unknown speaker", At: at, Text: text})
+		return
+	}
+	t.Turns = append(t.Turns, Turn{Speaker: speaker, At: at, Text: text})
+	for _, p := range t.Participants {
+		if p == speaker {
+			return
+		}
+	}
+	t.Participants = append(t.Participants, speaker)
+}
+
+// hms pads a timestamp to hh:mm:ss.
+func hms(s string) string {
+	if strings.Count(s, ":") == 1 {
+		s = "00:" + s
+	}
+	if len(s) == 7 {
+		s = "0" + s
+	}
+	return s
+}
+
+// String renders the transcript as the LLM reads it: a header naming
+// everyone, then one line per turn.
+func (t *Transcript) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Meeting: %s\nParticipants: %s\n\n", t.Title, strings.Join(t.Participants, ", "))
+	for _, turn := range t.Turns {
+		fmt.Fprintf(&b, "[%s] %s: %s\n", turn.At, turn.Speaker, turn.Text)
+	}
+	return b.String()
+}
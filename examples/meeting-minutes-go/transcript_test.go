@@ -0,0 +1,92 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTranscript(t *testing.T) {
+	for _, c := range []struct {
+		name, data   string
+		format       string
+		title        string
+		participants []string
+		turns        []Turn
+	}{
+		{
+			name: "weekly-sync.vtt",
+			data: "\ufeffWEBVTT\r\n\r\n1\r\n00:00:01.000 --> 00:00:03.000\r\nJane Doe: Hello\r\n\r\n" +
+				"2\r\n00:00:03.500 --> 00:00:05.000\r\nall.\r\n\r\n" +
+				"NOTE not a cue\r\n\r\n" +
+				"00:01:02.000 --> 00:01:04.000\r\nOmar Haddad: Hi, <i>Jane</i>.\r\nSecond line\r\n",
+			format:       "vtt",
+			title:        "weekly sync",
+			participants: []string{"Jane Doe", "Omar Haddad"},
+			turns: []Turn{
+				{"Jane Doe", "00:00:01", "Hello all."},
+				{"Omar Haddad", "00:01:02", "Hi, Jane. Second line"},
+			},
+		},
+		{
+			name: "teams.vtt",
+			data: "WEBVTT\n\nab12/3-0\n00:00:04.120 --> 00:00:06.000\n<v Li Wei>Can you hear me?</v>\n\n" +
+				"00:00:06.500 --> 00:00:07.000\n<v.loud Aisha Khan>Yes.</v>\n",
+			format:       "vtt",
+			title:        "teams",
+			participants: []string{"Li Wei", "Aisha Khan"},
+			turns: []Turn{
+				{"Li Wei", "00:00:04", "Can you hear me?"},
+				{"Aisha Khan", "00:00:06", "Yes."},
+			},
+		},
+		{
+			name:         "standup.txt",
+			data:         "[Li Wei] 9:30:04\nMorning.\n\n[Li Wei] 09:30:09\nLet's start.\n\n[+1 415 555 0142] 09:30:15\nDialed in.\n",
+			format:       "zoom",
+			title:        "standup",
+			participants: []string{"Li Wei", "+1 415 555 0142"},
+			turns: []Turn{
+				{"Li Wei", "09:30:04", "Morning. Let's start."},
+				{"+1 415 555 0142", "09:30:15", "Dialed in."},
+			},
+		},
+		{
+			name: "planning.txt",
+			data: "Planning - 2026/10/12 14:00 CEST - Transcript\nAttendees\nJane Doe, Li Wei, Tom Becker\nTranscript\n00:00:00\n\n" +
+				"Jane Doe: First item.\nNote: this stays with Jane.\nLi Wei: Agreed.\n00:05:00\n\nJane Doe: Done.\n" +
+				"Transcription ended after 00:06:10\n\nThis editable transcript was computer generated and might contain errors.\n",
+			format:       "meet",
+			title:        "Planning - 2026/10/12 14:00 CEST",
+			participants: []string{"Jane Doe", "Li Wei", "Tom Becker"},
+			turns: []Turn{
+				{"Jane Doe", "00:00:00", "First item. Note: this stays with Jane."},
+				{"Li Wei", "00:00:00", "Agreed."},
+				{"Jane Doe", "00:05:00", "Done."},
+			},
+		},
+	} {
+		tr, err := parseTranscript(c.name, []byte(c.data), "auto")
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if tr.Format != c.format || tr.Title != c.title {
+			t.Errorf("%s: format %q, title %q; want %q, %q", c.name, tr.Format, tr.Title, c.format, c.title)
+		}
+		if !reflect.DeepEqual(tr.Participants, c.participants) {
+			t.Errorf("%s: participants %q, want %q", c.name, tr.Participants, c.participants)
+		}
+		if !reflect.DeepEqual(tr.Turns, c.turns) {
+			t.Errorf("%s: turns\n%q\nwant\n%q", c.name, tr.Turns, c.turns)
+		}
+	}
+}
+
+func TestParseTranscriptEmpty(t *testing.T) {
+	if _, err := parseTranscript("empty.vtt", []byte("WEBVTT\n\n"), "auto"); err == nil {
+		t.Error("want an error for a transcript without turns")
+	}
+	if _, err := parseTranscript("notes.txt", []byte("Jane Doe: hi"), "docx"); err == nil {
+		t.Error("want an error for an unknown format")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+// newFakeAPI stands in for the chat completions and moderation
+// endpoints. Moderation scores phrases, the way a classifier reacts to
+// words, so a phrase hidden inside a token no longer counts. Chat replies
+// are scripted by topic and reuse the message's tokens.
+func newFakeAPI() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/moderations", fakeModeration)
+	mux.Handle("/v1/chat/completions", fakeopenai.Handler(fakeModel))
+	return httptest.NewServer(mux)
+}
+
+var phrases = []struct {
+	phrase   string
+	category string
+	score    float32
+}{
+	{"hurt someone", "violence", 0.93},
+	{"hurt yourself", "self-harm", 0.89},
+	{"idiots", "harassment", 0.84},
+}
+
+var nonLetters = regexp.MustCompile(`[^a-z]+`)
+
+func scores(text string) (openai.ResultCategories, openai.ResultCategoryScores, bool) {
+	norm := " " + nonLetters.ReplaceAllString(strings.ToLower(text), " ") + " "
+	var c openai.ResultCategories
+	s := openai.ResultCategoryScores{Violence: 0.01, Harassment: 0.01, SelfHarm: 0.01}
+	flagged := false
+	for _, p := range phrases {
+		if !strings.Contains(norm, " "+p.phrase+" ") {
+			continue
+		}
+		flagged = true
+		switch p.category {
+		case "violence":
+			s.Violence, c.Violence = p.score, true
+		case "self-harm":
+			s.SelfHarm, c.SelfHarm = p.score, true
+		case "harassment":
+			s.Harassment, c.Harassment = p.score, true
+		}
+	}
+	return c, s, flagged
+}
+
+var (
+	emailToken = regexp.MustCompile(`<Email Address_\d+>`)
+	orderNo    = regexp.MustCompile(`order \d+`)
+)
+
+func fakeModeration(w http.ResponseWriter, r *http.Request) {
+	var req openai.ModerationRequest
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	c, s, flagged := scores(req.Input)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(openai.ModerationResponse{ID: "modr-demo", Model: req.Model,
+		Results: []openai.Result{{Categories: c, CategoryScores: s, Flagged: flagged}}})
+}
+
+func fakeModel(req openai.ChatCompletionRequest) string {
+	q := fakeopenai.Prompt(req)
+	switch {
+	case strings.Contains(q, "arrived broken"):
+		return "Sorry about " + orderNo.FindString(q) + "! A replacement is on its way, and tracking details will go to " + emailToken.FindString(q) + "."
+	case strings.Contains(q, "update my account email"):
+		return "Done: your account email is now " + emailToken.FindString(q) + "."
+	}
+	return "Thanks for your message; an agent will follow up shortly."
+}
@@ -0,0 +1,207 @@
+// Moderation + Blindfold: Run OpenAI moderation on tokenized content, and
+// re-check the detokenized reply before it is shown.
+//
+// Moderation is an API call like any other, so it gets the same tokenized
+// text the chat model gets. Most harm is in the words around the PII, not
+// in the PII itself: a threat stays a threat when the phone number in it
+// becomes <Phone Number_1>, and its scores barely move. What tokenization
+// does hide is harm carried by a value, such as an abusive email address.
+// The chat model never sees it, but the user sees it once the reply is
+// restored, so the final text is moderated once more after
+// detokenization, and withheld with the values masked if it is flagged.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/redact"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+const systemPrompt = "You are a customer support assistant. Reply in one or two sentences. " +
+	"Values like <Email Address_1> are placeholders for customer data: copy them exactly as written."
+
+// refusal is what a user gets when their message is flagged.
+const refusal = "Sorry, we can't help with that message. If you need support, please write to us again."
+
+func ask(ctx context.Context, oa *openai.Client, model, message string) (string, error) {
+	res, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: message},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(res.Choices) == 0 {
+		return "", errors.New("empty response")
+	}
+	return strings.TrimSpace(res.Choices[0].Message.Content), nil
+}
+
+// masked replaces every token with its type label, for replies withheld
+// by the final re-check.
+func masked(text string) string {
+	return mapping.ReplaceTokens(text, func(token string) string {
+		typ, _, _ := mapping.ParseToken(token)
+		return redact.Label(typ)
+	})
+}
+
+type pipeline struct {
+	protector *protect.Protector
+	oa        *openai.Client
+	model     string
+	modModel  string
+	compare   bool
+	recheck   bool
+	flagged   map[string]int // stage → messages stopped there
+}
+
+// handle runs one message through the pipeline and returns the reply the
+// user sees.
+func (p *pipeline) handle(ctx context.Context, message string) (string, error) {
+	res, err := p.protector.Run(ctx, message, func(tokenized string) (string, error) {
+		fmt.Printf("   tokenized:         %s\n", tokenized)
+		in, err := moderate(ctx, p.oa, p.modModel, tokenized)
+		if err != nil {
+			return "", err
+		}
+		fmt.Printf("   input moderation:  %s\n", in)
+		if p.compare {
+			// The one place raw text goes out: -compare is for synthetic data
+			raw, err := moderate(ctx, p.oa, p.modModel, message)
+			if err != nil {
+				return "", err
+			}
+			note := ""
+			if raw.Flagged != in.Flagged {
+				note = "  ← verdict differs: the harm is in a value"
+			}
+			fmt.Printf("   raw vs tokenized:  %s%s\n", shift(raw, in), note)
+		}
+		if in.Flagged {
+			p.flagged["input"]++
+			return refusal, nil
+		}
+
+		answer, err := ask(ctx, p.oa, p.model, tokenized)
+		if err != nil {
+			return "", fmt.Errorf("chat: %w", err)
+		}
+		fmt.Printf("   answer:            %s\n", answer)
+		out, err := moderate(ctx, p.oa, p.modModel, answer)
+		if err != nil {
+			return "", err
+		}
+		fmt.Printf("   output moderation: %s\n", out)
+		if out.Flagged {
+			p.flagged["output"]++
+			return refusal, nil
+		}
+		return answer, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !p.recheck || res.Text == res.Output {
+		// Nothing was restored, so the output check already covered it
+		return res.Text, nil
+	}
+	again, err := moderate(ctx, p.oa, p.modModel, res.Text)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("   final re-check:    %s\n", again)
+	if again.Flagged {
+		p.flagged["final"]++
+		return masked(res.Output), nil
+	}
+	return res.Text, nil
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, sc.Err()
+}
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "", "policy file (YAML or JSON); empty = built-in policies only")
+	name := flag.String("policy", "strict", "policy to apply, by name")
+	messages := flag.String("messages", "messages.txt", "incoming messages, one per line")
+	model := flag.String("model", openai.GPT4oMini, "chat model")
+	modModel := flag.String("moderation-model", openai.ModerationOmniLatest, "moderation model")
+	compare := flag.Bool("compare", false, "also moderate the raw messages and show how scores move (synthetic data only)")
+	recheck := flag.Bool("recheck", true, "moderate the detokenized reply before showing it")
+	demo := flag.Bool("demo", false, "use scripted in-process chat and moderation instead of OpenAI")
+	flag.Parse()
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	protector := protect.New(bfclient.FromEnv(pol.ClientOptions()...))
+	protector.Policy = pol
+	protector.Strict = true
+
+	cfg := openai.DefaultConfig(os.Getenv("OPENAI_API_KEY"))
+	if *demo {
+		srv := newFakeAPI()
+		defer srv.Close()
+		cfg = fakeopenai.Config(srv)
+	} else if os.Getenv("OPENAI_API_KEY") == "" {
+		log.Fatal("OPENAI_API_KEY is required (or run with -demo)")
+	}
+
+	lines, err := readLines(*messages)
+	if err != nil {
+		log.Fatal(err)
+	}
+	p := &pipeline{protector: protector, oa: openai.NewClientWithConfig(cfg), model: *model, modModel: *modModel,
+		compare: *compare, recheck: *recheck, flagged: make(map[string]int)}
+	for i, msg := range lines {
+		fmt.Printf("── Message %d: %s\n", i+1, msg)
+		reply, err := p.handle(ctx, msg)
+		if err != nil {
+			log.Fatalf("message %d: %v", i+1, err)
+		}
+		fmt.Printf("   → reply:           %s\n\n", reply)
+	}
+	fmt.Printf("%d messages: %d stopped at input moderation, %d at output moderation, %d withheld by the final re-check\n",
+		len(lines), p.flagged["input"], p.flagged["output"], p.flagged["final"])
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Verdict is a moderation result in a form that is easy to compare.
+type Verdict struct {
+	Flagged    bool
+	Categories []string           // flagged categories, sorted
+	Scores     map[string]float64 // every category's score
+}
+
+// moderate runs the moderation endpoint on text.
+func moderate(ctx context.Context, oa *openai.Client, model, text string) (Verdict, error) {
+	res, err := oa.Moderations(ctx, openai.ModerationRequest{Input: text, Model: model})
+	if err != nil {
+		return Verdict{}, fmt.Errorf("moderation: %w", err)
+	}
+	if len(res.Results) == 0 {
+		return Verdict{}, fmt.Errorf("moderation: empty response")
+	}
+	r := res.Results[0]
+	v := Verdict{Flagged: r.Flagged}
+	// The SDK has a field per category; the JSON names are the ones the
+	// API documents
+	var flags map[string]bool
+	if err := roundTrip(r.Categories, &flags); err != nil {
+		return Verdict{}, err
+	}
+	if err := roundTrip(r.CategoryScores, &v.Scores); err != nil {
+		return Verdict{}, err
+	}
+	for c, on := range flags {
+		if on {
+			v.Categories = append(v.Categories, c)
+		}
+	}
+	sort.Strings(v.Categories)
+	return v, nil
+}
+
+func roundTrip(in, out any) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// top returns the highest-scoring category.
+func (v Verdict) top() (string, float64) {
+	best, score := "", -1.0
+	for c, s := range v.Scores {
+		if s > score || (s == score && c < best) {
+			best, score = c, s
+		}
+	}
+	return best, score
+}
+
+func (v Verdict) String() string {
+	if v.Flagged {
+		cats := make([]string, len(v.Categories))
+		for i, c := range v.Categories {
+			cats[i] = fmt.Sprintf("%s %.2f", c, v.Scores[c])
+		}
+		return "FLAGGED (" + strings.Join(cats, ", ") + ")"
+	}
+	c, s := v.top()
+	return fmt.Sprintf("ok (highest: %s %.2f)", c, s)
+}
+
+// shift describes how scores moved from a to b, for the categories
+// either of them flagged or, if none, the highest one.
+func shift(a, b Verdict) string {
+	cats := append(append([]string{}, a.Categories...), b.Categories...)
+	if len(cats) == 0 {
+		c, _ := a.top()
+		cats = []string{c}
+	}
+	sort.Strings(cats)
+	var parts []string
+	for i, c := range cats {
+		if i > 0 && cats[i-1] == c {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s %.2f → %.2f", c, a.Scores[c], b.Scores[c]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/leakscan"
+)
+
+const placeholderRule = " Values like <Person_1> and <Email Address_1> are placeholders for real customer data: " +
+	"copy them exactly as written and never guess what they stand for."
+
+// roles are the agents' system prompts. The first word names the role;
+// the demo model dispatches on it.
+var roles = map[string]string{
+	"researcher": "Researcher. Read the escalation case and list the facts a reply needs as short bullet points: " +
+		"who the customer is, what happened in order, what they are asking for, and which policy applies." + placeholderRule,
+	"writer": "Writer. Draft an email reply to the customer from the researcher's facts. Apologize where we failed, " +
+		"say exactly what we are doing and when. If reviewer feedback is included, revise the draft to address it." + placeholderRule,
+	"reviewer": "Reviewer. Check the draft against the facts: every commitment must be backed by a fact, every failure " +
+		"acknowledged, placeholders intact. Reply APPROVED on the first line if it can be sent, otherwise REVISE: followed by what to change." + placeholderRule,
+}
+
+// Agent is one specialized model call. It has no access to the mapping:
+// it can only read and write what the bus hands it.
+type Agent struct {
+	Name  string
+	oa    *openai.Client
+	model string
+}
+
+// Ask sends input to the agent with its role prompt and returns its reply.
+func (a *Agent) Ask(ctx context.Context, input string) (string, error) {
+	res, err := a.oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: a.model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: roles[a.Name]},
+			{Role: openai.ChatMessageRoleUser, Content: input},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", a.Name, err)
+	}
+	if len(res.Choices) == 0 {
+		return "", fmt.Errorf("%s: empty response", a.Name)
+	}
+	return strings.TrimSpace(res.Choices[0].Message.Content), nil
+}
+
+// Envelope is one message between agents, as delivered.
+type Envelope struct {
+	From, To string
+	Content  string
+	// Redacted counts real values the sender produced that the bus
+	// removed before delivery.
+	Redacted int
+}
+
+// Bus carries every message between agents and is the only way they
+// talk. It scans each message for real PII, which no agent should
+// produce since none was given any, and redacts it before delivery, so a
+// hallucinated phone number in one agent's output never reaches the next.
+type Bus struct {
+	scan *leakscan.Scanner
+	Log  []Envelope
+}
+
+// Send delivers content from one agent to another and returns what the
+// recipient gets.
+func (b *Bus) Send(ctx context.Context, from, to, content string) (string, error) {
+	out, leaks, err := b.scan.Scan(ctx, content)
+	if err != nil {
+		// An unscanned message isn't delivered
+		return "", fmt.Errorf("bus %s → %s: %w", from, to, err)
+	}
+	b.Log = append(b.Log, Envelope{From: from, To: to, Content: out, Redacted: len(leaks)})
+	return out, nil
+}
+
+// Audit reports how many delivered messages contain one of values. The
+// mapping never leaves the intake and presentation layers, so for a
+// sound pipeline this is zero.
+func (b *Bus) Audit(values []string) int {
+	n := 0
+	for _, e := range b.Log {
+		for _, v := range values {
+			if strings.Contains(e.Content, v) {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
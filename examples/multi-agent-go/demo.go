@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+var tokenOf = map[string]*regexp.Regexp{
+	"person":  regexp.MustCompile(`<Person_\d+>`),
+	"email":   regexp.MustCompile(`<Email Address_\d+>`),
+	"phone":   regexp.MustCompile(`<Phone Number_\d+>`),
+	"card":    regexp.MustCompile(`<Credit Card Number_\d+>`),
+	"account": regexp.MustCompile(`<Account ID_\d+>`),
+}
+
+// fakeModel answers chat completions with scripted agents, picked by the
+// first word of the system prompt. Like real agents they only use tokens
+// from their input. The first draft makes up a phone number, which the
+// bus redacts, and misses the callback, which the reviewer sends back.
+func fakeModel(req openai.ChatCompletionRequest) string {
+	role, _, _ := strings.Cut(req.Messages[0].Content, ".")
+	in := fakeopenai.Prompt(req)
+	tok := func(kind string) string { return tokenOf[kind].FindString(in) }
+
+	var out string
+	switch role {
+	case "Researcher":
+		out = fmt.Sprintf("- Customer: %s (account %s, %s, callback number %s), customer since 2019, no previous disputes\n"+
+			"- Feb 27: asked to cancel the annual plan; the request is in the chat log\n"+
+			"- Mar 01: plan renewed anyway; $249.00 charged to card %s\n"+
+			"- Mar 05: asked for a callback on %s; none happened\n"+
+			"- Mar 09: will file a chargeback if not refunded by Friday\n"+
+			"- Policy R-4: renewals after a cancellation request are refunded",
+			tok("person"), tok("account"), tok("email"), tok("phone"), tok("card"), tok("phone"))
+	case "Writer":
+		if !strings.Contains(in, "Reviewer feedback") {
+			out = fmt.Sprintf("Hi %s,\n\nYou asked us to cancel before your plan renewed, and we renewed it anyway. I've refunded the $249.00 to your card %s; "+
+				"it will appear within 5 business days. If anything else comes up, our billing team is at 212-555-0147.\n\nSupport team",
+				tok("person"), tok("card"))
+			break
+		}
+		out = fmt.Sprintf("Hi %s,\n\nYou asked us to cancel before your plan renewed, and we renewed it anyway. We also promised you a callback on %s and didn't make it. I'm sorry for both.\n\n"+
+			"I've refunded the $249.00 to your card %s under our renewal policy; it will appear within 5 business days, before Friday. Your plan is cancelled and won't renew again. "+
+			"A confirmation is on its way to %s.\n\nSupport team",
+			tok("person"), tok("phone"), tok("card"), tok("email"))
+	case "Reviewer":
+		draft := in[strings.Index(in, "Draft:"):]
+		if strings.Contains(draft, "callback") {
+			out = "APPROVED\nBacked by the facts, acknowledges both failures, placeholders intact."
+		} else {
+			out = "REVISE: Acknowledge the missed callback. Confirm the refund lands before the Friday deadline and that the plan won't renew. " +
+				"Remove the [Phone Number] line; there is no billing number in the facts."
+		}
+	default:
+		out = "There is no agent called " + role + "."
+	}
+	return out
+}
@@ -0,0 +1,164 @@
+// Multi-agent orchestration + Blindfold: Let a researcher, a writer and a
+// reviewer work a customer escalation over tokenized messages only.
+//
+// The case is tokenized once, at intake. The mapping stays with the
+// orchestrator, and no agent gets it: agents only receive messages, and
+// every message goes through a bus that scans it for real PII and redacts
+// whatever an agent produced on its own. Only the presentation layer, at
+// the very end, detokenizes the approved reply for the customer. The
+// transcript the agents built holds tokens alone, which the run checks
+// before it exits.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/leakscan"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/masking"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+// orchestrate runs research, then drafts and reviews until the reviewer
+// approves or rounds run out. It only ever handles tokenized text.
+func orchestrate(ctx context.Context, bus *Bus, agents map[string]*Agent, caseText string, rounds int) (string, error) {
+	in, err := bus.Send(ctx, "intake", "researcher", caseText)
+	if err != nil {
+		return "", err
+	}
+	facts, err := agents["researcher"].Ask(ctx, in)
+	if err != nil {
+		return "", err
+	}
+	facts, err = bus.Send(ctx, "researcher", "writer", facts)
+	if err != nil {
+		return "", err
+	}
+
+	request := "Facts:\n" + facts
+	for round := 1; round <= rounds; round++ {
+		draft, err := agents["writer"].Ask(ctx, request)
+		if err != nil {
+			return "", err
+		}
+		draft, err = bus.Send(ctx, "writer", "reviewer", draft)
+		if err != nil {
+			return "", err
+		}
+		review, err := agents["reviewer"].Ask(ctx, "Facts:\n"+facts+"\n\nDraft:\n"+draft)
+		if err != nil {
+			return "", err
+		}
+		review, err = bus.Send(ctx, "reviewer", "orchestrator", review)
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(strings.ToUpper(review), "APPROVED") {
+			return draft, nil
+		}
+		request = "Facts:\n" + facts + "\n\nPrevious draft:\n" + draft + "\n\nReviewer feedback:\n" + strings.TrimSpace(strings.TrimPrefix(review, "REVISE:"))
+	}
+	return "", fmt.Errorf("no approved draft after %d rounds", rounds)
+}
+
+// present is the presentation layer, the one place the mapping is used.
+// Card numbers come back masked to their last four digits: the customer
+// knows which card they mean, and the email doesn't carry the number.
+func present(draft string, m map[string]string) string {
+	rules := masking.Rules{blindfold.EntityCreditCard: masking.KeepLast(4)}
+	return mapping.ReplaceTokens(draft, func(token string) string {
+		value, ok := m[token]
+		if !ok {
+			return token
+		}
+		if typ, _, _ := mapping.ParseToken(token); typ == blindfold.EntityCreditCard {
+			return rules.Mask(typ, value)
+		}
+		return value
+	})
+}
+
+func indent(s string) string { return "    " + strings.ReplaceAll(s, "\n", "\n    ") }
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply (default: the file's default)")
+	casePath := flag.String("case", "case.txt", "escalation case to work")
+	model := flag.String("model", openai.GPT4oMini, "chat model for every agent")
+	rounds := flag.Int("rounds", 3, "most draft/review rounds")
+	demo := flag.Bool("demo", false, "use scripted in-process agents instead of OpenAI")
+	flag.Parse()
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+
+	cfg := openai.DefaultConfig(os.Getenv("OPENAI_API_KEY"))
+	if *demo {
+		srv := fakeopenai.Server(fakeModel)
+		defer srv.Close()
+		cfg = fakeopenai.Config(srv)
+	} else if os.Getenv("OPENAI_API_KEY") == "" {
+		log.Fatal("OPENAI_API_KEY is required (or run with -demo)")
+	}
+	oa := openai.NewClientWithConfig(cfg)
+	agents := make(map[string]*Agent)
+	for role := range roles {
+		agents[role] = &Agent{Name: role, oa: oa, model: *model}
+	}
+
+	raw, err := os.ReadFile(*casePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// The case is tokenized once, at intake; only the orchestrator holds
+	// the mapping
+	p := protect.New(bf)
+	p.Strict = true
+	bus := &Bus{scan: leakscan.New(bf, leakscan.Redact)}
+	res, err := p.Run(ctx, string(raw), func(caseText string) (string, error) {
+		return orchestrate(ctx, bus, agents, caseText, *rounds)
+	})
+	for _, e := range bus.Log {
+		note := ""
+		if e.Redacted > 0 {
+			note = fmt.Sprintf("  (bus redacted %d value(s) the sender made up)", e.Redacted)
+		}
+		fmt.Printf("── %s → %s%s\n%s\n\n", e.From, e.To, note, indent(e.Content))
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	values := make([]string, 0, len(res.Mapping))
+	for _, v := range res.Mapping {
+		values = append(values, v)
+	}
+	if n := bus.Audit(values); n > 0 {
+		log.Fatalf("isolation check failed: %d agent messages contain real values", n)
+	}
+	fmt.Printf("Isolation check: %d agent messages, no real values; %d values stayed with the orchestrator\n\n", len(bus.Log), len(res.Mapping))
+
+	fmt.Printf("── Reply to the customer\n%s\n", indent(present(res.Output, res.Mapping)))
+}
@@ -0,0 +1,121 @@
+// Multi-tenant mapping isolation + Blindfold: Guarantee that one tenant's
+// tokens can never be resolved in another tenant's context.
+//
+// Tokens look the same in every tenant (<Email Address_1>), so isolation
+// has to come from the mapping store. Mappings are namespaced by tenant
+// and encrypted with a per-tenant key, with the store key bound into the
+// ciphertext: another tenant's session is not found, and a mapping copied
+// into the wrong namespace doesn't decrypt.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/cache"
+)
+
+var tickets = map[string]string{
+	"acme":   "Refund order 4471 for jane.doe@acme.example, card ending 1881. Call 415-555-0134.",
+	"globex": "Reset MFA for raj.mehta@globex.example and confirm on 212-555-0199.",
+}
+
+func newStore(redisAddr string) cache.Store {
+	if redisAddr == "" {
+		return cache.NewLRU(10_000)
+	}
+	return cache.NewRedis(redis.NewClient(&redis.Options{Addr: redisAddr}), time.Hour)
+}
+
+func main() {
+	_ = godotenv.Load()
+	redisAddr := flag.String("redis", os.Getenv("REDIS_ADDR"), "Redis address for the mapping store (default: in-memory)")
+	flag.Parse()
+	ctx := context.Background()
+
+	master := []byte(os.Getenv("MAPPING_MASTER_KEY"))
+	if len(master) == 0 {
+		master = make([]byte, 32)
+		if _, err := rand.Read(master); err != nil {
+			log.Fatal(err)
+		}
+	}
+	kv := newStore(*redisAddr)
+	store := NewStore(kv, DerivedKeys(master))
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := bfclient.FromEnv()
+
+	// Both tenants use session "s-1001": the namespace keeps them apart
+	const session = "s-1001"
+	tenants := make(map[string]*Tenant)
+	tokenized := make(map[string]string)
+	for _, id := range []string{"acme", "globex"} {
+		t, err := store.For(id)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tenants[id] = t
+		text, err := t.Tokenize(ctx, bf, session, tickets[id])
+		if err != nil {
+			log.Fatalf("%s: %v", id, err)
+		}
+		tokenized[id] = text
+		fmt.Printf("[%s] stored at %s\n  %s\n", id, Key(id, session), text)
+	}
+
+	fmt.Println("\n1. Each tenant resolves its own tokens")
+	for _, id := range []string{"acme", "globex"} {
+		restored, err := tenants[id].Detokenize(ctx, session, tokenized[id])
+		if err != nil {
+			log.Fatalf("%s: %v", id, err)
+		}
+		fmt.Printf("  [%s] %s\n", id, restored)
+	}
+
+	fmt.Println("\n2. globex sends acme's reply: same session ID, same token names")
+	restored, err := tenants["globex"].Detokenize(ctx, session, tokenized["acme"])
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("  [globex] %s\n", restored)
+	if !strings.Contains(restored, "acme.example") {
+		fmt.Println("  → resolved with globex's mapping; no acme value can come back")
+	}
+
+	fmt.Println("\n3. globex asks for a session only acme has")
+	if _, err := tenants["acme"].Tokenize(ctx, bf, "s-2001", tickets["acme"]); err != nil {
+		log.Fatal(err)
+	}
+	_, err = tenants["globex"].Detokenize(ctx, "s-2001", tokenized["acme"])
+	fmt.Printf("  [globex] %v\n", err)
+
+	fmt.Println("\n4. A bug copies acme's stored mapping into globex's namespace")
+	blob, _, err := kv.Get(ctx, Key("acme", "s-2001"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := kv.Set(ctx, Key("globex", "s-2001"), blob); err != nil {
+		log.Fatal(err)
+	}
+	_, err = tenants["globex"].Detokenize(ctx, "s-2001", tokenized["acme"])
+	fmt.Printf("  [globex] %v\n", err)
+	fmt.Printf("  stored bytes hold no values: contains %q = %v\n", "jane.doe", strings.Contains(string(blob), "jane.doe"))
+
+	fmt.Println("\n5. An ID that tries to reach into another namespace")
+	_, err = store.For("acme/session/s-1001")
+	fmt.Printf("  %v\n", err)
+}
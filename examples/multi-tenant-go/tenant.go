@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/cache"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+var (
+	// ErrNotFound means the tenant has no mapping for the session.
+	ErrNotFound error = &bferrors.Error{Kind: bferrors.MappingMissing, Err: errors.New("mapping not found")}
+	// ErrUnreadable means a stored mapping failed to decrypt under the
+	// tenant's key: it was written by another tenant, for another session,
+	// or altered.
+	ErrUnreadable = errors.New("mapping unreadable for this tenant")
+)
+
+// validID restricts tenant and session IDs, so neither can reach into
+// another namespace ("acme/sessions/x" as a tenant ID).
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Keyring returns the encryption key for a tenant.
+type Keyring interface {
+	Key(tenant string) ([]byte, error)
+}
+
+// DerivedKeys derives a 256-bit key per tenant from one master key with
+// HMAC-SHA256. A KMS-backed Keyring holding a separate key per tenant is
+// the production alternative; rotating or deleting one tenant's key then
+// leaves the others untouched.
+type DerivedKeys []byte
+
+// Key returns HMAC-SHA256(master, "tenant:" + tenant).
+func (master DerivedKeys) Key(tenant string) ([]byte, error) {
+	if len(master) < 32 {
+		return nil, errors.New("master key must be at least 32 bytes")
+	}
+	mac := hmac.New(sha256.New, master)
+	mac.Write([]byte("tenant:" + tenant))
+	return mac.Sum(nil), nil
+}
+
+// Store keeps mappings in a cache.Store, namespaced by tenant and
+// encrypted with the tenant's key. The store key and tenant are bound into
+// each ciphertext as associated data, so a blob copied to another
+// tenant's or session's key doesn't decrypt.
+type Store struct {
+	kv   cache.Store
+	keys Keyring
+}
+
+// NewStore returns a Store over kv with keys from keys.
+func NewStore(kv cache.Store, keys Keyring) *Store {
+	return &Store{kv: kv, keys: keys}
+}
+
+// Key returns where a tenant's mapping for a session is stored.
+func Key(tenant, session string) string {
+	return "mapping/tenant/" + tenant + "/session/" + session
+}
+
+// For returns the store scoped to one tenant. A handler gets the tenant
+// from the authenticated request once and passes the scoped value on, so
+// no later call can name a different tenant.
+func (s *Store) For(tenant string) (*Tenant, error) {
+	if !validID.MatchString(tenant) {
+		return nil, fmt.Errorf("invalid tenant ID %q", tenant)
+	}
+	return &Tenant{id: tenant, store: s}, nil
+}
+
+func (s *Store) aead(tenant string) (cipher.AEAD, error) {
+	key, err := s.keys.Key(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("tenant %s: key: %w", tenant, err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Tenant is a Store scoped to one tenant.
+type Tenant struct {
+	id    string
+	store *Store
+}
+
+// ID returns the tenant ID.
+func (t *Tenant) ID() string { return t.id }
+
+// Save encrypts m and stores it under the tenant's namespace.
+func (t *Tenant) Save(ctx context.Context, session string, m map[string]string) error {
+	if !validID.MatchString(session) {
+		return fmt.Errorf("invalid session ID %q", session)
+	}
+	plain, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	aead, err := t.store.aead(t.id)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	key := Key(t.id, session)
+	return t.store.kv.Set(ctx, key, aead.Seal(nonce, nonce, plain, []byte(key)))
+}
+
+// Load returns the tenant's mapping for session.
+func (t *Tenant) Load(ctx context.Context, session string) (map[string]string, error) {
+	if !validID.MatchString(session) {
+		return nil, fmt.Errorf("invalid session ID %q", session)
+	}
+	key := Key(t.id, session)
+	blob, ok, err := t.store.kv.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	aead, err := t.store.aead(t.id)
+	if err != nil {
+		return nil, err
+	}
+	if len(blob) < aead.NonceSize() {
+		return nil, ErrUnreadable
+	}
+	plain, err := aead.Open(nil, blob[:aead.NonceSize()], blob[aead.NonceSize():], []byte(key))
+	if err != nil {
+		return nil, ErrUnreadable
+	}
+	var m map[string]string
+	if err := json.Unmarshal(plain, &m); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnreadable, err)
+	}
+	return m, nil
+}
+
+// Tokenize tokenizes text with bf and saves the mapping for session.
+func (t *Tenant) Tokenize(ctx context.Context, bf bfclient.Client, session, text string, opts ...blindfold.CallOption) (string, error) {
+	res, err := bf.Tokenize(ctx, text, opts...)
+	if err != nil {
+		return "", err
+	}
+	if err := t.Save(ctx, session, res.Mapping); err != nil {
+		return "", err
+	}
+	return res.Text, nil
+}
+
+// Detokenize restores text with the tenant's mapping for session. Tokens
+// are the same shape in every tenant (<Person_1>), so the mapping, not the
+// token, decides whose value comes back; another tenant's session is
+// simply not found.
+func (t *Tenant) Detokenize(ctx context.Context, session, text string) (string, error) {
+	m, err := t.Load(ctx, session)
+	if err != nil {
+		return "", err
+	}
+	return mapping.Detokenize(text, m), nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+var (
+	demoToken    = regexp.MustCompile(`<(Person|Email Address|Phone Number|Credit Card Number|IP Address)_\d+>`)
+	demoSentence = regexp.MustCompile(`.+?[.!?](?:\s|$)`)
+)
+
+// demoScripts are the sample documents' summaries. %[1]s and so on are
+// the first, second, … distinct tokens of the type, in order of
+// appearance.
+var demoScripts = map[string][]struct{ kind, text string }{
+	"account-notes.html": {{"Person", "%[1]s wants to move to annual billing from 1 November, with a prorated credit for October. "},
+		{"Email Address", "Invoices go to %[1]s and %[2]s, "}, {"Credit Card Number", "and the card on file is %[1]s."}},
+	"crm-export.json": {{"Person", "Two CRM contacts need follow-up: %[1]s wants a callback after 5pm about a broken kettle, "},
+		{"Phone Number", "on %[1]s. "}, {"Person", "%[2]s's refund for order 5531 is approved and they also want a replacement."}},
+	"escalation.eml": {{"Person", "%[1]s was charged twice for invoice INV-20931 "}, {"Credit Card Number", "on card %[1]s "},
+		{"Phone Number", "and never got the callback promised on %[1]s. "},
+		{"Person", "%[1]s wants the duplicate refunded and confirmed by email, or will dispute it with the bank on Thursday."}},
+	"incident-2026-10-13.md": {{"Person", "4%% of checkouts failed for 35 minutes on 13 October after the payment provider changed its TLS endpoint; on-call %[1]s pinned traffic to the old one. " +
+		"Customer %[2]s was affected, was charged once the retry succeeded, and is owed a 10%% voucher; %[1]s owns an alert on provider certificate changes."}},
+	"refunds.csv": {{"Person", "Three refunds were logged, $243.49 in total: %[1]s for order 5531 (damaged), %[2]s for order 6602 (late) and %[3]s for order 6610 (wrong size)."}},
+}
+
+// fakeModel answers chat completions with scripted summaries of the
+// sample documents, picked by the document name, and a digest built from
+// the first sentence of each summary. Like a real model it only uses
+// tokens from its input. Documents it has no script for, and sections,
+// get their first two sentences.
+func fakeModel(req openai.ChatCompletionRequest) string {
+	prompt, _, _ := strings.Cut(req.Messages[0].Content, ".")
+	in := fakeopenai.Prompt(req)
+
+	var out string
+	switch prompt {
+	case "Document summary", "Section summary":
+		header, body, _ := strings.Cut(in, "\n\n")
+		doc := strings.TrimPrefix(header, "Document: ")
+		out = script(doc, body)
+		if prompt == "Section summary" || out == "" {
+			out = firstSentences(body, 2)
+		}
+	case "Digest":
+		var lines []string
+		for _, sec := range strings.Split(in, "## ")[1:] {
+			doc, summary, _ := strings.Cut(sec, "\n")
+			lines = append(lines, fmt.Sprintf("- **%s**: %s", doc, firstSentences(summary, 1)))
+		}
+		out = fmt.Sprintf("%d documents came in overnight: two customers are waiting on refunds or callbacks, and checkout had a short outage.\n\n%s",
+			len(lines), strings.Join(lines, "\n"))
+	default:
+		out = "There is no script for the prompt " + prompt + "."
+	}
+	return out
+}
+
+// script fills doc's scripted summary with the tokens in body, or returns
+// "" if there is no script or body lacks a token it needs.
+func script(doc, body string) string {
+	parts, ok := demoScripts[doc]
+	if !ok {
+		return ""
+	}
+	seen := make(map[string]bool)
+	byKind := make(map[string][]any)
+	for _, m := range demoToken.FindAllStringSubmatch(body, -1) {
+		if !seen[m[0]] {
+			seen[m[0]] = true
+			byKind[m[1]] = append(byKind[m[1]], m[0])
+		}
+	}
+	var b strings.Builder
+	for _, p := range parts {
+		s := fmt.Sprintf(p.text, byKind[p.kind]...)
+		if strings.Contains(s, "%!") {
+			return ""
+		}
+		b.WriteString(s)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// firstSentences returns the first n sentences of text on one line.
+func firstSentences(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	s := demoSentence.FindAllString(text, n)
+	if len(s) == 0 {
+		return text
+	}
+	return strings.TrimSpace(strings.Join(s, ""))
+}
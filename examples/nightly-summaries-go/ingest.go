@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// extractors turn a file into plain text for tokenization, by extension.
+// Formats are kept to what the standard library reads; convert PDFs and
+// office documents to text upstream.
+var extractors = map[string]func([]byte) (string, error){
+	".txt":  plain,
+	".md":   plain,
+	".log":  plain,
+	".html": htmlText,
+	".htm":  htmlText,
+	".csv":  csvText,
+	".json": jsonText,
+	".eml":  emailText,
+}
+
+func plain(data []byte) (string, error) {
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("not UTF-8 text")
+	}
+	return string(data), nil
+}
+
+var (
+	htmlDrop  = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)>`)
+	htmlBreak = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/h[1-6]|/tr)\b[^>]*>`)
+	htmlTag   = regexp.MustCompile(`<[^>]*>`)
+	blankRuns = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlText drops scripts, styles and tags, keeps block breaks, and
+// decodes entities, so "&amp;" next to an email address doesn't hide it.
+func htmlText(data []byte) (string, error) {
+	s := htmlDrop.ReplaceAllString(string(data), "")
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = html.UnescapeString(htmlTag.ReplaceAllString(s, ""))
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		lines = append(lines, strings.TrimSpace(l))
+	}
+	return strings.TrimSpace(blankRuns.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")), nil
+}
+
+// csvText writes each row as "column: value" pairs, so the model knows
+// which column a value came from.
+func csvText(data []byte) (string, error) {
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", nil
+	}
+	var b strings.Builder
+	for i, row := range rows[1:] {
+		fmt.Fprintf(&b, "Row %d:", i+1)
+		for j, v := range row {
+			if j < len(rows[0]) {
+				fmt.Fprintf(&b, " %s: %s;", rows[0][j], v)
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// jsonText flattens a JSON document into "path: value" lines.
+func jsonText(data []byte) (string, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	var walk func(path string, v any)
+	walk = func(path string, v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(strings.TrimPrefix(path+"."+k, "."), v[k])
+			}
+		case []any:
+			for i, e := range v {
+				walk(fmt.Sprintf("%s[%d]", path, i), e)
+			}
+		default:
+			fmt.Fprintf(&b, "%s: %v\n", path, v)
+		}
+	}
+	walk("", v)
+	return b.String(), nil
+}
+
+// emailText keeps the headers a summary needs and the plain-text body;
+// in a multipart message, the first text/plain part.
+func emailText(data []byte) (string, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, h := range []string{"From", "To", "Date", "Subject"} {
+		if v := msg.Header.Get(h); v != "" {
+			if h == "Subject" {
+				v, _ = new(mime.WordDecoder).DecodeHeader(v)
+			}
+			fmt.Fprintf(&b, "%s: %s\n", h, v)
+		}
+	}
+	b.WriteString("\n")
+	body, err := textBody(msg.Header.Get("Content-Type"), msg.Body)
+	if err != nil {
+		return "", err
+	}
+	b.WriteString(body)
+	return b.String(), nil
+}
+
+func textBody(contentType string, r io.Reader) (string, error) {
+	media, params, err := mime.ParseMediaType(contentType)
+	if contentType == "" || err != nil {
+		media = "text/plain"
+	}
+	if strings.HasPrefix(media, "multipart/") {
+		mr := multipart.NewReader(r, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return "", fmt.Errorf("no text/plain part")
+			}
+			if err != nil {
+				return "", err
+			}
+			if text, err := textBody(part.Header.Get("Content-Type"), part); err == nil {
+				return text, nil
+			}
+		}
+	}
+	if media != "text/plain" {
+		return "", fmt.Errorf("unsupported body %s", media)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return plain(data)
+}
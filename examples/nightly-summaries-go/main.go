@@ -0,0 +1,354 @@
+// Nightly document summaries + Blindfold: Summarize a folder of mixed
+// documents every night and write a morning digest.
+//
+// Emails, HTML pages, CSV and JSON exports, Markdown and text files are
+// turned into plain text, tokenized in overlapping windows with one
+// mapping per document (pkg/chunk), and summarized by the LLM; documents
+// too long for one prompt are summarized section by section first. Each
+// tokenized summary is kept with its mapping in a local state file, so
+// the next night only documents whose content changed go back to the
+// LLM. The digest is written over every document's summary after
+// mapping.Merge gives their tokens one numbering, and restored at the end.
+// Only tokenized text ever reaches the LLM.
+//
+// Run it from cron, or pass -at to keep it running and summarize daily.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/chunk"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/resilience"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+type config struct {
+	dir, out string
+	workers  int
+	force    bool
+	dryRun   bool
+}
+
+type job struct {
+	doc, sum, format string
+	text             string
+}
+
+type outcome struct {
+	doc string
+	rec *Record
+	err error
+}
+
+func main() {
+	_ = godotenv.Load()
+	var cfg config
+	flag.StringVar(&cfg.dir, "dir", "docs", "directory of documents to summarize")
+	flag.StringVar(&cfg.out, "out", "output", "directory for per-document summaries and digests")
+	statePath := flag.String("state", "state.db", "state file: tokenized summaries and their mappings")
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply, by name (default: the file's default)")
+	model := flag.String("model", openai.GPT4oMini, "OpenAI model")
+	maxChars := flag.Int("max-chars", 8000, "summarize tokenized documents longer than this section by section")
+	flag.IntVar(&cfg.workers, "workers", 4, "documents processed concurrently")
+	rps := flag.Float64("rps", 5, "request rate limit, per service (0 = unlimited)")
+	flag.BoolVar(&cfg.force, "force", false, "summarize every document again, changed or not")
+	flag.BoolVar(&cfg.dryRun, "dry-run", false, "extract and tokenize only; skip OpenAI calls and write nothing")
+	at := flag.String("at", "", "keep running and summarize daily at this local time (HH:MM); default: run once")
+	demo := flag.Bool("demo", false, "use a built-in fake model instead of OpenAI (no OpenAI key needed)")
+	flag.Parse()
+
+	// Ctrl-C stops handing out documents; finished ones are in the state
+	// file for the next run
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	oaCfg := openai.DefaultConfig(os.Getenv("OPENAI_API_KEY"))
+	if *demo {
+		srv := fakeopenai.Server(fakeModel)
+		defer srv.Close()
+		oaCfg = fakeopenai.Config(srv)
+	} else if os.Getenv("OPENAI_API_KEY") == "" && !cfg.dryRun {
+		log.Fatal("OPENAI_API_KEY is required (or run with -demo)")
+	}
+
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := bfclient.FromEnv(append(pol.ClientOptions(), blindfold.WithMaxRetries(0))...)
+	p := &pipeline{
+		bf:       pol.Wrap(resilience.Wrap(bf, resilience.DefaultPolicy(*rps))),
+		llm:      resilience.WrapChat(openai.NewClientWithConfig(oaCfg), resilience.DefaultPolicy(*rps)),
+		model:    *model,
+		chunker:  chunk.Chunker{Size: 32 << 10, Overlap: 512},
+		maxChars: *maxChars,
+	}
+	st, err := openStore(*statePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer st.Close()
+
+	if *at == "" {
+		if err := run(ctx, p, st, cfg); err != nil {
+			st.Close()
+			log.Fatal(err)
+		}
+		return
+	}
+	clock, err := time.Parse("15:04", *at)
+	if err != nil {
+		log.Fatalf("-at: want HH:MM, got %q", *at)
+	}
+	for {
+		next := nextRun(time.Now(), clock)
+		log.Printf("next run at %s", next.Format(time.RFC3339))
+		select {
+		case <-time.After(time.Until(next)):
+		case <-ctx.Done():
+			return
+		}
+		// A failed night is logged and retried the next night; the state
+		// file keeps whatever finished
+		if err := run(ctx, p, st, cfg); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+// nextRun is the first time at the clock's hour and minute after now.
+func nextRun(now, clock time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// run is one night: summarize new and changed documents, drop state for
+// deleted ones, and write the digest over all of them.
+func run(ctx context.Context, p *pipeline, st *store, cfg config) error {
+	date := time.Now().Format("2006-01-02")
+	docs, err := listDocs(cfg.dir)
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return fmt.Errorf("no supported documents in %s", cfg.dir)
+	}
+
+	records := make(map[string]*Record)
+	failed := make(map[string]error)
+	var pending []job
+	for _, doc := range docs {
+		data, err := os.ReadFile(filepath.Join(cfg.dir, doc))
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		j := job{doc: doc, sum: hex.EncodeToString(sum[:]), format: strings.TrimPrefix(filepath.Ext(doc), ".")}
+		rec, err := st.get(doc)
+		if err != nil {
+			return err
+		}
+		if rec != nil && rec.SHA256 == j.sum && !cfg.force && !cfg.dryRun {
+			records[doc] = rec
+			continue
+		}
+		if j.text, err = extractors[strings.ToLower(filepath.Ext(doc))](data); err != nil {
+			failed[doc] = fmt.Errorf("extract %s: %w", j.format, err)
+			log.Printf("%s: %v", doc, failed[doc])
+			continue
+		}
+		pending = append(pending, j)
+	}
+	fmt.Printf("%s: %d documents, %d unchanged, %d to summarize\n", date, len(docs), len(records), len(pending))
+
+	if cfg.dryRun {
+		for _, j := range pending {
+			res, err := p.chunker.Tokenize(ctx, p.bf, j.text)
+			if err != nil {
+				return fmt.Errorf("%s: %w", j.doc, err)
+			}
+			fmt.Printf("  %s: %d chars, %d entities, %d sections\n", j.doc, len(res.Text), len(res.DetectedEntities), len(sections(res.Text, p.maxChars)))
+		}
+		return nil
+	}
+
+	jobs := make(chan job)
+	outcomes := make(chan outcome)
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				rec, err := p.summarize(ctx, j.doc, j.text)
+				if err == nil {
+					rec.SHA256, rec.Format, rec.ProcessedAt = j.sum, j.format, time.Now().UTC()
+					err = st.put(j.doc, rec)
+				}
+				outcomes <- outcome{doc: j.doc, rec: rec, err: err}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, j := range pending {
+			select {
+			case jobs <- j:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+	for o := range outcomes {
+		if o.err != nil {
+			failed[o.doc] = o.err
+			log.Printf("%s: %v", o.doc, o.err)
+			continue
+		}
+		records[o.doc] = o.rec
+		fmt.Printf("  %s: %d entities protected, %d section(s)\n", o.doc, total(o.rec.Entities), o.rec.Sections)
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted; rerun to resume")
+	}
+
+	keep := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		keep[doc] = true
+	}
+	dropped, err := st.prune(keep)
+	if err != nil {
+		return err
+	}
+	for _, doc := range dropped {
+		if err := os.Remove(filepath.Join(cfg.out, "summaries", doc+".md")); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if len(dropped) > 0 {
+		fmt.Printf("  dropped %d deleted document(s): %s\n", len(dropped), strings.Join(dropped, ", "))
+	}
+
+	// A document that failed tonight is left out of the digest rather
+	// than reported from an older version
+	var done []string
+	for _, doc := range docs {
+		if records[doc] != nil && failed[doc] == nil {
+			done = append(done, doc)
+		}
+	}
+	for _, doc := range done {
+		text := p.bf.Detokenize(records[doc].Summary, records[doc].Mapping).Text
+		if err := writePrivate(filepath.Join(cfg.out, "summaries", doc+".md"), "# "+doc+"\n\n"+text+"\n"); err != nil {
+			return err
+		}
+	}
+	if len(done) > 0 {
+		digest, merged, err := p.digest(ctx, done, records)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(cfg.out, "digest-"+date+".md")
+		doc := render(date, p.bf.Detokenize(digest, merged).Text, done, records, failed)
+		if err := writePrivate(path, doc); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s (%d documents, %d protected values)\n", path, len(done), len(merged))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d document(s) failed", len(failed))
+	}
+	return nil
+}
+
+// listDocs returns the supported files under dir, as slash-separated
+// paths relative to it, sorted.
+func listDocs(dir string) ([]string, error) {
+	var docs []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || extractors[strings.ToLower(filepath.Ext(path))] == nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(docs)
+	return docs, err
+}
+
+func total(counts map[string]int) int {
+	n := 0
+	for _, c := range counts {
+		n += c
+	}
+	return n
+}
+
+// render lays out the digest file: the restored digest, then a table of
+// what was processed and anything that failed.
+func render(date, digest string, docs []string, records map[string]*Record, failed map[string]error) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Nightly digest, %s\n\n%s\n\n## Documents\n\n", date, digest)
+	b.WriteString("| Document | Format | Entities protected | Sections | Summarized |\n|---|---|---|---|---|\n")
+	for _, doc := range docs {
+		r := records[doc]
+		fmt.Fprintf(&b, "| %s | %s | %d | %d | %s |\n", doc, r.Format, total(r.Entities), r.Sections, r.ProcessedAt.Local().Format("2006-01-02 15:04"))
+	}
+	if len(failed) > 0 {
+		names := make([]string, 0, len(failed))
+		for doc := range failed {
+			names = append(names, doc)
+		}
+		sort.Strings(names)
+		b.WriteString("\n## Failed\n\n")
+		for _, doc := range names {
+			fmt.Fprintf(&b, "- %s: %v\n", doc, failed[doc])
+		}
+	}
+	return b.String()
+}
+
+// writePrivate writes restored text, which holds the real values, for
+// the owner only.
+func writePrivate(path, text string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(text), 0o600)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var docsBucket = []byte("docs")
+
+// Record is what a run keeps per document. The summary stays tokenized;
+// the mapping restores it. Keeping both lets a later night build its
+// digest from documents it doesn't summarize again.
+type Record struct {
+	SHA256      string            `json:"sha256"`
+	Format      string            `json:"format"`
+	Summary     string            `json:"summary"` // tokenized
+	Mapping     map[string]string `json:"mapping"`
+	Entities    map[string]int    `json:"entities,omitempty"`
+	Sections    int               `json:"sections"`
+	ProcessedAt time.Time         `json:"processed_at"`
+}
+
+// store keeps records in a bbolt file, one per document path. The file
+// holds mappings, so it is created readable by its owner only.
+type store struct {
+	db *bolt.DB
+}
+
+func openStore(path string) (*store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%s is in use by another run", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(docsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &store{db: db}, nil
+}
+
+func (s *store) Close() error { return s.db.Close() }
+
+// get returns the record for doc, or nil.
+func (s *store) get(doc string) (*Record, error) {
+	var r *Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(docsBucket).Get([]byte(doc))
+		if data == nil {
+			return nil
+		}
+		r = new(Record)
+		return json.Unmarshal(data, r)
+	})
+	return r, err
+}
+
+func (s *store) put(doc string, r *Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(docsBucket).Put([]byte(doc), data)
+	})
+}
+
+// prune drops records of documents that are gone from the folder, and
+// their mappings with them, and returns the documents it dropped.
+func (s *store) prune(keep map[string]bool) ([]string, error) {
+	var dropped []string
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(docsBucket)
+		var gone [][]byte
+		err := b.ForEach(func(k, _ []byte) error {
+			if !keep[string(k)] {
+				gone = append(gone, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range gone {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		for _, k := range gone {
+			dropped = append(dropped, string(k))
+		}
+		return nil
+	})
+	return dropped, err
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/chunk"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+const placeholderRule = " Values like <Person_1> and <Email Address_1> are placeholders for real data: " +
+	"copy them exactly as written and never guess what they stand for."
+
+// The first words name the prompt; the demo model dispatches on them.
+const (
+	documentPrompt = "Document summary. Summarize one internal document for the operations team in at most three sentences: " +
+		"what it is about, what happened, and any open follow-ups." + placeholderRule
+	sectionPrompt = "Section summary. Summarize one section of a longer document in two sentences, keeping every fact " +
+		"a summary of the whole document would need." + placeholderRule
+	digestPrompt = "Digest. Write the morning digest from last night's document summaries: one overview sentence, " +
+		"then a bullet per document with its main point and any follow-up." + placeholderRule
+)
+
+type pipeline struct {
+	bf       bfclient.Client
+	llm      bfclient.ChatCompleter
+	model    string
+	chunker  chunk.Chunker
+	maxChars int // longer tokenized documents are summarized section by section
+}
+
+func (p *pipeline) ask(ctx context.Context, system, user string) (string, error) {
+	res, err := p.llm.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:       p.model,
+		Temperature: 0,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: system},
+			{Role: openai.ChatMessageRoleUser, Content: user},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("openai: %w", err)
+	}
+	if len(res.Choices) == 0 {
+		return "", fmt.Errorf("openai: empty response")
+	}
+	return strings.TrimSpace(res.Choices[0].Message.Content), nil
+}
+
+// summarize tokenizes one document's text and summarizes it. Detection
+// runs in overlapping windows (pkg/chunk), so a value on a window edge is
+// still found, and the whole document gets one mapping: the same value is
+// the same token in every section.
+func (p *pipeline) summarize(ctx context.Context, doc, text string) (*Record, error) {
+	res, err := p.chunker.Tokenize(ctx, p.bf, text)
+	if err != nil {
+		return nil, fmt.Errorf("tokenize: %w", err)
+	}
+	r := &Record{Mapping: res.Mapping, Entities: make(map[string]int)}
+	for _, e := range res.DetectedEntities {
+		r.Entities[e.Type]++
+	}
+
+	parts := sections(res.Text, p.maxChars)
+	r.Sections = len(parts)
+	body := res.Text
+	if len(parts) > 1 {
+		// Map-reduce: summarize each section, then the section summaries
+		var sums []string
+		for i, part := range parts {
+			s, err := p.ask(ctx, sectionPrompt, fmt.Sprintf("Document: %s, section %d of %d\n\n%s", doc, i+1, len(parts), part))
+			if err != nil {
+				return nil, fmt.Errorf("section %d: %w", i+1, err)
+			}
+			sums = append(sums, s)
+		}
+		body = strings.Join(sums, "\n\n")
+	}
+	if r.Summary, err = p.ask(ctx, documentPrompt, "Document: "+doc+"\n\n"+body); err != nil {
+		return nil, err
+	}
+	if bad := mapping.Unresolved(r.Summary, r.Mapping); len(bad) > 0 {
+		return nil, fmt.Errorf("summary has tokens the document never had: %s", strings.Join(bad, ", "))
+	}
+	return r, nil
+}
+
+// sections splits tokenized text into parts of at most max bytes at
+// paragraph breaks, then at line breaks, which never fall inside a
+// token. A single line longer than max is a part of its own.
+func sections(text string, max int) []string {
+	if max <= 0 || len(text) <= max {
+		return []string{text}
+	}
+	var parts []string
+	var cur strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			parts = append(parts, s)
+		}
+		cur.Reset()
+	}
+	add := func(piece, sep string) {
+		if cur.Len() > 0 && cur.Len()+len(sep)+len(piece) > max {
+			flush()
+		}
+		if cur.Len() > 0 {
+			cur.WriteString(sep)
+		}
+		cur.WriteString(piece)
+	}
+	for _, para := range strings.Split(text, "\n\n") {
+		if len(para) <= max {
+			add(para, "\n\n")
+			continue
+		}
+		for _, line := range strings.Split(para, "\n") {
+			add(line, "\n")
+		}
+	}
+	flush()
+	return parts
+}
+
+// digest writes the morning digest over every document's summary. Each
+// document was tokenized on its own, so <Person_1> means someone else in
+// each; mapping.Merge renumbers them into one mapping first, so a
+// customer who appears in two documents is one token in the digest.
+func (p *pipeline) digest(ctx context.Context, docs []string, records map[string]*Record) (string, map[string]string, error) {
+	sorted := append([]string(nil), docs...)
+	sort.Strings(sorted)
+	maps := make([]map[string]string, len(sorted))
+	for i, d := range sorted {
+		maps[i] = records[d].Mapping
+	}
+	m := mapping.Merge(maps...)
+	var b strings.Builder
+	for i, d := range sorted {
+		fmt.Fprintf(&b, "## %s\n%s\n\n", d, m.Rewrite(i, records[d].Summary))
+	}
+	out, err := p.ask(ctx, digestPrompt, strings.TrimSpace(b.String()))
+	if err != nil {
+		return "", nil, fmt.Errorf("digest: %w", err)
+	}
+	if bad := mapping.Unresolved(out, m.Mapping); len(bad) > 0 {
+		return "", nil, fmt.Errorf("digest has tokens no document had: %s", strings.Join(bad, ", "))
+	}
+	return out, m.Mapping, nil
+}
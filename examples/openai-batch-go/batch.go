@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// maxRequests is the Batch API's limit on requests per input file.
+const maxRequests = 50000
+
+// tokenizeFile reads a batch input file and returns it tokenized, with
+// the mapping of each request by custom_id. Every message of a request is
+// tokenized and the mappings merged, so a value keeps one token within
+// the request. Requests don't share tokens: each has its own mapping.
+func tokenizeFile(ctx context.Context, bf bfclient.Client, path string) ([]byte, map[string]map[string]string, map[string]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer f.Close()
+
+	var out bytes.Buffer
+	mappings := make(map[string]map[string]string)
+	counts := make(map[string]int)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		req, err := decode(line)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		id, _ := req["custom_id"].(string)
+		switch {
+		case id == "":
+			return nil, nil, nil, fmt.Errorf("%s:%d: no custom_id", path, n)
+		case mappings[id] != nil:
+			return nil, nil, nil, fmt.Errorf("%s:%d: custom_id %q used twice", path, n, id)
+		case req["url"] != "/v1/chat/completions":
+			return nil, nil, nil, fmt.Errorf("%s:%d: url %v: only /v1/chat/completions requests are tokenized", path, n, req["url"])
+		case len(mappings) == maxRequests:
+			return nil, nil, nil, fmt.Errorf("%s: more than %d requests; split the file", path, maxRequests)
+		}
+		body, _ := req["body"].(map[string]any)
+		slots, err := messageTexts(body)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		m, entities, err := tokenizeTexts(ctx, bf, slots)
+		if err != nil {
+			// Fail closed: no input file is written, so nothing is uploaded
+			return nil, nil, nil, fmt.Errorf("%s:%d (%s): tokenize: %w", path, n, id, err)
+		}
+		for typ, c := range entities {
+			counts[typ] += c
+		}
+		mappings[id] = m
+		out.Write(marshal(req))
+		out.WriteByte('\n')
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(mappings) == 0 {
+		return nil, nil, nil, fmt.Errorf("%s: no requests", path)
+	}
+	return out.Bytes(), mappings, counts, nil
+}
+
+// slot is a string field of a decoded JSON document.
+type slot struct {
+	doc map[string]any
+	key string
+}
+
+func (s slot) get() string  { v, _ := s.doc[s.key].(string); return v }
+func (s slot) set(v string) { s.doc[s.key] = v }
+
+// messageTexts returns the text of every message in a chat completion
+// body: string contents and text parts.
+func messageTexts(body map[string]any) ([]slot, error) {
+	msgs, ok := body["messages"].([]any)
+	if !ok {
+		return nil, errors.New("body has no messages")
+	}
+	var out []slot
+	for _, m := range msgs {
+		msg, _ := m.(map[string]any)
+		switch c := msg["content"].(type) {
+		case string:
+			out = append(out, slot{msg, "content"})
+		case []any:
+			for _, p := range c {
+				if part, _ := p.(map[string]any); part["type"] == "text" {
+					out = append(out, slot{part, "text"})
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// tokenizeTexts tokenizes each slot in place and returns the merged
+// mapping and the entity counts. Nothing is changed if a call fails.
+func tokenizeTexts(ctx context.Context, bf bfclient.Client, slots []slot) (map[string]string, map[string]int, error) {
+	tokenized := make([]string, len(slots))
+	mappings := make([]map[string]string, len(slots))
+	counts := make(map[string]int)
+	for i, s := range slots {
+		res, err := bf.Tokenize(ctx, s.get())
+		if err != nil {
+			return nil, nil, err
+		}
+		tokenized[i], mappings[i] = res.Text, res.Mapping
+		for _, e := range res.DetectedEntities {
+			counts[e.Type]++
+		}
+	}
+	merged := mapping.Merge(mappings...)
+	for i, s := range slots {
+		s.set(merged.Rewrite(i, tokenized[i]))
+	}
+	return merged.Mapping, counts, nil
+}
+
+// restoreFile detokenizes a batch output file into w, using the mapping
+// of each line's custom_id: message contents, refusals, and tool-call
+// arguments (with JSON-escaped values). It returns the number of lines
+// and the tokens left unresolved, by custom_id.
+func restoreFile(r io.Reader, w io.Writer, mappings map[string]map[string]string) (int, map[string][]string, error) {
+	unresolved := make(map[string][]string)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	n := 0
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		doc, err := decode(line)
+		if err != nil {
+			return n, unresolved, fmt.Errorf("output line %d: %w", n+1, err)
+		}
+		id, _ := doc["custom_id"].(string)
+		m, ok := mappings[id]
+		if !ok {
+			return n, unresolved, fmt.Errorf("output line %d: custom_id %q isn't in this batch's mappings", n+1, id)
+		}
+		resp, _ := doc["response"].(map[string]any)
+		body, _ := resp["body"].(map[string]any)
+		choices, _ := body["choices"].([]any)
+		for _, c := range choices {
+			choice, _ := c.(map[string]any)
+			msg, _ := choice["message"].(map[string]any)
+			if msg == nil {
+				continue
+			}
+			for _, s := range []slot{{msg, "content"}, {msg, "refusal"}} {
+				text := s.get()
+				if text == "" {
+					continue
+				}
+				unresolved[id] = append(unresolved[id], mapping.Unresolved(text, m)...)
+				s.set(mapping.Detokenize(text, m))
+			}
+			calls, _ := msg["tool_calls"].([]any)
+			for _, tc := range calls {
+				call, _ := tc.(map[string]any)
+				if fn, _ := call["function"].(map[string]any); fn != nil {
+					s := slot{fn, "arguments"}
+					s.set(mapping.Detokenize(s.get(), jsonEscaped(m)))
+				}
+			}
+		}
+		if _, err := w.Write(append(marshal(doc), '\n')); err != nil {
+			return n, unresolved, err
+		}
+		n++
+	}
+	return n, unresolved, sc.Err()
+}
+
+// jsonEscaped returns m with values escaped for splicing into JSON string
+// literals, so a restored quote or backslash can't break the arguments.
+func jsonEscaped(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		b := marshal(v)
+		out[k] = string(b[1 : len(b)-1])
+	}
+	return out
+}
+
+// decode parses a JSON object, keeping numbers as written.
+func decode(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// marshal encodes v without HTML escaping.
+func marshal(v any) []byte {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// fakeBatchAPI stands in for the OpenAI Files and Batches endpoints. A
+// batch moves from validating through in_progress to completed over a few
+// polls; each answer reuses the tokens of its request. It records the
+// uploaded input file.
+type fakeBatchAPI struct {
+	mu       sync.Mutex
+	files    map[string][]byte
+	batches  map[string]map[string]any
+	polls    map[string]int
+	uploaded []byte
+}
+
+func newFakeBatchAPI() (*httptest.Server, *fakeBatchAPI) {
+	f := &fakeBatchAPI{files: map[string][]byte{}, batches: map[string]map[string]any{}, polls: map[string]int{}}
+	return httptest.NewServer(f), f
+}
+
+func (f *fakeBatchAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	reply := func(v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	switch path := strings.TrimPrefix(r.URL.Path, "/v1"); {
+	case r.Method == http.MethodPost && path == "/files":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		id := fmt.Sprintf("file-%d", len(f.files)+1)
+		f.files[id] = data
+		f.uploaded = data
+		reply(map[string]any{"id": id, "object": "file", "bytes": len(data), "purpose": r.FormValue("purpose"), "filename": "input.jsonl"})
+	case r.Method == http.MethodPost && path == "/batches":
+		var req struct {
+			InputFileID string `json:"input_file_id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		id := fmt.Sprintf("batch_demo%d", len(f.batches)+1)
+		f.batches[id] = map[string]any{"id": id, "object": "batch", "endpoint": "/v1/chat/completions",
+			"input_file_id": req.InputFileID, "completion_window": "24h", "status": "validating", "created_at": time.Now().Unix()}
+		reply(f.batches[id])
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/batches/"):
+		id := strings.TrimPrefix(path, "/batches/")
+		b, ok := f.batches[id]
+		if !ok {
+			http.Error(w, `{"error":{"message":"No such batch"}}`, http.StatusNotFound)
+			return
+		}
+		f.polls[id]++
+		switch f.polls[id] {
+		case 1:
+			b["status"] = "in_progress"
+		case 2:
+			b["status"] = "finalizing"
+		default:
+			if b["status"] != "completed" {
+				out, total := f.answer(f.files[b["input_file_id"].(string)])
+				outID := fmt.Sprintf("file-%d", len(f.files)+1)
+				f.files[outID] = out
+				b["status"], b["output_file_id"] = "completed", outID
+				b["request_counts"] = map[string]int{"total": total, "completed": total}
+			}
+		}
+		reply(b)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/files/") && strings.HasSuffix(path, "/content"):
+		data, ok := f.files[strings.TrimSuffix(strings.TrimPrefix(path, "/files/"), "/content")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// answer writes an output file for an input file: one short reply per
+// request that quotes the placeholders it was sent.
+func (f *fakeBatchAPI) answer(input []byte) ([]byte, int) {
+	var out bytes.Buffer
+	sc := bufio.NewScanner(bytes.NewReader(input))
+	sc.Buffer(nil, 16<<20)
+	n := 0
+	for sc.Scan() {
+		var req struct {
+			CustomID string `json:"custom_id"`
+		}
+		_ = json.Unmarshal(sc.Bytes(), &req)
+		tokens := mapping.TokenPattern.FindAllString(sc.Text(), -1)
+		seen := map[string]bool{}
+		var uniq []string
+		for _, t := range tokens {
+			if !seen[t] {
+				seen[t] = true
+				uniq = append(uniq, t)
+			}
+		}
+		reply := "Drafted a reply."
+		if len(uniq) > 0 {
+			reply = "Drafted a reply; it confirms the details on file for " + strings.Join(uniq, " and ") + "."
+		}
+		n++
+		line := map[string]any{
+			"id": fmt.Sprintf("batch_req_%d", n), "custom_id": req.CustomID, "error": nil,
+			"response": map[string]any{"status_code": 200, "request_id": fmt.Sprintf("req_%d", n), "body": map[string]any{
+				"id": fmt.Sprintf("chatcmpl-%d", n), "object": "chat.completion", "model": "gpt-4o-mini",
+				"choices": []any{map[string]any{"index": 0, "finish_reason": "stop",
+					"message": map[string]any{"role": "assistant", "content": reply}}},
+			}},
+		}
+		out.Write(marshal(line))
+		out.WriteByte('\n')
+	}
+	return out.Bytes(), n
+}
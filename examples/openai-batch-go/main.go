@@ -0,0 +1,254 @@
+// OpenAI Batch API + Blindfold: Run thousands of chat completions at batch
+// prices without uploading a single customer's PII.
+//
+// Every request in a JSONL batch input file is tokenized before the file
+// is uploaded. Mappings are kept locally by custom_id, in a state file per
+// batch, so the batch can be collected hours later, by another process.
+// The tool polls until the batch is done, downloads the output file and
+// detokenizes each line with the mapping of its custom_id.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+// submit tokenizes the input file, uploads it, creates the batch and
+// saves its state, before anything else can fail.
+func submit(ctx context.Context, bf bfclient.Client, oa *openai.Client, in, stateDir, policy, window string) (*state, error) {
+	tokenized, mappings, counts, err := tokenizeFile(ctx, bf, in)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Tokenized %d requests: %s\n", len(mappings), summarize(counts))
+
+	file, err := oa.CreateFileBytes(ctx, openai.FileBytesRequest{Name: "blindfold-batch.jsonl", Bytes: tokenized, Purpose: openai.PurposeBatch})
+	if err != nil {
+		return nil, fmt.Errorf("upload: %w", err)
+	}
+	batch, err := oa.CreateBatch(ctx, openai.CreateBatchRequest{
+		InputFileID:      file.ID,
+		Endpoint:         openai.BatchEndpointChatCompletions,
+		CompletionWindow: window,
+		Metadata:         map[string]any{"tokenized_by": "blindfold"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create batch: %w", err)
+	}
+	st := &state{BatchID: batch.ID, InputFileID: file.ID, Policy: policy, SubmittedAt: time.Now().UTC(), Requests: len(mappings), Mappings: mappings}
+	if err := st.save(stateDir); err != nil {
+		return nil, fmt.Errorf("batch %s was created, but its mappings couldn't be saved: %w", batch.ID, err)
+	}
+	fmt.Printf("Submitted %s (input %s); mappings in %s\n", batch.ID, file.ID, statePath(stateDir, batch.ID))
+	return st, nil
+}
+
+// wait polls the batch until it reaches a final status.
+func wait(ctx context.Context, oa *openai.Client, id string, poll time.Duration) (openai.Batch, error) {
+	last := ""
+	for {
+		resp, err := oa.RetrieveBatch(ctx, id)
+		if err != nil {
+			return openai.Batch{}, fmt.Errorf("batch %s: %w", id, err)
+		}
+		b := resp.Batch
+		if b.Status != last {
+			c := b.RequestCounts
+			fmt.Printf("  %-12s %d/%d done, %d failed\n", b.Status, c.Completed, c.Total, c.Failed)
+			last = b.Status
+		}
+		switch b.Status {
+		case "completed", "expired", "cancelled", "failed":
+			return b, nil
+		}
+		select {
+		case <-ctx.Done():
+			return b, ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
+
+// collect downloads the batch's output and error files. The output is
+// detokenized into out; errors are written to errOut as they are, since
+// they hold no model output.
+func collect(ctx context.Context, oa *openai.Client, b openai.Batch, st *state, out, errOut string) error {
+	if b.Status == "failed" {
+		var msgs []string
+		if b.Errors != nil {
+			for _, e := range b.Errors.Data {
+				msgs = append(msgs, e.Code+": "+e.Message)
+			}
+		}
+		return fmt.Errorf("batch %s failed: %s", b.ID, strings.Join(msgs, "; "))
+	}
+	if b.ErrorFileID != nil && *b.ErrorFileID != "" {
+		if err := download(ctx, oa, *b.ErrorFileID, errOut, nil); err != nil {
+			return err
+		}
+		fmt.Printf("Failed requests written to %s\n", errOut)
+	}
+	if b.OutputFileID == nil || *b.OutputFileID == "" {
+		return fmt.Errorf("batch %s ended %s with no output", b.ID, b.Status)
+	}
+	var n int
+	var unresolved map[string][]string
+	err := download(ctx, oa, *b.OutputFileID, out, func(r io.Reader, w io.Writer) (err error) {
+		n, unresolved, err = restoreFile(r, w, st.Mappings)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Detokenized %d of %d results into %s\n", n, st.Requests, out)
+	ids := make([]string, 0, len(unresolved))
+	for id, tokens := range unresolved {
+		if len(tokens) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		fmt.Printf("  %s: unresolved %s\n", id, strings.Join(unresolved[id], ", "))
+	}
+	return nil
+}
+
+// download writes file id to path (owner-only: restored output holds PII),
+// through transform if it isn't nil.
+func download(ctx context.Context, oa *openai.Client, id, path string, transform func(io.Reader, io.Writer) error) error {
+	body, err := oa.GetFileContent(ctx, id)
+	if err != nil {
+		return fmt.Errorf("download %s: %w", id, err)
+	}
+	defer body.Close()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if transform == nil {
+		_, err = io.Copy(f, body)
+	} else {
+		err = transform(body, f)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return f.Close()
+}
+
+func summarize(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "no entities"
+	}
+	parts := make([]string, 0, len(counts))
+	for typ, n := range counts {
+		parts = append(parts, fmt.Sprintf("%s ×%d", typ, n))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+func main() {
+	_ = godotenv.Load()
+	in := flag.String("in", "requests.jsonl", "batch input file (JSONL, /v1/chat/completions requests)")
+	out := flag.String("out", "results.jsonl", "detokenized output file")
+	errOut := flag.String("errors", "errors.jsonl", "where failed requests go")
+	stateDir := flag.String("state", "batches", "directory for per-batch state files holding the mappings (keep it private)")
+	resume := flag.String("collect", "", "collect a batch submitted earlier, by ID, instead of submitting")
+	noWait := flag.Bool("no-wait", false, "submit and exit; collect later with -collect")
+	poll := flag.Duration("poll", 30*time.Second, "how often to check the batch")
+	window := flag.String("window", "24h", "completion window")
+	dryRun := flag.Bool("dry-run", false, "print the tokenized input file and exit")
+	file := flag.String("policies", "", "policy file (YAML or JSON); empty = built-in policies only")
+	name := flag.String("policy", "strict", "policy to apply, by name")
+	runDemo := flag.Bool("demo", false, "run against an in-process fake Batch API")
+	flag.Parse()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+
+	if *dryRun {
+		tokenized, _, counts, err := tokenizeFile(ctx, bf, *in)
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(tokenized)
+		fmt.Fprintf(os.Stderr, "Entities: %s\n", summarize(counts))
+		return
+	}
+
+	key := os.Getenv("OPENAI_API_KEY")
+	cfg := openai.DefaultConfig(key)
+	var fake *fakeBatchAPI
+	if *runDemo {
+		srv, f := newFakeBatchAPI()
+		defer srv.Close()
+		cfg.BaseURL, fake = srv.URL+"/v1", f
+		*poll = 100 * time.Millisecond
+	} else if key == "" {
+		log.Fatal("OPENAI_API_KEY is not set (use -dry-run or -demo to try it without one)")
+	}
+	oa := openai.NewClientWithConfig(cfg)
+
+	var st *state
+	if *resume != "" {
+		if st, err = loadState(*stateDir, *resume); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		if st, err = submit(ctx, bf, oa, *in, *stateDir, pol.Name, *window); err != nil {
+			log.Fatal(err)
+		}
+		if fake != nil {
+			fmt.Println("\nUploaded input file:")
+			for _, line := range bytes.Split(bytes.TrimSpace(fake.uploaded), []byte("\n")) {
+				fmt.Printf("  %s\n", line)
+			}
+			fmt.Println()
+		}
+		if *noWait {
+			fmt.Printf("Collect it later with: go run . -collect %s\n", st.BatchID)
+			return
+		}
+	}
+
+	b, err := wait(ctx, oa, st.BatchID, *poll)
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Printf("\nStopped waiting; the batch keeps running. Collect it with: go run . -collect %s\n", st.BatchID)
+			return
+		}
+		log.Fatal(err)
+	}
+	if err := collect(ctx, oa, b, st, *out, *errOut); err != nil {
+		log.Fatal(err)
+	}
+}
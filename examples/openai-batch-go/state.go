@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// state is what a submitted batch needs to be collected later, possibly
+// hours later and from another process: its ID and the mapping of every
+// request by custom_id. It holds original values, so it is written
+// owner-only.
+type state struct {
+	BatchID     string                       `json:"batch_id"`
+	InputFileID string                       `json:"input_file_id"`
+	Policy      string                       `json:"policy"`
+	SubmittedAt time.Time                    `json:"submitted_at"`
+	Requests    int                          `json:"requests"`
+	Mappings    map[string]map[string]string `json:"mappings"`
+}
+
+func statePath(dir, batchID string) string {
+	return filepath.Join(dir, batchID+".json")
+}
+
+func (s *state) save(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(statePath(dir, s.BatchID), data, 0o600)
+}
+
+func loadState(dir, batchID string) (*state, error) {
+	data, err := os.ReadFile(statePath(dir, batchID))
+	if err != nil {
+		return nil, fmt.Errorf("batch %s: %w (mappings are kept by the run that submitted it)", batchID, err)
+	}
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", statePath(dir, batchID), err)
+	}
+	return &s, nil
+}
@@ -10,6 +10,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -46,6 +47,9 @@ func protectedChat(ctx context.Context, userMessage, policy, model string) (stri
 	if err != nil {
 		return "", fmt.Errorf("openai: %w", err)
 	}
+	if len(completion.Choices) == 0 {
+		return "", errors.New("openai: empty response")
+	}
 	aiResponse := completion.Choices[0].Message.Content
 
 	// 3. Detokenize — restore original values in the AI response
@@ -0,0 +1,95 @@
+// OpenTelemetry + Blindfold: Trace every step of a PII-safe LLM pipeline.
+//
+// Each user message becomes one trace: a root "protected_chat" span with
+// child spans for tokenize, the OpenAI call, and detokenize. Spans carry
+// entity counts and types, text lengths and token usage — never the PII
+// values, prompts, or completions — and are exported to an OTLP endpoint.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	bfotel "github.com/blindfold-dev/blindfold-cookbook/pkg/otel"
+)
+
+func protectedChat(ctx context.Context, bf *bfotel.Client, oa bfclient.ChatCompleter, userMessage, policy, model string) (string, error) {
+	ctx, span := bfotel.Start(ctx, "protected_chat", bfotel.Policy.String(policy))
+	defer span.End()
+
+	// 1. Tokenize — span records entity count and types, not values
+	tokenized, err := bf.Tokenize(ctx, userMessage, blindfold.WithCallPolicy(policy))
+	if err != nil {
+		bfotel.RecordError(span, err)
+		return "", fmt.Errorf("tokenize: %w", err)
+	}
+	fmt.Printf("Tokenized: %s\n", tokenized.Text)
+
+	// 2. Send tokenized text to OpenAI — span records model and token usage
+	completion, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "You are a helpful assistant."},
+			{Role: openai.ChatMessageRoleUser, Content: tokenized.Text},
+		},
+	})
+	if err != nil {
+		bfotel.RecordError(span, err)
+		return "", fmt.Errorf("openai: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", errors.New("openai: empty response")
+	}
+
+	// 3. Detokenize — span records replacements and unresolved tokens
+	return bf.DetokenizeContext(ctx, completion.Choices[0].Message.Content, tokenized.Mapping).Text, nil
+}
+
+func main() {
+	_ = godotenv.Load()
+	ctx := context.Background()
+
+	shutdown, err := bfotel.Setup(ctx, "otel-tracing-go")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		// Flush buffered spans before exiting
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			log.Printf("otel shutdown: %v", err)
+		}
+	}()
+
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := bfotel.Wrap(bfclient.FromEnv())
+	oa := bfotel.WrapChat(openai.NewClient(os.Getenv("OPENAI_API_KEY")))
+
+	messages := []string{
+		"Hi, I'm John Smith. My email is john.smith@example.com and my phone is +1 555-123-4567.",
+		"Please refund card 4111 1111 1111 1111 and confirm to sam@example.org.",
+	}
+	for _, msg := range messages {
+		fmt.Printf("\nUser: %s\n", msg)
+		response, err := protectedChat(ctx, bf, oa, msg, "strict", "gpt-4o-mini")
+		if err != nil {
+			log.Printf("request failed: %v", err)
+			continue
+		}
+		fmt.Printf("Assistant: %s\n", response)
+	}
+}
@@ -0,0 +1,117 @@
+// Parallel chunk tokenization + Blindfold: Keep placeholders consistent across chunks.
+//
+// Splits a long document into chunks, tokenizes them concurrently, and merges
+// the per-chunk mappings so the same person or email gets one token across
+// the whole document before it is sent to OpenAI.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+const document = `Ticket opened by sarah.chen@acme.com about a failed refund.
+
+Sarah called back from +1 415-555-0134 and asked us to reply to sarah.chen@acme.com only.
+
+Escalated to billing. The refund was authorised by tom.baker@acme.com, cc sarah.chen@acme.com.
+
+Resolved: refund for customer ID 4532-7562-9102-3456 confirmed by tom.baker@acme.com.`
+
+// tokenizeChunks tokenizes every chunk concurrently. Each call numbers its
+// tokens independently, so the returned mappings overlap and disagree.
+func tokenizeChunks(ctx context.Context, bf bfclient.Client, chunks []string, policy string) ([]string, []map[string]string, error) {
+	texts := make([]string, len(chunks))
+	mappings := make([]map[string]string, len(chunks))
+	errs := make([]error, len(chunks))
+
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk string) {
+			defer wg.Done()
+			res, err := bf.Tokenize(ctx, chunk, blindfold.WithCallPolicy(policy))
+			if err != nil {
+				errs[i] = fmt.Errorf("chunk %d: %w", i, err)
+				return
+			}
+			texts[i], mappings[i] = res.Text, res.Mapping
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return texts, mappings, nil
+}
+
+func summarize(ctx context.Context, oa *openai.Client, text, model string) (string, error) {
+	completion, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "Summarize this support ticket in three sentences. Keep placeholders like <Email Address_1> exactly as written."},
+			{Role: openai.ChatMessageRoleUser, Content: text},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("openai: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", errors.New("openai: empty response")
+	}
+	return completion.Choices[0].Message.Content, nil
+}
+
+func main() {
+	_ = godotenv.Load()
+	ctx := context.Background()
+
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := bfclient.FromEnv()
+	oa := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
+
+	// 1. Fan out — tokenize each paragraph in parallel
+	chunks := strings.Split(document, "\n\n")
+	texts, mappings, err := tokenizeChunks(ctx, bf, chunks, "strict")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 2. Merge — one token per value across the whole document
+	merged := mapping.Merge(mappings...)
+	fmt.Printf("Merged %d chunk mappings into %d tokens (%d conflicts resolved)\n", len(mappings), len(merged.Mapping), len(merged.Conflicts))
+	for _, c := range merged.Conflicts {
+		fmt.Printf("  chunk %d: %s %s -> %s\n", c.Source, c.Kind, c.Token, c.Resolved)
+	}
+	tokenized := strings.Join(merged.Apply(texts), "\n\n")
+	fmt.Printf("\nTokenized document:\n%s\n\n", tokenized)
+
+	// 3. Send the consistent document to OpenAI
+	summary, err := summarize(ctx, oa, tokenized, "gpt-4o-mini")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 4. Detokenize with the merged mapping
+	restored := bf.Detokenize(summary, merged.Mapping)
+	fmt.Printf("Summary: %s\n", restored.Text)
+}
@@ -0,0 +1,164 @@
+// PCI card disputes + Blindfold: Handle transaction-dispute chats without
+// sending card or bank data to the LLM.
+//
+// A dispute chat is where customers paste everything: the full card number,
+// its CVV and expiry "for verification", the checking account a refund
+// should go to. Each customer message is tokenized with a policy built on
+// pci_dss plus routing, account and expiry patterns, the conversation keeps
+// one token per value across turns, and the agent's replies are restored.
+// With -policy disputes-masked, card data is masked in place instead — it is
+// never tokenized, so no mapping ever holds a PAN.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+const systemPrompt = "You are a card-dispute agent at a retail bank. Acknowledge the customer, " +
+	"say what you will do next, and ask for anything missing. Never ask for a CVV. " +
+	"Keep placeholders like <Credit Card Number_1> exactly as they are."
+
+var messages = []string{
+	"Hi, there's a charge of $842.19 from ELECTROMART on my card 4111 1111 1111 1111 that I never made.",
+	"The card expires 09/27 and the CVV is 381 if you need it. Can the refund go to my checking account 004417883920, routing 021000021?",
+	"Actually it's the same card, 4111 1111 1111 1111. Please block it and send a new one.",
+}
+
+// mask hides every digit of a value but the last four of a card number.
+func mask(entityType, value string) string {
+	keep := 0
+	if entityType == blindfold.EntityCreditCard {
+		keep = 4
+	}
+	n := 0
+	for _, c := range value {
+		if '0' <= c && c <= '9' {
+			n++
+		}
+	}
+	var b strings.Builder
+	for _, c := range value {
+		if '0' <= c && c <= '9' {
+			if n--; n >= keep {
+				c = '•'
+			}
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// applyMasks replaces the tokens of types the policy masks with their
+// masked value and drops them from the mapping.
+func applyMasks(pol *policyconf.Policy, res *blindfold.TokenizeResponse) *blindfold.TokenizeResponse {
+	masked := make(map[string]string)
+	for token, value := range res.Mapping {
+		if typ, _, _ := mapping.ParseToken(token); pol.Action(typ) == policyconf.Mask {
+			masked[token] = mask(typ, value)
+		}
+	}
+	if len(masked) == 0 {
+		return res
+	}
+	out := &blindfold.TokenizeResponse{
+		Text:             mapping.Detokenize(res.Text, masked),
+		Mapping:          make(map[string]string, len(res.Mapping)),
+		DetectedEntities: res.DetectedEntities,
+		EntitiesCount:    res.EntitiesCount,
+	}
+	for token, value := range res.Mapping {
+		if _, ok := masked[token]; !ok {
+			out.Mapping[token] = value
+		}
+	}
+	return out
+}
+
+// conversation holds the tokenized chat and one mapping for all of it.
+type conversation struct {
+	history []openai.ChatCompletionMessage
+	mapping map[string]string
+}
+
+// add tokenizes a customer message into the conversation. A value seen in
+// an earlier turn keeps its token.
+func (c *conversation) add(res *blindfold.TokenizeResponse) string {
+	merged := mapping.Merge(c.mapping, res.Mapping)
+	c.mapping = merged.Mapping
+	text := merged.Rewrite(1, res.Text)
+	c.history = append(c.history, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: text})
+	return text
+}
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply (default: the file's default)")
+	dryRun := flag.Bool("dry-run", false, "tokenize only; skip OpenAI calls")
+	flag.Parse()
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+	oa := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
+
+	conv := &conversation{history: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleSystem, Content: systemPrompt}}}
+	for i, msg := range messages {
+		fmt.Printf("== Turn %d ==\n", i+1)
+		res, err := bf.Tokenize(ctx, msg)
+		if err != nil {
+			log.Fatalf("tokenize: %v", err)
+		}
+		fmt.Printf("Customer: %s\nSent:     %s\n", msg, conv.add(applyMasks(pol, res)))
+		if *dryRun {
+			fmt.Println()
+			continue
+		}
+
+		completion, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+			Model:    openai.GPT4oMini,
+			Messages: conv.history,
+		})
+		if err != nil {
+			log.Fatalf("openai: %v", err)
+		}
+		if len(completion.Choices) == 0 {
+			log.Fatal("openai: empty response")
+		}
+		reply := completion.Choices[0].Message
+		conv.history = append(conv.history, reply)
+		fmt.Printf("Agent:    %s\n\n", bf.Detokenize(reply.Content, conv.mapping).Text)
+	}
+
+	tokens := make([]string, 0, len(conv.mapping))
+	for token := range conv.mapping {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	fmt.Printf("Mapping (%d values):\n", len(tokens))
+	for _, token := range tokens {
+		fmt.Printf("  %-32s %s\n", token, conv.mapping[token])
+	}
+}
@@ -0,0 +1,89 @@
+// Phone number validation + Blindfold: Cut phone false positives in logs.
+//
+// Regex phone detection is generous: in application logs it also fires on
+// ticket numbers, version strings and timestamps, and every false positive
+// is a placeholder the model can't reason about. This example post-validates
+// each Phone Number token with libphonenumber (github.com/nyaruka/phonenumbers),
+// puts the rejected values back, and records the E.164 form, region and
+// line type of the numbers it keeps as mapping metadata.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+const sample = `09:12:44 INFO  support: callback requested by +1 (415) 555-0134, alt 020 7946 0958
+09:12:45 WARN  ticket 0042-2024-17 reopened by agent
+09:12:47 INFO  sms sent to +49 30 12345678 (template 7)
+09:12:51 DEBUG retry job 1 555 010 4499 scheduled
+09:13:02 INFO  fax +44 123 45 failed`
+
+// sortedTokens orders tokens by entity type, then number.
+func sortedTokens(m map[string]string) []string {
+	tokens := make([]string, 0, len(m))
+	for t := range m {
+		tokens = append(tokens, t)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		ti, ni, _ := mapping.ParseToken(tokens[i])
+		tj, nj, _ := mapping.ParseToken(tokens[j])
+		if ti != tj {
+			return ti < tj
+		}
+		return ni < nj
+	})
+	return tokens
+}
+
+func main() {
+	_ = godotenv.Load()
+	regionList := flag.String("regions", "US,GB", "regions tried, in order, for numbers without a country code")
+	normalize := flag.Bool("normalize", false, "map phone tokens to their E.164 form")
+	flag.Parse()
+	regions := strings.Split(*regionList, ",")
+	ctx := context.Background()
+
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := bfclient.FromEnv()
+
+	raw, err := bf.Tokenize(ctx, sample)
+	if err != nil {
+		log.Fatalf("tokenize: %v", err)
+	}
+	tokenized, phones, suppressed := validatePhones(raw, regions, *normalize)
+
+	fmt.Printf("Regex only:\n%s\n\n", raw.Text)
+	fmt.Printf("Validated:\n%s\n\n", tokenized.Text)
+
+	fmt.Printf("Suppressed %d false positive(s):\n", len(suppressed))
+	for _, s := range suppressed {
+		fmt.Printf("  %q\n", s)
+	}
+
+	fmt.Println("\nPhone metadata:")
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(phones); err != nil {
+		log.Fatal(err)
+	}
+
+	restored := bf.Detokenize(tokenized.Text, tokenized.Mapping)
+	fmt.Printf("\nRestored:\n%s\n", restored.Text)
+}
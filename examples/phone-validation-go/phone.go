@@ -0,0 +1,103 @@
+package main
+
+import (
+	"github.com/nyaruka/phonenumbers"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// Phone is a Phone Number token that libphonenumber accepted.
+type Phone struct {
+	Token  string `json:"token"`
+	Raw    string `json:"raw"`
+	E164   string `json:"e164"`
+	Region string `json:"region"`
+	Kind   string `json:"kind"`
+}
+
+// parsePhone parses raw as a number of the first region in which it is
+// valid. Numbers in international format ignore the regions.
+func parsePhone(raw string, regions []string) (*phonenumbers.PhoneNumber, bool) {
+	for _, region := range regions {
+		n, err := phonenumbers.Parse(raw, region)
+		if err == nil && phonenumbers.IsValidNumber(n) {
+			return n, true
+		}
+	}
+	return nil, false
+}
+
+// validatePhones checks every Phone Number token in res with libphonenumber.
+// Values it rejects — ticket numbers, version strings, timestamps the regex
+// took for phone numbers — are put back in the text and dropped from the
+// mapping; they are returned as suppressed. Accepted numbers are returned
+// with their E.164 form. If normalize is set, the mapping maps their tokens
+// to the E.164 form, so detokenized text shows normalized numbers.
+func validatePhones(res *blindfold.TokenizeResponse, regions []string, normalize bool) (*blindfold.TokenizeResponse, []Phone, []string) {
+	out := &blindfold.TokenizeResponse{Mapping: make(map[string]string, len(res.Mapping))}
+	restore := make(map[string]string)
+	var phones []Phone
+	var suppressed []string
+	for _, token := range sortedTokens(res.Mapping) {
+		value := res.Mapping[token]
+		if typ, _, _ := mapping.ParseToken(token); typ != blindfold.EntityPhoneNumber {
+			out.Mapping[token] = value
+			continue
+		}
+		n, ok := parsePhone(value, regions)
+		if !ok {
+			restore[token] = value
+			suppressed = append(suppressed, value)
+			continue
+		}
+		p := Phone{
+			Token:  token,
+			Raw:    value,
+			E164:   phonenumbers.Format(n, phonenumbers.E164),
+			Region: phonenumbers.GetRegionCodeForNumber(n),
+			Kind:   kind(phonenumbers.GetNumberType(n)),
+		}
+		phones = append(phones, p)
+		out.Mapping[token] = value
+		if normalize {
+			out.Mapping[token] = p.E164
+		}
+	}
+	out.Text = mapping.Detokenize(res.Text, restore)
+	for _, e := range res.DetectedEntities {
+		if e.Type == blindfold.EntityPhoneNumber && containsValue(suppressed, e.Text) {
+			continue
+		}
+		out.DetectedEntities = append(out.DetectedEntities, e)
+	}
+	out.EntitiesCount = len(out.DetectedEntities)
+	return out, phones, suppressed
+}
+
+func kind(t phonenumbers.PhoneNumberType) string {
+	switch t {
+	case phonenumbers.MOBILE:
+		return "mobile"
+	case phonenumbers.FIXED_LINE:
+		return "fixed line"
+	case phonenumbers.FIXED_LINE_OR_MOBILE:
+		return "fixed line or mobile"
+	case phonenumbers.TOLL_FREE:
+		return "toll free"
+	case phonenumbers.VOIP:
+		return "voip"
+	default:
+		return "other"
+	}
+}
+
+func containsValue(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
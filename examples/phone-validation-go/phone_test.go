@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nyaruka/phonenumbers"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+func TestParsePhone(t *testing.T) {
+	for _, c := range []struct {
+		raw     string
+		regions []string
+		want    string // E.164, or empty if rejected
+	}{
+		{"020 7946 0958", []string{"US", "GB"}, "+442079460958"}, // not a US number, so GB
+		{"020 7946 0958", []string{"US"}, ""},
+		{"(415) 555-0134", []string{"US", "GB"}, "+14155550134"},
+		{"+49 30 12345678", []string{"US"}, "+493012345678"}, // international ignores the regions
+		{"0042-2024-17", []string{"US", "GB"}, ""},
+		{"+44 123 45", []string{"US", "GB"}, ""},
+	} {
+		n, ok := parsePhone(c.raw, c.regions)
+		got := ""
+		if ok {
+			got = phonenumbers.Format(n, phonenumbers.E164)
+		}
+		if got != c.want {
+			t.Errorf("parsePhone(%q, %v) = %q, want %q", c.raw, c.regions, got, c.want)
+		}
+	}
+}
+
+func TestValidatePhones(t *testing.T) {
+	res := &blindfold.TokenizeResponse{
+		Text: "Call <Phone Number_1> or <Phone Number_2>, ticket <Phone Number_3>, mail <Email Address_1>",
+		Mapping: map[string]string{
+			"<Phone Number_1>":  "+1 (415) 555-0134",
+			"<Phone Number_2>":  "020 7946 0958",
+			"<Phone Number_3>":  "0042-2024-17",
+			"<Email Address_1>": "ops@example.com",
+		},
+		DetectedEntities: []blindfold.DetectedEntity{
+			{Type: blindfold.EntityPhoneNumber, Text: "+1 (415) 555-0134"},
+			{Type: blindfold.EntityPhoneNumber, Text: "020 7946 0958"},
+			{Type: blindfold.EntityPhoneNumber, Text: "0042-2024-17"},
+			{Type: blindfold.EntityEmailAddress, Text: "ops@example.com"},
+		},
+	}
+	wantPhones := []Phone{
+		{Token: "<Phone Number_1>", Raw: "+1 (415) 555-0134", E164: "+14155550134", Region: "US", Kind: "fixed line or mobile"},
+		{Token: "<Phone Number_2>", Raw: "020 7946 0958", E164: "+442079460958", Region: "GB", Kind: "fixed line"},
+	}
+	for _, c := range []struct {
+		normalize bool
+		want      map[string]string
+	}{
+		{false, map[string]string{
+			"<Phone Number_1>":  "+1 (415) 555-0134",
+			"<Phone Number_2>":  "020 7946 0958",
+			"<Email Address_1>": "ops@example.com",
+		}},
+		{true, map[string]string{
+			"<Phone Number_1>":  "+14155550134",
+			"<Phone Number_2>":  "+442079460958",
+			"<Email Address_1>": "ops@example.com",
+		}},
+	} {
+		out, phones, suppressed := validatePhones(res, []string{"US", "GB"}, c.normalize)
+		if want := "Call <Phone Number_1> or <Phone Number_2>, ticket 0042-2024-17, mail <Email Address_1>"; out.Text != want {
+			t.Errorf("normalize=%v: text = %q, want %q", c.normalize, out.Text, want)
+		}
+		if !reflect.DeepEqual(out.Mapping, c.want) {
+			t.Errorf("normalize=%v: mapping = %v, want %v", c.normalize, out.Mapping, c.want)
+		}
+		if !reflect.DeepEqual(phones, wantPhones) {
+			t.Errorf("normalize=%v: phones = %+v, want %+v", c.normalize, phones, wantPhones)
+		}
+		if !reflect.DeepEqual(suppressed, []string{"0042-2024-17"}) {
+			t.Errorf("normalize=%v: suppressed = %q", c.normalize, suppressed)
+		}
+		if out.EntitiesCount != 3 || len(out.DetectedEntities) != 3 {
+			t.Errorf("normalize=%v: entities = %+v, want the ticket number dropped", c.normalize, out.DetectedEntities)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/genpii"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// fakeModel answers with the placeholders it was sent after a fixed
+// latency, slower than local tokenizing, so the LLM stage is the
+// bottleneck. Call numbers listed in fail get a 503 once; from call
+// rejectFrom on, every call gets a 401.
+type fakeModel struct {
+	latency    time.Duration
+	fail       map[int64]bool
+	rejectFrom int64
+	calls      atomic.Int64
+}
+
+func (m *fakeModel) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	n := m.calls.Add(1)
+	select {
+	case <-time.After(m.latency):
+	case <-ctx.Done():
+		return openai.ChatCompletionResponse{}, ctx.Err()
+	}
+	switch {
+	case m.rejectFrom > 0 && n >= m.rejectFrom:
+		return openai.ChatCompletionResponse{}, &openai.APIError{HTTPStatusCode: 401, Code: "invalid_api_key", Message: "Incorrect API key provided"}
+	case m.fail[n]:
+		return openai.ChatCompletionResponse{}, &openai.APIError{HTTPStatusCode: 503, Message: "The server is overloaded"}
+	}
+	var contacts []string
+	for _, token := range mapping.TokenPattern.FindAllString(req.Messages[1].Content, -1) {
+		if typ, _, _ := mapping.ParseToken(token); typ == "Email Address" || typ == "Phone Number" {
+			contacts = append(contacts, token)
+		}
+	}
+	reply := "No contact details given."
+	if len(contacts) > 0 {
+		reply = "Follow up at " + strings.Join(contacts, " or ") + "."
+	}
+	return openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: reply}}}}, nil
+}
+
+// demoInput returns n JSONL records of three generated paragraphs each.
+func demoInput(n int) []byte {
+	docs := genpii.New(7).Documents(3*n, "")
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i := 0; i < n; i++ {
+		paras := []string{docs[3*i].Text, docs[3*i+1].Text, docs[3*i+2].Text}
+		enc.Encode(record{ID: fmt.Sprintf("rec-%03d", i+1), Text: strings.Join(paras, "\n\n")})
+	}
+	return buf.Bytes()
+}
+
+func demo(bf bfclient.Client) error {
+	input := demoInput(40)
+	newDemo := func(m *fakeModel) *pipeline {
+		p := newPipeline(bf, m, "demo")
+		p.chunkSize, p.tokenizers, p.callers, p.buffer = 300, 2, 4, 4
+		return p
+	}
+	fmt.Printf("40 records, chunks of up to 300 bytes; 2 tokenizers, 4 model callers, 4 items between stages\n")
+
+	fmt.Println("\n── 1. A full run; the model takes 25ms a call and fails call 9 once")
+	p := newDemo(&fakeModel{latency: 25 * time.Millisecond, fail: map[int64]bool{9: true}})
+	var out bytes.Buffer
+	start := time.Now()
+	err := p.run(context.Background(), bytes.NewReader(input), &out)
+	fmt.Printf("finished in %s, err = %v\n", time.Since(start).Round(10*time.Millisecond), err)
+	p.report(os.Stdout)
+	printResults(out.String())
+
+	fmt.Println("\n── 2. The key is revoked at call 30")
+	before := runtime.NumGoroutine()
+	p = newDemo(&fakeModel{latency: 25 * time.Millisecond, rejectFrom: 30})
+	out.Reset()
+	err = p.run(context.Background(), bytes.NewReader(input), &out)
+	fmt.Printf("stopped: %v\n", err)
+	p.report(os.Stdout)
+	fmt.Printf("  %d results written before the cancel; goroutines left running: %d\n",
+		strings.Count(out.String(), "\n"), runtime.NumGoroutine()-before)
+	return nil
+}
+
+// printResults prints the first two results and any failed ones.
+func printResults(jsonl string) {
+	lines := strings.Split(strings.TrimSpace(jsonl), "\n")
+	fmt.Printf("  %d results, e.g.\n", len(lines))
+	for i, line := range lines {
+		if i < 2 || strings.Contains(line, `"error"`) {
+			fmt.Println("   ", line)
+		}
+	}
+}
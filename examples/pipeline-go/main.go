@@ -0,0 +1,100 @@
+// Streaming pipeline + Blindfold: Summarize a large JSONL export with
+// bounded memory.
+//
+// Records flow through read → chunk → tokenize → LLM → detokenize → write,
+// each stage a goroutine in one errgroup, with a pool of workers for the
+// tokenize and LLM stages. The channels between stages are bounded, so a
+// slow stage holds up the ones before it instead of letting documents
+// pile up in memory, and the first fatal error cancels every stage at
+// once. Each document's summary is written as soon as its last chunk is
+// restored.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/resilience"
+)
+
+func main() {
+	_ = godotenv.Load()
+	inPath := flag.String("in", "-", "JSONL input of {\"id\", \"text\"} records (- = stdin)")
+	outPath := flag.String("out", "-", "JSONL output of {\"id\", \"summary\"} results (- = stdout)")
+	policy := flag.String("policy", "strict", "built-in Blindfold policy")
+	model := flag.String("model", "gpt-4o-mini", "OpenAI model")
+	chunkSize := flag.Int("chunk-size", 2000, "bytes of paragraphs per chunk")
+	tokenizers := flag.Int("tokenizers", 4, "concurrent tokenize calls")
+	callers := flag.Int("callers", 8, "concurrent model calls")
+	buffer := flag.Int("buffer", 16, "items queued between two stages")
+	rps := flag.Float64("rps", 10, "request rate limit, per service (0 = unlimited)")
+	demoMode := flag.Bool("demo", false, "run synthetic records through a fake model")
+	flag.Parse()
+	if *tokenizers < 1 || *callers < 1 || *buffer < 0 || *chunkSize < 1 {
+		log.Fatal("-tokenizers, -callers and -chunk-size must be at least 1, -buffer at least 0")
+	}
+
+	pol, err := policyconf.Resolve("", *policy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(append(pol.ClientOptions(), blindfold.WithMaxRetries(0))...))
+
+	if *demoMode {
+		if err := demo(bf); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	p := newPipeline(
+		resilience.Wrap(bf, resilience.DefaultPolicy(*rps)),
+		resilience.WrapChat(openai.NewClient(os.Getenv("OPENAI_API_KEY")), resilience.DefaultPolicy(*rps)),
+		*model,
+	)
+	p.chunkSize, p.tokenizers, p.callers, p.buffer = *chunkSize, *tokenizers, *callers, *buffer
+
+	var in io.Reader = os.Stdin
+	if *inPath != "-" {
+		f, err := os.Open(*inPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	var out io.Writer = os.Stdout
+	if *outPath != "-" {
+		f, err := os.OpenFile(*outPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	// Ctrl-C cancels the pipeline like a fatal error does; the documents
+	// already restored are still written
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err = p.run(ctx, in, out)
+	p.report(os.Stderr)
+	if err != nil {
+		log.Fatalf("stopped: %v", err)
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+const systemPrompt = "Summarize this excerpt of a support record in one sentence. Keep placeholders like <Person_1> exactly as they are."
+
+// record is one input line.
+type record struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// result is one output line: a document's chunk summaries, restored, or
+// the error that failed it.
+type result struct {
+	ID      string `json:"id"`
+	Summary string `json:"summary,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Kind    string `json:"kind,omitempty"`
+}
+
+// piece is a chunk of a document on its way through the pipeline. text is
+// the raw chunk, then the tokenized chunk, then the model's answer.
+type piece struct {
+	doc          string
+	index, total int
+	text         string
+	mapping      map[string]string
+	err          error
+}
+
+func (p piece) String() string { return fmt.Sprintf("%s chunk %d/%d", p.doc, p.index+1, p.total) }
+
+// stage counts what one stage handled and how long it waited on a full
+// queue to hand its output on: the backpressure from the stages after it.
+type stage struct {
+	items   atomic.Int64
+	blocked atomic.Int64 // time.Duration
+}
+
+// pipeline is read → chunk → tokenize → LLM → detokenize → write, one
+// errgroup goroutine per stage and a pool each for tokenize and LLM,
+// joined by channels of buffer items.
+//
+// A document that fails is written with its error and the job goes on. A
+// fatal error (bferrors.IsFatal: a rejected key, a used-up quota), a
+// malformed input line or a failed write is different: it fails every
+// item after it too, so the first one cancels the group's context, every
+// stage returns, and run returns that error.
+type pipeline struct {
+	bf         bfclient.Client
+	llm        bfclient.ChatCompleter
+	model      string
+	chunkSize  int // bytes; paragraphs are packed into chunks up to this size
+	tokenizers int
+	callers    int
+	buffer     int // capacity of each channel between stages
+
+	read, chunk, tokenize, call, restore, write stage
+}
+
+func newPipeline(bf bfclient.Client, llm bfclient.ChatCompleter, model string) *pipeline {
+	return &pipeline{bf: bf, llm: llm, model: model, chunkSize: 2000, tokenizers: 4, callers: 8, buffer: 16}
+}
+
+// run processes the records in in and writes results to out, in the order
+// documents finish.
+func (p *pipeline) run(ctx context.Context, in io.Reader, out io.Writer) error {
+	g, ctx := errgroup.WithContext(ctx)
+	docs := make(chan record, p.buffer)
+	raw := make(chan piece, p.buffer)
+	safe := make(chan piece, p.buffer)
+	answered := make(chan piece, p.buffer)
+	done := make(chan result, p.buffer)
+
+	// Each stage closes its output when it returns, so the next one drains
+	// what is left and returns too; after a cancellation, sends and
+	// receives also select on ctx, so nothing blocks on a stage that quit
+	g.Go(func() error {
+		defer close(docs)
+		return p.readRecords(ctx, in, docs)
+	})
+	g.Go(func() error {
+		defer close(raw)
+		return p.chunkDocs(ctx, docs, raw)
+	})
+	pool(g, p.tokenizers, safe, func() error { return p.tokenizePieces(ctx, raw, safe) })
+	pool(g, p.callers, answered, func() error { return p.callModel(ctx, safe, answered) })
+	g.Go(func() error {
+		defer close(done)
+		return p.restorePieces(ctx, answered, done)
+	})
+	g.Go(func() error { return p.writeResults(ctx, done, out) })
+	return g.Wait()
+}
+
+// pool runs n copies of fn in g and closes out once all have returned.
+func pool[T any](g *errgroup.Group, n int, out chan<- T, fn func() error) {
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		g.Go(func() error {
+			defer wg.Done()
+			return fn()
+		})
+	}
+	g.Go(func() error {
+		wg.Wait()
+		close(out)
+		return nil
+	})
+}
+
+// send hands v to the next stage, counting the time s waits for room.
+func send[T any](ctx context.Context, s *stage, ch chan<- T, v T) error {
+	select {
+	case ch <- v:
+		s.items.Add(1)
+		return nil
+	default:
+	}
+	start := time.Now()
+	defer func() { s.blocked.Add(int64(time.Since(start))) }()
+	select {
+	case ch <- v:
+		s.items.Add(1)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *pipeline) readRecords(ctx context.Context, in io.Reader, docs chan<- record) error {
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var r record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			return fmt.Errorf("input line %d: %w", line, err)
+		}
+		if r.ID == "" || strings.TrimSpace(r.Text) == "" {
+			return fmt.Errorf("input line %d: want {\"id\": ..., \"text\": ...}", line)
+		}
+		if err := send(ctx, &p.read, docs, r); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+func (p *pipeline) chunkDocs(ctx context.Context, docs <-chan record, raw chan<- piece) error {
+	for r := range docs {
+		chunks := splitParagraphs(r.Text, p.chunkSize)
+		for i, c := range chunks {
+			if err := send(ctx, &p.chunk, raw, piece{doc: r.ID, index: i, total: len(chunks), text: c}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// splitParagraphs packs text's paragraphs into chunks of up to size bytes.
+// A paragraph longer than size is a chunk of its own: cutting inside one
+// could split an entity between two Tokenize calls.
+func splitParagraphs(text string, size int) []string {
+	var chunks []string
+	var cur strings.Builder
+	for _, para := range strings.Split(text, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		if cur.Len() > 0 && cur.Len()+2+len(para) > size {
+			chunks = append(chunks, cur.String())
+			cur.Reset()
+		}
+		if cur.Len() > 0 {
+			cur.WriteString("\n\n")
+		}
+		cur.WriteString(para)
+	}
+	if cur.Len() > 0 {
+		chunks = append(chunks, cur.String())
+	}
+	return chunks
+}
+
+// fail records err on the piece and reports whether it should stop the
+// job instead.
+func fail(ctx context.Context, pc *piece, op string, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	err = bferrors.Wrap(op, err)
+	if bferrors.IsFatal(err) {
+		return fmt.Errorf("%s: %w", pc, err)
+	}
+	pc.err = err
+	return nil
+}
+
+func (p *pipeline) tokenizePieces(ctx context.Context, raw <-chan piece, safe chan<- piece) error {
+	for pc := range raw {
+		res, err := p.bf.Tokenize(ctx, pc.text)
+		if err != nil {
+			if err := fail(ctx, &pc, "tokenize", err); err != nil {
+				return err
+			}
+		} else {
+			pc.text, pc.mapping = res.Text, res.Mapping
+		}
+		if err := send(ctx, &p.tokenize, safe, pc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *pipeline) callModel(ctx context.Context, safe <-chan piece, answered chan<- piece) error {
+	for pc := range safe {
+		// A piece that failed to tokenize is passed on as it is: its raw
+		// text must not reach the model
+		if pc.err == nil {
+			res, err := p.llm.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+				Model: p.model,
+				Messages: []openai.ChatCompletionMessage{
+					{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+					{Role: openai.ChatMessageRoleUser, Content: pc.text},
+				},
+			})
+			switch {
+			case err != nil:
+				if err := fail(ctx, &pc, "openai", err); err != nil {
+					return err
+				}
+			case len(res.Choices) == 0:
+				pc.err = bferrors.Wrap("openai", fmt.Errorf("empty response"))
+			default:
+				pc.text = res.Choices[0].Message.Content
+			}
+		}
+		if err := send(ctx, &p.call, answered, pc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restorePieces detokenizes each answer with its own chunk's mapping and
+// emits a document once all of its chunks are in. Chunks arrive in any
+// order; a mapping is dropped as soon as its chunk is restored.
+func (p *pipeline) restorePieces(ctx context.Context, answered <-chan piece, done chan<- result) error {
+	type assembly struct {
+		parts []string
+		got   int
+		err   error
+	}
+	pending := make(map[string]*assembly)
+	for pc := range answered {
+		a := pending[pc.doc]
+		if a == nil {
+			a = &assembly{parts: make([]string, pc.total)}
+			pending[pc.doc] = a
+		}
+		a.got++
+		if pc.err != nil && a.err == nil {
+			a.err = fmt.Errorf("%s: %w", pc, pc.err)
+		}
+		if pc.err == nil {
+			a.parts[pc.index] = mapping.Detokenize(pc.text, pc.mapping)
+		}
+		if a.got < pc.total {
+			continue
+		}
+		delete(pending, pc.doc)
+		r := result{ID: pc.doc, Summary: strings.Join(a.parts, " ")}
+		if a.err != nil {
+			// Nothing of a failed document is written, not even the
+			// chunks that made it
+			r = result{ID: pc.doc, Error: a.err.Error(), Kind: bferrors.KindOf(a.err).String()}
+		}
+		if err := send(ctx, &p.restore, done, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *pipeline) writeResults(ctx context.Context, done <-chan result, out io.Writer) error {
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+	for r := range done {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("write %s: %w", r.ID, err)
+		}
+		p.write.items.Add(1)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	// The channels only close once every stage is done, canceled or not
+	return ctx.Err()
+}
+
+// report prints what each stage handled and how long it was held up by
+// the stages after it.
+func (p *pipeline) report(w io.Writer) {
+	for _, s := range []struct {
+		name string
+		*stage
+	}{{"read", &p.read}, {"chunk", &p.chunk}, {"tokenize", &p.tokenize}, {"llm", &p.call}, {"detokenize", &p.restore}} {
+		fmt.Fprintf(w, "  %-10s %4d sent, blocked %s\n", s.name, s.items.Load(), time.Duration(s.blocked.Load()).Round(time.Millisecond))
+	}
+	fmt.Fprintf(w, "  %-10s %4d written\n", "write", p.write.items.Load())
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+var local = blindfold.New(blindfold.WithMode("local"))
+
+func TestSplitParagraphs(t *testing.T) {
+	got := splitParagraphs("one\n\ntwo\n\n\n\nthree is long", 8)
+	if strings.Join(got, "|") != "one\n\ntwo|three is long" {
+		t.Errorf("chunks = %q", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	input := `{"id": "a", "text": "Mail omar@example.com\n\nor call +1 415-555-0134"}` + "\n" + `{"id": "b", "text": "Nothing here"}` + "\n"
+	p := newPipeline(local, &fakeModel{fail: map[int64]bool{3: true}}, "test")
+	p.chunkSize, p.tokenizers, p.callers = 10, 1, 1
+	var out bytes.Buffer
+	if err := p.run(context.Background(), strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+	results := map[string]result{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var r result
+		json.Unmarshal([]byte(line), &r)
+		results[r.ID] = r
+	}
+	// One worker per stage keeps the order, so b's is the third call
+	if a := results["a"]; a.Summary != "Follow up at omar@example.com. Follow up at +1 415-555-0134." {
+		t.Errorf("a = %+v", a)
+	}
+	if b := results["b"]; b.Summary != "" || b.Kind != "transient" {
+		t.Errorf("b = %+v", b)
+	}
+}
+
+func TestFatalErrorCancelsAllStages(t *testing.T) {
+	before := runtime.NumGoroutine()
+	p := newPipeline(local, &fakeModel{latency: time.Millisecond, rejectFrom: 5}, "test")
+	p.buffer = 1
+	err := p.run(context.Background(), bytes.NewReader(demoInput(50)), &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("err = %v", err)
+	}
+	if p.read.items.Load() == 50 {
+		t.Error("reader went through the whole input")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n := runtime.NumGoroutine() - before; n > 0 {
+		t.Errorf("%d goroutines still running", n)
+	}
+}
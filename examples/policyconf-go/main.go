@@ -0,0 +1,71 @@
+// Policy configuration + Blindfold: Pick protection rules by name at runtime.
+//
+// Loads named policies from policies.yaml — entity types, locales, an
+// action per entity type, and custom regex patterns for internal
+// identifiers — and applies the one selected with -policy. Changing what
+// gets protected is a config edit, not a code change.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+const sample = "Agent EMP-204518 here. Jane Doe (jane.doe@example.com, +1 212-555-0187) " +
+	"asked about order ORD-0048213 charged to 4111 1111 1111 1111."
+
+func protect(ctx context.Context, pol *policyconf.Policy, text string) error {
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+
+	tokenized, err := bf.Tokenize(ctx, text)
+	if err != nil {
+		return fmt.Errorf("tokenize: %w", err)
+	}
+	fmt.Printf("Tokenized: %s\n", tokenized.Text)
+	for _, e := range tokenized.DetectedEntities {
+		fmt.Printf("  %-20s configured action: %s\n", e.Type, pol.Action(e.Type))
+	}
+	return nil
+}
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply (default: the file's default)")
+	all := flag.Bool("all", false, "apply every policy in the file to the sample")
+	flag.Parse()
+
+	cfg, err := policyconf.Load(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	names := []string{*name}
+	if *all {
+		names = cfg.Names()
+	}
+
+	fmt.Printf("Input: %s\n", sample)
+	for _, n := range names {
+		pol, err := cfg.Policy(n)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("\n== %s %s\n", pol.Name, strings.Repeat("=", 60-len(pol.Name)))
+		if err := protect(context.Background(), pol, sample); err != nil {
+			log.Printf("%s: %v", pol.Name, err)
+		}
+	}
+}
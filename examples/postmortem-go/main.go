@@ -0,0 +1,129 @@
+// Incident postmortems + Blindfold: Let the LLM draft the postmortem
+// without learning who was on call, which customer was hit, or where.
+//
+// The incident timeline comes from PagerDuty (or a saved log_entries
+// export) and is combined with the ops log for the same window. Engineer
+// names are taken from the timeline itself and added to the policy's
+// denylist, so they are tokenized even in local mode; hostnames, customer
+// IDs, emails and IP addresses are matched by the policy. The LLM drafts the
+// postmortem from tokens only. The internal copy is detokenized; the shared
+// copy keeps neutral labels like "Engineer 1" and "Hostname 2".
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+)
+
+const systemPrompt = "You are an SRE writing a blameless incident postmortem in Markdown. " +
+	"Sections: Summary, Impact, Timeline (UTC), Root cause, Resolution, Action items. " +
+	"Base it only on the timeline and logs given. " +
+	"Keep placeholders like <Engineer_1> and <Hostname_1> exactly as they are."
+
+// label turns a token into a neutral label for the shared copy:
+// <Hostname_2> becomes "Hostname 2".
+func label(token string) string {
+	typ, n, ok := mapping.ParseToken(token)
+	if !ok {
+		return token
+	}
+	return fmt.Sprintf("%s %d", typ, n)
+}
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply (default: the file's default)")
+	incident := flag.String("incident", "", "PagerDuty incident ID (needs PAGERDUTY_API_TOKEN); default: read -timeline")
+	timelineFile := flag.String("timeline", "incident/log_entries.json", "saved PagerDuty log_entries response")
+	logFile := flag.String("logs", "incident/ops.log", "ops log for the incident window")
+	outDir := flag.String("out", "postmortem", "directory for the internal and shared copies")
+	dryRun := flag.Bool("dry-run", false, "print the tokenized input; skip OpenAI calls")
+	flag.Parse()
+	ctx := context.Background()
+
+	var entries []LogEntry
+	var err error
+	if *incident != "" {
+		entries, err = fetchLogEntries(ctx, os.Getenv("PAGERDUTY_API_TOKEN"), *incident)
+	} else {
+		entries, err = loadLogEntries(*timelineFile)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	opsLog, err := os.ReadFile(*logFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// The responders are known from the timeline, so no detector has to
+	// guess at them
+	if engineers := responders(entries); len(engineers) > 0 {
+		if pol.Deny == nil {
+			pol.Deny = make(map[string][]string)
+		}
+		pol.Deny["Engineer"] = append(pol.Deny["Engineer"], engineers...)
+		if err := pol.Compile(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	p := protect.New(bfclient.FromEnv(pol.ClientOptions()...))
+	p.Policy = pol
+	oa := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
+
+	call := protect.Chat(ctx, oa, openai.GPT4oMini, systemPrompt)
+	if *dryRun {
+		call = func(string) (string, error) { return "", nil }
+	}
+	input := "PagerDuty timeline:\n" + timeline(entries) + "\nOps log:\n" + string(opsLog)
+	res, err := p.Run(ctx, input, call)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *dryRun {
+		fmt.Println(strings.TrimSpace(res.Safe))
+		return
+	}
+	draft := res.Output
+
+	id := *incident
+	if id == "" {
+		id = strings.TrimSuffix(filepath.Base(*timelineFile), filepath.Ext(*timelineFile))
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	internal := filepath.Join(*outDir, id+".internal.md")
+	shared := filepath.Join(*outDir, id+".shared.md")
+	// Only the internal copy gets real names, hosts and customers back
+	if err := os.WriteFile(internal, []byte(res.Text), 0o600); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(shared, []byte(mapping.ReplaceTokens(draft, label)), 0o644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s\n\nWrote %s (internal) and %s (shared)\n", mapping.ReplaceTokens(draft, label), internal, shared)
+}
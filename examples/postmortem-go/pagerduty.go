@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pagerDutyAPI is the PagerDuty REST API base URL.
+const pagerDutyAPI = "https://api.pagerduty.com"
+
+// LogEntry is one entry of a PagerDuty incident log, as returned by
+// GET /incidents/{id}/log_entries.
+type LogEntry struct {
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Summary   string    `json:"summary"`
+	Agent     struct {
+		Type    string `json:"type"`
+		Summary string `json:"summary"`
+	} `json:"agent"`
+}
+
+type logEntries struct {
+	LogEntries []LogEntry `json:"log_entries"`
+	More       bool       `json:"more"`
+}
+
+// fetchLogEntries reads the full log of a PagerDuty incident, oldest
+// first.
+func fetchLogEntries(ctx context.Context, token, incidentID string) ([]LogEntry, error) {
+	var out []LogEntry
+	for offset := 0; ; {
+		url := fmt.Sprintf("%s/incidents/%s/log_entries?is_overview=false&limit=100&offset=%d", pagerDutyAPI, incidentID, offset)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Token token="+token)
+		req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("pagerduty: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("pagerduty: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("pagerduty: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+		}
+		var page logEntries
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("pagerduty: %w", err)
+		}
+		out = append(out, page.LogEntries...)
+		if !page.More || len(page.LogEntries) == 0 {
+			break
+		}
+		offset += len(page.LogEntries)
+	}
+	sortEntries(out)
+	return out, nil
+}
+
+// loadLogEntries reads a saved log_entries response.
+func loadLogEntries(path string) ([]LogEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var page logEntries
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	sortEntries(page.LogEntries)
+	return page.LogEntries, nil
+}
+
+func sortEntries(entries []LogEntry) {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].CreatedAt.Before(entries[j].CreatedAt) })
+}
+
+// responders returns the people named in the incident log — those who
+// acted on it, and those notified or escalated to — with their first
+// names, for the Engineer denylist.
+func responders(entries []LogEntry) []string {
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name = strings.TrimSpace(name); name == "" {
+			return
+		}
+		seen[name] = true
+		if first, _, ok := strings.Cut(name, " "); ok {
+			seen[first] = true
+		}
+	}
+	for _, e := range entries {
+		if e.Agent.Type == "user_reference" {
+			add(e.Agent.Summary)
+		}
+		switch e.Type {
+		case "notify_log_entry":
+			if rest, ok := strings.CutPrefix(e.Summary, "Notified "); ok {
+				name, _, _ := strings.Cut(rest, " by ")
+				add(name)
+			}
+		case "escalate_log_entry":
+			if rest, ok := strings.CutPrefix(e.Summary, "Escalated to "); ok {
+				name, _, _ := strings.Cut(rest, " (")
+				add(name)
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for n := range seen {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// timeline renders entries one per line, as the LLM will read them.
+func timeline(entries []LogEntry) string {
+	var b strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&b, "%s %s\n", e.CreatedAt.UTC().Format("15:04:05"), e.Summary)
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Chunk is one paragraph of a knowledge-base document.
+type Chunk struct {
+	Doc, Title string
+	N          int // paragraph number within the document, from 1
+	Text       string
+	score      float64
+}
+
+// ID names the chunk in reports: "refunds.md#2".
+func (c Chunk) ID() string { return fmt.Sprintf("%s#%d", c.Doc, c.N) }
+
+// loadChunks splits every Markdown file in dir into paragraphs. The
+// heading is kept as the title, not as a chunk.
+func loadChunks(dir string) ([]Chunk, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	var out []Chunk
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		title, n := "", 0
+		for _, para := range strings.Split(string(data), "\n\n") {
+			para = strings.TrimSpace(para)
+			if para == "" {
+				continue
+			}
+			if strings.HasPrefix(para, "# ") {
+				title = strings.TrimPrefix(para, "# ")
+				continue
+			}
+			n++
+			out = append(out, Chunk{Doc: filepath.Base(p), Title: title, N: n, Text: para})
+		}
+	}
+	return out, nil
+}
+
+var stopwords = map[string]bool{"the": true, "and": true, "for": true, "who": true, "how": true, "what": true, "does": true, "can": true, "get": true, "with": true, "that": true, "this": true, "are": true, "you": true}
+
+func terms(s string) []string {
+	var out []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if len(w) >= 3 && !stopwords[w] {
+			out = append(out, strings.TrimSuffix(w, "s"))
+		}
+	}
+	return out
+}
+
+// retrieve returns the k chunks sharing the most terms with query, scaled
+// down for long chunks. It stands in for a vector search: what matters
+// here is that a poisoned chunk on the right topic gets retrieved.
+func retrieve(chunks []Chunk, query string, k int) []Chunk {
+	q := make(map[string]bool)
+	for _, t := range terms(query) {
+		q[t] = true
+	}
+	scored := make([]Chunk, 0, len(chunks))
+	for _, c := range chunks {
+		words := terms(c.Title + " " + c.Text)
+		hits := 0
+		for _, w := range words {
+			if q[w] {
+				hits++
+			}
+		}
+		if hits == 0 {
+			continue
+		}
+		c.score = float64(hits) / math.Sqrt(float64(len(words)))
+		scored = append(scored, c)
+	}
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+	if len(scored) > k {
+		scored = scored[:k]
+	}
+	return scored
+}
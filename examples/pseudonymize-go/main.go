@@ -0,0 +1,201 @@
+// Pseudonymization + Blindfold: Replace the people in a ticket corpus with
+// stable fake names, so LLM output reads naturally.
+//
+// Tokens like <Person_1> keep PII out of the prompt, but a summary full of
+// placeholders is hard to read and models sometimes mangle them. Here each
+// detected person becomes a consistent fake identity instead: "John Smith"
+// is "Marcus Webb" in every ticket, and "Mr. Smith" is "Mr. Webb". Emails
+// and other entities stay tokenized. The corpus mapping is written to a
+// file for authorized detokenization with -restore.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+const systemPrompt = "You are a support lead. Summarize this customer's ticket history for the account manager: " +
+	"who is involved, what happened in order, and what is still open. " +
+	"Keep placeholders like <Email Address_1> exactly as they are."
+
+// loadRoster reads one name per line, skipping blank lines and # comments.
+func loadRoster(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var names []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	}
+	return names, sc.Err()
+}
+
+// pseudonymize pseudonymizes every ticket in files and merges their
+// mappings, so tokens stay unique across the corpus.
+func pseudonymize(ctx context.Context, ps *Pseudonymizer, files []string) ([]string, map[string]string, error) {
+	texts := make([]string, len(files))
+	mappings := make([]map[string]string, len(files))
+	for i, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		res, err := ps.Tokenize(ctx, string(data))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: tokenize: %w", file, err)
+		}
+		texts[i], mappings[i] = res.Text, res.Mapping
+	}
+	merged := mapping.Merge(mappings...)
+	return merged.Apply(texts), merged.Mapping, nil
+}
+
+func summarize(ctx context.Context, oa *openai.Client, corpus string) (string, error) {
+	completion, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: openai.GPT4oMini,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: corpus},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("openai: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", errors.New("openai: empty response")
+	}
+	return completion.Choices[0].Message.Content, nil
+}
+
+// restoreFile prints the original of pseudonymized text read from path
+// ("-" for stdin), using the mapping at mappingPath.
+func restoreFile(path, mappingPath string) error {
+	var text []byte
+	var err error
+	if path == "-" {
+		text, err = io.ReadAll(os.Stdin)
+	} else {
+		text, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(mappingPath)
+	if err != nil {
+		return err
+	}
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("%s: %w", mappingPath, err)
+	}
+	restored := restore(string(text), m).Text
+	if left := mapping.Unresolved(restored, m); len(left) > 0 {
+		return fmt.Errorf("%d token(s) not in %s: %s", len(left), mappingPath, strings.Join(left, ", "))
+	}
+	_, err = fmt.Print(restored)
+	return err
+}
+
+func main() {
+	_ = godotenv.Load()
+	dir := flag.String("dir", "corpus", "directory of .txt tickets")
+	rosterPath := flag.String("roster", "roster.txt", "known names, tokenized as Person in local mode too")
+	out := flag.String("mapping", "pseudonyms.json", "where to write the corpus mapping")
+	dryRun := flag.Bool("dry-run", false, "pseudonymize only; skip OpenAI calls")
+	restorePath := flag.String("restore", "", `restore pseudonymized text from this file ("-" for stdin) and print it`)
+	flag.Parse()
+	ctx := context.Background()
+
+	if *restorePath != "" {
+		if err := restoreFile(*restorePath, *out); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// With the same key, the same person gets the same fake name in every run
+	key := []byte(os.Getenv("PSEUDONYM_KEY"))
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Local mode has no name detection, so known customers come from a
+	// deny list; cloud mode finds the rest
+	roster, err := loadRoster(*rosterPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pol := &policyconf.Policy{Base: "basic", Deny: map[string][]string{"Person": roster}}
+	if err := pol.Compile(); err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	ps := Wrap(pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...)), key)
+	oa := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
+
+	files, err := filepath.Glob(filepath.Join(*dir, "*.txt"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(files) == 0 {
+		log.Fatalf("no tickets in %s", *dir)
+	}
+	texts, m, err := pseudonymize(ctx, ps, files)
+	if err != nil {
+		log.Fatal(err)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// The mapping restores every person and token: authorized readers only
+	if err := os.WriteFile(*out, append(data, '\n'), 0o600); err != nil {
+		log.Fatal(err)
+	}
+
+	var corpus strings.Builder
+	for i, file := range files {
+		fmt.Printf("== %s %s\n%s\n\n", filepath.Base(file), strings.Repeat("=", 50-len(filepath.Base(file))), texts[i])
+		fmt.Fprintf(&corpus, "--- %s\n%s\n\n", filepath.Base(file), texts[i])
+	}
+	fmt.Printf("Wrote %s (%d entries)\n", *out, len(m))
+	if *dryRun {
+		return
+	}
+
+	summary, err := summarize(ctx, oa, corpus.String())
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("\n== Summary (as the LLM wrote it) ==\n%s\n", summary)
+	fmt.Printf("\n== Summary (restored) ==\n%s\n", ps.Detokenize(summary, m).Text)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+var firstNames = []string{
+	"Marcus", "Helen", "Tobias", "Naomi", "Felix", "Ingrid", "Rafael", "Clara",
+	"Owen", "Leila", "Hugo", "Maren", "Silas", "Daria", "Jonah", "Vera",
+	"Emil", "Nadia", "Caleb", "Iris", "Dorian", "Lena", "Anton", "Mira",
+	"Gideon", "Talia", "Ruben", "Esme", "Victor", "Ada", "Milo", "Sabine",
+}
+
+var lastNames = []string{
+	"Webb", "Lindgren", "Okoro", "Castell", "Hartley", "Moreau", "Vance", "Kessler",
+	"Rowan", "Albright", "Sato", "Delacroix", "Brennan", "Novak", "Faulkner", "Ibarra",
+	"Quinlan", "Mercer", "Whitlock", "Haddad", "Ashby", "Takeda", "Osei", "Lund",
+	"Carrow", "Ferrante", "Holm", "Pryce", "Varga", "Ellery", "Strand", "Marsh",
+}
+
+// titles are kept as they are: "Mr. Smith" becomes "Mr. Webb".
+var titles = map[string]bool{"mr": true, "mrs": true, "ms": true, "mx": true, "dr": true, "prof": true}
+
+// Pseudonymizer replaces people with stable fake names: every part of a
+// real name gets one fake part for the whole corpus, so "John Smith",
+// "John" and "Mr. Smith" become "Marcus Webb", "Marcus" and "Mr. Webb"
+// wherever they appear. Other entities stay tokenized. The mapping it
+// returns restores both.
+//
+// Fake parts are picked with an HMAC of the real part under the key, so a
+// corpus processed in another order, or by another process with the same
+// key, gets the same names unless two parts compete for one.
+type Pseudonymizer struct {
+	next bfclient.Client
+	key  []byte
+
+	mu    sync.Mutex
+	parts map[string]string // real name part → fake part
+	taken map[string]bool   // fake parts in use
+}
+
+var _ bfclient.Client = (*Pseudonymizer)(nil)
+
+// Wrap returns next with people pseudonymized under key.
+func Wrap(next bfclient.Client, key []byte) *Pseudonymizer {
+	return &Pseudonymizer{next: next, key: key, parts: make(map[string]string), taken: make(map[string]bool)}
+}
+
+// Detect passes through to the wrapped client.
+func (p *Pseudonymizer) Detect(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.DetectResponse, error) {
+	return p.next.Detect(ctx, text, opts...)
+}
+
+// Tokenize tokenizes with the wrapped client, replaces Person tokens with
+// pseudonyms, and then replaces name parts seen anywhere in the corpus so
+// far ("Mr. Smith" after "John Smith") that the detector didn't report
+// on their own.
+func (p *Pseudonymizer) Tokenize(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	res, err := p.next.Tokenize(ctx, text, opts...)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	out := &blindfold.TokenizeResponse{
+		Mapping:          make(map[string]string, len(res.Mapping)),
+		DetectedEntities: res.DetectedEntities,
+		EntitiesCount:    res.EntitiesCount,
+	}
+	toFake := make(map[string]string)
+	for token, value := range res.Mapping {
+		if typ, _, _ := mapping.ParseToken(token); typ != "Person" {
+			out.Mapping[token] = value
+			continue
+		}
+		fake := p.pseudonym(value)
+		toFake[token] = fake
+		out.Mapping[fake] = value
+	}
+	masked := mapping.Detokenize(res.Text, toFake)
+
+	// Standalone parts of known names
+	if re := p.partsRegexp(); re != nil {
+		masked = re.ReplaceAllStringFunc(masked, func(part string) string {
+			fake := p.parts[part]
+			out.Mapping[fake] = part
+			return fake
+		})
+	}
+	out.Text = masked
+	return out, nil
+}
+
+// Detokenize restores tokens and pseudonyms in text with restore.
+func (p *Pseudonymizer) Detokenize(text string, m map[string]string) *blindfold.DetokenizeResponse {
+	return restore(text, m)
+}
+
+// restore puts the originals of m's keys back in text. Pseudonyms are
+// restored as whole words only, so "Webb" is restored but "Webber" is not.
+// It needs no key: the mapping alone restores the text.
+func restore(text string, m map[string]string) *blindfold.DetokenizeResponse {
+	re := keysRegexp(m)
+	if re == nil {
+		return &blindfold.DetokenizeResponse{Text: text}
+	}
+	n := 0
+	restored := re.ReplaceAllStringFunc(text, func(k string) string {
+		n++
+		return m[k]
+	})
+	return &blindfold.DetokenizeResponse{Text: restored, ReplacementsMade: n}
+}
+
+// pseudonym returns the fake for a full name, part by part. p.mu must be
+// held.
+func (p *Pseudonymizer) pseudonym(name string) string {
+	words := strings.Fields(name)
+	var named []int // indexes of words that are name parts
+	for i, w := range words {
+		if !titles[strings.ToLower(strings.TrimSuffix(w, "."))] {
+			named = append(named, i)
+		}
+	}
+	for k, i := range named {
+		pool := firstNames
+		if len(named) > 1 && k == len(named)-1 {
+			pool = lastNames
+		}
+		words[i] = p.part(words[i], pool)
+	}
+	return strings.Join(words, " ")
+}
+
+// part returns the fake for one name part, picking it from pool if the
+// part is new. p.mu must be held.
+func (p *Pseudonymizer) part(real string, pool []string) string {
+	if fake, ok := p.parts[real]; ok {
+		return fake
+	}
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(real))
+	start := int(binary.BigEndian.Uint64(mac.Sum(nil)) % uint64(len(pool)))
+	var fake string
+	for round := 0; fake == ""; round++ {
+		for i := 0; i < len(pool); i++ {
+			candidate := pool[(start+i)%len(pool)]
+			if round > 0 {
+				candidate = fmt.Sprintf("%s%d", candidate, round+1)
+			}
+			if _, isReal := p.parts[candidate]; !p.taken[candidate] && !isReal && candidate != real {
+				fake = candidate
+				break
+			}
+		}
+	}
+	p.parts[real] = fake
+	p.taken[fake] = true
+	return fake
+}
+
+// partsRegexp matches any known real name part as a whole word. p.mu must
+// be held.
+func (p *Pseudonymizer) partsRegexp() *regexp.Regexp {
+	parts := make(map[string]string, len(p.parts))
+	for real := range p.parts {
+		parts[real] = real
+	}
+	return keysRegexp(parts)
+}
+
+// keysRegexp matches the keys of m, longest first, as whole words where
+// they start or end with a word character.
+func keysRegexp(m map[string]string) *regexp.Regexp {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	alts := make([]string, len(keys))
+	for i, k := range keys {
+		alt := regexp.QuoteMeta(k)
+		if isWordByte(k[0]) {
+			alt = `\b` + alt
+		}
+		if isWordByte(k[len(k)-1]) {
+			alt += `\b`
+		}
+		alts[i] = alt
+	}
+	return regexp.MustCompile(strings.Join(alts, "|"))
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// audience is the aud claim this service accepts.
+const audience = "detokenize-service"
+
+// Role is the role claim in a caller's JWT.
+type Role string
+
+// Roles the service knows. Any other role can tokenize and read tokens but
+// not resolve them.
+const (
+	Agent      Role = "agent"      // sees masked values
+	Supervisor Role = "supervisor" // sees original values
+)
+
+// Claims are the JWT claims the service reads. Tenant scopes sessions: a
+// caller can only resolve sessions created under their own tenant.
+type Claims struct {
+	Role   Role   `json:"role"`
+	Tenant string `json:"tenant"`
+	jwt.RegisteredClaims
+}
+
+// authenticate verifies the bearer token in an Authorization header. Only
+// HS256 is accepted, and the token must carry an expiry and this
+// service's audience.
+func authenticate(secret []byte, header string) (*Claims, error) {
+	raw, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || raw == "" {
+		return nil, errors.New("missing bearer token")
+	}
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(*jwt.Token) (any, error) { return secret, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	if claims.Tenant == "" {
+		return nil, errors.New("token has no tenant")
+	}
+	return claims, nil
+}
+
+// issue signs a token for subject with role in tenant, valid for ttl. In production
+// tokens come from your identity provider; this is for the demo.
+func issue(secret []byte, subject, tenant string, role Role, ttl time.Duration) (string, error) {
+	now := time.Now()
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		Role:   role,
+		Tenant: tenant,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}).SignedString(secret)
+}
@@ -0,0 +1,176 @@
+// RBAC-gated detokenization + Blindfold: Separate who can see tokens from
+// who can resolve them.
+//
+// A small HTTP service tokenizes text for any caller with a valid JWT and
+// keeps the mapping server-side. Resolving tokens is a separate call gated
+// by the role claim: support agents get masked values (***-**-6789),
+// supervisors the originals, and every other role is refused. Sessions
+// expire and belong to the tenant that created them. Every
+// detokenization is logged with who asked, never with values.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/config"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/health"
+)
+
+const ticket = "Customer jane.doe@example.com (SSN 123-45-6789) asks why card 4111-1111-1111-1111 was declined. Call back on 415-555-0134."
+
+// call posts body to the service as the holder of token and returns the
+// status and response body.
+func call(url, token string, body any) (int, string, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return 0, "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	out, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(bytes.TrimSpace(out)), err
+}
+
+// demo runs the service in-process and shows what each role gets back.
+func demo(handler http.Handler, secret []byte) error {
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	tokens := make(map[Role]string)
+	for _, role := range []Role{Agent, Supervisor, "analyst"} {
+		t, err := issue(secret, string(role)+"@example.com", "acme", role, 5*time.Minute)
+		if err != nil {
+			return err
+		}
+		tokens[role] = t
+	}
+	// A supervisor of another tenant can't see acme's sessions
+	other, err := issue(secret, "supervisor@globex.example", "globex", Supervisor, 5*time.Minute)
+	if err != nil {
+		return err
+	}
+
+	status, body, err := call(srv.URL+"/v1/tokenize", tokens[Agent], tokenizeRequest{Text: ticket})
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("tokenize: %d %s", status, body)
+	}
+	var tok tokenizeResponse
+	if err := json.Unmarshal([]byte(body), &tok); err != nil {
+		return err
+	}
+	fmt.Printf("Tokenized (any role, and the LLM, sees this):\n  %s\n\n", tok.Text)
+
+	// The tokenized text stands in for an LLM reply that kept the tokens
+	req := detokenizeRequest{Session: tok.Session, Text: tok.Text}
+	for _, who := range []struct {
+		label, token string
+	}{
+		{"agent", tokens[Agent]},
+		{"supervisor", tokens[Supervisor]},
+		{"supervisor of another tenant", other},
+		{"analyst", tokens["analyst"]},
+		{"no token", ""},
+	} {
+		status, body, err := call(srv.URL+"/v1/detokenize", who.token, req)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Detokenize as %s → %d\n  %s\n\n", who.label, status, body)
+	}
+	return nil
+}
+
+func main() {
+	// Every flag can also be set as RBAC_<FLAG> or in the -config file
+	cfg := config.New(flag.CommandLine, "RBAC")
+	addr := flag.String("addr", "127.0.0.1:8080", "listen address")
+	runDemo := flag.Bool("demo", false, "run the service in-process and call it as each role")
+	issueRole := flag.String("issue", "", "print a demo JWT for this role and exit")
+	subject := flag.String("sub", "demo@example.com", "subject for -issue")
+	tenant := flag.String("tenant", "demo", "tenant for -issue")
+	sessionTTL := flag.Duration("session-ttl", time.Hour, "how long a session's mapping can be resolved")
+	maxSessions := flag.Int("max-sessions", 10000, "most sessions held at once; tokenize answers 503 beyond it")
+	cfg.File("config")
+	cfg.Check("session-ttl", func() error {
+		if *sessionTTL <= 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	})
+	cfg.Check("max-sessions", func() error {
+		if *maxSessions <= 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	})
+	if err := cfg.Parse(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	secret := []byte(os.Getenv("JWT_SECRET"))
+	if len(secret) == 0 {
+		if !*runDemo {
+			log.Fatal("JWT_SECRET is required (see .env.example)")
+		}
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *issueRole != "" {
+		t, err := issue(secret, *subject, *tenant, Role(*issueRole), time.Hour)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(t)
+		return
+	}
+
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	s := newServer(bfclient.FromEnv(), secret, *sessionTTL, *maxSessions)
+
+	if *runDemo {
+		if err := demo(s.routes(), secret); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", s.routes())
+	health.NewService(flag.CommandLine, nil, s.bf).Register(mux)
+	log.Printf("listening on http://%s (POST /v1/tokenize, /v1/detokenize)", *addr)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/masking"
+)
+
+// server tokenizes text for any authenticated caller and keeps the
+// mapping to itself. Resolving tokens is a separate call, gated by role
+// and by the tenant the session was created under.
+type server struct {
+	bf     bfclient.Client
+	secret []byte
+	masks  masking.Rules
+	ttl    time.Duration // how long a session's mapping is kept
+	max    int           // most sessions held at once
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// session is one tokenization's mapping and who may resolve it.
+type session struct {
+	tenant  string
+	subject string // who created it, for the audit log
+	mapping map[string]string
+	expires time.Time
+}
+
+func newServer(bf bfclient.Client, secret []byte, ttl time.Duration, max int) *server {
+	return &server{bf: bf, secret: secret, masks: masking.Defaults(), ttl: ttl, max: max, sessions: make(map[string]*session)}
+}
+
+// store keeps m for the caller's tenant and returns its session ID, or false if the
+// service already holds max live sessions.
+func (s *server) store(c *Claims, m map[string]string) (string, bool) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.sessions) >= s.max {
+		for id, sess := range s.sessions {
+			if !now.Before(sess.expires) {
+				delete(s.sessions, id)
+			}
+		}
+		if len(s.sessions) >= s.max {
+			return "", false
+		}
+	}
+	id := newSessionID()
+	s.sessions[id] = &session{tenant: c.Tenant, subject: c.Subject, mapping: m, expires: now.Add(s.ttl)}
+	return id, true
+}
+
+// lookup returns the session id if it is live and belongs to the caller's
+// tenant. Expired sessions are dropped on the way.
+func (s *server) lookup(c *Claims, id string) (*session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if ok && !time.Now().Before(sess.expires) {
+		delete(s.sessions, id)
+		return nil, false
+	}
+	if !ok || sess.tenant != c.Tenant {
+		return nil, false
+	}
+	return sess, true
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/tokenize", s.authed(s.tokenize))
+	mux.HandleFunc("/v1/detokenize", s.authed(s.detokenize))
+	return mux
+}
+
+// authed rejects requests that aren't POSTs with a valid JWT.
+func (s *server) authed(next func(http.ResponseWriter, *http.Request, *Claims)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+			return
+		}
+		claims, err := authenticate(s.secret, r.Header.Get("Authorization"))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="detokenize-service"`)
+			writeError(w, http.StatusUnauthorized, "unauthorized", err.Error())
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+		next(w, r, claims)
+	}
+}
+
+type tokenizeRequest struct {
+	Text string `json:"text"`
+}
+
+type tokenizeResponse struct {
+	Session string `json:"session"`
+	Text    string `json:"text"`
+}
+
+// tokenize is open to every role: tokens reveal nothing, so anyone may see
+// them, and so may the LLM.
+func (s *server) tokenize(w http.ResponseWriter, r *http.Request, c *Claims) {
+	var req tokenizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	res, err := s.bf.Tokenize(r.Context(), req.Text)
+	if err != nil {
+		// Fail closed: nothing is returned unprotected
+		writeError(w, http.StatusBadGateway, "blindfold_error", "tokenization failed")
+		return
+	}
+	id, ok := s.store(c, res.Mapping)
+	if !ok {
+		w.Header().Set("Retry-After", "60")
+		writeError(w, http.StatusServiceUnavailable, "too_many_sessions", "session limit reached, try again later")
+		return
+	}
+	log.Printf("tokenize session=%s tenant=%s sub=%s role=%s tokens=%d", id, c.Tenant, c.Subject, c.Role, len(res.Mapping))
+	writeJSON(w, tokenizeResponse{Session: id, Text: res.Text})
+}
+
+type detokenizeRequest struct {
+	Session string `json:"session"`
+	Text    string `json:"text"`
+}
+
+type detokenizeResponse struct {
+	Text string `json:"text"`
+	View string `json:"view"` // "full" or "masked"
+}
+
+// detokenize resolves the session's tokens in text by role: supervisors
+// get the original values, agents a masked form, and every other role is
+// refused. A session from another tenant, or one past its TTL, is
+// reported as unknown so its existence isn't revealed.
+func (s *server) detokenize(w http.ResponseWriter, r *http.Request, c *Claims) {
+	var req detokenizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	sess, ok := s.lookup(c, req.Session)
+	if !ok {
+		log.Printf("detokenize NOT FOUND session=%s tenant=%s sub=%s role=%s", req.Session, c.Tenant, c.Subject, c.Role)
+		writeError(w, http.StatusNotFound, "not_found", "unknown session")
+		return
+	}
+	m := sess.mapping
+
+	var resp detokenizeResponse
+	switch c.Role {
+	case Supervisor:
+		resp = detokenizeResponse{Text: mapping.Detokenize(req.Text, m), View: "full"}
+	case Agent:
+		masked := make(map[string]string, len(m))
+		for token, value := range m {
+			typ, _, _ := mapping.ParseToken(token)
+			masked[token] = s.masks.Mask(typ, value)
+		}
+		resp = detokenizeResponse{Text: mapping.Detokenize(req.Text, masked), View: "masked"}
+	default:
+		log.Printf("detokenize DENIED session=%s tenant=%s sub=%s role=%q", req.Session, c.Tenant, c.Subject, c.Role)
+		writeError(w, http.StatusForbidden, "forbidden", "role may not resolve tokens")
+		return
+	}
+	// Who resolved what: token counts, never values
+	log.Printf("detokenize session=%s tenant=%s sub=%s role=%s view=%s tokens=%d owner=%s", req.Session, c.Tenant, c.Subject, c.Role, resp.View, len(m), sess.subject)
+	writeJSON(w, resp)
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, typ, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"message": message, "type": typ},
+	})
+}
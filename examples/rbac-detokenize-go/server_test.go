@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+var secret = []byte("test-secret-test-secret-test-sec")
+
+func newTestServer(t *testing.T, ttl time.Duration, max int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(newServer(blindfold.New(blindfold.WithMode("local")), secret, ttl, max).routes())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func token(t *testing.T, sub, tenant string, role Role) string {
+	t.Helper()
+	tok, err := issue(secret, sub, tenant, role, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+// tokenize stores a mapping as an acme agent and returns the session.
+func tokenize(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	status, out, _ := call(srv.URL+"/v1/tokenize", token(t, "alice", "acme", Agent), tokenizeRequest{Text: "SSN 123-45-6789"})
+	var tok tokenizeResponse
+	if status != http.StatusOK || json.Unmarshal([]byte(out), &tok) != nil {
+		t.Fatalf("tokenize: %d %s", status, out)
+	}
+	return tok.Session
+}
+
+func TestDetokenizeByRoleAndTenant(t *testing.T) {
+	srv := newTestServer(t, time.Minute, 10)
+	req := detokenizeRequest{Session: tokenize(t, srv), Text: "SSN <Social Security Number_1>"}
+
+	for _, c := range []struct {
+		name   string
+		token  string
+		status int
+		want   string
+	}{
+		{"supervisor", token(t, "bob", "acme", Supervisor), http.StatusOK, "SSN 123-45-6789"},
+		{"agent", token(t, "alice", "acme", Agent), http.StatusOK, "SSN ***-**-6789"},
+		{"supervisor of another tenant", token(t, "eve", "globex", Supervisor), http.StatusNotFound, "unknown session"},
+		{"other role", token(t, "carol", "acme", "analyst"), http.StatusForbidden, "forbidden"},
+		{"no token", "", http.StatusUnauthorized, "missing bearer token"},
+		{"no tenant", token(t, "bob", "", Supervisor), http.StatusUnauthorized, "no tenant"},
+	} {
+		status, out, _ := call(srv.URL+"/v1/detokenize", c.token, req)
+		if status != c.status || !strings.Contains(out, c.want) {
+			t.Errorf("%s: %d %s, want %d with %q", c.name, status, out, c.status, c.want)
+		}
+		if c.status != http.StatusOK && strings.Contains(out, "6789") {
+			t.Errorf("%s: refused but got a value: %s", c.name, out)
+		}
+	}
+}
+
+func TestSessionExpires(t *testing.T) {
+	srv := newTestServer(t, 50*time.Millisecond, 10)
+	req := detokenizeRequest{Session: tokenize(t, srv), Text: "SSN <Social Security Number_1>"}
+	time.Sleep(100 * time.Millisecond)
+	if status, out, _ := call(srv.URL+"/v1/detokenize", token(t, "bob", "acme", Supervisor), req); status != http.StatusNotFound {
+		t.Errorf("after ttl: %d %s", status, out)
+	}
+}
+
+func TestSessionCap(t *testing.T) {
+	srv := newTestServer(t, 50*time.Millisecond, 2)
+	tokenize(t, srv)
+	tokenize(t, srv)
+	status, out, _ := call(srv.URL+"/v1/tokenize", token(t, "alice", "acme", Agent), tokenizeRequest{Text: "SSN 123-45-6789"})
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("over the cap: %d %s", status, out)
+	}
+	// Expired sessions make room again
+	time.Sleep(100 * time.Millisecond)
+	tokenize(t, srv)
+}
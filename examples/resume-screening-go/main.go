@@ -0,0 +1,151 @@
+// HR resume screening + Blindfold: Score resumes on skills alone.
+//
+// Each resume in resumes/ is tokenized before the LLM scores it against
+// job.txt: names and contact details for privacy, and the details a
+// screener shouldn't weigh — age and graduation year, pronouns and gender,
+// family status, nationality, schools and affiliations — to keep them out
+// of the score. The LLM ranks <Person_1>, not Daniel or Amara; names are
+// restored only in the final shortlist.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+const systemPrompt = "You screen resumes against a job description. Judge only skills, " +
+	"experience and accomplishments relevant to the role. Reply with a JSON object: " +
+	`{"score": 1-10, "strengths": ["..."], "gaps": ["..."]}, at most three items each. ` +
+	"Keep placeholders like <Person_1> exactly as they are."
+
+// Assessment is the LLM's verdict on one resume.
+type Assessment struct {
+	Score     int      `json:"score"`
+	Strengths []string `json:"strengths"`
+	Gaps      []string `json:"gaps"`
+}
+
+type candidate struct {
+	file       string
+	tokenized  *blindfold.TokenizeResponse
+	assessment Assessment
+}
+
+func score(ctx context.Context, oa *openai.Client, job, resume string) (Assessment, error) {
+	completion, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: openai.GPT4oMini,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: "Job description:\n" + job + "\n\nResume:\n" + resume},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+	})
+	if err != nil {
+		return Assessment{}, fmt.Errorf("openai: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return Assessment{}, errors.New("openai: empty response")
+	}
+	var a Assessment
+	if err := json.Unmarshal([]byte(completion.Choices[0].Message.Content), &a); err != nil {
+		return Assessment{}, fmt.Errorf("parse assessment: %w", err)
+	}
+	return a, nil
+}
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply (default: the file's default)")
+	dir := flag.String("dir", "resumes", "directory of .txt resumes")
+	jobFile := flag.String("job", "job.txt", "job description")
+	dryRun := flag.Bool("dry-run", false, "print tokenized resumes; skip OpenAI calls")
+	flag.Parse()
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+	oa := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
+
+	job, err := os.ReadFile(*jobFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(*dir, "*.txt"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(files) == 0 {
+		log.Fatalf("no resumes in %s", *dir)
+	}
+
+	var shortlist []candidate
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tokenized, err := bf.Tokenize(ctx, string(data))
+		if err != nil {
+			log.Printf("%s: tokenize: %v", file, err)
+			continue
+		}
+		if *dryRun {
+			fmt.Printf("== %s %s\n%s\n", filepath.Base(file), strings.Repeat("=", 50-len(filepath.Base(file))), strings.TrimSpace(tokenized.Text))
+			fmt.Println()
+			continue
+		}
+		a, err := score(ctx, oa, string(job), tokenized.Text)
+		if err != nil {
+			log.Printf("%s: %v", file, err)
+			continue
+		}
+		shortlist = append(shortlist, candidate{file: file, tokenized: tokenized, assessment: a})
+	}
+	if *dryRun {
+		return
+	}
+
+	// Scored blind; names come back only now
+	sort.SliceStable(shortlist, func(i, j int) bool { return shortlist[i].assessment.Score > shortlist[j].assessment.Score })
+	for i, c := range shortlist {
+		restore := func(s string) string { return bf.Detokenize(s, c.tokenized.Mapping).Text }
+		who := filepath.Base(c.file)
+		for token, value := range c.tokenized.Mapping {
+			if strings.HasPrefix(token, "<Person_") {
+				who = value
+				break
+			}
+		}
+		fmt.Printf("%d. %s — %d/10\n", i+1, who, c.assessment.Score)
+		for _, s := range c.assessment.Strengths {
+			fmt.Printf("   + %s\n", restore(s))
+		}
+		for _, g := range c.assessment.Gaps {
+			fmt.Printf("   - %s\n", restore(g))
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/casedraft"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+// fakeOrg stands in for a Salesforce org: the OAuth token endpoint, a
+// Case query answered in two pages, and CaseComment creation. Comments
+// are kept in memory, so a Case drafted during the run shows its draft
+// to the next query.
+type fakeOrg struct {
+	mu       sync.Mutex
+	cases    []map[string]any
+	comments int
+}
+
+const demoToken = "00Ddemo!session"
+
+func newFakeOrg() *httptest.Server {
+	contact := func(name, email, phone string) map[string]any {
+		return map[string]any{"Name": name, "Email": email, "Phone": phone}
+	}
+	org := &fakeOrg{cases: []map[string]any{
+		{"Id": "500Dm00000A1026", "CaseNumber": "00001026", "Subject": "Charged twice for order 5531",
+			"Description": "I was charged twice on card 4111 1111 1111 1111 for order 5531. Please refund one of the charges " +
+				"and confirm to jane.doe@example.com.",
+			"Contact": contact("Jane Doe", "jane.doe@example.com", "+1 415 555 0142")},
+		{"Id": "500Dm00000A1027", "CaseNumber": "00001027", "Subject": "Can't log in after password reset",
+			"Description": "Since resetting my password yesterday the login page says my account is locked. " +
+				"My username is omar.haddad@example.com.",
+			"Contact": contact("Omar Haddad", "omar.haddad@example.com", "(415) 555-0199")},
+		{"Id": "500Dm00000A1028", "CaseNumber": "00001028", "Subject": "Please cancel my subscription",
+			"Description": "Web form: This is Priya Patel. Please cancel my plan at the end of the trial. " +
+				"Call me on 212-555-0111 if you need anything.",
+			"Contact": nil},
+		{"Id": "500Dm00000A1029", "CaseNumber": "00001029", "Subject": "Order 7710 arrived damaged",
+			"Description": "The box for order 7710 was crushed and the kettle inside is dented.",
+			"Contact":     contact("Li Wei", "li.wei@example.com", "617-555-0123"),
+			"comments":    []string{casedraft.Marker + "\n\nHi Li Wei, sorry to hear order 7710 arrived damaged..."}},
+	}}
+	return httptest.NewServer(org)
+}
+
+func (o *fakeOrg) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/services/oauth2/token" {
+		o.json(w, http.StatusOK, map[string]string{"access_token": demoToken, "instance_url": "http://" + r.Host, "token_type": "Bearer"})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+demoToken {
+		o.json(w, http.StatusUnauthorized, []map[string]string{{"errorCode": "INVALID_SESSION_ID", "message": "Session expired or invalid"}})
+		return
+	}
+	base := "/services/data/" + apiVersion
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == base+"/query":
+		if !strings.Contains(r.URL.Query().Get("q"), "FROM Case") {
+			o.json(w, http.StatusBadRequest, []map[string]string{{"errorCode": "MALFORMED_QUERY", "message": "demo org only has Cases"}})
+			return
+		}
+		o.json(w, http.StatusOK, map[string]any{"totalSize": len(o.cases), "done": false,
+			"nextRecordsUrl": base + "/query/01gDemo-2", "records": o.page(0, 2)})
+	case r.Method == http.MethodGet && r.URL.Path == base+"/query/01gDemo-2":
+		o.json(w, http.StatusOK, map[string]any{"totalSize": len(o.cases), "done": true, "records": o.page(2, len(o.cases))})
+	case r.Method == http.MethodPost && r.URL.Path == base+"/sobjects/CaseComment":
+		var cc struct {
+			ParentID    string `json:"ParentId"`
+			CommentBody string `json:"CommentBody"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&cc); err != nil {
+			o.json(w, http.StatusBadRequest, []map[string]string{{"errorCode": "JSON_PARSER_ERROR", "message": err.Error()}})
+			return
+		}
+		for _, c := range o.cases {
+			if c["Id"] == cc.ParentID {
+				comments, _ := c["comments"].([]string)
+				c["comments"] = append(comments, cc.CommentBody)
+				o.comments++
+				o.json(w, http.StatusCreated, map[string]any{"id": fmt.Sprintf("00aDm0000%06d", o.comments), "success": true, "errors": []any{}})
+				return
+			}
+		}
+		o.json(w, http.StatusBadRequest, []map[string]string{{"errorCode": "INVALID_CROSS_REFERENCE_KEY", "message": "invalid ParentId"}})
+	default:
+		o.json(w, http.StatusNotFound, []map[string]string{{"errorCode": "NOT_FOUND", "message": "The requested resource does not exist"}})
+	}
+}
+
+// page renders cases[from:to] as query records, with their comments as
+// the CaseComments relationship.
+func (o *fakeOrg) page(from, to int) []map[string]any {
+	var out []map[string]any
+	for _, c := range o.cases[from:to] {
+		rec := map[string]any{"attributes": map[string]string{"type": "Case"}}
+		for k, v := range c {
+			if k != "comments" {
+				rec[k] = v
+			}
+		}
+		if comments, _ := c["comments"].([]string); len(comments) > 0 {
+			var records []map[string]string
+			for _, body := range comments {
+				records = append(records, map[string]string{"CommentBody": body})
+			}
+			rec["CaseComments"] = map[string]any{"totalSize": len(records), "done": true, "records": records}
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+func (o *fakeOrg) json(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+var demoTokens = map[string]*regexp.Regexp{
+	"person": regexp.MustCompile(`<Person_\d+>`),
+	"email":  regexp.MustCompile(`<Email Address_\d+>`),
+	"phone":  regexp.MustCompile(`<Phone Number_\d+>`),
+	"card":   regexp.MustCompile(`<Credit Card Number_\d+>`),
+}
+
+// fakeModel answers chat completions with replies scripted by the Case
+// subject. Like a real model it only uses tokens from its input.
+func fakeModel(req openai.ChatCompletionRequest) string {
+	in := fakeopenai.Prompt(req)
+	tok := func(kind string) string { return demoTokens[kind].FindString(in) }
+	greeting := "Hi there,"
+	if p := tok("person"); p != "" {
+		greeting = "Hi " + p + ","
+	}
+
+	var out string
+	switch {
+	case strings.Contains(in, "Charged twice"):
+		out = fmt.Sprintf("%s thanks for letting us know, and sorry for the double charge on %s. "+
+			"I've asked our billing team to reverse the duplicate payment for order 5531, "+
+			"and we'll confirm to %s as soon as it's done.", greeting, tok("card"), tok("email"))
+	case strings.Contains(in, "log in"):
+		out = fmt.Sprintf("%s sorry you're locked out. Several failed sign-ins after a reset lock the account for 30 minutes; "+
+			"if it's still locked after that, reply here and I'll unlock %s for you.", greeting, tok("email"))
+	case strings.Contains(in, "cancel"):
+		out = fmt.Sprintf("%s I've noted your request to cancel at the end of your trial, so you won't be charged. "+
+			"If we need anything else we'll call you on %s.", greeting, tok("phone"))
+	default:
+		out = greeting + " thanks for getting in touch; we're looking into your case and will update you shortly."
+	}
+	return out
+}
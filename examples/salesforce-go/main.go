@@ -0,0 +1,150 @@
+// Salesforce + Blindfold: Draft replies to Salesforce Cases without the
+// LLM seeing who the customer is.
+//
+// New Cases are pulled with their Contact through the REST API and
+// handed to pkg/casedraft, which tokenizes each field value (contact
+// name, email, phone, subject, description) into one mapping, asks the
+// LLM for a draft, checks it for tokens the Case never had and restores
+// it. The draft is written back as an internal CaseComment for an agent
+// to review and send. Cases that already have a draft are skipped, so the
+// recipe can run on a schedule.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/casedraft"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+func drafted(c Case) bool {
+	if c.Comments == nil {
+		return false
+	}
+	for _, cc := range c.Comments.Records {
+		if casedraft.IsDraft(cc.CommentBody) {
+			return true
+		}
+	}
+	return false
+}
+
+// soqlQuote quotes a string literal for SOQL.
+func soqlQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func indent(s string) string {
+	lines := strings.Split(s, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = "              " + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply, by name (default: the file's default)")
+	status := flag.String("status", "New", "draft replies for Cases with this status")
+	limit := flag.Int("limit", 20, "at most this many Cases per run")
+	publish := flag.Bool("publish", false, "write drafts as public comments the customer can see (default: internal)")
+	dryRun := flag.Bool("dry-run", false, "print drafts without writing them to Salesforce")
+	model := flag.String("model", openai.GPT4oMini, "OpenAI model")
+	demo := flag.Bool("demo", false, "use an in-process Salesforce org and scripted model (no Salesforce or OpenAI account needed)")
+	flag.Parse()
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+
+	cfg := openai.DefaultConfig(os.Getenv("OPENAI_API_KEY"))
+	var sf *salesforce
+	if *demo {
+		llm, org := fakeopenai.Server(fakeModel), newFakeOrg()
+		defer llm.Close()
+		defer org.Close()
+		cfg = fakeopenai.Config(llm)
+		sf, err = login(ctx, org.URL, "demo", "demo")
+	} else {
+		if os.Getenv("OPENAI_API_KEY") == "" {
+			log.Fatal("OPENAI_API_KEY is required (or run with -demo)")
+		}
+		sf, err = connect(ctx)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	drafter := casedraft.New(bf, openai.NewClientWithConfig(cfg), *model)
+
+	soql := fmt.Sprintf("SELECT Id, CaseNumber, Subject, Description, Contact.Name, Contact.Email, Contact.Phone, "+
+		"(SELECT CommentBody FROM CaseComments) FROM Case WHERE Status = %s ORDER BY CreatedDate LIMIT %d", soqlQuote(*status), *limit)
+	cases, err := sf.cases(ctx, soql)
+	if err != nil {
+		log.Fatal(err)
+	}
+	visibility := "internal"
+	if *publish {
+		visibility = "public"
+	}
+	written, skipped := 0, 0
+	for _, c := range cases {
+		fmt.Printf("── Case %s: %s\n", c.CaseNumber, c.Subject)
+		var contact Contact
+		if c.Contact != nil {
+			contact = *c.Contact
+		}
+		if drafted(c) {
+			fmt.Printf("   skipped:   already has a draft\n\n")
+			skipped++
+			continue
+		}
+		d, err := drafter.Draft(ctx, casedraft.Case{Number: c.CaseNumber, Name: contact.Name, Email: contact.Email,
+			Phone: contact.Phone, Subject: c.Subject, Body: c.Description})
+		if d != nil {
+			fmt.Printf("   tokenized: %s\n", indent(d.Prompt.Text))
+			fmt.Printf("   draft:     %s\n", d.Reply)
+		}
+		if errors.Is(err, casedraft.ErrUnresolved) {
+			fmt.Printf("   skipped:   %v\n\n", err)
+			skipped++
+			continue
+		}
+		if err != nil {
+			log.Fatalf("case %s: %v", c.CaseNumber, err)
+		}
+		fmt.Printf("   → %s\n", d.Text)
+		if *dryRun {
+			fmt.Printf("   not written (-dry-run)\n\n")
+			continue
+		}
+		id, err := sf.comment(ctx, c.ID, d.Comment(), *publish)
+		if err != nil {
+			log.Fatalf("case %s: %v", c.CaseNumber, err)
+		}
+		fmt.Printf("   written:   CaseComment %s (%s)\n\n", id, visibility)
+		written++
+	}
+	fmt.Printf("%d case(s): %d draft(s) written, %d skipped\n", len(cases), written, skipped)
+}
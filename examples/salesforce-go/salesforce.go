@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// apiVersion is the Salesforce REST API version the recipe was written
+// against; any version since v20.0 has the calls it makes.
+const apiVersion = "v61.0"
+
+// Case is a Salesforce Case with the fields the recipe reads, including
+// its contact and the comments already on it.
+type Case struct {
+	ID          string   `json:"Id"`
+	CaseNumber  string   `json:"CaseNumber"`
+	Subject     string   `json:"Subject"`
+	Description string   `json:"Description"`
+	Contact     *Contact `json:"Contact"`
+	Comments    *struct {
+		Records []struct {
+			CommentBody string `json:"CommentBody"`
+		} `json:"records"`
+	} `json:"CaseComments"`
+}
+
+// Contact is the Case's contact. Every field identifies the customer.
+type Contact struct {
+	Name  string `json:"Name"`
+	Email string `json:"Email"`
+	Phone string `json:"Phone"`
+}
+
+// salesforce is a minimal REST API client: SOQL queries and record
+// creation, which is all the recipe needs.
+type salesforce struct {
+	instance string // https://yourorg.my.salesforce.com
+	token    string
+	http     *http.Client
+}
+
+// sfError is an error response from the REST API, which comes as a list
+// of {errorCode, message}.
+type sfError struct {
+	Status int
+	Code   string
+	Msg    string
+}
+
+func (e *sfError) Error() string {
+	return fmt.Sprintf("salesforce: %d %s: %s", e.Status, e.Code, e.Msg)
+}
+
+// connect builds a client from the environment: SF_INSTANCE_URL and
+// SF_ACCESS_TOKEN if you already have a session, or SF_LOGIN_URL,
+// SF_CLIENT_ID and SF_CLIENT_SECRET for a connected app with the client
+// credentials flow enabled.
+func connect(ctx context.Context) (*salesforce, error) {
+	if instance, token := os.Getenv("SF_INSTANCE_URL"), os.Getenv("SF_ACCESS_TOKEN"); instance != "" && token != "" {
+		return &salesforce{instance: strings.TrimSuffix(instance, "/"), token: token, http: &http.Client{Timeout: 30 * time.Second}}, nil
+	}
+	loginURL, id, secret := os.Getenv("SF_LOGIN_URL"), os.Getenv("SF_CLIENT_ID"), os.Getenv("SF_CLIENT_SECRET")
+	if loginURL == "" || id == "" || secret == "" {
+		return nil, fmt.Errorf("set SF_INSTANCE_URL and SF_ACCESS_TOKEN, or SF_LOGIN_URL, SF_CLIENT_ID and SF_CLIENT_SECRET (or run with -demo)")
+	}
+	return login(ctx, loginURL, id, secret)
+}
+
+// login runs the OAuth 2.0 client credentials flow against the org's My
+// Domain login URL.
+func login(ctx context.Context, loginURL, clientID, clientSecret string) (*salesforce, error) {
+	form := url.Values{"grant_type": {"client_credentials"}, "client_id": {clientID}, "client_secret": {clientSecret}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(loginURL, "/")+"/services/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	hc := &http.Client{Timeout: 30 * time.Second}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		InstanceURL string `json:"instance_url"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return nil, fmt.Errorf("salesforce login: %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("salesforce login: %d %s: %s", resp.StatusCode, tok.Error, tok.Description)
+	}
+	return &salesforce{instance: strings.TrimSuffix(tok.InstanceURL, "/"), token: tok.AccessToken, http: hc}, nil
+}
+
+func (sf *salesforce) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, sf.instance+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+sf.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := sf.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var errs []struct {
+			ErrorCode string `json:"errorCode"`
+			Message   string `json:"message"`
+		}
+		e := &sfError{Status: resp.StatusCode}
+		if json.NewDecoder(resp.Body).Decode(&errs) == nil && len(errs) > 0 {
+			e.Code, e.Msg = errs[0].ErrorCode, errs[0].Message
+		}
+		return e
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// cases runs a SOQL query for Cases and follows nextRecordsUrl until every
+// page is read.
+func (sf *salesforce) cases(ctx context.Context, soql string) ([]Case, error) {
+	var out []Case
+	path := "/services/data/" + apiVersion + "/query?q=" + url.QueryEscape(soql)
+	for path != "" {
+		var page struct {
+			Records []Case `json:"records"`
+			Done    bool   `json:"done"`
+			Next    string `json:"nextRecordsUrl"`
+		}
+		if err := sf.do(ctx, http.MethodGet, path, nil, &page); err != nil {
+			return nil, err
+		}
+		out = append(out, page.Records...)
+		path = ""
+		if !page.Done {
+			path = page.Next
+		}
+	}
+	return out, nil
+}
+
+// comment adds a CaseComment to a Case and returns its ID. Unpublished
+// comments are internal: agents see them, the customer doesn't.
+func (sf *salesforce) comment(ctx context.Context, caseID, body string, published bool) (string, error) {
+	var res struct {
+		ID      string `json:"id"`
+		Success bool   `json:"success"`
+	}
+	err := sf.do(ctx, http.MethodPost, "/services/data/"+apiVersion+"/sobjects/CaseComment",
+		map[string]any{"ParentId": caseID, "CommentBody": body, "IsPublished": published}, &res)
+	if err != nil {
+		return "", err
+	}
+	if !res.Success {
+		return "", fmt.Errorf("salesforce: CaseComment on %s not created", caseID)
+	}
+	return res.ID, nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/stream"
+)
+
+// gcs is a minimal Cloud Storage JSON API client: list, download, upload.
+// It authenticates with an OAuth access token, such as the one
+// `gcloud auth print-access-token` prints or the metadata server hands a
+// service running on Google Cloud.
+type gcs struct {
+	endpoint string
+	token    string
+	http     *http.Client
+}
+
+// newGCS reads GCS_ACCESS_TOKEN, and GCS_ENDPOINT for emulators and tests.
+func newGCS() *gcs {
+	ep := os.Getenv("GCS_ENDPOINT")
+	if ep == "" {
+		ep = "https://storage.googleapis.com"
+	}
+	return &gcs{endpoint: strings.TrimSuffix(ep, "/"), token: os.Getenv("GCS_ACCESS_TOKEN"), http: http.DefaultClient}
+}
+
+// gcsError is a non-2xx answer from Cloud Storage.
+type gcsError struct {
+	status  int
+	message string
+}
+
+func (e *gcsError) Error() string { return fmt.Sprintf("cloud storage: %d %s", e.status, e.message) }
+
+func (g *gcs) do(req *http.Request) (*http.Response, error) {
+	if g.token == "" {
+		return nil, fmt.Errorf("cloud storage: GCS_ACCESS_TOKEN is not set")
+	}
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	resp, err := g.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		var body struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+		return nil, &gcsError{resp.StatusCode, body.Error.Message}
+	}
+	return resp, nil
+}
+
+// object is the part of an object's metadata the scrubber uses.
+type object struct {
+	Name       string `json:"name"`
+	Generation string `json:"generation"`
+}
+
+// objects lists the objects under prefix, following pages.
+func (g *gcs) objects(ctx context.Context, bucket, prefix string) ([]object, error) {
+	var all []object
+	pageToken := ""
+	for {
+		q := url.Values{"prefix": {prefix}, "fields": {"items(name,generation),nextPageToken"}}
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.endpoint+"/storage/v1/b/"+url.PathEscape(bucket)+"/o?"+q.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := g.do(req)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items         []object `json:"items"`
+			NextPageToken string   `json:"nextPageToken"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page.Items...)
+		if pageToken = page.NextPageToken; pageToken == "" {
+			return all, nil
+		}
+	}
+}
+
+// open downloads one generation of an object. Pinning the generation
+// means the copy is of the version that was listed, even if the object is
+// overwritten meanwhile.
+func (g *gcs) open(ctx context.Context, bucket, name, generation string) (io.ReadCloser, error) {
+	u := g.endpoint + "/storage/v1/b/" + url.PathEscape(bucket) + "/o/" + url.PathEscape(name) + "?alt=media&generation=" + url.QueryEscape(generation)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// upload writes body as an object in a single request. Cloud Storage only
+// creates the object once the whole body has arrived, so a body that
+// fails midway leaves nothing behind.
+func (g *gcs) upload(ctx context.Context, bucket, name string, body io.Reader) error {
+	u := g.endpoint + "/upload/storage/v1/b/" + url.PathEscape(bucket) + "/o?uploadType=media&name=" + url.QueryEscape(name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	resp, err := g.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// bucketSource scrubs the objects under a prefix into another prefix.
+type bucketSource struct {
+	gcs                  *gcs
+	bucket, prefix       string
+	outBucket, outPrefix string
+	exts                 map[string]bool
+}
+
+func newBucketSource(g *gcs, c *bucketConfig) *bucketSource {
+	out := c.OutBucket
+	if out == "" {
+		out = c.Bucket
+	}
+	return &bucketSource{gcs: g, bucket: c.Bucket, prefix: c.Prefix, outBucket: out, outPrefix: c.OutPrefix, exts: extSet(c.Extensions)}
+}
+
+// list keys items by object name below the prefix; the version is the
+// object's generation, which changes on every overwrite.
+func (b *bucketSource) list(ctx context.Context) ([]item, error) {
+	objs, err := b.gcs.objects(ctx, b.bucket, b.prefix)
+	if err != nil {
+		return nil, err
+	}
+	var items []item
+	for _, o := range objs {
+		if strings.HasSuffix(o.Name, "/") || !b.exts[strings.ToLower(path.Ext(o.Name))] {
+			continue
+		}
+		items = append(items, item{key: strings.TrimPrefix(o.Name, b.prefix), version: o.Generation})
+	}
+	return items, nil
+}
+
+// scrub streams the object through pkg/stream straight into the upload:
+// it is never held whole in memory, or written to disk.
+func (b *bucketSource) scrub(ctx context.Context, bf bfclient.Client, it item) (*scrubbed, error) {
+	in, err := b.gcs.open(ctx, b.bucket, b.prefix+it.key, it.version)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	r := stream.NewReader(ctx, bf, in)
+	if err := b.gcs.upload(ctx, b.outBucket, b.outPrefix+it.key, r); err != nil {
+		return nil, err
+	}
+	return &scrubbed{mapping: r.Mapping(), entities: r.Counts()}, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config is the jobs file: what to scrub, where the copies go, and when.
+type config struct {
+	Jobs []jobConfig `yaml:"jobs"`
+}
+
+// jobConfig is one scrub job. Exactly one of Dir, Bucket and Table is set.
+type jobConfig struct {
+	Name     string `yaml:"name"`
+	Schedule string `yaml:"schedule"`
+	// Policy names the policy from the -policies file; empty for the
+	// file's default.
+	Policy string `yaml:"policy"`
+	// Mappings is a private directory for each item's mapping. Empty drops
+	// the mappings, so the scrubbed copies can't be restored.
+	Mappings string `yaml:"mappings"`
+
+	Dir    *dirConfig    `yaml:"dir"`
+	Bucket *bucketConfig `yaml:"bucket"`
+	Table  *tableConfig  `yaml:"table"`
+}
+
+// dirConfig scrubs a local directory tree into a mirrored one.
+type dirConfig struct {
+	Src        string   `yaml:"src"`
+	Out        string   `yaml:"out"`
+	Extensions []string `yaml:"extensions"`
+}
+
+// bucketConfig scrubs the objects under a Cloud Storage prefix into
+// another prefix, or another bucket.
+type bucketConfig struct {
+	Bucket     string   `yaml:"bucket"`
+	Prefix     string   `yaml:"prefix"`
+	OutBucket  string   `yaml:"out_bucket"` // default: Bucket
+	OutPrefix  string   `yaml:"out_prefix"`
+	Extensions []string `yaml:"extensions"`
+}
+
+// tableConfig scrubs text columns of a Postgres table into a copy of it.
+type tableConfig struct {
+	// DSNEnv names the environment variable holding the connection string,
+	// so the jobs file holds no credentials.
+	DSNEnv  string   `yaml:"dsn_env"`
+	Table   string   `yaml:"table"`
+	Key     string   `yaml:"key"`
+	Columns []string `yaml:"columns"`
+	// Into is the table the scrubbed rows are upserted into. It has the
+	// key, as primary key, and the columns.
+	Into string `yaml:"into"`
+}
+
+// defaultExtensions are the file types dir and bucket jobs scrub when a
+// job lists none.
+var defaultExtensions = []string{".txt", ".md", ".csv", ".json", ".jsonl", ".log", ".eml", ".html", ".xml"}
+
+func loadConfig(path string) (*config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var cfg config
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func (c *config) validate() error {
+	if len(c.Jobs) == 0 {
+		return errors.New("no jobs")
+	}
+	seen := make(map[string]bool)
+	for i, j := range c.Jobs {
+		if j.Name == "" {
+			return fmt.Errorf("job %d: no name", i+1)
+		}
+		if seen[j.Name] {
+			return fmt.Errorf("job %q: defined twice", j.Name)
+		}
+		seen[j.Name] = true
+		if _, err := parseSchedule(j.Schedule); err != nil {
+			return fmt.Errorf("job %q: %w", j.Name, err)
+		}
+		if err := j.validateSource(); err != nil {
+			return fmt.Errorf("job %q: %w", j.Name, err)
+		}
+	}
+	return nil
+}
+
+func (j *jobConfig) validateSource() error {
+	n := 0
+	for _, set := range []bool{j.Dir != nil, j.Bucket != nil, j.Table != nil} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return errors.New("set exactly one of dir, bucket and table")
+	}
+	switch {
+	case j.Dir != nil:
+		if j.Dir.Src == "" || j.Dir.Out == "" {
+			return errors.New("dir: src and out are required")
+		}
+	case j.Bucket != nil:
+		b := j.Bucket
+		if b.Bucket == "" {
+			return errors.New("bucket: bucket is required")
+		}
+		if (b.OutBucket == "" || b.OutBucket == b.Bucket) && !separate(b.Prefix, b.OutPrefix) {
+			return errors.New("bucket: out_prefix must not overlap prefix in the same bucket")
+		}
+	case j.Table != nil:
+		t := j.Table
+		if t.DSNEnv == "" || t.Table == "" || t.Key == "" || len(t.Columns) == 0 || t.Into == "" {
+			return errors.New("table: dsn_env, table, key, columns and into are required")
+		}
+		if t.Into == t.Table {
+			return errors.New("table: into must be a different table")
+		}
+	}
+	return nil
+}
+
+// separate reports whether neither prefix contains the other, so a job
+// never scrubs its own output.
+func separate(a, b string) bool {
+	return !strings.HasPrefix(a, b) && !strings.HasPrefix(b, a)
+}
+
+// kind names the job's source type.
+func (j *jobConfig) kind() string {
+	switch {
+	case j.Dir != nil:
+		return "dir"
+	case j.Bucket != nil:
+		return "bucket"
+	}
+	return "table"
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule says when a job runs next.
+type schedule interface {
+	// next returns the first run time strictly after t.
+	next(t time.Time) time.Time
+}
+
+// every runs at a fixed interval from the previous run.
+type every time.Duration
+
+func (e every) next(t time.Time) time.Time { return t.Add(time.Duration(e)) }
+
+// cronSpec is a standard five-field cron expression: minute, hour, day of
+// month, month, day of week. Each field is a set of allowed values, one
+// bit per value.
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record a "*" day field. As in cron, when both
+	// day fields are restricted a day matching either one runs.
+	domStar, dowStar bool
+}
+
+// descriptors are the @-shorthands cron accepts.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseSchedule parses a cron expression ("*/15 * * * *", "0 2 * * 1-5"),
+// an @-descriptor ("@daily"), or "@every <duration>" ("@every 90s").
+// Times are in the service's local time zone.
+func parseSchedule(s string) (schedule, error) {
+	s = strings.TrimSpace(s)
+	if d, ok := strings.CutPrefix(s, "@every "); ok {
+		dur, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", s, err)
+		}
+		if dur < time.Second {
+			return nil, fmt.Errorf("schedule %q: interval under a second", s)
+		}
+		return every(dur), nil
+	}
+	if expr, ok := descriptors[s]; ok {
+		s = expr
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q: want 5 fields (minute hour day month weekday), got %d", s, len(fields))
+	}
+	var c cronSpec
+	var err error
+	bounds := []struct {
+		set      *uint64
+		min, max int
+		name     string
+	}{
+		{&c.minute, 0, 59, "minute"},
+		{&c.hour, 0, 23, "hour"},
+		{&c.dom, 1, 31, "day of month"},
+		{&c.month, 1, 12, "month"},
+		{&c.dow, 0, 7, "day of week"},
+	}
+	for i, b := range bounds {
+		if *b.set, err = parseField(fields[i], b.min, b.max); err != nil {
+			return nil, fmt.Errorf("schedule %q: %s: %w", s, b.name, err)
+		}
+	}
+	// 7 is Sunday too
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = strings.HasPrefix(fields[2], "*")
+	c.dowStar = strings.HasPrefix(fields[4], "*")
+	if c.next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("schedule %q never matches a date", s)
+	}
+	return &c, nil
+}
+
+// parseField parses a comma-separated list of values, ranges ("1-5") and
+// steps ("*/15", "0-30/10") within [min, max].
+func parseField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step %q", stepText)
+			}
+			step = n
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("bad value %q", a)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("bad value %q", b)
+				}
+			} else if hasStep {
+				hi = max // "5/15" is "5-max/15"
+			}
+			if lo < min || hi > max || lo > hi {
+				return 0, fmt.Errorf("%q is outside %d-%d", rng, min, max)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func (c *cronSpec) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Every valid expression matches within four years (February 29th);
+	// stop past that rather than loop on an impossible one ("0 0 31 2 *")
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *cronSpec) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// Wednesday 14 October 2026, 16:56:41
+	now := time.Date(2026, 10, 14, 16, 56, 41, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 10, 14, 17, 0, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2026, 10, 15, 2, 0, 0, 0, time.UTC)},
+		{"0 * * * 1-5", time.Date(2026, 10, 14, 17, 0, 0, 0, time.UTC)},
+		{"30 9 * * 0", time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)},
+		{"30 9 * * 7", time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		// :05, :25 and :45 past 16:00 on 14 October: all passed this year
+		{"5/20 16 14 10 *", time.Date(2027, 10, 14, 16, 5, 0, 0, time.UTC)},
+		// Both day fields restricted: the 1st of the month or a Friday
+		{"0 0 1 * 5", time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", now.Add(90 * time.Second)},
+	}
+	for _, tt := range tests {
+		s, err := parseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("%q: %v", tt.spec, err)
+			continue
+		}
+		if got := s.next(now); !got.Equal(tt.want) {
+			t.Errorf("%q: next = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "0 0 31 2 *", "@every 10ms", "@sometimes"} {
+		if _, err := parseSchedule(spec); err == nil {
+			t.Errorf("%q: accepted", spec)
+		}
+	}
+}
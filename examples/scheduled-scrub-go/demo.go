@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/resilience"
+)
+
+// demoToken is the access token the fake bucket accepts.
+const demoToken = "ya29.demo"
+
+var demoFiles = map[string]string{
+	"tickets/T-1042.txt": "From: Dana Whitfield <dana.whitfield@example.com>\nPlease refund card 4111 1111 1111 1111, or call 415-555-0142.\n",
+	"tickets/T-1043.txt": "Login fails from 203.0.113.57 since Tuesday. Reach me at priya.n@example.org.\n",
+	"notes/standup.md":   "# Standup\n\n- Billing export moved to Thursdays\n",
+	"diagram.png":        "\x89PNG not text",
+}
+
+var demoObjects = map[string]string{
+	"transcripts/raw/call-5531.txt": "Agent: Can I have the card number?\nCaller: 5500 0000 0000 0004, and my email is omar.haddad@example.com.\n",
+	"transcripts/raw/call-5532.txt": "Caller: My SSN is 123-45-6789, call me back on 212-555-0187.\n",
+}
+
+// demo runs two jobs, a directory and a bucket, against a temporary
+// directory and an in-process fake Cloud Storage. It triggers them through
+// the status API the way an operator would, changes the sources, runs them
+// again to show that only what changed is scrubbed, and prints the status.
+func demo(policies *policyconf.Config, limits *resilience.Policy) error {
+	log.SetFlags(0)
+	tmp, err := os.MkdirTemp("", "scheduled-scrub-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	for rel, text := range demoFiles {
+		path := filepath.Join(tmp, "drop", filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			return err
+		}
+	}
+	bucket := newFakeBucket()
+	for name, text := range demoObjects {
+		bucket.put("acme-call-center", name, []byte(text))
+	}
+	gcsSrv := httptest.NewServer(bucket)
+	defer gcsSrv.Close()
+
+	cfg := &config{Jobs: []jobConfig{
+		{
+			Name: "support-exports", Schedule: "*/15 * * * *",
+			Mappings: filepath.Join(tmp, "mappings", "support-exports"),
+			Dir:      &dirConfig{Src: filepath.Join(tmp, "drop"), Out: filepath.Join(tmp, "clean")},
+		},
+		{
+			Name: "call-transcripts", Schedule: "0 2 * * *", Policy: "transcripts",
+			Mappings: filepath.Join(tmp, "mappings", "call-transcripts"),
+			Bucket:   &bucketConfig{Bucket: "acme-call-center", Prefix: "transcripts/raw/", OutBucket: "acme-analytics", OutPrefix: "transcripts/"},
+		},
+	}}
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	st, err := openState(filepath.Join(tmp, "state.db"))
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	g := &gcs{endpoint: gcsSrv.URL, token: demoToken, http: gcsSrv.Client()}
+	// One worker per job keeps the log in a stable order
+	jobs, err := buildJobs(cfg, policies, limits, st, 1, g)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := startJobs(ctx, jobs)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	api := httptest.NewServer(newServer(jobs))
+	defer api.Close()
+
+	// The jobs log to a buffer that is printed once a run is over, so the
+	// output reads in order
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	runAll := func() error {
+		for _, j := range jobs {
+			before := len(j.status(true).Runs)
+			if err := demoRequest(api.URL, http.MethodPost, "/jobs/"+j.cfg.Name+"/run"); err != nil {
+				return err
+			}
+			for len(j.status(true).Runs) == before {
+				time.Sleep(10 * time.Millisecond)
+			}
+			fmt.Printf("%s\n", logs.Bytes())
+			logs.Reset()
+		}
+		return nil
+	}
+
+	if err := runAll(); err != nil {
+		return err
+	}
+	scrubbedFile, _ := os.ReadFile(filepath.Join(tmp, "clean", "tickets", "T-1042.txt"))
+	fmt.Printf("clean/tickets/T-1042.txt:\n%s\n", indent(string(scrubbedFile)))
+	fmt.Printf("gs://acme-analytics/transcripts/call-5532.txt:\n%s\n", indent(string(bucket.get("acme-analytics", "transcripts/call-5532.txt"))))
+
+	// A ticket is edited and a new call lands; the next runs scrub only those
+	fmt.Println("── appended to tickets/T-1043.txt, uploaded transcripts/raw/call-5533.txt")
+	fmt.Println()
+	f, err := os.OpenFile(filepath.Join(tmp, "drop", "tickets", "T-1043.txt"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString("Update: it works from 198.51.100.23.\n")
+	f.Close()
+	if err != nil {
+		return err
+	}
+	bucket.put("acme-call-center", "transcripts/raw/call-5533.txt", []byte("Caller: It's Lena, lena.k@example.net.\n"))
+	if err := runAll(); err != nil {
+		return err
+	}
+	return demoRequest(api.URL, http.MethodGet, "/jobs")
+}
+
+// demoRequest calls the status API and prints the exchange.
+func demoRequest(base, method, path string) error {
+	req, err := http.NewRequest(method, base+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	sep := " "
+	if bytes.Count(body, []byte("\n")) > 1 {
+		sep = "\n"
+	}
+	fmt.Printf("%s %s → %d%s%s", method, path, resp.StatusCode, sep, body)
+	return nil
+}
+
+func indent(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = "  " + l
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// fakeBucket serves the Cloud Storage JSON API calls the scrubber makes,
+// from memory.
+type fakeBucket struct {
+	mu      sync.Mutex
+	objects map[string]map[string]fakeObject // bucket → name → object
+	gen     int
+}
+
+type fakeObject struct {
+	data       []byte
+	generation int
+}
+
+func newFakeBucket() *fakeBucket {
+	return &fakeBucket{objects: map[string]map[string]fakeObject{}}
+}
+
+// put writes an object under a new generation, as every write does.
+func (b *fakeBucket) put(bucket, name string, data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.objects[bucket] == nil {
+		b.objects[bucket] = map[string]fakeObject{}
+	}
+	b.gen++
+	b.objects[bucket][name] = fakeObject{data, b.gen}
+}
+
+func (b *fakeBucket) get(bucket, name string) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.objects[bucket][name].data
+}
+
+func (b *fakeBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+demoToken {
+		http.Error(w, `{"error":{"message":"Invalid Credentials"}}`, http.StatusUnauthorized)
+		return
+	}
+	path := r.URL.EscapedPath()
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/upload/storage/v1/b/"):
+		bucket, _ := url.PathUnescape(strings.TrimSuffix(strings.TrimPrefix(path, "/upload/storage/v1/b/"), "/o"))
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			// The upload was cut off: nothing is created
+			return
+		}
+		b.put(bucket, r.URL.Query().Get("name"), data)
+		_ = json.NewEncoder(w).Encode(map[string]string{"name": r.URL.Query().Get("name")})
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/storage/v1/b/"):
+		rest := strings.TrimPrefix(path, "/storage/v1/b/")
+		bucketPart, namePart, hasName := strings.Cut(rest, "/o/")
+		bucket, _ := url.PathUnescape(strings.TrimSuffix(bucketPart, "/o"))
+		if hasName {
+			name, _ := url.PathUnescape(namePart)
+			data := b.get(bucket, name)
+			if data == nil {
+				http.Error(w, `{"error":{"message":"No such object"}}`, http.StatusNotFound)
+				return
+			}
+			_, _ = io.Copy(w, bytes.NewReader(data))
+			return
+		}
+		b.list(w, bucket, r.URL.Query().Get("prefix"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (b *fakeBucket) list(w http.ResponseWriter, bucket, prefix string) {
+	b.mu.Lock()
+	var items []object
+	for name, o := range b.objects[bucket] {
+		if strings.HasPrefix(name, prefix) {
+			items = append(items, object{Name: name, Generation: strconv.Itoa(o.generation)})
+		}
+	}
+	b.mu.Unlock()
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	_ = json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/stream"
+)
+
+// item is one thing a job scrubs: a file, an object or a row.
+type item struct {
+	// key names the item within its job: a relative path, an object name,
+	// a row key. It carries no content, so it is safe to log.
+	key string
+	// version changes whenever the item's content does: a modification
+	// time, an object generation, a hash of the row.
+	version string
+}
+
+// scrubbed is what scrubbing one item produced besides the copy.
+type scrubbed struct {
+	mapping  map[string]string
+	entities map[string]int
+}
+
+// source is where a job finds its items and puts their scrubbed copies.
+type source interface {
+	// list returns the items to scrub, with their current versions.
+	list(ctx context.Context) ([]item, error)
+	// scrub writes the tokenized copy of it. A failure leaves no partial
+	// copy behind.
+	scrub(ctx context.Context, bf bfclient.Client, it item) (*scrubbed, error)
+}
+
+// dirSource scrubs the files of a directory tree into a mirrored tree.
+type dirSource struct {
+	src, out string
+	exts     map[string]bool
+}
+
+func newDirSource(c *dirConfig) *dirSource {
+	return &dirSource{src: c.Src, out: c.Out, exts: extSet(c.Extensions)}
+}
+
+func (d *dirSource) list(ctx context.Context) ([]item, error) {
+	out, _ := filepath.Abs(d.out)
+	var items []item
+	err := filepath.WalkDir(d.src, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if e.IsDir() {
+			if abs, _ := filepath.Abs(path); abs == out || e.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !e.Type().IsRegular() || !d.exts[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(d.src, path)
+		if err != nil {
+			return err
+		}
+		items = append(items, item{
+			key:     filepath.ToSlash(rel),
+			version: fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()),
+		})
+		return nil
+	})
+	return items, err
+}
+
+// scrub streams the file through pkg/stream into a temporary file that is
+// renamed into place once the whole file is tokenized.
+func (d *dirSource) scrub(ctx context.Context, bf bfclient.Client, it item) (*scrubbed, error) {
+	in, err := os.Open(filepath.Join(d.src, filepath.FromSlash(it.key)))
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	dst := filepath.Join(d.out, filepath.FromSlash(it.key))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".scrub-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	r := stream.NewReader(ctx, bf, in)
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("tokenize: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return nil, err
+	}
+	return &scrubbed{mapping: r.Mapping(), entities: r.Counts()}, nil
+}
+
+// extSet normalizes extensions to lowercase with a leading dot.
+func extSet(exts []string) map[string]bool {
+	if len(exts) == 0 {
+		exts = defaultExtensions
+	}
+	set := make(map[string]bool, len(exts))
+	for _, e := range exts {
+		if e = strings.ToLower(strings.TrimSpace(e)); e != "" {
+			set["."+strings.TrimPrefix(e, ".")] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+)
+
+// historySize is how many runs a job's status keeps.
+const historySize = 10
+
+// maxErrors is how many item failures a run's status lists.
+const maxErrors = 10
+
+// run is the record of one run of a job.
+type run struct {
+	Trigger   string         `json:"trigger"` // "schedule" or "manual"
+	Started   time.Time      `json:"started"`
+	Finished  *time.Time     `json:"finished,omitempty"`
+	Duration  string         `json:"duration,omitempty"`
+	Items     int            `json:"items"`
+	Unchanged int            `json:"unchanged"`
+	Scrubbed  int            `json:"scrubbed"`
+	Failed    int            `json:"failed"`
+	Entities  map[string]int `json:"entities"`
+	// Errors lists the first failed items. Keys and errors only: neither
+	// holds item content.
+	Errors []itemError `json:"errors,omitempty"`
+	// Error is set when the run couldn't list its items at all.
+	Error string `json:"error,omitempty"`
+}
+
+type itemError struct {
+	Key   string `json:"key"`
+	Kind  string `json:"kind"` // bferrors kind
+	Error string `json:"error"`
+}
+
+// job runs one configured scrub job on its schedule, or when triggered.
+// Runs of a job never overlap: a run that is due while the previous one is
+// still going waits for it, and ticks missed meanwhile are skipped.
+type job struct {
+	cfg     jobConfig
+	sched   schedule
+	src     source
+	bf      bfclient.Client
+	state   *state
+	workers int
+	trigger chan struct{}
+
+	mu      sync.Mutex
+	next    time.Time
+	current *run  // the run in progress, or nil
+	history []run // newest first
+}
+
+func newJob(cfg jobConfig, src source, bf bfclient.Client, st *state, workers int) (*job, error) {
+	sched, err := parseSchedule(cfg.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	j := &job{cfg: cfg, sched: sched, src: src, bf: bf, state: st, workers: max(workers, 1), trigger: make(chan struct{}, 1)}
+	last, err := st.lastRun(cfg.Name)
+	if err != nil {
+		return nil, err
+	}
+	if last != nil {
+		j.history = []run{*last}
+	}
+	return j, nil
+}
+
+// loop runs the job at every scheduled time until ctx is done.
+func (j *job) loop(ctx context.Context) {
+	for {
+		next := j.sched.next(time.Now())
+		j.mu.Lock()
+		j.next = next
+		j.mu.Unlock()
+		timer := time.NewTimer(time.Until(next))
+		trigger := "schedule"
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		case <-j.trigger:
+			timer.Stop()
+			trigger = "manual"
+		}
+		j.run(ctx, trigger)
+	}
+}
+
+// runNow asks the loop for a run outside the schedule. It reports false
+// if a run is already in progress or asked for.
+func (j *job) runNow() bool {
+	j.mu.Lock()
+	running := j.current != nil
+	j.mu.Unlock()
+	if running {
+		return false
+	}
+	select {
+	case j.trigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (j *job) run(ctx context.Context, trigger string) {
+	r := &run{Trigger: trigger, Started: time.Now().UTC().Truncate(time.Millisecond), Entities: map[string]int{}}
+	j.mu.Lock()
+	j.current = r
+	j.mu.Unlock()
+	log.Printf("%s: run started (%s)", j.cfg.Name, trigger)
+
+	if err := j.scrubAll(ctx, r); err != nil {
+		j.mu.Lock()
+		r.Error = err.Error()
+		j.mu.Unlock()
+		log.Printf("%s: %v", j.cfg.Name, err)
+	}
+
+	j.mu.Lock()
+	finished := time.Now().UTC().Truncate(time.Millisecond)
+	r.Finished = &finished
+	r.Duration = finished.Sub(r.Started).String()
+	j.mu.Unlock()
+	log.Printf("%s: run finished in %s: %d scrubbed, %d unchanged, %d failed", j.cfg.Name, r.Duration, r.Scrubbed, r.Unchanged, r.Failed)
+	if err := j.state.putRun(j.cfg.Name, r); err != nil {
+		log.Printf("%s: save run: %v", j.cfg.Name, err)
+	}
+
+	j.mu.Lock()
+	j.current = nil
+	j.history = append([]run{*r}, j.history...)
+	if len(j.history) > historySize {
+		j.history = j.history[:historySize]
+	}
+	j.mu.Unlock()
+}
+
+// scrubAll scrubs the items that are new or changed since they were last
+// scrubbed, on up to workers goroutines. A failed item isn't recorded, so
+// the next run tries it again. When ctx is done no more items are started,
+// but those in progress finish. A fatal error, such as a rejected API key,
+// stops the run the same way and is returned: every other item would fail
+// with it too.
+func (j *job) scrubAll(ctx context.Context, r *run) error {
+	items, err := j.src.list(ctx)
+	if err != nil {
+		return err
+	}
+	done, err := j.state.versions(j.cfg.Name)
+	if err != nil {
+		return err
+	}
+	var pending []item
+	for _, it := range items {
+		if done[it.key] != it.version {
+			pending = append(pending, it)
+		}
+	}
+	j.mu.Lock()
+	r.Items, r.Unchanged = len(items), len(items)-len(pending)
+	j.mu.Unlock()
+
+	itemCtx := context.WithoutCancel(ctx)
+	ctx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+	next := make(chan item)
+	var wg sync.WaitGroup
+	for w := 0; w < j.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for it := range next {
+				res, err := j.scrub(itemCtx, it)
+				j.finish(r, it, res, err)
+				if bferrors.IsFatal(err) {
+					abort(fmt.Errorf("stopped: %w", err))
+				}
+			}
+		}()
+	}
+	for _, it := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- it
+	}
+	close(next)
+	wg.Wait()
+	return context.Cause(ctx)
+}
+
+// scrub scrubs one item, stores its mapping and records its version.
+func (j *job) scrub(ctx context.Context, it item) (*scrubbed, error) {
+	res, err := j.src.scrub(ctx, j.bf, it)
+	if err != nil {
+		return nil, err
+	}
+	if j.cfg.Mappings != "" {
+		if err := writeMapping(filepath.Join(j.cfg.Mappings, filepath.FromSlash(it.key)+".json"), res.mapping); err != nil {
+			return nil, err
+		}
+	}
+	return res, j.state.record(j.cfg.Name, it)
+}
+
+// finish adds an item's outcome to the run.
+func (j *job) finish(r *run, it item, res *scrubbed, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err != nil {
+		r.Failed++
+		if len(r.Errors) < maxErrors {
+			r.Errors = append(r.Errors, itemError{it.key, bferrors.KindOf(err).String(), err.Error()})
+		}
+		log.Printf("%s: FAIL %s: %v", j.cfg.Name, it.key, err)
+		return
+	}
+	r.Scrubbed++
+	n := 0
+	for typ, c := range res.entities {
+		r.Entities[typ] += c
+		n += c
+	}
+	log.Printf("%s: ok   %s  %d entities", j.cfg.Name, it.key, n)
+}
+
+// writeMapping stores an item's mapping with owner-only permissions. An
+// item without entities gets none.
+func writeMapping(path string, m map[string]string) error {
+	if len(m) == 0 {
+		os.Remove(path)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o600)
+}
+
+// jobStatus is a job as the status API shows it.
+type jobStatus struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Schedule string `json:"schedule"`
+	State    string `json:"state"` // "idle" or "running"
+	// NextRun is when the schedule runs the job next; unset while it runs.
+	NextRun *time.Time `json:"next_run,omitempty"`
+	Current *run       `json:"current,omitempty"`
+	// LastRun is the last finished run, in the list of jobs; a single
+	// job's view has Runs, its recent history, newest first, instead.
+	LastRun *run  `json:"last_run,omitempty"`
+	Runs    []run `json:"runs,omitempty"`
+}
+
+// status snapshots the job, with its recent runs or its last one.
+func (j *job) status(history bool) jobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := jobStatus{Name: j.cfg.Name, Kind: j.cfg.kind(), Schedule: j.cfg.Schedule, State: "idle"}
+	if j.current != nil {
+		cur := *j.current
+		cur.Entities = make(map[string]int, len(j.current.Entities))
+		for typ, n := range j.current.Entities {
+			cur.Entities[typ] = n
+		}
+		cur.Errors = append([]itemError(nil), j.current.Errors...)
+		s.State, s.Current = "running", &cur
+	} else if !j.next.IsZero() {
+		next := j.next
+		s.NextRun = &next
+	}
+	if history {
+		s.Runs = append([]run(nil), j.history...)
+	} else if len(j.history) > 0 {
+		last := j.history[0]
+		s.LastRun = &last
+	}
+	return s
+}
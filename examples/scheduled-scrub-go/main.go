@@ -0,0 +1,170 @@
+// Scheduled scrubber + Blindfold: Scrub directories, buckets and tables on
+// a cron schedule.
+//
+// A long-running service with a built-in cron scheduler. jobs.yaml lists
+// scrub jobs, each a source with a schedule: a directory tree mirrored
+// into a scrubbed one, a Cloud Storage prefix copied to another, or text
+// columns of a Postgres table upserted into a second table. A run only
+// scrubs what is new or changed since the last one, and every item is
+// tokenized whole or not at all, so a failure never leaves a partial
+// copy. Each job can keep its mappings in a private directory. Job status
+// (next run, the run in progress, recent runs with counts and failures) is
+// served over HTTP, where a job can also be run on demand.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	flagconfig "github.com/blindfold-dev/blindfold-cookbook/pkg/config"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/health"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/resilience"
+)
+
+func main() {
+	// Every flag can also be set as SCRUB_<FLAG>; -config is the jobs file
+	fc := flagconfig.New(flag.CommandLine, "SCRUB")
+	configPath := flag.String("config", "jobs.yaml", "jobs file")
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	addr := flag.String("addr", "127.0.0.1:8084", "status API listen address")
+	statePath := flag.String("state", "scrub-state.db", "state file: versions of scrubbed items and last runs")
+	workers := flag.Int("workers", 4, "items scrubbed concurrently, per job")
+	rps := flag.Float64("rps", 10, "Blindfold request rate limit, shared by all jobs (0 = unlimited)")
+	runDemo := flag.Bool("demo", false, "run sample jobs against a temporary directory and a fake bucket, and exit")
+	fc.Check("workers", func() error {
+		if *workers < 1 {
+			return errors.New("must be at least 1")
+		}
+		return nil
+	})
+	fc.Check("rps", func() error {
+		if *rps <= 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	})
+	if err := fc.Parse(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	policies := &policyconf.Config{}
+	if *file != "" {
+		var err error
+		if policies, err = policyconf.Load(*file); err != nil {
+			log.Fatal(err)
+		}
+	}
+	limits := resilience.DefaultPolicy(*rps)
+
+	if *runDemo {
+		if err := demo(policies, limits); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	st, err := openState(*statePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer st.Close()
+	jobs, err := buildJobs(cfg, policies, limits, st, *workers, newGCS())
+	if err != nil {
+		st.Close()
+		log.Fatal(err)
+	}
+
+	// SIGTERM, as from a container runtime, lets runs in progress finish
+	// the items they hold; the rest wait for the next run
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	wg := startJobs(ctx, jobs)
+	mux := http.NewServeMux()
+	mux.Handle("/", newServer(jobs))
+	// Jobs pick their own policies, so /configz shows the whole file
+	cfgz := health.StandardConfig(flag.CommandLine, nil)
+	cfgz["policies"] = policies
+	cfgz["sources"] = fc.Sources()
+	hc := health.New()
+	hc.Config = cfgz
+	hc.Register(mux)
+	srv := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+	log.Printf("%d jobs scheduled; status on http://%s/jobs", len(jobs), *addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Print(err)
+		stop()
+	}
+	wg.Wait()
+}
+
+// buildJobs makes a job of each configured one, with its own policy over a
+// Blindfold client whose rate limit all jobs share.
+func buildJobs(cfg *config, policies *policyconf.Config, limits *resilience.Policy, st *state, workers int, g *gcs) ([]*job, error) {
+	jobs := make([]*job, 0, len(cfg.Jobs))
+	for _, jc := range cfg.Jobs {
+		pol, err := policies.Policy(jc.Policy)
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %w", jc.Name, err)
+		}
+		// API key is optional — omit it to run in local mode (regex-based, offline)
+		bf := bfclient.FromEnv(append(pol.ClientOptions(), blindfold.WithMaxRetries(0))...)
+		var src source
+		switch {
+		case jc.Dir != nil:
+			src = newDirSource(jc.Dir)
+		case jc.Bucket != nil:
+			src = newBucketSource(g, jc.Bucket)
+		default:
+			if src, err = newTableSource(jc.Table); err != nil {
+				return nil, fmt.Errorf("job %q: %w", jc.Name, err)
+			}
+		}
+		j, err := newJob(jc, src, pol.Wrap(resilience.Wrap(bf, limits)), st, workers)
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %w", jc.Name, err)
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+// startJobs starts every job's loop; the WaitGroup is done once they have
+// all stopped after ctx is.
+func startJobs(ctx context.Context, jobs []*job) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(j *job) {
+			defer wg.Done()
+			j.loop(ctx)
+		}(j)
+	}
+	return &wg
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// server exposes job status over HTTP:
+//
+//	GET  /jobs             every job, with its last run and next one
+//	GET  /jobs/{name}      one job, with its recent runs
+//	POST /jobs/{name}/run  run a job now (202), unless it is running (409)
+//
+// Status holds job names, item keys, counts and errors: never item
+// content or mappings.
+type server struct {
+	jobs  []*job
+	byKey map[string]*job
+}
+
+func newServer(jobs []*job) *server {
+	s := &server{jobs: jobs, byKey: make(map[string]*job, len(jobs))}
+	for _, j := range jobs {
+		s.byKey[j.cfg.Name] = j
+	}
+	return s
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	switch {
+	case path == "jobs":
+		if !allow(w, r, http.MethodGet) {
+			return
+		}
+		out := make([]jobStatus, len(s.jobs))
+		for i, j := range s.jobs {
+			out[i] = j.status(false)
+		}
+		reply(w, http.StatusOK, out)
+	case strings.HasPrefix(path, "jobs/"):
+		name, action, _ := strings.Cut(strings.TrimPrefix(path, "jobs/"), "/")
+		j, ok := s.byKey[name]
+		if !ok || (action != "" && action != "run") {
+			reply(w, http.StatusNotFound, map[string]string{"error": "no such job"})
+			return
+		}
+		if action == "" {
+			if allow(w, r, http.MethodGet) {
+				reply(w, http.StatusOK, j.status(true))
+			}
+			return
+		}
+		if !allow(w, r, http.MethodPost) {
+			return
+		}
+		if !j.runNow() {
+			reply(w, http.StatusConflict, map[string]string{"error": "a run is already in progress or queued"})
+			return
+		}
+		reply(w, http.StatusAccepted, map[string]string{"status": "queued"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	reply(w, http.StatusMethodNotAllowed, map[string]string{"error": "use " + method})
+	return false
+}
+
+// reply writes v as JSON: indented for the job status, which is read by
+// people as often as by scripts, on one line for short answers.
+func reply(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if _, short := v.(map[string]string); !short {
+		enc.SetIndent("", "  ")
+	}
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var runsBucket = []byte("runs")
+
+// state remembers, per job, the version of every item it scrubbed, so a
+// run only scrubs what is new or changed, and each job's last run, so the
+// status survives a restart. It holds keys and versions, never content or
+// mappings.
+type state struct {
+	db *bolt.DB
+}
+
+func openState(path string) (*state, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%s is in use by another scheduler", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(runsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &state{db: db}, nil
+}
+
+func (s *state) Close() error { return s.db.Close() }
+
+func itemsBucket(job string) []byte { return []byte("items/" + job) }
+
+// versions returns the recorded version of every item of job.
+func (s *state) versions(job string) (map[string]string, error) {
+	out := make(map[string]string)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(itemsBucket(job))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			out[string(k)] = string(v)
+			return nil
+		})
+	})
+	return out, err
+}
+
+// record notes that job scrubbed the item at version.
+func (s *state) record(job string, it item) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(itemsBucket(job))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(it.key), []byte(it.version))
+	})
+}
+
+// lastRun returns job's last finished run, or nil.
+func (s *state) lastRun(job string) (*run, error) {
+	var r *run
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(runsBucket).Get([]byte(job))
+		if data == nil {
+			return nil
+		}
+		r = new(run)
+		return json.Unmarshal(data, r)
+	})
+	return r, err
+}
+
+func (s *state) putRun(job string, r *run) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(runsBucket).Put([]byte(job), data)
+	})
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" database/sql driver
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// tableSource scrubs text columns of a Postgres table, row by row, into a
+// second table with the same key. Other columns aren't copied: join back
+// on the key for them.
+type tableSource struct {
+	db                                *sql.DB
+	listQuery, readQuery, upsertQuery string
+	columns                           int
+}
+
+// newTableSource opens the database named by the job's dsn_env. The
+// queries are built once, from quoted identifiers.
+func newTableSource(c *tableConfig) (*tableSource, error) {
+	dsn := os.Getenv(c.DSNEnv)
+	if dsn == "" {
+		return nil, fmt.Errorf("%s is not set", c.DSNEnv)
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	table, into, key := ident(c.Table), ident(c.Into), ident(c.Key)
+	cols := make([]string, len(c.Columns))
+	params := make([]string, len(c.Columns))
+	sets := make([]string, len(c.Columns))
+	for i, col := range c.Columns {
+		cols[i] = ident(col)
+		params[i] = fmt.Sprintf("$%d", i+2)
+		sets[i] = fmt.Sprintf("%s = EXCLUDED.%s", cols[i], cols[i])
+	}
+	colList := strings.Join(cols, ", ")
+	return &tableSource{
+		db: db,
+		// The database hashes the columns, so a run only reads the rows
+		// that changed, and the version never holds a value
+		listQuery: fmt.Sprintf("SELECT %s::text, md5(ROW(%s)::text) FROM %s ORDER BY 1", key, colList, table),
+		readQuery: fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1", colList, table, key),
+		upsertQuery: fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES ($1, %s) ON CONFLICT (%s) DO UPDATE SET %s",
+			into, key, colList, strings.Join(params, ", "), key, strings.Join(sets, ", ")),
+		columns: len(c.Columns),
+	}, nil
+}
+
+// ident quotes a possibly schema-qualified name: support.tickets →
+// "support"."tickets".
+func ident(name string) string {
+	return pgx.Identifier(strings.Split(name, ".")).Sanitize()
+}
+
+// list keys items by the row key, escaped so it is safe in a mapping's
+// file name.
+func (t *tableSource) list(ctx context.Context) ([]item, error) {
+	rows, err := t.db.QueryContext(ctx, t.listQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []item
+	for rows.Next() {
+		var key, version string
+		if err := rows.Scan(&key, &version); err != nil {
+			return nil, err
+		}
+		items = append(items, item{key: url.PathEscape(key), version: version})
+	}
+	return items, rows.Err()
+}
+
+// scrub tokenizes each column of the row on its own, merges the mappings
+// so a value has one token across the row, and upserts the result. NULL
+// stays NULL.
+func (t *tableSource) scrub(ctx context.Context, bf bfclient.Client, it item) (*scrubbed, error) {
+	key, err := url.PathUnescape(it.key)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]sql.NullString, t.columns)
+	dest := make([]any, t.columns)
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := t.db.QueryRowContext(ctx, t.readQuery, key).Scan(dest...); err != nil {
+		return nil, err
+	}
+	texts := make([]string, t.columns)
+	maps := make([]map[string]string, t.columns)
+	entities := make(map[string]int)
+	for i, v := range values {
+		if !v.Valid {
+			continue
+		}
+		res, err := bf.Tokenize(ctx, v.String)
+		if err != nil {
+			return nil, fmt.Errorf("tokenize: %w", err)
+		}
+		texts[i], maps[i] = res.Text, res.Mapping
+		for _, e := range res.DetectedEntities {
+			entities[e.Type]++
+		}
+	}
+	merged := mapping.Merge(maps...)
+	args := []any{key}
+	for i, v := range values {
+		if !v.Valid {
+			args = append(args, nil)
+			continue
+		}
+		args = append(args, merged.Rewrite(i, texts[i]))
+	}
+	if _, err := t.db.ExecContext(ctx, t.upsertQuery, args...); err != nil {
+		return nil, err
+	}
+	return &scrubbed{mapping: merged.Mapping, entities: entities}, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is one compiled .gitignore-style pattern.
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool // "!pattern" re-includes what an earlier rule excluded
+	dirOnly bool // "pattern/" matches directories only
+}
+
+// ignoreList matches slash-separated paths, relative to the scanned root,
+// against .gitignore-style patterns. As in git, the last matching pattern
+// decides, and a file inside an excluded directory can't be re-included:
+// the walker never enters the directory.
+type ignoreList struct {
+	rules []ignoreRule
+}
+
+// loadIgnore reads patterns from path, one per line. A missing file is an
+// empty list.
+func loadIgnore(path string) (*ignoreList, error) {
+	l := &ignoreList{}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		l.add(sc.Text())
+	}
+	return l, sc.Err()
+}
+
+// add compiles one pattern line. Blank lines and # comments are skipped.
+func (l *ignoreList) add(line string) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+	var r ignoreRule
+	if strings.HasPrefix(line, "!") {
+		r.negate, line = true, line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:] // \# and \! stand for themselves
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly, line = true, strings.TrimRight(line, "/")
+	}
+	// A pattern with a slash other than at the end is relative to the
+	// root; one without matches at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return
+	}
+	expr := globToRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	r.re = regexp.MustCompile("^" + expr + "$")
+	l.rules = append(l.rules, r)
+}
+
+// globToRegexp translates gitignore glob syntax: ** spans directories,
+// * and ? stay within one, and [...] classes pass through.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("(?:/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// match reports whether rel is excluded.
+func (l *ignoreList) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range l.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(rel) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
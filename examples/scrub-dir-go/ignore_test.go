@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestIgnoreList(t *testing.T) {
+	l := &ignoreList{}
+	for _, p := range []string{
+		"# comment",
+		"",
+		"*.log",
+		"!keep.log",
+		"build/",
+		"/secrets.env",
+		"docs/**/draft-*.md",
+		"tmp[0-9]",
+		`\#literal`,
+	} {
+		l.add(p)
+	}
+	for _, c := range []struct {
+		path string
+		dir  bool
+		want bool
+	}{
+		{"app.log", false, true},
+		{"logs/deep/app.log", false, true},
+		{"logs/keep.log", false, false},
+		{"build", true, true},
+		{"src/build", true, true},
+		{"build", false, false}, // a file named build, not the directory
+		{"secrets.env", false, true},
+		{"config/secrets.env", false, false}, // anchored to the root
+		{"docs/draft-a.md", false, true},
+		{"docs/2026/q3/draft-b.md", false, true},
+		{"docs/final.md", false, false},
+		{"tmp7", true, true},
+		{"tmpx", true, false},
+		{"#literal", false, true},
+		{"notes.txt", false, false},
+	} {
+		if got := l.match(c.path, c.dir); got != c.want {
+			t.Errorf("match(%q, dir=%v) = %v, want %v", c.path, c.dir, got, c.want)
+		}
+	}
+}
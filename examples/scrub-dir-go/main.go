@@ -0,0 +1,182 @@
+// Directory scrubber + Blindfold: Tokenize a whole file tree.
+//
+// Walks a directory, skips what a .gitignore-style exclude file lists,
+// and tokenizes every supported file with a pool of workers. Each file is
+// streamed through pkg/stream, so file size doesn't matter. Tokenized
+// copies land in a mirrored output tree for analysts, and each file's
+// mapping goes to a separate, owner-only tree that stays behind. One line
+// of progress is printed per file, and a summary at the end. Every
+// finished file is checkpointed, so an interrupted run picks up where it
+// stopped and a rerun only scrubs what changed. With -watch it then keeps
+// watching the tree as a drop folder, scrubbing files as they land.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/joho/godotenv"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/resilience"
+)
+
+// excludes holds repeated -exclude flags.
+type excludes []string
+
+func (e *excludes) String() string     { return strings.Join(*e, ",") }
+func (e *excludes) Set(v string) error { *e = append(*e, v); return nil }
+
+// policySettings identifies the policy a run applies: its name and a hash
+// of the policy file, if there is one.
+func policySettings(file, name string) (string, error) {
+	settings := "policy=" + name
+	if file == "" {
+		return settings, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return settings + " policies-sha256=" + hex.EncodeToString(sum[:8]), nil
+}
+
+func main() {
+	_ = godotenv.Load()
+	src := flag.String("src", "sample", "directory to scrub")
+	out := flag.String("out", "scrubbed", "directory for the tokenized copies")
+	mappings := flag.String("mappings", "mappings", "directory for the per-file mappings (keep it private)")
+	ignoreFile := flag.String("ignore", "", "exclude file with .gitignore syntax (default: <src>/.scrubignore)")
+	var extra excludes
+	flag.Var(&extra, "exclude", "extra exclude pattern, .gitignore syntax (repeatable)")
+	exts := flag.String("ext", ".txt,.md,.csv,.json,.log,.eml,.html,.xml,.yaml,.yml", "file extensions to scrub")
+	maxBytes := flag.Int64("max-bytes", 1<<30, "skip files larger than this (0 = no limit)")
+	policiesFile := flag.String("policies", "", "policy file (YAML or JSON); empty = built-in policies only")
+	policy := flag.String("policy", "strict", "policy to apply, by name")
+	workers := flag.Int("workers", 4, "files scrubbed concurrently")
+	rps := flag.Float64("rps", 10, "Blindfold request rate limit (0 = unlimited)")
+	statePath := flag.String("state", "scrub-state.db", `checkpoint file; an interrupted run resumes from it ("" = none)`)
+	restart := flag.Bool("restart", false, "discard the checkpoints and scrub every file again")
+	watch := flag.Bool("watch", false, "after the first pass, keep watching -src and scrub files as they arrive or change")
+	settle := flag.Duration("settle", 2*time.Second, "with -watch, how long a file must go unchanged before it is scrubbed")
+	flag.Parse()
+
+	// Ctrl-C stops handing out files; files in progress finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	pol, err := policyconf.Resolve(*policiesFile, *policy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := bfclient.FromEnv(append(pol.ClientOptions(), blindfold.WithMaxRetries(0))...)
+
+	if *ignoreFile == "" {
+		*ignoreFile = filepath.Join(*src, ".scrubignore")
+	}
+	ignore, err := loadIgnore(*ignoreFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, p := range extra {
+		ignore.add(p)
+	}
+	ignore.add(".scrubignore")
+
+	w := &walker{root: *src, ignore: ignore, exts: map[string]bool{}, maxBytes: *maxBytes}
+	for _, e := range strings.Split(*exts, ",") {
+		if e = strings.ToLower(strings.TrimSpace(e)); e != "" {
+			w.exts["."+strings.TrimPrefix(e, ".")] = true
+		}
+	}
+	for _, dir := range []string{*out, *mappings} {
+		if abs, err := filepath.Abs(dir); err == nil {
+			w.avoid = append(w.avoid, abs)
+		}
+	}
+	files, skipped, err := w.walk()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s := &scrubber{
+		bf:       pol.Wrap(resilience.Wrap(bf, resilience.DefaultPolicy(*rps))),
+		src:      *src,
+		out:      *out,
+		mappings: *mappings,
+	}
+	if *statePath != "" {
+		settings, err := policySettings(*policiesFile, *policy)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if s.state, err = openState(*statePath, settings, *restart); err != nil {
+			log.Fatal(err)
+		}
+		defer s.state.Close()
+	}
+	var pending []file
+	for _, f := range files {
+		if !s.done(f) {
+			pending = append(pending, f)
+		}
+	}
+	fmt.Printf("%d files in %s, %d already scrubbed, %d to scrub (%d skipped)\n\n", len(files), *src, len(files)-len(pending), len(pending), len(skipped))
+	p := newProgress(os.Stdout, len(pending), skipped)
+
+	jobs := make(chan file)
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				p.report(s.scrub(ctx, f))
+			}
+		}()
+	}
+	for _, f := range pending {
+		select {
+		case jobs <- f:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if *watch && ctx.Err() == nil {
+		fmt.Printf("Watching %s for new and changed files (Ctrl-C to stop)\n", *src)
+		wt := &watcher{walker: w, s: s, p: p, jobs: jobs, settle: *settle}
+		if err := wt.watch(ctx); err != nil {
+			log.Print(err)
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	p.summary()
+	if ctx.Err() != nil && !*watch {
+		log.Fatal("interrupted; rerun to resume")
+	}
+	if len(p.failed) > 0 {
+		os.Exit(1)
+	}
+}
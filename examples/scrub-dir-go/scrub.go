@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/stream"
+)
+
+// file is one file to scrub, by path relative to the source root.
+type file struct {
+	rel     string
+	size    int64
+	modTime time.Time
+}
+
+// skip records a file the walker passed over, and why.
+type skip struct {
+	rel, reason string
+}
+
+// walker finds the files to scrub under root.
+type walker struct {
+	root     string
+	ignore   *ignoreList
+	exts     map[string]bool // lowercase, with the dot
+	maxBytes int64
+	avoid    []string // absolute directories never to enter: the outputs
+}
+
+// walk returns the files to scrub in path order and the files skipped.
+// Excluded directories aren't entered.
+func (w *walker) walk() ([]file, []skip, error) {
+	return w.walkFrom(w.root)
+}
+
+// walkFrom is walk over the subtree at dir, a directory under the root.
+func (w *walker) walkFrom(dir string) ([]file, []skip, error) {
+	var files []file
+	var skipped []skip
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(w.root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if w.skipDir(path, rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if f, reason, ok := w.accept(rel, info); ok {
+			files = append(files, f)
+		} else if reason != "" {
+			skipped = append(skipped, skip{rel, reason})
+		}
+		return nil
+	})
+	return files, skipped, err
+}
+
+// skipDir reports whether the directory at path is not to be entered.
+func (w *walker) skipDir(path, rel string) bool {
+	return filepath.Base(path) == ".git" || w.ignore.match(rel, true) || w.avoided(path)
+}
+
+// accept decides whether the file at rel is scrubbed. A file that isn't
+// comes with the reason, or none if it is excluded.
+func (w *walker) accept(rel string, info fs.FileInfo) (file, string, bool) {
+	switch {
+	case w.ignore.match(rel, false):
+		return file{}, "", false
+	case !info.Mode().IsRegular():
+		return file{}, "not a regular file", false
+	case !w.exts[strings.ToLower(filepath.Ext(rel))]:
+		return file{}, "unsupported type", false
+	case w.maxBytes > 0 && info.Size() > w.maxBytes:
+		return file{}, fmt.Sprintf("larger than %d bytes", w.maxBytes), false
+	}
+	return file{rel, info.Size(), info.ModTime()}, "", true
+}
+
+func (w *walker) avoided(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for _, a := range w.avoid {
+		if abs == a {
+			return true
+		}
+	}
+	return false
+}
+
+// result is the outcome of scrubbing one file.
+type result struct {
+	file
+	entities map[string]int
+	elapsed  time.Duration
+	skipped  string // why the file was passed over after all, if it was
+	err      error
+}
+
+// scrubber writes tokenized copies of files under out, mirroring the
+// source tree, and each file's mapping under mappings.
+type scrubber struct {
+	bf                 bfclient.Client
+	src, out, mappings string
+	state              *state // checkpoints; nil to keep none
+}
+
+// scrub tokenizes one file. The copy is written to a temporary file and
+// renamed into place only once the whole file is tokenized, so a failure
+// never leaves a partial copy, or a stale one, behind.
+func (s *scrubber) scrub(ctx context.Context, f file) result {
+	start := time.Now()
+	res := result{file: f}
+	res.err = s.tokenizeTo(ctx, f, &res)
+	res.elapsed = time.Since(start)
+	if s.state != nil && res.skipped == "" {
+		if res.err != nil {
+			_ = s.state.forget(f.rel)
+		} else if err := s.state.record(f, res.entities); err != nil {
+			res.err = fmt.Errorf("checkpoint: %w", err)
+		}
+	}
+	return res
+}
+
+// done reports whether f has a checkpoint and its copy is still in place.
+func (s *scrubber) done(f file) bool {
+	if s.state == nil || !s.state.done(f) {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(s.out, filepath.FromSlash(f.rel)))
+	return err == nil
+}
+
+func (s *scrubber) tokenizeTo(ctx context.Context, f file, res *result) error {
+	in, err := os.Open(filepath.Join(s.src, filepath.FromSlash(f.rel)))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// Binary files that slipped past the extension filter
+	head := make([]byte, 8<<10)
+	n, err := io.ReadFull(in, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	if bytes.IndexByte(head[:n], 0) >= 0 {
+		res.skipped = "binary content"
+		return nil
+	}
+
+	dst := filepath.Join(s.out, filepath.FromSlash(f.rel))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".scrub-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	os.Remove(dst) // an old copy must not outlive a failed run
+	r := stream.NewReader(ctx, s.bf, io.MultiReader(bytes.NewReader(head[:n]), in))
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("tokenize: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := writeMapping(filepath.Join(s.mappings, filepath.FromSlash(f.rel)+".json"), r.Mapping()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return err
+	}
+	res.entities = r.Counts()
+	return nil
+}
+
+// writeMapping stores a file's mapping with owner-only permissions. A file
+// without entities gets none.
+func writeMapping(path string, m map[string]string) error {
+	if len(m) == 0 {
+		os.Remove(path)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o600)
+}
+
+// progress prints one line per finished file and keeps the totals for
+// the summary.
+type progress struct {
+	mu          sync.Mutex
+	w           io.Writer
+	total       int
+	done        int
+	scrubbed    int
+	failed      []result
+	interrupted int
+	skipped     []skip
+	entities    map[string]int
+	bytes       int64
+	start       time.Time
+}
+
+func newProgress(w io.Writer, total int, skipped []skip) *progress {
+	return &progress{w: w, total: total, skipped: skipped, entities: map[string]int{}, start: time.Now()}
+}
+
+// expect adds n files to the total, for files found while watching.
+func (p *progress) expect(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total += n
+}
+
+// skip records a file passed over while watching.
+func (p *progress) skip(s skip) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.skipped = append(p.skipped, s)
+	fmt.Fprintf(p.w, "skip %s (%s)\n", s.rel, s.reason)
+}
+
+func (p *progress) report(r result) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	width := len(fmt.Sprint(p.total))
+	prefix := fmt.Sprintf("[%*d/%d]", width, p.done, p.total)
+	switch {
+	case errors.Is(r.err, context.Canceled):
+		p.interrupted++
+		fmt.Fprintf(p.w, "%s stop %s (interrupted)\n", prefix, r.rel)
+	case r.err != nil:
+		p.failed = append(p.failed, r)
+		fmt.Fprintf(p.w, "%s FAIL %s: %v\n", prefix, r.rel, r.err)
+	case r.skipped != "":
+		p.skipped = append(p.skipped, skip{r.rel, r.skipped})
+		fmt.Fprintf(p.w, "%s skip %s (%s)\n", prefix, r.rel, r.skipped)
+	default:
+		p.scrubbed++
+		p.bytes += r.size
+		n := 0
+		for typ, c := range r.entities {
+			p.entities[typ] += c
+			n += c
+		}
+		fmt.Fprintf(p.w, "%s ok   %s  %d entities  %s\n", prefix, r.rel, n, r.elapsed.Round(time.Millisecond))
+	}
+}
+
+// summary prints the totals: files by outcome, entities by type.
+func (p *progress) summary() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, "\nScrubbed %d of %d files (%s) in %s; %d skipped, %d failed\n",
+		p.scrubbed, p.total, formatBytes(p.bytes), time.Since(p.start).Round(time.Millisecond), len(p.skipped), len(p.failed))
+	if left := p.total - p.done + p.interrupted; left > 0 {
+		fmt.Fprintf(p.w, "%d files left for the next run\n", left)
+	}
+	if len(p.entities) > 0 {
+		types := make([]string, 0, len(p.entities))
+		for typ := range p.entities {
+			types = append(types, typ)
+		}
+		sort.Slice(types, func(i, j int) bool {
+			if p.entities[types[i]] != p.entities[types[j]] {
+				return p.entities[types[i]] > p.entities[types[j]]
+			}
+			return types[i] < types[j]
+		})
+		fmt.Fprintln(p.w, "Entities:")
+		for _, typ := range types {
+			fmt.Fprintf(p.w, "  %-24s %d\n", typ, p.entities[typ])
+		}
+	}
+	if len(p.skipped) > 0 {
+		fmt.Fprintln(p.w, "Skipped:")
+		for _, s := range p.skipped {
+			fmt.Fprintf(p.w, "  %s (%s)\n", s.rel, s.reason)
+		}
+	}
+	if len(p.failed) > 0 {
+		fmt.Fprintln(p.w, "Failed:")
+		for _, r := range p.failed {
+			fmt.Fprintf(p.w, "  %s: %v\n", r.rel, r.err)
+		}
+	}
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	filesBucket = []byte("files")
+	metaBucket  = []byte("meta")
+	settingsKey = []byte("settings")
+)
+
+// checkpoint is the record of one scrubbed file. A file whose size and
+// modification time still match is not scrubbed again.
+type checkpoint struct {
+	Size       int64          `json:"size"`
+	ModTime    time.Time      `json:"mod_time"`
+	Entities   map[string]int `json:"entities,omitempty"`
+	ScrubbedAt time.Time      `json:"scrubbed_at"`
+}
+
+// state is a run's checkpoint file: a bbolt database holding one record
+// per scrubbed file, written as each file completes, so an interrupted
+// run resumes where it stopped. It also holds the settings that decide a
+// copy's content, so a resumed run can't mix copies made under two
+// policies.
+type state struct {
+	db *bolt.DB
+}
+
+// openState opens or creates the state file at path. settings identifies
+// the policy in use; if the file was written with other settings it is
+// refused, unless restart is set, which discards every checkpoint.
+func openState(path, settings string, restart bool) (*state, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%s is in use by another run", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if restart {
+			for _, b := range [][]byte{filesBucket, metaBucket} {
+				if err := tx.DeleteBucket(b); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+					return err
+				}
+			}
+		}
+		files, err := tx.CreateBucketIfNotExists(filesBucket)
+		if err != nil {
+			return err
+		}
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		if prev := meta.Get(settingsKey); prev != nil && string(prev) != settings && files.Stats().KeyN > 0 {
+			return fmt.Errorf("%s was written with %s, not %s; rerun with -restart to scrub everything again", path, prev, settings)
+		}
+		return meta.Put(settingsKey, []byte(settings))
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &state{db: db}, nil
+}
+
+// done reports whether f was scrubbed and hasn't changed since.
+func (s *state) done(f file) bool {
+	var c checkpoint
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(filesBucket).Get([]byte(f.rel))
+		if data == nil {
+			return bolt.ErrBucketNotFound // any error: not done
+		}
+		return json.Unmarshal(data, &c)
+	})
+	return err == nil && c.Size == f.size && c.ModTime.Equal(f.modTime)
+}
+
+// record checkpoints a scrubbed file. Concurrent calls are batched into
+// one transaction, so workers don't queue behind each other's fsyncs.
+func (s *state) record(f file, entities map[string]int) error {
+	data, err := json.Marshal(checkpoint{Size: f.size, ModTime: f.modTime, Entities: entities, ScrubbedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	return s.db.Batch(func(tx *bolt.Tx) error {
+		return tx.Bucket(filesBucket).Put([]byte(f.rel), data)
+	})
+}
+
+// forget drops a file's checkpoint, for a file that failed or vanished.
+func (s *state) forget(rel string) error {
+	return s.db.Batch(func(tx *bolt.Tx) error {
+		return tx.Bucket(filesBucket).Delete([]byte(rel))
+	})
+}
+
+func (s *state) Close() error { return s.db.Close() }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	s, err := openState(path, "policy=strict", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := file{rel: "tickets/T-1.txt", size: 120, modTime: time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)}
+	if s.done(f) {
+		t.Fatal("done before it was recorded")
+	}
+	if err := s.record(f, map[string]int{"Email Address": 1}); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = openState(path, "policy=strict", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.done(f) {
+		t.Error("checkpoint lost across reopening")
+	}
+	changed := f
+	changed.modTime = changed.modTime.Add(time.Second)
+	if s.done(changed) {
+		t.Error("a modified file counts as done")
+	}
+	if err := s.forget(f.rel); err != nil || s.done(f) {
+		t.Errorf("forget: %v, still done %v", err, s.done(f))
+	}
+	if err := s.record(f, nil); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	if _, err := openState(path, "policy=basic", false); err == nil {
+		t.Error("state written under another policy was accepted")
+	}
+	s, err = openState(path, "policy=basic", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.done(f) {
+		t.Error("-restart kept a checkpoint")
+	}
+}
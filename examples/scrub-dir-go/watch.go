@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watcher feeds files that appear or change under the root to the
+// scrubbing workers. A file is only handed over once it has stopped
+// changing for the settle time, so a copy still being written into the
+// drop folder isn't scrubbed half-way.
+type watcher struct {
+	walker *walker
+	s      *scrubber
+	p      *progress
+	jobs   chan<- file
+	settle time.Duration
+
+	fs      *fsnotify.Watcher
+	pending map[string]pendingFile // rel → last seen state
+}
+
+type pendingFile struct {
+	seen    time.Time // last event or change
+	size    int64
+	modTime time.Time
+}
+
+// watch runs until ctx is done. New directories are watched as they
+// appear, along with whatever was created in them before the watch took
+// hold. Deleting a source file leaves its copy in place: it may already
+// have been picked up downstream.
+func (w *watcher) watch(ctx context.Context) error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+	w.fs, w.pending = fw, make(map[string]pendingFile)
+	if err := w.addTree(w.walker.root); err != nil {
+		return err
+	}
+
+	tick := time.NewTicker(w.settle / 4)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-fw.Errors:
+			log.Printf("watch: %v", err) // e.g. the kernel's event queue overflowed
+		case ev := <-fw.Events:
+			w.event(ev)
+		case <-tick.C:
+			w.dispatch(ctx)
+		}
+	}
+}
+
+// addTree watches dir and every directory under it that isn't excluded,
+// and queues the files already there.
+func (w *watcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil // gone again before we got to it
+			}
+			return err
+		}
+		rel, err := w.rel(path)
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			w.touch(rel)
+			return nil
+		}
+		if rel != "." && w.walker.skipDir(path, rel) {
+			return filepath.SkipDir
+		}
+		return w.fs.Add(path)
+	})
+}
+
+func (w *watcher) rel(path string) (string, error) {
+	rel, err := filepath.Rel(w.walker.root, path)
+	return filepath.ToSlash(rel), err
+}
+
+func (w *watcher) event(ev fsnotify.Event) {
+	rel, err := w.rel(ev.Name)
+	if err != nil {
+		return
+	}
+	switch {
+	case ev.Has(fsnotify.Create):
+		info, err := os.Lstat(ev.Name)
+		if err != nil {
+			return
+		}
+		if info.IsDir() {
+			if !w.walker.skipDir(ev.Name, rel) {
+				if err := w.addTree(ev.Name); err != nil {
+					log.Printf("watch %s: %v", rel, err)
+				}
+			}
+			return
+		}
+		w.touch(rel)
+	case ev.Has(fsnotify.Write):
+		w.touch(rel)
+	case ev.Has(fsnotify.Remove), ev.Has(fsnotify.Rename):
+		delete(w.pending, rel) // a rename shows up again as a Create of the new name
+	}
+}
+
+// touch marks rel as changed just now.
+func (w *watcher) touch(rel string) {
+	p := pendingFile{seen: time.Now()}
+	if info, err := os.Lstat(filepath.Join(w.walker.root, filepath.FromSlash(rel))); err == nil {
+		p.size, p.modTime = info.Size(), info.ModTime()
+	}
+	w.pending[rel] = p
+}
+
+// dispatch hands over the files that have settled: no event and no change
+// in size or modification time for the settle time.
+func (w *watcher) dispatch(ctx context.Context) {
+	now := time.Now()
+	for rel, p := range w.pending {
+		if now.Sub(p.seen) < w.settle {
+			continue
+		}
+		info, err := os.Lstat(filepath.Join(w.walker.root, filepath.FromSlash(rel)))
+		if err != nil {
+			delete(w.pending, rel)
+			continue
+		}
+		if info.Size() != p.size || !info.ModTime().Equal(p.modTime) {
+			// Still being written without events (some network mounts)
+			w.pending[rel] = pendingFile{seen: now, size: info.Size(), modTime: info.ModTime()}
+			continue
+		}
+		delete(w.pending, rel)
+		f, reason, ok := w.walker.accept(rel, info)
+		if !ok {
+			if reason != "" {
+				w.p.skip(skip{rel, reason})
+			}
+			continue
+		}
+		if w.s.done(f) {
+			continue // an event without a change, e.g. a touch of the atime
+		}
+		w.p.expect(1)
+		select {
+		case w.jobs <- f:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/semcache"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+// newFakeAPI stands in for the chat completions and embeddings
+// endpoints. Answers are scripted by topic and reuse the prompt's tokens
+// and order numbers; embeddings hash words with semcache.Words, which is
+// enough to match a close rewording.
+func newFakeAPI() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/embeddings", fakeEmbeddings)
+	mux.Handle("/v1/chat/completions", fakeopenai.Handler(fakeModel))
+	return httptest.NewServer(mux)
+}
+
+var (
+	emailToken = regexp.MustCompile(`<Email Address_\d+>`)
+	orderNo    = regexp.MustCompile(`order \d+`)
+)
+
+func fakeEmbeddings(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Input []string `json:"input"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	res := openai.EmbeddingResponse{Object: "list"}
+	for i, in := range req.Input {
+		v, _ := semcache.Words(512).Embed(context.Background(), in)
+		res.Data = append(res.Data, openai.Embedding{Object: "embedding", Index: i, Embedding: v})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func fakeModel(req openai.ChatCompletionRequest) string {
+	q := fakeopenai.Prompt(req)
+	email := emailToken.FindString(q)
+	switch {
+	case strings.Contains(q, "invoice"):
+		return "Done: the invoice for " + orderNo.FindString(q) + " has been resent to " + email + "."
+	case strings.Contains(q, "password"):
+		return "A password reset link is on its way to " + email + ". It expires in 30 minutes."
+	case strings.Contains(q, "cancel"):
+		return "Your subscription is cancelled at the end of the billing period. A confirmation has been sent to " + email + "."
+	case strings.Contains(q, "hours"):
+		return "On weekends we're open 10:00–16:00 (chat and phone)."
+	}
+	return "Thanks for reaching out; an agent will follow up shortly."
+}
@@ -0,0 +1,155 @@
+// Semantic cache + Blindfold: Answer the same question about different
+// customers once.
+//
+// Each question is tokenized, so "resend the invoice to jane@…" and
+// "resend the invoice to bob@…" both read "… to <Email Address_1>". The
+// cache (pkg/semcache) is keyed on that tokenized prompt: an exact match
+// is found without an embedding call, and a reworded one by embedding
+// similarity. The cached answer is a template in tokens, rewritten into
+// the new request's tokens and detokenized with that request's own
+// mapping, so every customer gets their own values back and the cache
+// holds no PII at all.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/semcache"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+const systemPrompt = "You are a customer support assistant. Answer in one or two sentences. " +
+	"Values like <Email Address_1> are placeholders for customer data: copy them exactly as written."
+
+// protect tokenizes a question. Local mode gives each occurrence its own
+// token; folding repeats of a value into one means the same question
+// always tokenizes the same way.
+func protect(ctx context.Context, bf bfclient.Client, text string) (string, map[string]string, error) {
+	res, err := bf.Tokenize(ctx, text)
+	if err != nil {
+		return "", nil, err
+	}
+	tokenized, m := mapping.Fold(res.Text, res.Mapping)
+	return tokenized, m, nil
+}
+
+func ask(ctx context.Context, oa *openai.Client, model, question string) (string, error) {
+	res, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: question},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(res.Choices) == 0 {
+		return "", errors.New("empty response")
+	}
+	return strings.TrimSpace(res.Choices[0].Message.Content), nil
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, sc.Err()
+}
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "", "policy file (YAML or JSON); empty = built-in policies only")
+	name := flag.String("policy", "strict", "policy to apply, by name")
+	questions := flag.String("questions", "questions.txt", "incoming questions, one per line")
+	model := flag.String("model", openai.GPT4oMini, "chat model")
+	threshold := flag.Float64("threshold", 0.9, "least cosine similarity for a cache hit")
+	demo := flag.Bool("demo", false, "use a scripted in-process model instead of OpenAI")
+	flag.Parse()
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+
+	cfg := openai.DefaultConfig(os.Getenv("OPENAI_API_KEY"))
+	if *demo {
+		srv := newFakeAPI()
+		defer srv.Close()
+		cfg = fakeopenai.Config(srv)
+	} else if os.Getenv("OPENAI_API_KEY") == "" {
+		log.Fatal("OPENAI_API_KEY is required (or run with -demo)")
+	}
+	oa := openai.NewClientWithConfig(cfg)
+	cache := semcache.New(semcache.OpenAI(oa, openai.SmallEmbedding3), *threshold)
+
+	lines, err := readLines(*questions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	calls := 0
+	for i, q := range lines {
+		tokenized, m, err := protect(ctx, bf, q)
+		if err != nil {
+			log.Fatalf("tokenize: %v", err)
+		}
+		exact := cache.Stats().Exact
+		answer, ok, err := cache.Lookup(ctx, tokenized)
+		if err != nil {
+			log.Fatal(err)
+		}
+		status := "HIT (similar)"
+		if cache.Stats().Exact > exact {
+			status = "HIT (exact)"
+		}
+		if !ok {
+			status = "MISS"
+			calls++
+			if answer, err = ask(ctx, oa, *model, tokenized); err != nil {
+				log.Fatal(err)
+			}
+			if stored, err := cache.Store(ctx, tokenized, answer); err != nil {
+				log.Fatal(err)
+			} else if !stored {
+				status = "MISS (not cached: answer uses tokens the question doesn't have)"
+			}
+		}
+		if bad := mapping.Unresolved(answer, m); len(bad) > 0 {
+			log.Fatalf("answer has tokens the question never had: %s", strings.Join(bad, ", "))
+		}
+		fmt.Printf("%2d %s  %s\n   cache key: %s\n   answer:    %s\n\n", i+1, status, q, tokenized, mapping.Detokenize(answer, m))
+	}
+
+	s := cache.Stats()
+	fmt.Printf("%d questions: %d hits (%d exact, no embedding call), %d misses; %d model calls, %d cached templates\n",
+		len(lines), s.Hits, s.Exact, s.Misses, calls, s.Entries)
+}
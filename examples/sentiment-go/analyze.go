@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// topics is the fixed list reviews are tagged with, so they can be
+// counted across months.
+var topics = []string{"delivery", "product quality", "support", "price", "billing", "app"}
+
+var systemPrompt = "You analyze the sentiment of one customer review for the product team. Reply with a JSON object: " +
+	`{"sentiment": "positive, neutral or negative", "score": a number from -1 (very negative) to 1 (very positive), ` +
+	`"topics": the topics the review is about, from: ` + strings.Join(topics, ", ") + "}. " +
+	"Values like <Person_1> are placeholders for real data; they don't affect sentiment."
+
+// Sentiment is the model's reading of one review.
+type Sentiment struct {
+	Label  string   `json:"sentiment"`
+	Score  float64  `json:"score"`
+	Topics []string `json:"topics"`
+}
+
+// analyze asks the model for the sentiment of one tokenized review.
+func analyze(ctx context.Context, oa *openai.Client, model, review string) (*Sentiment, error) {
+	res, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:          model,
+		Temperature:    0,
+		ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: review},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Choices) == 0 {
+		return nil, fmt.Errorf("empty response")
+	}
+	var s Sentiment
+	if err := json.Unmarshal([]byte(res.Choices[0].Message.Content), &s); err != nil {
+		return nil, fmt.Errorf("bad JSON from model: %w", err)
+	}
+	switch s.Label {
+	case "positive", "neutral", "negative":
+	default:
+		return nil, fmt.Errorf("unknown sentiment %q", s.Label)
+	}
+	if s.Score < -1 || s.Score > 1 {
+		return nil, fmt.Errorf("score %v out of range", s.Score)
+	}
+	known := s.Topics[:0]
+	for _, t := range s.Topics {
+		if t = strings.ToLower(strings.TrimSpace(t)); contains(topics, t) {
+			known = append(known, t)
+		}
+	}
+	s.Topics = known
+	return &s, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+var (
+	demoGood = []string{"great", "fast", "happy", "better", "recommend", "works", "sorted out", "on time", "fine", "would buy again"}
+	demoBad  = []string{"late", "disappointed", "billed twice", "broke", "cancelling", "logs me out", "logging me out", "nobody",
+		"went up", "three calls", "reinstall"}
+	demoTopics = map[string][]string{
+		"delivery":        {"delivery", "arrived", "package"},
+		"product quality": {"quality", "kettle", "blender", "product", "broke"},
+		"support":         {"support", "replied", "calls back", "three calls"},
+		"price":           {"price"},
+		"billing":         {"billed", "refund", "card"},
+		"app":             {"app", "logs me out", "logging me out"},
+	}
+)
+
+// fakeModel answers chat completions with a keyword lexicon: good and
+// bad phrases set the score, and whole topic words set the topics. It
+// reads the review as the real model would, tokens and all.
+func fakeModel(req openai.ChatCompletionRequest) string {
+	text := strings.ToLower(fakeopenai.Prompt(req))
+	var good, bad float64
+	for _, p := range demoGood {
+		good += float64(strings.Count(text, p))
+	}
+	for _, p := range demoBad {
+		bad += float64(strings.Count(text, p))
+	}
+	s := Sentiment{Label: "neutral", Score: math.Round((good-bad)/(good+bad+1)*100) / 100, Topics: []string{}}
+	switch {
+	case s.Score > 0.3:
+		s.Label = "positive"
+	case s.Score < -0.3:
+		s.Label = "negative"
+	}
+	for _, t := range topics {
+		for _, word := range demoTopics[t] {
+			if regexp.MustCompile(`\b` + word + `\b`).MatchString(text) {
+				s.Topics = append(s.Topics, t)
+				break
+			}
+		}
+	}
+	out, _ := json.Marshal(s)
+	return string(out)
+}
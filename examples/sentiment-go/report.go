@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Result is one analyzed review. Customer is the reviewer's token and
+// Text the tokenized review; nothing in it says who wrote it.
+type Result struct {
+	ID        string   `json:"id"`
+	Date      string   `json:"date"`
+	Customer  string   `json:"customer"`
+	NPS       int      `json:"nps"`
+	Sentiment string   `json:"sentiment"`
+	Score     float64  `json:"score"`
+	Topics    []string `json:"topics"`
+	Text      string   `json:"text"`
+}
+
+// category is the NPS bucket of a 0–10 answer.
+func category(nps int) string {
+	switch {
+	case nps >= 9:
+		return "promoter"
+	case nps >= 7:
+		return "passive"
+	default:
+		return "detractor"
+	}
+}
+
+// npsScore is the share of promoters minus the share of detractors, from
+// -100 to 100.
+func npsScore(answers []int) int {
+	if len(answers) == 0 {
+		return 0
+	}
+	var promoters, detractors int
+	for _, a := range answers {
+		switch category(a) {
+		case "promoter":
+			promoters++
+		case "detractor":
+			detractors++
+		}
+	}
+	return (promoters - detractors) * 100 / len(answers)
+}
+
+// Customer is the trend for one tokenized customer.
+type Customer struct {
+	Customer  string         `json:"customer"`
+	Reviews   int            `json:"reviews"`
+	MeanScore float64        `json:"mean_score"`
+	Trend     float64        `json:"trend"` // last score minus first
+	FirstNPS  int            `json:"first_nps"`
+	LatestNPS int            `json:"latest_nps"`
+	Topics    map[string]int `json:"topics"`
+}
+
+// Month sums one calendar month.
+type Month struct {
+	Month     string         `json:"month"`
+	Reviews   int            `json:"reviews"`
+	NPS       int            `json:"nps"`
+	MeanScore float64        `json:"mean_score"`
+	Negative  map[string]int `json:"negative_topics"` // topics of negative reviews
+}
+
+// Report is what the export holds.
+type Report struct {
+	Reviews   []Result   `json:"reviews"`
+	Customers []Customer `json:"customers"`
+	Months    []Month    `json:"months"`
+	NPS       int        `json:"nps"`
+	// Mismatched are reviews whose text and score disagree: a promoter
+	// writing a negative review, or a detractor a positive one.
+	Mismatched []string `json:"mismatched,omitempty"`
+}
+
+// aggregate builds the per-customer and per-month views. results must be
+// sorted by date.
+func aggregate(results []Result) *Report {
+	r := &Report{Reviews: results}
+	byCustomer := make(map[string][]Result)
+	byMonth := make(map[string][]Result)
+	var order, months []string
+	var answers []int
+	for _, res := range results {
+		if byCustomer[res.Customer] == nil {
+			order = append(order, res.Customer)
+		}
+		byCustomer[res.Customer] = append(byCustomer[res.Customer], res)
+		m := res.Date[:7]
+		if byMonth[m] == nil {
+			months = append(months, m)
+		}
+		byMonth[m] = append(byMonth[m], res)
+		answers = append(answers, res.NPS)
+		if c := category(res.NPS); c == "promoter" && res.Sentiment == "negative" || c == "detractor" && res.Sentiment == "positive" {
+			r.Mismatched = append(r.Mismatched, res.ID)
+		}
+	}
+	r.NPS = npsScore(answers)
+
+	for _, c := range order {
+		rs := byCustomer[c]
+		cu := Customer{Customer: c, Reviews: len(rs), Topics: make(map[string]int),
+			Trend: rs[len(rs)-1].Score - rs[0].Score, FirstNPS: rs[0].NPS, LatestNPS: rs[len(rs)-1].NPS}
+		for _, res := range rs {
+			cu.MeanScore += res.Score / float64(len(rs))
+			for _, t := range res.Topics {
+				cu.Topics[t]++
+			}
+		}
+		r.Customers = append(r.Customers, cu)
+	}
+	for _, m := range months {
+		rs := byMonth[m]
+		mo := Month{Month: m, Reviews: len(rs), Negative: make(map[string]int)}
+		var answers []int
+		for _, res := range rs {
+			answers = append(answers, res.NPS)
+			mo.MeanScore += res.Score / float64(len(rs))
+			if res.Sentiment == "negative" {
+				for _, t := range res.Topics {
+					mo.Negative[t]++
+				}
+			}
+		}
+		mo.NPS = npsScore(answers)
+		r.Months = append(r.Months, mo)
+	}
+	return r
+}
+
+// top lists counts as "topic ×n", most frequent first.
+func top(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s ×%d", k, counts[k])
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// trend describes a change in score.
+func trend(d float64) string {
+	switch {
+	case d >= 0.3:
+		return fmt.Sprintf("↑ %+.2f", d)
+	case d <= -0.3:
+		return fmt.Sprintf("↓ %+.2f", d)
+	default:
+		return fmt.Sprintf("→ %+.2f", d)
+	}
+}
+
+func (r *Report) print(w io.Writer) {
+	fmt.Fprintf(w, "\n%d reviews from %d customers, NPS %+d\n\n", len(r.Reviews), len(r.Customers), r.NPS)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MONTH\tREVIEWS\tNPS\tMEAN SCORE\tNEGATIVE ABOUT")
+	for _, m := range r.Months {
+		fmt.Fprintf(tw, "%s\t%d\t%+d\t%+.2f\t%s\n", m.Month, m.Reviews, m.NPS, m.MeanScore, top(m.Negative))
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CUSTOMER\tREVIEWS\tMEAN SCORE\tTREND\tNPS\tTOPICS")
+	for _, c := range r.Customers {
+		fmt.Fprintf(tw, "%s\t%d\t%+.2f\t%s\t%d → %d\t%s\n", c.Customer, c.Reviews, c.MeanScore, trend(c.Trend), c.FirstNPS, c.LatestNPS, top(c.Topics))
+	}
+	tw.Flush()
+	if len(r.Mismatched) > 0 {
+		fmt.Fprintf(w, "\nScore and text disagree: %s\n", strings.Join(r.Mismatched, ", "))
+	}
+}
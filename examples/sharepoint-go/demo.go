@@ -0,0 +1,464 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+const (
+	demoSite   = "contoso.sharepoint.com:/sites/CustomerSuccess"
+	demoFolder = "Q3"
+	demoTenant = "contoso.onmicrosoft.com"
+
+	demoSiteID  = "contoso.sharepoint.com,6c1f2a8e-36b5-4c6e-9a5b-1f0c8d2e7a41,0e3b5d7c-9f21-4d8a-b6c4-2a7e9f1d3b58"
+	demoDriveID = "b!jioPbLU2bkyaWx8MjS56QXxdOw4hn4pNtsQqfp8dO1hDemoDocs"
+	demoRootID  = "01DEMOROOT"
+	// demoTokenUses is how many Graph calls an access token lasts, so the
+	// demo shows the client fetching a token Graph turned down
+	demoTokenUses = 12
+)
+
+// fakeGraph stands in for Entra ID's token endpoint and the parts of
+// Microsoft Graph the recipe calls, for one site with one document
+// library. Every edit is appended to a change log, and a delta token is
+// a position in it. The first folder listing is throttled once.
+type fakeGraph struct {
+	mu        sync.Mutex
+	items     map[string]*fakeItem
+	log       []string // item IDs, one per change
+	token     string
+	uses      int
+	issued    int
+	throttled bool
+	now       time.Time
+}
+
+type fakeItem struct {
+	Item
+	content []byte
+	fields  map[string]string
+}
+
+// newFakeGraph starts the fake and returns a client for it that holds
+// app credentials, as the client credentials flow would.
+func newFakeGraph() (*fakeGraph, *graph, func()) {
+	fg := &fakeGraph{items: make(map[string]*fakeItem), now: time.Date(2026, 10, 13, 9, 0, 0, 0, time.UTC)}
+	fg.add("01DEMOQ3", "Q3", demoRootID, nil)
+	fg.add("01DEMOARCHIVE", "Archive", "01DEMOQ3", nil)
+	fg.add("01DEMOESCALATIONS", "Q3 customer escalations.docx", "01DEMOQ3", mustDocx("Q3 customer escalations", escalations))
+	fg.add("01DEMORENEWALS", "Renewals tracker.xlsx", "01DEMOQ3", demoXLSX([]demoSheet{
+		{"Renewals", [][]any{
+			{"Customer", "Contact", "Email", "Phone", "Renewal date", "ARR"},
+			{"Northwind", "Priya Patel", "priya@northwind.example", "212-555-0111", "2026-11-01", 48000},
+			{"Contoso", "Aisha Khan", "aisha.khan@contoso.example", "(415) 555-0199", "2026-11-15", 120000},
+			{"Fabrikam", "Omar Haddad", "omar.haddad@example.com", "+1 415 555 0142", "2026-12-01", 36000},
+		}},
+		{"Churn risk", [][]any{
+			{"Customer", "Owner", "Risk", "Note"},
+			{"Fabrikam", "Li Wei", "High", nil, "Budget cut; call li.wei@example.com before 1 Nov"},
+		}},
+	}))
+	fg.add("01DEMONOTES", "onboarding-call-notes.txt", "01DEMOQ3", []byte("Onboarding call with Northwind, 9 Oct. Priya Patel wants SSO "+
+		"before rollout and asked for the admin guide at priya@northwind.example. Next call 16 Oct.\n"))
+	fg.add("01DEMOCONTRACT", "Signed contract.pdf", "01DEMOQ3", []byte("%PDF-1.7"))
+	fg.add("01DEMOOFFSITE", "Team offsite plan.docx", demoRootID, mustDocx("Team offsite", "Offsite in Lisbon, 3-5 Nov."))
+
+	srv := httptest.NewServer(fg)
+	hc := srv.Client()
+	return fg, &graph{base: srv.URL + "/v1.0", http: hc,
+		tokens: clientCredentials(hc, srv.URL, demoTenant, "3f2b7c1e-demo-app", "demo-secret")}, srv.Close
+}
+
+const escalations = "1. Double charge, order 5531. Jane Doe (jane.doe@example.com) was charged twice on card 4111 1111 1111 1111. " +
+	"Refund approved by billing on 12 Sept; Jane wants written confirmation.\n" +
+	"2. Locked account. Omar Haddad could not sign in after a password reset for three days. Unlocked by support; " +
+	"root cause is the lockout rule, ticket open with the identity team.\n" +
+	"3. Damaged delivery, order 7710. Li Wei sent photos of a dented kettle. Replacement shipped; " +
+	"courier claim still open. Call back on 617-555-0123 when the claim is settled."
+
+func mustDocx(title, text string) []byte {
+	data, err := docxFile(title, text)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// add creates or replaces an item; nil content makes a folder.
+func (fg *fakeGraph) add(id, name, parent string, content []byte) *fakeItem {
+	fg.now = fg.now.Add(47 * time.Minute)
+	it := &fakeItem{Item: Item{ID: id, Name: name, LastModified: fg.now.Format(time.RFC3339)}, content: content, fields: map[string]string{}}
+	it.ParentReference.DriveID, it.ParentReference.ID = demoDriveID, parent
+	if content == nil {
+		it.Folder = &struct{}{}
+	} else {
+		fg.setContent(it, content)
+	}
+	fg.items[id] = it
+	fg.log = append(fg.log, id)
+	return it
+}
+
+// setContent stores content and updates what Graph derives from it. The
+// hash stands in for a quickXorHash.
+func (fg *fakeGraph) setContent(it *fakeItem, content []byte) {
+	sum := sha1.Sum(content)
+	it.content, it.Size = content, int64(len(content))
+	it.File = &struct {
+		MimeType string `json:"mimeType"`
+		Hashes   struct {
+			QuickXorHash string `json:"quickXorHash"`
+		} `json:"hashes"`
+	}{MimeType: mimeTypes[strings.ToLower(it.Name[strings.LastIndex(it.Name, "."):])]}
+	it.File.Hashes.QuickXorHash = base64.StdEncoding.EncodeToString(sum[:15])
+	it.CTag = fmt.Sprintf(`"c:{%s},%d"`, it.ID, len(fg.log)+1)
+}
+
+var mimeTypes = map[string]string{
+	".docx": docxMIME,
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".txt":  "text/plain",
+	".pdf":  "application/pdf",
+}
+
+// edit changes the library between the demo's two runs, and describes
+// what it did.
+func (fg *fakeGraph) edit() string {
+	fg.mu.Lock()
+	defer fg.mu.Unlock()
+	fg.now = time.Date(2026, 10, 14, 8, 30, 0, 0, time.UTC)
+	f := fg.items["01DEMOESCALATIONS"]
+	fg.setContent(f, mustDocx("Q3 customer escalations", escalations+"\n4. Wrong invoice address. Priya Patel (priya@northwind.example) "+
+		"asked for invoice 2291 to be reissued to the Boston office. Reissued today."))
+	f.LastModified = fg.now.Format(time.RFC3339)
+	fg.log = append(fg.log, f.ID)
+	fg.items["01DEMONOTES"].Deleted = &struct {
+		State string `json:"state"`
+	}{"deleted"}
+	fg.log = append(fg.log, "01DEMONOTES")
+	fg.add("01DEMOOFFSITE", "Team offsite plan.docx", demoRootID, mustDocx("Team offsite", "Offsite in Lisbon, 3-5 Nov. Bring laptops."))
+	return "adds an escalation to Q3 customer escalations.docx, deletes onboarding-call-notes.txt " +
+		"and edits a document in another folder"
+}
+
+func (fg *fakeGraph) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fg.mu.Lock()
+	defer fg.mu.Unlock()
+	if r.URL.Path == "/"+demoTenant+"/oauth2/v2.0/token" {
+		if r.FormValue("grant_type") != "client_credentials" || r.FormValue("client_secret") != "demo-secret" || r.FormValue("scope") != graphScope {
+			fg.json(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client",
+				"error_description": "AADSTS7000215: Invalid client secret provided.\r\nTrace ID: demo"})
+			return
+		}
+		fg.issued++
+		fg.token, fg.uses = fmt.Sprintf("eyJ0eXAiOiJKV1Qi.demo-%d", fg.issued), 0
+		fg.json(w, http.StatusOK, map[string]any{"token_type": "Bearer", "expires_in": 3599, "access_token": fg.token})
+		return
+	}
+	// Download URLs are pre-authenticated, like the real ones
+	if id, ok := strings.CutPrefix(r.URL.Path, "/download/"); ok && r.URL.Query().Get("tempauth") == "demo" {
+		w.Write(fg.items[id].content)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+fg.token || fg.uses >= demoTokenUses {
+		fg.fail(w, http.StatusUnauthorized, "InvalidAuthenticationToken", "Access token has expired or is not yet valid.")
+		return
+	}
+	fg.uses++
+
+	p := strings.TrimPrefix(r.URL.Path, "/v1.0")
+	drive := "/drives/" + demoDriveID
+	switch {
+	case r.Method == http.MethodGet && p == "/sites/contoso.sharepoint.com:/sites/CustomerSuccess":
+		fg.json(w, http.StatusOK, map[string]string{"id": demoSiteID, "displayName": "Customer Success"})
+	case r.Method == http.MethodGet && p == "/sites/"+demoSiteID+"/drive":
+		fg.json(w, http.StatusOK, map[string]string{"id": demoDriveID})
+	case r.Method == http.MethodGet && p == "/sites/"+demoSiteID+"/drives":
+		fg.json(w, http.StatusOK, map[string]any{"value": []map[string]string{{"id": demoDriveID, "name": "Documents"}}})
+	case r.Method == http.MethodGet && strings.HasPrefix(p, drive+"/root:/"):
+		fg.byPath(w, strings.TrimPrefix(p, drive+"/root:/"))
+	case r.Method == http.MethodGet && p == drive+"/root/delta":
+		fg.delta(w, r)
+	case strings.HasPrefix(p, drive+"/items/"):
+		fg.item(w, r, strings.TrimPrefix(p, drive+"/items/"))
+	default:
+		fg.fail(w, http.StatusNotFound, "itemNotFound", "The resource could not be found.")
+	}
+}
+
+func (fg *fakeGraph) byPath(w http.ResponseWriter, p string) {
+	parent := demoRootID
+	var found *fakeItem
+	for _, name := range strings.Split(strings.Trim(p, "/"), "/") {
+		found = nil
+		for _, it := range fg.items {
+			if it.ParentReference.ID == parent && it.Name == name && it.Deleted == nil {
+				found = it
+			}
+		}
+		if found == nil {
+			fg.fail(w, http.StatusNotFound, "itemNotFound", "The resource could not be found.")
+			return
+		}
+		parent = found.ID
+	}
+	fg.json(w, http.StatusOK, found.Item)
+}
+
+// delta answers the delta feed, three changes a page. token=latest gives
+// a link for changes from now on.
+func (fg *fakeGraph) delta(w http.ResponseWriter, r *http.Request) {
+	link := func(token int) string {
+		return "http://" + r.Host + "/v1.0/drives/" + demoDriveID + "/root/delta?token=" + strconv.Itoa(token)
+	}
+	t := r.URL.Query().Get("token")
+	if t == "latest" {
+		fg.json(w, http.StatusOK, map[string]any{"value": []Item{}, "@odata.deltaLink": link(len(fg.log))})
+		return
+	}
+	from, err := strconv.Atoi(t)
+	if err != nil || from < 0 || from > len(fg.log) {
+		fg.fail(w, http.StatusGone, "resyncRequired", "Resync required. Replace any local items with the server's version.")
+		return
+	}
+	to := min(from+3, len(fg.log))
+	var items []Item
+	for _, id := range fg.log[from:to] {
+		it := fg.items[id].Item
+		if it.Deleted != nil {
+			it = Item{ID: it.ID, Deleted: it.Deleted, ParentReference: it.ParentReference}
+		}
+		items = append(items, it)
+	}
+	page := map[string]any{"value": items}
+	if to < len(fg.log) {
+		page["@odata.nextLink"] = link(to)
+	} else {
+		page["@odata.deltaLink"] = link(to)
+	}
+	fg.json(w, http.StatusOK, page)
+}
+
+// item answers children, content, uploads and list item fields.
+func (fg *fakeGraph) item(w http.ResponseWriter, r *http.Request, rest string) {
+	// Upload by name: {parent-id}:/{name}:/content
+	if parent, tail, ok := strings.Cut(rest, ":/"); ok && r.Method == http.MethodPut {
+		name, ok := strings.CutSuffix(tail, ":/content")
+		if !ok || fg.items[parent] == nil || fg.items[parent].Folder == nil {
+			fg.fail(w, http.StatusBadRequest, "invalidRequest", "Invalid request")
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		for _, it := range fg.items {
+			if it.ParentReference.ID == parent && it.Name == name {
+				fg.replace(it, body)
+				fg.json(w, http.StatusOK, it.Item)
+				return
+			}
+		}
+		id := fmt.Sprintf("01DEMOSUMMARY%d", len(fg.log))
+		fg.json(w, http.StatusCreated, fg.add(id, name, parent, body).Item)
+		return
+	}
+	id, sub, _ := strings.Cut(rest, "/")
+	it := fg.items[id]
+	if it == nil || it.Deleted != nil {
+		fg.fail(w, http.StatusNotFound, "itemNotFound", "The resource could not be found.")
+		return
+	}
+	switch {
+	case sub == "children" && r.Method == http.MethodGet:
+		if !fg.throttled {
+			fg.throttled = true
+			w.Header().Set("Retry-After", "1")
+			fg.fail(w, http.StatusTooManyRequests, "activityLimitReached", "The request has been throttled")
+			return
+		}
+		fg.children(w, r, id)
+	case sub == "content" && r.Method == http.MethodGet:
+		http.Redirect(w, r, "/download/"+id+"?tempauth=demo", http.StatusFound)
+	case sub == "content" && r.Method == http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		fg.replace(it, body)
+		fg.json(w, http.StatusOK, it.Item)
+	case sub == "listItem/fields" && r.Method == http.MethodPatch:
+		var fields map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+			fg.fail(w, http.StatusBadRequest, "invalidRequest", "Invalid request")
+			return
+		}
+		for k, v := range fields {
+			if k != "BlindfoldSummary" {
+				fg.fail(w, http.StatusBadRequest, "invalidRequest", "Field '"+k+"' is not recognized")
+				return
+			}
+			it.fields[k] = v
+		}
+		// Metadata changes show in the delta feed, with the same content
+		it.LastModified = fg.now.Format(time.RFC3339)
+		fg.log = append(fg.log, id)
+		fg.json(w, http.StatusOK, it.fields)
+	default:
+		fg.fail(w, http.StatusBadRequest, "invalidRequest", "Not supported by the demo site.")
+	}
+}
+
+func (fg *fakeGraph) replace(it *fakeItem, body []byte) {
+	fg.now = fg.now.Add(time.Minute)
+	fg.setContent(it, body)
+	it.LastModified = fg.now.Format(time.RFC3339)
+	fg.log = append(fg.log, it.ID)
+}
+
+// children lists a folder, two items a page.
+func (fg *fakeGraph) children(w http.ResponseWriter, r *http.Request, id string) {
+	var items []Item
+	for _, it := range fg.items {
+		if it.ParentReference.ID == id && it.Deleted == nil {
+			items = append(items, it.Item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	from, _ := strconv.Atoi(r.URL.Query().Get("$skiptoken"))
+	to := min(from+2, len(items))
+	page := map[string]any{"value": items[from:to]}
+	if to < len(items) {
+		next := url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path, RawQuery: "$top=200&$skiptoken=" + strconv.Itoa(to)}
+		page["@odata.nextLink"] = next.String()
+	}
+	fg.json(w, http.StatusOK, page)
+}
+
+func (fg *fakeGraph) fail(w http.ResponseWriter, status int, code, msg string) {
+	fg.json(w, status, map[string]any{"error": map[string]any{"code": code, "message": msg,
+		"innerError": map[string]string{"request-id": "demo", "date": time.Now().UTC().Format(time.RFC3339)}}})
+}
+
+func (fg *fakeGraph) json(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// demoSheet is one sheet of a demo workbook: rows of strings, numbers or
+// nil for an empty cell.
+type demoSheet struct {
+	name string
+	rows [][]any
+}
+
+// demoXLSX builds a workbook the way Excel stores one: strings in a
+// shared table, cells that are empty left out.
+func demoXLSX(sheets []demoSheet) []byte {
+	var strs []string
+	index := map[string]int{}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(name, data string) {
+		w, _ := zw.Create(name)
+		io.WriteString(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n"+data)
+	}
+	esc := func(s string) string {
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	var list, rels string
+	for i, s := range sheets {
+		var data strings.Builder
+		for r, row := range s.rows {
+			fmt.Fprintf(&data, `<row r="%d">`, r+1)
+			for c, v := range row {
+				ref := fmt.Sprintf("%c%d", 'A'+c, r+1)
+				switch v := v.(type) {
+				case string:
+					if _, ok := index[v]; !ok {
+						index[v] = len(strs)
+						strs = append(strs, v)
+					}
+					fmt.Fprintf(&data, `<c r="%s" t="s"><v>%d</v></c>`, ref, index[v])
+				case int:
+					fmt.Fprintf(&data, `<c r="%s"><v>%d</v></c>`, ref, v)
+				}
+			}
+			data.WriteString("</row>")
+		}
+		write(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`+
+			data.String()+`</sheetData></worksheet>`)
+		list += fmt.Sprintf(`<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, esc(s.name), i+1, i+1)
+		rels += fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	write("xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" `+
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`+list+`</sheets></workbook>`)
+	write("xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+rels+`</Relationships>`)
+	var shared strings.Builder
+	for _, s := range strs {
+		shared.WriteString("<si><t>" + esc(s) + "</t></si>")
+	}
+	write("xl/sharedStrings.xml", fmt.Sprintf(`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="%d">%s</sst>`, len(strs), shared.String()))
+	zw.Close()
+	return buf.Bytes()
+}
+
+var demoToken = regexp.MustCompile(`<(Person|Email Address|Phone Number|Credit Card Number)_\d+>`)
+
+// fakeModel answers chat completions with summaries scripted by file
+// name. Like a real model it only uses tokens from its input.
+func fakeModel(req openai.ChatCompletionRequest) string {
+	in := fakeopenai.Prompt(req)
+	// tok returns the n-th distinct token of a type, in order of
+	// appearance
+	tok := func(typ string, n int) string {
+		seen := map[string]bool{}
+		for _, t := range demoToken.FindAllString(in, -1) {
+			if strings.HasPrefix(t, "<"+typ+"_") && !seen[t] {
+				seen[t] = true
+				if len(seen) == n {
+					return t
+				}
+			}
+		}
+		return "someone"
+	}
+
+	var out string
+	switch {
+	case strings.Contains(in, "File: Q3 customer escalations"):
+		out = fmt.Sprintf("Open and resolved customer escalations for Q3. %s's double charge on order 5531 is refunded but still needs "+
+			"written confirmation to %s; %s's lockout is fixed, with the root cause open with the identity team; "+
+			"%s's replacement kettle has shipped and the courier claim is open, with a callback due on %s.",
+			tok("Person", 1), tok("Email Address", 1), tok("Person", 2), tok("Person", 3), tok("Phone Number", 1))
+		if strings.Contains(in, "invoice 2291") {
+			out += fmt.Sprintf(" Invoice 2291 was reissued to the Boston office for %s.", tok("Person", 4))
+		}
+	case strings.Contains(in, "File: Renewals tracker"):
+		out = fmt.Sprintf("Three renewals are due by 1 December, worth 204,000 in ARR, with Contoso (%s, 120,000) the largest. "+
+			"Fabrikam (%s) renews last and is the one high churn risk: %s owns it and is to call %s before 1 November.",
+			tok("Person", 2), tok("Person", 3), tok("Person", 4), tok("Email Address", 4))
+	case strings.Contains(in, "File: onboarding-call-notes.txt"):
+		out = fmt.Sprintf("Notes from Northwind's onboarding call on 9 October: %s needs SSO before rollout. "+
+			"Follow-up: send the admin guide to %s before the next call on 16 October.", tok("Person", 1), tok("Email Address", 1))
+	default:
+		out = "A file in the folder; nothing needs follow-up."
+	}
+	return out
+}
@@ -0,0 +1,462 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxFile is the largest file downloaded. Larger ones are skipped.
+const maxFile = 25 << 20
+
+// Item is a driveItem with the fields the recipe reads. A file has File
+// set, a folder Folder; an item from the delta feed that was deleted has
+// Deleted set and little else.
+type Item struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	CTag         string `json:"cTag"`
+	LastModified string `json:"lastModifiedDateTime"`
+	Size         int64  `json:"size"`
+	File         *struct {
+		MimeType string `json:"mimeType"`
+		Hashes   struct {
+			QuickXorHash string `json:"quickXorHash"`
+		} `json:"hashes"`
+	} `json:"file"`
+	Folder  *struct{} `json:"folder"`
+	Deleted *struct {
+		State string `json:"state"`
+	} `json:"deleted"`
+	ParentReference struct {
+		DriveID string `json:"driveId"`
+		ID      string `json:"id"`
+	} `json:"parentReference"`
+}
+
+// version identifies an item's content: its hash, or failing that its
+// cTag, which changes with the content and not with its metadata. Setting
+// a column on an item changes its lastModifiedDateTime but not its
+// version, so a sync doesn't summarize a file again for its own write.
+func (it Item) version() string {
+	if it.File != nil && it.File.Hashes.QuickXorHash != "" {
+		return it.File.Hashes.QuickXorHash
+	}
+	if it.CTag != "" {
+		return it.CTag
+	}
+	return it.LastModified
+}
+
+// graph is a minimal Microsoft Graph client for drives: resolving a
+// SharePoint site's library or a user's OneDrive, listing a folder, the
+// delta feed, downloads and uploads, and list item fields, which is all
+// the recipe needs.
+type graph struct {
+	base   string // https://graph.microsoft.com/v1.0
+	tokens *tokenSource
+	http   *http.Client
+}
+
+// graphError is an error response from Graph.
+type graphError struct {
+	Status int
+	Code   string
+	Msg    string
+}
+
+func (e *graphError) Error() string {
+	return fmt.Sprintf("graph: %d %s: %s", e.Status, e.Code, e.Msg)
+}
+
+func isStatus(err error, status int) bool {
+	var ge *graphError
+	return errors.As(err, &ge) && ge.Status == status
+}
+
+// errResync is returned by delta when the saved link has expired and the
+// folder has to be listed again.
+var errResync = errors.New("graph: delta link expired")
+
+const (
+	loginURL   = "https://login.microsoftonline.com"
+	graphScope = "https://graph.microsoft.com/.default"
+)
+
+// connect builds a client from the environment, in order of preference:
+//
+//   - GRAPH_ACCESS_TOKEN: an access token you already have, such as from
+//     `az account get-access-token --resource https://graph.microsoft.com`.
+//   - AZURE_TENANT_ID, AZURE_CLIENT_ID, AZURE_CLIENT_SECRET and
+//     GRAPH_REFRESH_TOKEN: an app registration and a refresh token a user
+//     granted it (delegated: the recipe sees what the user sees).
+//   - AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET alone: the
+//     client credentials flow (app-only: the recipe sees what the app's
+//     application permissions allow).
+func connect(ctx context.Context) (*graph, error) {
+	g := &graph{base: "https://graph.microsoft.com/v1.0", http: &http.Client{Timeout: 60 * time.Second}}
+	tenant, id, secret := os.Getenv("AZURE_TENANT_ID"), os.Getenv("AZURE_CLIENT_ID"), os.Getenv("AZURE_CLIENT_SECRET")
+	switch {
+	case os.Getenv("GRAPH_ACCESS_TOKEN") != "":
+		g.tokens = &tokenSource{token: os.Getenv("GRAPH_ACCESS_TOKEN")}
+	case tenant == "" || id == "" || secret == "":
+		return nil, fmt.Errorf("set GRAPH_ACCESS_TOKEN, or AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET (or run with -demo)")
+	case os.Getenv("GRAPH_REFRESH_TOKEN") != "":
+		g.tokens = refreshToken(g.http, loginURL, tenant, id, secret, os.Getenv("GRAPH_REFRESH_TOKEN"))
+	default:
+		g.tokens = clientCredentials(g.http, loginURL, tenant, id, secret)
+	}
+	// Fail on bad credentials now, not halfway through a folder
+	if _, err := g.tokens.get(ctx, false); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// tokenSource hands out an access token and fetches a new one shortly
+// before it expires, or when Graph turns the current one down.
+type tokenSource struct {
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+	// fetch gets a new token and its lifetime. Nil for a fixed token.
+	fetch func(ctx context.Context) (string, time.Duration, error)
+}
+
+func (ts *tokenSource) get(ctx context.Context, force bool) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.fetch == nil {
+		if force {
+			return "", fmt.Errorf("graph: access token rejected; GRAPH_ACCESS_TOKEN has likely expired")
+		}
+		return ts.token, nil
+	}
+	if ts.token != "" && !force && time.Until(ts.expiry) > time.Minute {
+		return ts.token, nil
+	}
+	token, ttl, err := ts.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	ts.token, ts.expiry = token, time.Now().Add(ttl)
+	return token, nil
+}
+
+// clientCredentials gets app-only tokens for the app registration.
+func clientCredentials(hc *http.Client, login, tenant, clientID, clientSecret string) *tokenSource {
+	return &tokenSource{fetch: func(ctx context.Context) (string, time.Duration, error) {
+		token, ttl, _, err := exchange(ctx, hc, login, tenant, url.Values{"grant_type": {"client_credentials"},
+			"client_id": {clientID}, "client_secret": {clientSecret}, "scope": {graphScope}})
+		return token, ttl, err
+	}}
+}
+
+// refreshToken exchanges a user's refresh token for delegated tokens.
+// Entra ID hands out a new refresh token with each access token; the
+// newest is used for the next exchange.
+func refreshToken(hc *http.Client, login, tenant, clientID, clientSecret, refresh string) *tokenSource {
+	return &tokenSource{fetch: func(ctx context.Context) (string, time.Duration, error) {
+		token, ttl, next, err := exchange(ctx, hc, login, tenant, url.Values{"grant_type": {"refresh_token"},
+			"client_id": {clientID}, "client_secret": {clientSecret}, "refresh_token": {refresh},
+			"scope": {graphScope + " offline_access"}})
+		if next != "" {
+			refresh = next
+		}
+		return token, ttl, err
+	}}
+}
+
+// exchange posts a grant to the tenant's token endpoint.
+func exchange(ctx context.Context, hc *http.Client, login, tenant string, form url.Values) (string, time.Duration, string, error) {
+	endpoint := strings.TrimSuffix(login, "/") + "/" + url.PathEscape(tenant) + "/oauth2/v2.0/token"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := hc.Do(req)
+	if err != nil {
+		return "", 0, "", err
+	}
+	defer resp.Body.Close()
+	var tok struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		Error        string `json:"error"`
+		Description  string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", 0, "", fmt.Errorf("entra id: %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || tok.AccessToken == "" {
+		// The description starts with an AADSTS code that says what's wrong
+		desc, _, _ := strings.Cut(tok.Description, "\r\n")
+		return "", 0, "", fmt.Errorf("entra id: %d %s: %s", resp.StatusCode, tok.Error, desc)
+	}
+	return tok.AccessToken, time.Duration(tok.ExpiresIn) * time.Second, tok.RefreshToken, nil
+}
+
+// send makes one request to a path under base, or to an absolute URL
+// such as a nextLink. It makes it again with a new token if Graph turns
+// the current one down, and waits out throttling (429 and 503 with
+// Retry-After) up to three times. It returns the response body, at most
+// maxFile bytes of it.
+func (g *graph) send(ctx context.Context, method, path, contentType string, body []byte) ([]byte, error) {
+	target := path
+	if !strings.HasPrefix(path, "https://") && !strings.HasPrefix(path, "http://") {
+		target = g.base + path
+	}
+	refreshed, throttled := false, 0
+	for {
+		token, err := g.tokens.get(ctx, refreshed)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := g.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxFile+1))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized && !refreshed:
+			refreshed = true
+			continue
+		case (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) && throttled < 3:
+			throttled++
+			wait, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+			if err != nil || wait <= 0 {
+				wait = 1 << throttled
+			}
+			select {
+			case <-time.After(min(time.Duration(wait)*time.Second, time.Minute)):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			var e struct {
+				Error struct {
+					Code    string `json:"code"`
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			ge := &graphError{Status: resp.StatusCode, Msg: strings.TrimSpace(string(data))}
+			if json.Unmarshal(data, &e) == nil && e.Error.Code != "" {
+				ge.Code, ge.Msg = e.Error.Code, e.Error.Message
+			}
+			return nil, ge
+		}
+		if len(data) > maxFile {
+			return nil, fmt.Errorf("graph: response larger than %d MB", maxFile>>20)
+		}
+		return data, nil
+	}
+}
+
+func (g *graph) do(ctx context.Context, method, path string, body, out any) error {
+	var b []byte
+	var contentType string
+	if body != nil {
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	}
+	data, err := g.send(ctx, method, path, contentType, b)
+	if err != nil || out == nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// escapePath escapes each segment of a slash-separated path.
+func escapePath(p string) string {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	for i, s := range parts {
+		parts[i] = url.PathEscape(s)
+	}
+	return strings.Join(parts, "/")
+}
+
+// siteDrive returns the ID of a SharePoint site's document library: the
+// default one ("Documents", shown as "Shared Documents" in URLs), or the
+// one named library. site is a hostname and server-relative path, such
+// as contoso.sharepoint.com:/sites/Operations.
+func (g *graph) siteDrive(ctx context.Context, site, library string) (string, error) {
+	host, path, ok := strings.Cut(site, ":")
+	if !ok || !strings.HasPrefix(path, "/") {
+		return "", fmt.Errorf("-site: want hostname:/sites/name, got %q", site)
+	}
+	var s struct {
+		ID string `json:"id"`
+	}
+	if err := g.do(ctx, http.MethodGet, "/sites/"+url.PathEscape(host)+":/"+escapePath(path), nil, &s); err != nil {
+		return "", err
+	}
+	var d struct {
+		ID string `json:"id"`
+	}
+	if library == "" {
+		err := g.do(ctx, http.MethodGet, "/sites/"+url.PathEscape(s.ID)+"/drive?$select=id", nil, &d)
+		return d.ID, err
+	}
+	var drives struct {
+		Value []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"value"`
+	}
+	if err := g.do(ctx, http.MethodGet, "/sites/"+url.PathEscape(s.ID)+"/drives?$select=id,name", nil, &drives); err != nil {
+		return "", err
+	}
+	var names []string
+	for _, d := range drives.Value {
+		if strings.EqualFold(d.Name, library) {
+			return d.ID, nil
+		}
+		names = append(names, d.Name)
+	}
+	return "", fmt.Errorf("site %s has no library %q (it has %s)", site, library, strings.Join(names, ", "))
+}
+
+// userDrive returns the ID of a user's OneDrive; "me" is the signed-in
+// user, with delegated credentials.
+func (g *graph) userDrive(ctx context.Context, user string) (string, error) {
+	path := "/users/" + url.PathEscape(user) + "/drive?$select=id"
+	if user == "me" {
+		path = "/me/drive?$select=id"
+	}
+	var d struct {
+		ID string `json:"id"`
+	}
+	err := g.do(ctx, http.MethodGet, path, nil, &d)
+	return d.ID, err
+}
+
+// folder returns the ID of the folder at path in a drive; "" is the root.
+func (g *graph) folder(ctx context.Context, driveID, path string) (string, error) {
+	p := "/drives/" + url.PathEscape(driveID) + "/root"
+	if strings.Trim(path, "/") != "" {
+		p += ":/" + escapePath(path)
+	}
+	var it Item
+	if err := g.do(ctx, http.MethodGet, p+"?$select=id,name,folder", nil, &it); err != nil {
+		return "", err
+	}
+	if it.Folder == nil {
+		return "", fmt.Errorf("%s is a file, not a folder", path)
+	}
+	return it.ID, nil
+}
+
+// children lists the items directly in a folder, following nextLink
+// until every page is read.
+func (g *graph) children(ctx context.Context, driveID, folderID string) ([]Item, error) {
+	var out []Item
+	next := "/drives/" + url.PathEscape(driveID) + "/items/" + url.PathEscape(folderID) + "/children?$top=200"
+	for next != "" {
+		var page struct {
+			Value []Item `json:"value"`
+			Next  string `json:"@odata.nextLink"`
+		}
+		if err := g.do(ctx, http.MethodGet, next, nil, &page); err != nil {
+			return nil, err
+		}
+		out = append(out, page.Value...)
+		next = page.Next
+	}
+	return out, nil
+}
+
+// latestDelta returns a delta link for changes from now on, without
+// reading the drive's current contents.
+func (g *graph) latestDelta(ctx context.Context, driveID string) (string, error) {
+	var page struct {
+		Delta string `json:"@odata.deltaLink"`
+	}
+	err := g.do(ctx, http.MethodGet, "/drives/"+url.PathEscape(driveID)+"/root/delta?token=latest", nil, &page)
+	return page.Delta, err
+}
+
+// delta returns every item changed since a delta link, following
+// nextLink, and the delta link to use next time. It returns errResync if
+// Graph no longer has the changes since link.
+func (g *graph) delta(ctx context.Context, link string) ([]Item, string, error) {
+	var out []Item
+	for {
+		var page struct {
+			Value []Item `json:"value"`
+			Next  string `json:"@odata.nextLink"`
+			Delta string `json:"@odata.deltaLink"`
+		}
+		if err := g.do(ctx, http.MethodGet, link, nil, &page); err != nil {
+			if isStatus(err, http.StatusGone) {
+				return nil, "", errResync
+			}
+			return nil, "", err
+		}
+		out = append(out, page.Value...)
+		if page.Delta != "" {
+			return out, page.Delta, nil
+		}
+		link = page.Next
+	}
+}
+
+// content downloads a file. Graph answers with a redirect to a
+// short-lived, pre-authenticated download URL, which the client follows
+// without the Authorization header.
+func (g *graph) content(ctx context.Context, driveID, itemID string) ([]byte, error) {
+	return g.send(ctx, http.MethodGet, "/drives/"+url.PathEscape(driveID)+"/items/"+url.PathEscape(itemID)+"/content", "", nil)
+}
+
+// upload writes a small file (Graph takes up to 250 MB this way) named
+// name into a folder, replacing one of that name, and returns its ID.
+func (g *graph) upload(ctx context.Context, driveID, folderID, name, contentType string, data []byte) (string, error) {
+	out, err := g.send(ctx, http.MethodPut, "/drives/"+url.PathEscape(driveID)+"/items/"+url.PathEscape(folderID)+":/"+
+		url.PathEscape(name)+":/content?@microsoft.graph.conflictBehavior=replace", contentType, data)
+	if err != nil {
+		return "", err
+	}
+	var it Item
+	return it.ID, json.Unmarshal(out, &it)
+}
+
+// replace writes new content into an existing file.
+func (g *graph) replace(ctx context.Context, driveID, itemID, contentType string, data []byte) error {
+	_, err := g.send(ctx, http.MethodPut, "/drives/"+url.PathEscape(driveID)+"/items/"+url.PathEscape(itemID)+"/content", contentType, data)
+	return err
+}
+
+// setField sets a column of a SharePoint library item. OneDrive has no
+// columns.
+func (g *graph) setField(ctx context.Context, driveID, itemID, column, value string) error {
+	return g.do(ctx, http.MethodPatch, "/drives/"+url.PathEscape(driveID)+"/items/"+url.PathEscape(itemID)+"/listItem/fields",
+		map[string]string{column: value}, nil)
+}
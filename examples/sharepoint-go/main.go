@@ -0,0 +1,402 @@
+// SharePoint / OneDrive + Blindfold: Summarize the Word, Excel and text
+// files in a document library folder without the LLM seeing the personal
+// data in them.
+//
+// The Microsoft Graph variant of the Google Drive recipe. Files are
+// downloaded through Graph, and Word and Excel files are read with the
+// standard library (office.go). pkg/docsummary, the core both recipes
+// share, tokenizes the text, asks the LLM for a summary, checks it for
+// tokens the file never had and restores it. The summary goes back as a
+// companion Word document next to the file, or into a column of the
+// SharePoint library.
+//
+// The first run lists the folder; later runs read the drive's delta feed
+// from the link the last run saved, so only files edited, added or
+// removed since then are downloaded and summarized again.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/docsummary"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+type config struct {
+	site, library, user, drive, folder string
+	statePath, write, column           string
+	full, dryRun                       bool
+}
+
+// summarySuffix ends the name of every companion doc.
+const summarySuffix = " (summary).docx"
+
+func main() {
+	_ = godotenv.Load()
+	var cfg config
+	flag.StringVar(&cfg.site, "site", os.Getenv("SHAREPOINT_SITE"), "SharePoint site, as hostname:/sites/name (default: $SHAREPOINT_SITE)")
+	flag.StringVar(&cfg.library, "library", "", "document library of -site, by name (default: the site's default library, Documents)")
+	flag.StringVar(&cfg.user, "user", "", "summarize this user's OneDrive instead (a user principal name, or me with delegated credentials)")
+	flag.StringVar(&cfg.drive, "drive", "", "summarize the drive with this ID instead")
+	flag.StringVar(&cfg.folder, "folder", os.Getenv("SHAREPOINT_FOLDER"), "folder path within the drive (default: $SHAREPOINT_FOLDER, or the root)")
+	flag.StringVar(&cfg.statePath, "state", "sharepoint-state.json", "state file: the delta link and what was summarized")
+	flag.StringVar(&cfg.write, "write", "doc", "where summaries go: doc (a companion Word document) or column (a SharePoint library column)")
+	flag.StringVar(&cfg.column, "column", "BlindfoldSummary", "internal name of the multi-line text column for -write column")
+	flag.BoolVar(&cfg.full, "full", false, "list the whole folder instead of reading changes since the last run")
+	flag.BoolVar(&cfg.dryRun, "dry-run", false, "summarize and print, but write nothing to SharePoint or the state file")
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply, by name (default: the file's default)")
+	model := flag.String("model", openai.GPT4oMini, "OpenAI model")
+	maxChars := flag.Int("max-chars", docsummary.DefaultMaxChars, "summarize tokenized files longer than this section by section")
+	demo := flag.Bool("demo", false, "use an in-process SharePoint site and scripted model (no Microsoft 365 or OpenAI account needed)")
+	flag.Parse()
+	if cfg.write != "doc" && cfg.write != "column" {
+		log.Fatalf("-write: want doc or column, got %q", cfg.write)
+	}
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...))
+
+	oaCfg := openai.DefaultConfig(os.Getenv("OPENAI_API_KEY"))
+	var g *graph
+	var fake *fakeGraph
+	if *demo {
+		llm := fakeopenai.Server(fakeModel)
+		defer llm.Close()
+		oaCfg = fakeopenai.Config(llm)
+		var stop func()
+		fake, g, stop = newFakeGraph()
+		defer stop()
+		// A fresh state, so the demo always starts with a full sync
+		dir, err := os.MkdirTemp("", "sharepoint-demo-")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		cfg.site, cfg.library, cfg.user, cfg.drive = demoSite, "", "", ""
+		cfg.folder, cfg.statePath = demoFolder, filepath.Join(dir, "sharepoint-state.json")
+	} else {
+		if os.Getenv("OPENAI_API_KEY") == "" {
+			log.Fatal("OPENAI_API_KEY is required (or run with -demo)")
+		}
+		if g, err = connect(ctx); err != nil {
+			log.Fatal(err)
+		}
+	}
+	driveID, folderID, err := locate(ctx, g, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := docsummary.New(bf, openai.NewClientWithConfig(oaCfg), *model)
+	s.MaxChars = *maxChars
+
+	if err := run(ctx, g, s, cfg, driveID, folderID); err != nil {
+		log.Fatal(err)
+	}
+	if fake != nil {
+		fmt.Printf("\n(demo) Meanwhile, someone %s\n\n", fake.edit())
+		if err := run(ctx, g, s, cfg, driveID, folderID); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// locate finds the drive and folder the flags name.
+func locate(ctx context.Context, g *graph, cfg config) (driveID, folderID string, err error) {
+	switch {
+	case cfg.drive != "":
+		driveID = cfg.drive
+	case cfg.user != "":
+		driveID, err = g.userDrive(ctx, cfg.user)
+	case cfg.site != "":
+		driveID, err = g.siteDrive(ctx, cfg.site, cfg.library)
+	default:
+		return "", "", fmt.Errorf("-site (or SHAREPOINT_SITE), -user or -drive is required")
+	}
+	if err != nil {
+		return "", "", err
+	}
+	folderID, err = g.folder(ctx, driveID, cfg.folder)
+	if err != nil {
+		return "", "", fmt.Errorf("folder %q: %w", cfg.folder, err)
+	}
+	return driveID, folderID, nil
+}
+
+// run is one sync: it summarizes the files that are new or changed since
+// the last, and saves the delta link to pick up from next time. If any
+// file fails, the link is kept where it was, so the next run sees those
+// changes again; files summarized in the meantime are recorded and not
+// summarized twice.
+func run(ctx context.Context, g *graph, s *docsummary.Summarizer, cfg config, driveID, folderID string) error {
+	st, err := loadState(cfg.statePath, driveID, folderID)
+	if err != nil {
+		return err
+	}
+	items, next, err := pending(ctx, g, st, cfg)
+	if err != nil {
+		return err
+	}
+	summarized, unchanged, failed := 0, 0, 0
+	for _, it := range items {
+		ext := strings.ToLower(path.Ext(it.Name))
+		switch {
+		case it.Folder != nil:
+			fmt.Printf("── %s: skipped, subfolders aren't synced\n\n", it.Name)
+			continue
+		case it.File == nil:
+			continue
+		case extractors[ext] == nil:
+			fmt.Printf("── %s: skipped, can't read %s as text\n\n", it.Name, it.File.MimeType)
+			continue
+		case it.Size > maxFile:
+			fmt.Printf("── %s: skipped, larger than %d MB\n\n", it.Name, maxFile>>20)
+			continue
+		}
+		e := st.Files[it.ID]
+		if e != nil && e.Version == it.version() {
+			unchanged++
+			continue
+		}
+		fmt.Printf("── %s (edited %s)\n", it.Name, when(it.LastModified))
+		if e == nil {
+			e = &entry{}
+		}
+		if err := summarizeFile(ctx, g, s, cfg, st, it, e); err != nil {
+			fmt.Printf("   failed:    %v\n\n", err)
+			failed++
+			continue
+		}
+		st.Files[it.ID] = e
+		summarized++
+		fmt.Println()
+	}
+	fmt.Printf("%d summarized, %d unchanged, %d failed\n", summarized, unchanged, failed)
+	if cfg.dryRun {
+		return nil
+	}
+	if failed == 0 {
+		st.DeltaLink = next
+	}
+	if err := st.save(cfg.statePath); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d file(s) failed; the next run tries them again", failed)
+	}
+	fmt.Printf("Saved delta link to %s\n", cfg.statePath)
+	return nil
+}
+
+// pending returns the folder's items to look at, and the delta link to
+// save after them. With no link saved, -full, or a link Graph no longer
+// honors, that is every item in the folder; otherwise it is the items the
+// delta feed reports in the folder, and the state of files removed from
+// it is dropped.
+func pending(ctx context.Context, g *graph, st *state, cfg config) ([]Item, string, error) {
+	if st.DeltaLink != "" && !cfg.full {
+		items, link, err := g.delta(ctx, st.DeltaLink)
+		if err == nil {
+			return changed(st, items, folderName(cfg.folder)), link, nil
+		}
+		if !errors.Is(err, errResync) {
+			return nil, "", err
+		}
+		fmt.Printf("The saved delta link has expired; listing the folder again.\n")
+	}
+
+	// The link comes first: a file edited while the folder is read shows
+	// up in the next run's changes rather than being missed
+	link, err := g.latestDelta(ctx, st.Drive)
+	if err != nil {
+		return nil, "", err
+	}
+	items, err := g.children(ctx, st.Drive, st.Folder)
+	if err != nil {
+		return nil, "", err
+	}
+	items = withoutSummaries(st, items)
+	keep := make(map[string]bool, len(items))
+	for _, it := range items {
+		keep[it.ID] = true
+	}
+	for id := range st.Files {
+		if !keep[id] {
+			delete(st.Files, id)
+		}
+	}
+	fmt.Printf("Folder %s: full sync, %d item(s)\n\n", folderName(cfg.folder), len(items))
+	return items, link, nil
+}
+
+// changed picks the items in the folder out of a delta page, and forgets
+// files deleted or moved out of it. The delta feed covers the whole
+// drive: Graph only serves it for folders on OneDrive personal.
+func changed(st *state, items []Item, folder string) []Item {
+	// An item changed twice may appear twice; its last state wins
+	latest := make(map[string]Item, len(items))
+	var order []string
+	for _, it := range items {
+		if _, ok := latest[it.ID]; !ok {
+			order = append(order, it.ID)
+		}
+		latest[it.ID] = it
+	}
+	var out []Item
+	var gone []string
+	for _, id := range order {
+		it := latest[id]
+		if it.Deleted != nil || it.ParentReference.ID != st.Folder {
+			if e := st.Files[id]; e != nil {
+				gone = append(gone, e.Name)
+				delete(st.Files, id)
+			}
+			continue
+		}
+		out = append(out, it)
+	}
+	out = withoutSummaries(st, out)
+	fmt.Printf("Folder %s: %d change(s) in the drive, %d in the folder\n\n", folder, len(items), len(out)+len(gone))
+	for _, name := range gone {
+		fmt.Printf("── %s: removed from the folder, forgotten\n\n", name)
+	}
+	return out
+}
+
+// withoutSummaries drops the companion docs a sync wrote, so it never
+// summarizes its own output.
+func withoutSummaries(st *state, items []Item) []Item {
+	ours := st.summaries()
+	out := items[:0]
+	for _, it := range items {
+		if !ours[it.ID] && !strings.HasSuffix(it.Name, summarySuffix) {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// summarizeFile downloads one file, summarizes it, and unless -dry-run
+// writes the summary back and records where it went in e.
+func summarizeFile(ctx context.Context, g *graph, s *docsummary.Summarizer, cfg config, st *state, it Item, e *entry) error {
+	data, err := g.content(ctx, st.Drive, it.ID)
+	if err != nil {
+		return err
+	}
+	text, err := extractors[strings.ToLower(path.Ext(it.Name))](data)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("no text")
+	}
+	sum, err := s.Summarize(ctx, it.Name, text)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("   tokenized: %d chars, %d section(s), %s\n", len(text), sum.Sections, entities(sum.Entities))
+	fmt.Printf("   summary:   %s\n", sum.Tokenized)
+	fmt.Printf("   → %s\n", sum.Text)
+	if cfg.dryRun {
+		fmt.Printf("   not written (-dry-run)\n")
+		return nil
+	}
+
+	switch cfg.write {
+	case "doc":
+		doc, err := docxFile("Summary of "+it.Name, sum.Text+"\n\n"+docFooter)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(it.Name, path.Ext(it.Name)) + summarySuffix
+		updated := false
+		if e.DocID != "" {
+			err := g.replace(ctx, st.Drive, e.DocID, docxMIME, doc)
+			if err != nil && !isStatus(err, http.StatusNotFound) {
+				return err
+			}
+			updated = err == nil
+		}
+		if !updated {
+			if e.DocID, err = g.upload(ctx, st.Drive, st.Folder, name, docxMIME, doc); err != nil {
+				return err
+			}
+		}
+		verb := "created"
+		if updated {
+			verb = "updated"
+		}
+		fmt.Printf("   written:   %s %s\n", verb, name)
+	case "column":
+		if err := g.setField(ctx, st.Drive, it.ID, cfg.column, sum.Text); err != nil {
+			if isStatus(err, http.StatusBadRequest) || isStatus(err, http.StatusNotFound) {
+				return fmt.Errorf("%w (does the library have a multi-line text column named %s? OneDrive has no columns)", err, cfg.column)
+			}
+			return err
+		}
+		e.Column = cfg.column
+		fmt.Printf("   written:   column %s\n", cfg.column)
+	}
+	e.Name, e.Version, e.Entities, e.SummarizedAt = it.Name, it.version(), sum.Entities, time.Now().UTC()
+	return nil
+}
+
+const docFooter = "Written by an LLM from a copy of the file with personal data replaced by placeholders. It is updated when the file changes."
+
+func folderName(p string) string {
+	if strings.Trim(p, "/") == "" {
+		return "(root)"
+	}
+	return strings.Trim(p, "/")
+}
+
+// when shows an ISO 8601 time from Graph in local time.
+func when(iso string) string {
+	t, err := time.Parse(time.RFC3339, iso)
+	if err != nil {
+		return iso
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func entities(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "no entities"
+	}
+	types := make([]string, 0, len(counts))
+	n := 0
+	for t, c := range counts {
+		types = append(types, t)
+		n += c
+	}
+	sort.Strings(types)
+	for i, t := range types {
+		types[i] = fmt.Sprintf("%s %d", t, counts[t])
+	}
+	return fmt.Sprintf("%d entities protected (%s)", n, strings.Join(types, ", "))
+}
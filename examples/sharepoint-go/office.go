@@ -0,0 +1,287 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Word and Excel files are zip archives of XML parts (Office Open XML).
+// The standard library reads them well enough for text: paragraphs from
+// a .docx, cell values from a .xlsx. Legacy .doc and .xls, and PDFs, are
+// skipped.
+
+// maxPart is the most read from one part once uncompressed, so a small
+// file that inflates enormously can't exhaust memory.
+const maxPart = 32 << 20
+
+// extractors turn a file into plain text for tokenization, by extension.
+var extractors = map[string]func([]byte) (string, error){
+	".docx": docxText,
+	".xlsx": xlsxText,
+	".txt":  plain,
+	".md":   plain,
+	".csv":  plain,
+}
+
+func plain(data []byte) (string, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("not UTF-8 text")
+	}
+	return string(data), nil
+}
+
+// part reads one part of an Office file.
+func part(zr *zip.Reader, name string) ([]byte, error) {
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxPart+1))
+	if err == nil && len(data) > maxPart {
+		err = fmt.Errorf("%s: larger than %d MB uncompressed", name, maxPart>>20)
+	}
+	return data, err
+}
+
+// docxText returns a Word document's body text, a line per paragraph.
+// Tabs and breaks are kept; table cells come out a paragraph each.
+func docxText(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("docx: %w", err)
+	}
+	doc, err := part(zr, "word/document.xml")
+	if err != nil {
+		return "", fmt.Errorf("docx: %w", err)
+	}
+	var b strings.Builder
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	inText := false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("docx: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				b.WriteByte('\t')
+			case "br", "cr":
+				b.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				b.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// xlsxText returns every sheet of a workbook as CSV, each under a
+// "Sheet: <name>" line. Cells hold what Excel last calculated; formulas
+// aren't evaluated.
+func xlsxText(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("xlsx: %w", err)
+	}
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	var shared struct {
+		Items []struct {
+			T    string `xml:"t"`
+			Runs []struct {
+				T string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if err := readXML(zr, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+	if err := readXML(zr, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	// A workbook without text cells has no shared strings
+	if err := readXML(zr, "xl/sharedStrings.xml", &shared); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	strs := make([]string, len(shared.Items))
+	for i, si := range shared.Items {
+		strs[i] = si.T
+		for _, r := range si.Runs {
+			strs[i] += r.T
+		}
+	}
+	targets := make(map[string]string, len(rels.Rels))
+	for _, r := range rels.Rels {
+		// Targets are relative to xl/, or absolute within the package
+		if strings.HasPrefix(r.Target, "/") {
+			targets[r.ID] = strings.TrimPrefix(r.Target, "/")
+		} else {
+			targets[r.ID] = path.Join("xl", r.Target)
+		}
+	}
+
+	var b strings.Builder
+	for _, s := range workbook.Sheets {
+		var sheet struct {
+			Rows []struct {
+				Cells []struct {
+					Ref    string `xml:"r,attr"`
+					Type   string `xml:"t,attr"`
+					Value  string `xml:"v"`
+					Inline string `xml:"is>t"`
+				} `xml:"c"`
+			} `xml:"sheetData>row"`
+		}
+		if err := readXML(zr, targets[s.RID], &sheet); err != nil {
+			return "", err
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "Sheet: %s\n", s.Name)
+		w := csv.NewWriter(&b)
+		for _, row := range sheet.Rows {
+			var rec []string
+			for _, c := range row.Cells {
+				v := c.Value
+				switch c.Type {
+				case "s":
+					i, err := strconv.Atoi(c.Value)
+					if err != nil || i < 0 || i >= len(strs) {
+						return "", fmt.Errorf("xlsx: %s!%s: bad shared string %q", s.Name, c.Ref, c.Value)
+					}
+					v = strs[i]
+				case "inlineStr":
+					v = c.Inline
+				case "b":
+					v = "FALSE"
+					if c.Value == "1" {
+						v = "TRUE"
+					}
+				}
+				// Empty cells aren't stored: place each by its column
+				for col := column(c.Ref); len(rec) < col; {
+					rec = append(rec, "")
+				}
+				rec = append(rec, v)
+			}
+			if err := w.Write(rec); err != nil {
+				return "", err
+			}
+		}
+		w.Flush()
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// column returns the zero-based column of a cell reference such as
+// "C7", or -1 without one.
+func column(ref string) int {
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		n = n*26 + int(r-'A'+1)
+	}
+	return n - 1
+}
+
+func readXML(zr *zip.Reader, name string, v any) error {
+	data, err := part(zr, name)
+	if err != nil {
+		return fmt.Errorf("xlsx: %w", err)
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("xlsx: %s: %w", name, err)
+	}
+	return nil
+}
+
+const docxMIME = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+// docxFile builds a minimal Word document with a title and a paragraph
+// per line of text, for companion summaries.
+func docxFile(title, text string) ([]byte, error) {
+	var body strings.Builder
+	para := func(style, s string) {
+		body.WriteString("<w:p>")
+		if style != "" {
+			fmt.Fprintf(&body, `<w:pPr><w:pStyle w:val="%s"/></w:pPr>`, style)
+		}
+		body.WriteString(`<w:r><w:t xml:space="preserve">`)
+		xml.EscapeText(&body, []byte(s))
+		body.WriteString("</w:t></w:r></w:p>")
+	}
+	para("Title", title)
+	for _, line := range strings.Split(text, "\n") {
+		para("", line)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range []struct{ name, data string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+			`</Relationships>`},
+		{"word/document.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` + body.String() + `</w:body></w:document>`},
+	} {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(w, f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
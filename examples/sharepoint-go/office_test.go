@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestDocxRoundTrip(t *testing.T) {
+	data, err := docxFile("Summary: A & B", "First line <with markup>\n\tIndented")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := docxText(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Summary: A & B\nFirst line <with markup>\n\tIndented"; got != want {
+		t.Errorf("docxText = %q, want %q", got, want)
+	}
+}
+
+func TestXLSXText(t *testing.T) {
+	data := demoXLSX([]demoSheet{
+		{"People", [][]any{
+			{"Name", "Email"},
+			{"Jane Doe", "jane@example.com"},
+		}},
+		{"Gaps, \"quoted\"", [][]any{
+			{"a", nil, "c"},
+			{nil, 42},
+		}},
+	})
+	got, err := xlsxText(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Sheet: People\nName,Email\nJane Doe,jane@example.com\n\n" +
+		"Sheet: Gaps, \"quoted\"\na,,c\n,42"
+	if got != want {
+		t.Errorf("xlsxText = %q, want %q", got, want)
+	}
+}
+
+func TestColumn(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "C7": 2, "Z9": 25, "AA10": 26, "AB1": 27, "": -1} {
+		if got := column(ref); got != want {
+			t.Errorf("column(%q) = %d, want %d", ref, got, want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// state is what a sync keeps between runs: the delta link to read changes
+// from, and per file the version summarized and where its summary went.
+// It holds no mappings and no text, but file names can be personal, so
+// it is written owner-only.
+type state struct {
+	Drive     string            `json:"drive"`
+	Folder    string            `json:"folder"`
+	DeltaLink string            `json:"delta_link"`
+	Files     map[string]*entry `json:"files"`
+}
+
+type entry struct {
+	Name         string         `json:"name"`
+	Version      string         `json:"version"` // of the content summarized; see Item.version
+	DocID        string         `json:"doc_id,omitempty"`
+	Column       string         `json:"column,omitempty"`
+	Entities     map[string]int `json:"entities,omitempty"`
+	SummarizedAt time.Time      `json:"summarized_at"`
+}
+
+// loadState reads the state for a folder of a drive. A missing file is a
+// first sync.
+func loadState(path, driveID, folderID string) (*state, error) {
+	st := &state{Drive: driveID, Folder: folderID, Files: make(map[string]*entry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if st.Drive != driveID || st.Folder != folderID {
+		return nil, fmt.Errorf("%s is the state for another folder (item %s of drive %s); pass another -state", path, st.Folder, st.Drive)
+	}
+	if st.Files == nil {
+		st.Files = make(map[string]*entry)
+	}
+	return st, nil
+}
+
+// summaries returns the IDs of the companion docs written so far.
+func (st *state) summaries() map[string]bool {
+	ids := make(map[string]bool)
+	for _, e := range st.Files {
+		if e.DocID != "" {
+			ids[e.DocID] = true
+		}
+	}
+	return ids
+}
+
+// save writes the state through a temporary file, so a crash leaves the
+// previous state rather than half of this one.
+func (st *state) save(path string) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".sharepoint-state-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+)
+
+// demo starts the service in-process and calls it the way Snowflake does:
+// a JSON batch of numbered rows per POST, with the query and batch IDs in
+// sf-external-function-* headers and the secret in the header the
+// function's HEADERS clause sets.
+func demo(h *handler) error {
+	h.secret = "demo-secret"
+	log.SetFlags(0)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	call := func(fn, batchID string, rows [][]any) (*result, error) {
+		var body bytes.Buffer
+		enc := json.NewEncoder(&body)
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(map[string]any{"data": rows})
+		fmt.Printf("POST /%s (batch %s)\n  → %s\n", fn, batchID, bytes.TrimSpace(body.Bytes()))
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/"+fn, &body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("sf-external-function-current-query-id", "01b7c3d2-0000-demo")
+		req.Header.Set("sf-external-function-query-batch-id", batchID)
+		req.Header.Set("sf-custom-secret", h.secret)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		fmt.Printf("  ← %d %s\n", resp.StatusCode, bytes.TrimSpace(data))
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: %s", fn, resp.Status)
+		}
+		var out result
+		return &out, json.Unmarshal(data, &out)
+	}
+
+	// SELECT BLINDFOLD_TOKENIZE(body) FROM support.tickets
+	if _, err := call("tokenize", "0", [][]any{
+		{0, "Refund order 5531 to card 4111 1111 1111 1111, confirm to jane.doe@example.com"},
+		{1, "Call me back on 415-555-0199 after 5pm"},
+		{2, nil},
+		{3, "Where is my order? It's been two weeks."},
+	}); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	// SELECT BLINDFOLD_TOKENIZE_WITH_MAPPING(body) AS t ..., then
+	// BLINDFOLD_DETOKENIZE(t:text, t:mapping) where the values must come back
+	res, err := call("tokenize_with_mapping", "1", [][]any{
+		{0, "Ticket from omar.haddad@example.com about IP 203.0.113.42"},
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	v := res.Data[0][1].(map[string]any)
+	if _, err := call("detokenize", "2", [][]any{{0, v["text"], v["mapping"]}}); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	// A batch without the secret is refused before any row is read
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/tokenize", bytes.NewReader([]byte(`{"data":[[0,"jane.doe@example.com"]]}`)))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	fmt.Printf("POST /tokenize without sf-custom-secret\n  ← %d %s\n", resp.StatusCode, bytes.TrimSpace(data))
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/sqlfn"
+)
+
+// Snowflake external functions POST a batch of rows as
+// {"data": [[row, arg1, arg2, ...], ...]}, and expect exactly one result per
+// row back, in the same order: {"data": [[row, result], ...]}. Any status
+// other than 200 fails the query, except 429, which Snowflake retries with
+// backoff.
+type batch struct {
+	Data [][]json.RawMessage `json:"data"`
+}
+
+type result struct {
+	Data [][2]any `json:"data"`
+}
+
+// handler serves the pkg/sqlfn functions at /<name>; the CREATE EXTERNAL
+// FUNCTION statements in setup.sql map SQL names onto them. Rows of a
+// batch are processed by up to workers goroutines. If any row fails, the
+// whole batch fails and nothing is returned: Snowflake never gets a row
+// back untokenized.
+type handler struct {
+	bf      bfclient.Client
+	workers int
+	secret  string // if set, the sf-custom-secret header must match
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(r.URL.Path, "/")
+	fn, ok := sqlfn.Funcs[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown function %q", name), http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "external functions are called with POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.secret != "" && r.Header.Get("sf-custom-secret") != h.secret {
+		http.Error(w, "missing or wrong sf-custom-secret header", http.StatusUnauthorized)
+		return
+	}
+	var in batch
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "body is not an external function batch: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	numbers := make([]json.RawMessage, len(in.Data))
+	rows := make([][]*string, len(in.Data))
+	for i, row := range in.Data {
+		if len(row) == 0 {
+			http.Error(w, fmt.Sprintf("%s: row %d has no row number", name, i), http.StatusBadRequest)
+			return
+		}
+		numbers[i], rows[i] = row[0], sqlfn.Args(row[1:])
+	}
+	results, err := sqlfn.Run(r.Context(), h.bf, fn, rows, h.workers)
+	query, batchID := r.Header.Get("sf-external-function-current-query-id"), r.Header.Get("sf-external-function-query-batch-id")
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, sqlfn.ErrBadArgs):
+			status = http.StatusBadRequest
+		case bferrors.IsRetryable(err):
+			status = http.StatusTooManyRequests
+		}
+		log.Printf("%s query=%s batch=%s rows=%d kind=%s: %v", name, query, batchID, len(rows), bferrors.KindOf(err), err)
+		http.Error(w, fmt.Sprintf("%s: %v", name, err), status)
+		return
+	}
+	// Counts only: the log never holds a value
+	if fn.Tokenizes {
+		log.Printf("%s query=%s batch=%s rows=%d entities=%s", name, query, batchID, len(rows), sqlfn.Entities(results))
+	} else {
+		log.Printf("%s query=%s batch=%s rows=%d", name, query, batchID, len(rows))
+	}
+	out := result{Data: make([][2]any, len(results))}
+	for i, v := range results {
+		out.Data[i] = [2]any{numbers[i], v}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(out)
+}
@@ -0,0 +1,80 @@
+// Snowflake external function + Blindfold: Tokenize warehouse columns from
+// SQL.
+//
+// Runs an HTTP service that speaks Snowflake's external-function
+// contract, so once the function is created (see setup.sql),
+//
+//	SELECT BLINDFOLD_TOKENIZE(body) FROM support.tickets;
+//
+// scrubs a column in place, before the rows are exported to an LLM
+// workflow. Snowflake sends rows in batches through an API gateway; each
+// batch is tokenized in parallel and answered in row order. A batch that
+// can't be tokenized fails as a whole, so no row ever comes back raw.
+// BLINDFOLD_TOKENIZE_WITH_MAPPING returns the mapping too, for tables
+// whose values must be restored later with BLINDFOLD_DETOKENIZE.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/config"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/health"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+func main() {
+	// Every flag can also be set as SNOWFLAKE_FN_<FLAG> or in the -config file
+	cfg := config.New(flag.CommandLine, "SNOWFLAKE_FN")
+	addr := flag.String("addr", "127.0.0.1:8082", "listen address")
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply, by name (default: the file's default)")
+	workers := flag.Int("workers", 8, "rows of a batch tokenized in parallel")
+	runDemo := flag.Bool("demo", false, "send sample batches the way Snowflake does to an in-process service and exit")
+	cfg.File("config")
+	cfg.Check("workers", func() error {
+		if *workers < 1 {
+			return errors.New("must be at least 1")
+		}
+		return nil
+	})
+	if err := cfg.Parse(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	h := &handler{
+		// API key is optional — omit it to run in local mode (regex-based, offline)
+		bf:      pol.Wrap(bfclient.FromEnv(pol.ClientOptions()...)),
+		workers: *workers,
+		secret:  os.Getenv("SNOWFLAKE_FUNCTION_SECRET"),
+	}
+
+	if *runDemo {
+		if err := demo(h); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if h.secret == "" {
+		log.Print("warning: SNOWFLAKE_FUNCTION_SECRET is not set; rely on the API gateway to authenticate Snowflake")
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", h)
+	health.NewService(flag.CommandLine, pol, h.bf).Register(mux)
+	log.Printf("external functions listening on http://%s (policy %s)", *addr, pol.Name)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,73 @@
+-- Blindfold external functions for Snowflake.
+--
+-- Snowflake calls external functions through a cloud API gateway (AWS API
+-- Gateway, Azure API Management or Google Cloud API Gateway) that forwards
+-- to this service. Create the gateway first, with one POST route per
+-- function (/tokenize, /tokenize_with_mapping, /detokenize), then run this
+-- as a role that can create integrations (ACCOUNTADMIN or one with CREATE
+-- INTEGRATION).
+
+-- 1. The API integration: which gateway Snowflake may call, and as whom.
+CREATE OR REPLACE API INTEGRATION blindfold_api
+  API_PROVIDER = aws_api_gateway
+  API_AWS_ROLE_ARN = 'arn:aws:iam::123456789012:role/snowflake-blindfold'
+  API_ALLOWED_PREFIXES = ('https://abc123.execute-api.us-east-1.amazonaws.com/prod/')
+  ENABLED = TRUE;
+
+-- DESCRIBE INTEGRATION blindfold_api; gives API_AWS_IAM_USER_ARN and
+-- API_AWS_EXTERNAL_ID for the role's trust policy.
+
+-- 2. The functions. HEADERS sends the shared secret on every call as
+-- sf-custom-secret; set SNOWFLAKE_FUNCTION_SECRET to the same value.
+-- MAX_BATCH_ROWS bounds the work per request.
+CREATE OR REPLACE EXTERNAL FUNCTION blindfold_tokenize(text VARCHAR)
+  RETURNS VARCHAR
+  RETURNS NULL ON NULL INPUT
+  VOLATILE
+  API_INTEGRATION = blindfold_api
+  HEADERS = ('secret' = 'change-me')
+  MAX_BATCH_ROWS = 200
+  AS 'https://abc123.execute-api.us-east-1.amazonaws.com/prod/tokenize';
+
+CREATE OR REPLACE EXTERNAL FUNCTION blindfold_tokenize_with_mapping(text VARCHAR)
+  RETURNS VARIANT
+  RETURNS NULL ON NULL INPUT
+  VOLATILE
+  API_INTEGRATION = blindfold_api
+  HEADERS = ('secret' = 'change-me')
+  MAX_BATCH_ROWS = 200
+  AS 'https://abc123.execute-api.us-east-1.amazonaws.com/prod/tokenize_with_mapping';
+
+CREATE OR REPLACE EXTERNAL FUNCTION blindfold_detokenize(text VARCHAR, mapping VARIANT)
+  RETURNS VARCHAR
+  VOLATILE
+  API_INTEGRATION = blindfold_api
+  HEADERS = ('secret' = 'change-me')
+  MAX_BATCH_ROWS = 200
+  AS 'https://abc123.execute-api.us-east-1.amazonaws.com/prod/detokenize';
+
+-- 3. Use them.
+
+-- Scrub in place before an export: the values are gone for good.
+CREATE OR REPLACE TABLE support.tickets_for_llm AS
+  SELECT id, created_at, blindfold_tokenize(body) AS body
+  FROM support.tickets;
+
+-- Keep a way back: text for the LLM workflow, mappings in a schema only a
+-- few roles can read.
+CREATE OR REPLACE TABLE support.tickets_tokenized AS
+  SELECT id, blindfold_tokenize_with_mapping(body) AS t
+  FROM support.tickets;
+
+CREATE OR REPLACE TABLE support.tickets_protected AS
+  SELECT id, t:text::VARCHAR AS body FROM support.tickets_tokenized;
+
+CREATE OR REPLACE TABLE restricted.ticket_mappings AS
+  SELECT id, t:mapping AS mapping FROM support.tickets_tokenized;
+
+DROP TABLE support.tickets_tokenized;
+
+-- Restore model output for a ticket, for roles that may see the values.
+SELECT s.id, blindfold_detokenize(s.summary, m.mapping) AS summary
+FROM support.ticket_summaries s
+JOIN restricted.ticket_mappings m USING (id);
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// slowTokenizer delays Tokenize, as a Blindfold API under load would,
+// giving up when ctx does.
+type slowTokenizer struct {
+	bfclient.Client
+	delay atomic.Int64 // time.Duration
+}
+
+func (s *slowTokenizer) Tokenize(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	select {
+	case <-time.After(time.Duration(s.delay.Load())):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return s.Client.Tokenize(ctx, text, opts...)
+}
+
+// fakeModel streams chat completions over SSE: a reply that reuses the
+// tokens it was sent, 8 bytes per chunk so placeholders get split. With
+// stallAfter set, it stops sending after that many chunks and waits. It
+// counts the calls it got and those whose caller went away before the
+// reply was done.
+type fakeModel struct {
+	stallAfter atomic.Int32
+	calls      atomic.Int32
+	canceled   atomic.Int32
+}
+
+func (f *fakeModel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.calls.Add(1)
+	var req openai.ChatCompletionRequest
+	json.NewDecoder(r.Body).Decode(&req)
+	tokens := mapping.TokenPattern.FindAllString(req.Messages[len(req.Messages)-1].Content, -1)
+	reply := "Thanks, I've sent a reset link to " + strings.Join(tokens, " and ") + ". It expires in an hour."
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	stallAfter := int(f.stallAfter.Load())
+	for i, n := 0, 0; i < len(reply); i, n = i+8, n+1 {
+		if stallAfter > 0 && n == stallAfter {
+			<-r.Context().Done()
+			f.canceled.Add(1)
+			return
+		}
+		chunk := openai.ChatCompletionStreamResponse{Choices: []openai.ChatCompletionStreamChoice{{
+			Delta: openai.ChatCompletionStreamChoiceDelta{Content: reply[i:min(i+8, len(reply))]},
+		}}}
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		w.(http.Flusher).Flush()
+		select {
+		case <-time.After(20 * time.Millisecond):
+		case <-r.Context().Done():
+			f.canceled.Add(1)
+			return
+		}
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// demo runs s against fakeModel with short budgets, through the four ways
+// a request ends.
+func demo(s *server) error {
+	model := &fakeModel{}
+	up := httptest.NewServer(model)
+	defer up.Close()
+	oc := openai.DefaultConfig("demo")
+	oc.BaseURL = up.URL
+	s.llm = openai.NewClientWithConfig(oc)
+
+	slow := &slowTokenizer{Client: s.bf}
+	s.bf = slow
+	s.b = budgets{tokenize: 200 * time.Millisecond, firstToken: time.Second, idle: 300 * time.Millisecond, llm: 5 * time.Second, write: time.Second}
+	fmt.Printf("Budgets: tokenize %s, first token %s, idle %s, llm %s, write %s\n",
+		s.b.tokenize, s.b.firstToken, s.b.idle, s.b.llm, s.b.write)
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	log.SetFlags(0)
+	log.SetPrefix("  server: ")
+	log.SetOutput(os.Stdout)
+
+	report := func(conversation string) {
+		m, _ := s.sessions.Load(context.Background(), conversation)
+		tokens := make([]string, 0, len(m))
+		for token := range m {
+			tokens = append(tokens, token)
+		}
+		sort.Strings(tokens)
+		fmt.Printf("  model calls %d, upstream canceled %d; conversation %s holds %v\n",
+			model.calls.Load(), model.canceled.Load(), conversation, tokens)
+	}
+
+	fmt.Println("\n── 1. Everything in time")
+	chat(srv.URL, "c1", "I'm locked out. Email me at omar.haddad@example.com", 0)
+	report("c1")
+
+	fmt.Println("\n── 2. Blindfold is slow (tokenize takes 1s)")
+	slow.delay.Store(int64(time.Second))
+	chat(srv.URL, "c2", "Reset my password, I'm lina.k@example.com", 0)
+	slow.delay.Store(0)
+	report("c2")
+
+	fmt.Println("\n── 3. The model stalls after 3 chunks")
+	model.stallAfter.Store(3)
+	chat(srv.URL, "c1", "Also cc my manager, dana.levi@example.com", 0)
+	model.stallAfter.Store(0)
+	report("c1")
+
+	fmt.Println("\n── 4. The caller hangs up after 2 chunks")
+	chat(srv.URL, "c1", "Or use my personal address, omar.h@example.org", 2)
+	time.Sleep(100 * time.Millisecond) // let the server notice
+	report("c1")
+	return nil
+}
+
+// chat posts a message and prints the streamed reply as it arrives. With
+// hangUpAfter > 0, it disconnects after reading that many chunks.
+func chat(base, conversation, message string, hangUpAfter int) {
+	fmt.Printf("  → %s: %q\n", conversation, message)
+	body, _ := json.Marshal(chatRequest{Conversation: conversation, Message: message})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, base+"/chat", bytes.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Printf("  ← %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		fmt.Printf("  ← %s: %s", resp.Status, msg)
+		return
+	}
+
+	var got strings.Builder
+	r := bufio.NewReader(resp.Body)
+	buf := make([]byte, 64)
+	for chunks := 0; ; chunks++ {
+		if hangUpAfter > 0 && chunks == hangUpAfter {
+			cancel()
+			fmt.Printf("  ← %q … hung up\n", got.String())
+			return
+		}
+		n, err := r.Read(buf)
+		got.Write(buf[:n])
+		if err == io.EOF {
+			fmt.Printf("  ← %q\n", got.String())
+			return
+		}
+		if err != nil {
+			fmt.Printf("  ← %q … cut off: %v\n", got.String(), err)
+			return
+		}
+	}
+}
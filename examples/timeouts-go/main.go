@@ -0,0 +1,104 @@
+// Timeouts + Blindfold: Give every stage of a protected call its own deadline.
+//
+// Serves POST /chat, which tokenizes a message, streams the model's reply
+// and restores it chunk by chunk. Tokenizing, waiting for the model's
+// first token, waiting between chunks, the whole model call and writing
+// each chunk back each have a budget, and a request that runs over one
+// fails naming the stage. A caller that hangs up cancels whichever stage
+// is running, down to the upstream stream, and a request abandoned
+// mid-pipeline takes the tokens it added to the conversation with it.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/config"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+)
+
+func main() {
+	// Every flag can also be set as TIMEOUTS_<FLAG>
+	cfg := config.New(flag.CommandLine, "TIMEOUTS")
+	addr := flag.String("addr", "127.0.0.1:8080", "listen address")
+	policiesFile := flag.String("policies", "", "policy file (YAML or JSON); empty = built-in policies only")
+	policy := flag.String("policy", "basic", "policy to apply, by name")
+	model := flag.String("model", "gpt-4o-mini", "OpenAI model")
+	var b budgets
+	flag.DurationVar(&b.tokenize, "tokenize-timeout", 2*time.Second, "budget for tokenizing the message")
+	flag.DurationVar(&b.firstToken, "first-token-timeout", 10*time.Second, "budget for the model's first streamed chunk")
+	flag.DurationVar(&b.idle, "idle-timeout", 5*time.Second, "budget between streamed chunks")
+	flag.DurationVar(&b.llm, "llm-timeout", 60*time.Second, "budget for the whole model call")
+	flag.DurationVar(&b.write, "write-timeout", 5*time.Second, "budget for delivering each restored chunk to the caller")
+	demoMode := flag.Bool("demo", false, "run the scenarios against a fake model instead of serving")
+	cfg.Check("tokenize-timeout", positive(&b.tokenize))
+	cfg.Check("first-token-timeout", positive(&b.firstToken))
+	cfg.Check("idle-timeout", positive(&b.idle))
+	cfg.Check("llm-timeout", positive(&b.llm))
+	cfg.Check("write-timeout", positive(&b.write))
+	if err := cfg.Parse(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	pol, err := policyconf.Resolve(*policiesFile, *policy)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// API key is optional — omit it to run in local mode (regex-based, offline).
+	// No SDK retries: the tokenize budget, not the SDK, decides how long a
+	// slow API is waited for
+	bf := bfclient.FromEnv(append(pol.ClientOptions(), blindfold.WithMaxRetries(0))...)
+	s := &server{
+		bf:       pol.Wrap(bf),
+		model:    *model,
+		b:        b,
+		sessions: protect.NewMemoryStore(),
+	}
+
+	if *demoMode {
+		if err := demo(s); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	key := os.Getenv("OPENAI_API_KEY")
+	if key == "" {
+		log.Fatal("OPENAI_API_KEY is required (or run with -demo)")
+	}
+	oc := openai.DefaultConfig(key)
+	if base := os.Getenv("OPENAI_BASE_URL"); base != "" {
+		oc.BaseURL = base
+	}
+	s.llm = openai.NewClientWithConfig(oc)
+
+	mux := http.NewServeMux()
+	mux.Handle("/chat", s)
+	log.Printf("listening on http://%s/chat (policy %s)", *addr, pol.Name)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		log.Print(err)
+	}
+}
+
+func positive(d *time.Duration) func() error {
+	return func() error {
+		if *d <= 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/streamdetok"
+)
+
+const systemPrompt = "You are a support agent. Keep placeholders like <Email Address_1> exactly as they are."
+
+// budgets are the deadlines of one request, stage by stage. Each is
+// derived from the request's context, so a caller hanging up cancels
+// whichever stage is running.
+type budgets struct {
+	tokenize   time.Duration // the Tokenize call
+	firstToken time.Duration // from sending the prompt to the first streamed chunk
+	idle       time.Duration // between streamed chunks after the first
+	llm        time.Duration // the whole model call, however steadily it streams
+	write      time.Duration // restoring and delivering each chunk to the caller
+}
+
+// stageError is a stage running out of its budget. It is the cause of the
+// stage's context, so the error a request fails with says which stage
+// was slow instead of a bare "context deadline exceeded".
+type stageError struct {
+	stage  string
+	budget time.Duration
+}
+
+func (e *stageError) Error() string { return fmt.Sprintf("%s: timed out after %s", e.stage, e.budget) }
+
+// Unwrap makes a stage timeout a deadline, which bferrors classifies as
+// Canceled.
+func (e *stageError) Unwrap() error { return context.DeadlineExceeded }
+
+// cause returns the stage timeout behind err if ctx ran out, and err
+// otherwise: a caller hanging up stays context.Canceled.
+func cause(ctx context.Context, err error) error {
+	var se *stageError
+	if errors.As(context.Cause(ctx), &se) {
+		return se
+	}
+	return err
+}
+
+// server answers POST /chat, streaming the model's reply as plain text
+// with the customer's values restored.
+type server struct {
+	bf    bfclient.Client
+	llm   *openai.Client
+	model string
+	b     budgets
+	// sessions keeps each conversation's mapping, so a value seen in an
+	// earlier turn keeps its token.
+	sessions *protect.MemoryStore
+	mu       sync.Mutex // serializes read-modify-write of sessions
+}
+
+type chatRequest struct {
+	Conversation string `json:"conversation"`
+	Message      string `json:"message"`
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Conversation == "" {
+		http.Error(w, "want {\"conversation\": ..., \"message\": ...}", http.StatusBadRequest)
+		return
+	}
+	// r.Context() is canceled when the caller disconnects; every stage
+	// below derives from it
+	ctx := r.Context()
+
+	safe, m, rollback, err := s.tokenize(ctx, req.Conversation, req.Message)
+	if err != nil {
+		log.Printf("conversation %s: %v", req.Conversation, err)
+		http.Error(w, err.Error(), bferrors.HTTPStatus(err))
+		return
+	}
+
+	started, err := s.stream(ctx, w, safe, m)
+	if err == nil {
+		return
+	}
+	// Abandoned mid-pipeline: the reply never reached the caller, so the
+	// values this turn added to the conversation are dropped rather than
+	// kept for turns that will never refer to them
+	rollback()
+	log.Printf("conversation %s: abandoned: %v", req.Conversation, err)
+	if !started {
+		http.Error(w, err.Error(), bferrors.HTTPStatus(err))
+		return
+	}
+	// The status line is gone. Abort the connection so the caller sees a
+	// truncated response instead of a partial reply that looks complete
+	panic(http.ErrAbortHandler)
+}
+
+// tokenize protects text under the tokenize budget and adds its tokens to
+// the conversation. rollback removes the tokens this call added.
+func (s *server) tokenize(ctx context.Context, conversation, text string) (safe string, m map[string]string, rollback func(), err error) {
+	tctx, cancel := context.WithTimeoutCause(ctx, s.b.tokenize, &stageError{"tokenize", s.b.tokenize})
+	defer cancel()
+	res, err := s.bf.Tokenize(tctx, text)
+	if err != nil {
+		return "", nil, nil, bferrors.Wrap("blindfold", cause(tctx, err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before, _ := s.sessions.Load(ctx, conversation)
+	merged := mapping.Merge(before, res.Mapping)
+	var added []string
+	for token := range merged.Mapping {
+		if _, ok := before[token]; !ok {
+			added = append(added, token)
+		}
+	}
+	s.sessions.Save(ctx, conversation, merged.Mapping)
+
+	rollback = func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// Reload rather than restore before: another turn of the same
+		// conversation may have added tokens since
+		current, _ := s.sessions.Load(context.Background(), conversation)
+		for _, token := range added {
+			delete(current, token)
+		}
+		s.sessions.Save(context.Background(), conversation, current)
+	}
+	return merged.Rewrite(1, res.Text), merged.Mapping, rollback, nil
+}
+
+// stream sends safe to the model and writes the restored reply to w as it
+// arrives. started reports whether any of it was written.
+func (s *server) stream(ctx context.Context, w http.ResponseWriter, safe string, m map[string]string) (started bool, err error) {
+	llmCtx, cancel := context.WithTimeoutCause(ctx, s.b.llm, &stageError{"llm", s.b.llm})
+	defer cancel()
+	// A stream that stalls is cut short as well: the watchdog cancels the
+	// call unless a chunk arrives in time. Canceling the context closes
+	// the upstream connection, so the model stops generating too
+	llmCtx, stall := context.WithCancelCause(llmCtx)
+	defer stall(nil)
+	var waiting atomic.Pointer[stageError]
+	waiting.Store(&stageError{"first token", s.b.firstToken})
+	watchdog := time.AfterFunc(s.b.firstToken, func() { stall(waiting.Load()) })
+	defer watchdog.Stop()
+
+	st, err := s.llm.CreateChatCompletionStream(llmCtx, openai.ChatCompletionRequest{
+		Model: s.model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: safe},
+		},
+	})
+	if err != nil {
+		return false, bferrors.Wrap("openai", cause(llmCtx, err))
+	}
+	defer st.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rc := http.NewResponseController(w)
+	// A caller that stops reading holds the upstream stream open; the
+	// write deadline turns that into a failed write
+	write := func(text string) error {
+		if text == "" {
+			return nil
+		}
+		rc.SetWriteDeadline(time.Now().Add(s.b.write))
+		if _, err := io.WriteString(w, text); err != nil {
+			return fmt.Errorf("restore: %w", err)
+		}
+		started = true
+		return rc.Flush()
+	}
+
+	d := streamdetok.New(m)
+	for {
+		chunk, err := st.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return started, bferrors.Wrap("openai", cause(llmCtx, err))
+		}
+		waiting.Store(&stageError{"idle stream", s.b.idle})
+		watchdog.Reset(s.b.idle)
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		if err := write(d.Push(chunk.Choices[0].Delta.Content)); err != nil {
+			return started, err
+		}
+	}
+	watchdog.Stop()
+	// Only a finished stream is flushed. On every early return, the text
+	// d holds back, possibly half a token, is dropped with it
+	return started, write(d.Flush())
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+)
+
+func newTestServer(t *testing.T) (*server, *fakeModel, *slowTokenizer, string) {
+	model := &fakeModel{}
+	up := httptest.NewServer(model)
+	t.Cleanup(up.Close)
+	oc := openai.DefaultConfig("test")
+	oc.BaseURL = up.URL
+	slow := &slowTokenizer{Client: blindfold.New(blindfold.WithMode("local"))}
+	s := &server{
+		bf:       slow,
+		llm:      openai.NewClientWithConfig(oc),
+		b:        budgets{tokenize: 100 * time.Millisecond, firstToken: time.Second, idle: 100 * time.Millisecond, llm: 5 * time.Second, write: time.Second},
+		sessions: protect.NewMemoryStore(),
+	}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return s, model, slow, srv.URL
+}
+
+func post(t *testing.T, base, conversation, message string) (int, string, error) {
+	t.Helper()
+	body, _ := json.Marshal(chatRequest{Conversation: conversation, Message: message})
+	resp, err := http.Post(base+"/chat", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	out, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(out), err
+}
+
+func TestRestoresAndKeepsTokens(t *testing.T) {
+	s, _, _, base := newTestServer(t)
+	status, out, err := post(t, base, "c1", "Mail omar@example.com")
+	if status != 200 || err != nil || !strings.Contains(out, "reset link to omar@example.com.") {
+		t.Fatalf("%d %q %v", status, out, err)
+	}
+	if m, _ := s.sessions.Load(context.Background(), "c1"); m["<Email Address_1>"] != "omar@example.com" {
+		t.Errorf("session = %v", m)
+	}
+}
+
+func TestTokenizeTimeoutSkipsModel(t *testing.T) {
+	_, model, slow, base := newTestServer(t)
+	slow.delay.Store(int64(time.Second))
+	status, out, _ := post(t, base, "c1", "Mail omar@example.com")
+	if status != http.StatusGatewayTimeout || !strings.Contains(out, "tokenize: timed out after 100ms") {
+		t.Errorf("%d %q", status, out)
+	}
+	if model.calls.Load() != 0 {
+		t.Error("model was called")
+	}
+}
+
+func TestStallDropsTurnTokens(t *testing.T) {
+	s, model, _, base := newTestServer(t)
+	post(t, base, "c1", "Mail omar@example.com")
+	model.stallAfter.Store(2)
+	status, out, err := post(t, base, "c1", "And lina@example.com")
+	if status != 200 || err == nil || strings.Contains(out, "lina") {
+		t.Errorf("%d %q %v: want a truncated reply", status, out, err)
+	}
+	// The fake sees the disconnect shortly after the server makes it
+	for i := 0; model.canceled.Load() == 0 && i < 50; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if model.canceled.Load() != 1 {
+		t.Error("upstream stream not canceled")
+	}
+	m, _ := s.sessions.Load(context.Background(), "c1")
+	if len(m) != 1 || m["<Email Address_1>"] != "omar@example.com" {
+		t.Errorf("session = %v, want the first turn's token only", m)
+	}
+}
@@ -0,0 +1,160 @@
+// Token cost tracking + Blindfold: Measure what placeholder substitution costs.
+//
+// Tokenization changes the prompt the model is billed for: "john@acme.com"
+// becomes "<Email Address_1>", which may encode to more or fewer LLM tokens.
+// This example counts prompt tokens before and after tokenization with the
+// model's own encoding, records billed usage and cost per request from the
+// API response, and reports how much protection inflates (or shrinks) the
+// bill. The raw prompt is only ever counted locally — never sent.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/joho/godotenv"
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+)
+
+const systemPrompt = "You are a helpful customer support assistant. Reply in two sentences."
+
+// Usage is the token accounting for one request.
+type Usage struct {
+	RawPrompt       int // prompt tokens if the raw text had been sent (local count)
+	TokenizedPrompt int // prompt tokens of the tokenized text (local count)
+	BilledPrompt    int // prompt tokens reported by the API
+	BilledOutput    int // completion tokens reported by the API
+	Cost            float64
+}
+
+// Inflation is the relative change in prompt size caused by tokenization.
+func (u Usage) Inflation() float64 {
+	if u.RawPrompt == 0 {
+		return 0
+	}
+	return float64(u.TokenizedPrompt-u.RawPrompt) / float64(u.RawPrompt)
+}
+
+// counter counts chat prompt tokens the way OpenAI bills them: content
+// tokens plus a fixed overhead per message and for priming the reply.
+type counter struct {
+	enc *tiktoken.Tiktoken
+}
+
+func (c counter) prompt(messages ...string) int {
+	n := 3 // every reply is primed with <|start|>assistant<|message|>
+	for _, m := range messages {
+		n += 3 + len(c.enc.Encode(m, nil, nil))
+	}
+	return n
+}
+
+func protectedCall(ctx context.Context, bf bfclient.Client, oa *openai.Client, tc counter, price Price, userMessage, policy, model string, dryRun bool) (Usage, error) {
+	u := Usage{RawPrompt: tc.prompt(systemPrompt, userMessage)}
+
+	// 1. Tokenize — then count the prompt the model will actually see
+	tokenized, err := bf.Tokenize(ctx, userMessage, blindfold.WithCallPolicy(policy))
+	if err != nil {
+		return u, fmt.Errorf("tokenize: %w", err)
+	}
+	u.TokenizedPrompt = tc.prompt(systemPrompt, tokenized.Text)
+	if dryRun {
+		return u, nil
+	}
+
+	// 2. Send tokenized text to OpenAI and record billed usage
+	completion, err := oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: tokenized.Text},
+		},
+	})
+	if err != nil {
+		return u, fmt.Errorf("openai: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return u, errors.New("openai: empty response")
+	}
+	u.BilledPrompt = completion.Usage.PromptTokens
+	u.BilledOutput = completion.Usage.CompletionTokens
+	u.Cost = price.Cost(u.BilledPrompt, u.BilledOutput)
+
+	// 3. Detokenize — free, runs client-side
+	_ = bf.Detokenize(completion.Choices[0].Message.Content, tokenized.Mapping)
+	return u, nil
+}
+
+func main() {
+	_ = godotenv.Load()
+	model := flag.String("model", "gpt-4o-mini", "OpenAI model")
+	policy := flag.String("policy", "basic", "Blindfold policy to apply")
+	dryRun := flag.Bool("dry-run", false, "only compare prompt sizes; skip OpenAI calls")
+	inputPrice := flag.Float64("input-price", 0, "USD per 1M input tokens (default: built-in price for -model)")
+	outputPrice := flag.Float64("output-price", 0, "USD per 1M output tokens (default: built-in price for -model)")
+	flag.Parse()
+
+	price := Price{Input: *inputPrice, Output: *outputPrice}
+	if price == (Price{}) {
+		var err error
+		if price, err = priceFor(*model); err != nil && !*dryRun {
+			log.Fatal(err)
+		}
+	}
+
+	// Encodings ship with the loader, so counting works offline
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+	enc, err := tiktoken.EncodingForModel(*model)
+	if err != nil {
+		log.Fatalf("encoding for %s: %v", *model, err)
+	}
+	tc := counter{enc: enc}
+
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	bf := bfclient.FromEnv()
+	oa := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
+
+	messages := []string{
+		"Hi, I'm John Smith. My email is john.smith@example.com and my phone is +1 555-123-4567.",
+		"Refund card 4111 1111 1111 1111 and email the receipt to maria.garcia@example.org.",
+		"Please update my address: 221B Baker Street, London NW1 6XE. Reach me at s.holmes@example.co.uk.",
+		"My order hasn't arrived yet. Can you check the status?",
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "#\traw prompt\ttokenized\tchange\tbilled in\tbilled out\tcost (USD)\t")
+	var total Usage
+	for i, msg := range messages {
+		u, err := protectedCall(context.Background(), bf, oa, tc, price, msg, *policy, *model, *dryRun)
+		if err != nil {
+			log.Printf("request %d failed: %v", i+1, err)
+			continue
+		}
+		fmt.Fprintf(w, "%d\t%d\t%d\t%+.1f%%\t%d\t%d\t%.6f\t\n", i+1, u.RawPrompt, u.TokenizedPrompt, 100*u.Inflation(), u.BilledPrompt, u.BilledOutput, u.Cost)
+		total.RawPrompt += u.RawPrompt
+		total.TokenizedPrompt += u.TokenizedPrompt
+		total.BilledPrompt += u.BilledPrompt
+		total.BilledOutput += u.BilledOutput
+		total.Cost += u.Cost
+	}
+	fmt.Fprintf(w, "total\t%d\t%d\t%+.1f%%\t%d\t%d\t%.6f\t\n", total.RawPrompt, total.TokenizedPrompt, 100*total.Inflation(), total.BilledPrompt, total.BilledOutput, total.Cost)
+	w.Flush()
+
+	extra := total.TokenizedPrompt - total.RawPrompt
+	fmt.Printf("\nTokenization changed prompt size by %+d tokens (%+.1f%%), about %+.6f USD at %s input pricing.\n",
+		extra, 100*total.Inflation(), price.Cost(extra, 0), *model)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Price is the USD cost per million tokens.
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// prices lists published per-million-token list prices. They change; check
+// your provider's pricing page and override with -input-price and
+// -output-price when they do.
+var prices = map[string]Price{
+	"gpt-4o-mini":  {Input: 0.15, Output: 0.60},
+	"gpt-4o":       {Input: 2.50, Output: 10.00},
+	"gpt-4.1-mini": {Input: 0.40, Output: 1.60},
+	"gpt-4.1":      {Input: 2.00, Output: 8.00},
+}
+
+// priceFor returns the price of model, matching dated snapshots such as
+// gpt-4o-2024-08-06 by their longest known prefix.
+func priceFor(model string) (Price, error) {
+	if p, ok := prices[model]; ok {
+		return p, nil
+	}
+	best := ""
+	for name := range prices {
+		if strings.HasPrefix(model, name+"-") && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return Price{}, fmt.Errorf("no price for model %q; pass -input-price and -output-price", model)
+	}
+	return prices[best], nil
+}
+
+// Cost returns the USD cost of a call.
+func (p Price) Cost(inputTokens, outputTokens int) float64 {
+	return (float64(inputTokens)*p.Input + float64(outputTokens)*p.Output) / 1e6
+}
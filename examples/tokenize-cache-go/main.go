@@ -0,0 +1,83 @@
+// Tokenization cache + Blindfold: Skip detection for inputs you've already seen.
+//
+// Batch jobs and retries send the same text over and over — canned replies,
+// templated notifications, a job re-run after a crash. This example wraps
+// the client with a content-hash cache (in-memory LRU, or Redis to share it
+// across processes) and reports hit/miss metrics for a run with repeats.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/cache"
+)
+
+// sampleInputs returns n messages drawn from a small set of distinct texts,
+// the way notification and support-macro traffic repeats in practice.
+func sampleInputs(n, distinct int) []string {
+	texts := make([]string, distinct)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("Hi, your order #%d ships to customer%d@example.com. Questions? Call +1 415-555-%04d.", 5000+i, i, i)
+	}
+	rng := rand.New(rand.NewSource(1))
+	inputs := make([]string, n)
+	for i := range inputs {
+		inputs[i] = texts[rng.Intn(distinct)]
+	}
+	return inputs
+}
+
+func newStore(redisAddr string) cache.Store {
+	if redisAddr == "" {
+		return cache.NewLRU(10_000)
+	}
+	return cache.NewRedis(redis.NewClient(&redis.Options{Addr: redisAddr}), time.Hour)
+}
+
+func main() {
+	_ = godotenv.Load()
+
+	n := flag.Int("n", 2000, "number of inputs to tokenize")
+	distinct := flag.Int("distinct", 200, "number of distinct texts among the inputs")
+	redisAddr := flag.String("redis", os.Getenv("REDIS_ADDR"), "Redis address for a shared cache (default: in-memory LRU)")
+	policy := flag.String("policy", "basic", "Blindfold policy to apply")
+	flag.Parse()
+
+	// API key is optional — omit it to run in local mode (regex-based, offline).
+	// The namespace ties cached results to the policy they were produced with.
+	bf := cache.Wrap(bfclient.FromEnv(), newStore(*redisAddr), *policy)
+
+	ctx := context.Background()
+	inputs := sampleInputs(*n, *distinct)
+	start := time.Now()
+	for i, text := range inputs {
+		if _, err := bf.Tokenize(ctx, text, blindfold.WithCallPolicy(*policy)); err != nil {
+			log.Fatalf("input %d: %v", i, err)
+		}
+	}
+	elapsed := time.Since(start)
+
+	stats := bf.Stats()
+	fmt.Printf("Tokenized %d inputs (%d distinct) in %s\n", len(inputs), *distinct, elapsed.Round(time.Millisecond))
+	fmt.Printf("  cache hits:   %d\n", stats.Hits)
+	fmt.Printf("  cache misses: %d (detection calls made)\n", stats.Misses)
+	fmt.Printf("  hit rate:     %.1f%%\n", stats.HitRate()*100)
+	if stats.Errors > 0 {
+		fmt.Printf("  store errors: %d (served by the backend instead)\n", stats.Errors)
+	}
+}
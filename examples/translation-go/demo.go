@@ -0,0 +1,83 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+// demoScripts are keyed by a phrase of the source. In a translation,
+// {Person} is the source's first Person token, {Person~Persona} the same
+// token with its type translated, and {Email Address~[]} the same token in
+// brackets.
+var demoScripts = map[string]struct{ translation, repaired string }{
+	"refund of $42.00": {
+		translation: "Hola {Person}, hoy enviamos tu reembolso de 42,00 $ a la tarjeta {Credit Card Number}. Si no ha llegado en 5 días, responde a {Email Address} y lo reclamaremos.",
+	},
+	"technician visit": {
+		translation: "{Person~Persona} te llamará al {Phone Number~Número de teléfono} mañana entre las 9 y las 11 para concertar la visita del técnico.",
+	},
+	"tracking link": {
+		translation: "{Person}, tu nueva dirección de entrega está confirmada y te enviaremos el enlace de seguimiento por correo.",
+		repaired:    "{Person}, tu nueva dirección de entrega está confirmada y enviaremos el enlace de seguimiento a {Email Address}.",
+	},
+	"the old number": {
+		translation: "{Person} nos pidió enviar la factura a {Email Address~[]} y llamar al {Phone Number}, no al número antiguo, por la tarjeta que termina en 4444.",
+	},
+}
+
+var demoSlot = regexp.MustCompile(`\{([^{}~]+)(?:~([^{}]*))?\}`)
+
+// fakeModel answers chat completions with scripted Spanish translations
+// of messages.txt, picked by a phrase of the source. Three of them make
+// the mistakes real models make: a translated token type, a bracketed
+// token, and a token dropped while rephrasing. The repair prompt gets the
+// correct translation. Messages it has no script for come back unchanged.
+func fakeModel(req openai.ChatCompletionRequest) string {
+	prompt, _, _ := strings.Cut(req.Messages[0].Content, ".")
+	in := fakeopenai.Prompt(req)
+	source := in
+	if prompt == "Repair" {
+		source, _, _ = strings.Cut(strings.TrimPrefix(in, "Source:\n"), "\n\nTranslation:")
+	}
+
+	out := source
+	for phrase, s := range demoScripts {
+		if !strings.Contains(source, phrase) {
+			continue
+		}
+		out = fill(s.translation, source)
+		if prompt == "Repair" && s.repaired != "" {
+			out = fill(s.repaired, source)
+		}
+	}
+	return out
+}
+
+// fill puts the source's tokens into a scripted translation.
+func fill(script, source string) string {
+	first := make(map[string]string) // type → first token of it
+	for _, token := range mapping.TokenPattern.FindAllString(source, -1) {
+		if typ, _, ok := mapping.ParseToken(token); ok && first[typ] == "" {
+			first[typ] = token
+		}
+	}
+	return demoSlot.ReplaceAllStringFunc(script, func(slot string) string {
+		m := demoSlot.FindStringSubmatch(slot)
+		token := first[m[1]]
+		_, n, _ := mapping.ParseToken(token)
+		switch m[2] {
+		case "":
+			return token
+		case "[]":
+			return "[" + m[1] + " " + strconv.Itoa(n) + "]"
+		default:
+			return "<" + m[2] + "_" + strconv.Itoa(n) + ">"
+		}
+	})
+}
@@ -0,0 +1,225 @@
+// Translation + Blindfold: Translate customer messages without sending
+// the names, numbers and addresses in them, and make sure every one of
+// them comes back.
+//
+// A translation model treats placeholders as text: it may translate the
+// type (<Person_1> becomes <Persona_1>), swap the brackets, or drop a
+// token while rephrasing, and the value it stood for silently disappears
+// from the restored translation. So every translation is checked against
+// the source's tokens before it is restored. Mangled placeholders that
+// can only mean one token are put back locally; anything else goes to a
+// repair pass that shows the model what it lost. A translation that
+// still fails the check is reported, never restored.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+const placeholderRule = " Values like <Person_1> and <Email Address_1> are placeholders for real data: copy each one exactly as written, " +
+	"in English, with its angle brackets, underscore and number. Never translate, reformat or drop a placeholder."
+
+// The first word names the prompt; the demo model dispatches on it.
+const (
+	translatePrompt = "Translate. Translate the user's message into %s. Keep its tone and formatting, and reply with the translation only." +
+		placeholderRule
+	repairPrompt = "Repair. A translation lost or changed some placeholders from its source. Return the translation with every " +
+		"placeholder of the source put back exactly as the source writes it, and nothing else changed. Reply with the translation only." +
+		placeholderRule
+)
+
+type pipeline struct {
+	protector *protect.Protector
+	oa        *openai.Client
+	model     string
+	to        string
+	repairs   int // model repair passes per message
+	stats     map[string]int
+}
+
+func (p *pipeline) ask(ctx context.Context, system, user string) (string, error) {
+	res, err := p.oa.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:       p.model,
+		Temperature: 0,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: system},
+			{Role: openai.ChatMessageRoleUser, Content: user},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(res.Choices) == 0 {
+		return "", fmt.Errorf("empty response")
+	}
+	return strings.TrimSpace(res.Choices[0].Message.Content), nil
+}
+
+// translate runs one message through the pipeline and returns the
+// restored translation.
+func (p *pipeline) translate(ctx context.Context, message string) (string, error) {
+	outcome := "intact"
+	res, err := p.protector.Run(ctx, message, func(tokenized string) (string, error) {
+		fmt.Printf("   tokenized:   %s\n", tokenized)
+		out, err := p.ask(ctx, fmt.Sprintf(translatePrompt, p.to), tokenized)
+		if err != nil {
+			return "", fmt.Errorf("translate: %w", err)
+		}
+		fmt.Printf("   translation: %s\n", out)
+		check := verify(tokenized, out)
+		fmt.Printf("   check:       %s\n", describe(check, tokenized))
+
+		if !check.OK() {
+			fixed, fixes := repairLocally(tokenized, out, check)
+			if len(fixes) > 0 {
+				var parts []string
+				for _, f := range fixes {
+					parts = append(parts, f.From+" → "+f.To)
+				}
+				out, check = fixed, verify(tokenized, fixed)
+				outcome = "repaired locally"
+				fmt.Printf("   local fix:   %s; %s\n", strings.Join(parts, ", "), describe(check, tokenized))
+			}
+		}
+		for pass := 1; !check.OK() && pass <= p.repairs; pass++ {
+			req := fmt.Sprintf("Source:\n%s\n\nTranslation:\n%s\n\n%s", tokenized, out, problems(check))
+			if out, err = p.ask(ctx, repairPrompt, req); err != nil {
+				return "", fmt.Errorf("repair: %w", err)
+			}
+			check = verify(tokenized, out)
+			outcome = "repaired by the model"
+			fmt.Printf("   repair %d:    %s\n                %s\n", pass, out, describe(check, tokenized))
+		}
+		if !check.OK() {
+			return "", fmt.Errorf("translation still loses data after %d repair pass(es): %s", p.repairs, problems(check))
+		}
+		return out, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	p.stats[outcome]++
+	return res.Text, nil
+}
+
+// describe sums up a check for the log.
+func describe(c Check, source string) string {
+	total := len(distinct(source))
+	if c.OK() {
+		return fmt.Sprintf("%d/%d tokens intact", total, total)
+	}
+	s := fmt.Sprintf("%d/%d tokens intact", total-len(c.Missing), total)
+	if len(c.Missing) > 0 {
+		s += "; missing " + strings.Join(c.Missing, ", ")
+	}
+	if len(c.Unknown) > 0 {
+		s += "; unknown " + strings.Join(c.Unknown, ", ")
+	}
+	return s
+}
+
+// problems tells the repair pass what to fix.
+func problems(c Check) string {
+	var lines []string
+	if len(c.Missing) > 0 {
+		lines = append(lines, "Placeholders missing from the translation: "+strings.Join(c.Missing, ", "))
+	}
+	if len(c.Unknown) > 0 {
+		lines = append(lines, "Not placeholders of the source: "+strings.Join(c.Unknown, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func distinct(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, token := range mapping.TokenPattern.FindAllString(text, -1) {
+		set[token] = true
+	}
+	return set
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, sc.Err()
+}
+
+func main() {
+	_ = godotenv.Load()
+	file := flag.String("policies", "policies.yaml", "policy file (YAML or JSON)")
+	name := flag.String("policy", "", "policy to apply, by name (default: the file's default)")
+	messages := flag.String("messages", "messages.txt", "messages to translate, one per line")
+	to := flag.String("to", "Spanish", "target language")
+	model := flag.String("model", openai.GPT4oMini, "OpenAI model")
+	repairs := flag.Int("repairs", 2, "repair passes before a translation is rejected")
+	demo := flag.Bool("demo", false, "use a scripted in-process translator instead of OpenAI (Spanish only)")
+	flag.Parse()
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*file, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// API key is optional — omit it to run in local mode (regex-based, offline)
+	protector := protect.New(bfclient.FromEnv(pol.ClientOptions()...))
+	protector.Policy = pol
+
+	cfg := openai.DefaultConfig(os.Getenv("OPENAI_API_KEY"))
+	if *demo {
+		srv := fakeopenai.Server(fakeModel)
+		defer srv.Close()
+		cfg = fakeopenai.Config(srv)
+	} else if os.Getenv("OPENAI_API_KEY") == "" {
+		log.Fatal("OPENAI_API_KEY is required (or run with -demo)")
+	}
+
+	lines, err := readLines(*messages)
+	if err != nil {
+		log.Fatal(err)
+	}
+	p := &pipeline{protector: protector, oa: openai.NewClientWithConfig(cfg), model: *model, to: *to, repairs: *repairs, stats: make(map[string]int)}
+	for i, line := range lines {
+		fmt.Printf("── Message %d: %s\n", i+1, line)
+		out, err := p.translate(ctx, line)
+		if err != nil {
+			p.stats["failed"]++
+			fmt.Printf("   ✗ %v\n\n", err)
+			continue
+		}
+		fmt.Printf("   → %s\n\n", out)
+	}
+	fmt.Printf("%d messages: %d intact, %d repaired locally, %d repaired by the model, %d failed\n",
+		len(lines), p.stats["intact"], p.stats["repaired locally"], p.stats["repaired by the model"], p.stats["failed"])
+	if p.stats["failed"] > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// Check is how a translation's tokens compare with the source's.
+type Check struct {
+	Missing []string // source tokens the translation lacks, in source order
+	Unknown []string // tokens the source doesn't have: invented or mangled
+}
+
+// OK reports whether every source token survived and nothing else looks
+// like one.
+func (c Check) OK() bool { return len(c.Missing) == 0 && len(c.Unknown) == 0 }
+
+// looseToken matches what a placeholder tends to become in translation:
+// the type translated (<Persona_1>, <Número de teléfono_1>), brackets
+// swapped ([Email Address 1]), or spacing changed (< Person_1 >).
+var looseToken = regexp.MustCompile(`[<\[]\s*(\p{L}[^<>\[\]\n]*?)[\s_-]*(\d+)\s*[>\]]`)
+
+// verify checks translated against the tokens of source. A token may
+// appear a different number of times, since languages differ in how
+// often they repeat a name; it may not disappear.
+func verify(source, translated string) Check {
+	var c Check
+	seen := make(map[string]bool)
+	for _, token := range mapping.TokenPattern.FindAllString(source, -1) {
+		if !seen[token] && !strings.Contains(translated, token) {
+			c.Missing = append(c.Missing, token)
+		}
+		seen[token] = true
+	}
+	for _, token := range mapping.TokenPattern.FindAllString(translated, -1) {
+		if !seen[token] {
+			c.Unknown = append(c.Unknown, token)
+		}
+	}
+	return c
+}
+
+// A Fix is one mangled placeholder put back.
+type Fix struct{ From, To string }
+
+// repairLocally puts back mangled placeholders that can only mean one
+// missing token: the same number, and either the only missing token with
+// that number or the only one whose type shares a four-letter prefix
+// with the mangled type (Persona → Person). Anything less certain is left
+// for the model; a wrong guess would put a real value in the wrong place.
+func repairLocally(source, translated string, c Check) (string, []Fix) {
+	known := distinct(source)
+	missing := append([]string(nil), c.Missing...)
+	var fixes []Fix
+	fixed := make(map[string]string) // a mangled token repeated is the same token
+	out := looseToken.ReplaceAllStringFunc(translated, func(s string) string {
+		if token, ok := fixed[s]; ok {
+			return token
+		}
+		if known[s] || len(missing) == 0 {
+			return s
+		}
+		sub := looseToken.FindStringSubmatch(s)
+		n, _ := strconv.Atoi(sub[2])
+		var sameNumber, sameType []int
+		for i, token := range missing {
+			typ, tn, _ := mapping.ParseToken(token)
+			if tn != n {
+				continue
+			}
+			sameNumber = append(sameNumber, i)
+			if prefix(typ) == prefix(sub[1]) {
+				sameType = append(sameType, i)
+			}
+		}
+		pick := -1
+		switch {
+		case len(sameNumber) == 1:
+			pick = sameNumber[0]
+		case len(sameType) == 1:
+			pick = sameType[0]
+		default:
+			return s
+		}
+		token := missing[pick]
+		missing = append(missing[:pick], missing[pick+1:]...)
+		fixes = append(fixes, Fix{From: s, To: token})
+		fixed[s] = token
+		return token
+	})
+	return out, fixes
+}
+
+// prefix is the first four letters of a type name, lowercased.
+func prefix(typ string) string {
+	var letters []rune
+	for _, r := range strings.ToLower(typ) {
+		if unicode.IsLetter(r) && len(letters) < 4 {
+			letters = append(letters, r)
+		}
+	}
+	return string(letters)
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/cache"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapstore"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fakeopenai"
+)
+
+// fakeVault stands in for a Vault server: AppRole login, token lookup and
+// renewal with a TTL and a max TTL, one KV v2 secret with versions, and a
+// derived transit key. Tokens expire for real, so a token nobody renews
+// stops working.
+type fakeVault struct {
+	ttl, maxTTL time.Duration
+	roleID      string
+	secretID    string
+
+	mu       sync.Mutex
+	tokens   map[string]fakeToken
+	issued   int
+	versions []map[string]string // KV secret secret/blindfold, version i+1
+	transit  []byte
+}
+
+type fakeToken struct {
+	created, expires time.Time
+}
+
+func newFakeVault(ttl, maxTTL time.Duration, data map[string]string) *fakeVault {
+	f := &fakeVault{ttl: ttl, maxTTL: maxTTL, roleID: "blindfold-chat", secretID: "demo-secret-id",
+		tokens: make(map[string]fakeToken), transit: make([]byte, 32)}
+	rand.Read(f.transit)
+	f.put(data)
+	return f
+}
+
+// put writes a new version of the KV secret.
+func (f *fakeVault) put(data map[string]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.versions = append(f.versions, data)
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var body map[string]string
+	json.NewDecoder(r.Body).Decode(&body)
+	now := time.Now()
+
+	if r.URL.Path == "/v1/auth/approle/login" {
+		if body["role_id"] != f.roleID || body["secret_id"] != f.secretID {
+			f.fail(w, http.StatusBadRequest, "invalid role or secret ID")
+			return
+		}
+		f.issued++
+		token := fmt.Sprintf("hvs.demo-%d", f.issued)
+		f.tokens[token] = fakeToken{created: now, expires: now.Add(f.ttl)}
+		f.json(w, map[string]any{"auth": f.auth(token)})
+		return
+	}
+	token := r.Header.Get("X-Vault-Token")
+	t, ok := f.tokens[token]
+	if !ok || now.After(t.expires) {
+		f.fail(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/v1/auth/token/lookup-self":
+		f.json(w, map[string]any{"data": map[string]any{"ttl": int(time.Until(t.expires).Seconds()), "renewable": true}})
+	case r.URL.Path == "/v1/auth/token/renew-self":
+		t.expires = now.Add(f.ttl)
+		if limit := t.created.Add(f.maxTTL); t.expires.After(limit) {
+			t.expires = limit
+		}
+		f.tokens[token] = t
+		f.json(w, map[string]any{"auth": f.auth(token)})
+	case r.URL.Path == "/v1/secret/data/blindfold":
+		f.json(w, map[string]any{"data": map[string]any{
+			"data":     f.versions[len(f.versions)-1],
+			"metadata": map[string]any{"version": len(f.versions)},
+		}})
+	case r.URL.Path == "/v1/transit/encrypt/mappings":
+		plaintext, _ := base64.StdEncoding.DecodeString(body["plaintext"])
+		aead := f.derive(body["context"])
+		nonce := make([]byte, aead.NonceSize())
+		rand.Read(nonce)
+		sealed := aead.Seal(nonce, nonce, plaintext, nil)
+		f.json(w, map[string]any{"data": map[string]string{"ciphertext": "vault:v1:" + base64.StdEncoding.EncodeToString(sealed)}})
+	case r.URL.Path == "/v1/transit/decrypt/mappings":
+		sealed, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(body["ciphertext"], "vault:v1:"))
+		aead := f.derive(body["context"])
+		if len(sealed) < aead.NonceSize() {
+			f.fail(w, http.StatusBadRequest, "invalid ciphertext")
+			return
+		}
+		plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+		if err != nil {
+			f.fail(w, http.StatusBadRequest, "cipher: message authentication failed")
+			return
+		}
+		f.json(w, map[string]any{"data": map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}})
+	default:
+		f.fail(w, http.StatusNotFound, "no handler for route "+r.URL.Path)
+	}
+}
+
+// logins returns how many tokens AppRole login issued.
+func (f *fakeVault) logins() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.issued
+}
+
+// auth is the lease of token as Vault reports it, in whole seconds.
+func (f *fakeVault) auth(token string) vaultAuth {
+	left := time.Until(f.tokens[token].expires).Round(time.Second)
+	return vaultAuth{ClientToken: token, LeaseDuration: int(left.Seconds()), Renewable: true}
+}
+
+// derive returns the transit key for a derivation context, as a key
+// created with derived=true does.
+func (f *fakeVault) derive(context string) cipher.AEAD {
+	mac := hmac.New(sha256.New, f.transit)
+	mac.Write([]byte(context))
+	block, _ := aes.NewCipher(mac.Sum(nil))
+	aead, _ := cipher.NewGCM(block)
+	return aead
+}
+
+func (f *fakeVault) json(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeVault) fail(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string][]string{"errors": {msg}})
+}
+
+// fakeModel answers chat completions with a reply that reuses the tokens it
+// was sent.
+func fakeModel(req openai.ChatCompletionRequest) string {
+	tokens := mapping.TokenPattern.FindAllString(fakeopenai.Prompt(req), -1)
+	return "Thanks, I've updated the account and will write to " + strings.Join(tokens, " and ") + "."
+}
+
+func demoKey(id string) mapstore.Key {
+	k, err := mapstore.NewKey(id)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return k
+}
+
+// demo runs the service against fakeVault: login, secrets, token renewal
+// through a max TTL, a mapping key rotated in Vault, and transit.
+func demo(pol *policyconf.Policy) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	july, october := demoKey("2026-07"), demoKey("2026-10")
+	fv := newFakeVault(2*time.Second, 3*time.Second, map[string]string{fieldMappingKeys: july.Encode()})
+	vs := httptest.NewServer(fv)
+	defer vs.Close()
+	up := fakeopenai.Server(fakeModel)
+	defer up.Close()
+	oc := fakeopenai.Config(up)
+	log.SetFlags(0)
+	log.SetPrefix("  service: ")
+	log.SetOutput(os.Stdout)
+
+	fmt.Println("── 1. Log in with AppRole and read the secret")
+	v := &vault{addr: vs.URL, http: vs.Client()}
+	v.login = func(ctx context.Context) (*vaultAuth, error) { return v.appRoleLogin(ctx, fv.roleID, fv.secretID) }
+	if err := v.authenticate(ctx); err != nil {
+		return err
+	}
+	fmt.Printf("  token for %s, renewable\n", v.lease().ttl())
+	kv := cache.NewLRU(1000)
+	sec := &secrets{v: v, mount: "secret", path: "blindfold", kv: kv, opts: pol.ClientOptions()}
+	loaded, err := sec.refresh(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  secret/blindfold %s\n", loaded)
+
+	p := protect.New(sec.client())
+	p.Policy = pol
+	p.Store = sec.mappings()
+	srv := httptest.NewServer(&server{p: p, llm: openai.NewClientWithConfig(oc), model: "demo"})
+	defer srv.Close()
+
+	fmt.Println("\n── 2. Chat; the mapping is stored under the key from Vault")
+	chat(srv.URL, "c1", "I moved, please send the invoice to omar.haddad@example.com")
+	chat(srv.URL, "c2", "Call me back on 415-555-0134")
+	entry(kv, "c1")
+
+	fmt.Println("\n── 3. Keep the token alive (TTL 2s, max TTL 3s)")
+	go v.keepAlive(ctx, log.Printf)
+	time.Sleep(2500 * time.Millisecond)
+	if _, err := sec.refresh(ctx); err != nil {
+		return err
+	}
+	fmt.Printf("  still reading secrets with a %s token, %d logins in all\n", v.lease().ttl(), fv.logins())
+
+	fmt.Println("\n── 4. Rotate the mapping key: a new version of the secret")
+	fv.put(map[string]string{fieldMappingKeys: october.Encode() + "," + july.Encode()})
+	if loaded, err = sec.refresh(ctx); err != nil {
+		return err
+	}
+	fmt.Printf("  secret/blindfold %s\n", loaded)
+	chat(srv.URL, "c1", "And cc my manager, dana.levi@example.com")
+	entry(kv, "c1")
+	entry(kv, "c2")
+	stats, err := sec.store.Load().Rotate(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  rotate: %d entries, %d rotated, %d already under 2026-10\n", stats.Entries, stats.Rotated, stats.Current)
+	fv.put(map[string]string{fieldMappingKeys: october.Encode()})
+	if loaded, err = sec.refresh(ctx); err != nil {
+		return err
+	}
+	fmt.Printf("  secret/blindfold %s\n", loaded)
+	chat(srv.URL, "c2", "Or text me on 415-555-0134, same number")
+	entry(kv, "c2")
+
+	fmt.Println("\n── 5. -transit: Vault encrypts the mapping, the key never leaves it")
+	p.Store = &transitStore{v: v, kv: kv, mount: "transit", key: "mappings"}
+	chat(srv.URL, "t1", "Refund order 5531 and confirm to lina.k@example.com")
+	data, _, _ := kv.Get(ctx, transitEntry("t1"))
+	fmt.Printf("  %s: %.40s…\n", transitEntry("t1"), data)
+	fmt.Printf("  holds a value in the clear: %v\n", bytes.Contains(data, []byte("lina.k")))
+	kv.Set(ctx, transitEntry("t2"), data)
+	_, err = p.Store.Load(ctx, "t2")
+	fmt.Printf("  the same bytes under session t2: %v\n", err)
+	return nil
+}
+
+// chat posts a message and prints the reply.
+func chat(base, conversation, message string) {
+	fmt.Printf("  → %s: %q\n", conversation, message)
+	body, _ := json.Marshal(chatRequest{Conversation: conversation, Message: message})
+	resp, err := http.Post(base+"/chat", "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("  ← %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+	var out chatResponse
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&out) != nil {
+		fmt.Printf("  ← %s\n", resp.Status)
+		return
+	}
+	fmt.Printf("  ← %q\n", out.Reply)
+}
+
+// entry prints what the stored mapping file of a session says about
+// itself.
+func entry(kv cache.Store, session string) {
+	data, _, _ := kv.Get(context.Background(), mapstore.EntryKey(session))
+	meta, keyID, err := mapstore.Inspect(data)
+	if err != nil {
+		fmt.Printf("  %s: %v\n", mapstore.EntryKey(session), err)
+		return
+	}
+	fmt.Printf("  %s: %d tokens, key %s\n", mapstore.EntryKey(session), meta.Tokens(), keyID)
+}
@@ -0,0 +1,165 @@
+// HashiCorp Vault + Blindfold: Keep the Blindfold API key and the mapping
+// encryption keys in Vault instead of the environment.
+//
+// Serves POST /chat, which tokenizes a message, asks the model and
+// restores the reply, keeping each conversation's mapping encrypted in a
+// mapping store. The service logs in to Vault with AppRole (or a token a
+// Vault Agent keeps current), reads the API key and the mapping keys from
+// a KV v2 secret and renews its token before the lease runs out. The
+// secret is read again on an interval, so rotating a key is a write to
+// Vault. With -transit, mappings are encrypted by Vault's transit engine
+// instead, and no mapping key ever reaches the service.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/cache"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/config"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+)
+
+const systemPrompt = "You are a support agent. Keep placeholders like <Email Address_1> exactly as they are."
+
+type chatRequest struct {
+	Conversation string `json:"conversation"`
+	Message      string `json:"message"`
+}
+
+type chatResponse struct {
+	Reply string `json:"reply"`
+}
+
+// server answers POST /chat.
+type server struct {
+	p     *protect.Protector
+	llm   *openai.Client
+	model string
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Conversation == "" || req.Message == "" {
+		http.Error(w, "want {conversation, message}", http.StatusBadRequest)
+		return
+	}
+	ctx := protect.Session(r.Context(), req.Conversation)
+	reply, err := s.p.Do(ctx, req.Message, protect.Chat(ctx, s.llm, s.model, systemPrompt))
+	if err != nil {
+		log.Printf("%s: %v", req.Conversation, err)
+		http.Error(w, "request failed", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chatResponse{Reply: reply})
+}
+
+func newStore(redisAddr string) cache.Store {
+	if redisAddr == "" {
+		return cache.NewLRU(10_000)
+	}
+	return cache.NewRedis(redis.NewClient(&redis.Options{Addr: redisAddr}), 24*time.Hour)
+}
+
+func main() {
+	// Every flag can also be set as VAULT_RECIPE_<FLAG>; the Vault address
+	// and namespace use Vault's own variables
+	cfg := config.New(flag.CommandLine, "VAULT_RECIPE")
+	addr := flag.String("addr", "127.0.0.1:8080", "listen address")
+	vaultAddr := flag.String("vault-addr", "", "Vault address")
+	namespace := flag.String("vault-namespace", "", "Vault Enterprise namespace")
+	mount := flag.String("kv-mount", "secret", "KV v2 engine mount")
+	path := flag.String("kv-path", "blindfold", "secret holding "+fieldAPIKey+" and "+fieldMappingKeys)
+	refresh := flag.Duration("refresh", 5*time.Minute, "how often to read the secret again")
+	transitKey := flag.String("transit", "", "encrypt mappings with this transit key (created with derived=true) instead of "+fieldMappingKeys)
+	transitMount := flag.String("transit-mount", "transit", "transit engine mount")
+	redisAddr := flag.String("redis", "", "Redis address for the mapping store (default: in-memory)")
+	policiesFile := flag.String("policies", "", "policy file (YAML or JSON); empty = built-in policies only")
+	policy := flag.String("policy", "basic", "policy to apply, by name")
+	model := flag.String("model", "gpt-4o-mini", "OpenAI model")
+	demoMode := flag.Bool("demo", false, "run the scenarios against an in-process Vault and model instead of serving")
+	cfg.Env("vault-addr", "VAULT_ADDR")
+	cfg.Env("vault-namespace", "VAULT_NAMESPACE")
+	cfg.Env("redis", "REDIS_ADDR")
+	cfg.Check("refresh", func() error {
+		if *refresh <= 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	})
+	if err := cfg.Parse(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+
+	pol, err := policyconf.Resolve(*policiesFile, *policy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *demoMode {
+		if err := demo(pol); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	v, err := connect(ctx, *vaultAddr, *namespace)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go v.keepAlive(ctx, log.Printf)
+
+	// API key is optional — leave blindfold_api_key out of the secret to run
+	// in local mode (regex-based, offline)
+	kv := newStore(*redisAddr)
+	sec := &secrets{v: v, mount: *mount, path: *path, kv: kv, opts: pol.ClientOptions(), transit: *transitKey != ""}
+	loaded, err := sec.refresh(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("secrets read from %s/%s %s", *mount, *path, loaded)
+	go sec.watch(ctx, *refresh, log.Printf)
+
+	p := protect.New(sec.client())
+	p.Policy = pol
+	p.Store = sec.mappings()
+	if *transitKey != "" {
+		p.Store = &transitStore{v: v, kv: kv, mount: *transitMount, key: *transitKey}
+	}
+
+	key := os.Getenv("OPENAI_API_KEY")
+	if key == "" {
+		log.Fatal("OPENAI_API_KEY is required (or run with -demo)")
+	}
+	oc := openai.DefaultConfig(key)
+	if base := os.Getenv("OPENAI_BASE_URL"); base != "" {
+		oc.BaseURL = base
+	}
+	s := &server{p: p, llm: openai.NewClientWithConfig(oc), model: *model}
+
+	mux := http.NewServeMux()
+	mux.Handle("/chat", s)
+	log.Printf("listening on http://%s/chat (policy %s)", *addr, pol.Name)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/cache"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapstore"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+)
+
+// The fields of the KV secret the recipe reads.
+const (
+	fieldAPIKey      = "blindfold_api_key" // optional: without it, local mode
+	fieldMappingKeys = "mapping_keys"      // id:base64 keys, as blindfold mapping keygen prints; the first encrypts
+)
+
+// secrets holds what was last read from the KV secret: a Blindfold client
+// built with its API key, and a mapping store over its mapping keys.
+// Refresh reads the secret again and swaps both when it has a new
+// version, so a key rotated in Vault reaches the service without a
+// restart; requests already running finish with what they started with.
+type secrets struct {
+	v           *vault
+	mount, path string
+	kv          cache.Store
+	opts        []blindfold.Option // for every client built
+	transit     bool               // mappings are encrypted by transit: no mapping_keys needed
+
+	mu      sync.Mutex
+	version int
+	apiKey  string
+	bf      atomic.Pointer[blindfold.Client]
+	store   atomic.Pointer[mapstore.Store]
+}
+
+// refresh reads the secret and applies it if its version changed. It
+// returns what changed, for the log, and keeps the current values if the
+// new version can't be used.
+func (s *secrets) refresh(ctx context.Context) (string, error) {
+	sec, err := s.v.readKV(ctx, s.mount, s.path)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sec.Version == s.version {
+		return "", nil
+	}
+
+	var store *mapstore.Store
+	var ids []string
+	if !s.transit {
+		keys, err := mapstore.ParseKeys(sec.Data[fieldMappingKeys])
+		if err != nil {
+			return "", fmt.Errorf("%s/%s version %d: %s: %w", s.mount, s.path, sec.Version, fieldMappingKeys, err)
+		}
+		if store, err = mapstore.NewStore(s.kv, keys...); err != nil {
+			return "", err
+		}
+		store.Source = "vault-go"
+		for _, k := range keys {
+			ids = append(ids, k.ID)
+		}
+	}
+
+	var changes []string
+	if key := sec.Data[fieldAPIKey]; key != s.apiKey || s.bf.Load() == nil {
+		opts := s.opts
+		mode := "local mode (no " + fieldAPIKey + ")"
+		if key != "" {
+			opts = append([]blindfold.Option{blindfold.WithAPIKey(key)}, opts...)
+			mode = "cloud mode"
+		}
+		s.bf.Store(blindfold.New(opts...))
+		s.apiKey = key
+		changes = append(changes, "Blindfold client in "+mode)
+	}
+	if store != nil {
+		s.store.Store(store)
+		changes = append(changes, "mapping keys "+strings.Join(ids, ","))
+	}
+	s.version = sec.Version
+	return fmt.Sprintf("version %d: %s", sec.Version, strings.Join(changes, ", ")), nil
+}
+
+// watch refreshes every interval until ctx is done.
+func (s *secrets) watch(ctx context.Context, every time.Duration, logf func(format string, args ...any)) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+		changed, err := s.refresh(ctx)
+		switch {
+		case err != nil:
+			logf("secrets not refreshed, keeping version %d: %v", s.currentVersion(), err)
+		case changed != "":
+			logf("secrets refreshed: %s", changed)
+		}
+	}
+}
+
+func (s *secrets) currentVersion() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version
+}
+
+// client returns a bfclient.Client that calls the latest client.
+func (s *secrets) client() bfclient.Client { return liveClient{s} }
+
+// mappings returns a protect.Store over the latest mapping keys.
+func (s *secrets) mappings() protect.Store { return liveStore{s} }
+
+type liveClient struct{ s *secrets }
+
+func (c liveClient) Detect(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.DetectResponse, error) {
+	return c.s.bf.Load().Detect(ctx, text, opts...)
+}
+
+func (c liveClient) Tokenize(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	return c.s.bf.Load().Tokenize(ctx, text, opts...)
+}
+
+func (c liveClient) Detokenize(text string, mapping map[string]string) *blindfold.DetokenizeResponse {
+	return c.s.bf.Load().Detokenize(text, mapping)
+}
+
+type liveStore struct{ s *secrets }
+
+func (l liveStore) Load(ctx context.Context, session string) (map[string]string, error) {
+	return l.s.store.Load().Load(ctx, session)
+}
+
+func (l liveStore) Save(ctx context.Context, session string, m map[string]string) error {
+	return l.s.store.Load().Save(ctx, session, m)
+}
+
+// transitStore is a protect.Store whose entries are encrypted by Vault's
+// transit engine, so the key never leaves Vault and every Load and Save
+// is a call to it. The session is the key derivation context: an entry
+// copied under another session doesn't decrypt. The transit key must be
+// created with derived=true.
+type transitStore struct {
+	v          *vault
+	kv         cache.Store
+	mount, key string
+}
+
+var _ protect.Store = (*transitStore)(nil)
+
+// transitEntry returns where a session's ciphertext is kept.
+func transitEntry(session string) string { return "mapping/transit/" + session }
+
+func (t *transitStore) Load(ctx context.Context, session string) (map[string]string, error) {
+	data, ok, err := t.kv.Get(ctx, transitEntry(session))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return map[string]string{}, nil
+	}
+	plaintext, err := t.v.decrypt(ctx, t.mount, t.key, string(data), []byte(session))
+	if err != nil {
+		return nil, fmt.Errorf("session %s: %w", session, err)
+	}
+	m := make(map[string]string)
+	if err := json.Unmarshal(plaintext, &m); err != nil {
+		return nil, fmt.Errorf("session %s: %w", session, err)
+	}
+	return m, nil
+}
+
+func (t *transitStore) Save(ctx context.Context, session string, m map[string]string) error {
+	if session == "" {
+		return errors.New("transit store: empty session")
+	}
+	plaintext, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	ciphertext, err := t.v.encrypt(ctx, t.mount, t.key, plaintext, []byte(session))
+	if err != nil {
+		return err
+	}
+	return t.kv.Set(ctx, transitEntry(session), []byte(ciphertext))
+}
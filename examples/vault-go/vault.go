@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// vault is a minimal Vault HTTP API client: login, token renewal, KV v2
+// reads and transit encrypt/decrypt, which is all the recipe needs.
+type vault struct {
+	addr      string // https://vault.example.com:8200
+	namespace string // Vault Enterprise namespace, if any
+	http      *http.Client
+	// login gets a fresh token: AppRole, or the file a Vault Agent sink
+	// keeps current. It is nil for a fixed token.
+	login func(ctx context.Context) (*vaultAuth, error)
+
+	mu    sync.Mutex
+	token string
+	auth  vaultAuth // lease of the current token
+}
+
+// vaultAuth is a token and its lease, as login and renewal return it.
+type vaultAuth struct {
+	ClientToken   string `json:"client_token"`
+	LeaseDuration int    `json:"lease_duration"` // seconds; 0 never expires
+	Renewable     bool   `json:"renewable"`
+}
+
+func (a vaultAuth) ttl() time.Duration { return time.Duration(a.LeaseDuration) * time.Second }
+
+// vaultError is an error response from the API, which comes as a list of
+// messages.
+type vaultError struct {
+	Status int
+	Errors []string
+}
+
+func (e *vaultError) Error() string {
+	return fmt.Sprintf("vault: %d: %s", e.Status, strings.Join(e.Errors, "; "))
+}
+
+// connect builds a client from the environment: VAULT_ADDR, and either
+// VAULT_ROLE_ID with VAULT_SECRET_ID_FILE for AppRole, VAULT_TOKEN_FILE
+// for a token a Vault Agent keeps current, or VAULT_TOKEN for
+// development. No secret has to be in the environment: the AppRole
+// secret ID and the Agent's token are read from files.
+func connect(ctx context.Context, addr, namespace string) (*vault, error) {
+	if addr == "" {
+		return nil, errors.New("VAULT_ADDR is required (or run with -demo)")
+	}
+	v := &vault{addr: strings.TrimSuffix(addr, "/"), namespace: namespace, http: &http.Client{Timeout: 30 * time.Second}}
+	switch roleID, tokenFile := os.Getenv("VAULT_ROLE_ID"), os.Getenv("VAULT_TOKEN_FILE"); {
+	case roleID != "":
+		secretFile := os.Getenv("VAULT_SECRET_ID_FILE")
+		if secretFile == "" {
+			return nil, errors.New("VAULT_ROLE_ID needs VAULT_SECRET_ID_FILE")
+		}
+		v.login = func(ctx context.Context) (*vaultAuth, error) {
+			secretID, err := os.ReadFile(secretFile)
+			if err != nil {
+				return nil, err
+			}
+			return v.appRoleLogin(ctx, roleID, strings.TrimSpace(string(secretID)))
+		}
+	case tokenFile != "":
+		v.login = func(ctx context.Context) (*vaultAuth, error) {
+			token, err := os.ReadFile(tokenFile)
+			if err != nil {
+				return nil, err
+			}
+			return v.lookupSelf(ctx, strings.TrimSpace(string(token)))
+		}
+	case os.Getenv("VAULT_TOKEN") != "":
+		auth, err := v.lookupSelf(ctx, os.Getenv("VAULT_TOKEN"))
+		if err != nil {
+			return nil, err
+		}
+		v.token, v.auth = auth.ClientToken, *auth
+		return v, nil
+	default:
+		return nil, errors.New("set VAULT_ROLE_ID and VAULT_SECRET_ID_FILE, VAULT_TOKEN_FILE or VAULT_TOKEN (or run with -demo)")
+	}
+	return v, v.authenticate(ctx)
+}
+
+// authenticate replaces the token with a fresh one from login.
+func (v *vault) authenticate(ctx context.Context) error {
+	auth, err := v.login(ctx)
+	if err != nil {
+		return fmt.Errorf("vault login: %w", err)
+	}
+	v.mu.Lock()
+	v.token, v.auth = auth.ClientToken, *auth
+	v.mu.Unlock()
+	return nil
+}
+
+func (v *vault) appRoleLogin(ctx context.Context, roleID, secretID string) (*vaultAuth, error) {
+	var resp struct {
+		Auth vaultAuth `json:"auth"`
+	}
+	err := v.call(ctx, "", http.MethodPost, "/v1/auth/approle/login", map[string]string{"role_id": roleID, "secret_id": secretID}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Auth, nil
+}
+
+// lookupSelf returns token's lease, for a token that was not issued to
+// this process.
+func (v *vault) lookupSelf(ctx context.Context, token string) (*vaultAuth, error) {
+	var resp struct {
+		Data struct {
+			TTL       int  `json:"ttl"`
+			Renewable bool `json:"renewable"`
+		} `json:"data"`
+	}
+	if err := v.call(ctx, token, http.MethodGet, "/v1/auth/token/lookup-self", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &vaultAuth{ClientToken: token, LeaseDuration: resp.Data.TTL, Renewable: resp.Data.Renewable}, nil
+}
+
+// keepAlive renews the token at half its lease until ctx is done. Vault
+// stops extending a token at its max TTL, which shows as a renewal
+// granting less than the one before; the token is then replaced with a
+// fresh login, if there is one, so it never lapses. A token without a
+// lease, or one that can't be renewed, is left as it is.
+func (v *vault) keepAlive(ctx context.Context, logf func(format string, args ...any)) {
+	for {
+		v.mu.Lock()
+		last := v.auth
+		v.mu.Unlock()
+		if last.LeaseDuration == 0 || (!last.Renewable && v.login == nil) {
+			return
+		}
+		select {
+		case <-time.After(last.ttl() / 2):
+		case <-ctx.Done():
+			return
+		}
+
+		if last.Renewable {
+			auth, err := v.renew(ctx)
+			switch {
+			case err == nil && auth.LeaseDuration >= last.LeaseDuration:
+				logf("token renewed for %s", auth.ttl())
+				continue
+			case err == nil:
+				logf("token renewed for %s only: near its max TTL", auth.ttl())
+			default:
+				logf("token renewal failed: %v", err)
+			}
+		}
+		if v.login == nil {
+			continue
+		}
+		if err := v.authenticate(ctx); err != nil {
+			logf("%v; retrying", err)
+			continue
+		}
+		logf("logged in again: new token for %s", v.lease().ttl())
+	}
+}
+
+func (v *vault) renew(ctx context.Context) (*vaultAuth, error) {
+	var resp struct {
+		Auth vaultAuth `json:"auth"`
+	}
+	if err := v.do(ctx, http.MethodPost, "/v1/auth/token/renew-self", struct{}{}, &resp); err != nil {
+		return nil, err
+	}
+	v.mu.Lock()
+	v.auth = resp.Auth
+	v.mu.Unlock()
+	return &resp.Auth, nil
+}
+
+func (v *vault) lease() vaultAuth {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.auth
+}
+
+// kvSecret is the latest version of a KV v2 secret.
+type kvSecret struct {
+	Data    map[string]string
+	Version int
+}
+
+// readKV reads the secret at path in the KV v2 engine mounted at mount.
+func (v *vault) readKV(ctx context.Context, mount, path string) (*kvSecret, error) {
+	var resp struct {
+		Data struct {
+			Data     map[string]string `json:"data"`
+			Metadata struct {
+				Version int `json:"version"`
+			} `json:"metadata"`
+		} `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodGet, "/v1/"+mount+"/data/"+path, nil, &resp); err != nil {
+		return nil, fmt.Errorf("read %s/%s: %w", mount, path, err)
+	}
+	return &kvSecret{Data: resp.Data.Data, Version: resp.Data.Metadata.Version}, nil
+}
+
+// encrypt encrypts plaintext with the transit key under mount, deriving
+// the key from derivation context. It returns Vault's "vault:vN:..."
+// ciphertext, which names the key version that decrypts it.
+func (v *vault) encrypt(ctx context.Context, mount, key string, plaintext, derivation []byte) (string, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	body := map[string]string{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+		"context":   base64.StdEncoding.EncodeToString(derivation),
+	}
+	if err := v.do(ctx, http.MethodPost, "/v1/"+mount+"/encrypt/"+key, body, &resp); err != nil {
+		return "", fmt.Errorf("transit encrypt: %w", err)
+	}
+	return resp.Data.Ciphertext, nil
+}
+
+// decrypt reverses encrypt. It fails unless derivation is the context the
+// ciphertext was encrypted with.
+func (v *vault) decrypt(ctx context.Context, mount, key, ciphertext string, derivation []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	body := map[string]string{"ciphertext": ciphertext, "context": base64.StdEncoding.EncodeToString(derivation)}
+	if err := v.do(ctx, http.MethodPost, "/v1/"+mount+"/decrypt/"+key, body, &resp); err != nil {
+		return nil, fmt.Errorf("transit decrypt: %w", err)
+	}
+	return base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+}
+
+// do calls the API with the current token.
+func (v *vault) do(ctx context.Context, method, path string, body, out any) error {
+	v.mu.Lock()
+	token := v.token
+	v.mu.Unlock()
+	return v.call(ctx, token, method, path, body, out)
+}
+
+func (v *vault) call(ctx context.Context, token, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, v.addr+path, r)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := v.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		e := &vaultError{Status: resp.StatusCode}
+		var errs struct {
+			Errors []string `json:"errors"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&errs) == nil {
+			e.Errors = errs.Errors
+		}
+		return e
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,54 @@
+// Loads the js/wasm build the way an edge worker does, installs
+// globalThis.blindfold, and serves one request through a fetch-style
+// handler: tokenize on the way in, detokenize on the way out.
+//
+//   node edge.mjs blindfold.wasm wasm_exec.js
+//   node edge.mjs blindfold.wasm wasm_exec.js --bench 500   # one JSON line of timings
+import { readFile } from "node:fs/promises";
+import { createRequire } from "node:module";
+import { resolve } from "node:path";
+
+const [wasmPath, execPath, flag, n] = process.argv.slice(2);
+createRequire(import.meta.url)(resolve(execPath)); // defines globalThis.Go
+
+const started = performance.now();
+const go = new Go();
+const ready = new Promise((done) => { globalThis.onBlindfoldReady = done; });
+const { instance } = await WebAssembly.instantiate(await readFile(wasmPath), go.importObject);
+go.run(instance); // settles only when Go exits, which it doesn't
+await ready;
+const startupMs = performance.now() - started;
+
+// The worker: in production this is `export default { fetch }`, and the
+// model call goes where the fake reply is
+async function fetchHandler(request) {
+  const { message } = await request.json();
+  const t = blindfold.tokenize(message, { policy: "edge" });
+  if (t.error) {
+    return Response.json({ error: t.error }, { status: 500 });
+  }
+  const modelReply = "Thanks! We'll send the refund confirmation to <Email Address_1> today.";
+  const restored = blindfold.detokenize(modelReply, t.mapping);
+  return Response.json({ sent_to_model: t.text, entities: t.entities, reply: restored.text });
+}
+
+const sample = "Hi, I'm Dana. Refund card 4111 1111 1111 1111 and email dana.w@example.com, or call 415-555-0142.";
+
+if (flag === "--bench") {
+  const calls = Number(n) || 500;
+  blindfold.tokenize(sample); // warm up
+  const t0 = performance.now();
+  for (let i = 0; i < calls; i++) {
+    blindfold.tokenize(sample);
+  }
+  const perCall = ((performance.now() - t0) * 1000) / calls;
+  console.log(JSON.stringify({ startup_ms: Math.round(startupMs), tokenize_us: Math.round(perCall), calls }));
+} else {
+  console.log(`module ready in ${Math.round(startupMs)} ms`);
+  const req = new Request("https://edge.example/chat", { method: "POST", body: JSON.stringify({ message: sample }) });
+  const res = await fetchHandler(req);
+  console.log(`POST /chat → ${res.status}`);
+  console.log(JSON.stringify(await res.json(), null, 2));
+  console.log("unknown policy →", JSON.stringify(blindfold.tokenize(sample, { policy: "nope" })));
+}
+process.exit(0);
@@ -0,0 +1,11 @@
+// Runs the wasip1 build under Node's built-in WASI host, wired to this
+// process's stdin and stdout: JSON-lines requests in, responses out.
+//
+//   node --no-warnings wasi.mjs blindfold-wasi.wasm < requests.jsonl
+import { readFile } from "node:fs/promises";
+import { WASI } from "node:wasi";
+
+const wasi = new WASI({ version: "preview1", args: ["blindfold"], env: {}, stdin: 0, stdout: 1, stderr: 2, returnOnExit: true });
+const module = await WebAssembly.compile(await readFile(process.argv[2]));
+const instance = await WebAssembly.instantiate(module, wasi.getImportObject());
+process.exitCode = wasi.start(instance);
@@ -0,0 +1,184 @@
+//go:build !js && !wasip1
+
+// WASM + Blindfold: Run the local-mode pipeline in edge workers and other
+// runtimes.
+//
+// Compiles the local-mode protection pipeline (the SDK's regex detectors
+// under the policies in policies.yaml, which are compiled in) to two
+// WebAssembly targets:
+//
+//   - js/wasm: installs globalThis.blindfold with tokenize(text, {policy})
+//     and detokenize(text, mapping), for edge workers and browsers.
+//   - wasip1/wasm: a WASI command answering JSON-lines requests on stdin,
+//     for any WASI host (wasmtime, wazero, Node's node:wasi).
+//
+// On the host, this program is the build: it writes both modules and the
+// JS glue to -out, and prints their sizes. With -demo it then runs them
+// under Node.js. The modules only ever run in local mode: no API key, no
+// network, nothing leaves the runtime.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+//go:embed js
+var glue embed.FS
+
+// module is one built target.
+type module struct {
+	target string // GOOS
+	path   string
+	size   int64
+	gzip   int64
+}
+
+func main() {
+	out := flag.String("out", "dist", "directory for the built modules and the JS glue")
+	runDemo := flag.Bool("demo", false, "build into a temporary directory, run both modules under Node.js, and exit")
+	flag.Parse()
+
+	if err := run(*out, *runDemo); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(dir string, runDemo bool) error {
+	if runDemo {
+		tmp, err := os.MkdirTemp("", "wasm-edge-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+	mods, err := build(dir)
+	if err != nil {
+		return err
+	}
+	for _, m := range mods {
+		fmt.Printf("%-7s %-20s %5.1f MiB  (%.1f MiB gzipped)\n", m.target, filepath.Base(m.path), mib(m.size), mib(m.gzip))
+	}
+	if !runDemo {
+		fmt.Printf("\nwritten to %s/ with wasm_exec.js, edge.mjs and wasi.mjs\n", dir)
+		return nil
+	}
+	return demo(dir)
+}
+
+// build compiles both targets into dir, stripped of symbols and debug
+// information, and copies the glue next to them: wasm_exec.js from the Go
+// toolchain, which must match the compiler that built the module, and
+// the Node.js runners.
+func build(dir string) ([]module, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	var mods []module
+	for _, t := range []struct{ goos, name string }{{"js", "blindfold.wasm"}, {"wasip1", "blindfold-wasi.wasm"}} {
+		path := filepath.Join(dir, t.name)
+		cmd := exec.Command("go", "build", "-trimpath", "-ldflags=-s -w", "-o", path, ".")
+		cmd.Env = append(os.Environ(), "GOOS="+t.goos, "GOARCH=wasm")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("build %s/wasm: %v\n%s", t.goos, err, out)
+		}
+		m := module{target: t.goos, path: path}
+		var err error
+		if m.size, m.gzip, err = sizes(path); err != nil {
+			return nil, err
+		}
+		mods = append(mods, m)
+	}
+
+	goroot, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		return nil, err
+	}
+	// lib/wasm since Go 1.24, misc/wasm before
+	var wasmExec []byte
+	for _, sub := range []string{"lib/wasm", "misc/wasm"} {
+		if wasmExec, err = os.ReadFile(filepath.Join(strings.TrimSpace(string(goroot)), sub, "wasm_exec.js")); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, errors.New("wasm_exec.js not found in GOROOT")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "wasm_exec.js"), wasmExec, 0o644); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"edge.mjs", "wasi.mjs"} {
+		data, _ := glue.ReadFile("js/" + name)
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return mods, nil
+}
+
+// sizes returns the size of the file at path, raw and gzipped: edge
+// platforms limit the compressed size of a worker.
+func sizes(path string) (raw, gz int64, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	zw.Write(data)
+	zw.Close()
+	return int64(len(data)), int64(buf.Len()), nil
+}
+
+func mib(n int64) float64 { return float64(n) / (1 << 20) }
+
+// demo runs the js/wasm module through edge.mjs, a fetch-style handler,
+// and pipes sample requests through the WASI command.
+func demo(dir string) error {
+	if _, err := exec.LookPath("node"); err != nil {
+		return errors.New("-demo runs the modules under Node.js 20+, which isn't on PATH")
+	}
+	fmt.Println("\n── js/wasm, as an edge worker (node edge.mjs)")
+	cmd := exec.Command("node", "edge.mjs", "blindfold.wasm", "wasm_exec.js")
+	cmd.Dir, cmd.Stdout, cmd.Stderr = dir, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("edge.mjs: %w", err)
+	}
+
+	fmt.Println("\n── wasip1/wasm, as a WASI command (node wasi.mjs)")
+	requests := strings.Join([]string{
+		`{"op":"tokenize","text":"Ticket from omar.haddad@example.com, IP 203.0.113.42, cc support@example.com"}`,
+		`{"op":"tokenize","text":"Card 5500 0000 0000 0004 on file","policy":"payments"}`,
+		`{"op":"detokenize","text":"Reply sent to <Email Address_1>","mapping":{"<Email Address_1>":"omar.haddad@example.com"}}`,
+		`{"op":"redact","text":"x"}`,
+	}, "\n") + "\n"
+	fmt.Print(indent("→ ", requests))
+	var out bytes.Buffer
+	cmd = exec.Command("node", "--no-warnings", "wasi.mjs", "blindfold-wasi.wasm")
+	cmd.Dir, cmd.Stdin, cmd.Stdout, cmd.Stderr = dir, strings.NewReader(requests), &out, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("wasi.mjs: %w", err)
+	}
+	fmt.Print(indent("← ", out.String()))
+	return nil
+}
+
+func indent(prefix, lines string) string {
+	var b strings.Builder
+	for _, l := range strings.SplitAfter(lines, "\n") {
+		if l != "" {
+			b.WriteString(prefix + l)
+		}
+	}
+	return b.String()
+}
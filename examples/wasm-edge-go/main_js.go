@@ -0,0 +1,60 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"syscall/js"
+)
+
+// main installs globalThis.blindfold and then blocks, keeping the Go
+// runtime alive for calls from JS:
+//
+//	blindfold.tokenize(text, {policy: "payments"}) → {text, mapping, entities}
+//	blindfold.detokenize(text, mapping)           → {text}
+//
+// Both return {error} instead when a call fails. Values cross the
+// boundary as JSON, so results are plain JS objects.
+func main() {
+	p, err := newProtector()
+	if err != nil {
+		panic(err)
+	}
+	jsonAPI := js.Global().Get("JSON")
+	toJS := func(res response) any {
+		data, _ := json.Marshal(res)
+		return jsonAPI.Call("parse", string(data))
+	}
+	api := js.Global().Get("Object").New()
+	api.Set("tokenize", js.FuncOf(func(_ js.Value, args []js.Value) any {
+		req := request{Op: "tokenize"}
+		if len(args) > 0 {
+			req.Text = args[0].String()
+		}
+		if len(args) > 1 && args[1].Type() == js.TypeObject {
+			if pol := args[1].Get("policy"); pol.Type() == js.TypeString {
+				req.Policy = pol.String()
+			}
+		}
+		return toJS(p.handle(context.Background(), req))
+	}))
+	api.Set("detokenize", js.FuncOf(func(_ js.Value, args []js.Value) any {
+		req := request{Op: "detokenize"}
+		if len(args) > 0 {
+			req.Text = args[0].String()
+		}
+		if len(args) > 1 && args[1].Type() == js.TypeObject {
+			if err := json.Unmarshal([]byte(jsonAPI.Call("stringify", args[1]).String()), &req.Mapping); err != nil {
+				return toJS(response{Error: "mapping: " + err.Error()})
+			}
+		}
+		return toJS(p.handle(context.Background(), req))
+	}))
+	js.Global().Set("blindfold", api)
+	// Hosts that can't poll for the global wait on this instead
+	if ready := js.Global().Get("onBlindfoldReady"); ready.Type() == js.TypeFunction {
+		ready.Invoke()
+	}
+	select {}
+}
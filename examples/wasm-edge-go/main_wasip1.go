@@ -0,0 +1,28 @@
+//go:build wasip1
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// main is a WASI command. It reads JSON-lines requests on stdin and writes
+// one response line each on stdout, so any WASI host (wasmtime, wazero,
+// Node's node:wasi, edge platforms with WASI support) can embed it with
+// nothing but pipes:
+//
+//	{"op":"tokenize","text":"mail jane@example.com"}
+//	{"op":"detokenize","text":"mail <Email Address_1>","mapping":{...}}
+func main() {
+	p, err := newProtector()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := p.serve(context.Background(), os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+// policyFile is compiled into the module, so an edge worker applies the
+// same policies as the services behind it without reading a file.
+//
+//go:embed policies.yaml
+var policyFile []byte
+
+// request is one call: the JSON a WASI host writes, one per line, and
+// what the JS surface builds from its arguments.
+type request struct {
+	Op      string            `json:"op"` // "tokenize" or "detokenize"
+	Text    string            `json:"text"`
+	Policy  string            `json:"policy,omitempty"`  // tokenize; default: the file's default
+	Mapping map[string]string `json:"mapping,omitempty"` // detokenize
+}
+
+// response answers a request. Error is set, and nothing else, when it
+// failed.
+type response struct {
+	Text     string            `json:"text,omitempty"`
+	Mapping  map[string]string `json:"mapping,omitempty"`
+	Entities map[string]int    `json:"entities,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// protector is the local-mode pipeline: the embedded policies over the
+// SDK's regex detectors. It makes no network calls, so it runs wherever
+// the module does, sandboxes without sockets included.
+type protector struct {
+	policies *policyconf.Config
+	local    *blindfold.Client
+	clients  map[string]*policyconf.Client // by policy name, built on first use
+}
+
+func newProtector() (*protector, error) {
+	cfg, err := policyconf.Parse(policyFile, "yaml")
+	if err != nil {
+		return nil, err
+	}
+	return &protector{
+		policies: cfg,
+		local:    blindfold.New(blindfold.WithMode("local")),
+		clients:  make(map[string]*policyconf.Client),
+	}, nil
+}
+
+func (p *protector) client(name string) (*policyconf.Client, error) {
+	if c, ok := p.clients[name]; ok {
+		return c, nil
+	}
+	pol, err := p.policies.Policy(name)
+	if err != nil {
+		return nil, err
+	}
+	c := pol.Wrap(blindfold.New(append(pol.ClientOptions(), blindfold.WithMode("local"))...))
+	p.clients[name] = c
+	return c, nil
+}
+
+// handle runs one request. Callers in JS and WASI are single-threaded, so
+// it isn't safe for concurrent use.
+func (p *protector) handle(ctx context.Context, req request) response {
+	switch req.Op {
+	case "tokenize":
+		c, err := p.client(req.Policy)
+		if err != nil {
+			return response{Error: err.Error()}
+		}
+		res, err := c.Tokenize(ctx, req.Text)
+		if err != nil {
+			return response{Error: err.Error()}
+		}
+		counts := make(map[string]int)
+		for _, e := range res.DetectedEntities {
+			counts[e.Type]++
+		}
+		return response{Text: res.Text, Mapping: res.Mapping, Entities: counts}
+	case "detokenize":
+		return response{Text: p.local.Detokenize(req.Text, req.Mapping).Text}
+	}
+	return response{Error: fmt.Sprintf("unknown op %q (want tokenize or detokenize)", req.Op)}
+}
+
+// serve answers JSON-lines requests from r on w, one response line per
+// request line, until r ends. A line that isn't a request gets an error
+// response, so the host can keep request and response lines paired.
+func (p *protector) serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	out := bufio.NewWriter(w)
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	for sc.Scan() {
+		var req request
+		res := response{}
+		if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
+			res.Error = "bad request: " + err.Error()
+		} else {
+			res = p.handle(ctx, req)
+		}
+		if err := enc.Encode(res); err != nil {
+			return err
+		}
+		// Flush per line: the host may wait for each answer before writing
+		// the next request
+		if err := out.Flush(); err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
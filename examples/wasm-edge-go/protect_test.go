@@ -0,0 +1,63 @@
+//go:build !js && !wasip1
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	p, err := newProtector()
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := strings.Join([]string{
+		`{"op":"tokenize","text":"mail jane.doe@example.com or support@example.com"}`,
+		`not json`,
+		`{"op":"tokenize","text":"x","policy":"nope"}`,
+		`{"op":"detokenize","text":"mail <Email Address_1>","mapping":{"<Email Address_1>":"jane.doe@example.com"}}`,
+	}, "\n")
+	var out bytes.Buffer
+	if err := p.serve(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("%d response lines for 4 requests:\n%s", len(lines), out.String())
+	}
+	var res []response
+	for _, l := range lines {
+		var r response
+		if err := json.Unmarshal([]byte(l), &r); err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, r)
+	}
+	// The edge policy allowlists the support address
+	if res[0].Text != "mail <Email Address_1> or support@example.com" || res[0].Entities["Email Address"] != 1 {
+		t.Errorf("tokenize = %+v", res[0])
+	}
+	if !strings.HasPrefix(res[1].Error, "bad request") || !strings.Contains(res[2].Error, "unknown policy") {
+		t.Errorf("errors = %q, %q", res[1].Error, res[2].Error)
+	}
+	if res[3].Text != "mail jane.doe@example.com" {
+		t.Errorf("detokenize = %q", res[3].Text)
+	}
+}
+
+// BenchmarkTokenize is the native baseline for the per-call figures
+// TestModules measures in WebAssembly.
+func BenchmarkTokenize(b *testing.B) {
+	p, err := newProtector()
+	if err != nil {
+		b.Fatal(err)
+	}
+	req := request{Op: "tokenize", Text: benchText}
+	for i := 0; i < b.N; i++ {
+		p.handle(context.Background(), req)
+	}
+}
@@ -0,0 +1,106 @@
+//go:build !js && !wasip1
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Size budgets for the stripped modules. Go 1.27 builds them at about
+// 12.7 MiB, 3.3 MiB gzipped; most of it is the runtime, net/http, which
+// the SDK imports for cloud mode, and the SDK's detector tables. The
+// gzipped size is what edge platforms limit: it fits Cloudflare Workers'
+// paid plan (10 MB) but not the free one (3 MB). A dependency that pushes
+// a module past a budget fails here, not at deploy time.
+const (
+	maxModuleBytes = 16 << 20
+	maxGzipBytes   = 4 << 20
+)
+
+// Per-call budgets under Node.js 20, generous so the test doesn't flake on
+// a loaded machine. Measured: startup (instantiate, runtime init, detector
+// compilation) about 330 ms, a call on benchText 3 to 5 ms, against about
+// 60 µs natively (BenchmarkTokenize). Startup is paid once per isolate, so
+// keep instances warm rather than instantiating per request.
+const (
+	maxStartupMs  = 3000
+	maxTokenizeUs = 25000
+)
+
+// benchText is the sample js/edge.mjs times in --bench mode; keep the two
+// in sync.
+const benchText = "Hi, I'm Dana. Refund card 4111 1111 1111 1111 and email dana.w@example.com, or call 415-555-0142."
+
+// TestModules builds both targets, checks their sizes, then runs them
+// under Node.js: the WASI command must answer exactly as the native
+// build does, and js/wasm calls must stay within the time budgets.
+func TestModules(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds two WebAssembly modules")
+	}
+	dir := t.TempDir()
+	mods, err := build(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range mods {
+		t.Logf("%s: %.1f MiB, %.1f MiB gzipped", m.target, mib(m.size), mib(m.gzip))
+		if m.size > maxModuleBytes || m.gzip > maxGzipBytes {
+			t.Errorf("%s: %d bytes (%d gzipped), over the %d (%d) budget", m.target, m.size, m.gzip, maxModuleBytes, maxGzipBytes)
+		}
+	}
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node not on PATH; skipping the runtime checks")
+	}
+
+	t.Run("wasi matches native", func(t *testing.T) {
+		in := strings.Join([]string{
+			`{"op":"tokenize","text":"` + benchText + `"}`,
+			`{"op":"tokenize","text":"Card 5500 0000 0000 0004, IP 203.0.113.42","policy":"payments"}`,
+			`{"op":"detokenize","text":"to <Email Address_1>","mapping":{"<Email Address_1>":"dana.w@example.com"}}`,
+			`{"op":"nope"}`,
+		}, "\n") + "\n"
+		cmd := exec.Command("node", "--no-warnings", filepath.Join(dir, "wasi.mjs"), filepath.Join(dir, "blindfold-wasi.wasm"))
+		cmd.Stdin = strings.NewReader(in)
+		got, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := newProtector()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want bytes.Buffer
+		if err := p.serve(context.Background(), strings.NewReader(in), &want); err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want.String() {
+			t.Errorf("wasi:\n%s\nnative:\n%s", got, want.String())
+		}
+	})
+
+	t.Run("js timings", func(t *testing.T) {
+		cmd := exec.Command("node", filepath.Join(dir, "edge.mjs"), filepath.Join(dir, "blindfold.wasm"), filepath.Join(dir, "wasm_exec.js"), "--bench", "200")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var bench struct {
+			StartupMs  int `json:"startup_ms"`
+			TokenizeUs int `json:"tokenize_us"`
+		}
+		if err := json.Unmarshal(out, &bench); err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+		t.Logf("startup %d ms, tokenize %d µs per call", bench.StartupMs, bench.TokenizeUs)
+		if bench.StartupMs > maxStartupMs || bench.TokenizeUs > maxTokenizeUs {
+			t.Errorf("startup %d ms (budget %d), tokenize %d µs (budget %d)", bench.StartupMs, maxStartupMs, bench.TokenizeUs, maxTokenizeUs)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+const ticket = "Hi, this is about order 8812. Please send the refund confirmation to jane.doe@example.com or call 415-555-0134."
+
+// fakeRealtime stands in for a Realtime-style model API. It answers each
+// response.create with a reply that reuses the tokens it was sent,
+// streamed in 7-byte deltas so placeholders get split across frames, and
+// records every frame it received.
+type fakeRealtime struct {
+	mu   sync.Mutex
+	seen []string
+}
+
+func (f *fakeRealtime) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	var tokens []string
+	for {
+		_, frame, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.seen = append(f.seen, string(frame))
+		f.mu.Unlock()
+
+		tokens = append(tokens, mapping.TokenPattern.FindAllString(string(frame), -1)...)
+		doc, _ := decode(frame)
+		if doc["type"] != "response.create" {
+			continue
+		}
+		reply := "Done. The confirmation goes to " + strings.Join(tokens, " and ") + "."
+		for i := 0; i < len(reply); i += 7 {
+			end := min(i+7, len(reply))
+			_ = conn.WriteMessage(websocket.TextMessage, marshal(map[string]any{
+				"type": "response.text.delta", "response_id": "resp_1", "item_id": "item_1",
+				"output_index": 0, "content_index": 0, "delta": reply[i:end],
+			}))
+		}
+		_ = conn.WriteMessage(websocket.TextMessage, marshal(map[string]any{
+			"type": "response.text.done", "response_id": "resp_1", "item_id": "item_1",
+			"output_index": 0, "content_index": 0, "text": reply,
+		}))
+	}
+}
+
+// demo proxies a conversation with fakeRealtime and shows both sides.
+func demo(p *proxy) error {
+	model := &fakeRealtime{}
+	up := httptest.NewServer(model)
+	defer up.Close()
+	p.upstream = "ws" + strings.TrimPrefix(up.URL, "http")
+
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/realtime?model=demo", nil)
+	if err != nil {
+		return err
+	}
+
+	for _, ev := range []map[string]any{
+		{"type": "session.update", "session": map[string]any{"instructions": "You are a support agent. Escalations go to ops@acme.example."}},
+		{"type": "conversation.item.create", "item": map[string]any{
+			"type": "message", "role": "user",
+			"content": []any{map[string]any{"type": "input_text", "text": ticket}},
+		}},
+		{"type": "response.create"},
+	} {
+		if err := conn.WriteMessage(websocket.TextMessage, marshal(ev)); err != nil {
+			return err
+		}
+	}
+
+	var deltas []string
+	var final string
+	for final == "" {
+		_, frame, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		doc, _ := decode(frame)
+		switch doc["type"] {
+		case "response.text.delta":
+			deltas = append(deltas, doc["delta"].(string))
+		case "response.text.done":
+			final = doc["text"].(string)
+		}
+	}
+	_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye"))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break // the close handshake completed through the proxy
+		}
+	}
+	conn.Close()
+	p.sessions.Wait()
+
+	model.mu.Lock()
+	defer model.mu.Unlock()
+	fmt.Println("\nUpstream received:")
+	for _, f := range model.seen {
+		fmt.Printf("  %s\n", f)
+	}
+	fmt.Printf("\nClient received %d deltas:\n  %q\n", len(deltas), deltas)
+	fmt.Printf("\nJoined:  %s\nDone:    %s\n", strings.Join(deltas, ""), final)
+	return nil
+}
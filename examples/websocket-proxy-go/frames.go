@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/streamdetok"
+)
+
+// defaultFields are the JSON keys whose string values are protected: the
+// text-bearing fields of Realtime-style client and server events.
+const defaultFields = "text,content,instructions,input,output,transcript"
+
+func parseFields(list string) map[string]bool {
+	fields := make(map[string]bool)
+	for _, f := range strings.Split(list, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields[f] = true
+		}
+	}
+	return fields
+}
+
+// collect returns a pointer to every non-empty string held under one of
+// fields in doc, at any depth, and a commit that writes them back.
+func collect(doc any, fields map[string]bool) ([]*string, func()) {
+	var texts []*string
+	var commits []func()
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, x := range v {
+				if s, ok := x.(string); ok {
+					if fields[k] && s != "" {
+						k, p := k, new(string)
+						*p = s
+						texts = append(texts, p)
+						commits = append(commits, func() { v[k] = *p })
+					}
+					continue
+				}
+				walk(x)
+			}
+		case []any:
+			for _, x := range v {
+				walk(x)
+			}
+		}
+	}
+	walk(doc)
+	return texts, func() {
+		for _, c := range commits {
+			c()
+		}
+	}
+}
+
+// tokenizeFrame tokenizes an outbound text frame. A JSON object has the
+// strings under fields tokenized; any other frame is tokenized whole.
+// Tokens continue known, the connection's mapping so far, so a value keeps
+// one token for the whole connection. It returns the frame to forward, the
+// connection's mapping after it, and the entities found.
+func tokenizeFrame(ctx context.Context, bf bfclient.Client, frame []byte, fields map[string]bool, known map[string]string) ([]byte, map[string]string, []blindfold.DetectedEntity, error) {
+	doc, ok := decode(frame)
+	if !ok {
+		s := string(frame)
+		mp, entities, err := tokenizeAll(ctx, bf, []*string{&s}, known)
+		return []byte(s), mp, entities, err
+	}
+	texts, commit := collect(doc, fields)
+	mp, entities, err := tokenizeAll(ctx, bf, texts, known)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	commit()
+	return marshal(doc), mp, entities, nil
+}
+
+// tokenizeAll tokenizes texts in place and merges their mappings into
+// known. known itself is never modified.
+func tokenizeAll(ctx context.Context, bf bfclient.Client, texts []*string, known map[string]string) (map[string]string, []blindfold.DetectedEntity, error) {
+	if len(texts) == 0 {
+		return known, nil, nil
+	}
+	mappings := []map[string]string{known}
+	tokenized := make([]string, len(texts))
+	var entities []blindfold.DetectedEntity
+	for i, t := range texts {
+		res, err := bf.Tokenize(ctx, *t)
+		if err != nil {
+			return nil, nil, err
+		}
+		tokenized[i] = res.Text
+		mappings = append(mappings, res.Mapping)
+		entities = append(entities, res.DetectedEntities...)
+	}
+	merged := mapping.Merge(mappings...)
+	for i, t := range texts {
+		*t = merged.Rewrite(i+1, tokenized[i])
+	}
+	return merged.Mapping, entities, nil
+}
+
+// restorer detokenizes inbound frames. Streamed text arrives as ".delta"
+// events whose placeholders can be split across frames; each stream gets
+// its own streamdetok.Detokenizer, and whatever it still holds is sent as
+// one more delta just before the stream's ".done" event.
+type restorer struct {
+	fields  map[string]bool
+	streams map[string]*stream
+}
+
+type stream struct {
+	detok *streamdetok.Detokenizer
+	last  map[string]any // most recent delta event, the template for the final one
+}
+
+func newRestorer(fields map[string]bool) *restorer {
+	return &restorer{fields: fields, streams: make(map[string]*stream)}
+}
+
+// restore detokenizes frame with mp and returns the frames to send in its
+// place: usually one, two when a stream ends with text held back.
+func (r *restorer) restore(frame []byte, mp map[string]string) [][]byte {
+	doc, ok := decode(frame)
+	if !ok {
+		return [][]byte{[]byte(mapping.Detokenize(string(frame), mp))}
+	}
+	var out [][]byte
+	typ, _ := doc["type"].(string)
+	if base, ok := strings.CutSuffix(typ, ".delta"); ok {
+		if delta, ok := doc["delta"].(string); ok {
+			key := streamKey(base, doc)
+			st := r.streams[key]
+			if st == nil {
+				st = &stream{detok: streamdetok.New(streamMapping(base, mp))}
+				r.streams[key] = st
+			}
+			doc["delta"] = st.detok.Push(delta)
+			st.last = doc
+		}
+	}
+	if base, ok := strings.CutSuffix(typ, ".done"); ok {
+		out = append(out, r.finish(streamKey(base, doc))...)
+	}
+
+	texts, commit := collect(doc, r.fields)
+	for _, t := range texts {
+		*t = mapping.Detokenize(*t, mp)
+	}
+	args, commitArgs := collect(doc, map[string]bool{"arguments": true})
+	for _, a := range args {
+		*a = mapping.Detokenize(*a, jsonEscaped(mp))
+	}
+	commit()
+	commitArgs()
+	return append(out, marshal(doc))
+}
+
+// finish ends the stream under key and returns a delta frame carrying its
+// held-back text, if any.
+func (r *restorer) finish(key string) [][]byte {
+	st := r.streams[key]
+	if st == nil {
+		return nil
+	}
+	delete(r.streams, key)
+	rest := st.detok.Flush()
+	if rest == "" {
+		return nil
+	}
+	st.last["delta"] = rest
+	return [][]byte{marshal(st.last)}
+}
+
+// flush ends every open stream, for when the upstream closes mid-stream.
+func (r *restorer) flush() [][]byte {
+	var out [][]byte
+	for key := range r.streams {
+		out = append(out, r.finish(key)...)
+	}
+	return out
+}
+
+// streamKey identifies one streamed field: the event family plus the IDs
+// and indexes Realtime-style events carry.
+func streamKey(base string, doc map[string]any) string {
+	key := base
+	for _, k := range []string{"response_id", "item_id", "output_index", "content_index", "call_id"} {
+		key += fmt.Sprintf("/%v", doc[k])
+	}
+	return key
+}
+
+// streamMapping returns mp as it should be spliced into the stream:
+// function-call arguments are JSON text, so their values are escaped.
+func streamMapping(base string, mp map[string]string) map[string]string {
+	if strings.Contains(base, "arguments") {
+		return jsonEscaped(mp)
+	}
+	return mp
+}
+
+// jsonEscaped returns mp with values escaped for splicing into JSON string
+// literals, so a restored quote or backslash can't break the arguments.
+func jsonEscaped(mp map[string]string) map[string]string {
+	out := make(map[string]string, len(mp))
+	for k, v := range mp {
+		b := marshal(v)
+		out[k] = string(b[1 : len(b)-1])
+	}
+	return out
+}
+
+// decode parses frame as a JSON object, keeping numbers as they were
+// written.
+func decode(frame []byte) (map[string]any, bool) {
+	dec := json.NewDecoder(bytes.NewReader(frame))
+	dec.UseNumber()
+	var doc map[string]any
+	if dec.Decode(&doc) != nil || doc == nil || dec.More() {
+		return nil, false
+	}
+	return doc, true
+}
+
+// marshal encodes v as JSON without HTML escaping, so tokens stay readable
+// as <Person_1> rather than \u003cPerson_1\u003e.
+func marshal(v any) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		panic(err) // only JSON-decoded values and strings are encoded
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n")
+}
@@ -0,0 +1,122 @@
+// WebSocket proxy + Blindfold: Protect Realtime-style and custom chat
+// WebSocket APIs.
+//
+// Runs a WebSocket proxy in front of a model API. Every text frame the
+// client sends is tokenized before it goes upstream; every frame coming
+// back is detokenized before the client sees it. Each connection keeps its
+// own mapping, so a value keeps one token for the whole conversation, and
+// the mapping is dropped when the connection closes. Streamed deltas are
+// restored even when a placeholder is split across frames, and close
+// frames are relayed with their code and reason. On SIGTERM the proxy
+// stops taking connections and closes the open ones with 1001 once their
+// responses are restored, within -shutdown-timeout.
+//
+// Works in two modes:
+//   - Local mode (no API key): PII detected via built-in regex patterns (emails, cards, SSNs, etc.)
+//   - Cloud mode (with API key): NLP-powered detection adds names, addresses, organizations
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/config"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/health"
+)
+
+func main() {
+	// Every flag can also be set as WS_PROXY_<FLAG> or in the -config file
+	cfg := config.New(flag.CommandLine, "WS_PROXY")
+	addr := flag.String("addr", "127.0.0.1:8081", "listen address")
+	upstream := flag.String("upstream", "wss://api.openai.com", "upstream WebSocket base URL; the request path and query are appended")
+	fields := flag.String("fields", defaultFields, "comma-separated JSON keys whose string values are protected")
+	allowBinary := flag.Bool("allow-binary", false, "relay binary frames as-is instead of closing the connection")
+	runDemo := flag.Bool("demo", false, "proxy a conversation with an in-process fake model and exit")
+	drainDelay := flag.Duration("drain-delay", 0, "on SIGTERM, keep taking connections this long while /readyz reports draining, for load balancers to notice")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "on SIGTERM, how long open connections get to finish their responses before they are closed")
+	cfg.File("config")
+	cfg.Check("upstream", func() error {
+		if u, err := url.Parse(*upstream); err != nil || (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+			return errors.New("want a ws:// or wss:// URL")
+		}
+		return nil
+	})
+	cfg.Check("drain-delay", func() error {
+		if *drainDelay < 0 {
+			return errors.New("must not be negative")
+		}
+		return nil
+	})
+	cfg.Check("shutdown-timeout", func() error {
+		if *shutdownTimeout <= 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	})
+	if err := cfg.Parse(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	p := &proxy{
+		// API key is optional — omit it to run in local mode (regex-based, offline)
+		bf:          bfclient.FromEnv(),
+		upstream:    *upstream,
+		header:      http.Header{},
+		fields:      parseFields(*fields),
+		allowBinary: *allowBinary,
+	}
+	if key := os.Getenv("OPENAI_API_KEY"); key != "" && !*runDemo {
+		p.header.Set("Authorization", "Bearer "+key)
+	}
+
+	if *runDemo {
+		if err := demo(p); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	// SIGTERM, as from a container runtime, drains the proxy; a second
+	// signal exits at once
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Printf("proxying ws://%s → %s", *addr, *upstream)
+	mux := http.NewServeMux()
+	mux.Handle("/", p)
+	hc := health.NewService(flag.CommandLine, nil, p.bf)
+	hc.Register(mux)
+	srv := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	select {
+	case err := <-errc:
+		log.Print(err)
+		return
+	case <-ctx.Done():
+		stop()
+	}
+
+	hc.Drain()
+	p.mu.Lock()
+	log.Printf("shutting down: draining %d connections", len(p.live))
+	p.mu.Unlock()
+	if *drainDelay > 0 {
+		time.Sleep(*drainDelay)
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	// Stop listening first, then let the upgraded connections finish
+	if err := srv.Shutdown(shutdown); err != nil {
+		log.Printf("shutdown: %v", err)
+	}
+	p.shutdown(shutdown)
+	log.Printf("proxy stopped after %d connections", p.conns.Load())
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+)
+
+// closeGrace is how long the other side gets to answer a close frame
+// before its connection is dropped.
+const closeGrace = 5 * time.Second
+
+// drainQuiet is how long a session must go without a frame either way,
+// and without a delta stream open, before a shutdown closes it.
+const drainQuiet = time.Second
+
+// proxy relays WebSocket connections to upstream, tokenizing frames on the
+// way out and detokenizing them on the way back.
+type proxy struct {
+	bf          bfclient.Client
+	upstream    string      // ws:// or wss:// base URL; the request path and query are appended
+	header      http.Header // sent on every upstream handshake
+	fields      map[string]bool
+	allowBinary bool
+	upgrader    websocket.Upgrader
+	conns       atomic.Int64
+	sessions    sync.WaitGroup // open connections
+
+	mu       sync.Mutex
+	live     map[*session]bool
+	draining bool
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsWebSocketUpgrade(r) {
+		http.Error(w, "expected a WebSocket upgrade", http.StatusBadRequest)
+		return
+	}
+	if p.isDraining() {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Dial upstream first, so a refused handshake reaches the caller as an
+	// HTTP error rather than an immediate close
+	header := p.header.Clone()
+	for _, h := range []string{"OpenAI-Beta", "OpenAI-Organization", "OpenAI-Project"} {
+		if v := r.Header.Get(h); v != "" {
+			header.Set(h, v)
+		}
+	}
+	if header.Get("Authorization") == "" {
+		if v := r.Header.Get("Authorization"); v != "" {
+			header.Set("Authorization", v)
+		}
+	}
+	dialer := *websocket.DefaultDialer
+	dialer.Subprotocols = websocket.Subprotocols(r)
+	up, resp, err := dialer.DialContext(r.Context(), p.upstream+r.URL.RequestURI(), header)
+	if err != nil {
+		status := http.StatusBadGateway
+		if resp != nil && resp.StatusCode >= 400 {
+			status = resp.StatusCode
+		}
+		http.Error(w, "upstream: "+err.Error(), status)
+		return
+	}
+
+	var accept http.Header
+	if sp := up.Subprotocol(); sp != "" {
+		accept = http.Header{"Sec-Websocket-Protocol": {sp}}
+	}
+	client, err := p.upgrader.Upgrade(w, r, accept)
+	if err != nil {
+		up.Close() // Upgrade has already answered the caller
+		return
+	}
+
+	p.sessions.Add(1)
+	defer p.sessions.Done()
+	s := &session{
+		id:       p.conns.Add(1),
+		proxy:    p,
+		client:   client,
+		up:       up,
+		mapping:  map[string]string{},
+		entities: map[string]int{},
+	}
+	s.last.Store(time.Now().UnixNano())
+	if !p.track(s) {
+		// The drain began during the handshake
+		closeBoth(s, websocket.CloseGoingAway, "proxy shutting down")
+		client.Close()
+		up.Close()
+		return
+	}
+	defer p.untrack(s)
+	s.run()
+}
+
+func (p *proxy) isDraining() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.draining
+}
+
+// track registers s for shutdown, unless the proxy is draining.
+func (p *proxy) track(s *session) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.draining {
+		return false
+	}
+	if p.live == nil {
+		p.live = make(map[*session]bool)
+	}
+	p.live[s] = true
+	return true
+}
+
+func (p *proxy) untrack(s *session) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.live, s)
+}
+
+// shutdown refuses new connections and closes the open ones as they go
+// quiet, so a response being streamed is restored to its end before its
+// connection closes. When ctx is done, the sessions still busy are closed
+// too. shutdown returns once every session is over.
+//
+// The HTTP server's own Shutdown doesn't wait for these: upgraded
+// connections are hijacked, and it no longer sees them.
+func (p *proxy) shutdown(ctx context.Context) {
+	p.mu.Lock()
+	p.draining = true
+	p.mu.Unlock()
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		p.mu.Lock()
+		open := len(p.live)
+		for s := range p.live {
+			if ctx.Err() != nil || s.quiet() {
+				s.goAway()
+			}
+		}
+		p.mu.Unlock()
+		if open == 0 {
+			return
+		}
+		<-tick.C
+	}
+}
+
+// session is one proxied connection. Its mapping lives only as long as the
+// connection and is never logged.
+type session struct {
+	id     int64
+	proxy  *proxy
+	client *websocket.Conn
+	up     *websocket.Conn
+
+	mu       sync.Mutex
+	mapping  map[string]string // replaced, never modified, so readers can hold a snapshot
+	entities map[string]int    // entity type → count, for the close log
+
+	framesOut, framesIn atomic.Int64
+	last                atomic.Int64 // UnixNano of the last frame either way
+	streams             atomic.Int64 // delta streams the restorer holds open
+	leaving             sync.Once
+}
+
+func (s *session) snapshot() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mapping
+}
+
+// quiet reports whether s is between responses, with no delta stream open
+// and no frame for drainQuiet.
+func (s *session) quiet() bool {
+	return s.streams.Load() == 0 && time.Since(time.Unix(0, s.last.Load())) >= drainQuiet
+}
+
+// goAway closes s from the upstream side with 1001, as if upstream were
+// closing: inbound releases any text its restorer holds, then relays the
+// close to the client. Upstream gets closeGrace to answer.
+func (s *session) goAway() {
+	s.leaving.Do(func() {
+		msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "proxy shutting down")
+		_ = s.up.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		_ = s.up.SetReadDeadline(time.Now().Add(closeGrace))
+	})
+}
+
+// run relays frames both ways until either side closes, then gives the
+// other side closeGrace to finish the close handshake.
+func (s *session) run() {
+	log.Printf("conn %d: open", s.id)
+	done := make(chan string, 2)
+	go func() { done <- s.outbound() }()
+	go func() { done <- s.inbound() }()
+
+	reason := <-done
+	deadline := time.Now().Add(closeGrace)
+	_ = s.client.SetReadDeadline(deadline)
+	_ = s.up.SetReadDeadline(deadline)
+	<-done
+	s.client.Close()
+	s.up.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	log.Printf("conn %d: %s; %d frames out, %d in; entities %s",
+		s.id, reason, s.framesOut.Load(), s.framesIn.Load(), formatCounts(s.entities))
+}
+
+// outbound tokenizes client frames and forwards them upstream. If
+// tokenization fails the frame is dropped and both sides are closed:
+// nothing is forwarded unprotected.
+func (s *session) outbound() string {
+	for {
+		typ, frame, err := s.client.ReadMessage()
+		if err != nil {
+			return "client " + relayClose(s.up, err)
+		}
+		switch typ {
+		case websocket.BinaryMessage:
+			if !s.proxy.allowBinary {
+				closeBoth(s, websocket.CloseUnsupportedData, "binary frames can't be inspected")
+				return "client sent a binary frame"
+			}
+		case websocket.TextMessage:
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			out, mp, entities, err := tokenizeFrame(ctx, s.proxy.bf, frame, s.proxy.fields, s.snapshot())
+			cancel()
+			if err != nil {
+				log.Printf("conn %d: tokenize: %v", s.id, err)
+				closeBoth(s, websocket.CloseInternalServerErr, "blindfold: tokenization failed")
+				return "tokenization failed"
+			}
+			s.mu.Lock()
+			s.mapping = mp
+			for _, e := range entities {
+				s.entities[e.Type]++
+			}
+			s.mu.Unlock()
+			frame = out
+		}
+		if err := s.up.WriteMessage(typ, frame); err != nil {
+			return "upstream write failed"
+		}
+		s.framesOut.Add(1)
+		s.last.Store(time.Now().UnixNano())
+	}
+}
+
+// inbound detokenizes upstream frames and forwards them to the client.
+func (s *session) inbound() string {
+	r := newRestorer(s.proxy.fields)
+	for {
+		typ, frame, err := s.up.ReadMessage()
+		if err != nil {
+			// Release text held back by streams the upstream never finished
+			for _, f := range r.flush() {
+				_ = s.client.WriteMessage(websocket.TextMessage, f)
+			}
+			return "upstream " + relayClose(s.client, err)
+		}
+		s.last.Store(time.Now().UnixNano())
+		frames := [][]byte{frame}
+		if typ == websocket.TextMessage {
+			frames = r.restore(frame, s.snapshot())
+			s.streams.Store(int64(len(r.streams)))
+		}
+		for _, f := range frames {
+			if err := s.client.WriteMessage(typ, f); err != nil {
+				return "client write failed"
+			}
+		}
+		s.framesIn.Add(1)
+	}
+}
+
+// relayClose passes the close frame behind err on to dst, keeping its code
+// and reason, and describes what happened.
+func relayClose(dst *websocket.Conn, err error) string {
+	code, text := websocket.CloseGoingAway, ""
+	var ce *websocket.CloseError
+	if errors.As(err, &ce) {
+		code, text = ce.Code, ce.Text
+	}
+	msg := websocket.FormatCloseMessage(code, text)
+	switch code {
+	case websocket.CloseNoStatusReceived:
+		msg = []byte{} // a close frame without a status
+	case websocket.CloseAbnormalClosure, websocket.CloseTLSHandshake:
+		msg = websocket.FormatCloseMessage(websocket.CloseGoingAway, "") // not sendable on the wire
+	}
+	_ = dst.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	if ce != nil {
+		return "closed (" + closeName(ce.Code) + ")"
+	}
+	return "dropped"
+}
+
+func closeBoth(s *session, code int, text string) {
+	msg := websocket.FormatCloseMessage(code, text)
+	deadline := time.Now().Add(time.Second)
+	_ = s.client.WriteControl(websocket.CloseMessage, msg, deadline)
+	_ = s.up.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), deadline)
+}
+
+func closeName(code int) string {
+	switch code {
+	case websocket.CloseNormalClosure:
+		return "1000 normal"
+	case websocket.CloseGoingAway:
+		return "1001 going away"
+	case websocket.CloseNoStatusReceived:
+		return "no status"
+	case websocket.CloseAbnormalClosure:
+		return "1006 abnormal"
+	}
+	return fmt.Sprint(code)
+}
+
+func formatCounts(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "none"
+	}
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	parts := make([]string, len(types))
+	for i, t := range types {
+		parts[i] = fmt.Sprintf("%s=%d", t, counts[t])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,332 @@
+// Package anomaly watches how much PII a service detects, per route and
+// tenant, and raises an alert when it changes in a way that needs a human:
+//
+//   - a spike: far more entities per request than the baseline, over a
+//     window or in a single request, as when someone pastes a customer
+//     export or a database dump into a prompt;
+//   - a drop to zero: requests keep coming but nothing is detected where
+//     there used to be plenty, as when a policy change, an encoding trick
+//     or a broken deployment lets PII through undetected.
+//
+// A Detector is an audit.Sink, so it sees the events a protection service
+// already records. It counts entities and requests in fixed windows, and
+// at the end of each window compares the window's entities per request,
+// and its largest request, with an exponentially weighted baseline for
+// its route and tenant.
+// Requests that were rejected take no part. Changes in traffic alone
+// don't alert: twice the requests at the usual rate is not an anomaly.
+//
+//	det := anomaly.New(anomaly.Slack{URL: os.Getenv("SLACK_WEBHOOK_URL")})
+//	go det.Run(ctx)
+//	gw := gateway.New(gateway.Config{Audit: audit.Tee(sink, det), …})
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/audit"
+)
+
+// Kinds of alert.
+const (
+	Spike = "spike" // entities per request far above the baseline
+	Zero  = "zero"  // requests with no entities where some were expected
+)
+
+// Key is what a baseline is kept for.
+type Key struct {
+	Route  string `json:"route"` // the event's operation, such as chat.completions
+	Tenant string `json:"tenant,omitempty"`
+}
+
+func (k Key) String() string {
+	if k.Tenant == "" {
+		return k.Route
+	}
+	return k.Route + " (tenant " + k.Tenant + ")"
+}
+
+// Alert describes one anomalous window. It holds counts and entity types,
+// never values.
+type Alert struct {
+	Kind string `json:"kind"`
+	Key
+	Start    time.Time      `json:"window_start"`
+	End      time.Time      `json:"window_end"`
+	Requests int            `json:"requests"`
+	Entities int            `json:"entities"`
+	Largest  int            `json:"largest_request"` // entities in the window's largest request
+	Types    map[string]int `json:"types,omitempty"`
+	// Baseline is the usual number of entities per request; Expected is
+	// what the window's requests would have held at that rate.
+	Baseline float64 `json:"baseline_per_request"`
+	Expected float64 `json:"expected"`
+}
+
+// Message describes a in a sentence or two, for chat and logs.
+func (a Alert) Message() string {
+	when := fmt.Sprintf("in the %s from %s UTC", a.End.Sub(a.Start).Round(time.Second), a.Start.UTC().Format("15:04"))
+	switch a.Kind {
+	case Spike:
+		return fmt.Sprintf("PII spike on %s: %d entities in %d requests %s, %.1f per request and %d in the largest, against a baseline of %.1f. Most detected: %s. Possible data dump into a prompt.",
+			a.Key, a.Entities, a.Requests, when, float64(a.Entities)/float64(a.Requests), a.Largest, a.Baseline, topTypes(a.Types, 3))
+	case Zero:
+		return fmt.Sprintf("No PII detected on %s: 0 entities in %d requests %s, where about %.0f were expected at %.1f per request. Possible detection bypass or policy change.",
+			a.Key, a.Requests, when, a.Expected, a.Baseline)
+	}
+	return fmt.Sprintf("%s on %s", a.Kind, a.Key)
+}
+
+// Notifier delivers alerts.
+type Notifier interface {
+	Notify(ctx context.Context, a Alert) error
+}
+
+// Defaults for the Detector's settings.
+const (
+	DefaultWindow      = time.Minute
+	DefaultWarmup      = 10
+	DefaultAlpha       = 0.1
+	DefaultSpikeFactor = 5
+	DefaultMinSpike    = 50
+	DefaultMinRequests = 5
+	DefaultMinExpected = 10
+	DefaultCooldown    = 15 * time.Minute
+	DefaultMaxKeys     = 1000
+	DefaultForget      = 24 * time.Hour
+)
+
+// Detector keeps a baseline per route and tenant and raises alerts. Set
+// its fields before the first Record; zero values take the defaults.
+type Detector struct {
+	// Window is how long entities and requests are counted before they
+	// are compared with the baseline.
+	Window time.Duration
+	// Warmup is how many windows with traffic a key needs before its
+	// baseline is trusted.
+	Warmup int
+	// Alpha is the weight of each new window in the baseline.
+	Alpha float64
+	// SpikeFactor and MinSpike: a window is a spike when its entities per
+	// request, or the entities of one of its requests, reach SpikeFactor
+	// times the baseline and number at least MinSpike.
+	SpikeFactor float64
+	MinSpike    int
+	// MinRequests and MinExpected: a window is a drop to zero when it has
+	// at least MinRequests requests and no entities, and the baseline
+	// expected at least MinExpected.
+	MinRequests int
+	MinExpected float64
+	// Cooldown is how long an alert of one kind isn't repeated for a key.
+	Cooldown time.Duration
+	// MaxKeys caps the routes and tenants tracked. Events for new keys
+	// beyond it are ignored, so a caller can't exhaust memory with made-up
+	// tenants.
+	MaxKeys int
+	// Forget drops a key after this long without traffic.
+	Forget time.Duration
+
+	notifiers []Notifier
+
+	mu    sync.Mutex
+	keys  map[Key]*state
+	start time.Time // of the current window
+}
+
+// state is one key's baseline and current window.
+type state struct {
+	perRequest float64 // baseline entities per request
+	windows    int     // windows with traffic folded into the baseline
+	lastSeen   time.Time
+	alerted    map[string]time.Time // by kind
+
+	requests, entities, largest int
+	types                       map[string]int
+}
+
+var _ audit.Sink = (*Detector)(nil)
+
+// New returns a Detector that sends alerts to every notifier, and logs
+// them.
+func New(notifiers ...Notifier) *Detector {
+	return &Detector{notifiers: notifiers, keys: make(map[Key]*state)}
+}
+
+func (d *Detector) defaults() {
+	set := func(v *int, def int) {
+		if *v <= 0 {
+			*v = def
+		}
+	}
+	setf := func(v *float64, def float64) {
+		if *v <= 0 {
+			*v = def
+		}
+	}
+	setd := func(v *time.Duration, def time.Duration) {
+		if *v <= 0 {
+			*v = def
+		}
+	}
+	setd(&d.Window, DefaultWindow)
+	set(&d.Warmup, DefaultWarmup)
+	setf(&d.Alpha, DefaultAlpha)
+	setf(&d.SpikeFactor, DefaultSpikeFactor)
+	set(&d.MinSpike, DefaultMinSpike)
+	set(&d.MinRequests, DefaultMinRequests)
+	setf(&d.MinExpected, DefaultMinExpected)
+	setd(&d.Cooldown, DefaultCooldown)
+	set(&d.MaxKeys, DefaultMaxKeys)
+	setd(&d.Forget, DefaultForget)
+}
+
+// Record counts a forwarded event in its key's current window. It never
+// fails, so it can't hold up a request.
+func (d *Detector) Record(_ context.Context, e audit.Event) error {
+	if e.Outcome != audit.Forwarded {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	k := Key{Route: e.Operation, Tenant: e.Tenant}
+	s, ok := d.keys[k]
+	if !ok {
+		d.defaults()
+		if len(d.keys) >= d.MaxKeys {
+			return nil
+		}
+		s = &state{alerted: make(map[string]time.Time), types: make(map[string]int)}
+		d.keys[k] = s
+	}
+	s.requests++
+	total := 0
+	for typ, n := range e.Entities {
+		total += n
+		s.types[typ] += n
+	}
+	s.entities += total
+	s.largest = max(s.largest, total)
+	return nil
+}
+
+// Close is a no-op.
+func (d *Detector) Close() error { return nil }
+
+// Run closes a window every Window until ctx is done, and delivers the
+// alerts they raise.
+func (d *Detector) Run(ctx context.Context) {
+	d.mu.Lock()
+	d.defaults()
+	d.start = time.Now()
+	window := d.Window
+	d.mu.Unlock()
+
+	tick := time.NewTicker(window)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-tick.C:
+			for _, a := range d.rotate(now) {
+				d.notify(ctx, a)
+			}
+		}
+	}
+}
+
+func (d *Detector) notify(ctx context.Context, a Alert) {
+	log.Printf("anomaly: %s", a.Message())
+	for _, n := range d.notifiers {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		if err := n.Notify(ctx, a); err != nil {
+			log.Printf("anomaly: notify: %v", err)
+		}
+		cancel()
+	}
+}
+
+// rotate ends the window running until now: it compares each key's
+// counts with its baseline, folds the normal ones in, and starts a new
+// window. It returns the alerts raised, by key.
+func (d *Detector) rotate(now time.Time) []Alert {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.defaults()
+	start := d.start
+	if start.IsZero() {
+		start = now.Add(-d.Window)
+	}
+	d.start = now
+
+	var alerts []Alert
+	for k, s := range d.keys {
+		requests, entities, largest, types := s.requests, s.entities, s.largest, s.types
+		s.requests, s.entities, s.largest, s.types = 0, 0, 0, make(map[string]int)
+		if requests == 0 {
+			if now.Sub(s.lastSeen) > d.Forget {
+				delete(d.keys, k)
+			}
+			continue
+		}
+		s.lastSeen = now
+
+		rate := float64(entities) / float64(requests)
+		a := Alert{Key: k, Start: start, End: now, Requests: requests, Entities: entities,
+			Largest: largest, Types: types, Baseline: s.perRequest, Expected: s.perRequest * float64(requests)}
+		if s.windows >= d.Warmup {
+			switch {
+			case entities >= d.MinSpike && rate >= d.SpikeFactor*s.perRequest,
+				largest >= d.MinSpike && float64(largest) >= d.SpikeFactor*s.perRequest:
+				a.Kind = Spike
+			case entities == 0 && requests >= d.MinRequests && a.Expected >= d.MinExpected:
+				a.Kind = Zero
+			}
+		}
+		if a.Kind != "" {
+			// Anomalous windows stay out of the baseline, so an anomaly
+			// that lasts keeps alerting, once per Cooldown
+			if last, ok := s.alerted[a.Kind]; !ok || now.Sub(last) >= d.Cooldown {
+				s.alerted[a.Kind] = now
+				alerts = append(alerts, a)
+			}
+			continue
+		}
+		if s.windows == 0 {
+			s.perRequest = rate
+		} else {
+			s.perRequest += d.Alpha * (rate - s.perRequest)
+		}
+		s.windows++
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Key.String() < alerts[j].Key.String() })
+	return alerts
+}
+
+// topTypes lists the n most frequent types in counts.
+func topTypes(counts map[string]int, n int) string {
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if counts[types[i]] != counts[types[j]] {
+			return counts[types[i]] > counts[types[j]]
+		}
+		return types[i] < types[j]
+	})
+	if len(types) > n {
+		types = types[:n]
+	}
+	parts := make([]string, len(types))
+	for i, t := range types {
+		parts[i] = fmt.Sprintf("%s %d", t, counts[t])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,141 @@
+package anomaly
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/audit"
+)
+
+func record(d *Detector, tenant string, requests int, entities map[string]int) {
+	for i := 0; i < requests; i++ {
+		d.Record(context.Background(), audit.Event{Operation: "chat.completions", Tenant: tenant, Outcome: audit.Forwarded, Entities: entities})
+	}
+}
+
+func TestSpikeAndZero(t *testing.T) {
+	d := New()
+	d.MaxKeys = 2
+	now := time.Date(2026, 10, 14, 14, 0, 0, 0, time.UTC)
+	rotate := func() []Alert {
+		now = now.Add(time.Minute)
+		return d.rotate(now)
+	}
+
+	// Warm up: two emails a request for both tenants
+	for i := 0; i < DefaultWarmup; i++ {
+		record(d, "acme", 10, map[string]int{"Email Address": 2})
+		record(d, "globex", 10, map[string]int{"Email Address": 2})
+		if alerts := rotate(); len(alerts) != 0 {
+			t.Fatalf("warmup window %d: %v", i, alerts)
+		}
+	}
+
+	// Twice the traffic at the usual rate is not an anomaly; rejected
+	// requests and a third tenant, beyond MaxKeys, aren't counted
+	record(d, "acme", 20, map[string]int{"Email Address": 2})
+	record(d, "globex", 10, map[string]int{"Email Address": 2})
+	record(d, "initech", 10, nil)
+	d.Record(context.Background(), audit.Event{Operation: "chat.completions", Tenant: "acme", Outcome: audit.Rejected})
+	if alerts := rotate(); len(alerts) != 0 {
+		t.Fatalf("busy window: %v", alerts)
+	}
+
+	// A customer export pasted into one of acme's prompts
+	record(d, "acme", 9, map[string]int{"Email Address": 2})
+	record(d, "acme", 1, map[string]int{"Email Address": 300, "Phone Number": 120})
+	record(d, "globex", 10, map[string]int{"Email Address": 2})
+	alerts := rotate()
+	if len(alerts) != 1 || alerts[0].Kind != Spike || alerts[0].Tenant != "acme" || alerts[0].Entities != 438 {
+		t.Fatalf("spike: %+v", alerts)
+	}
+	msg := alerts[0].Message()
+	if !strings.Contains(msg, "PII spike on chat.completions (tenant acme): 438 entities in 10 requests in the 1m0s from 14:11 UTC, 43.8 per request and 420 in the largest") ||
+		!strings.Contains(msg, "Email Address 318, Phone Number 120") {
+		t.Errorf("message: %s", msg)
+	}
+
+	// It lasts: kept out of the baseline, and not repeated within Cooldown
+	record(d, "acme", 1, map[string]int{"Email Address": 300})
+	if alerts := rotate(); len(alerts) != 0 {
+		t.Errorf("repeated within cooldown: %v", alerts)
+	}
+	if base := d.keys[Key{"chat.completions", "acme"}].perRequest; base < 1.9 || base > 2.1 {
+		t.Errorf("acme baseline = %.2f, want about 2", base)
+	}
+
+	// One large request among many ordinary ones
+	record(d, "globex", 100, map[string]int{"Email Address": 2})
+	record(d, "globex", 1, map[string]int{"Social Security Number": 80})
+	alerts = rotate()
+	if len(alerts) != 1 || alerts[0].Kind != Spike || alerts[0].Tenant != "globex" || alerts[0].Largest != 80 {
+		t.Fatalf("one large request: %+v", alerts)
+	}
+
+	// globex's requests keep coming, with nothing detected
+	record(d, "globex", 30, nil)
+	alerts = rotate()
+	if len(alerts) != 1 || alerts[0].Kind != Zero || alerts[0].Tenant != "globex" || alerts[0].Expected != 60 {
+		t.Fatalf("zero: %+v", alerts)
+	}
+	if !strings.Contains(alerts[0].Message(), "0 entities in 30 requests") {
+		t.Errorf("message: %s", alerts[0].Message())
+	}
+
+	// A quiet window alerts on neither
+	if alerts := rotate(); len(alerts) != 0 {
+		t.Errorf("no traffic: %v", alerts)
+	}
+}
+
+func TestNotifiers(t *testing.T) {
+	var got []*http.Request
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got, bodies = append(got, r), append(bodies, string(body))
+		if r.URL.Path == "/down" {
+			http.Error(w, "no_service", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	a := Alert{Kind: Zero, Key: Key{Route: "embeddings"}, Requests: 30, Baseline: 2, Expected: 60,
+		Start: time.Date(2026, 10, 14, 14, 0, 0, 0, time.UTC), End: time.Date(2026, 10, 14, 14, 1, 0, 0, time.UTC)}
+	ctx := context.Background()
+	if err := (Webhook{URL: srv.URL + "/hook", Secret: "s3cret"}).Notify(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := (Slack{URL: srv.URL + "/services/T000/B000/XXXX"}).Notify(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	err := (Slack{URL: srv.URL + "/down?token=XXXX"}).Notify(ctx, a)
+	if err == nil || strings.Contains(err.Error(), "XXXX") || !strings.Contains(err.Error(), "404") {
+		t.Errorf("failing Slack: %v", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(bodies[0]))
+	if got[0].Header.Get("X-Blindfold-Signature") != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("signature %q", got[0].Header.Get("X-Blindfold-Signature"))
+	}
+	var hook map[string]any
+	json.Unmarshal([]byte(bodies[0]), &hook)
+	if hook["kind"] != "zero" || hook["route"] != "embeddings" || hook["expected"] != 60.0 || !strings.HasPrefix(hook["message"].(string), "No PII detected on embeddings") {
+		t.Errorf("webhook body: %s", bodies[0])
+	}
+	var slack map[string]string
+	json.Unmarshal([]byte(bodies[1]), &slack)
+	if !strings.HasPrefix(slack["text"], ":warning: No PII detected") {
+		t.Errorf("slack body: %s", bodies[1])
+	}
+}
@@ -0,0 +1,97 @@
+package anomaly
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Webhook posts each alert as JSON: the Alert's fields plus "message".
+// With a Secret, the body is signed with HMAC-SHA256 in the
+// X-Blindfold-Signature header, as "sha256=<hex>", so the receiver can
+// tell alerts from forgeries.
+type Webhook struct {
+	URL    string
+	Secret string
+	// Client sends the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Notify posts a.
+func (w Webhook) Notify(ctx context.Context, a Alert) error {
+	body, err := json.Marshal(struct {
+		Alert
+		Message string `json:"message"`
+	}{a, a.Message()})
+	if err != nil {
+		return err
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	if w.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.Secret))
+		mac.Write(body)
+		header.Set("X-Blindfold-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return post(ctx, w.Client, w.URL, header, body)
+}
+
+// Slack posts each alert to a Slack incoming webhook. The URL is a
+// secret: anyone who has it can post to the channel.
+type Slack struct {
+	URL string
+	// Client sends the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Notify posts a as a message.
+func (s Slack) Notify(ctx context.Context, a Alert) error {
+	icon := ":rotating_light:"
+	if a.Kind == Zero {
+		icon = ":warning:"
+	}
+	body, err := json.Marshal(map[string]string{"text": icon + " " + a.Message()})
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.Client, s.URL, http.Header{"Content-Type": {"application/json"}}, body)
+}
+
+func post(ctx context.Context, client *http.Client, url string, header http.Header, body []byte) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL may hold a token; the error names only the host
+		return fmt.Errorf("post to %s: %w", req.URL.Host, unwrapURL(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("post to %s: %s: %s", req.URL.Host, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// unwrapURL strips the *url.Error around err, which would repeat the whole
+// URL.
+func unwrapURL(err error) error {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		return ue.Err
+	}
+	return err
+}
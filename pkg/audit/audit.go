@@ -0,0 +1,89 @@
+// Package audit records what a protection pipeline did with each request:
+// which entity types were found, which tokens replaced them, the policy
+// applied, and where the tokenized text was sent. Events never contain the
+// detected values, so the audit log can be kept and shared far more freely
+// than the data it describes.
+//
+// Together with the payload hash, an event lets you show an auditor that
+// the bytes sent to an LLM vendor contained tokens, not the PII they stand
+// for.
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+// Outcomes of an audited request.
+const (
+	Forwarded = "forwarded" // tokenized text was sent to the destination
+	Rejected  = "rejected"  // the request was refused before anything was sent
+)
+
+// Event is one audited request.
+type Event struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	// Operation names the protected call, e.g. "chat.completions".
+	Operation string `json:"operation"`
+	Policy    string `json:"policy,omitempty"`
+	// Tenant is the caller's tenant, when the service is told one.
+	Tenant string `json:"tenant,omitempty"`
+	// Destination is where the tokenized payload went (host or service).
+	Destination string `json:"destination,omitempty"`
+	Model       string `json:"model,omitempty"`
+	// Entities counts detected entities by type.
+	Entities map[string]int `json:"entities"`
+	// Tokens lists the placeholders in the payload, sorted.
+	Tokens []string `json:"tokens"`
+	// PayloadSHA256 is the hex SHA-256 of the exact bytes sent to the
+	// destination.
+	PayloadSHA256 string `json:"payload_sha256,omitempty"`
+	Outcome       string `json:"outcome"`
+}
+
+// Sink stores events.
+type Sink interface {
+	Record(ctx context.Context, e Event) error
+	Close() error
+}
+
+// NewEvent builds an event for a tokenized request from the detected
+// entities and the mapping's tokens. Only types and token names are kept.
+func NewEvent(requestID, operation, policy string, entities []blindfold.DetectedEntity, mapping map[string]string) Event {
+	e := Event{
+		Time:      time.Now().UTC(),
+		RequestID: requestID,
+		Operation: operation,
+		Policy:    policy,
+		Entities:  make(map[string]int),
+		Tokens:    make([]string, 0, len(mapping)),
+	}
+	for _, ent := range entities {
+		e.Entities[ent.Type]++
+	}
+	for token := range mapping {
+		e.Tokens = append(e.Tokens, token)
+	}
+	sort.Strings(e.Tokens)
+	return e
+}
+
+// HashPayload returns the hex SHA-256 of payload, for Event.PayloadSHA256.
+func HashPayload(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewRequestID returns a random 128-bit request ID.
+func NewRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,223 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+func event(at time.Time, outcome string, entities map[string]int) Event {
+	return Event{Time: at, RequestID: at.Format("150405"), Operation: "chat.completions", Policy: "basic",
+		Destination: "api.openai.com", Entities: entities, Tokens: []string{}, Outcome: outcome}
+}
+
+func TestNewEventKeepsNoValues(t *testing.T) {
+	e := NewEvent("r1", "chat.completions", "basic",
+		[]blindfold.DetectedEntity{{Type: "Person", Text: "Jane Doe"}, {Type: "Person", Text: "John Roe"}, {Type: "Email Address", Text: "jane@example.com"}},
+		map[string]string{"<Person_2>": "John Roe", "<Person_1>": "Jane Doe", "<Email Address_1>": "jane@example.com"})
+	if want := map[string]int{"Person": 2, "Email Address": 1}; !reflect.DeepEqual(e.Entities, want) {
+		t.Errorf("entities = %v", e.Entities)
+	}
+	if want := []string{"<Email Address_1>", "<Person_1>", "<Person_2>"}; !reflect.DeepEqual(e.Tokens, want) {
+		t.Errorf("tokens = %v", e.Tokens)
+	}
+	var buf bytes.Buffer
+	_ = NewWriter(&buf).Record(context.Background(), e)
+	for _, v := range []string{"Jane Doe", "John Roe", "jane@example.com"} {
+		if strings.Contains(buf.String(), v) {
+			t.Errorf("event holds %q: %s", v, buf.String())
+		}
+	}
+}
+
+func TestFileSinkRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	t0 := time.Date(2026, 10, 13, 9, 0, 0, 0, time.UTC)
+	events := []Event{
+		event(t0, Forwarded, map[string]int{"Person": 1}),
+		event(t0.Add(time.Minute), Rejected, map[string]int{}),
+	}
+	events[0].Tenant, events[0].Model, events[0].PayloadSHA256 = "acme", "gpt-4o-mini", HashPayload([]byte("<Person_1>"))
+
+	for i := 0; i < 2; i++ { // the second open appends
+		sink, err := Open(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Record(ctx, events[i]); err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("log mode: %v, %v", fi.Mode(), err)
+	}
+
+	got, err := Events(ctx, path, t0, t0.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, events) {
+		t.Errorf("read back\n%+v\nwant\n%+v", got, events)
+	}
+	if got, _ := Events(ctx, path, t0.Add(time.Second), t0.Add(time.Hour)); len(got) != 1 || got[0].Outcome != Rejected {
+		t.Errorf("from after the first event: %+v", got)
+	}
+}
+
+func TestReadJSONLRejectsGarbage(t *testing.T) {
+	log := `{"time":"2026-10-13T09:00:00Z","operation":"x","entities":{},"tokens":[],"outcome":"forwarded"}` + "\n\nnot json\n"
+	if _, err := ReadJSONL(strings.NewReader(log), time.Time{}, time.Now()); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("err = %v, want line 3", err)
+	}
+}
+
+func TestOpenTargets(t *testing.T) {
+	if s, err := Open(context.Background(), ""); s != nil || err != nil {
+		t.Errorf(`Open("") = %v, %v`, s, err)
+	}
+	if _, err := Events(context.Background(), "-", time.Time{}, time.Now()); err == nil {
+		t.Error("stdout is not readable: want an error")
+	}
+}
+
+func TestPostgresTableName(t *testing.T) {
+	// No connection is made before the name is checked
+	db, err := sql.Open("pgx", "postgres://nobody@127.0.0.1:1/none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, name := range []string{"", "audit events", "audit;DROP TABLE users", "1audit", `"audit"`, "audit.events"} {
+		if _, err := NewPostgres(context.Background(), db, name); err == nil || !strings.Contains(err.Error(), "invalid table name") {
+			t.Errorf("%q: err = %v", name, err)
+		}
+	}
+}
+
+func TestSummarizeWindow(t *testing.T) {
+	w := Window{Start: time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)}
+	events := []Event{
+		event(w.Start.Add(-time.Nanosecond), Forwarded, map[string]int{"Person": 9}), // before
+		event(w.Start, Forwarded, map[string]int{"Person": 2, "Email Address": 1}),   // first instant is in
+		event(w.Start.Add(12*time.Hour), Rejected, map[string]int{}),
+		event(w.End.Add(-time.Nanosecond), Forwarded, map[string]int{"Person": 0}),
+		event(w.End, Forwarded, map[string]int{"Person": 9}), // end is out
+	}
+	r := Summarize(events, w, w.End)
+	if r.Requests != 3 || r.RequestsWithPII != 1 {
+		t.Errorf("requests %d, with PII %d; want 3 and 1", r.Requests, r.RequestsWithPII)
+	}
+	if want := map[string]int{"Person": 2, "Email Address": 1}; !reflect.DeepEqual(r.Entities, want) {
+		t.Errorf("entities = %v", r.Entities)
+	}
+	if want := map[string]int{Forwarded: 2, Rejected: 1}; !reflect.DeepEqual(r.Outcomes, want) {
+		t.Errorf("outcomes = %v", r.Outcomes)
+	}
+	if r.Models["(none)"] != 3 {
+		t.Errorf("models = %v", r.Models)
+	}
+	// The hash covers the window's events only
+	if again := Summarize(events[1:4], w, w.End); again.EventsSHA256 != r.EventsSHA256 {
+		t.Error("events outside the window changed the hash")
+	}
+}
+
+func TestWindows(t *testing.T) {
+	from := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC) // a Wednesday
+	for _, c := range []struct {
+		unit  string
+		to    time.Time
+		start time.Time
+		n     int
+	}{
+		{"hour", from.Add(2 * time.Hour), time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC), 3},
+		{"day", from.Add(time.Hour), time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), 1},
+		{"week", from.AddDate(0, 0, 7), time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), 2},
+		{"month", from.AddDate(0, 1, 0), time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), 2},
+	} {
+		ws, err := Windows(from, c.to, c.unit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ws) != c.n || !ws[0].Start.Equal(c.start) {
+			t.Errorf("%s: %d windows from %s, want %d from %s", c.unit, len(ws), ws[0].Start, c.n, c.start)
+		}
+		for i := 1; i < len(ws); i++ {
+			if !ws[i].Start.Equal(ws[i-1].End) {
+				t.Errorf("%s: gap between %v and %v", c.unit, ws[i-1], ws[i])
+			}
+		}
+	}
+	if _, err := Windows(from, from, "fortnight"); err == nil {
+		t.Error("unknown unit: want an error")
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	report := []byte(`{"requests":3,"requests_with_pii":1}`)
+	at := time.Date(2026, 10, 14, 9, 30, 15, 500, time.FixedZone("CEST", 2*3600))
+
+	sig := Sign(report, key, at)
+	if err := Verify(report, sig, pub); err != nil {
+		t.Fatalf("round trip: %v", err)
+	}
+	if !sig.SignedAt.Equal(at.Truncate(time.Second)) || sig.SignedAt.Location() != time.UTC {
+		t.Errorf("signed at %v", sig.SignedAt)
+	}
+	// A signature read back from JSON still verifies
+	var buf bytes.Buffer
+	_ = json.NewEncoder(&buf).Encode(sig)
+	var decoded Signature
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || Verify(report, decoded, pub) != nil {
+		t.Errorf("after JSON: %v, %v", err, Verify(report, decoded, pub))
+	}
+
+	tampered := bytes.Replace(report, []byte(`"requests":3`), []byte(`"requests":2`), 1)
+	for _, c := range []struct {
+		name   string
+		report []byte
+		sig    func(Signature) Signature
+		pub    ed25519.PublicKey
+	}{
+		{"tampered report", tampered, nil, pub},
+		{"tampered report, hash updated", tampered, func(s Signature) Signature {
+			sum := sha256.Sum256(tampered)
+			s.SHA256 = hex.EncodeToString(sum[:])
+			return s
+		}, pub},
+		{"altered signed_at", report, func(s Signature) Signature { s.SignedAt = s.SignedAt.Add(-24 * time.Hour); return s }, pub},
+		{"wrong key id", report, func(s Signature) Signature { s.KeyID = KeyID(otherPub); return s }, pub},
+		{"other key", report, nil, otherPub},
+		{"bad algorithm", report, func(s Signature) Signature { s.Algorithm = "rsa"; return s }, pub},
+		{"bad encoding", report, func(s Signature) Signature { s.Signature = "not base64!"; return s }, pub},
+	} {
+		s := sig
+		if c.sig != nil {
+			s = c.sig(s)
+		}
+		if err := Verify(c.report, s, c.pub); err == nil {
+			t.Errorf("%s: verified", c.name)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// WriterSink writes events as JSON lines to an io.Writer.
+type WriterSink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewWriter returns a sink writing JSON lines to w. Close does not close w.
+func NewWriter(w io.Writer) *WriterSink {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &WriterSink{w: w, enc: enc}
+}
+
+// Record writes e as one line.
+func (s *WriterSink) Record(_ context.Context, e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(e)
+}
+
+// Close is a no-op.
+func (s *WriterSink) Close() error { return nil }
+
+// FileSink appends events as JSON lines to a file.
+type FileSink struct {
+	*WriterSink
+	f *os.File
+}
+
+// OpenFile opens path for appending, creating it with mode 0600.
+func OpenFile(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{WriterSink: NewWriter(f), f: f}, nil
+}
+
+// Close flushes the file to disk and closes it. Events recorded after
+// Close fail.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.f.Sync()
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" database/sql driver
+)
+
+// Open returns the sink described by target:
+//
+//   - "" — no sink (nil, nil)
+//   - "-" — JSON lines on stdout
+//   - "postgres://…" or "postgresql://…" — the audit_events table in that
+//     database
+//   - anything else — a JSON lines file at that path
+func Open(ctx context.Context, target string) (Sink, error) {
+	switch {
+	case target == "":
+		return nil, nil
+	case target == "-":
+		return NewWriter(os.Stdout), nil
+	case strings.HasPrefix(target, "postgres://"), strings.HasPrefix(target, "postgresql://"):
+		db, err := sql.Open("pgx", target)
+		if err != nil {
+			return nil, err
+		}
+		s, err := NewPostgres(ctx, db, "audit_events")
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		return &ownedDB{PostgresSink: s}, nil
+	default:
+		return OpenFile(target)
+	}
+}
+
+// ownedDB closes the database Open created along with its sink.
+type ownedDB struct {
+	*PostgresSink
+}
+
+func (s *ownedDB) Close() error { return s.db.Close() }
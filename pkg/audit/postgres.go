@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// PostgresSink inserts events into a Postgres table.
+type PostgresSink struct {
+	db     *sql.DB
+	table  string
+	insert string
+}
+
+// NewPostgres returns a sink writing to table, creating the table if it
+// does not exist. db must use a Postgres driver; Close does not close it.
+func NewPostgres(ctx context.Context, db *sql.DB, table string) (*PostgresSink, error) {
+	if !identifier.MatchString(table) {
+		return nil, fmt.Errorf("audit: invalid table name %q", table)
+	}
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+table+` (
+		id             BIGSERIAL PRIMARY KEY,
+		time           TIMESTAMPTZ NOT NULL,
+		request_id     TEXT NOT NULL,
+		operation      TEXT NOT NULL,
+		policy         TEXT NOT NULL,
+		destination    TEXT NOT NULL,
+		model          TEXT NOT NULL,
+		entities       JSONB NOT NULL,
+		tokens         JSONB NOT NULL,
+		payload_sha256 TEXT NOT NULL,
+		outcome        TEXT NOT NULL,
+		tenant         TEXT NOT NULL DEFAULT ''
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("audit: create table: %w", err)
+	}
+	// Tables created by NewPostgres before events carried a tenant lack
+	// the column: add it, empty for the rows they already hold
+	if _, err := db.ExecContext(ctx, `ALTER TABLE `+table+` ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT ''`); err != nil {
+		return nil, fmt.Errorf("audit: add tenant column: %w", err)
+	}
+	return &PostgresSink{
+		db:    db,
+		table: table,
+		insert: `INSERT INTO ` + table + ` (time, request_id, operation, policy, destination, model, entities, tokens, payload_sha256, outcome, tenant)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+	}, nil
+}
+
+// Record inserts e.
+func (s *PostgresSink) Record(ctx context.Context, e Event) error {
+	entities, err := json.Marshal(e.Entities)
+	if err != nil {
+		return err
+	}
+	tokens, err := json.Marshal(e.Tokens)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, s.insert, e.Time, e.RequestID, e.Operation, e.Policy, e.Destination, e.Model,
+		string(entities), string(tokens), e.PayloadSHA256, e.Outcome, e.Tenant)
+	return err
+}
+
+// Close is a no-op; the caller owns db.
+func (s *PostgresSink) Close() error { return nil }
+
+// Ping checks that the database is reachable, for readiness checks.
+func (s *PostgresSink) Ping(ctx context.Context) error { return s.db.PingContext(ctx) }
+
+// Events returns the events recorded in [from, to), oldest first.
+func (s *PostgresSink) Events(ctx context.Context, from, to time.Time) ([]Event, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT time, request_id, operation, policy, destination, model, entities, tokens, payload_sha256, outcome, tenant
+		FROM `+s.table+` WHERE time >= $1 AND time < $2 ORDER BY time, id`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("audit: query: %w", err)
+	}
+	defer rows.Close()
+	var events []Event
+	for rows.Next() {
+		var e Event
+		var entities, tokens string
+		if err := rows.Scan(&e.Time, &e.RequestID, &e.Operation, &e.Policy, &e.Destination, &e.Model,
+			&entities, &tokens, &e.PayloadSHA256, &e.Outcome, &e.Tenant); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(entities), &e.Entities); err != nil {
+			return nil, fmt.Errorf("audit: request %s: entities: %w", e.RequestID, err)
+		}
+		if err := json.Unmarshal([]byte(tokens), &e.Tokens); err != nil {
+			return nil, fmt.Errorf("audit: request %s: tokens: %w", e.RequestID, err)
+		}
+		e.Time = e.Time.UTC()
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
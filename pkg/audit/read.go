@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReadJSONL reads JSON lines events from r and returns those in
+// [from, to), oldest first. Blank lines are skipped; any other line that
+// isn't an event is an error, since a log that can't be read whole
+// shouldn't be reported on.
+func ReadJSONL(r io.Reader, from, to time.Time) ([]Event, error) {
+	var events []Event
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("audit: line %d: %w", n, err)
+		}
+		if !e.Time.Before(from) && e.Time.Before(to) {
+			events = append(events, e)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, nil
+}
+
+// Events reads the events in [from, to) from the log Open would write to
+// for target: a JSON lines file, or the audit_events table of a
+// postgres:// URL.
+func Events(ctx context.Context, target string, from, to time.Time) ([]Event, error) {
+	switch {
+	case target == "" || target == "-":
+		return nil, fmt.Errorf("audit: %q is not a readable log", target)
+	case strings.HasPrefix(target, "postgres://"), strings.HasPrefix(target, "postgresql://"):
+		db, err := sql.Open("pgx", target)
+		if err != nil {
+			return nil, err
+		}
+		defer db.Close()
+		s, err := NewPostgres(ctx, db, "audit_events")
+		if err != nil {
+			return nil, err
+		}
+		return s.Events(ctx, from, to)
+	default:
+		f, err := os.Open(target)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return ReadJSONL(f, from, to)
+	}
+}
@@ -0,0 +1,159 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Window is a half-open time range [Start, End).
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Windows splits [from, to) into calendar windows of unit ("hour", "day",
+// "week" starting Monday, or "month"), in UTC. The first and last windows
+// are whole: they may start before from and end after to.
+func Windows(from, to time.Time, unit string) ([]Window, error) {
+	start, step, err := truncate(from.UTC(), unit)
+	if err != nil {
+		return nil, err
+	}
+	var out []Window
+	for s := start; s.Before(to); {
+		e := step(s)
+		out = append(out, Window{s, e})
+		s = e
+	}
+	return out, nil
+}
+
+func truncate(t time.Time, unit string) (time.Time, func(time.Time) time.Time, error) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch unit {
+	case "hour":
+		return t.Truncate(time.Hour), func(s time.Time) time.Time { return s.Add(time.Hour) }, nil
+	case "day":
+		return day, func(s time.Time) time.Time { return s.AddDate(0, 0, 1) }, nil
+	case "week":
+		offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+		return day.AddDate(0, 0, -offset), func(s time.Time) time.Time { return s.AddDate(0, 0, 7) }, nil
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), func(s time.Time) time.Time { return s.AddDate(0, 1, 0) }, nil
+	}
+	return time.Time{}, nil, fmt.Errorf("audit: unknown window %q (want hour, day, week or month)", unit)
+}
+
+// Report summarizes the audited requests of one window for auditors. Like
+// the events it is built from, it holds types, policies and destinations,
+// never values.
+type Report struct {
+	Window          Window         `json:"window"`
+	GeneratedAt     time.Time      `json:"generated_at"`
+	Requests        int            `json:"requests"`
+	RequestsWithPII int            `json:"requests_with_pii"`
+	Outcomes        map[string]int `json:"outcomes"`
+	Operations      map[string]int `json:"operations"`
+	Policies        map[string]int `json:"policies"`
+	Destinations    map[string]int `json:"destinations"`
+	Models          map[string]int `json:"models"`
+	// Entities counts detected entities by type over all requests.
+	Entities map[string]int `json:"entities"`
+	// EventsSHA256 is the SHA-256 over the window's events as JSON lines,
+	// oldest first: re-export the same events and compare to show the
+	// report was computed from them.
+	EventsSHA256 string `json:"events_sha256"`
+}
+
+// Summarize builds the report for the events that fall in w.
+func Summarize(events []Event, w Window, now time.Time) *Report {
+	r := &Report{
+		Window:       w,
+		GeneratedAt:  now.UTC(),
+		Outcomes:     make(map[string]int),
+		Operations:   make(map[string]int),
+		Policies:     make(map[string]int),
+		Destinations: make(map[string]int),
+		Models:       make(map[string]int),
+		Entities:     make(map[string]int),
+	}
+	h := sha256.New()
+	for _, e := range events {
+		if e.Time.Before(w.Start) || !e.Time.Before(w.End) {
+			continue
+		}
+		line, _ := json.Marshal(e) // Event always marshals
+		h.Write(append(line, '\n'))
+
+		r.Requests++
+		r.Outcomes[e.Outcome]++
+		r.Operations[e.Operation]++
+		r.Policies[orNone(e.Policy)]++
+		r.Destinations[orNone(e.Destination)]++
+		r.Models[orNone(e.Model)]++
+		pii := false
+		for typ, n := range e.Entities {
+			r.Entities[typ] += n
+			pii = pii || n > 0
+		}
+		if pii {
+			r.RequestsWithPII++
+		}
+	}
+	r.EventsSHA256 = hex.EncodeToString(h.Sum(nil))
+	return r
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// WriteCSV writes the report as window_start, window_end, dimension, key,
+// count rows.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	start, end := r.Window.Start.Format(time.RFC3339), r.Window.End.Format(time.RFC3339)
+	rows := [][]string{
+		{"window_start", "window_end", "dimension", "key", "count"},
+		{start, end, "requests", "total", strconv.Itoa(r.Requests)},
+		{start, end, "requests", "with_pii", strconv.Itoa(r.RequestsWithPII)},
+	}
+	for _, dim := range []struct {
+		name   string
+		counts map[string]int
+	}{
+		{"outcome", r.Outcomes},
+		{"operation", r.Operations},
+		{"policy", r.Policies},
+		{"destination", r.Destinations},
+		{"model", r.Models},
+		{"entity_type", r.Entities},
+	} {
+		keys := make([]string, 0, len(dim.counts))
+		for k := range dim.counts {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			rows = append(rows, []string{start, end, dim.name, k, strconv.Itoa(dim.counts[k])})
+		}
+	}
+	rows = append(rows,
+		[]string{start, end, "meta", "events_sha256", r.EventsSHA256},
+		[]string{start, end, "meta", "generated_at", r.GeneratedAt.Format(time.RFC3339)},
+	)
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Signature is a detached Ed25519 signature over an exported report and
+// the time it was signed. Auditors verify it with the public key alone.
+type Signature struct {
+	Algorithm string    `json:"algorithm"`
+	KeyID     string    `json:"key_id"`
+	SignedAt  time.Time `json:"signed_at"`
+	SHA256    string    `json:"sha256"` // of the report file
+	Signature string    `json:"signature"`
+}
+
+// KeyID names a public key by the first 16 hex digits of its SHA-256.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// signedMessage binds the report hash to the signing time, so neither can
+// be changed without breaking the signature.
+func signedMessage(sha string, at time.Time) []byte {
+	return []byte("blindfold-audit-report/v1\n" + at.UTC().Format(time.RFC3339) + "\n" + sha)
+}
+
+// Sign signs the exported report bytes with key at time at.
+func Sign(report []byte, key ed25519.PrivateKey, at time.Time) Signature {
+	sum := sha256.Sum256(report)
+	sha := hex.EncodeToString(sum[:])
+	at = at.UTC().Truncate(time.Second)
+	return Signature{
+		Algorithm: "ed25519",
+		KeyID:     KeyID(key.Public().(ed25519.PublicKey)),
+		SignedAt:  at,
+		SHA256:    sha,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, signedMessage(sha, at))),
+	}
+}
+
+// Verify checks that sig signs report under pub.
+func Verify(report []byte, sig Signature, pub ed25519.PublicKey) error {
+	if sig.Algorithm != "ed25519" {
+		return fmt.Errorf("audit: unsupported algorithm %q", sig.Algorithm)
+	}
+	if id := KeyID(pub); sig.KeyID != id {
+		return fmt.Errorf("audit: signed with key %s, not %s", sig.KeyID, id)
+	}
+	sum := sha256.Sum256(report)
+	if sha := hex.EncodeToString(sum[:]); sha != sig.SHA256 {
+		return errors.New("audit: report does not match the signed hash")
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return fmt.Errorf("audit: signature: %w", err)
+	}
+	if !ed25519.Verify(pub, signedMessage(sig.SHA256, sig.SignedAt), raw) {
+		return errors.New("audit: invalid signature")
+	}
+	return nil
+}
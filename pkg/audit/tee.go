@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"context"
+	"errors"
+)
+
+// Tee returns a sink that records every event to each of sinks in turn,
+// skipping nil ones. Record stops at the first failure and returns it, so
+// a sink that must not miss an event, such as the audit log itself, goes
+// first. It returns nil when every sink is nil.
+func Tee(sinks ...Sink) Sink {
+	var t tee
+	for _, s := range sinks {
+		if s != nil {
+			t = append(t, s)
+		}
+	}
+	switch len(t) {
+	case 0:
+		return nil
+	case 1:
+		return t[0]
+	}
+	return t
+}
+
+type tee []Sink
+
+func (t tee) Record(ctx context.Context, e Event) error {
+	for _, s := range t {
+		if err := s.Record(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes every sink and returns their errors joined.
+func (t tee) Close() error {
+	var errs []error
+	for _, s := range t {
+		errs = append(errs, s.Close())
+	}
+	return errors.Join(errs...)
+}
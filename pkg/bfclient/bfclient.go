@@ -0,0 +1,48 @@
+// Package bfclient defines the Blindfold and LLM client surfaces shared
+// by the cookbook packages, and the constructor the examples use to build
+// a Blindfold client.
+//
+// Wrappers such as resilience.Client accept and return a Client, so they can
+// be stacked on a real *blindfold.Client or on each other.
+package bfclient
+
+import (
+	"context"
+	"os"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Client is the subset of *blindfold.Client the cookbook relies on.
+type Client interface {
+	Detect(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.DetectResponse, error)
+	Tokenize(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, error)
+	Detokenize(text string, mapping map[string]string) *blindfold.DetokenizeResponse
+}
+
+var _ Client = (*blindfold.Client)(nil)
+
+// ChatCompleter is the part of *openai.Client used for chat completions.
+// The cookbook's chat wrappers accept and return one, so they stack the
+// same way Client wrappers do.
+type ChatCompleter interface {
+	CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+}
+
+var _ ChatCompleter = (*openai.Client)(nil)
+
+// FromEnv builds a client from BLINDFOLD_API_KEY. The key is optional —
+// without it the client runs in local mode (regex-based, offline).
+// BLINDFOLD_BASE_URL, if set, overrides the API endpoint (for example to
+// point at testing/mockserver in CI). Extra options are applied last.
+func FromEnv(opts ...blindfold.Option) *blindfold.Client {
+	var all []blindfold.Option
+	if key := os.Getenv("BLINDFOLD_API_KEY"); key != "" {
+		all = append(all, blindfold.WithAPIKey(key))
+	}
+	if url := os.Getenv("BLINDFOLD_BASE_URL"); url != "" {
+		all = append(all, blindfold.WithBaseURL(url))
+	}
+	return blindfold.New(append(all, opts...)...)
+}
@@ -0,0 +1,256 @@
+// Package bferrors sorts the errors a protected call can fail with, from
+// the Blindfold SDK, go-openai, the network and the cookbook packages,
+// into the few kinds a caller handles differently:
+//
+//	switch bferrors.KindOf(err) {
+//	case bferrors.Auth:      // fix the key: every call will fail the same way
+//	case bferrors.Quota:     // back off, or stop if the quota is used up
+//	case bferrors.Transient: // retry
+//	}
+//
+// Wrap attaches the operation that failed and keeps the cause, so
+// errors.As still finds the SDK's error types underneath. Packages that
+// define their own sentinel errors make them *Error values with a Kind,
+// so they classify without this package knowing them.
+package bferrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Kind is a category of failure.
+type Kind int
+
+const (
+	// Unknown is anything not recognized, including a provider rejecting
+	// a request as invalid. Not retryable.
+	Unknown Kind = iota
+	// Auth is a missing, invalid or unauthorized API key. Not retryable:
+	// every call fails the same way until the key is fixed.
+	Auth
+	// Quota is a rate limit, retryable after backing off, or a used-up
+	// quota or plan, which is not.
+	Quota
+	// Transient is a server error, a network failure or an unavailable
+	// dependency. Retryable.
+	Transient
+	// Detection is Blindfold refusing to process the text, such as text
+	// that is too long. Nothing was protected, so nothing may be sent.
+	// Not retryable as is.
+	Detection
+	// MappingMissing is a token with no value to restore it to: a mapping
+	// that was lost, expired, or never had it, or a placeholder the model
+	// mangled. Not retryable as is.
+	MappingMissing
+	// Canceled is the caller giving up or running out of time. Not
+	// retryable by the callee.
+	Canceled
+)
+
+var kindNames = [...]string{"unknown", "auth", "quota", "transient", "detection", "mapping_missing", "canceled"}
+
+func (k Kind) String() string {
+	if k >= 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// ErrMappingMissing is the generic MappingMissing error, for code that
+// has no more specific one.
+var ErrMappingMissing error = &Error{Kind: MappingMissing, Err: errors.New("mapping missing")}
+
+// Error is a classified error.
+type Error struct {
+	Kind Kind
+	// Op is the operation that failed, such as "tokenize" or "openai".
+	// Empty for a package sentinel.
+	Op  string
+	Err error
+	// exhausted marks a Quota error that retrying won't fix.
+	exhausted bool
+}
+
+func (e *Error) Error() string {
+	if e.Op == "" {
+		return e.Err.Error()
+	}
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// IsRetryable reports whether the same call may succeed if tried again
+// later.
+func (e *Error) IsRetryable() bool {
+	switch e.Kind {
+	case Transient:
+		return true
+	case Quota:
+		return !e.exhausted
+	}
+	return false
+}
+
+// Wrap classifies err and attaches op. It returns nil for a nil err, and
+// keeps an existing classification.
+func Wrap(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	e := classify(err)
+	return &Error{Kind: e.Kind, Op: op, Err: err, exhausted: e.exhausted}
+}
+
+// KindOf classifies err; see Kind for what goes where. A call that timed
+// out on the network is Transient, even if the timeout was the caller's
+// own deadline: use KindOfContext where the caller's context is at hand.
+func KindOf(err error) Kind {
+	if err == nil {
+		return Unknown
+	}
+	return classify(err).Kind
+}
+
+// KindOfContext is KindOf for a call made with ctx: Canceled if ctx is
+// done, since then the caller gave up whatever err says.
+func KindOfContext(ctx context.Context, err error) Kind {
+	if err != nil && ctx.Err() != nil {
+		return Canceled
+	}
+	return KindOf(err)
+}
+
+// IsRetryable reports whether the call that failed with err may succeed
+// if tried again later.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	return classify(err).IsRetryable()
+}
+
+// IsFatal reports whether err will fail every other call the same way: a
+// rejected key or a used-up quota. A batch should stop on it rather than
+// work through the rest of its items.
+func IsFatal(err error) bool {
+	if err == nil {
+		return false
+	}
+	e := classify(err)
+	return e.Kind == Auth || e.Kind == Quota && e.exhausted
+}
+
+// classify returns the classified error nearest the top of err's chain,
+// or a new one.
+func classify(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	// Only a caller cancels; an HTTP client that times out reports
+	// DeadlineExceeded, so Canceled comes first and DeadlineExceeded
+	// after the network errors it may be wrapped in.
+	if errors.Is(err, context.Canceled) {
+		return &Error{Kind: Canceled}
+	}
+	var bfNet *blindfold.NetworkError
+	var netErr net.Error
+	// context.DeadlineExceeded is itself a net.Error
+	if errors.As(err, &bfNet) || errors.As(err, &netErr) && netErr != context.DeadlineExceeded {
+		return &Error{Kind: Transient}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &Error{Kind: Canceled}
+	}
+
+	var bfAuth *blindfold.AuthenticationError
+	if errors.As(err, &bfAuth) {
+		return &Error{Kind: Auth}
+	}
+	var bfAPI *blindfold.APIError
+	if errors.As(err, &bfAPI) {
+		if k := byStatus(bfAPI.StatusCode); k.Kind != Unknown {
+			return k
+		}
+		return &Error{Kind: Detection}
+	}
+	var oaAPI *openai.APIError
+	if errors.As(err, &oaAPI) {
+		k := byStatus(oaAPI.HTTPStatusCode)
+		// OpenAI answers 429 both for rate limits and for a used-up quota
+		if code, _ := oaAPI.Code.(string); code == "insufficient_quota" {
+			k.exhausted = true
+		}
+		return k
+	}
+	var oaReq *openai.RequestError
+	if errors.As(err, &oaReq) {
+		return byStatus(oaReq.HTTPStatusCode)
+	}
+	return &Error{Kind: Unknown}
+}
+
+func byStatus(code int) *Error {
+	switch {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return &Error{Kind: Auth}
+	case code == http.StatusTooManyRequests:
+		return &Error{Kind: Quota}
+	case code == http.StatusPaymentRequired:
+		return &Error{Kind: Quota, exhausted: true}
+	case code >= 500:
+		return &Error{Kind: Transient}
+	}
+	return &Error{Kind: Unknown}
+}
+
+// HTTPStatus is the status a service answers with when a dependency
+// fails with err: 503 for what may succeed later, 429 to pass on a rate
+// limit, 422 for text that can't be protected or restored, and 502 for
+// the rest, including the service's own credentials being rejected,
+// which is not the caller's fault.
+func HTTPStatus(err error) int {
+	e := classify(err)
+	switch {
+	case e.Kind == Quota && e.IsRetryable():
+		return http.StatusTooManyRequests
+	case e.IsRetryable():
+		return http.StatusServiceUnavailable
+	case e.Kind == Detection || e.Kind == MappingMissing:
+		return http.StatusUnprocessableEntity
+	case e.Kind == Canceled:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
+
+// StatusCode is the HTTP status the dependency answered err with, or 0
+// if it didn't answer, such as on a network error or a cancellation.
+// Unlike the errors' messages, it never echoes the request text.
+func StatusCode(err error) int {
+	var bfAuth *blindfold.AuthenticationError
+	if errors.As(err, &bfAuth) {
+		return bfAuth.StatusCode
+	}
+	var bfAPI *blindfold.APIError
+	if errors.As(err, &bfAPI) {
+		return bfAPI.StatusCode
+	}
+	var oaAPI *openai.APIError
+	if errors.As(err, &oaAPI) {
+		return oaAPI.HTTPStatusCode
+	}
+	var oaReq *openai.RequestError
+	if errors.As(err, &oaReq) {
+		return oaReq.HTTPStatusCode
+	}
+	return 0
+}
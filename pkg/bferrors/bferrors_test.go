@@ -0,0 +1,146 @@
+package bferrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func bfAPI(status int) error {
+	return &blindfold.APIError{BlindfoldError: blindfold.BlindfoldError{Message: "api", StatusCode: status}}
+}
+
+func oaAPI(status int, code any) error {
+	return &openai.APIError{HTTPStatusCode: status, Code: code, Message: "api"}
+}
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		err       error
+		kind      Kind
+		retryable bool
+		status    int
+	}{
+		{"bad key", &blindfold.AuthenticationError{BlindfoldError: blindfold.BlindfoldError{Message: "invalid key", StatusCode: 401}}, Auth, false, 502},
+		{"rate limited", bfAPI(429), Quota, true, 429},
+		{"plan used up", bfAPI(402), Quota, false, 502},
+		{"server error", bfAPI(503), Transient, true, 503},
+		{"text too long", bfAPI(413), Detection, false, 422},
+		{"network", &blindfold.NetworkError{BlindfoldError: blindfold.BlindfoldError{Message: "connection refused"}}, Transient, true, 503},
+		{"openai rate limit", oaAPI(429, "rate_limit_exceeded"), Quota, true, 429},
+		{"openai quota", oaAPI(429, "insufficient_quota"), Quota, false, 502},
+		{"openai key", oaAPI(401, "invalid_api_key"), Auth, false, 502},
+		{"openai bad request", oaAPI(400, nil), Unknown, false, 502},
+		{"dial", &net.OpError{Op: "dial", Err: errors.New("refused")}, Transient, true, 503},
+		{"deadline", fmt.Errorf("tokenize: %w", context.DeadlineExceeded), Canceled, false, 504},
+		{"mapping", fmt.Errorf("session 42: %w", ErrMappingMissing), MappingMissing, false, 422},
+		{"other", errors.New("boom"), Unknown, false, 502},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if k := KindOf(tc.err); k != tc.kind {
+				t.Errorf("kind = %s, want %s", k, tc.kind)
+			}
+			if r := IsRetryable(tc.err); r != tc.retryable {
+				t.Errorf("retryable = %v", r)
+			}
+			if f := IsFatal(tc.err); f != (tc.kind == Auth || tc.kind == Quota && !tc.retryable) {
+				t.Errorf("fatal = %v", f)
+			}
+			if s := HTTPStatus(tc.err); s != tc.status {
+				t.Errorf("status = %d, want %d", s, tc.status)
+			}
+		})
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{Wrap("tokenize", bfAPI(413)), 413},
+		{&blindfold.AuthenticationError{BlindfoldError: blindfold.BlindfoldError{StatusCode: 401}}, 401},
+		{fmt.Errorf("chat: %w", oaAPI(429, nil)), 429},
+		{&openai.RequestError{HTTPStatusCode: 502, Err: errors.New("bad gateway")}, 502},
+		{&blindfold.NetworkError{BlindfoldError: blindfold.BlindfoldError{Message: "connection refused"}}, 0},
+		{context.Canceled, 0},
+	} {
+		if got := StatusCode(tc.err); got != tc.want {
+			t.Errorf("StatusCode(%v) = %d, want %d", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	if Wrap("tokenize", nil) != nil {
+		t.Error("Wrap(nil) != nil")
+	}
+	cause := bfAPI(503)
+	err := fmt.Errorf("row 3: %w", Wrap("tokenize", cause))
+	if err.Error() != "row 3: tokenize: api" {
+		t.Errorf("message = %q", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Kind != Transient || e.Op != "tokenize" || !e.IsRetryable() {
+		t.Errorf("error = %+v", e)
+	}
+	var api *blindfold.APIError
+	if !errors.As(err, &api) {
+		t.Error("cause lost")
+	}
+	// An outer Wrap keeps the inner classification
+	rewrapped := Wrap("batch", Wrap("openai", oaAPI(429, "insufficient_quota")))
+	if KindOf(rewrapped) != Quota || IsRetryable(rewrapped) || !IsFatal(rewrapped) {
+		t.Errorf("rewrapped: %s, retryable %v", KindOf(rewrapped), IsRetryable(rewrapped))
+	}
+}
+
+func TestClassifyHTTPTimeout(t *testing.T) {
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { <-release }))
+	defer hung.Close()
+	defer close(release)
+	bf := blindfold.New(blindfold.WithAPIKey("test"), blindfold.WithBaseURL(hung.URL), blindfold.WithMaxRetries(0),
+		blindfold.WithHTTPClient(&http.Client{Timeout: 50 * time.Millisecond}))
+
+	// The client timed out, not the caller: retry
+	_, err := bf.Tokenize(context.Background(), "text")
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+	if k := KindOf(err); k != Transient || !IsRetryable(err) {
+		t.Errorf("client timeout: kind = %s, retryable %v", k, IsRetryable(err))
+	}
+	if s := HTTPStatus(err); s != http.StatusServiceUnavailable {
+		t.Errorf("client timeout: status = %d", s)
+	}
+	if k := KindOfContext(context.Background(), err); k != Transient {
+		t.Errorf("client timeout in context: kind = %s", k)
+	}
+
+	// The caller's deadline ran out: only the context can tell
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	bf = blindfold.New(blindfold.WithAPIKey("test"), blindfold.WithBaseURL(hung.URL), blindfold.WithMaxRetries(0))
+	_, err = bf.Tokenize(ctx, "text")
+	if k := KindOfContext(ctx, err); k != Canceled {
+		t.Errorf("caller deadline: kind = %s, want canceled", k)
+	}
+
+	// A caller canceling is Canceled however deep it is wrapped
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = bf.Tokenize(ctx, "text")
+	if k := KindOf(err); k != Canceled {
+		t.Errorf("caller canceled: kind = %s, want canceled (%v)", k, err)
+	}
+}
@@ -0,0 +1,136 @@
+// Package cache skips redundant detection calls for inputs the client has
+// already seen. Batch jobs and retries routinely tokenize the same text
+// many times; a Client answers repeats from a Store keyed by a SHA-256
+// hash of the content.
+//
+// Cached tokenize results contain the mapping, i.e. the original PII
+// values. Use a Store you would trust with the mapping itself: in-process
+// (LRU) or a private Redis with a TTL.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync/atomic"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+)
+
+// Store holds cached responses by key.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte) error
+}
+
+// Rewriter is a Store that can list its keys and replace an entry only if
+// it is unchanged, for jobs that rewrite entries in place while the Store
+// is in use, such as key rotation.
+type Rewriter interface {
+	Store
+	// Keys returns the keys starting with prefix.
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	// Swap sets key to new if it still holds old, and reports whether it
+	// did. An entry's expiry, if any, is kept.
+	Swap(ctx context.Context, key string, old, new []byte) (bool, error)
+}
+
+// Stats is a snapshot of cache counters.
+type Stats struct {
+	Hits   int64
+	Misses int64
+	Errors int64 // store failures; the call went to the backend instead
+}
+
+// HitRate returns hits / (hits + misses), or 0 before any lookups.
+func (s Stats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Client caches Detect and Tokenize results of the wrapped client.
+//
+// Call options are functions and cannot be hashed, so the key is built from
+// the namespace and the text only. Use one namespace per policy or option
+// set — two calls with the same text and namespace must be interchangeable.
+type Client struct {
+	next      bfclient.Client
+	store     Store
+	namespace string
+
+	hits, misses, errors atomic.Int64
+}
+
+var _ bfclient.Client = (*Client)(nil)
+
+// Wrap returns next with results cached in store under namespace.
+func Wrap(next bfclient.Client, store Store, namespace string) *Client {
+	return &Client{next: next, store: store, namespace: namespace}
+}
+
+// Stats returns the current hit/miss counters.
+func (c *Client) Stats() Stats {
+	return Stats{Hits: c.hits.Load(), Misses: c.misses.Load(), Errors: c.errors.Load()}
+}
+
+// Key returns the store key for an operation on text.
+func (c *Client) Key(op, text string) string {
+	h := sha256.New()
+	h.Write([]byte(c.namespace))
+	h.Write([]byte{0})
+	h.Write([]byte(text))
+	return "blindfold:" + op + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Detect returns a cached result or calls the wrapped client.
+func (c *Client) Detect(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.DetectResponse, error) {
+	return cached(c, ctx, c.Key("detect", text), func() (*blindfold.DetectResponse, error) {
+		return c.next.Detect(ctx, text, opts...)
+	})
+}
+
+// Tokenize returns a cached result or calls the wrapped client.
+func (c *Client) Tokenize(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	return cached(c, ctx, c.Key("tokenize", text), func() (*blindfold.TokenizeResponse, error) {
+		return c.next.Tokenize(ctx, text, opts...)
+	})
+}
+
+// Detokenize is local and not cached.
+func (c *Client) Detokenize(text string, mapping map[string]string) *blindfold.DetokenizeResponse {
+	return c.next.Detokenize(text, mapping)
+}
+
+// cached decodes a stored copy on every hit, so callers may modify the
+// result without touching the cache.
+func cached[T any](c *Client, ctx context.Context, key string, call func() (*T, error)) (*T, error) {
+	data, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		c.errors.Add(1)
+	}
+	if ok {
+		var v T
+		if err := json.Unmarshal(data, &v); err == nil {
+			c.hits.Add(1)
+			return &v, nil
+		}
+		c.errors.Add(1)
+	}
+
+	c.misses.Add(1)
+	v, err := call()
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(v); err == nil {
+		if err := c.store.Set(ctx, key, data); err != nil {
+			c.errors.Add(1)
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fake"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	l := NewLRU(2)
+	_ = l.Set(ctx, "a", []byte("1"))
+	_ = l.Set(ctx, "b", []byte("2"))
+	// Reading a makes b the oldest
+	if v, ok, _ := l.Get(ctx, "a"); !ok || string(v) != "1" {
+		t.Fatalf("Get(a) = %q, %v", v, ok)
+	}
+	_ = l.Set(ctx, "c", []byte("3"))
+	if _, ok, _ := l.Get(ctx, "b"); ok {
+		t.Error("b survived eviction")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok, _ := l.Get(ctx, k); !ok {
+			t.Errorf("%s evicted", k)
+		}
+	}
+	// Overwriting doesn't grow the cache
+	_ = l.Set(ctx, "c", []byte("4"))
+	if v, _, _ := l.Get(ctx, "c"); l.Len() != 2 || string(v) != "4" {
+		t.Errorf("after overwrite: len %d, c = %q", l.Len(), v)
+	}
+}
+
+func TestLRUSwap(t *testing.T) {
+	ctx := context.Background()
+	l := NewLRU(10)
+	_ = l.Set(ctx, "blindfold:tokenize:1", []byte("old"))
+	_ = l.Set(ctx, "other", []byte("x"))
+	if ok, _ := l.Swap(ctx, "blindfold:tokenize:1", []byte("stale"), []byte("new")); ok {
+		t.Error("swapped a changed entry")
+	}
+	if ok, _ := l.Swap(ctx, "blindfold:tokenize:1", []byte("old"), []byte("new")); !ok {
+		t.Error("swap refused")
+	}
+	if v, _, _ := l.Get(ctx, "blindfold:tokenize:1"); string(v) != "new" {
+		t.Errorf("after swap: %q", v)
+	}
+	if keys, _ := l.Keys(ctx, "blindfold:"); len(keys) != 1 {
+		t.Errorf("Keys = %v", keys)
+	}
+}
+
+func TestNamespacesAreIsolated(t *testing.T) {
+	ctx := context.Background()
+	store := NewLRU(10)
+	next := fake.New(fake.WithPatterns("us"))
+	basic, strict := Wrap(next, store, "basic"), Wrap(next, store, "strict")
+	text := "Mail jane@example.com"
+
+	for _, c := range []*Client{basic, strict, basic, strict} {
+		if _, err := c.Tokenize(ctx, text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(next.Calls()); n != 2 {
+		t.Errorf("%d backend calls, want one per namespace", n)
+	}
+	if basic.Key("tokenize", text) == strict.Key("tokenize", text) {
+		t.Error("namespaces share a key")
+	}
+	if basic.Key("tokenize", text) == basic.Key("detect", text) {
+		t.Error("operations share a key")
+	}
+	if s := basic.Stats(); s.Hits != 1 || s.Misses != 1 || s.HitRate() != 0.5 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestCachedResultsAreCopies(t *testing.T) {
+	ctx := context.Background()
+	c := Wrap(fake.New(fake.WithPatterns("us")), NewLRU(10), "basic")
+	text := "Mail jane@example.com"
+
+	for i := 0; i < 3; i++ {
+		res, err := c.Tokenize(ctx, text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Text != "Mail <Email Address_1>" || res.Mapping["<Email Address_1>"] != "jane@example.com" || len(res.Mapping) != 1 {
+			t.Fatalf("call %d: %+v", i, res)
+		}
+		// A caller merging into the mapping or rewriting the text must not
+		// change what the next caller gets, from a miss or a hit
+		res.Text = "changed"
+		res.Mapping["<Email Address_1>"] = "changed"
+		res.Mapping[fmt.Sprintf("<Person_%d>", i+1)] = "Jane"
+		res.DetectedEntities[0].Text = "changed"
+	}
+}
+
+func TestEscapeGlob(t *testing.T) {
+	if got, want := escapeGlob(`ns[1]*?\:`), `ns\[1\]\*\?\\:`; got != want {
+		t.Errorf("escapeGlob = %q, want %q", got, want)
+	}
+}
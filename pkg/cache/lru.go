@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+)
+
+// LRU is an in-memory Store that evicts the least recently used entry once
+// it holds more than its capacity.
+type LRU struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List               // front = most recently used
+	entries  map[string]*list.Element // key → element holding *lruEntry
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+var _ Rewriter = (*LRU)(nil)
+
+// NewLRU returns an LRU holding at most capacity entries.
+func NewLRU(capacity int) *LRU {
+	return &LRU{capacity: capacity, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Get returns the value for key and marks it as recently used.
+func (l *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	l.order.MoveToFront(el)
+	return el.Value.(*lruEntry).value, true, nil
+}
+
+// Set stores value under key, evicting the oldest entry if full.
+func (l *LRU) Set(_ context.Context, key string, value []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.entries[key]; ok {
+		el.Value.(*lruEntry).value = value
+		l.order.MoveToFront(el)
+		return nil
+	}
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, value: value})
+	if l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+// Keys returns the keys starting with prefix, without marking them used.
+func (l *LRU) Keys(_ context.Context, prefix string) ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var keys []string
+	for k := range l.entries {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+// Swap sets key to new if it holds old.
+func (l *LRU) Swap(_ context.Context, key string, old, new []byte) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.entries[key]
+	if !ok || !bytes.Equal(el.Value.(*lruEntry).value, old) {
+		return false, nil
+	}
+	el.Value.(*lruEntry).value = new
+	return true, nil
+}
+
+// Len returns the number of cached entries.
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
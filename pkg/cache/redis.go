@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a Store backed by a Redis server, shared by every process that
+// points at it. Entries expire after the TTL.
+type Redis struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+var _ Rewriter = (*Redis)(nil)
+
+// NewRedis returns a Store using client with the given entry TTL.
+func NewRedis(client *redis.Client, ttl time.Duration) *Redis {
+	return &Redis{client: client, ttl: ttl}
+}
+
+// Get returns the value for key.
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Set stores value under key with the TTL.
+func (r *Redis) Set(ctx context.Context, key string, value []byte) error {
+	return r.client.Set(ctx, key, value, r.ttl).Err()
+}
+
+// Keys returns the keys starting with prefix, with SCAN, so a large
+// keyspace doesn't block the server the way KEYS would. Keys written
+// while it runs may be missed.
+func (r *Redis) Keys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	iter := r.client.Scan(ctx, 0, escapeGlob(prefix)+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// swapScript compares and sets in one step on the server. KEEPTTL needs
+// Redis 6.
+var swapScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[2], "KEEPTTL")
+	return 1
+end
+return 0`)
+
+// Swap sets key to new if it holds old, keeping its TTL.
+func (r *Redis) Swap(ctx context.Context, key string, old, new []byte) (bool, error) {
+	n, err := swapScript.Run(ctx, r.client, []string{key}, old, new).Int()
+	return n == 1, err
+}
+
+// escapeGlob escapes the characters SCAN's MATCH treats as patterns.
+func escapeGlob(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`).Replace(s)
+}
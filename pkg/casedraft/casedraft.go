@@ -0,0 +1,128 @@
+// Package casedraft is the protect-call-restore core of the CRM recipes:
+// it turns a support case (a Salesforce Case, a HubSpot ticket) into a
+// tokenized prompt, asks the model for a reply, checks the reply, and
+// restores it into a draft an agent can send.
+//
+//	d := casedraft.New(bf, llm, openai.GPT4oMini)
+//	draft, err := d.Draft(ctx, casedraft.Case{Number: "00001026", Name: "Jane Doe", Email: "jane@example.com", Subject: s, Body: b})
+//	// draft.Prompt.Text is what the model saw; draft.Comment() goes back to the CRM
+//
+// The contact's name, email and phone are PII by definition, so they are
+// tokenized as their type without running detection; the subject and
+// body are tokenized by detection. All fields share one mapping, so the
+// contact's email in the body gets the same token as the email field.
+// Only the CRM client differs between recipes.
+package casedraft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/prompttmpl"
+)
+
+// DefaultSystem is the system prompt a Drafter starts with.
+const DefaultSystem = "You are a customer support agent. Draft a reply to the customer's case in three or four " +
+	"sentences, addressed to them by name, without promising refunds or dates you can't know. Values like " +
+	"<Email Address_1> are placeholders for customer data: copy them exactly as written."
+
+// Marker starts every draft comment, so a case that already has a draft
+// can be recognized and skipped.
+const Marker = "[Draft reply: review before sending]"
+
+// ErrUnresolved is returned, wrapped, when the reply has tokens the case
+// never had. Restoring it would leave placeholders in front of the customer.
+var ErrUnresolved error = &bferrors.Error{Kind: bferrors.MappingMissing, Err: errors.New("casedraft: reply has tokens the case never had")}
+
+// Case is a support case and its contact. Any field may be empty.
+type Case struct {
+	Number  string // the CRM's case or ticket number; not tokenized
+	Name    string
+	Email   string
+	Phone   string
+	Subject string
+	Body    string
+}
+
+// promptText renders a case for the model. Empty contact fields are left
+// out rather than shown blank.
+const promptText = `Case {{.Number}}
+{{- with .Name}}
+From: {{protectAs "Person" .}}{{end}}
+{{- with .Email}}
+Email: {{protectAs "Email Address" .}}{{end}}
+{{- with .Phone}}
+Phone: {{protectAs "Phone Number" .}}{{end}}
+Subject: {{.Subject | protect}}
+
+{{.Body | protect}}`
+
+// Drafter drafts replies to cases. Set System before the first Draft to
+// change the instructions.
+type Drafter struct {
+	System string
+	tmpl   *prompttmpl.Template
+	llm    bfclient.ChatCompleter
+	model  string
+}
+
+// New returns a Drafter that tokenizes with tk and asks llm, which may be
+// an *openai.Client or a resilience.ChatClient.
+func New(tk prompttmpl.Tokenizer, llm bfclient.ChatCompleter, model string) *Drafter {
+	return &Drafter{System: DefaultSystem, tmpl: prompttmpl.Must(prompttmpl.New("case", tk).Parse(promptText)), llm: llm, model: model}
+}
+
+// Draft is a reply to one case.
+type Draft struct {
+	// Prompt is the tokenized case the model saw, with its mapping.
+	Prompt *prompttmpl.Prompt
+	// Reply is the model's reply, tokenized.
+	Reply string
+	// Text is Reply with the values restored. It is empty if the reply
+	// was rejected.
+	Text string
+}
+
+// Comment is the draft as a CRM comment: Marker, a blank line, the text.
+func (d *Draft) Comment() string { return Marker + "\n\n" + d.Text }
+
+// Draft tokenizes c, asks the model for a reply and restores it. If the
+// reply has tokens the case never had, Draft returns the draft without
+// Text and an error wrapping ErrUnresolved. If a field can't be
+// tokenized, it fails before anything is sent.
+func (d *Drafter) Draft(ctx context.Context, c Case) (*Draft, error) {
+	p, err := d.tmpl.Execute(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	res, err := d.llm.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: d.model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: d.System},
+			{Role: openai.ChatMessageRoleUser, Content: p.Text},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Choices) == 0 {
+		return nil, fmt.Errorf("casedraft: empty response")
+	}
+	out := &Draft{Prompt: p, Reply: strings.TrimSpace(res.Choices[0].Message.Content)}
+	if bad := mapping.Unresolved(out.Reply, p.Mapping); len(bad) > 0 {
+		return out, fmt.Errorf("%w: %s", ErrUnresolved, strings.Join(bad, ", "))
+	}
+	out.Text = p.Restore(out.Reply)
+	return out, nil
+}
+
+// IsDraft reports whether a comment body is a draft. It looks for Marker
+// anywhere, since some CRMs wrap comment bodies in HTML.
+func IsDraft(body string) bool { return strings.Contains(body, Marker) }
@@ -0,0 +1,75 @@
+package casedraft
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+var local = blindfold.New(blindfold.WithMode("local"))
+
+// echo replies with a fixed text and keeps the prompt it was sent.
+type echo struct {
+	reply  string
+	prompt string
+}
+
+func (e *echo) CreateChatCompletion(_ context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	e.prompt = req.Messages[1].Content
+	return openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: e.reply}}}}, nil
+}
+
+var jane = Case{
+	Number:  "00001026",
+	Name:    "Jane Doe",
+	Email:   "jane.doe@example.com",
+	Subject: "Charged twice",
+	Body:    "Card 4111 1111 1111 1111 was charged twice. Confirm to jane.doe@example.com or bob@example.org.",
+}
+
+func TestDraft(t *testing.T) {
+	llm := &echo{reply: " Hi <Person_1>, we'll confirm to <Email Address_1> once <Credit Card Number_1> is refunded.\n"}
+	d, err := New(local, llm, "m").Draft(context.Background(), jane)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Case 00001026
+From: <Person_1>
+Email: <Email Address_1>
+Subject: Charged twice
+
+Card <Credit Card Number_1> was charged twice. Confirm to <Email Address_1> or <Email Address_2>.`
+	if llm.prompt != want || d.Prompt.Text != want {
+		t.Errorf("prompt =\n%s\nwant\n%s", llm.prompt, want)
+	}
+	if got := d.Text; got != "Hi Jane Doe, we'll confirm to jane.doe@example.com once 4111 1111 1111 1111 is refunded." {
+		t.Errorf("text = %q", got)
+	}
+	if c := d.Comment(); !strings.HasPrefix(c, Marker+"\n\n") || !IsDraft("<p>"+c+"</p>") {
+		t.Errorf("comment = %q", c)
+	}
+}
+
+func TestDraftUnresolved(t *testing.T) {
+	d, err := New(local, &echo{reply: "Hi <Person_2>."}, "m").Draft(context.Background(), jane)
+	if !errors.Is(err, ErrUnresolved) || !strings.Contains(err.Error(), "<Person_2>") {
+		t.Fatalf("err = %v", err)
+	}
+	if d == nil || d.Reply != "Hi <Person_2>." || d.Text != "" {
+		t.Errorf("draft = %+v", d)
+	}
+}
+
+func TestDraftNoContact(t *testing.T) {
+	llm := &echo{reply: "Done."}
+	if _, err := New(local, llm, "m").Draft(context.Background(), Case{Number: "7", Subject: "Cancel", Body: "Please cancel."}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Case 7\nSubject: Cancel\n\nPlease cancel."; llm.prompt != want {
+		t.Errorf("prompt = %q, want %q", llm.prompt, want)
+	}
+}
@@ -0,0 +1,203 @@
+// Package chunk detects and tokenizes documents too large for one call by
+// splitting them into overlapping windows.
+//
+// Cutting a document at fixed offsets splits whatever entity sits on the
+// cut: "jane.doe@exa" in one chunk and "mple.com" in the next, neither of
+// them detected. Windows that overlap by more than the longest entity
+// expected see every entity whole at least once. The price is that
+// entities in an overlap are detected twice, and a window's edge may still
+// yield a truncated match; Detect settles both, so each entity is reported
+// once, with offsets into the whole document.
+//
+//	c := chunk.Chunker{Size: 32 << 10, Overlap: 512}
+//	res, err := c.Tokenize(ctx, bf, document)
+package chunk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// Chunker splits documents into overlapping windows.
+type Chunker struct {
+	// Size is the window size in bytes.
+	Size int
+	// Overlap is the number of bytes neighbouring windows share. An entity
+	// longer than Overlap can be missed if it straddles a cut.
+	Overlap int
+	// Concurrency caps Detect calls in flight. Defaults to 4.
+	Concurrency int
+}
+
+// Window is a byte range [Start, End) of a document.
+type Window struct{ Start, End int }
+
+func (c Chunker) validate() error {
+	if c.Size <= 0 || c.Overlap < 0 || c.Overlap >= c.Size/2 {
+		return fmt.Errorf("chunk: need Size > 0 and 0 <= Overlap < Size/2, got Size %d, Overlap %d", c.Size, c.Overlap)
+	}
+	return nil
+}
+
+// Windows returns the windows covering text. Cuts fall on rune
+// boundaries, so a window may be a few bytes shorter than Size. If c is
+// invalid, the whole text is one window.
+func (c Chunker) Windows(text string) []Window {
+	if c.validate() != nil {
+		return []Window{{0, len(text)}}
+	}
+	var out []Window
+	for start := 0; ; {
+		end := start + c.Size
+		if end >= len(text) {
+			return append(out, Window{start, len(text)})
+		}
+		for end > start && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		out = append(out, Window{start, end})
+		start = end - c.Overlap
+		for start < end && !utf8.RuneStart(text[start]) {
+			start++
+		}
+	}
+}
+
+// candidate is an entity found in one window, in document offsets.
+type candidate struct {
+	blindfold.DetectedEntity
+	edge bool // touches a cut, so it may be truncated
+}
+
+// Detect detects entities window by window and returns them in document
+// order with offsets into text. Where windows disagree, a match touching a
+// cut gives way to any overlapping match that doesn't, and then the
+// earlier, longer, higher-scoring match wins.
+func (c Chunker) Detect(ctx context.Context, bf bfclient.Client, text string, opts ...blindfold.CallOption) ([]blindfold.DetectedEntity, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	windows := c.Windows(text)
+	found := make([][]candidate, len(windows))
+	errs := make([]error, len(windows))
+
+	n := c.Concurrency
+	if n <= 0 {
+		n = 4
+	}
+	sem := make(chan struct{}, n)
+	var wg sync.WaitGroup
+	for i, w := range windows {
+		wg.Add(1)
+		go func(i int, w Window) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			res, err := bf.Detect(ctx, text[w.Start:w.End], opts...)
+			if err != nil {
+				errs[i] = fmt.Errorf("window %d (bytes %d-%d): %w", i, w.Start, w.End, err)
+				return
+			}
+			for _, e := range res.DetectedEntities {
+				cut := (e.Start == 0 && w.Start > 0) || (e.End == w.End-w.Start && w.End < len(text))
+				e.Start += w.Start
+				e.End += w.Start
+				found[i] = append(found[i], candidate{e, cut})
+			}
+		}(i, w)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	var all []candidate
+	for _, f := range found {
+		all = append(all, f...)
+	}
+	return settle(all), nil
+}
+
+// settle drops edge matches that overlap a whole one, then keeps a
+// non-overlapping set: earliest first, then longest, then highest score.
+// Duplicates from two windows collapse into one.
+func settle(all []candidate) []blindfold.DetectedEntity {
+	var kept []candidate
+	for _, c := range all {
+		if c.edge && overlapsWhole(all, c) {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		a, b := kept[i], kept[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.End-a.Start != b.End-b.Start {
+			return a.End-a.Start > b.End-b.Start
+		}
+		return a.Score > b.Score
+	})
+	var out []blindfold.DetectedEntity
+	last := 0
+	for _, c := range kept {
+		if c.Start >= last {
+			out = append(out, c.DetectedEntity)
+			last = c.End
+		}
+	}
+	return out
+}
+
+func overlapsWhole(all []candidate, c candidate) bool {
+	for _, o := range all {
+		if !o.edge && o.Start < c.End && c.Start < o.End {
+			return true
+		}
+	}
+	return false
+}
+
+// Tokenize detects entities with Detect and replaces them with tokens
+// numbered in order of appearance, one token per entity type and value.
+// DetectedEntities keep offsets into text, not into the tokenized output.
+func (c Chunker) Tokenize(ctx context.Context, bf bfclient.Client, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	entities, err := c.Detect(ctx, bf, text, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := &blindfold.TokenizeResponse{
+		Mapping:          make(map[string]string),
+		DetectedEntities: entities,
+		EntitiesCount:    len(entities),
+	}
+	byValue := make(map[string]string)
+	count := make(map[string]int)
+	var b []byte
+	last := 0
+	for _, e := range entities {
+		value := text[e.Start:e.End]
+		key := e.Type + "\x00" + value
+		token, ok := byValue[key]
+		if !ok {
+			count[e.Type]++
+			token = mapping.FormatToken(e.Type, count[e.Type])
+			byValue[key] = token
+			res.Mapping[token] = value
+		}
+		b = append(append(b, text[last:e.Start]...), token...)
+		last = e.End
+	}
+	res.Text = string(append(b, text[last:]...))
+	return res, nil
+}
@@ -0,0 +1,116 @@
+package chunk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+var local = blindfold.New(blindfold.WithMode("local"))
+
+// document puts entities at irregular offsets, so every window size cuts
+// some of them.
+func document() string {
+	var b strings.Builder
+	for i := 0; i < 60; i++ {
+		fmt.Fprintf(&b, "%s ünïcode note %d for user%d@example.com, card 4111 1111 1111 1111, call 415-555-%04d. ", strings.Repeat("x", i%11), i, i%9, i%5)
+	}
+	return b.String()
+}
+
+func TestWindows(t *testing.T) {
+	text := document()
+	c := Chunker{Size: 97, Overlap: 40}
+	ws := c.Windows(text)
+	if ws[0].Start != 0 || ws[len(ws)-1].End != len(text) {
+		t.Fatalf("windows %v don't cover [0, %d)", ws, len(text))
+	}
+	for i, w := range ws {
+		if w.End-w.Start > c.Size || !utf8.ValidString(text[w.Start:w.End]) {
+			t.Errorf("window %d %v: %d bytes, valid UTF-8 %v", i, w, w.End-w.Start, utf8.ValidString(text[w.Start:w.End]))
+		}
+		if i > 0 && ws[i-1].End-w.Start < c.Overlap-utf8.UTFMax {
+			t.Errorf("windows %d and %d overlap by %d bytes, want about %d", i-1, i, ws[i-1].End-w.Start, c.Overlap)
+		}
+	}
+}
+
+func TestDetectMatchesWholeText(t *testing.T) {
+	text := document()
+	whole, err := local.Detect(context.Background(), text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{90, 97, 128, 301, 1 << 20} {
+		got, err := Chunker{Size: size, Overlap: 40}.Detect(context.Background(), local, text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(whole.DetectedEntities) {
+			t.Errorf("size %d: %d entities, want %d", size, len(got), len(whole.DetectedEntities))
+			continue
+		}
+		for i, e := range got {
+			w := whole.DetectedEntities[i]
+			if e.Type != w.Type || e.Start != w.Start || e.End != w.End {
+				t.Errorf("size %d: entity %d = %s %q [%d,%d), want %s %q [%d,%d)", size, i, e.Type, text[e.Start:e.End], e.Start, e.End, w.Type, w.Text, w.Start, w.End)
+				break
+			}
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	text := document()
+	res, err := Chunker{Size: 97, Overlap: 40}.Tokenize(context.Background(), local, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(res.Text, "@example.com") || strings.Contains(res.Text, "4111") {
+		t.Errorf("tokenized text still holds values: %.300q", res.Text)
+	}
+	if back := mapping.Detokenize(res.Text, res.Mapping); back != text {
+		t.Errorf("round trip differs:\n got %.300q\nwant %.300q", back, text)
+	}
+	// 9 users, one card, 5 phone numbers
+	if len(res.Mapping) != 15 {
+		t.Errorf("%d tokens, want 15: %v", len(res.Mapping), res.Mapping)
+	}
+	if !strings.HasPrefix(res.Text, " ünïcode note 0 for <Email Address_1>, card <Credit Card Number_1>, call <Phone Number_1>.") {
+		t.Errorf("tokens not numbered in order of appearance: %.120q", res.Text)
+	}
+}
+
+func TestInvalidChunker(t *testing.T) {
+	for _, c := range []Chunker{{}, {Size: 100, Overlap: 50}, {Size: 100, Overlap: -1}} {
+		if _, err := c.Detect(context.Background(), local, "x"); err == nil {
+			t.Errorf("%+v: Detect accepted it", c)
+		}
+	}
+}
+
+func TestSettle(t *testing.T) {
+	ent := func(typ string, start, end int, score float64, edge bool) candidate {
+		return candidate{blindfold.DetectedEntity{Type: typ, Start: start, End: end, Score: score}, edge}
+	}
+	got := settle([]candidate{
+		ent("Person", 5, 15, 0.9, true),   // cut short by a window edge...
+		ent("Address", 8, 30, 0.8, false), // ...while the next window saw this whole
+		ent("Email Address", 40, 52, 0.9, false),
+		ent("Email Address", 40, 52, 0.9, false), // the same match from two windows
+		ent("Phone Number", 60, 70, 0.9, true),   // only ever seen at an edge: kept
+	})
+	var spans []string
+	for _, e := range got {
+		spans = append(spans, fmt.Sprintf("%s[%d,%d)", e.Type, e.Start, e.End))
+	}
+	if want := "Address[8,30) Email Address[40,52) Phone Number[60,70)"; strings.Join(spans, " ") != want {
+		t.Errorf("settle = %s, want %s", strings.Join(spans, " "), want)
+	}
+}
@@ -0,0 +1,326 @@
+// Package config fills a recipe's flags from the command line, environment
+// variables and an optional config file, in that order of precedence, with
+// defaults last.
+//
+// Flags stay the single declaration of every setting: define them with the
+// flag package as usual, then call Parse instead of flag.Parse.
+//
+//	cfg := config.New(flag.CommandLine, "GATEWAY")
+//	addr := flag.String("addr", "127.0.0.1:8080", "listen address")
+//	cfg.Env("upstream", "OPENAI_BASE_URL") // a name that predates the prefix
+//	cfg.File("config")                     // -config gateway.yaml
+//	if err := cfg.Parse(os.Args[1:]); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Each flag can be set by an environment variable, the prefix plus the flag
+// name in upper case with '-' as '_' (GATEWAY_ADDR), and by a key in the
+// config file named like the flag (addr: 0.0.0.0:8080). A .env file in the
+// working directory is loaded first, as the recipes always have.
+//
+// Every problem is reported at once, with where the bad value came from:
+//
+//	config: -hedge-after "3" from GATEWAY_HEDGE_AFTER: parse error (want a duration)
+//	config: gateway.yaml: unknown setting "hedge_afer" (did you mean "hedge-after"?)
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config fills the flags of one FlagSet. Bind environment variables, the
+// config file flag, requirements and checks before Parse.
+type Config struct {
+	fs       *flag.FlagSet
+	prefix   string
+	env      map[string]string // flag name → variable, where not the prefix default
+	fileFlag string
+	required []string
+	checks   []check
+	sources  map[string]string // flag name → where its value came from
+}
+
+type check struct {
+	name string
+	fn   func() error
+}
+
+// New returns a Config for fs whose environment variables start with
+// prefix and '_'; an empty prefix binds only the variables named with Env.
+// It loads .env right away, so defaults computed from the environment
+// before Parse see it too.
+func New(fs *flag.FlagSet, prefix string) *Config {
+	_ = godotenv.Load()
+	if prefix != "" {
+		prefix = strings.TrimSuffix(prefix, "_") + "_"
+	}
+	return &Config{fs: fs, prefix: prefix, env: map[string]string{}, sources: map[string]string{}}
+}
+
+// Env binds the flag name to the environment variable variable instead of
+// the prefixed default, for names other tools already use.
+func (c *Config) Env(name, variable string) {
+	c.env[name] = variable
+}
+
+// File defines a string flag, name, holding the path of an optional YAML
+// or JSON config file. It can be set from the environment like any other
+// flag, but not from the file itself.
+func (c *Config) File(name string) {
+	c.fileFlag = name
+	c.fs.String(name, "", "config file (YAML or JSON) with settings named like the flags")
+}
+
+// Require reports an error from Parse if any of the named flags is still
+// empty once every source has been applied.
+func (c *Config) Require(names ...string) {
+	c.required = append(c.required, names...)
+}
+
+// Check adds a validation of the flag name, run by Parse after every source
+// has been applied. When fn fails its error is reported with the flag's
+// value and where it came from.
+func (c *Config) Check(name string, fn func() error) {
+	c.checks = append(c.checks, check{name, fn})
+}
+
+// envVar returns the environment variable bound to the flag name, if any.
+func (c *Config) envVar(name string) string {
+	if v, ok := c.env[name]; ok {
+		return v
+	}
+	if c.prefix == "" {
+		return ""
+	}
+	return c.prefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Parse parses args, as flag.Parse would os.Args[1:], then fills every flag
+// not given on the command line from its environment variable, then from
+// the config file, and validates the result. Errors from all sources are
+// joined, so one run reports every problem.
+func (c *Config) Parse(args []string) error {
+	c.fs.VisitAll(func(f *flag.Flag) {
+		if v := c.envVar(f.Name); v != "" {
+			f.Usage += " [$" + v + "]"
+		}
+	})
+	if err := c.fs.Parse(args); err != nil {
+		return err
+	}
+	c.fs.Visit(func(f *flag.Flag) { c.sources[f.Name] = "flag" })
+
+	var errs []error
+	c.fs.VisitAll(func(f *flag.Flag) {
+		if c.sources[f.Name] != "" {
+			return
+		}
+		v := c.envVar(f.Name)
+		if v == "" {
+			return
+		}
+		// Empty counts as unset, as it does for the lines of a .env file
+		// copied from .env.example
+		if val := os.Getenv(v); val != "" {
+			if err := c.set(f, val, v); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	})
+	if c.fileFlag != "" {
+		if path := c.fs.Lookup(c.fileFlag).Value.String(); path != "" {
+			errs = append(errs, c.loadFile(path)...)
+		}
+	}
+	c.fs.VisitAll(func(f *flag.Flag) {
+		if c.sources[f.Name] == "" {
+			c.sources[f.Name] = "default"
+		}
+	})
+
+	for _, name := range c.required {
+		if f := c.fs.Lookup(name); f != nil && f.Value.String() == "" {
+			errs = append(errs, fmt.Errorf("config: -%s is required%s", name, c.hint(name)))
+		}
+	}
+	for _, ch := range c.checks {
+		if err := ch.fn(); err != nil {
+			f := c.fs.Lookup(ch.name)
+			errs = append(errs, fmt.Errorf("config: -%s %q%s: %w", ch.name, f.Value.String(), c.from(ch.name), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// set sets f from source, recording it.
+func (c *Config) set(f *flag.Flag, val, source string) error {
+	if err := f.Value.Set(val); err != nil {
+		return fmt.Errorf("config: -%s %q from %s: %w%s", f.Name, val, source, err, want(f))
+	}
+	c.sources[f.Name] = source
+	return nil
+}
+
+// want names the kind of value f takes, since the flag package's own
+// parse errors don't.
+func want(f *flag.Flag) string {
+	if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		return " (want true or false)"
+	}
+	if kind, _ := flag.UnquoteUsage(f); kind != "" && kind != "value" {
+		return " (want a " + kind + ")"
+	}
+	return ""
+}
+
+// loadFile applies the settings in the config file at path to the flags
+// still unset.
+func (c *Config) loadFile(path string) []error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("config: %w", err)}
+	}
+	var settings map[string]any
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return []error{fmt.Errorf("config: %s: %w", path, err)}
+	}
+	var errs []error
+	for _, key := range sortedKeys(settings) {
+		name := strings.ReplaceAll(key, "_", "-")
+		f := c.fs.Lookup(name)
+		switch {
+		case f == nil:
+			errs = append(errs, fmt.Errorf("config: %s: unknown setting %q%s", path, key, c.suggest(name)))
+			continue
+		case name == c.fileFlag:
+			errs = append(errs, fmt.Errorf("config: %s: %q can't be set from the config file", path, key))
+			continue
+		case c.sources[name] != "":
+			continue // set on the command line or in the environment
+		}
+		val, err := scalar(settings[key])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config: %s: %s: %w", path, key, err))
+			continue
+		}
+		if err := c.set(f, val, path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// scalar turns a config file value into flag syntax. Lists become
+// comma-separated, the form the recipes' list flags take.
+func scalar(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			s, err := scalar(item)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	case map[string]any:
+		return "", errors.New("want a value or a list, not a mapping")
+	}
+	return fmt.Sprint(v), nil
+}
+
+// Source returns where the flag name got its value: "flag", the
+// environment variable, the config file's path, or "default".
+func (c *Config) Source(name string) string {
+	return c.sources[name]
+}
+
+// Sources returns Source for every flag, for /configz.
+func (c *Config) Sources() map[string]string {
+	out := make(map[string]string, len(c.sources))
+	for k, v := range c.sources {
+		out[k] = v
+	}
+	return out
+}
+
+// from describes where a value came from, for error messages.
+func (c *Config) from(name string) string {
+	switch s := c.sources[name]; s {
+	case "", "default":
+		return " (the default)"
+	case "flag":
+		return ""
+	default:
+		return " from " + s
+	}
+}
+
+// hint tells how a required setting can be given.
+func (c *Config) hint(name string) string {
+	ways := []string{"-" + name}
+	if v := c.envVar(name); v != "" {
+		ways = append(ways, "$"+v)
+	}
+	if c.fileFlag != "" {
+		ways = append(ways, name+" in the -"+c.fileFlag+" file")
+	}
+	return ": set " + strings.Join(ways, ", ")
+}
+
+// suggest names the flag closest to an unknown one, if it is close.
+func (c *Config) suggest(name string) string {
+	best, bestDist := "", 3
+	c.fs.VisitAll(func(f *flag.Flag) {
+		if d := distance(name, f.Name); d < bestDist {
+			best, bestDist = f.Name, d
+		}
+	})
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// distance is the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type settings struct {
+	addr     *string
+	upstream *string
+	hedge    *time.Duration
+	demo     *bool
+}
+
+func newConfig(t *testing.T, file string) (*Config, settings) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := New(fs, "GW")
+	s := settings{
+		addr:     fs.String("addr", "127.0.0.1:8080", "listen address"),
+		upstream: fs.String("upstream", "https://api.openai.com/v1", "upstream"),
+		hedge:    fs.Duration("hedge-after", 300*time.Millisecond, "hedge delay"),
+		demo:     fs.Bool("demo", false, "demo"),
+	}
+	cfg.Env("upstream", "OPENAI_BASE_URL")
+	cfg.File("config")
+	if file != "" {
+		path := filepath.Join(t.TempDir(), "gw.yaml")
+		if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("GW_CONFIG", path)
+	}
+	return cfg, s
+}
+
+func TestPrecedence(t *testing.T) {
+	cfg, s := newConfig(t, "addr: 0.0.0.0:9000\nupstream: http://file\nhedge_after: 1s\n")
+	t.Setenv("OPENAI_BASE_URL", "http://env")
+	t.Setenv("GW_HEDGE_AFTER", "") // empty counts as unset
+	if err := cfg.Parse([]string{"-addr", ":7000"}); err != nil {
+		t.Fatal(err)
+	}
+	if *s.addr != ":7000" || *s.upstream != "http://env" || *s.hedge != time.Second || *s.demo {
+		t.Errorf("addr=%s upstream=%s hedge=%s demo=%v", *s.addr, *s.upstream, *s.hedge, *s.demo)
+	}
+	want := map[string]string{"addr": "flag", "upstream": "OPENAI_BASE_URL", "demo": "default", "config": "GW_CONFIG"}
+	for name, source := range want {
+		if got := cfg.Source(name); got != source {
+			t.Errorf("Source(%s) = %q, want %q", name, got, source)
+		}
+	}
+	if got := cfg.Source("hedge-after"); !strings.HasSuffix(got, "gw.yaml") {
+		t.Errorf("Source(hedge-after) = %q, want the file", got)
+	}
+}
+
+func TestErrorsNameTheirSource(t *testing.T) {
+	cfg, s := newConfig(t, "hedge_afer: 1s\ndemo: [1, 2]\nconfig: other.yaml\n")
+	t.Setenv("GW_HEDGE_AFTER", "3")
+	cfg.Require("upstream")
+	cfg.Check("addr", func() error {
+		if !strings.Contains(*s.addr, ":") {
+			return errors.New("want host:port")
+		}
+		return nil
+	})
+	err := cfg.Parse([]string{"-upstream", "", "-addr", "localhost"})
+	if err == nil {
+		t.Fatal("no error")
+	}
+	for _, want := range []string{
+		`-hedge-after "3" from GW_HEDGE_AFTER: parse error (want a duration)`,
+		`unknown setting "hedge_afer" (did you mean "hedge-after"?)`,
+		`gw.yaml: "config" can't be set from the config file`,
+		`-demo "1,2" from`,
+		`(want true or false)`,
+		`-upstream is required: set -upstream, $OPENAI_BASE_URL, upstream in the -config file`,
+		`-addr "localhost": want host:port`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error lacks %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestUsageNamesVariables(t *testing.T) {
+	cfg, _ := newConfig(t, "")
+	if err := cfg.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if u := cfg.fs.Lookup("upstream").Usage; !strings.HasSuffix(u, "[$OPENAI_BASE_URL]") {
+		t.Errorf("usage = %q", u)
+	}
+	if u := cfg.fs.Lookup("addr").Usage; !strings.HasSuffix(u, "[$GW_ADDR]") {
+		t.Errorf("usage = %q", u)
+	}
+}
@@ -0,0 +1,172 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// Client is a connection to a daemon. It is safe for concurrent use;
+// calls take turns on the one connection.
+type Client struct {
+	addr string
+
+	mu     sync.Mutex // guards the fields below, and the connection between a request and its response
+	conn   net.Conn
+	in     *bufio.Scanner
+	nextID int64
+	closed bool
+}
+
+// Dial connects to the daemon at addr, a socket path or, on Windows, a
+// pipe name; "" means DefaultAddress.
+func Dial(ctx context.Context, addr string) (*Client, error) {
+	if addr == "" {
+		addr = DefaultAddress()
+	}
+	c := &Client{addr: addr}
+	if err := c.connect(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) connect(ctx context.Context) error {
+	conn, err := dial(ctx, c.addr)
+	if err != nil {
+		return fmt.Errorf("daemon: connect to %s (is blindfoldd running?): %w", c.addr, err)
+	}
+	c.conn = conn
+	c.in = bufio.NewScanner(conn)
+	c.in.Buffer(make([]byte, 0, 64<<10), maxLineBytes)
+	return nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// Do sends req and returns the daemon's response. A failed request comes
+// back as an error that bferrors classifies as the daemon did; a
+// connection lost between calls, as when the daemon restarts, is redialed
+// once.
+func (c *Client) Do(ctx context.Context, req Request) (*Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+	c.nextID++
+	req.ID = c.nextID
+	line, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	line = append(line, '\n')
+
+	res, err := c.roundTrip(ctx, line)
+	if err != nil && c.conn == nil && ctx.Err() == nil {
+		if err = c.connect(ctx); err == nil {
+			res, err = c.roundTrip(ctx, line)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.ID != req.ID {
+		c.drop()
+		return nil, fmt.Errorf("daemon: response %d to request %d", res.ID, req.ID)
+	}
+	if res.Error != nil {
+		return nil, res.Error.classified()
+	}
+	return res, nil
+}
+
+// roundTrip writes one request line and reads the response. On a
+// connection error it drops the connection, so the next call redials.
+func (c *Client) roundTrip(ctx context.Context, line []byte) (*Response, error) {
+	if c.conn == nil {
+		return nil, fmt.Errorf("daemon: not connected to %s", c.addr)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// A canceled call can't be taken back from the stream: close it
+	conn := c.conn
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if _, err := conn.Write(line); err != nil {
+		c.drop()
+		return nil, fmt.Errorf("daemon: %w", err)
+	}
+	if !c.in.Scan() {
+		err := c.in.Err()
+		c.drop()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err == nil {
+			err = fmt.Errorf("connection closed by %s", c.addr)
+		}
+		return nil, fmt.Errorf("daemon: %w", err)
+	}
+	res := &Response{}
+	if err := json.Unmarshal(c.in.Bytes(), res); err != nil {
+		c.drop()
+		return nil, fmt.Errorf("daemon: bad response: %w", err)
+	}
+	return res, nil
+}
+
+func (c *Client) drop() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// Tokenize tokenizes req.Text; see OpTokenize.
+func (c *Client) Tokenize(ctx context.Context, req Request) (*Response, error) {
+	req.Op = OpTokenize
+	return c.Do(ctx, req)
+}
+
+// Detokenize restores the tokens in req.Text; see OpDetokenize.
+func (c *Client) Detokenize(ctx context.Context, req Request) (*Response, error) {
+	req.Op = OpDetokenize
+	return c.Do(ctx, req)
+}
+
+// Scan lists the PII in req.Text; see OpScan.
+func (c *Client) Scan(ctx context.Context, req Request) (*Response, error) {
+	req.Op = OpScan
+	return c.Do(ctx, req)
+}
+
+// Forget drops a session's mapping.
+func (c *Client) Forget(ctx context.Context, session string) error {
+	_, err := c.Do(ctx, Request{Op: OpForget, Session: session})
+	return err
+}
+
+// Status reports on the daemon.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	res, err := c.Do(ctx, Request{Op: OpStatus})
+	if err != nil {
+		return nil, err
+	}
+	return res.Status, nil
+}
@@ -0,0 +1,145 @@
+// Package daemon is the protocol of blindfoldd, a local daemon that
+// tokenizes, restores and scans text for every tool on one machine, with
+// the server and a client for it.
+//
+// Editor plugins, shell scripts and desktop apps each starting their own
+// Blindfold client pay for it every time: a cold client, an empty cache
+// and a mapping that dies with the process. Pointed at one daemon they
+// share a warm client per policy, one cache, and session mappings that
+// outlive the tool that made them — tokenize a selection in the editor,
+// restore the model's reply from a script.
+//
+//	c, err := daemon.Dial(ctx, "") // DefaultAddress
+//	res, err := c.Tokenize(ctx, daemon.Request{Text: selection, Session: bufferID})
+//	// send res.Text to the model
+//	restored, err := c.Detokenize(ctx, daemon.Request{Text: reply, Session: bufferID})
+//
+// The protocol is JSON lines over a Unix domain socket, or a named pipe
+// on Windows: one Request per line, answered by one Response per line,
+// in order. Anything that can write a line to a socket can use it
+// without this package:
+//
+//	echo '{"op":"tokenize","text":"mail jane@example.com"}' | nc -U "$XDG_RUNTIME_DIR/blindfoldd.sock"
+//
+// Only the user running the daemon can connect: the socket is created
+// with mode 0600 in a directory only they can enter, and the pipe's
+// security descriptor admits only their account.
+package daemon
+
+import (
+	"errors"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+)
+
+// Ops a Request can ask for.
+const (
+	// OpTokenize tokenizes Text. In a Session, a value keeps the token it
+	// got in earlier calls.
+	OpTokenize = "tokenize"
+	// OpDetokenize restores the tokens in Text from the Session's mapping,
+	// Mapping, or both.
+	OpDetokenize = "detokenize"
+	// OpScan lists the PII in Text without tokenizing it. Values come back
+	// masked.
+	OpScan = "scan"
+	// OpForget drops the Session's mapping.
+	OpForget = "forget"
+	// OpStatus reports on the daemon.
+	OpStatus = "status"
+)
+
+// maxLineBytes caps a request or response line.
+const maxLineBytes = 10 << 20
+
+// Request is one call to the daemon.
+type Request struct {
+	// ID, if set, is echoed in the Response.
+	ID int64  `json:"id,omitempty"`
+	Op string `json:"op"`
+	// Text is the text to tokenize, restore or scan.
+	Text string `json:"text,omitempty"`
+	// Session names the mapping shared by calls about the same document
+	// or conversation. Without one, a tokenize call's mapping is only in
+	// its Response.
+	Session string `json:"session,omitempty"`
+	// Policy names the policy to tokenize or scan with; empty selects the
+	// daemon's default.
+	Policy string `json:"policy,omitempty"`
+	// Mapping restores tokens on a detokenize call, over the Session's
+	// mapping when both are set.
+	Mapping map[string]string `json:"mapping,omitempty"`
+}
+
+// Response answers a Request. Error is set if it failed, and nothing else
+// but ID is.
+type Response struct {
+	ID   int64  `json:"id,omitempty"`
+	Text string `json:"text,omitempty"`
+	// Mapping holds the tokens in a tokenize call's Text and their values.
+	Mapping map[string]string `json:"mapping,omitempty"`
+	// Entities is the number of entities a tokenize call protected.
+	Entities int `json:"entities,omitempty"`
+	// Findings are a scan call's entities.
+	Findings []Finding `json:"findings,omitempty"`
+	// Replacements is the number of tokens a detokenize call restored.
+	Replacements int `json:"replacements,omitempty"`
+	// Unresolved lists the tokens a detokenize call had no value for.
+	Unresolved []string `json:"unresolved,omitempty"`
+	Status     *Status  `json:"status,omitempty"`
+	Error      *Error   `json:"error,omitempty"`
+}
+
+// Finding is an entity found by a scan call. The value is masked, so a
+// scan result is safe to log or show in a status bar.
+type Finding struct {
+	Type   string  `json:"type"`
+	Start  int     `json:"start"`
+	End    int     `json:"end"`
+	Score  float64 `json:"score"`
+	Masked string  `json:"masked"`
+}
+
+// Status describes a running daemon.
+type Status struct {
+	// Mode is "local" or "cloud".
+	Mode string `json:"mode"`
+	// Policy is the default policy; Policies the ones a request can name.
+	Policy   string   `json:"policy"`
+	Policies []string `json:"policies"`
+	// Requests counts the requests served since the daemon started.
+	Requests    int64     `json:"requests"`
+	CacheHits   int64     `json:"cache_hits"`
+	CacheMisses int64     `json:"cache_misses"`
+	Started     time.Time `json:"started"`
+}
+
+// Error is a failed Request: a bferrors kind, such as "auth" or
+// "transient", and a message.
+type Error struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// errorOf describes err for a Response.
+func errorOf(err error) *Error {
+	return &Error{Kind: bferrors.KindOf(err).String(), Message: err.Error()}
+}
+
+// classified turns a Response's Error back into a classified error, so
+// callers can use bferrors on it as on a local call.
+func (e *Error) classified() error {
+	kind := bferrors.Unknown
+	for k := bferrors.Unknown; k <= bferrors.Canceled; k++ {
+		if k.String() == e.Kind {
+			kind = k
+		}
+	}
+	return &bferrors.Error{Kind: kind, Err: e}
+}
+
+// ErrClosed is returned by calls on a closed Client.
+var ErrClosed = errors.New("daemon: client closed")
@@ -0,0 +1,234 @@
+//go:build unix
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/cache"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+// serve starts a daemon in local mode and returns a client for it.
+func serve(t *testing.T) (*Server, *Client) {
+	t.Helper()
+	// Not t.TempDir: socket paths are limited to about 100 bytes
+	dir, err := os.MkdirTemp("", "bfd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	addr := filepath.Join(dir, "d.sock")
+	l, err := Listen(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(Config{
+		NewClient: func(pol *policyconf.Policy) bfclient.Client {
+			return blindfold.New(append(pol.ClientOptions(), blindfold.WithMode("local"))...)
+		},
+		Cache: cache.NewLRU(100),
+	})
+	go s.Serve(l)
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+
+	c, err := Dial(context.Background(), addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return s, c
+}
+
+func TestSessionRoundTrip(t *testing.T) {
+	_, c := serve(t)
+	ctx := context.Background()
+
+	first, err := c.Tokenize(ctx, Request{Text: "Mail omar@example.com", Session: "doc-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Text != "Mail <Email Address_1>" || first.Entities != 1 {
+		t.Errorf("first tokenize = %+v", first)
+	}
+	// A later call in the session reuses the first one's token for the
+	// same address; its mapping has only its own tokens
+	second, err := c.Tokenize(ctx, Request{Text: "cc lina@example.com, not omar@example.com", Session: "doc-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"<Email Address_1>": "omar@example.com", "<Email Address_3>": "lina@example.com"}
+	if second.Text != "cc <Email Address_3>, not <Email Address_1>" || !reflect.DeepEqual(second.Mapping, want) {
+		t.Errorf("second tokenize = %+v", second)
+	}
+
+	res, err := c.Detokenize(ctx, Request{Text: "Reply to <Email Address_1> and <Email Address_9>", Session: "doc-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Text != "Reply to omar@example.com and <Email Address_9>" || res.Replacements != 1 || !reflect.DeepEqual(res.Unresolved, []string{"<Email Address_9>"}) {
+		t.Errorf("detokenize = %+v", res)
+	}
+
+	if err := c.Forget(ctx, "doc-1"); err != nil {
+		t.Fatal(err)
+	}
+	res, err = c.Detokenize(ctx, Request{Text: "<Email Address_1>", Session: "doc-1"})
+	if err != nil || res.Text != "<Email Address_1>" {
+		t.Errorf("after forget: %+v, %v", res, err)
+	}
+}
+
+func TestDetokenizeWithMapping(t *testing.T) {
+	_, c := serve(t)
+	res, err := c.Detokenize(context.Background(), Request{Text: "Hi <Person_1>", Mapping: map[string]string{"<Person_1>": "Jane"}})
+	if err != nil || res.Text != "Hi Jane" || res.Replacements != 1 {
+		t.Errorf("detokenize = %+v, %v", res, err)
+	}
+}
+
+func TestScanMasks(t *testing.T) {
+	_, c := serve(t)
+	res, err := c.Scan(context.Background(), Request{Text: "mail omar@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Findings) != 1 {
+		t.Fatalf("findings = %+v", res.Findings)
+	}
+	f := res.Findings[0]
+	if f.Type != "Email Address" || f.Start != 5 || f.End != 21 || f.Masked == "omar@example.com" || f.Masked == "" {
+		t.Errorf("finding = %+v", f)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	_, c := serve(t)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := c.Tokenize(ctx, Request{Text: "Mail omar@example.com"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st, err := c.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode != "local" || st.Policy != DefaultPolicy || st.Requests != 3 || st.CacheHits != 1 || st.CacheMisses != 1 {
+		t.Errorf("status = %+v", st)
+	}
+}
+
+func TestErrorsAreClassified(t *testing.T) {
+	_, c := serve(t)
+	ctx := context.Background()
+	_, err := c.Tokenize(ctx, Request{Text: "hi", Policy: "no-such-policy"})
+	var de *Error
+	if !errors.As(err, &de) || bferrors.KindOf(err) != bferrors.Unknown {
+		t.Errorf("unknown policy: %v", err)
+	}
+	if _, err := c.Do(ctx, Request{Op: "shred"}); err == nil {
+		t.Error("want an error for an unknown op")
+	}
+	// The connection is still usable after a failed request
+	if _, err := c.Tokenize(ctx, Request{Text: "hi"}); err != nil {
+		t.Errorf("after errors: %v", err)
+	}
+}
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "bfd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	addr := filepath.Join(dir, "d.sock")
+	l, err := Listen(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen(addr); err == nil {
+		t.Error("want an error while a daemon serves the socket")
+	}
+	if fi, err := os.Stat(addr); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("socket mode: %v, %v", fi.Mode(), err)
+	}
+	// A crashed daemon leaves its socket behind
+	l.(interface{ SetUnlinkOnClose(bool) }).SetUnlinkOnClose(false)
+	l.Close()
+	l, err = Listen(addr)
+	if err != nil {
+		t.Fatalf("stale socket: %v", err)
+	}
+	l.Close()
+}
+
+func TestListenRefusesSharedDirectory(t *testing.T) {
+	parent, err := os.MkdirTemp("", "bfd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(parent)
+
+	// Created by Listen: private
+	if l, err := Listen(filepath.Join(parent, "new", "d.sock")); err != nil {
+		t.Errorf("new directory: %v", err)
+	} else {
+		l.Close()
+	}
+
+	// Left open to others, as a directory another user created might be
+	open := filepath.Join(parent, "open")
+	if err := os.Mkdir(open, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(open, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen(filepath.Join(open, "d.sock")); err == nil {
+		t.Error("mode 0777 directory: want an error")
+	}
+
+	// A symlink to a private directory: the link could be swapped
+	link := filepath.Join(parent, "link")
+	if err := os.Symlink(filepath.Join(parent, "new"), link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen(filepath.Join(link, "d.sock")); err == nil {
+		t.Error("symlink: want an error")
+	}
+}
+
+func TestClientRedials(t *testing.T) {
+	s, c := serve(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := c.Status(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Idle connections are closed on shutdown; a new daemon on the same
+	// socket picks up where it left off
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Listen(c.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2 := NewServer(Config{NewClient: func(*policyconf.Policy) bfclient.Client { return blindfold.New(blindfold.WithMode("local")) }})
+	go s2.Serve(l)
+	defer s2.Shutdown(ctx)
+	if _, err := c.Status(ctx); err != nil {
+		t.Errorf("after restart: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+//go:build unix
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// DefaultAddress is the socket blindfoldd serves on by default:
+// blindfoldd.sock in $XDG_RUNTIME_DIR, or in a blindfoldd-<uid> directory
+// in the temp directory when that isn't set.
+func DefaultAddress() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "blindfoldd.sock")
+	}
+	return filepath.Join(os.TempDir(), "blindfoldd-"+strconv.Itoa(os.Getuid()), "blindfoldd.sock")
+}
+
+// Listen creates the socket at addr ("" means DefaultAddress), readable
+// and writable by the current user only. A missing directory is created
+// with mode 0700; an existing one must be a directory of the current user
+// with mode 0700, not a symlink, so no other user can swap the socket for
+// their own. A socket left behind by a daemon that didn't shut down
+// is replaced; one a daemon still answers on is an error.
+func Listen(addr string) (net.Listener, error) {
+	if addr == "" {
+		addr = DefaultAddress()
+	}
+	dir := filepath.Dir(addr)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := checkPrivate(dir); err != nil {
+		return nil, err
+	}
+	if fi, err := os.Lstat(addr); err == nil {
+		if fi.Mode()&fs.ModeSocket == 0 {
+			return nil, fmt.Errorf("daemon: %s exists and isn't a socket", addr)
+		}
+		if conn, err := net.DialTimeout("unix", addr, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("daemon: a daemon is already serving on %s", addr)
+		}
+		if err := os.Remove(addr); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	// The umask keeps the socket private from the moment it exists,
+	// not from the chmod after
+	old := syscall.Umask(0o177)
+	l, err := net.Listen("unix", addr)
+	syscall.Umask(old)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(addr, 0o600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// checkPrivate returns an error unless dir is a real directory owned by
+// the current user with mode 0700. In a shared temp directory another
+// user may have created it first.
+func checkPrivate(dir string) error {
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("daemon: %s isn't a directory", dir)
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); !ok || int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("daemon: %s isn't owned by the current user", dir)
+	}
+	if fi.Mode().Perm() != 0o700 {
+		return fmt.Errorf("daemon: %s has mode %#o, want 0700", dir, fi.Mode().Perm())
+	}
+	return nil
+}
+
+func dial(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", addr)
+}
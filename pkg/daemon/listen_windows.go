@@ -0,0 +1,179 @@
+//go:build windows
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// pipeBuffer is the size of a pipe instance's input and output buffers.
+const pipeBuffer = 64 << 10
+
+// DefaultAddress is the pipe blindfoldd serves on by default,
+// \\.\pipe\blindfoldd-<user>.
+func DefaultAddress() string {
+	name := "blindfoldd"
+	if u, err := user.Current(); err == nil {
+		name += "-" + strings.NewReplacer(`\`, "-", "/", "-").Replace(u.Username)
+	}
+	return `\\.\pipe\` + name
+}
+
+// Listen creates the named pipe addr ("" means DefaultAddress), open to
+// the current user only and to no one over the network. A pipe another
+// daemon already serves is an error.
+func Listen(addr string) (net.Listener, error) {
+	if addr == "" {
+		addr = DefaultAddress()
+	}
+	tu, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return nil, err
+	}
+	// Protected DACL with a single entry: full access for this user
+	sd, err := windows.SecurityDescriptorFromString("D:P(A;;GA;;;" + tu.User.Sid.String() + ")")
+	if err != nil {
+		return nil, err
+	}
+	l := &pipeListener{path: addr, sa: &windows.SecurityAttributes{Length: uint32(unsafe.Sizeof(windows.SecurityAttributes{})), SecurityDescriptor: sd}}
+	if l.next, err = l.create(true); err != nil {
+		return nil, &net.OpError{Op: "listen", Net: "pipe", Addr: pipeAddr(addr), Err: err}
+	}
+	return l, nil
+}
+
+// pipeListener accepts clients on a named pipe. There is always one
+// instance of the pipe waiting for the next client.
+type pipeListener struct {
+	path string
+	sa   *windows.SecurityAttributes
+
+	mu     sync.Mutex // guards the fields below
+	next   windows.Handle
+	closed bool
+}
+
+// create makes a pipe instance. The first instance fails if the pipe
+// already exists, so a second daemon can't take over the first's clients.
+func (l *pipeListener) create(first bool) (windows.Handle, error) {
+	name, err := windows.UTF16PtrFromString(l.path)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+	flags := uint32(windows.PIPE_ACCESS_DUPLEX)
+	if first {
+		flags |= windows.FILE_FLAG_FIRST_PIPE_INSTANCE
+	}
+	mode := uint32(windows.PIPE_TYPE_BYTE | windows.PIPE_READMODE_BYTE | windows.PIPE_WAIT | windows.PIPE_REJECT_REMOTE_CLIENTS)
+	return windows.CreateNamedPipe(name, flags, mode, windows.PIPE_UNLIMITED_INSTANCES, pipeBuffer, pipeBuffer, 0, l.sa)
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	for {
+		l.mu.Lock()
+		h, closed := l.next, l.closed
+		l.mu.Unlock()
+		if closed {
+			return nil, net.ErrClosed
+		}
+		err := windows.ConnectNamedPipe(h, nil)
+		if errors.Is(err, windows.ERROR_PIPE_CONNECTED) {
+			err = nil // the client connected before ConnectNamedPipe was called
+		}
+
+		l.mu.Lock()
+		if l.closed {
+			l.next = windows.InvalidHandle
+			l.mu.Unlock()
+			windows.CloseHandle(h)
+			return nil, net.ErrClosed
+		}
+		next, cerr := l.create(false)
+		if cerr != nil {
+			l.mu.Unlock()
+			windows.CloseHandle(h)
+			return nil, &net.OpError{Op: "accept", Net: "pipe", Addr: pipeAddr(l.path), Err: cerr}
+		}
+		l.next = next
+		l.mu.Unlock()
+
+		if err != nil {
+			// The client left before it was connected: wait for the next
+			windows.CloseHandle(h)
+			continue
+		}
+		return &pipeConn{File: os.NewFile(uintptr(h), l.path), addr: pipeAddr(l.path)}, nil
+	}
+}
+
+// Close stops Accept. ConnectNamedPipe only returns for a client, so
+// Close connects to the pipe itself.
+func (l *pipeListener) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	l.mu.Unlock()
+	if h, err := openPipe(l.path); err == nil {
+		windows.CloseHandle(h)
+	}
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr { return pipeAddr(l.path) }
+
+// pipeConn is one end of a connected pipe instance. Deadlines aren't
+// supported.
+type pipeConn struct {
+	*os.File
+	addr pipeAddr
+}
+
+func (c *pipeConn) LocalAddr() net.Addr  { return c.addr }
+func (c *pipeConn) RemoteAddr() net.Addr { return c.addr }
+
+type pipeAddr string
+
+func (pipeAddr) Network() string  { return "pipe" }
+func (a pipeAddr) String() string { return string(a) }
+
+// openPipe opens the client end of the pipe. The daemon may only
+// identify the client, not act as it.
+func openPipe(path string) (windows.Handle, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+	return windows.CreateFile(name, windows.GENERIC_READ|windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING,
+		windows.SECURITY_SQOS_PRESENT|windows.SECURITY_IDENTIFICATION, 0)
+}
+
+func dial(ctx context.Context, addr string) (net.Conn, error) {
+	for {
+		h, err := openPipe(addr)
+		if err == nil {
+			return &pipeConn{File: os.NewFile(uintptr(h), addr), addr: pipeAddr(addr)}, nil
+		}
+		// Every instance is taken until the daemon makes the next one
+		if !errors.Is(err, windows.ERROR_PIPE_BUSY) {
+			return nil, &net.OpError{Op: "dial", Net: "pipe", Addr: pipeAddr(addr), Err: err}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/cache"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/masking"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+)
+
+// DefaultPolicy is the policy of a daemon without a policy file.
+const DefaultPolicy = "basic"
+
+// ErrServerClosed is returned by Serve after Shutdown.
+var ErrServerClosed = errors.New("daemon: server closed")
+
+// Config configures a Server.
+type Config struct {
+	// Policies holds the policies a request can name. Nil means the
+	// built-in policies only.
+	Policies *policyconf.Config
+	// Policy applies to requests that don't name one. Defaults to the
+	// default of Policies, or DefaultPolicy.
+	Policy string
+	// NewClient builds the client a policy is applied to. Defaults to
+	// bfclient.FromEnv with the policy's client options.
+	NewClient func(pol *policyconf.Policy) bfclient.Client
+	// Cache, if set, keeps Detect and Tokenize results under one
+	// namespace per policy. It holds the original values; see package
+	// cache for what to trust with that.
+	Cache cache.Store
+	// Store keeps session mappings. Defaults to a protect.MemoryStore, so
+	// sessions end with the daemon.
+	Store protect.Store
+	// Mode is reported by status requests. Defaults to "cloud" when
+	// BLINDFOLD_API_KEY is set and "local" otherwise.
+	Mode string
+}
+
+// Server answers Requests on the connections of its listeners. Each
+// connection's requests are answered in order; connections are served
+// concurrently.
+type Server struct {
+	cfg      Config
+	started  time.Time
+	requests atomic.Int64
+
+	mu      sync.Mutex // guards clients
+	clients map[string]*policyClient
+
+	sessions sync.Mutex // serializes load-merge-save of session mappings
+
+	connMu    sync.Mutex // guards the fields below
+	closing   bool
+	listeners map[net.Listener]bool
+	conns     map[net.Conn]bool // connection → busy with a request
+	active    sync.WaitGroup
+}
+
+// policyClient is a policy's warm client.
+type policyClient struct {
+	bf    bfclient.Client
+	cache *cache.Client // nil without Config.Cache
+}
+
+// NewServer returns a Server for cfg.
+func NewServer(cfg Config) *Server {
+	if cfg.Policies == nil {
+		cfg.Policies = &policyconf.Config{}
+	}
+	if cfg.Policy == "" {
+		cfg.Policy = cfg.Policies.Default
+	}
+	if cfg.Policy == "" {
+		cfg.Policy = DefaultPolicy
+	}
+	if cfg.NewClient == nil {
+		cfg.NewClient = func(pol *policyconf.Policy) bfclient.Client { return bfclient.FromEnv(pol.ClientOptions()...) }
+	}
+	if cfg.Store == nil {
+		cfg.Store = protect.NewMemoryStore()
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = "local"
+		if os.Getenv("BLINDFOLD_API_KEY") != "" {
+			cfg.Mode = "cloud"
+		}
+	}
+	return &Server{
+		cfg:       cfg,
+		started:   time.Now(),
+		clients:   make(map[string]*policyClient),
+		listeners: make(map[net.Listener]bool),
+		conns:     make(map[net.Conn]bool),
+	}
+}
+
+// Serve accepts connections on l until Shutdown, and always returns a
+// non-nil error: ErrServerClosed after Shutdown.
+func (s *Server) Serve(l net.Listener) error {
+	s.connMu.Lock()
+	if s.closing {
+		s.connMu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listeners[l] = true
+	s.connMu.Unlock()
+	defer func() {
+		s.connMu.Lock()
+		delete(s.listeners, l)
+		s.connMu.Unlock()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.connMu.Lock()
+			closing := s.closing
+			s.connMu.Unlock()
+			if closing {
+				return ErrServerClosed
+			}
+			return err
+		}
+		s.connMu.Lock()
+		if s.closing {
+			s.connMu.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = false
+		s.active.Add(1)
+		s.connMu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+// Shutdown stops accepting connections, closes idle ones, and waits for
+// the requests in flight to be answered or ctx to end, when it closes the
+// rest.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.connMu.Lock()
+	s.closing = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for conn, busy := range s.conns {
+		if !busy {
+			conn.Close()
+		}
+	}
+	s.connMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.connMu.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.connMu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// serveConn answers the requests on conn, one line at a time.
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.connMu.Lock()
+		delete(s.conns, conn)
+		s.connMu.Unlock()
+		s.active.Done()
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := bufio.NewScanner(conn)
+	in.Buffer(make([]byte, 0, 64<<10), maxLineBytes)
+	out := bufio.NewWriter(conn)
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false) // tokens are <Type_N>
+	for in.Scan() {
+		if len(in.Bytes()) == 0 {
+			continue
+		}
+		if !s.setBusy(conn, true) {
+			return
+		}
+		var req Request
+		var res *Response
+		if err := json.Unmarshal(in.Bytes(), &req); err != nil {
+			res = &Response{Error: &Error{Kind: "unknown", Message: "daemon: bad request: " + err.Error()}}
+		} else {
+			res = s.Handle(ctx, &req)
+		}
+		if enc.Encode(res) != nil || out.Flush() != nil {
+			return
+		}
+		if !s.setBusy(conn, false) {
+			return
+		}
+	}
+	if errors.Is(in.Err(), bufio.ErrTooLong) {
+		_ = enc.Encode(&Response{Error: &Error{Kind: "unknown", Message: fmt.Sprintf("daemon: request longer than %d bytes", maxLineBytes)}})
+		_ = out.Flush()
+	}
+}
+
+// setBusy marks conn busy with a request or idle again, and reports
+// whether to go on serving it: not once the server is shutting down.
+func (s *Server) setBusy(conn net.Conn, busy bool) bool {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	s.conns[conn] = busy
+	return !s.closing
+}
+
+// Handle answers req. Serve calls it for every request line; it is
+// exported for embedding the daemon's operations in another server.
+func (s *Server) Handle(ctx context.Context, req *Request) *Response {
+	s.requests.Add(1)
+	var res *Response
+	var err error
+	switch req.Op {
+	case OpTokenize:
+		res, err = s.tokenize(ctx, req)
+	case OpDetokenize:
+		res, err = s.detokenize(ctx, req)
+	case OpScan:
+		res, err = s.scan(ctx, req)
+	case OpForget:
+		res, err = s.forget(ctx, req)
+	case OpStatus:
+		res = &Response{Status: s.status()}
+	default:
+		err = fmt.Errorf("daemon: unknown op %q (want %s, %s, %s, %s or %s)", req.Op, OpTokenize, OpDetokenize, OpScan, OpForget, OpStatus)
+	}
+	if err != nil {
+		res = &Response{Error: errorOf(err)}
+	}
+	res.ID = req.ID
+	return res
+}
+
+// client returns the warm client for a policy, building it on first use.
+func (s *Server) client(name string) (*policyClient, error) {
+	if name == "" {
+		name = s.cfg.Policy
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pc, ok := s.clients[name]; ok {
+		return pc, nil
+	}
+	pol, err := s.cfg.Policies.Policy(name)
+	if err != nil {
+		return nil, err
+	}
+	pc := &policyClient{}
+	next := s.cfg.NewClient(pol)
+	if s.cfg.Cache != nil {
+		// One namespace per policy: the cache key is the text alone
+		pc.cache = cache.Wrap(next, s.cfg.Cache, "blindfoldd/"+name)
+		next = pc.cache
+	}
+	pc.bf = pol.Wrap(next)
+	s.clients[name] = pc
+	return pc, nil
+}
+
+// tokenize tokenizes req.Text and, in a session, merges its mapping into
+// the stored one and rewrites the text to the stored tokens.
+func (s *Server) tokenize(ctx context.Context, req *Request) (*Response, error) {
+	pc, err := s.client(req.Policy)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := pc.bf.Tokenize(ctx, req.Text)
+	if err != nil {
+		return nil, fmt.Errorf("tokenize: %w", err)
+	}
+	res := &Response{Text: tok.Text, Mapping: tok.Mapping, Entities: tok.EntitiesCount}
+	if req.Session == "" {
+		return res, nil
+	}
+
+	s.sessions.Lock()
+	defer s.sessions.Unlock()
+	stored, err := s.cfg.Store.Load(ctx, req.Session)
+	if err != nil {
+		return nil, fmt.Errorf("daemon: load mapping: %w", err)
+	}
+	merged := mapping.Merge(stored, tok.Mapping)
+	if len(merged.Mapping) != len(stored) {
+		if err := s.cfg.Store.Save(ctx, req.Session, merged.Mapping); err != nil {
+			return nil, fmt.Errorf("daemon: save mapping: %w", err)
+		}
+	}
+	// Only the tokens in this text: the rest of the session is other
+	// callers' business
+	res.Text, res.Mapping = merged.Rewrite(1, tok.Text), make(map[string]string)
+	for _, token := range mapping.TokenPattern.FindAllString(res.Text, -1) {
+		if value, ok := merged.Mapping[token]; ok {
+			res.Mapping[token] = value
+		}
+	}
+	return res, nil
+}
+
+// detokenize restores req.Text from the session's mapping and req.Mapping.
+func (s *Server) detokenize(ctx context.Context, req *Request) (*Response, error) {
+	m := make(map[string]string)
+	if req.Session != "" {
+		stored, err := s.cfg.Store.Load(ctx, req.Session)
+		if err != nil {
+			return nil, fmt.Errorf("daemon: load mapping: %w", err)
+		}
+		for token, value := range stored {
+			m[token] = value
+		}
+	}
+	for token, value := range req.Mapping {
+		m[token] = value
+	}
+	res := &Response{Text: mapping.Detokenize(req.Text, m), Unresolved: mapping.Unresolved(req.Text, m)}
+	for _, token := range mapping.TokenPattern.FindAllString(req.Text, -1) {
+		if _, ok := m[token]; ok {
+			res.Replacements++
+		}
+	}
+	return res, nil
+}
+
+// scan lists the entities in req.Text with their values masked.
+func (s *Server) scan(ctx context.Context, req *Request) (*Response, error) {
+	pc, err := s.client(req.Policy)
+	if err != nil {
+		return nil, err
+	}
+	det, err := pc.bf.Detect(ctx, req.Text)
+	if err != nil {
+		return nil, fmt.Errorf("detect: %w", err)
+	}
+	rules := masking.Defaults()
+	res := &Response{Findings: make([]Finding, 0, len(det.DetectedEntities))}
+	for _, e := range det.DetectedEntities {
+		res.Findings = append(res.Findings, Finding{Type: e.Type, Start: e.Start, End: e.End, Score: e.Score, Masked: rules.Mask(e.Type, e.Text)})
+	}
+	return res, nil
+}
+
+// forget drops the session's mapping.
+func (s *Server) forget(ctx context.Context, req *Request) (*Response, error) {
+	if req.Session == "" {
+		return nil, errors.New("daemon: forget needs a session")
+	}
+	s.sessions.Lock()
+	defer s.sessions.Unlock()
+	if f, ok := s.cfg.Store.(interface{ Forget(session string) }); ok {
+		f.Forget(req.Session)
+		return &Response{}, nil
+	}
+	// A Store that can't delete gets an empty mapping instead
+	if err := s.cfg.Store.Save(ctx, req.Session, map[string]string{}); err != nil {
+		return nil, fmt.Errorf("daemon: save mapping: %w", err)
+	}
+	return &Response{}, nil
+}
+
+func (s *Server) status() *Status {
+	st := &Status{
+		Mode:     s.cfg.Mode,
+		Policy:   s.cfg.Policy,
+		Policies: s.cfg.Policies.Names(),
+		Requests: s.requests.Load(),
+		Started:  s.started,
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, pc := range s.clients {
+		if pc.cache != nil {
+			stats := pc.cache.Stats()
+			st.CacheHits += stats.Hits
+			st.CacheMisses += stats.Misses
+		}
+	}
+	return st
+}
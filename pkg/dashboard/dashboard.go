@@ -0,0 +1,296 @@
+// Package dashboard serves a small live web page of what a protection
+// service is detecting: entity types, the policies requests ran under,
+// fallback and failover events, detokenization failures, and the most
+// recent requests.
+//
+// It is built from what the service already produces. A Dashboard is an
+// audit.Sink, so it sees every event the gateway records, and it reads
+// the counters of pkg/metrics from a Prometheus gatherer. Like both, it
+// never holds a detected value: an activity row has entity types and
+// token counts only.
+//
+//	dash := dashboard.New(reg)
+//	gw := gateway.New(gateway.Config{Audit: audit.Tee(sink, dash), …})
+//	mux.Handle("/dashboard/", http.StripPrefix("/dashboard", dash.Handler()))
+//
+// The page follows the counts as they change, over server-sent events.
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/audit"
+)
+
+// DefaultRecent is how many requests the activity list keeps.
+const DefaultRecent = 50
+
+// throttle is the shortest time between two updates on one stream, and
+// heartbeat the longest.
+const (
+	throttle  = 500 * time.Millisecond
+	heartbeat = 5 * time.Second
+)
+
+// Activity is one request, as the activity list shows it.
+type Activity struct {
+	Time      time.Time      `json:"time"`
+	Operation string         `json:"operation"`
+	Policy    string         `json:"policy,omitempty"`
+	Model     string         `json:"model,omitempty"`
+	Outcome   string         `json:"outcome"`
+	Entities  map[string]int `json:"entities"`
+	Tokens    int            `json:"tokens"`
+}
+
+// Snapshot is everything the page shows, as of Time.
+type Snapshot struct {
+	Time    time.Time `json:"time"`
+	Started time.Time `json:"started"`
+
+	// From the audit events since Started
+	Requests int            `json:"requests"`
+	Outcomes map[string]int `json:"outcomes"`
+	Policies map[string]int `json:"policies"`
+	Recent   []Activity     `json:"recent"` // newest first
+
+	// From pkg/metrics, or from the events without a gatherer
+	Entities           map[string]float64 `json:"entities"`            // by entity type
+	Fallbacks          map[string]float64 `json:"fallbacks"`           // by operation
+	Failovers          map[string]float64 `json:"failovers"`           // by endpoint
+	DetokenizeFailures float64            `json:"detokenize_failures"` // responses left with placeholders
+}
+
+// Dashboard collects audit events, and serves them with the metrics.
+type Dashboard struct {
+	// Recent is how many requests the activity list keeps; 0 means
+	// DefaultRecent. Set it before the first Record.
+	Recent int
+
+	gatherer prometheus.Gatherer
+	started  time.Time
+
+	mu       sync.Mutex
+	recent   []Activity // a ring, next is the oldest once full
+	next     int
+	requests int
+	outcomes map[string]int
+	policies map[string]int
+	entities map[string]int
+	changed  chan struct{} // closed and replaced on every Record
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+var _ audit.Sink = (*Dashboard)(nil)
+
+// New returns an empty Dashboard reading metrics from g. With a nil g,
+// entity counts come from the events, and fallbacks, failovers and
+// detokenization failures aren't shown.
+func New(g prometheus.Gatherer) *Dashboard {
+	return &Dashboard{
+		gatherer: g,
+		started:  time.Now(),
+		outcomes: make(map[string]int),
+		policies: make(map[string]int),
+		entities: make(map[string]int),
+		changed:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Record counts e and adds it to the activity list. It never fails, so
+// it can't hold up a request.
+func (d *Dashboard) Record(_ context.Context, e audit.Event) error {
+	a := Activity{
+		Time:      e.Time,
+		Operation: e.Operation,
+		Policy:    e.Policy,
+		Model:     e.Model,
+		Outcome:   e.Outcome,
+		Entities:  make(map[string]int, len(e.Entities)),
+		Tokens:    len(e.Tokens),
+	}
+	for typ, n := range e.Entities {
+		a.Entities[typ] = n
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.requests++
+	d.outcomes[e.Outcome]++
+	d.policies[orNone(e.Policy)]++
+	for typ, n := range e.Entities {
+		d.entities[typ] += n
+	}
+	keep := d.Recent
+	if keep <= 0 {
+		keep = DefaultRecent
+	}
+	if len(d.recent) < keep {
+		d.recent = append(d.recent, a)
+	} else {
+		d.recent[d.next] = a
+		d.next = (d.next + 1) % len(d.recent)
+	}
+	close(d.changed)
+	d.changed = make(chan struct{})
+	return nil
+}
+
+// Close ends the live streams of open pages, which would otherwise hold
+// an http.Server's Shutdown until its deadline. Register it with
+// RegisterOnShutdown. Record keeps working after Close.
+func (d *Dashboard) Close() error {
+	d.closeOnce.Do(func() { close(d.done) })
+	return nil
+}
+
+// Snapshot returns the counts and the activity list as of now.
+func (d *Dashboard) Snapshot() Snapshot {
+	d.mu.Lock()
+	s := Snapshot{
+		Time:     time.Now().UTC(),
+		Started:  d.started.UTC(),
+		Requests: d.requests,
+		Outcomes: copyCounts(d.outcomes),
+		Policies: copyCounts(d.policies),
+		Recent:   make([]Activity, 0, len(d.recent)),
+	}
+	for i := range d.recent {
+		// Newest first: walk back from the slot before next
+		s.Recent = append(s.Recent, d.recent[(d.next-1-i+2*len(d.recent))%len(d.recent)])
+	}
+	fromEvents := make(map[string]float64, len(d.entities))
+	for typ, n := range d.entities {
+		fromEvents[typ] = float64(n)
+	}
+	d.mu.Unlock()
+
+	s.Entities, s.Fallbacks, s.Failovers = fromEvents, map[string]float64{}, map[string]float64{}
+	if d.gatherer == nil {
+		return s
+	}
+	families, err := d.gatherer.Gather()
+	if err != nil {
+		return s
+	}
+	s.Entities = sumBy(families, "blindfold_entities_detected_total", "entity_type")
+	s.Fallbacks = sumBy(families, "blindfold_fallback_events_total", "op")
+	s.Failovers = sumBy(families, "blindfold_failovers_total", "endpoint")
+	for _, v := range sumBy(families, "blindfold_detokenize_failures_total", "reason") {
+		s.DetokenizeFailures += v
+	}
+	return s
+}
+
+// Handler serves the page at /, the snapshot as JSON at /summary, and a
+// stream of snapshots at /events: one when it opens, then one after each
+// change, at most every half second. Mount it under a prefix with
+// http.StripPrefix; the page's URLs are relative.
+func (d *Dashboard) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.page)
+	mux.HandleFunc("/summary", d.summary)
+	mux.HandleFunc("/events", d.events)
+	return mux
+}
+
+func (d *Dashboard) page(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+	w.Write([]byte(pageHTML))
+}
+
+func (d *Dashboard) summary(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(d.Snapshot())
+}
+
+func (d *Dashboard) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	for {
+		d.mu.Lock()
+		changed := d.changed
+		d.mu.Unlock()
+
+		data, _ := json.Marshal(d.Snapshot())
+		if _, err := w.Write([]byte("data: " + string(data) + "\n\n")); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		// Metrics change without an event too (a health check's fallback),
+		// so a quiet stream still gets a snapshot every heartbeat
+		select {
+		case <-changed:
+		case <-time.After(heartbeat):
+		case <-r.Context().Done():
+			return
+		case <-d.done:
+			return
+		}
+		select {
+		case <-time.After(throttle):
+		case <-r.Context().Done():
+			return
+		case <-d.done:
+			return
+		}
+	}
+}
+
+// sumBy adds up the counters of the family name by the value of label.
+func sumBy(families []*dto.MetricFamily, name, label string) map[string]float64 {
+	out := make(map[string]float64)
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == label {
+					out[l.GetValue()] += m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return out
+}
+
+func copyCounts(m map[string]int) map[string]int {
+	out := make(map[string]int, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
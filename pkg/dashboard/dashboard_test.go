@@ -0,0 +1,116 @@
+package dashboard
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/audit"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/metrics"
+)
+
+func event(op, policy, outcome string, entities map[string]int) audit.Event {
+	e := audit.Event{Time: time.Now(), Operation: op, Policy: policy, Outcome: outcome, Entities: entities}
+	for typ, n := range entities {
+		for i := 1; i <= n; i++ {
+			e.Tokens = append(e.Tokens, fmt.Sprintf("<%s_%d>", typ, i))
+		}
+	}
+	return e
+}
+
+func TestSnapshot(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.New(reg)
+	d := New(reg)
+	d.Recent = 2
+
+	sink := audit.Tee(d, nil)
+	sink.Record(context.Background(), event("chat.completions", "support", audit.Forwarded, map[string]int{"Email Address": 2}))
+	sink.Record(context.Background(), event("embeddings", "support", audit.Forwarded, map[string]int{"Phone Number": 1}))
+	sink.Record(context.Background(), event("chat.completions", "basic", audit.Rejected, nil))
+	m.Entities.WithLabelValues("tokenize", "Email Address").Add(2)
+	m.Entities.WithLabelValues("detect", "Email Address").Add(1)
+	m.ObserveFallback("tokenize", errors.New("503"))
+	m.ObserveFailover("tokenize", "eu", errors.New("503"))
+	m.ObserveDetokenize("Hi <Person_1>", nil)
+
+	s := d.Snapshot()
+	if s.Requests != 3 || s.Outcomes["rejected"] != 1 || s.Policies["support"] != 2 || s.Policies["basic"] != 1 {
+		t.Errorf("counts = %d %v %v", s.Requests, s.Outcomes, s.Policies)
+	}
+	if s.Entities["Email Address"] != 3 || s.Fallbacks["tokenize"] != 1 || s.Failovers["eu"] != 1 || s.DetokenizeFailures != 1 {
+		t.Errorf("metrics = %v %v %v %v", s.Entities, s.Fallbacks, s.Failovers, s.DetokenizeFailures)
+	}
+	if len(s.Recent) != 2 || s.Recent[0].Outcome != audit.Rejected || s.Recent[1].Operation != "embeddings" || s.Recent[1].Tokens != 1 {
+		t.Errorf("recent = %+v", s.Recent)
+	}
+
+	// Without a gatherer, entities come from the events
+	d = New(nil)
+	d.Record(context.Background(), event("chat.completions", "", audit.Forwarded, map[string]int{"Email Address": 2}))
+	if s := d.Snapshot(); s.Entities["Email Address"] != 2 || s.Policies["(none)"] != 1 {
+		t.Errorf("no gatherer: %v %v", s.Entities, s.Policies)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	d := New(nil)
+	srv := httptest.NewServer(http.StripPrefix("/dashboard", d.Handler()))
+	defer srv.Close()
+
+	if resp, err := http.Get(srv.URL + "/dashboard/"); err != nil || resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("page: %v %v", resp, err)
+	}
+	resp, err := http.Get(srv.URL + "/dashboard/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	r := bufio.NewReader(resp.Body)
+	next := func() (Snapshot, error) {
+		var s Snapshot
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return s, err
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				return s, json.Unmarshal([]byte(data), &s)
+			}
+		}
+	}
+
+	if s, err := next(); err != nil || s.Requests != 0 {
+		t.Fatalf("first snapshot: %+v %v", s, err)
+	}
+	d.Record(context.Background(), event("chat.completions", "basic", audit.Forwarded, map[string]int{"Email Address": 1}))
+	if s, err := next(); err != nil || s.Requests != 1 || s.Recent[0].Entities["Email Address"] != 1 {
+		t.Fatalf("after Record: %+v %v", s, err)
+	}
+
+	// Close ends open streams, so they don't hold up a server's Shutdown
+	d.Close()
+	done := make(chan error)
+	go func() {
+		_, err := next()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("stream sent another snapshot after Close")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("stream still open after Close")
+	}
+}
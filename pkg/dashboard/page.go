@@ -0,0 +1,114 @@
+package dashboard
+
+// pageHTML is the dashboard. It holds no data: it opens events, and
+// renders each snapshot with textContent, never as markup.
+const pageHTML = `<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Blindfold detection dashboard</title>
+<style>
+body { font: 14px system-ui, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; margin-bottom: .2em; }
+h2 { font-size: 1.05em; margin: 1.5em 0 .5em; }
+.muted { color: #777; }
+.cards { display: flex; gap: 1em; flex-wrap: wrap; }
+.card { border: 1px solid #ddd; border-radius: 6px; padding: .8em 1.2em; min-width: 9em; }
+.card b { display: block; font-size: 1.8em; }
+.cols { display: flex; gap: 3em; flex-wrap: wrap; }
+table { border-collapse: collapse; }
+td, th { border-bottom: 1px solid #eee; padding: .35em .8em .35em 0; text-align: left; vertical-align: top; }
+td.n { text-align: right; font-variant-numeric: tabular-nums; }
+.bar { background: #4a7bd0; height: .7em; border-radius: 2px; }
+.rejected { color: #b00; }
+.flash { animation: flash 1s; }
+@keyframes flash { from { background: #fff3b0; } }
+</style>
+</head>
+<body>
+<h1>Blindfold detection dashboard</h1>
+<p class="muted"><span id="status">connecting…</span> · since <span id="started"></span>. Counts hold entity types and token names only, never values.</p>
+<div class="cards">
+  <div class="card"><b id="requests">0</b>requests</div>
+  <div class="card"><b id="forwarded">0</b>forwarded</div>
+  <div class="card"><b id="rejected">0</b>rejected</div>
+  <div class="card"><b id="fallbacks">0</b>fallbacks to local mode</div>
+  <div class="card"><b id="failovers">0</b>failovers</div>
+  <div class="card"><b id="detok">0</b>unresolved placeholders</div>
+</div>
+<div class="cols">
+  <div><h2>Entities detected</h2><table id="entities"></table></div>
+  <div><h2>Policies</h2><table id="policies"></table></div>
+  <div><h2>Fallbacks by operation</h2><table id="fallbackOps"></table></div>
+</div>
+<h2>Recent activity</h2>
+<table>
+<thead><tr><th>Time</th><th>Operation</th><th>Model</th><th>Policy</th><th>Entities</th><th>Tokens</th><th>Outcome</th></tr></thead>
+<tbody id="recent"></tbody>
+</table>
+<script>
+const $ = id => document.getElementById(id);
+const sum = m => Object.values(m || {}).reduce((a, b) => a + b, 0);
+const sorted = m => Object.entries(m || {}).sort((a, b) => b[1] - a[1] || a[0].localeCompare(b[0]));
+
+function set(id, value) {
+  const el = $(id), text = String(value);
+  if (el.textContent !== text) {
+    el.textContent = text;
+    el.classList.remove("flash"); void el.offsetWidth; el.classList.add("flash");
+  }
+}
+
+function row(tbody, cells) {
+  const tr = tbody.insertRow();
+  for (const [text, cls] of cells) {
+    const td = tr.insertCell();
+    td.textContent = text;
+    if (cls) td.className = cls;
+  }
+  return tr;
+}
+
+function counts(id, m, bars) {
+  const t = $(id), entries = sorted(m), max = entries.length ? entries[0][1] : 0;
+  t.replaceChildren();
+  if (!entries.length) row(t, [["none yet", "muted"]]);
+  for (const [name, n] of entries) {
+    const tr = row(t, [[name], [n, "n"]]);
+    if (bars) {
+      const bar = document.createElement("div");
+      bar.className = "bar";
+      bar.style.width = Math.max(2, 160 * n / max) + "px";
+      tr.insertCell().appendChild(bar);
+    }
+  }
+}
+
+function render(s) {
+  $("started").textContent = new Date(s.started).toLocaleString();
+  set("requests", s.requests);
+  set("forwarded", (s.outcomes || {}).forwarded || 0);
+  set("rejected", (s.outcomes || {}).rejected || 0);
+  set("fallbacks", sum(s.fallbacks));
+  set("failovers", sum(s.failovers));
+  set("detok", s.detokenize_failures);
+  counts("entities", s.entities, true);
+  counts("policies", s.policies);
+  counts("fallbackOps", s.fallbacks);
+  const t = $("recent");
+  t.replaceChildren();
+  for (const a of s.recent || []) {
+    const ents = sorted(a.entities).map(([k, n]) => k + " ×" + n).join(", ") || "–";
+    row(t, [[new Date(a.time).toLocaleTimeString()], [a.operation], [a.model || "–"], [a.policy || "–"],
+            [ents], [a.tokens, "n"], [a.outcome, a.outcome === "rejected" ? "rejected" : ""]]);
+  }
+}
+
+const events = new EventSource("events");
+events.onopen = () => $("status").textContent = "live";
+events.onerror = () => $("status").textContent = "reconnecting…";
+events.onmessage = e => render(JSON.parse(e.data));
+</script>
+</body>
+</html>
+`
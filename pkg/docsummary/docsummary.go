@@ -0,0 +1,174 @@
+// Package docsummary is the tokenize-summarize-restore core of the
+// document connector recipes: it takes the text of one file from a
+// shared folder (a Google Doc, a SharePoint or OneDrive file), tokenizes
+// it, asks the model for a summary, checks the summary and restores it
+// for writing back next to the file.
+//
+//	s := docsummary.New(bf, llm, openai.GPT4oMini)
+//	sum, err := s.Summarize(ctx, "Q3 customer escalations", text)
+//	// sum.Tokenized is what the model wrote; sum.Text goes back to the folder
+//
+// The text is tokenized in overlapping windows (pkg/chunk), so a value on
+// a window edge is still found, and the whole file gets one mapping: the
+// same value is the same token in every section. Files longer than
+// MaxChars after tokenizing are summarized section by section, then from
+// the section summaries. Only the storage client differs between recipes.
+package docsummary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/chunk"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+const placeholderRule = " Values like <Person_1> and <Email Address_1> are placeholders for real data: " +
+	"copy them exactly as written and never guess what they stand for."
+
+// The system prompts a Summarizer starts with. Their first words name
+// them, which is what the recipes' demo models dispatch on.
+const (
+	DefaultSystem = "File summary. Summarize one file from a shared folder for the people who work in it, in at most " +
+		"three sentences: what it is, the main points or figures, and any open follow-ups. A CSV file is one sheet of " +
+		"a spreadsheet." + placeholderRule
+	DefaultSectionSystem = "Section summary. Summarize one section of a longer file in two sentences, keeping every fact " +
+		"a summary of the whole file would need." + placeholderRule
+)
+
+// DefaultMaxChars is the longest tokenized file summarized in one call.
+const DefaultMaxChars = 8000
+
+// ErrUnresolved is returned, wrapped, when the summary has tokens the
+// file never had. Restoring it would leave placeholders in the folder.
+var ErrUnresolved error = &bferrors.Error{Kind: bferrors.MappingMissing, Err: errors.New("docsummary: summary has tokens the file never had")}
+
+// Summarizer summarizes files. Set its fields before the first Summarize
+// to change the prompts or the section size.
+type Summarizer struct {
+	System        string
+	SectionSystem string
+	MaxChars      int // longer tokenized files are summarized section by section; <= 0 never splits
+
+	bf      bfclient.Client
+	llm     bfclient.ChatCompleter
+	model   string
+	chunker chunk.Chunker
+}
+
+// New returns a Summarizer that tokenizes with bf and asks llm, which may
+// be an *openai.Client or a resilience.ChatClient.
+func New(bf bfclient.Client, llm bfclient.ChatCompleter, model string) *Summarizer {
+	return &Summarizer{System: DefaultSystem, SectionSystem: DefaultSectionSystem, MaxChars: DefaultMaxChars,
+		bf: bf, llm: llm, model: model, chunker: chunk.Chunker{Size: 32 << 10, Overlap: 512}}
+}
+
+// Summary is one file's summary.
+type Summary struct {
+	// Tokenized is the summary as the model wrote it.
+	Tokenized string
+	// Text is Tokenized with the values restored.
+	Text string
+	// Entities counts the entities tokenized in the file, by type.
+	Entities map[string]int
+	// Sections is how many parts the file was summarized in.
+	Sections int
+}
+
+// Summarize tokenizes a file's text, summarizes it and restores the
+// summary. name is shown to the model as it is, so it shouldn't hold
+// personal data.
+func (s *Summarizer) Summarize(ctx context.Context, name, text string) (*Summary, error) {
+	res, err := s.chunker.Tokenize(ctx, s.bf, text)
+	if err != nil {
+		return nil, fmt.Errorf("tokenize: %w", err)
+	}
+	sum := &Summary{Entities: make(map[string]int)}
+	for _, e := range res.DetectedEntities {
+		sum.Entities[e.Type]++
+	}
+
+	parts := sections(res.Text, s.MaxChars)
+	sum.Sections = len(parts)
+	body := res.Text
+	if len(parts) > 1 {
+		var sums []string
+		for i, part := range parts {
+			out, err := s.ask(ctx, s.SectionSystem, fmt.Sprintf("File: %s, section %d of %d\n\n%s", name, i+1, len(parts), part))
+			if err != nil {
+				return nil, fmt.Errorf("section %d: %w", i+1, err)
+			}
+			sums = append(sums, out)
+		}
+		body = strings.Join(sums, "\n\n")
+	}
+	if sum.Tokenized, err = s.ask(ctx, s.System, "File: "+name+"\n\n"+body); err != nil {
+		return nil, err
+	}
+	if bad := mapping.Unresolved(sum.Tokenized, res.Mapping); len(bad) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnresolved, strings.Join(bad, ", "))
+	}
+	sum.Text = s.bf.Detokenize(sum.Tokenized, res.Mapping).Text
+	return sum, nil
+}
+
+func (s *Summarizer) ask(ctx context.Context, system, user string) (string, error) {
+	res, err := s.llm.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:       s.model,
+		Temperature: 0,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: system},
+			{Role: openai.ChatMessageRoleUser, Content: user},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("openai: %w", err)
+	}
+	if len(res.Choices) == 0 {
+		return "", fmt.Errorf("openai: empty response")
+	}
+	return strings.TrimSpace(res.Choices[0].Message.Content), nil
+}
+
+// sections splits tokenized text into parts of at most max bytes at
+// paragraph breaks, then at line breaks, which never fall inside a
+// token. A single line longer than max is a part of its own.
+func sections(text string, max int) []string {
+	if max <= 0 || len(text) <= max {
+		return []string{text}
+	}
+	var parts []string
+	var cur strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			parts = append(parts, s)
+		}
+		cur.Reset()
+	}
+	add := func(piece, sep string) {
+		if cur.Len() > 0 && cur.Len()+len(sep)+len(piece) > max {
+			flush()
+		}
+		if cur.Len() > 0 {
+			cur.WriteString(sep)
+		}
+		cur.WriteString(piece)
+	}
+	for _, para := range strings.Split(text, "\n\n") {
+		if len(para) <= max {
+			add(para, "\n\n")
+			continue
+		}
+		for _, line := range strings.Split(para, "\n") {
+			add(line, "\n")
+		}
+	}
+	flush()
+	return parts
+}
@@ -0,0 +1,75 @@
+package docsummary
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+var local = blindfold.New(blindfold.WithMode("local"))
+
+// scripted replies to each system prompt with a fixed text and keeps the
+// prompts it was sent.
+type scripted struct {
+	replies map[string]string
+	prompts []string
+}
+
+func (s *scripted) CreateChatCompletion(_ context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	s.prompts = append(s.prompts, req.Messages[1].Content)
+	reply := s.replies[req.Messages[0].Content]
+	return openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: reply}}}}, nil
+}
+
+const escalations = "1. Double charge. jane.doe@example.com was charged twice on card 4111 1111 1111 1111.\n\n" +
+	"2. Callback. Call 617-555-0123 back, and copy jane.doe@example.com."
+
+func TestSummarize(t *testing.T) {
+	llm := &scripted{replies: map[string]string{DefaultSystem: " Refund <Credit Card Number_1> and confirm to <Email Address_1>.\n"}}
+	sum, err := New(local, llm, "m").Summarize(context.Background(), "Escalations", escalations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "File: Escalations\n\n1. Double charge. <Email Address_1> was charged twice on card <Credit Card Number_1>.\n\n" +
+		"2. Callback. Call <Phone Number_1> back, and copy <Email Address_1>."
+	if len(llm.prompts) != 1 || llm.prompts[0] != want {
+		t.Errorf("prompts = %q", llm.prompts)
+	}
+	if sum.Text != "Refund 4111 1111 1111 1111 and confirm to jane.doe@example.com." || sum.Sections != 1 {
+		t.Errorf("summary = %+v", sum)
+	}
+	if sum.Entities["Email Address"] != 2 || sum.Entities["Phone Number"] != 1 {
+		t.Errorf("entities = %v", sum.Entities)
+	}
+}
+
+func TestSummarizeSections(t *testing.T) {
+	llm := &scripted{replies: map[string]string{DefaultSectionSystem: "Section about <Email Address_1>.", DefaultSystem: "Both mention <Email Address_1>."}}
+	s := New(local, llm, "m")
+	s.MaxChars = 80
+	sum, err := s.Summarize(context.Background(), "Escalations", escalations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each part is cut at the paragraph break, and the email is one token
+	// in both
+	if sum.Sections != 2 || len(llm.prompts) != 3 || !strings.HasPrefix(llm.prompts[1], "File: Escalations, section 2 of 2\n\n2. Callback.") ||
+		!strings.HasSuffix(llm.prompts[1], "copy <Email Address_1>.") {
+		t.Fatalf("prompts = %q", llm.prompts)
+	}
+	if llm.prompts[2] != "File: Escalations\n\nSection about <Email Address_1>.\n\nSection about <Email Address_1>." || sum.Text != "Both mention jane.doe@example.com." {
+		t.Errorf("final prompt %q, summary %q", llm.prompts[2], sum.Text)
+	}
+}
+
+func TestSummarizeUnresolved(t *testing.T) {
+	llm := &scripted{replies: map[string]string{DefaultSystem: "Call <Person_1>."}}
+	_, err := New(local, llm, "m").Summarize(context.Background(), "Escalations", escalations)
+	if !errors.Is(err, ErrUnresolved) || !strings.Contains(err.Error(), "<Person_1>") {
+		t.Fatalf("err = %v", err)
+	}
+}
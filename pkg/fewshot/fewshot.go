@@ -0,0 +1,203 @@
+// Package fewshot provides few-shot prompt examples made entirely of
+// synthetic identities, and helpers to splice them into prompts.
+//
+// A few-shot prompt works best with examples that look like the real
+// input, and the quickest way to get one is to paste a real ticket. That
+// ticket then goes out with every request, on every call, to every
+// provider the prompt is ever used with. The examples here are curated
+// templates filled by pkg/genpii: reserved email domains, 555-01xx
+// phone numbers, test card numbers, and names from common name lists.
+// Generation is deterministic for a seed, so a prompt is stable between
+// runs and its prefix stays cacheable.
+//
+//	shots, _ := fewshot.Generate("triage", 3, 1)
+//	msgs := fewshot.Messages(system, shots, tokenizedTicket)
+//
+// When the real input is tokenized, call Placeholders on the examples
+// first, so they show the model the same <Email Address_1> shape it will
+// be asked about.
+package fewshot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/genpii"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// Template is an example with pkg/genpii slots ({Person:1},
+// {Email Address}). Input and Output share slot groups, so a reply that
+// repeats the customer's email gets the same value.
+type Template struct {
+	Input  string
+	Output string
+}
+
+// Library holds the curated templates, by task.
+var Library = map[string][]Template{
+	"support-reply": {
+		{"Hi, I'm {Person:1}. I was charged twice for order 4471. Please refund it and send the receipt to {Email Address:1}.",
+			"Hi {Person:1}, sorry about the double charge on order 4471. I've refunded the duplicate, and the receipt is on its way to {Email Address:1}. It can take up to 5 business days to show."},
+		{"Please call me back on {Phone Number:1} about my delivery to {Address:1}, it's two days late. – {Person:1}",
+			"Hi {Person:1}, I'm sorry your delivery to {Address:1} is late. A courier coordinator will call you on {Phone Number:1} today with a new time."},
+		{"I can't log in since this morning and I see alerts from {IP Address:1}. Reset link to {Email Address:1} please.",
+			"I've signed out all sessions, blocked {IP Address:1}, and sent a reset link to {Email Address:1}. It expires in 30 minutes."},
+		{"Close my account please. I'm {Person:1}, born {Date of Birth:1}. Confirm to {Email Address:1}.",
+			"Hi {Person:1}, your account is scheduled for closure. We'll confirm to {Email Address:1} once your data has been erased, within 30 days."},
+	},
+	"triage": {
+		{"Card {Credit Card Number} was charged twice for the same order and nobody answers at {Phone Number}!!",
+			`{"category":"billing","priority":"high","needs_human":true}`},
+		{"Hi, could you update my email to {Email Address}? Thanks, {Person}",
+			`{"category":"account","priority":"low","needs_human":false}`},
+		{"Package for {Person} at {Address} never arrived, tracking says delivered.",
+			`{"category":"shipping","priority":"medium","needs_human":true}`},
+		{"Someone logged in from {IP Address} and changed my password. My SSN {Social Security Number} is on that account.",
+			`{"category":"security","priority":"urgent","needs_human":true}`},
+	},
+	"extract": {
+		{"Hello, this is {Person:1}. You can reach me at {Email Address:1} or on {Phone Number:1} after 5pm.",
+			`{"name":"{Person:1}","email":"{Email Address:1}","phone":"{Phone Number:1}"}`},
+		{"Ship the replacement to {Person:1}, {Address:1}. Questions to {Email Address:1}.",
+			`{"name":"{Person:1}","email":"{Email Address:1}","address":"{Address:1}"}`},
+		{"Caller {Person:1} (DOB {Date of Birth:1}) asked us to use {Phone Number:1} from now on.",
+			`{"name":"{Person:1}","phone":"{Phone Number:1}","date_of_birth":"{Date of Birth:1}"}`},
+	},
+	"summarize-call": {
+		{"Agent: Thanks for calling, who am I speaking with?\nCaller: {Person:1}. My card {Credit Card Number:1} got declined at checkout.\nAgent: I see a fraud hold. I've lifted it; please try again.\nCaller: Works now. Send me a note at {Email Address:1}?\nAgent: Done.",
+			"{Person:1} called about a declined card ({Credit Card Number:1}). A fraud hold was lifted and the payment went through. Confirmation sent to {Email Address:1}."},
+		{"Agent: Billing, how can I help?\nCaller: This is {Person:1}, I moved to {Address:1} and my invoices still go to the old place.\nAgent: Updated. Anything else?\nCaller: Call {Phone Number:1} if there's a problem.",
+			"{Person:1} moved to {Address:1}; the billing address was updated. Callback number: {Phone Number:1}. No further issues."},
+	},
+}
+
+// Tasks returns the tasks in the library, in alphabetical order.
+func Tasks() []string {
+	names := make([]string, 0, len(Library))
+	for t := range Library {
+		names = append(names, t)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Example is a filled template.
+type Example struct {
+	Task   string
+	Input  string
+	Output string
+	// InputEntities and OutputEntities annotate every synthetic value,
+	// with offsets into Input and Output.
+	InputEntities  []genpii.Span
+	OutputEntities []genpii.Span
+}
+
+// sep joins Input and Output for filling, so their slot groups are shared.
+const sep = "\x1f"
+
+// Generate returns n examples for task, cycling through its templates
+// with fresh identities for each example. The same seed gives the same
+// examples.
+func Generate(task string, n int, seed int64) ([]Example, error) {
+	templates, ok := Library[task]
+	if !ok {
+		return nil, fmt.Errorf("fewshot: unknown task %q (have %s)", task, strings.Join(Tasks(), ", "))
+	}
+	g := genpii.New(seed)
+	out := make([]Example, n)
+	for i := range out {
+		t := templates[i%len(templates)]
+		text, spans := g.Fill(t.Input + sep + t.Output)
+		in, outText, _ := strings.Cut(text, sep)
+		ex := Example{Task: task, Input: in, Output: outText}
+		cut := len(in) + len(sep)
+		for _, s := range spans {
+			if s.End <= len(in) {
+				ex.InputEntities = append(ex.InputEntities, s)
+			} else {
+				s.Start, s.End = s.Start-cut, s.End-cut
+				ex.OutputEntities = append(ex.OutputEntities, s)
+			}
+		}
+		out[i] = ex
+	}
+	return out, nil
+}
+
+// Placeholders returns e with every synthetic value replaced by a
+// Blindfold-style token, numbered per type in order of appearance and
+// shared between Input and Output. Values are swapped by their
+// annotations, not searched for.
+func (e Example) Placeholders() Example {
+	tokens := make(map[string]string) // type + value → token
+	counts := make(map[string]int)
+	replace := func(text string, spans []genpii.Span) (string, []genpii.Span) {
+		var b strings.Builder
+		out := make([]genpii.Span, 0, len(spans))
+		last := 0
+		for _, s := range spans {
+			key := s.Type + "\x00" + s.Text
+			tok, ok := tokens[key]
+			if !ok {
+				counts[s.Type]++
+				tok = mapping.FormatToken(s.Type, counts[s.Type])
+				tokens[key] = tok
+			}
+			b.WriteString(text[last:s.Start])
+			start := b.Len()
+			b.WriteString(tok)
+			out = append(out, genpii.Span{Type: s.Type, Start: start, End: b.Len(), Text: tok})
+			last = s.End
+		}
+		b.WriteString(text[last:])
+		return b.String(), out
+	}
+	p := e
+	p.Input, p.InputEntities = replace(e.Input, e.InputEntities)
+	p.Output, p.OutputEntities = replace(e.Output, e.OutputEntities)
+	return p
+}
+
+// Placeholders applies Example.Placeholders to each example.
+func Placeholders(examples []Example) []Example {
+	out := make([]Example, len(examples))
+	for i, e := range examples {
+		out[i] = e.Placeholders()
+	}
+	return out
+}
+
+// Messages builds a chat prompt: the system message, each example as a
+// user turn and an assistant turn, then query as the last user turn.
+func Messages(system string, examples []Example, query string) []openai.ChatCompletionMessage {
+	msgs := make([]openai.ChatCompletionMessage, 0, 2+2*len(examples))
+	if system != "" {
+		msgs = append(msgs, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: system})
+	}
+	for _, e := range examples {
+		msgs = append(msgs,
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: e.Input},
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: e.Output})
+	}
+	return append(msgs, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: query})
+}
+
+// Prompt builds a single-text prompt for completion-style models and
+// templates: the instructions, each example as an Input/Output pair, and
+// query with an empty Output for the model to fill.
+func Prompt(instructions string, examples []Example, query string) string {
+	var b strings.Builder
+	if instructions != "" {
+		b.WriteString(strings.TrimSpace(instructions))
+		b.WriteString("\n\n")
+	}
+	for _, e := range examples {
+		fmt.Fprintf(&b, "Input: %s\nOutput: %s\n\n", e.Input, e.Output)
+	}
+	fmt.Fprintf(&b, "Input: %s\nOutput:", query)
+	return b.String()
+}
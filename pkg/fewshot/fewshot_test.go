@@ -0,0 +1,73 @@
+package fewshot
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/genpii"
+)
+
+var slot = regexp.MustCompile(`\{[A-Z][A-Za-z ]*(:\d+)?\}`)
+
+func checkSpans(t *testing.T, text string, spans []genpii.Span) {
+	t.Helper()
+	for _, s := range spans {
+		if text[s.Start:s.End] != s.Text {
+			t.Errorf("span %+v doesn't match %q", s, text[s.Start:s.End])
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	for _, task := range Tasks() {
+		n := 2 * len(Library[task])
+		got, err := Generate(task, n, 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range got {
+			if slot.MatchString(e.Input+e.Output) || strings.Contains(e.Input+e.Output, sep) {
+				t.Errorf("%s: unfilled slot in %q / %q", task, e.Input, e.Output)
+			}
+			checkSpans(t, e.Input, e.InputEntities)
+			checkSpans(t, e.Output, e.OutputEntities)
+		}
+		again, _ := Generate(task, n, 7)
+		if !reflect.DeepEqual(got, again) {
+			t.Errorf("%s: not deterministic", task)
+		}
+	}
+	if _, err := Generate("nope", 1, 1); err == nil {
+		t.Error("unknown task: no error")
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	shots, err := Generate("extract", 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := shots[0].Placeholders()
+	want := `{"name":"<Person_1>","email":"<Email Address_1>","phone":"<Phone Number_1>"}`
+	if p.Output != want {
+		t.Errorf("output = %s, want %s", p.Output, want)
+	}
+	if !strings.HasPrefix(p.Input, "Hello, this is <Person_1>. You can reach me at <Email Address_1>") {
+		t.Errorf("input = %s", p.Input)
+	}
+	checkSpans(t, p.Input, p.InputEntities)
+	checkSpans(t, p.Output, p.OutputEntities)
+}
+
+func TestMessages(t *testing.T) {
+	shots, _ := Generate("triage", 2, 1)
+	msgs := Messages("Classify the ticket.", shots, "query")
+	if len(msgs) != 6 || msgs[1].Content != shots[0].Input || msgs[4].Content != shots[1].Output || msgs[5].Content != "query" {
+		t.Errorf("messages = %+v", msgs)
+	}
+	if p := Prompt("Classify.", shots[:1], "q"); !strings.HasSuffix(p, "Input: q\nOutput:") {
+		t.Errorf("prompt = %q", p)
+	}
+}
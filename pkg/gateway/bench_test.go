@@ -0,0 +1,98 @@
+package gateway
+
+// Profile of the hot path, local mode, one request of four messages (360
+// bytes of text, seven entities) against an in-process upstream:
+//
+//	go test -run - -bench Chat -cpuprofile cpu.out -memprofile mem.out ./pkg/gateway
+//
+//	BenchmarkChat        600 µs/op   32 KB/op    376 allocs/op
+//	BenchmarkChatStream  700 µs/op  100 KB/op   1240 allocs/op
+//
+// CPU, non-streaming: over 90% is PIIScanner.Tokenize, almost all of it
+// regexp backtracking in the SDK's detectors. Parsing the body, merging
+// mappings, re-encoding and restoring the reply come to under 10%. The
+// gateway's own code is not where latency comes from; the size of the
+// text is, since every pattern scans all of it.
+//
+// CPU, streaming: Tokenize drops to about 68%. Relaying the stream takes
+// the other 27%, mostly decoding each SSE chunk into a map and encoding
+// it again (rewriteChunk, marshal), which also accounts for the threefold
+// allocations: per chunk, a bufio line, a JSON decoder and its map.
+//
+// In cloud mode the regexps run on Blindfold's side and each Tokenize is
+// a round trip instead. tokenizeTexts makes them one after another, so a
+// request's tokenize stage grows with its message count. Compare
+// stage_ns["tokenize"] per request in /debug/vars with
+// blindfold_detection_duration_seconds per call in /metrics to tell a
+// slow API from a long conversation.
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+const benchRequest = `{"model":"gpt-4o-mini","messages":[` +
+	`{"role":"system","content":"You are a support agent. Keep placeholders as they are."},` +
+	`{"role":"user","content":"Hi, I'm Sarah Chen. My card 4532-7562-9102-3456 was charged twice. Reach me at sarah.chen@acme.com or +1 415-555-0134."},` +
+	`{"role":"assistant","content":"Sorry to hear that. Which order was it?"},` +
+	`{"role":"user","content":"Order 8812, shipped to 221B Baker Street. Please reply to sarah.chen@acme.com only."}]%s}`
+
+// upstreamTransport answers in process, so a benchmark measures the
+// gateway rather than the network.
+type upstreamTransport struct{ h http.Handler }
+
+func (t upstreamTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.h.ServeHTTP(rec, r)
+	return rec.Result(), nil
+}
+
+// fakeCompletions replies with the placeholders of the last message,
+// whole or as an SSE stream of 6-byte deltas.
+func fakeCompletions(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	reply := `Refund issued. Confirmation sent to <Email Address_1>; we will call <Phone Number_1> if needed.`
+	if !strings.Contains(string(body), `"stream":true`) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"c1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":%q},"finish_reason":"stop"}]}`, reply)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	for i := 0; i < len(reply); i += 6 {
+		fmt.Fprintf(w, "data: {\"id\":\"c1\",\"object\":\"chat.completion.chunk\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%q},\"finish_reason\":null}]}\n\n", reply[i:min(i+6, len(reply))])
+	}
+	fmt.Fprint(w, "data: {\"id\":\"c1\",\"object\":\"chat.completion.chunk\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+}
+
+func benchmarkChat(b *testing.B, stream bool) {
+	g := New(Config{
+		Blindfold:    blindfold.New(blindfold.WithMode("local")),
+		Upstream:     "http://upstream.test/v1",
+		HTTPClient:   &http.Client{Transport: upstreamTransport{http.HandlerFunc(fakeCompletions)}},
+		Stats:        NewStats(),
+		ServerTiming: true,
+	})
+	extra := ""
+	if stream {
+		extra = `,"stream":true`
+	}
+	body := fmt.Sprintf(benchRequest, extra)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "sarah.chen@acme.com") {
+			b.Fatalf("%d %s", rec.Code, rec.Body)
+		}
+	}
+}
+
+func BenchmarkChat(b *testing.B)       { benchmarkChat(b, false) }
+func BenchmarkChatStream(b *testing.B) { benchmarkChat(b, true) }
@@ -0,0 +1,279 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/audit"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/resilience"
+)
+
+// chatCompletions handles POST /v1/chat/completions.
+func (g *Gateway) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "gateway: use POST")
+		return
+	}
+	t := g.begin(w, "chat.completions")
+	defer t.end()
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&body); err != nil {
+		t.fail("decode")
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "gateway: invalid JSON body: "+err.Error())
+		return
+	}
+	var stream bool
+	if raw, ok := body["stream"]; ok {
+		_ = json.Unmarshal(raw, &stream)
+	}
+
+	var model string
+	_ = json.Unmarshal(body["model"], &model)
+	t.stage("decode")
+
+	// 1. Tokenize every message; fail closed if Blindfold is unavailable.
+	// A caller is waiting on the reply, so a multi-region client may hedge
+	mp, entities, err := g.tokenizeMessages(resilience.LatencySensitive(r.Context()), body)
+	if err != nil {
+		t.fail("tokenize")
+		g.audit(r, audit.Event{Operation: "chat.completions", Model: model, Outcome: audit.Rejected})
+		writeBlindfoldError(w, err)
+		return
+	}
+	t.stage("tokenize")
+
+	// 2. Record the audit event, then forward the tokenized request upstream
+	payload := marshal(body)
+	event := audit.NewEvent("", "chat.completions", g.cfg.Policy, entities, mp)
+	event.Model, event.PayloadSHA256, event.Outcome = model, audit.HashPayload(payload), audit.Forwarded
+	if err := g.audit(r, event); err != nil {
+		t.fail("audit")
+		writeError(w, http.StatusInternalServerError, "audit_error", "gateway: audit: "+err.Error())
+		return
+	}
+	t.stage("audit")
+	resp, err := g.forward(r, "/chat/completions", payload)
+	if err != nil {
+		t.fail("upstream")
+		writeError(w, http.StatusBadGateway, "upstream_error", "gateway: upstream: "+err.Error())
+		return
+	}
+	defer resp.Body.Close()
+	t.stage("upstream")
+	defer t.stage("respond")
+	if resp.StatusCode != http.StatusOK {
+		copyResponse(w, resp)
+		return
+	}
+
+	// 3. Detokenize the response, chunk by chunk when streaming
+	if stream && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		g.streamChat(w, resp, mp, t)
+		return
+	}
+	g.restoreChat(w, resp, mp, t)
+}
+
+// tokenizeMessages tokenizes the text of every message in body in place —
+// string contents, "text" parts of multi-part contents, participant names
+// and the arguments of assistant tool calls — and returns the mapping for
+// the whole conversation along with the detected entities. Messages are
+// tokenized separately and their mappings merged, so a value keeps one
+// token across messages, and arguments restored in an earlier reply are
+// tokenized again before going back upstream.
+func (g *Gateway) tokenizeMessages(ctx context.Context, body map[string]json.RawMessage) (map[string]string, []blindfold.DetectedEntity, error) {
+	var messages []map[string]json.RawMessage
+	if err := json.Unmarshal(body["messages"], &messages); err != nil {
+		return nil, nil, fmt.Errorf("messages: %w", err)
+	}
+
+	var texts []*string
+	var commits []func()
+	// field queues the string at obj[key], if any, and runs done once it
+	// has been written back.
+	field := func(obj map[string]json.RawMessage, key string, done func()) {
+		var s string
+		if json.Unmarshal(obj[key], &s) != nil || s == "" {
+			return
+		}
+		t := &s
+		texts = append(texts, t)
+		commits = append(commits, func() {
+			obj[key] = marshal(*t)
+			if done != nil {
+				done()
+			}
+		})
+	}
+	for _, msg := range messages {
+		msg := msg
+		field(msg, "name", nil)
+		field(msg, "content", nil)
+		var calls []map[string]json.RawMessage
+		if json.Unmarshal(msg["tool_calls"], &calls) == nil {
+			for _, call := range calls {
+				var fn map[string]json.RawMessage
+				if json.Unmarshal(call["function"], &fn) != nil {
+					continue
+				}
+				call := call
+				field(fn, "arguments", func() {
+					call["function"] = marshal(fn)
+					msg["tool_calls"] = marshal(calls)
+				})
+			}
+		}
+		var fn map[string]json.RawMessage
+		if json.Unmarshal(msg["function_call"], &fn) == nil {
+			field(fn, "arguments", func() { msg["function_call"] = marshal(fn) })
+		}
+		var parts []map[string]json.RawMessage
+		if json.Unmarshal(msg["content"], &parts) != nil {
+			continue
+		}
+		for _, part := range parts {
+			var typ string
+			_ = json.Unmarshal(part["type"], &typ)
+			if typ == "text" {
+				field(part, "text", func() { msg["content"] = marshal(parts) })
+			}
+		}
+	}
+
+	mp, entities, err := g.tokenizeTexts(ctx, texts)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, commit := range commits {
+		commit()
+	}
+	body["messages"] = marshal(messages)
+	return mp, entities, nil
+}
+
+// tokenizeTexts tokenizes every text in place and returns their merged
+// mapping along with the detected entities. Texts are left untouched if
+// any of them fails.
+func (g *Gateway) tokenizeTexts(ctx context.Context, texts []*string) (map[string]string, []blindfold.DetectedEntity, error) {
+	tokenized := make([]string, len(texts))
+	mappings := make([]map[string]string, len(texts))
+	var entities []blindfold.DetectedEntity
+	for i, t := range texts {
+		res, err := g.cfg.Blindfold.Tokenize(ctx, *t)
+		if err != nil {
+			return nil, nil, err
+		}
+		tokenized[i], mappings[i] = res.Text, res.Mapping
+		entities = append(entities, res.DetectedEntities...)
+	}
+	merged := mapping.Merge(mappings...)
+	for i, t := range texts {
+		*t = merged.Rewrite(i, tokenized[i])
+	}
+	return merged.Mapping, entities, nil
+}
+
+// forward sends payload to the upstream endpoint path.
+func (g *Gateway) forward(r *http.Request, path string, payload []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, g.cfg.Upstream+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if auth := g.authorization(r); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	for _, h := range []string{"Accept", "OpenAI-Organization", "OpenAI-Project"} {
+		if v := r.Header.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+	return g.cfg.HTTPClient.Do(req)
+}
+
+// restoreChat detokenizes a non-streaming chat completion: message
+// contents and tool-call arguments of every choice.
+func (g *Gateway) restoreChat(w http.ResponseWriter, resp *http.Response, mp map[string]string, t *timer) {
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	var completion map[string]any
+	if err := dec.Decode(&completion); err != nil {
+		writeError(w, http.StatusBadGateway, "upstream_error", "gateway: invalid upstream response: "+err.Error())
+		return
+	}
+
+	start := time.Now()
+	choices, _ := completion["choices"].([]any)
+	for _, c := range choices {
+		choice, _ := c.(map[string]any)
+		msg, _ := choice["message"].(map[string]any)
+		if msg == nil {
+			continue
+		}
+		if content, ok := msg["content"].(string); ok {
+			g.observeDetokenize(content, mp)
+			msg["content"] = mapping.Detokenize(content, mp)
+		}
+		calls, _ := msg["tool_calls"].([]any)
+		for _, tc := range calls {
+			call, _ := tc.(map[string]any)
+			fn, _ := call["function"].(map[string]any)
+			if args, ok := fn["arguments"].(string); ok {
+				fn["arguments"] = mapping.Detokenize(args, jsonEscaped(mp))
+			}
+		}
+	}
+	t.detokenized(start)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(marshal(completion))
+}
+
+func (g *Gateway) observeDetokenize(text string, mp map[string]string) {
+	if g.cfg.Metrics != nil {
+		g.cfg.Metrics.ObserveDetokenize(text, mp)
+	}
+}
+
+// jsonEscaped returns mp with values escaped for splicing into JSON string
+// literals. Tool-call arguments are JSON text, so a restored value holding
+// a quote or backslash must not break it.
+func jsonEscaped(mp map[string]string) map[string]string {
+	out := make(map[string]string, len(mp))
+	for k, v := range mp {
+		b := marshal(v)
+		out[k] = string(b[1 : len(b)-1])
+	}
+	return out
+}
+
+// copyResponse relays an upstream response unchanged.
+func copyResponse(w http.ResponseWriter, resp *http.Response) {
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+// marshal encodes v as JSON without HTML escaping, so tokens stay readable
+// as <Person_1> rather than \u003cPerson_1\u003e.
+func marshal(v any) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		panic(err) // only JSON-decoded values and strings are encoded
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n")
+}
@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fake"
+)
+
+// A second turn carrying the assistant's earlier tool call, restored by the
+// gateway, and the tool's result: nothing in it may reach the provider.
+const toolHistoryRequest = `{"model":"gpt-4o-mini","messages":[
+	{"role":"user","name":"jane_doe","content":"Look up my account, I'm Jane Doe."},
+	{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function",
+		"function":{"name":"find_customer","arguments":"{\"email\":\"jane@example.com\",\"name\":\"Jane Doe\"}"}}]},
+	{"role":"tool","tool_call_id":"call_1","content":"{\"customer\":\"Jane Doe\",\"phone\":\"+1 415-555-0134\"}"},
+	{"role":"user","content":[{"type":"text","text":"Email jane@example.com the summary."}]}]}`
+
+func TestChatForwardsNoRawValues(t *testing.T) {
+	var forwarded string
+	upstream := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		forwarded = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"c1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":null,`+
+			`"tool_calls":[{"id":"call_2","type":"function","function":{"name":"send_email","arguments":"{\"to\":\"<Email Address_1>\",\"name\":\"<Person_2>\"}"}}]},"finish_reason":"tool_calls"}]}`)
+	}
+	g := New(Config{
+		Blindfold:  fake.New(fake.WithEntity("Person", "Jane Doe", "jane_doe"), fake.WithPatterns("us")),
+		Upstream:   "http://upstream.test/v1",
+		HTTPClient: &http.Client{Transport: upstreamTransport{http.HandlerFunc(upstream)}},
+	})
+
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(toolHistoryRequest)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+
+	for _, raw := range []string{"jane@example.com", "Jane Doe", "jane_doe", "415-555-0134"} {
+		if strings.Contains(forwarded, raw) {
+			t.Errorf("%q forwarded upstream: %s", raw, forwarded)
+		}
+	}
+	var req struct {
+		Messages []map[string]any `json:"messages"`
+	}
+	if err := json.Unmarshal([]byte(forwarded), &req); err != nil {
+		t.Fatalf("forwarded body is not JSON: %v", err)
+	}
+	// The tool-call arguments must still be JSON for the provider.
+	calls := req.Messages[1]["tool_calls"].([]any)
+	args := calls[0].(map[string]any)["function"].(map[string]any)["arguments"].(string)
+	var parsed map[string]string
+	if err := json.Unmarshal([]byte(args), &parsed); err != nil {
+		t.Fatalf("arguments %q: %v", args, err)
+	}
+	// One token per value across fields and messages.
+	if parsed["email"] != "<Email Address_1>" || parsed["name"] != "<Person_2>" {
+		t.Errorf("arguments = %v", parsed)
+	}
+	if got := req.Messages[3]["content"].([]any)[0].(map[string]any)["text"]; got != "Email <Email Address_1> the summary." {
+		t.Errorf("text part = %q", got)
+	}
+
+	// The reply's arguments are restored for the caller.
+	if want := `{\"to\":\"jane@example.com\",\"name\":\"Jane Doe\"}`; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("reply %s lacks %s", rec.Body, want)
+	}
+}
@@ -0,0 +1,183 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/audit"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// extractor finds the text fields of a request body to tokenize. The
+// returned commit writes the tokenized texts back into body.
+type extractor func(body map[string]json.RawMessage) (texts []*string, commit func(), err error)
+
+// restorer relays a successful upstream response, detokenizing it.
+type restorer func(g *Gateway, w http.ResponseWriter, resp *http.Response, mp map[string]string, t *timer)
+
+// embeddings handles POST /v1/embeddings. Vectors are computed from the
+// tokenized input, so there is nothing to restore in the response.
+func (g *Gateway) embeddings(w http.ResponseWriter, r *http.Request) {
+	g.protect(w, r, "embeddings", "/embeddings", inputTexts, nil)
+}
+
+// moderations handles POST /v1/moderations. The response holds only
+// categories and scores.
+func (g *Gateway) moderations(w http.ResponseWriter, r *http.Request) {
+	g.protect(w, r, "moderations", "/moderations", inputTexts, nil)
+}
+
+// imageGenerations handles POST /v1/images/generations, restoring the
+// revised prompt some models return.
+func (g *Gateway) imageGenerations(w http.ResponseWriter, r *http.Request) {
+	g.protect(w, r, "images.generations", "/images/generations", promptText, restoreImages)
+}
+
+// protect tokenizes the fields extract finds, records the audit event,
+// forwards the request to path, and relays the response through restore,
+// or unchanged if restore is nil. Like chat completions, nothing is
+// forwarded if tokenization fails.
+func (g *Gateway) protect(w http.ResponseWriter, r *http.Request, op, path string, extract extractor, restore restorer) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "gateway: use POST")
+		return
+	}
+	t := g.begin(w, op)
+	defer t.end()
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&body); err != nil {
+		t.fail("decode")
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "gateway: invalid JSON body: "+err.Error())
+		return
+	}
+	var model string
+	_ = json.Unmarshal(body["model"], &model)
+
+	texts, commit, err := extract(body)
+	if err != nil {
+		t.fail("decode")
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "gateway: "+err.Error())
+		return
+	}
+	t.stage("decode")
+	mp, entities, err := g.tokenizeTexts(r.Context(), texts)
+	if err != nil {
+		t.fail("tokenize")
+		g.audit(r, audit.Event{Operation: op, Model: model, Outcome: audit.Rejected})
+		writeBlindfoldError(w, err)
+		return
+	}
+	commit()
+	t.stage("tokenize")
+
+	payload := marshal(body)
+	event := audit.NewEvent("", op, g.cfg.Policy, entities, mp)
+	event.Model, event.PayloadSHA256, event.Outcome = model, audit.HashPayload(payload), audit.Forwarded
+	if err := g.audit(r, event); err != nil {
+		t.fail("audit")
+		writeError(w, http.StatusInternalServerError, "audit_error", "gateway: audit: "+err.Error())
+		return
+	}
+	t.stage("audit")
+	resp, err := g.forward(r, path, payload)
+	if err != nil {
+		t.fail("upstream")
+		writeError(w, http.StatusBadGateway, "upstream_error", "gateway: upstream: "+err.Error())
+		return
+	}
+	defer resp.Body.Close()
+	t.stage("upstream")
+	defer t.stage("respond")
+	if resp.StatusCode != http.StatusOK || restore == nil {
+		copyResponse(w, resp)
+		return
+	}
+	restore(g, w, resp, mp, t)
+}
+
+// inputTexts extracts "input" as the embeddings and moderations endpoints
+// accept it: a string, an array of strings, or an array of content parts,
+// of which "text" parts are tokenized and image parts left as they are.
+// Token-ID arrays are refused: their text can't be inspected, so they
+// can't be protected.
+func inputTexts(body map[string]json.RawMessage) ([]*string, func(), error) {
+	raw, ok := body["input"]
+	if !ok {
+		return nil, nil, errors.New("missing input")
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return []*string{&s}, func() { body["input"] = marshal(s) }, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, nil, errors.New("input must be a string or an array")
+	}
+	var texts []*string
+	var commits []func()
+	for i := range items {
+		i, t := i, new(string)
+		if json.Unmarshal(items[i], t) == nil {
+			texts = append(texts, t)
+			commits = append(commits, func() { items[i] = marshal(*t) })
+			continue
+		}
+		var part map[string]json.RawMessage
+		if json.Unmarshal(items[i], &part) != nil {
+			return nil, nil, errors.New("token-ID input can't be protected; send text instead")
+		}
+		var typ string
+		_ = json.Unmarshal(part["type"], &typ)
+		if typ != "text" || json.Unmarshal(part["text"], t) != nil {
+			continue
+		}
+		texts = append(texts, t)
+		commits = append(commits, func() {
+			part["text"] = marshal(*t)
+			items[i] = marshal(part)
+		})
+	}
+	return texts, func() {
+		for _, c := range commits {
+			c()
+		}
+		body["input"] = marshal(items)
+	}, nil
+}
+
+// promptText extracts an image-generation "prompt".
+func promptText(body map[string]json.RawMessage) ([]*string, func(), error) {
+	var s string
+	if err := json.Unmarshal(body["prompt"], &s); err != nil {
+		return nil, nil, errors.New("prompt must be a string")
+	}
+	return []*string{&s}, func() { body["prompt"] = marshal(s) }, nil
+}
+
+// restoreImages detokenizes the revised_prompt of every generated image.
+// Image data and URLs are relayed as they are: a model asked to draw
+// "<Person_1>" may render the placeholder, and there is no restoring that.
+func restoreImages(g *Gateway, w http.ResponseWriter, resp *http.Response, mp map[string]string, t *timer) {
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	var images map[string]any
+	if err := dec.Decode(&images); err != nil {
+		writeError(w, http.StatusBadGateway, "upstream_error", "gateway: invalid upstream response: "+err.Error())
+		return
+	}
+	start := time.Now()
+	data, _ := images["data"].([]any)
+	for _, d := range data {
+		img, _ := d.(map[string]any)
+		if revised, ok := img["revised_prompt"].(string); ok {
+			g.observeDetokenize(revised, mp)
+			img["revised_prompt"] = mapping.Detokenize(revised, mp)
+		}
+	}
+	t.detokenized(start)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(marshal(images))
+}
@@ -0,0 +1,159 @@
+// Package gateway implements an OpenAI-compatible HTTP proxy that protects
+// every request on its way to the LLM provider.
+//
+// Point any OpenAI SDK at the gateway instead of api.openai.com. For each
+// chat completion the gateway tokenizes message contents, names and
+// tool-call arguments with Blindfold, forwards the tokenized request upstream, and detokenizes the response —
+// including streamed (SSE) responses, where placeholders may be split
+// across chunks. The provider only ever sees tokens; callers get plain
+// text back without changing their code.
+//
+// Embeddings and moderations inputs and image-generation prompts are
+// tokenized the same way. Any other endpoint is refused rather than
+// passed through.
+//
+// If tokenization fails the request is rejected, never forwarded
+// unprotected.
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/audit"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/metrics"
+)
+
+// DefaultUpstream is the OpenAI API base URL.
+const DefaultUpstream = "https://api.openai.com/v1"
+
+// maxBodyBytes caps request bodies read by the gateway.
+const maxBodyBytes = 10 << 20
+
+// Config configures a Gateway.
+type Config struct {
+	// Blindfold tokenizes outbound text. Required.
+	Blindfold bfclient.Client
+	// Upstream is the OpenAI-compatible base URL requests are forwarded
+	// to. Defaults to DefaultUpstream.
+	Upstream string
+	// APIKey authenticates upstream requests. If empty, the caller's
+	// Authorization header is forwarded as-is.
+	APIKey string
+	// Policy names the policy recorded in audit events. The policy itself
+	// is applied by Blindfold — wrap the client with policyconf's
+	// Policy.Wrap.
+	Policy string
+	// HTTPClient sends upstream requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// Metrics, if set, records detokenization outcomes. Wrap Blindfold
+	// with metrics.Wrap as well to record detection metrics.
+	Metrics *metrics.Metrics
+	// Audit, if set, receives one event per request before anything is
+	// sent upstream. If recording fails the request is refused.
+	Audit audit.Sink
+	// TenantHeader, if set, names the request header whose value is
+	// recorded as the tenant in audit events, such as X-Tenant-Id. The
+	// gateway doesn't check it: set it at an ingress that does.
+	TenantHeader string
+	// Stats, if set, counts requests and times their stages.
+	Stats *Stats
+	// ServerTiming reports each request's stage times (see Stats) to the
+	// caller in a Server-Timing trailer, for load tests and browser dev
+	// tools. It tells callers how long Blindfold and the upstream took, so
+	// leave it off where that is nobody else's business.
+	ServerTiming bool
+}
+
+// Gateway is an http.Handler serving the OpenAI-compatible API under /v1/.
+type Gateway struct {
+	cfg         Config
+	destination string // upstream host, for audit events
+	mux         *http.ServeMux
+}
+
+// New returns a Gateway for cfg.
+func New(cfg Config) *Gateway {
+	if cfg.Upstream == "" {
+		cfg.Upstream = DefaultUpstream
+	}
+	cfg.Upstream = strings.TrimRight(cfg.Upstream, "/")
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	g := &Gateway{cfg: cfg, destination: cfg.Upstream, mux: http.NewServeMux()}
+	if u, err := url.Parse(cfg.Upstream); err == nil && u.Host != "" {
+		g.destination = u.Host
+	}
+	g.mux.HandleFunc("/v1/chat/completions", g.chatCompletions)
+	g.mux.HandleFunc("/v1/embeddings", g.embeddings)
+	g.mux.HandleFunc("/v1/moderations", g.moderations)
+	g.mux.HandleFunc("/v1/images/generations", g.imageGenerations)
+	g.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "gateway: unsupported endpoint "+r.URL.Path)
+	})
+	return g
+}
+
+// ServeHTTP implements http.Handler.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+// audit fills in the request ID, policy and destination of e and records
+// it. The request ID comes from the caller's X-Request-Id header when set.
+func (g *Gateway) audit(r *http.Request, e audit.Event) error {
+	if g.cfg.Audit == nil {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if e.RequestID = r.Header.Get("X-Request-Id"); e.RequestID == "" {
+		e.RequestID = audit.NewRequestID()
+	}
+	if e.Entities == nil {
+		e.Entities = map[string]int{}
+	}
+	if e.Tokens == nil {
+		e.Tokens = []string{}
+	}
+	e.Policy, e.Destination = g.cfg.Policy, g.destination
+	if g.cfg.TenantHeader != "" {
+		e.Tenant = r.Header.Get(g.cfg.TenantHeader)
+	}
+	return g.cfg.Audit.Record(r.Context(), e)
+}
+
+func (g *Gateway) authorization(r *http.Request) string {
+	if g.cfg.APIKey != "" {
+		return "Bearer " + g.cfg.APIKey
+	}
+	return r.Header.Get("Authorization")
+}
+
+// writeBlindfoldError reports a failed tokenize call, with the status its
+// bferrors kind calls for: 503 or 429 when the caller may retry, 422 for
+// text Blindfold refused, 502 otherwise. The kind goes in the code field.
+func writeBlindfoldError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(bferrors.HTTPStatus(err))
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"message": "gateway: tokenize: " + err.Error(), "type": "blindfold_error", "code": bferrors.KindOf(err).String()},
+	})
+}
+
+// writeError writes an error body in the OpenAI API format, so SDK clients
+// surface it like any other API error.
+func writeError(w http.ResponseWriter, status int, typ, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"message": message, "type": typ},
+	})
+}
@@ -0,0 +1,157 @@
+package gateway
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Stats counts the gateway's requests and where their time goes, as
+// expvar values. Publish it to serve it at /debug/vars:
+//
+//	stats := gateway.NewStats()
+//	expvar.Publish("gateway", stats)
+//
+// Stages are timed back to back, so per request they add up to the time
+// in the handler:
+//
+//	decode    reading and parsing the request body
+//	tokenize  finding the texts, the Blindfold calls, merging mappings
+//	audit     encoding the tokenized body and recording the audit event
+//	upstream  sending the request until the response headers arrive
+//	respond   restoring and writing the response; for a stream, this
+//	          includes waiting on the model's chunks
+//
+// stage_ns holds cumulative nanoseconds by stage: divide the difference
+// between two reads by the requests in between for a per-request figure.
+// detokenize_ns is the part of respond spent restoring tokens, as opposed
+// to waiting on upstream and writing to the caller.
+type Stats struct {
+	root            expvar.Map
+	requests        expvar.Map // by operation
+	inFlight        expvar.Int
+	failures        expvar.Map // by the stage that failed
+	stageNanos      expvar.Map
+	detokenizeNanos expvar.Int
+}
+
+var _ expvar.Var = (*Stats)(nil)
+
+// NewStats returns zeroed Stats.
+func NewStats() *Stats {
+	s := &Stats{}
+	s.root.Set("requests", &s.requests)
+	s.root.Set("in_flight", &s.inFlight)
+	s.root.Set("failures", &s.failures)
+	s.root.Set("stage_ns", &s.stageNanos)
+	s.root.Set("detokenize_ns", &s.detokenizeNanos)
+	return s
+}
+
+// String returns the stats as JSON, for expvar.
+func (s *Stats) String() string { return s.root.String() }
+
+// Requests returns the number of requests handled or in flight.
+func (s *Stats) Requests() int64 {
+	var n int64
+	s.requests.Do(func(kv expvar.KeyValue) { n += kv.Value.(*expvar.Int).Value() })
+	return n
+}
+
+// InFlight returns the number of requests being handled.
+func (s *Stats) InFlight() int64 { return s.inFlight.Value() }
+
+// timer times one request's stages, into Stats and, with ServerTiming,
+// into the response's Server-Timing trailer. A nil timer, for a gateway
+// with neither, records nothing.
+type timer struct {
+	s          *Stats              // nil without Stats
+	w          http.ResponseWriter // nil without ServerTiming
+	last       time.Time
+	stages     []string
+	durations  []time.Duration
+	detokenize time.Duration
+}
+
+func (g *Gateway) begin(w http.ResponseWriter, op string) *timer {
+	if g.cfg.Stats == nil && !g.cfg.ServerTiming {
+		return nil
+	}
+	t := &timer{s: g.cfg.Stats, last: time.Now()}
+	if t.s != nil {
+		t.s.requests.Add(op, 1)
+		t.s.inFlight.Add(1)
+	}
+	if g.cfg.ServerTiming {
+		// A trailer, because respond is only over once the body is
+		// written
+		t.w = w
+		w.Header().Set("Trailer", "Server-Timing")
+	}
+	return t
+}
+
+// stage records the time since the previous stage ended as stage's.
+func (t *timer) stage(stage string) {
+	if t == nil {
+		return
+	}
+	now := time.Now()
+	d := now.Sub(t.last)
+	t.last = now
+	t.stages, t.durations = append(t.stages, stage), append(t.durations, d)
+	if t.s != nil {
+		t.s.stageNanos.Add(stage, int64(d))
+	}
+}
+
+// fail records stage's time and counts it as the stage the request failed
+// in.
+func (t *timer) fail(stage string) {
+	if t == nil {
+		return
+	}
+	t.stage(stage)
+	if t.s != nil {
+		t.s.failures.Add(stage, 1)
+	}
+}
+
+// detokenized adds time spent restoring tokens since start.
+func (t *timer) detokenized(start time.Time) {
+	if t == nil {
+		return
+	}
+	d := time.Since(start)
+	t.detokenize += d
+	if t.s != nil {
+		t.s.detokenizeNanos.Add(int64(d))
+	}
+}
+
+func (t *timer) end() {
+	if t == nil {
+		return
+	}
+	if t.s != nil {
+		t.s.inFlight.Add(-1)
+	}
+	if t.w != nil {
+		t.w.Header().Set("Server-Timing", t.serverTiming())
+	}
+}
+
+// serverTiming formats the stages as a Server-Timing value, durations in
+// milliseconds: "decode;dur=0.081, tokenize;dur=0.512, ...".
+func (t *timer) serverTiming() string {
+	parts := make([]string, 0, len(t.stages)+1)
+	for i, stage := range t.stages {
+		parts = append(parts, fmt.Sprintf("%s;dur=%.3f", stage, float64(t.durations[i].Microseconds())/1000))
+	}
+	if t.detokenize > 0 {
+		parts = append(parts, fmt.Sprintf("detokenize;dur=%.3f", float64(t.detokenize.Microseconds())/1000))
+	}
+	return strings.Join(parts, ", ")
+}
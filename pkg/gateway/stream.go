@@ -0,0 +1,149 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/streamdetok"
+)
+
+// choiceStream tracks one choice of a streamed completion.
+type choiceStream struct {
+	detok *streamdetok.Detokenizer
+	raw   strings.Builder // tokenized content as received, for metrics
+	done  bool
+}
+
+// streamChat relays an SSE chat completion stream, detokenizing the delta
+// content of every choice. Streamed tool-call arguments are relayed as-is,
+// and data lines that are not JSON chunks with their placeholders restored.
+func (g *Gateway) streamChat(w http.ResponseWriter, resp *http.Response, mp map[string]string, t *timer) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	choices := make(map[int]*choiceStream)
+	var template map[string]any // last chunk seen, reused for a final flush
+	r := bufio.NewReader(resp.Body)
+	for {
+		line, err := r.ReadString('\n')
+		if line != "" {
+			data, isData := strings.CutPrefix(strings.TrimRight(line, "\r\n"), "data:")
+			data = strings.TrimSpace(data)
+			switch {
+			case !isData:
+				_, _ = w.Write([]byte(line))
+			case data == "[DONE]":
+				start := time.Now()
+				chunk := g.finish(choices, template, mp)
+				t.detokenized(start)
+				if chunk != nil {
+					_, _ = w.Write([]byte("data: " + string(marshal(chunk)) + "\n\n"))
+				}
+				_, _ = w.Write([]byte(line))
+			default:
+				start := time.Now()
+				chunk, ok := g.rewriteChunk(data, choices, mp)
+				if !ok {
+					// Not a chunk (truncated, or an error page from a
+					// proxy): relayed as-is, but never with placeholders
+					_, _ = w.Write([]byte(mapping.Detokenize(line, jsonEscaped(mp))))
+					t.detokenized(start)
+					break
+				}
+				t.detokenized(start)
+				template = chunk
+				_, _ = w.Write([]byte("data: " + string(marshal(chunk)) + "\n"))
+			}
+			if strings.TrimSpace(line) == "" && flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// rewriteChunk detokenizes the delta content of one chunk. A choice's
+// held-back text is released with its finish_reason chunk.
+func (g *Gateway) rewriteChunk(data string, choices map[int]*choiceStream, mp map[string]string) (map[string]any, bool) {
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	var chunk map[string]any
+	if dec.Decode(&chunk) != nil {
+		return nil, false
+	}
+	list, _ := chunk["choices"].([]any)
+	for _, c := range list {
+		choice, _ := c.(map[string]any)
+		if choice == nil {
+			continue
+		}
+		idx := 0
+		if n, ok := choice["index"].(json.Number); ok {
+			i, _ := n.Int64()
+			idx = int(i)
+		}
+		cs := choices[idx]
+		if cs == nil {
+			cs = &choiceStream{detok: streamdetok.New(mp)}
+			choices[idx] = cs
+		}
+		delta, _ := choice["delta"].(map[string]any)
+		finished := choice["finish_reason"] != nil
+		content, hasContent := delta["content"].(string)
+		if !hasContent && !finished {
+			continue
+		}
+		cs.raw.WriteString(content)
+		out := cs.detok.Push(content)
+		if finished {
+			out += cs.detok.Flush()
+			cs.done = true
+			g.observeDetokenize(cs.raw.String(), mp)
+		}
+		if hasContent || out != "" {
+			if delta == nil {
+				delta = make(map[string]any)
+				choice["delta"] = delta
+			}
+			delta["content"] = out
+		}
+	}
+	return chunk, true
+}
+
+// finish flushes choices the upstream ended without a finish_reason and
+// returns a chunk carrying their remaining text, or nil if there is none.
+func (g *Gateway) finish(choices map[int]*choiceStream, template map[string]any, mp map[string]string) map[string]any {
+	var list []any
+	for idx, cs := range choices {
+		if cs.done {
+			continue
+		}
+		cs.done = true
+		g.observeDetokenize(cs.raw.String(), mp)
+		if rest := cs.detok.Flush(); rest != "" {
+			list = append(list, map[string]any{"index": idx, "delta": map[string]any{"content": rest}, "finish_reason": nil})
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	chunk := map[string]any{"object": "chat.completion.chunk", "choices": list}
+	for _, k := range []string{"id", "created", "model"} {
+		if v, ok := template[k]; ok {
+			chunk[k] = v
+		}
+	}
+	return chunk
+}
@@ -0,0 +1,176 @@
+// Package genpii generates realistic fake documents containing PII, with
+// ground-truth annotations for every generated value.
+//
+// Every identity is synthetic: names are drawn from common first/last name
+// lists, emails use reserved example domains, phone numbers use the 555-01xx
+// fiction range, and card numbers use test-card prefixes (Luhn-valid).
+// Output is deterministic for a given seed, so corpora can be regenerated
+// instead of committed.
+package genpii
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Span is one annotated entity. Start and End are UTF-8 byte offsets.
+type Span struct {
+	Type  string `json:"type"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text,omitempty"`
+}
+
+// Document is a generated text with its annotations. It is also the line
+// format of the labeled corpora read by the eval harness.
+type Document struct {
+	ID       string `json:"id"`
+	Domain   string `json:"domain,omitempty"`
+	Text     string `json:"text"`
+	Entities []Span `json:"entities"`
+}
+
+// Entity types produced by the generator, named as Blindfold reports them.
+const (
+	Person        = "Person"
+	Email         = "Email Address"
+	Phone         = "Phone Number"
+	CreditCard    = "Credit Card Number"
+	Address       = "Address"
+	SSN           = "Social Security Number"
+	DateOfBirth   = "Date of Birth"
+	IPAddress     = "IP Address"
+	MedicalRecord = "Medical Record Number"
+	HealthPlanID  = "Health Insurance Number"
+)
+
+var (
+	firstNames = []string{"James", "Maria", "Wei", "Fatima", "Lars", "Priya", "Olivia", "Mateo", "Aisha", "Noah", "Sofia", "Kenji", "Amara", "Lucas", "Elena", "Omar"}
+	lastNames  = []string{"Smith", "Garcia", "Zhang", "Ali", "Jensen", "Patel", "Brown", "Rossi", "Okafor", "Müller", "Kim", "Novak", "Silva", "Cohen", "Dubois", "Walker"}
+	streets    = []string{"Maple Street", "Oak Avenue", "Cedar Lane", "Pine Road", "Elm Court", "Harbor Boulevard", "Sunset Drive", "Mill Road"}
+	cities     = []string{"Springfield, IL 62704", "Portland, OR 97205", "Austin, TX 78701", "Denver, CO 80202", "Madison, WI 53703", "Raleigh, NC 27601"}
+	domains    = []string{"example.com", "example.org", "example.net"}
+)
+
+// Generator produces fake values and documents from a seeded source.
+// It is not safe for concurrent use.
+type Generator struct {
+	r *rand.Rand
+}
+
+// New returns a generator seeded with seed.
+func New(seed int64) *Generator {
+	return &Generator{r: rand.New(rand.NewSource(seed))}
+}
+
+func (g *Generator) pick(list []string) string { return list[g.r.Intn(len(list))] }
+
+// Name returns a full name.
+func (g *Generator) Name() string { return g.pick(firstNames) + " " + g.pick(lastNames) }
+
+// EmailFor returns an address derived from name.
+func (g *Generator) EmailFor(name string) string {
+	local := strings.ToLower(strings.ReplaceAll(name, " ", "."))
+	local = strings.NewReplacer("ü", "u", "ö", "o", "ä", "a").Replace(local)
+	return fmt.Sprintf("%s@%s", local, g.pick(domains))
+}
+
+// Email returns an address for a random person.
+func (g *Generator) Email() string { return g.EmailFor(g.Name()) }
+
+// Phone returns a US number in the 555-0100–0199 range reserved for fiction.
+func (g *Generator) Phone() string {
+	area := []string{"212", "415", "312", "617", "206"}[g.r.Intn(5)]
+	if g.r.Intn(2) == 0 {
+		return fmt.Sprintf("+1 %s-555-01%02d", area, g.r.Intn(100))
+	}
+	return fmt.Sprintf("(%s) 555-01%02d", area, g.r.Intn(100))
+}
+
+// CreditCard returns a Luhn-valid 16-digit test-range card number.
+func (g *Generator) CreditCard() string {
+	prefix := []string{"4111", "4242", "5555", "5105"}[g.r.Intn(4)]
+	digits := []byte(prefix)
+	for len(digits) < 15 {
+		digits = append(digits, byte('0'+g.r.Intn(10)))
+	}
+	digits = append(digits, LuhnCheckDigit(string(digits)))
+	s := string(digits)
+	return s[0:4] + " " + s[4:8] + " " + s[8:12] + " " + s[12:16]
+}
+
+// LuhnCheckDigit returns the digit that makes partial Luhn-valid.
+func LuhnCheckDigit(partial string) byte {
+	sum := 0
+	double := true
+	for i := len(partial) - 1; i >= 0; i-- {
+		d := int(partial[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// Address returns a US street address.
+func (g *Generator) Address() string {
+	return fmt.Sprintf("%d %s, %s", 10+g.r.Intn(9890), g.pick(streets), g.pick(cities))
+}
+
+// SSN returns a well-formed SSN outside the never-issued ranges.
+func (g *Generator) SSN() string {
+	return fmt.Sprintf("%03d-%02d-%04d", 100+g.r.Intn(565), 1+g.r.Intn(98), 1+g.r.Intn(9998))
+}
+
+// DateOfBirth returns an MM/DD/YYYY date between 1940 and 2005.
+func (g *Generator) DateOfBirth() string {
+	return fmt.Sprintf("%02d/%02d/%d", 1+g.r.Intn(12), 1+g.r.Intn(28), 1940+g.r.Intn(66))
+}
+
+// IPAddress returns a private-range IPv4 address.
+func (g *Generator) IPAddress() string {
+	return fmt.Sprintf("10.%d.%d.%d", g.r.Intn(256), g.r.Intn(256), 1+g.r.Intn(254))
+}
+
+// MedicalRecord returns a hospital-style MRN.
+func (g *Generator) MedicalRecord() string { return fmt.Sprintf("MRN-%07d", g.r.Intn(10_000_000)) }
+
+// HealthPlanID returns an insurance member ID.
+func (g *Generator) HealthPlanID() string {
+	return fmt.Sprintf("%c%c%c%09d", 'A'+g.r.Intn(26), 'A'+g.r.Intn(26), 'A'+g.r.Intn(26), g.r.Intn(1_000_000_000))
+}
+
+// Value returns a fresh value of the given entity type, or "" if the type
+// is not one the generator knows.
+func (g *Generator) Value(entityType string) string {
+	switch entityType {
+	case Person:
+		return g.Name()
+	case Email:
+		return g.Email()
+	case Phone:
+		return g.Phone()
+	case CreditCard:
+		return g.CreditCard()
+	case Address:
+		return g.Address()
+	case SSN:
+		return g.SSN()
+	case DateOfBirth:
+		return g.DateOfBirth()
+	case IPAddress:
+		return g.IPAddress()
+	case MedicalRecord:
+		return g.MedicalRecord()
+	case HealthPlanID:
+		return g.HealthPlanID()
+	default:
+		return ""
+	}
+}
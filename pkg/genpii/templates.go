@@ -0,0 +1,108 @@
+package genpii
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Templates are document skeletons per domain. {Type} slots are filled with
+// a generated value; {Type:n} slots with the same n reuse one value, and an
+// Email slot with the same n as a Person slot is derived from that name.
+var Templates = map[string][]string{
+	"support": {
+		"Hi, I'm {Person:1}. Please reach me at {Email Address:1} or {Phone Number}.",
+		"Ticket from {Email Address}: the order delivered to {Address} arrived damaged.",
+		"{Person:1} called from {Phone Number} asking to update the shipping address to {Address}. Confirmation sent to {Email Address:1}.",
+		"Customer {Person:1} reports login alerts from {IP Address}; they want a reset link at {Email Address:1}.",
+		"Please close the account of {Person}, born {Date of Birth}, and confirm by phone on {Phone Number}.",
+	},
+	"billing": {
+		"Card {Credit Card Number} was charged twice for {Person:1}; refund to the same card and email {Email Address:1}.",
+		"Dispute opened by {Person} for a payment on card {Credit Card Number}. Callback number: {Phone Number}.",
+		"Invoice for {Person:1} ({Email Address:1}) at {Address} is overdue; the card on file is {Credit Card Number}.",
+		"Identity check for {Person}: SSN {Social Security Number}, date of birth {Date of Birth}.",
+	},
+	"medical": {
+		"Patient {Person:1}, DOB {Date of Birth}, {Medical Record Number}, presented with chest pain. Contact: {Phone Number}.",
+		"Referral for {Person} (member ID {Health Insurance Number}) to cardiology; records under {Medical Record Number}.",
+		"Discharge summary for {Person:1}, {Medical Record Number}. Follow-up reminders go to {Email Address:1}.",
+		"{Person}'s insurance {Health Insurance Number} was denied; the patient lives at {Address} and their SSN is {Social Security Number}.",
+	},
+}
+
+// Domains returns the template domains in alphabetical order.
+func Domains() []string {
+	names := make([]string, 0, len(Templates))
+	for d := range Templates {
+		names = append(names, d)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var slotPattern = regexp.MustCompile(`\{([^{}:]+)(?::(\d+))?\}`)
+
+// Fill renders a template, returning the text and the annotation for every
+// slot it filled.
+func (g *Generator) Fill(template string) (string, []Span) {
+	var b strings.Builder
+	var spans []Span
+	names := map[string]string{}  // slot group → person name
+	values := map[string]string{} // type + group → value
+
+	last := 0
+	for _, loc := range slotPattern.FindAllStringSubmatchIndex(template, -1) {
+		b.WriteString(template[last:loc[0]])
+		last = loc[1]
+
+		typ := template[loc[2]:loc[3]]
+		group := ""
+		if loc[4] >= 0 {
+			group = template[loc[4]:loc[5]]
+		}
+
+		value, ok := values[typ+group]
+		switch {
+		case ok && group != "":
+		case typ == Email && names[group] != "" && group != "":
+			value = g.EmailFor(names[group])
+		default:
+			value = g.Value(typ)
+		}
+		if group != "" {
+			values[typ+group] = value
+			if typ == Person {
+				names[group] = value
+			}
+		}
+
+		start := b.Len()
+		b.WriteString(value)
+		spans = append(spans, Span{Type: typ, Start: start, End: b.Len(), Text: value})
+	}
+	b.WriteString(template[last:])
+	return b.String(), spans
+}
+
+// Document generates one document from a random template in domain, or in
+// any domain if domain is empty.
+func (g *Generator) Document(id, domain string) Document {
+	if domain == "" {
+		domains := Domains()
+		domain = domains[g.r.Intn(len(domains))]
+	}
+	templates := Templates[domain]
+	text, spans := g.Fill(templates[g.r.Intn(len(templates))])
+	return Document{ID: id, Domain: domain, Text: text, Entities: spans}
+}
+
+// Documents generates n documents with IDs doc-00001, doc-00002, ...
+func (g *Generator) Documents(n int, domain string) []Document {
+	docs := make([]Document, n)
+	for i := range docs {
+		docs[i] = g.Document(fmt.Sprintf("doc-%05d", i+1), domain)
+	}
+	return docs
+}
@@ -0,0 +1,280 @@
+// Package guardrail re-scans a fully assembled prompt right before it is
+// sent to a model, and blocks, strips, or reports PII that earlier
+// tokenization missed.
+//
+// Tokenizing the user's message is the easy part. By the time a request
+// goes out it also holds the system prompt, template fields, the
+// conversation history, tool results, and retrieved documents, and any of
+// those can carry a value that never went through Tokenize: a RAG chunk
+// indexed before the pipeline existed, a template field nobody marked, a
+// tool that returns a customer record. The guard runs one more Detect
+// over everything that is about to leave, with Blindfold tokens blanked
+// out so they never count, and applies its Mode to whatever it finds.
+//
+//	g := guardrail.New(bf, guardrail.Block)
+//	chat := g.WrapChat(openaiClient)
+//	res, err := chat.CreateChatCompletion(ctx, req) // errors.Is(err, guardrail.ErrBlocked)
+//
+// Findings hold the entity type and position, never the value, so they
+// can be logged and alerted on.
+package guardrail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/redact"
+)
+
+// Mode is what a Guard does with residual PII.
+type Mode int
+
+const (
+	// Block refuses the prompt: Check returns ErrBlocked and nothing is
+	// sent.
+	Block Mode = iota
+	// Strip replaces each finding with its redact.Label and lets the
+	// prompt through.
+	Strip
+	// Warn lets the prompt through unchanged and only reports.
+	Warn
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Block:
+		return "block"
+	case Strip:
+		return "strip"
+	case Warn:
+		return "warn"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// ParseMode parses "block", "strip" or "warn", for flags and config.
+func ParseMode(s string) (Mode, error) {
+	for _, m := range []Mode{Block, Strip, Warn} {
+		if strings.EqualFold(s, m.String()) {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("guardrail: unknown mode %q (want block, strip or warn)", s)
+}
+
+// ErrBlocked is returned, wrapped, when a Block guard finds residual PII.
+var ErrBlocked = errors.New("guardrail: residual PII in prompt")
+
+// Finding is one entity found in a prompt. It never holds the value.
+type Finding struct {
+	// Message is the index of the message it was found in; 0 for Check.
+	Message int
+	// Part is the index of the content part for multi-part messages, or
+	// -1 for Content.
+	Part       int
+	Type       string
+	Start, End int // byte offsets into the text that was checked
+	Score      float64
+
+	text int // index of the checked text
+}
+
+// Guard checks prompts with a detector. Set its fields before first use.
+type Guard struct {
+	d    redact.Detector
+	Mode Mode
+	// MinScore ignores findings scored below it. Zero keeps everything
+	// the detector reports.
+	MinScore float64
+	// OnFinding, if set, is called with the findings of every check that
+	// had any, in every mode, before the mode is applied. It is where
+	// warnings get logged or counted.
+	OnFinding func(ctx context.Context, findings []Finding)
+	// CallOptions apply to every Detect call.
+	CallOptions []blindfold.CallOption
+}
+
+// New returns a guard that detects with d and applies mode.
+func New(d redact.Detector, mode Mode) *Guard {
+	return &Guard{d: d, Mode: mode}
+}
+
+// Check scans text and returns it with the mode applied: unchanged for
+// Warn, with findings replaced for Strip. For Block, findings make Check
+// return an error wrapping ErrBlocked. A detection error is returned as
+// is; the caller should not send text it couldn't check.
+func (g *Guard) Check(ctx context.Context, text string) (string, []Finding, error) {
+	out, findings, err := g.check(ctx, []string{text}, func(*Finding) {})
+	if err != nil {
+		return "", findings, err
+	}
+	return out[0], findings, nil
+}
+
+// CheckMessages scans every message: contents, text parts, and tool-call
+// arguments. It returns a copy with the mode applied; msgs is
+// not modified.
+func (g *Guard) CheckMessages(ctx context.Context, msgs []openai.ChatCompletionMessage) ([]openai.ChatCompletionMessage, []Finding, error) {
+	var texts []string
+	var refs []ref
+	add := func(r ref, s string) {
+		if s != "" {
+			refs = append(refs, r)
+			texts = append(texts, s)
+		}
+	}
+	for i, m := range msgs {
+		add(ref{msg: i, part: -1}, m.Content)
+		for j, p := range m.MultiContent {
+			if p.Type == openai.ChatMessagePartTypeText {
+				add(ref{msg: i, part: j}, p.Text)
+			}
+		}
+		for j, tc := range m.ToolCalls {
+			add(ref{msg: i, part: -1, tool: j + 1}, tc.Function.Arguments)
+		}
+	}
+	out, findings, err := g.check(ctx, texts, func(f *Finding) {
+		f.Message, f.Part = refs[f.text].msg, refs[f.text].part
+	})
+	if err != nil {
+		return nil, findings, err
+	}
+
+	cp := make([]openai.ChatCompletionMessage, len(msgs))
+	copy(cp, msgs)
+	for k, r := range refs {
+		m := &cp[r.msg]
+		switch {
+		case r.tool > 0:
+			m.ToolCalls = append([]openai.ToolCall(nil), m.ToolCalls...)
+			m.ToolCalls[r.tool-1].Function.Arguments = out[k]
+		case r.part >= 0:
+			m.MultiContent = append([]openai.ChatMessagePart(nil), m.MultiContent...)
+			m.MultiContent[r.part].Text = out[k]
+		default:
+			m.Content = out[k]
+		}
+	}
+	return cp, findings, nil
+}
+
+// ref locates a checked text in a message list.
+type ref struct {
+	msg, part int
+	tool      int // tool call index + 1, or 0
+}
+
+// check scans texts in one Detect call. locate sets where each finding
+// sits in the caller's terms.
+func (g *Guard) check(ctx context.Context, texts []string, locate func(*Finding)) ([]string, []Finding, error) {
+	if len(texts) == 0 {
+		return texts, nil, nil
+	}
+	// Tokens are blanked out to the same length, so offsets still line
+	// up and "<Person_1>" never reads as something to detect.
+	blanked := make([]string, len(texts))
+	for i, t := range texts {
+		blanked[i] = mapping.TokenPattern.ReplaceAllStringFunc(t, func(tok string) string {
+			return strings.Repeat(" ", len(tok))
+		})
+	}
+	joined := strings.Join(blanked, "\n")
+	res, err := g.d.Detect(ctx, joined, g.CallOptions...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("guardrail: %w", err)
+	}
+
+	var findings []Finding
+	start, i := 0, 0
+	ents := res.DetectedEntities
+	sort.SliceStable(ents, func(a, b int) bool { return ents[a].Start < ents[b].Start })
+	for _, e := range ents {
+		if e.Score < g.MinScore {
+			continue
+		}
+		for i < len(texts)-1 && e.Start >= start+len(texts[i])+1 {
+			start += len(texts[i]) + 1
+			i++
+		}
+		end := min(e.End-start, len(texts[i]))
+		if end <= e.Start-start {
+			continue
+		}
+		f := Finding{Part: -1, Type: e.Type, Start: e.Start - start, End: end, Score: e.Score, text: i}
+		locate(&f)
+		findings = append(findings, f)
+	}
+	if len(findings) == 0 {
+		return texts, nil, nil
+	}
+	if g.OnFinding != nil {
+		g.OnFinding(ctx, findings)
+	}
+
+	switch g.Mode {
+	case Warn:
+		return texts, findings, nil
+	case Strip:
+		return strip(texts, findings), findings, nil
+	}
+	return nil, findings, fmt.Errorf("%w: %s", ErrBlocked, Summary(findings))
+}
+
+// strip replaces each finding with its label. Findings are in order.
+func strip(texts []string, findings []Finding) []string {
+	out := append([]string(nil), texts...)
+	for i := len(findings) - 1; i >= 0; i-- {
+		f := findings[i]
+		t := out[f.text]
+		out[f.text] = t[:f.Start] + redact.Label(f.Type) + t[f.End:]
+	}
+	return out
+}
+
+// Summary describes findings by type, such as "Email Address ×2, Phone
+// Number ×1". It holds no values.
+func Summary(findings []Finding) string {
+	counts := make(map[string]int)
+	for _, f := range findings {
+		counts[f.Type]++
+	}
+	parts := make([]string, 0, len(counts))
+	for typ, n := range counts {
+		parts = append(parts, fmt.Sprintf("%s ×%d", typ, n))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+// ChatClient checks every request with a guard before passing it on.
+type ChatClient struct {
+	next bfclient.ChatCompleter
+	g    *Guard
+}
+
+var _ bfclient.ChatCompleter = (*ChatClient)(nil)
+
+// WrapChat returns next with every request checked by g.
+func (g *Guard) WrapChat(next bfclient.ChatCompleter) *ChatClient {
+	return &ChatClient{next: next, g: g}
+}
+
+// CreateChatCompletion checks req.Messages and sends the checked copy. A
+// blocked request or a failed check never reaches next.
+func (c *ChatClient) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	msgs, _, err := c.g.CheckMessages(ctx, req.Messages)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+	req.Messages = msgs
+	return c.next.CreateChatCompletion(ctx, req)
+}
@@ -0,0 +1,77 @@
+package guardrail
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+var local = blindfold.New(blindfold.WithMode("local"))
+
+func prompt() []openai.ChatCompletionMessage {
+	return []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "Answer using the context. Escalations go to ops@example.com."},
+		{Role: openai.ChatMessageRoleUser, Content: "Why was <Email Address_1> charged twice?"},
+		{Role: openai.ChatMessageRoleTool, Content: `{"card":"4111 1111 1111 1111","status":"refunded"}`},
+	}
+}
+
+func TestModes(t *testing.T) {
+	ctx := context.Background()
+	var reported []Finding
+	g := New(local, Block)
+	g.OnFinding = func(_ context.Context, f []Finding) { reported = f }
+
+	if _, _, err := g.CheckMessages(ctx, prompt()); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("block: err = %v", err)
+	}
+	if len(reported) != 2 || reported[0].Message != 0 || reported[1].Message != 2 || reported[1].Type != "Credit Card Number" {
+		t.Errorf("findings = %+v", reported)
+	}
+
+	g.Mode = Strip
+	in := prompt()
+	out, _, err := g.CheckMessages(ctx, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out[0].Content != "Answer using the context. Escalations go to [Email Address]." ||
+		out[1].Content != in[1].Content ||
+		out[2].Content != `{"card":"[Credit Card Number]","status":"refunded"}` {
+		t.Errorf("strip = %+v", out)
+	}
+	if in[0].Content != prompt()[0].Content {
+		t.Error("input modified")
+	}
+
+	g.Mode = Warn
+	out, f, err := g.CheckMessages(ctx, in)
+	if err != nil || len(f) != 2 || out[2].Content != in[2].Content {
+		t.Errorf("warn: %v %v %+v", err, f, out)
+	}
+}
+
+func TestClean(t *testing.T) {
+	text, f, err := New(local, Block).Check(context.Background(), "Hi <Person_1>, your refund to <Email Address_2> is on its way.")
+	if err != nil || len(f) != 0 || text == "" {
+		t.Errorf("text = %q, findings = %v, err = %v", text, f, err)
+	}
+}
+
+type recorder struct{ req openai.ChatCompletionRequest }
+
+func (r *recorder) CreateChatCompletion(_ context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	r.req = req
+	return openai.ChatCompletionResponse{}, nil
+}
+
+func TestWrapChat(t *testing.T) {
+	next := &recorder{}
+	chat := New(local, Block).WrapChat(next)
+	if _, err := chat.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{Messages: prompt()}); !errors.Is(err, ErrBlocked) || next.req.Messages != nil {
+		t.Fatalf("err = %v, sent = %v", err, next.req.Messages)
+	}
+}
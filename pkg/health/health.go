@@ -0,0 +1,199 @@
+// Package health serves the operational endpoints the cookbook's services
+// share, so they can run behind a load balancer or an orchestrator:
+//
+//   - /healthz answers 200 while the process is serving (liveness),
+//   - /readyz runs the registered checks and answers 503 when a required
+//     one fails or the service is draining for shutdown (readiness),
+//   - /configz shows the configuration in effect, with secrets redacted.
+//
+// Readiness checks run concurrently on every request, each under a
+// two-second timeout; wrap expensive ones, such as a cloud-mode Blindfold
+// call, in Cached.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+// Check reports whether a dependency is usable.
+type Check func(ctx context.Context) error
+
+// checkTimeout bounds each check on a /readyz request.
+const checkTimeout = 2 * time.Second
+
+type namedCheck struct {
+	name     string
+	check    Check
+	required bool
+}
+
+// Handler serves /healthz, /readyz and /configz.
+type Handler struct {
+	// Config is served at /configz after Redact. Anything encoding/json
+	// can marshal; StandardConfig covers what every service shares.
+	Config any
+
+	mu       sync.Mutex
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+// New returns a Handler with no checks, which is always ready.
+func New() *Handler {
+	return &Handler{}
+}
+
+// AddCheck registers a check that must pass for the service to be ready.
+func (h *Handler) AddCheck(name string, c Check) {
+	h.add(namedCheck{name: name, check: c, required: true})
+}
+
+// AddInfo registers a check that is reported by /readyz but doesn't make
+// the service unready, for a degraded dependency the service works
+// around, such as a cloud API it falls back from.
+func (h *Handler) AddInfo(name string, c Check) {
+	h.add(namedCheck{name: name, check: c})
+}
+
+func (h *Handler) add(c namedCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, c)
+}
+
+// Drain makes the service unready for good, so load balancers stop
+// routing to it while it finishes what it has in flight. /healthz keeps
+// answering 200: the process is still alive.
+func (h *Handler) Drain() {
+	h.draining.Store(true)
+}
+
+// Register mounts the three endpoints on mux.
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", h.healthz)
+	mux.HandleFunc("/readyz", h.readyz)
+	mux.HandleFunc("/configz", h.configz)
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Required bool   `json:"required"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Ready runs every check and reports whether all required ones passed.
+func (h *Handler) Ready(ctx context.Context) (bool, []Result) {
+	h.mu.Lock()
+	checks := append([]namedCheck(nil), h.checks...)
+	h.mu.Unlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c namedCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			err := c.check(ctx)
+			results[i] = Result{Name: c.name, OK: err == nil, Required: c.required}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}(i, c)
+	}
+	wg.Wait()
+	if h.draining.Load() {
+		results = append(results, Result{Name: "draining", Required: true, Error: "shutting down"})
+	}
+
+	ready := true
+	for _, r := range results {
+		if r.Required && !r.OK {
+			ready = false
+		}
+	}
+	return ready, results
+}
+
+func (h *Handler) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+func (h *Handler) readyz(w http.ResponseWriter, r *http.Request) {
+	ready, results := h.Ready(r.Context())
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, map[string]any{"ready": ready, "checks": results})
+}
+
+func (h *Handler) configz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Redact(h.Config))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// Blindfold checks a Blindfold client with a small Detect call, through
+// whatever wrappers it has. In cloud mode that is one API call per check,
+// so wrap it in Cached.
+func Blindfold(bf bfclient.Client) Check {
+	return func(ctx context.Context) error {
+		_, err := bf.Detect(ctx, "health check")
+		return err
+	}
+}
+
+// Cached runs c at most once per ttl and reports its last result in
+// between, so load balancer probes don't each cost a call.
+func Cached(c Check, ttl time.Duration) Check {
+	var (
+		mu   sync.Mutex
+		at   time.Time
+		last error
+	)
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !at.IsZero() && time.Since(at) < ttl {
+			return last
+		}
+		err := c(ctx)
+		if ctx.Err() != nil {
+			return err // the probe gave up; says nothing about the dependency
+		}
+		last, at = err, time.Now()
+		return last
+	}
+}
+
+// NewService returns a Handler with what every cookbook service reports:
+// StandardConfig for fs and pol, and a required check of bf, cached for
+// ten seconds.
+func NewService(fs *flag.FlagSet, pol *policyconf.Policy, bf bfclient.Client) *Handler {
+	h := New()
+	h.Config = StandardConfig(fs, pol)
+	h.AddCheck("blindfold", Cached(Blindfold(bf), 10*time.Second))
+	return h
+}
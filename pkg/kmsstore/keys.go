@@ -0,0 +1,140 @@
+package kmsstore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapstore"
+)
+
+// Stats counts the KMS calls a Store made and the ones its cache saved.
+type Stats struct {
+	Generated int // GenerateDataKey calls
+	Decrypted int // Decrypt calls
+	Hits      int // data keys served from the cache
+}
+
+// keyCache holds data keys in the clear: the one each encryption context
+// currently encrypts with, and those decrypted for reading.
+type keyCache struct {
+	mu        sync.Mutex
+	encrypt   map[string]*dataKey // by contextKey
+	decrypt   map[string]*dataKey // by encrypted key and contextKey
+	generated int
+	decrypted int
+	hits      int
+}
+
+type dataKey struct {
+	key       mapstore.Key
+	encrypted []byte
+	created   time.Time
+	uses      int
+}
+
+func (s *Store) limits() (time.Duration, int, int) {
+	maxAge, maxUses, maxKeys := s.MaxAge, s.MaxUses, s.MaxKeys
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	if maxUses <= 0 {
+		maxUses = DefaultMaxUses
+	}
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxKeys
+	}
+	return maxAge, maxUses, maxKeys
+}
+
+// encryptKey returns the data key to encrypt an entry under ec with, and
+// its encrypted form: the cached one, or a new one once that has been
+// used MaxUses times or is MaxAge old.
+func (s *Store) encryptKey(ctx context.Context, ec map[string]string) (mapstore.Key, []byte, error) {
+	maxAge, maxUses, maxKeys := s.limits()
+	c := &s.keys
+	ck := contextKey(ec)
+	c.mu.Lock()
+	if dk := c.encrypt[ck]; dk != nil && dk.uses < maxUses && time.Since(dk.created) < maxAge {
+		dk.uses++
+		c.hits++
+		c.mu.Unlock()
+		return dk.key, dk.encrypted, nil
+	}
+	c.mu.Unlock()
+
+	// Outside the lock: concurrent writers may each generate one, and the
+	// last one stays cached
+	plain, encrypted, err := s.kms.GenerateDataKey(ctx, s.keyID, ec)
+	if err != nil {
+		return mapstore.Key{}, nil, fmt.Errorf("kmsstore: generate data key: %w", err)
+	}
+	dk := &dataKey{key: mapstore.Key{ID: dataKeyID(encrypted), Secret: plain}, encrypted: encrypted, created: time.Now(), uses: 1}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generated++
+	if c.encrypt == nil {
+		c.encrypt, c.decrypt = make(map[string]*dataKey), make(map[string]*dataKey)
+	}
+	c.encrypt[ck] = dk
+	// Entries written with it are read back without a Decrypt call
+	c.putDecrypted(string(encrypted)+ck, dk, maxAge, maxKeys)
+	return dk.key, encrypted, nil
+}
+
+// decryptKey returns the data key an entry was encrypted with, from the
+// cache or from KMS.
+func (s *Store) decryptKey(ctx context.Context, kmsKeyID string, encrypted []byte, ec map[string]string) (mapstore.Key, error) {
+	maxAge, _, maxKeys := s.limits()
+	c := &s.keys
+	id := string(encrypted) + contextKey(ec)
+	c.mu.Lock()
+	if dk := c.decrypt[id]; dk != nil && time.Since(dk.created) < maxAge {
+		c.hits++
+		c.mu.Unlock()
+		return dk.key, nil
+	}
+	c.mu.Unlock()
+
+	plain, err := s.kms.Decrypt(ctx, kmsKeyID, encrypted, ec)
+	if err != nil {
+		return mapstore.Key{}, fmt.Errorf("kmsstore: decrypt data key: %w", err)
+	}
+	dk := &dataKey{key: mapstore.Key{ID: dataKeyID(encrypted), Secret: plain}, encrypted: encrypted, created: time.Now()}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.decrypted++
+	if c.decrypt == nil {
+		c.encrypt, c.decrypt = make(map[string]*dataKey), make(map[string]*dataKey)
+	}
+	c.putDecrypted(id, dk, maxAge, maxKeys)
+	return dk.key, nil
+}
+
+// putDecrypted caches dk, first dropping expired keys and then the oldest
+// if the cache is full. c.mu must be held.
+func (c *keyCache) putDecrypted(id string, dk *dataKey, maxAge time.Duration, maxKeys int) {
+	if len(c.decrypt) >= maxKeys {
+		var oldest string
+		for k, v := range c.decrypt {
+			if time.Since(v.created) >= maxAge {
+				delete(c.decrypt, k)
+				continue
+			}
+			if oldest == "" || v.created.Before(c.decrypt[oldest].created) {
+				oldest = k
+			}
+		}
+		if len(c.decrypt) >= maxKeys && oldest != "" {
+			delete(c.decrypt, oldest)
+		}
+	}
+	c.decrypt[id] = dk
+}
+
+func (c *keyCache) stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{Generated: c.generated, Decrypted: c.decrypted, Hits: c.hits}
+}
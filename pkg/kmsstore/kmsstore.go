@@ -0,0 +1,291 @@
+// Package kmsstore is a protect.Store that envelope-encrypts each mapping
+// with an AWS KMS data key. KMS generates a data key under a KMS key
+// (the key never leaves KMS), the mapping is encrypted locally with it as
+// a pkg/mapstore file, and the entry keeps the data key only in its
+// KMS-encrypted form. Reading an entry is a KMS Decrypt call, which
+// CloudTrail records and key policy can deny.
+//
+// Every data key is bound to an encryption context naming the tenant,
+// taken from the request context:
+//
+//	store := kmsstore.NewStore(kv, awsKMS{kms.NewFromConfig(cfg)}, "alias/blindfold-mappings")
+//	store.Context = map[string]string{"app": "support-bot"}
+//	p.Store = store
+//	...
+//	ctx = protect.Session(kmsstore.WithTenant(r.Context(), tenant), conversation)
+//
+// KMS refuses to decrypt a data key under any other context, so an entry
+// copied into another tenant's namespace doesn't open, and a key policy
+// condition on kms:EncryptionContext:tenant can restrict a role to the
+// tenants it serves. The session is bound by the mapping file itself.
+//
+// Data keys are cached: one is used for up to MaxUses entries or MaxAge,
+// per encryption context, before a new one is generated, and decrypted
+// keys are kept as long, so a conversation's turns don't each cost a KMS
+// call. The cache holds plaintext data keys in memory; set MaxUses to 1
+// for a data key per write.
+//
+// The Store needs only two KMS operations, behind the KMS interface. With
+// aws-sdk-go-v2 the adapter is:
+//
+//	type awsKMS struct{ c *kms.Client }
+//
+//	func (a awsKMS) GenerateDataKey(ctx context.Context, keyID string, ec map[string]string) ([]byte, []byte, error) {
+//		out, err := a.c.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+//			KeyId: &keyID, KeySpec: types.DataKeySpecAes256, EncryptionContext: ec})
+//		if err != nil {
+//			return nil, nil, err
+//		}
+//		return out.Plaintext, out.CiphertextBlob, nil
+//	}
+//
+//	func (a awsKMS) Decrypt(ctx context.Context, keyID string, blob []byte, ec map[string]string) ([]byte, error) {
+//		out, err := a.c.Decrypt(ctx, &kms.DecryptInput{KeyId: &keyID, CiphertextBlob: blob, EncryptionContext: ec})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return out.Plaintext, nil
+//	}
+package kmsstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/cache"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapstore"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+)
+
+// KMS is the part of AWS KMS the Store uses. Both calls take the KMS key
+// and the encryption context; Decrypt must fail unless the context is the
+// one the data key was generated under, as KMS does.
+type KMS interface {
+	// GenerateDataKey returns a new AES-256 data key, in the clear and
+	// encrypted under keyID.
+	GenerateDataKey(ctx context.Context, keyID string, encryptionContext map[string]string) (plaintext, encrypted []byte, err error)
+	// Decrypt returns the data key encrypted by GenerateDataKey.
+	Decrypt(ctx context.Context, keyID string, encrypted []byte, encryptionContext map[string]string) ([]byte, error)
+}
+
+const (
+	// Format names the entry format in every entry.
+	Format = "blindfold-mapping-kms"
+	// Version is the entry format version this package writes.
+	Version = 1
+)
+
+var (
+	// ErrNoTenant means the context has no tenant: see WithTenant.
+	ErrNoTenant = errors.New("kmsstore: no tenant in context")
+	// ErrContext means an entry's encryption context isn't the one its
+	// tenant's entries are written under: it was copied from another
+	// tenant, or the Store's Context changed.
+	ErrContext = errors.New("kmsstore: entry has another encryption context")
+)
+
+// Defaults for the Store's cache limits.
+const (
+	DefaultMaxAge  = 5 * time.Minute
+	DefaultMaxUses = 1000
+	DefaultMaxKeys = 1000
+)
+
+// validID restricts tenant IDs, so one can't reach into another tenant's
+// namespace.
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type tenantKeyType struct{}
+
+// WithTenant marks ctx as acting for tenant: its entries are namespaced by
+// it and their data keys bound to it.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKeyType{}, tenant)
+}
+
+// Tenant returns the tenant WithTenant put in ctx.
+func Tenant(ctx context.Context) (string, bool) {
+	t, ok := ctx.Value(tenantKeyType{}).(string)
+	return t, ok
+}
+
+// Store is a protect.Store over a cache.Store whose entries are mapping
+// files encrypted with KMS data keys. Set its fields before first use.
+type Store struct {
+	kv    cache.Store
+	kms   KMS
+	keyID string
+
+	// Context is added to the encryption context of every data key, next
+	// to "tenant", which it can't override.
+	Context map[string]string
+	// Policy and Source go into the metadata of every entry.
+	Policy, Source string
+	// MaxAge and MaxUses bound how long and for how many entries one data
+	// key encrypts; MaxKeys bounds the decrypted keys kept. Zero means the
+	// Default.
+	MaxAge  time.Duration
+	MaxUses int
+	MaxKeys int
+
+	keys keyCache
+}
+
+var _ protect.Store = (*Store)(nil)
+
+// NewStore returns a Store over kv that generates data keys under the KMS
+// key keyID (an ID, ARN or alias).
+func NewStore(kv cache.Store, k KMS, keyID string) *Store {
+	return &Store{kv: kv, kms: k, keyID: keyID}
+}
+
+// EntryKey returns where a tenant's session mapping is kept in the
+// cache.Store.
+func EntryKey(tenant, session string) string {
+	return "mapping/kms/tenant/" + tenant + "/session/" + session
+}
+
+// entry is a mapping as stored: the mapping file and the data key that
+// opens it, encrypted by KMS.
+type entry struct {
+	Format            string            `json:"format"`
+	Version           int               `json:"version"`
+	KMSKeyID          string            `json:"kms_key_id"`
+	EncryptedKey      []byte            `json:"encrypted_key"`
+	EncryptionContext map[string]string `json:"encryption_context"`
+	File              json.RawMessage   `json:"file"`
+}
+
+// encryptionContext returns the context of tenant's data keys.
+func (s *Store) encryptionContext(tenant string) map[string]string {
+	ec := make(map[string]string, len(s.Context)+1)
+	for k, v := range s.Context {
+		ec[k] = v
+	}
+	ec["tenant"] = tenant
+	return ec
+}
+
+// contextKey is ec as a string, the same for equal contexts.
+func contextKey(ec map[string]string) string {
+	keys := make([]string, 0, len(ec))
+	for k := range ec {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([][2]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = [2]string{k, ec[k]}
+	}
+	b, _ := json.Marshal(pairs)
+	return string(b)
+}
+
+// dataKeyID names a data key by its encrypted form, as the mapping file's
+// key ID.
+func dataKeyID(encrypted []byte) string {
+	sum := sha256.Sum256(encrypted)
+	return "dk-" + hex.EncodeToString(sum[:8])
+}
+
+func tenantOf(ctx context.Context) (string, error) {
+	tenant, ok := Tenant(ctx)
+	if !ok {
+		return "", ErrNoTenant
+	}
+	if !validID.MatchString(tenant) {
+		return "", fmt.Errorf("kmsstore: invalid tenant ID %q", tenant)
+	}
+	return tenant, nil
+}
+
+// Load implements protect.Store for the tenant in ctx.
+func (s *Store) Load(ctx context.Context, session string) (map[string]string, error) {
+	m, _, err := s.open(ctx, session)
+	return m, err
+}
+
+func (s *Store) open(ctx context.Context, session string) (map[string]string, *mapstore.Metadata, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, ok, err := s.kv.Get(ctx, EntryKey(tenant, session))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return map[string]string{}, nil, nil
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || e.Format != Format {
+		return nil, nil, fmt.Errorf("session %s: %w", session, mapstore.ErrFormat)
+	}
+	if e.Version < 1 || e.Version > Version {
+		return nil, nil, fmt.Errorf("session %s: %w: version %d", session, mapstore.ErrFormat, e.Version)
+	}
+	ec := s.encryptionContext(tenant)
+	if contextKey(e.EncryptionContext) != contextKey(ec) {
+		return nil, nil, fmt.Errorf("session %s: %w", session, ErrContext)
+	}
+	// Decrypt under the context expected, not the one stored: KMS is what
+	// refuses an edited entry
+	key, err := s.decryptKey(ctx, e.KMSKeyID, e.EncryptedKey, ec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("session %s: %w", session, err)
+	}
+	m, meta, err := mapstore.Unmarshal(e.File, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("session %s: %w", session, err)
+	}
+	if meta.Session != session {
+		return nil, nil, fmt.Errorf("session %s: %w: entry is for session %q", session, mapstore.ErrUnreadable, meta.Session)
+	}
+	return m, &meta, nil
+}
+
+// Save implements protect.Store for the tenant in ctx. The entry keeps the
+// Created time of the one it replaces.
+func (s *Store) Save(ctx context.Context, session string, m map[string]string) error {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+	meta := mapstore.Metadata{Session: session, Policy: s.Policy, Source: s.Source}
+	var prev entry
+	if data, ok, err := s.kv.Get(ctx, EntryKey(tenant, session)); err == nil && ok && json.Unmarshal(data, &prev) == nil {
+		if old, _, err := mapstore.Inspect(prev.File); err == nil {
+			meta.Created, meta.Labels = old.Created, old.Labels
+		}
+	}
+
+	ec := s.encryptionContext(tenant)
+	key, encrypted, err := s.encryptKey(ctx, ec)
+	if err != nil {
+		return err
+	}
+	file, err := mapstore.Marshal(m, meta, key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry{
+		Format: Format, Version: Version, KMSKeyID: s.keyID,
+		EncryptedKey: encrypted, EncryptionContext: ec, File: file,
+	})
+	if err != nil {
+		return err
+	}
+	return s.kv.Set(ctx, EntryKey(tenant, session), data)
+}
+
+// Stats returns the Store's KMS calls and data key cache hits.
+func (s *Store) Stats() Stats {
+	return s.keys.stats()
+}
@@ -0,0 +1,179 @@
+package kmsstore
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/cache"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapstore"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+)
+
+var local = blindfold.New(blindfold.WithMode("local"))
+
+// fakeKMS encrypts data keys under one AES key with the encryption
+// context as associated data, so Decrypt fails under any other context,
+// as KMS does.
+type fakeKMS struct {
+	aead cipher.AEAD
+}
+
+func newFakeKMS(t *testing.T) *fakeKMS {
+	master := make([]byte, 32)
+	rand.Read(master)
+	block, err := aes.NewCipher(master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, _ := cipher.NewGCM(block)
+	return &fakeKMS{aead: aead}
+}
+
+func (f *fakeKMS) GenerateDataKey(_ context.Context, keyID string, ec map[string]string) ([]byte, []byte, error) {
+	plain := make([]byte, 32)
+	rand.Read(plain)
+	nonce := make([]byte, f.aead.NonceSize())
+	rand.Read(nonce)
+	return plain, f.aead.Seal(nonce, nonce, plain, []byte(keyID+contextKey(ec))), nil
+}
+
+func (f *fakeKMS) Decrypt(_ context.Context, keyID string, blob []byte, ec map[string]string) ([]byte, error) {
+	n := f.aead.NonceSize()
+	if len(blob) < n {
+		return nil, errors.New("InvalidCiphertextException")
+	}
+	plain, err := f.aead.Open(nil, blob[:n], blob[n:], []byte(keyID+contextKey(ec)))
+	if err != nil {
+		return nil, errors.New("InvalidCiphertextException")
+	}
+	return plain, nil
+}
+
+const keyID = "alias/blindfold-mappings"
+
+func TestStoreWithProtector(t *testing.T) {
+	kv := cache.NewLRU(100)
+	store := NewStore(kv, newFakeKMS(t), keyID)
+	store.Context = map[string]string{"app": "support-bot"}
+	p := protect.New(local)
+	p.Store = store
+
+	// Both tenants use session s-1, and each turn is a Load and a Save
+	turns := map[string][]string{
+		"acme":   {"I'm jane.doe@acme.example", "Also cc ops@acme.example"},
+		"globex": {"Reset MFA for raj.mehta@globex.example"},
+	}
+	for tenant, msgs := range turns {
+		ctx := protect.Session(WithTenant(context.Background(), tenant), "s-1")
+		for _, msg := range msgs {
+			if _, err := p.Do(ctx, msg, func(safe string) (string, error) { return safe, nil }); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if st := store.Stats(); st.Generated != 2 || st.Decrypted != 0 {
+		t.Errorf("stats = %+v, want a data key per tenant and no Decrypt calls", st)
+	}
+
+	ctx := WithTenant(context.Background(), "acme")
+	m, err := store.Load(ctx, "s-1")
+	if err != nil || len(m) != 2 || m["<Email Address_2>"] != "ops@acme.example" {
+		t.Errorf("acme s-1 = %v, %v", m, err)
+	}
+	data, _, _ := kv.Get(ctx, EntryKey("acme", "s-1"))
+	if strings.Contains(string(data), "jane.doe") {
+		t.Fatalf("entry holds a value in the clear:\n%s", data)
+	}
+	var e entry
+	json.Unmarshal(data, &e)
+	meta, _, err := mapstore.Inspect(e.File)
+	if err != nil || meta.Session != "s-1" || meta.Tokens() != 2 || e.EncryptionContext["tenant"] != "acme" || e.EncryptionContext["app"] != "support-bot" {
+		t.Errorf("entry: context %v, metadata %+v, %v", e.EncryptionContext, meta, err)
+	}
+
+	if _, err := store.Load(context.Background(), "s-1"); !errors.Is(err, ErrNoTenant) {
+		t.Errorf("no tenant: err = %v", err)
+	}
+	if _, err := store.Load(WithTenant(ctx, "acme/session/s-1"), "s-1"); err == nil {
+		t.Error("loaded with a tenant ID that reaches into another namespace")
+	}
+}
+
+func TestCopiedEntryRefused(t *testing.T) {
+	kv := cache.NewLRU(100)
+	store := NewStore(kv, newFakeKMS(t), keyID)
+	acme, globex := WithTenant(context.Background(), "acme"), WithTenant(context.Background(), "globex")
+	if err := store.Save(acme, "s-1", map[string]string{"<Email Address_1>": "jane.doe@acme.example"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _, _ := kv.Get(acme, EntryKey("acme", "s-1"))
+
+	// Copied as it is: the context names acme
+	kv.Set(globex, EntryKey("globex", "s-1"), data)
+	if _, err := store.Load(globex, "s-1"); !errors.Is(err, ErrContext) {
+		t.Errorf("copied entry: err = %v", err)
+	}
+
+	// With its context edited to globex's: KMS refuses the data key
+	var e entry
+	json.Unmarshal(data, &e)
+	e.EncryptionContext["tenant"] = "globex"
+	edited, _ := json.Marshal(e)
+	kv.Set(globex, EntryKey("globex", "s-1"), edited)
+	if _, err := store.Load(globex, "s-1"); err == nil || !strings.Contains(err.Error(), "decrypt data key") {
+		t.Errorf("edited context: err = %v", err)
+	}
+
+	// Under another session of the same tenant: the file names its own
+	kv.Set(acme, EntryKey("acme", "s-2"), data)
+	if _, err := store.Load(acme, "s-2"); !errors.Is(err, mapstore.ErrUnreadable) {
+		t.Errorf("other session: err = %v", err)
+	}
+}
+
+func TestKeyCacheLimits(t *testing.T) {
+	kms := newFakeKMS(t)
+	kv := cache.NewLRU(100)
+	ctx := WithTenant(context.Background(), "acme")
+	m := map[string]string{"<Email Address_1>": "jane.doe@acme.example"}
+
+	writer := NewStore(kv, kms, keyID)
+	writer.MaxUses = 2
+	for _, s := range []string{"s-1", "s-2", "s-3"} {
+		if err := writer.Save(ctx, s, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if st := writer.Stats(); st.Generated != 2 || st.Hits != 1 {
+		t.Errorf("writer stats = %+v, want 2 data keys for 3 entries", st)
+	}
+
+	// Another process reads s-1 and s-2, under one data key, with one
+	// Decrypt call
+	reader := NewStore(kv, kms, keyID)
+	for _, s := range []string{"s-1", "s-2", "s-1"} {
+		if got, err := reader.Load(ctx, s); err != nil || got["<Email Address_1>"] != "jane.doe@acme.example" {
+			t.Fatalf("%s = %v, %v", s, got, err)
+		}
+	}
+	if st := reader.Stats(); st.Decrypted != 1 || st.Hits != 2 {
+		t.Errorf("reader stats = %+v", st)
+	}
+
+	expired := NewStore(kv, kms, keyID)
+	expired.MaxAge = time.Nanosecond
+	expired.Load(ctx, "s-1")
+	expired.Load(ctx, "s-1")
+	if st := expired.Stats(); st.Decrypted != 2 || st.Hits != 0 {
+		t.Errorf("expired stats = %+v", st)
+	}
+}
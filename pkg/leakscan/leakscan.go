@@ -0,0 +1,177 @@
+// Package leakscan scans raw model output, before detokenization, for
+// real PII the model produced on its own.
+//
+// The prompt went out tokenized, so the raw reply should hold tokens and
+// nothing else personal. When it doesn't, the model made the value up or
+// recalled it from training data: "you can also reach Jane at
+// jane.doe@gmail.com", a plausible phone number for a business, a card
+// number from a tutorial it memorized. Detokenizing doesn't touch those,
+// and the reply then shows the user PII that was never theirs. Scanning
+// before detokenization is the one point where every real value in the
+// text is new: tokens are blanked out, the mapping's values aren't in the
+// text yet, and whatever Detect still finds came from the model.
+//
+//	s := leakscan.New(bf, leakscan.Redact)
+//	text, leaks, err := s.Detokenize(ctx, reply, tokenized.Mapping, tokenized.Text)
+//
+// Values that were sent to the model in the clear (pass the prompt texts
+// as sent) are not counted as leaks: the model is only repeating them.
+package leakscan
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/redact"
+)
+
+// Action is what a Scanner does with a leak.
+type Action int
+
+const (
+	// Flag reports leaks and leaves the text unchanged.
+	Flag Action = iota
+	// Redact replaces each leak with its redact.Label.
+	Redact
+)
+
+// Leak is one value in model output that the prompt didn't contain. It
+// holds the type and position, never the value.
+type Leak struct {
+	// Choice is the index of the response choice; 0 for Scan.
+	Choice     int
+	Type       string
+	Start, End int // byte offsets into the raw output
+	Score      float64
+}
+
+// Scanner scans model output with a detector. Set its fields before
+// first use.
+type Scanner struct {
+	d      redact.Detector
+	Action Action
+	// MinScore ignores entities scored below it.
+	MinScore float64
+	// OnLeak, if set, is called with the leaks of every scan that found
+	// any, before the action is applied.
+	OnLeak func(ctx context.Context, leaks []Leak)
+	// CallOptions apply to every Detect call.
+	CallOptions []blindfold.CallOption
+}
+
+// New returns a scanner that detects with d and applies action.
+func New(d redact.Detector, action Action) *Scanner {
+	return &Scanner{d: d, Action: action}
+}
+
+// Scan scans raw output and returns it with the action applied. Entities
+// whose value appears verbatim in one of sent, the texts the model was
+// given, are not leaks. A detection error is returned as is, with no
+// text: output that couldn't be scanned shouldn't be shown.
+func (s *Scanner) Scan(ctx context.Context, output string, sent ...string) (string, []Leak, error) {
+	return s.scan(ctx, 0, output, sent)
+}
+
+func (s *Scanner) scan(ctx context.Context, choice int, output string, sent []string) (string, []Leak, error) {
+	if output == "" {
+		return output, nil, nil
+	}
+	// Blank tokens to the same length, so offsets line up and a token
+	// never reads as PII.
+	blanked := mapping.TokenPattern.ReplaceAllStringFunc(output, func(tok string) string {
+		return strings.Repeat(" ", len(tok))
+	})
+	res, err := s.d.Detect(ctx, blanked, s.CallOptions...)
+	if err != nil {
+		return "", nil, fmt.Errorf("leakscan: %w", err)
+	}
+	ents := res.DetectedEntities
+	sort.SliceStable(ents, func(a, b int) bool { return ents[a].Start < ents[b].Start })
+	var leaks []Leak
+	for _, e := range ents {
+		if e.Score < s.MinScore || wasSent(output[e.Start:e.End], sent) {
+			continue
+		}
+		leaks = append(leaks, Leak{Choice: choice, Type: e.Type, Start: e.Start, End: e.End, Score: e.Score})
+	}
+	if len(leaks) == 0 {
+		return output, nil, nil
+	}
+	if s.OnLeak != nil {
+		s.OnLeak(ctx, leaks)
+	}
+	if s.Action == Redact {
+		for i := len(leaks) - 1; i >= 0; i-- {
+			l := leaks[i]
+			output = output[:l.Start] + redact.Label(l.Type) + output[l.End:]
+		}
+	}
+	return output, leaks, nil
+}
+
+func wasSent(value string, sent []string) bool {
+	for _, t := range sent {
+		if strings.Contains(t, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Detokenize scans raw output, then restores m's tokens in the result.
+// Leaks are found before any real value is put back, so restored values
+// are never mistaken for new ones.
+func (s *Scanner) Detokenize(ctx context.Context, output string, m map[string]string, sent ...string) (string, []Leak, error) {
+	out, leaks, err := s.Scan(ctx, output, sent...)
+	if err != nil {
+		return "", leaks, err
+	}
+	return mapping.Detokenize(out, m), leaks, nil
+}
+
+// ChatClient scans every response with a scanner before returning it.
+type ChatClient struct {
+	next bfclient.ChatCompleter
+	s    *Scanner
+}
+
+var _ bfclient.ChatCompleter = (*ChatClient)(nil)
+
+// WrapChat returns next with every response scanned by s. It goes
+// between the tokenizing code and the model, so responses are scanned
+// before the caller detokenizes them.
+func (s *Scanner) WrapChat(next bfclient.ChatCompleter) *ChatClient {
+	return &ChatClient{next: next, s: s}
+}
+
+// CreateChatCompletion calls next and scans the content of every choice.
+// The request's message contents count as sent. If a scan fails, the
+// response is not returned.
+func (c *ChatClient) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	res, err := c.next.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return res, err
+	}
+	var sent []string
+	for _, m := range req.Messages {
+		sent = append(sent, m.Content)
+		for _, p := range m.MultiContent {
+			sent = append(sent, p.Text)
+		}
+	}
+	for i := range res.Choices {
+		content, _, err := c.s.scan(ctx, i, res.Choices[i].Message.Content, sent)
+		if err != nil {
+			return openai.ChatCompletionResponse{}, err
+		}
+		res.Choices[i].Message.Content = content
+	}
+	return res, nil
+}
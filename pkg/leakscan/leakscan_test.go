@@ -0,0 +1,59 @@
+package leakscan
+
+import (
+	"context"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+var local = blindfold.New(blindfold.WithMode("local"))
+
+const reply = "I've refunded <Email Address_1>. For faster help, email support@example.org or call 415-555-0134."
+
+func TestDetokenize(t *testing.T) {
+	m := map[string]string{"<Email Address_1>": "jane.doe@example.com"}
+	sent := "Refund <Email Address_1>. Our line is 415-555-0134."
+
+	s := New(local, Redact)
+	out, leaks, err := s.Detokenize(context.Background(), reply, m, sent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "I've refunded jane.doe@example.com. For faster help, email [Email Address] or call 415-555-0134."; out != want {
+		t.Errorf("out = %q, want %q", out, want)
+	}
+	if len(leaks) != 1 || leaks[0].Type != "Email Address" || reply[leaks[0].Start:leaks[0].End] != "support@example.org" {
+		t.Errorf("leaks = %+v", leaks)
+	}
+
+	s.Action = Flag
+	out, leaks, _ = s.Scan(context.Background(), reply)
+	if out != reply || len(leaks) != 2 {
+		t.Errorf("flag: out = %q, leaks = %+v", out, leaks)
+	}
+}
+
+type canned struct{ content []string }
+
+func (c canned) CreateChatCompletion(context.Context, openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	var res openai.ChatCompletionResponse
+	for _, s := range c.content {
+		res.Choices = append(res.Choices, openai.ChatCompletionChoice{Message: openai.ChatCompletionMessage{Content: s}})
+	}
+	return res, nil
+}
+
+func TestWrapChat(t *testing.T) {
+	var got []Leak
+	s := New(local, Redact)
+	s.OnLeak = func(_ context.Context, l []Leak) { got = append(got, l...) }
+	res, err := s.WrapChat(canned{[]string{"Hi <Person_1>", reply}}).CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Choices[0].Message.Content != "Hi <Person_1>" || len(got) != 2 || got[0].Choice != 1 {
+		t.Errorf("res = %+v, leaks = %+v", res.Choices, got)
+	}
+}
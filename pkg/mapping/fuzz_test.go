@@ -0,0 +1,216 @@
+package mapping
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+// Run a target with, for example:
+//
+//	go test -run '^$' -fuzz FuzzDetokenizeMutated -fuzztime 60s ./pkg/mapping
+//
+// Plain `go test` runs only the seed corpus below.
+
+var fuzzScanner = blindfold.NewPIIScanner([]string{"us", "eu", "uk"})
+
+var seedTexts = []string{
+	"",
+	"no pii here",
+	"Email john@acme.com or call +1 415-555-0134.",
+	"<john@acme.com> and <<jane@acme.com>>",
+	"card 4111 1111 1111 1111, ip 10.0.0.1, ssn 123-45-6789",
+	"a@b.co a@b.co a@b.co",
+	"ünïcödé — ünï@exämple.com ☃ 10.0.0.1",
+	"Address_1> <Email Address_ john@acme.com _1>",
+}
+
+// segment is a piece of tokenized text: a literal, or a token and the value
+// it stands for.
+type segment struct {
+	text  string
+	value string
+	token bool
+}
+
+// split cuts tokenized text into literal and token segments.
+func split(text string, m map[string]string) []segment {
+	var segs []segment
+	last := 0
+	for _, loc := range TokenPattern.FindAllStringIndex(text, -1) {
+		tok := text[loc[0]:loc[1]]
+		v, ok := m[tok]
+		if !ok {
+			continue
+		}
+		segs = append(segs, segment{text: text[last:loc[0]]}, segment{text: tok, value: v, token: true})
+		last = loc[1]
+	}
+	return append(segs, segment{text: text[last:]})
+}
+
+// noise turns fuzz bytes into text an LLM might add around a token. It
+// holds no '<', '>' or newline, so it can never complete or break a token.
+func noise(b byte) string {
+	words := []string{"", " ", "Dear ", ", ", "the customer ", "(", ")", "ünï ", "_", "1", "Address"}
+	return words[int(b)%len(words)]
+}
+
+// FuzzDetokenizeRoundTrip checks that detokenizing freshly tokenized text
+// restores the original exactly.
+func FuzzDetokenizeRoundTrip(f *testing.F) { fuzzRoundTrip(f, Detokenize) }
+
+// FuzzSDKDetokenizeRoundTrip is FuzzDetokenizeRoundTrip for the SDK's
+// multi-pass Detokenize, which bfclient.Client wrappers end in.
+func FuzzSDKDetokenizeRoundTrip(f *testing.F) { fuzzRoundTrip(f, fuzzScanner.Detokenize) }
+
+func fuzzRoundTrip(f *testing.F, detokenize func(string, map[string]string) string) {
+	for _, s := range seedTexts {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, text string) {
+		if TokenPattern.MatchString(text) {
+			// Text that already contains token-shaped strings is ambiguous:
+			// a literal "<Email Address_1>" is indistinguishable from a token.
+			t.Skip()
+		}
+		res := fuzzScanner.Tokenize(text, nil)
+		if got := detokenize(res.Text, res.Mapping); got != text {
+			t.Fatalf("round trip changed text\n got: %q\nwant: %q\ntokenized: %q\nmapping: %v", got, text, res.Text, res.Mapping)
+		}
+	})
+}
+
+// FuzzDetokenizeMutated simulates an LLM rewriting tokenized text: noise is
+// inserted before tokens, and tokens are duplicated, dropped, or mangled.
+// Intact tokens must be restored, mangled ones left alone, and everything
+// around them preserved byte for byte.
+func FuzzDetokenizeMutated(f *testing.F) { fuzzMutated(f, Detokenize) }
+
+// FuzzSDKDetokenizeMutated is FuzzDetokenizeMutated for the SDK's
+// Detokenize.
+func FuzzSDKDetokenizeMutated(f *testing.F) { fuzzMutated(f, fuzzScanner.Detokenize) }
+
+func fuzzMutated(f *testing.F, detokenize func(string, map[string]string) string) {
+	for i, s := range seedTexts {
+		f.Add(s, []byte{byte(i), 1, 2, 3, 4, 5, 6, 7})
+	}
+	f.Fuzz(func(t *testing.T, text string, ops []byte) {
+		if TokenPattern.MatchString(text) {
+			t.Skip()
+		}
+		res := fuzzScanner.Tokenize(text, nil)
+
+		var mutated, want strings.Builder
+		op := 0
+		next := func() byte {
+			if len(ops) == 0 {
+				return 0
+			}
+			b := ops[op%len(ops)]
+			op++
+			return b
+		}
+		for _, seg := range split(res.Text, res.Mapping) {
+			if !seg.token {
+				mutated.WriteString(seg.text)
+				want.WriteString(seg.text)
+				continue
+			}
+			n := noise(next())
+			mutated.WriteString(n)
+			want.WriteString(n)
+			switch next() % 4 {
+			case 0: // keep
+				mutated.WriteString(seg.text)
+				want.WriteString(seg.value)
+			case 1: // duplicate
+				mutated.WriteString(seg.text + seg.text)
+				want.WriteString(seg.value + seg.value)
+			case 2: // drop
+			case 3: // mangle: <Type_1> → <Type-1>, which is no longer a token
+				i := strings.LastIndex(seg.text, "_")
+				mangled := seg.text[:i] + "-" + seg.text[i+1:]
+				mutated.WriteString(mangled)
+				want.WriteString(mangled)
+			}
+		}
+
+		if got := detokenize(mutated.String(), res.Mapping); got != want.String() {
+			t.Fatalf("detokenize corrupted text\n got: %q\nwant: %q\ninput: %q\nmapping: %v", got, want.String(), mutated.String(), res.Mapping)
+		}
+	})
+}
+
+// referenceDetokenize is a slow, obviously-correct single-pass detokenizer:
+// at each position the longest matching key wins, then the scan moves past
+// the replaced key.
+func referenceDetokenize(text string, m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		matched := false
+		for _, k := range keys {
+			if strings.HasPrefix(text[i:], k) {
+				b.WriteString(m[k])
+				i += len(k)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteByte(text[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// FuzzDetokenizeMalformedMapping feeds arbitrary mappings — empty keys,
+// keys that overlap or prefix each other, values that contain tokens — and
+// compares against the reference implementation. It must never panic or
+// re-substitute a restored value.
+func FuzzDetokenizeMalformedMapping(f *testing.F) {
+	f.Add("<Person_1> met <Person_12>", "<Person_1>", "<Person_12>", "<Person_12>", "Ann")
+	f.Add("<A_1><A_1", "<A_1", "x", "<A_1>", "<A_1")
+	f.Add("aaaa", "a", "aa", "aa", "a")
+	f.Add("<Email Address_1>", "", "boom", "<Email Address_1>", "<Email Address_1>")
+	f.Fuzz(func(t *testing.T, text, k1, v1, k2, v2 string) {
+		m := map[string]string{k1: v1, k2: v2}
+		got := Detokenize(text, m)
+		if want := referenceDetokenize(text, m); got != want {
+			t.Fatalf("Detokenize(%q, %v)\n got: %q\nwant: %q", text, m, got, want)
+		}
+	})
+}
+
+// FuzzParseToken checks that ParseToken never panics and that formatting a
+// parsed token parses back to the same parts.
+func FuzzParseToken(f *testing.F) {
+	for _, s := range []string{"<Person_1>", "<Email Address_12>", "<a_b_3>", "<_1>", "<x_007>", "<<x_1>>", "x_1"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, token string) {
+		typ, n, ok := ParseToken(token)
+		if !ok {
+			return
+		}
+		typ2, n2, ok2 := ParseToken(FormatToken(typ, n))
+		if !ok2 || typ2 != typ || n2 != n {
+			t.Fatalf("ParseToken(%q) = %q, %d; reformatted parses as %q, %d, %v", token, typ, n, typ2, n2, ok2)
+		}
+	})
+}
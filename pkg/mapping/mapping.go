@@ -0,0 +1,246 @@
+// Package mapping provides helpers for Blindfold token mappings — the
+// token → original value maps returned by Tokenize.
+//
+// The main entry point is Merge, which combines mappings produced by
+// independent Tokenize calls (for example, one call per document chunk run
+// in parallel) into a single consistent mapping. Each call numbers its
+// tokens from 1, so without merging the same person can show up as
+// <Person_1> in one chunk and <Person_3> in another, while <Person_1> in a
+// third chunk refers to someone else entirely.
+package mapping
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TokenPattern matches Blindfold placeholder tokens such as <Person_1> or
+// <Email Address_12>. The first group is the entity type, the second the
+// sequence number.
+var TokenPattern = regexp.MustCompile(`<([^<>\n]+)_(\d+)>`)
+
+// ParseToken splits a token into its entity type and sequence number.
+func ParseToken(token string) (entityType string, n int, ok bool) {
+	m := TokenPattern.FindStringSubmatch(token)
+	if m == nil || m[0] != token {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(m[2])
+	if err != nil {
+		return "", 0, false
+	}
+	return m[1], n, true
+}
+
+// FormatToken builds the token for an entity type and sequence number.
+func FormatToken(entityType string, n int) string {
+	return fmt.Sprintf("<%s_%d>", entityType, n)
+}
+
+// ReplaceTokens rewrites every token in text using fn. All tokens are
+// replaced in a single pass, so renames may swap or chain safely
+// (<Person_1> → <Person_2> and <Person_2> → <Person_1> at the same time).
+func ReplaceTokens(text string, fn func(token string) string) string {
+	return TokenPattern.ReplaceAllStringFunc(text, fn)
+}
+
+// ConflictKind classifies a conflict found while merging.
+type ConflictKind int
+
+const (
+	// SameValue means one value received different tokens in different
+	// inputs. The later token is renamed to the first one seen.
+	SameValue ConflictKind = iota
+	// SameToken means one token stood for different values in different
+	// inputs. The later occurrence is renamed to a fresh token.
+	SameToken
+)
+
+func (k ConflictKind) String() string {
+	switch k {
+	case SameValue:
+		return "same-value"
+	case SameToken:
+		return "same-token"
+	default:
+		return "unknown"
+	}
+}
+
+// Conflict describes one token that had to be rewritten during a merge.
+type Conflict struct {
+	Kind     ConflictKind
+	Source   int    // index of the input mapping that was rewritten
+	Token    string // token as it appeared in that input
+	Resolved string // token it maps to in the merged mapping; empty if unresolved
+}
+
+// Merged is the result of merging several mappings.
+type Merged struct {
+	// Mapping is the combined token → value map, valid for every
+	// rewritten input.
+	Mapping map[string]string
+	// Renames holds, per input, the tokens that changed (old → new).
+	Renames []map[string]string
+	// Conflicts lists every rename in the order it was decided.
+	Conflicts []Conflict
+}
+
+// Rewrite applies the renames of input i to its tokenized text.
+func (m *Merged) Rewrite(i int, text string) string {
+	renames := m.Renames[i]
+	if len(renames) == 0 {
+		return text
+	}
+	return ReplaceTokens(text, func(token string) string {
+		if r, ok := renames[token]; ok {
+			return r
+		}
+		return token
+	})
+}
+
+// Apply rewrites a slice of tokenized texts, one per input mapping.
+func (m *Merged) Apply(texts []string) []string {
+	out := make([]string, len(texts))
+	for i, t := range texts {
+		out[i] = m.Rewrite(i, t)
+	}
+	return out
+}
+
+// Fold gives each value in the result of one Tokenize call a single token.
+// Local mode tokenizes every occurrence separately, so a customer who
+// writes their email twice gets <Email Address_1> and <Email Address_2>;
+// Fold rewrites text to use the first and drops the second from the
+// mapping.
+func Fold(text string, m map[string]string) (string, map[string]string) {
+	merged := Merge(m)
+	return merged.Rewrite(0, text), merged.Mapping
+}
+
+// Merge combines mappings from independent Tokenize calls.
+//
+// Inputs are processed in order and tokens within an input in entity-type
+// and sequence order, so the result is deterministic. Tokens from the first
+// input are never renamed. A value seen before (same entity type, same
+// text) reuses its existing token; a token already bound to a different
+// value is renamed to the next free number for its entity type.
+func Merge(mappings ...map[string]string) *Merged {
+	merged := &Merged{
+		Mapping: make(map[string]string),
+		Renames: make([]map[string]string, len(mappings)),
+	}
+	byValue := make(map[string]string) // entity type + value → merged token
+	highest := make(map[string]int)    // entity type → highest sequence number in use
+
+	for _, m := range mappings {
+		for _, token := range sortedTokens(m) {
+			if typ, n, ok := ParseToken(token); ok && n > highest[typ] {
+				highest[typ] = n
+			}
+		}
+	}
+
+	for i, m := range mappings {
+		merged.Renames[i] = make(map[string]string)
+		for _, token := range sortedTokens(m) {
+			value := m[token]
+			typ, _, parsed := ParseToken(token)
+			key := typ + "\x00" + value
+
+			if existing, ok := byValue[key]; ok {
+				if existing != token {
+					merged.Renames[i][token] = existing
+					merged.Conflicts = append(merged.Conflicts, Conflict{Kind: SameValue, Source: i, Token: token, Resolved: existing})
+				}
+				continue
+			}
+
+			target := token
+			if prev, taken := merged.Mapping[token]; taken && prev != value {
+				if !parsed {
+					// Not a Blindfold token, so there is no numbering to
+					// renumber into. Keep the first value and report it.
+					merged.Conflicts = append(merged.Conflicts, Conflict{Kind: SameToken, Source: i, Token: token})
+					continue
+				}
+				highest[typ]++
+				target = FormatToken(typ, highest[typ])
+				merged.Renames[i][token] = target
+				merged.Conflicts = append(merged.Conflicts, Conflict{Kind: SameToken, Source: i, Token: token, Resolved: target})
+			}
+			merged.Mapping[target] = value
+			byValue[key] = target
+		}
+	}
+	return merged
+}
+
+// sortedTokens returns the tokens of m ordered by entity type, then by
+// sequence number. Tokens that do not follow the Blindfold format sort last.
+func sortedTokens(m map[string]string) []string {
+	tokens := make([]string, 0, len(m))
+	for t := range m {
+		tokens = append(tokens, t)
+	}
+	sort.Slice(tokens, func(a, b int) bool {
+		ta, na, oka := ParseToken(tokens[a])
+		tb, nb, okb := ParseToken(tokens[b])
+		switch {
+		case oka != okb:
+			return oka
+		case !oka:
+			return tokens[a] < tokens[b]
+		case ta != tb:
+			return ta < tb
+		default:
+			return na < nb
+		}
+	})
+	return tokens
+}
+
+// Detokenize restores every mapping key in text with its value in a single
+// left-to-right pass; where keys overlap, the longest wins. Restored values
+// are never scanned again, so a value that happens to look like a token
+// (or contain one) comes back verbatim. Empty keys are ignored.
+func Detokenize(text string, m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return text
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	pairs := make([]string, 0, 2*len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k, m[k])
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Unresolved returns the token-shaped strings in text that have no entry in
+// m, in order of appearance. After detokenization a non-empty result means
+// placeholders leaked through — usually because the model mangled or
+// invented them.
+func Unresolved(text string, m map[string]string) []string {
+	var out []string
+	for _, token := range TokenPattern.FindAllString(text, -1) {
+		if _, ok := m[token]; !ok {
+			out = append(out, token)
+		}
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package mapping
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	// Three chunks tokenized independently, each numbering from 1
+	texts := []string{
+		"<Person_1> emailed <Email Address_1>.",
+		"<Person_1> replied to <Person_2>.",
+		"<Person_2> copied <Person_1> and <Email Address_1>.",
+	}
+	mappings := []map[string]string{
+		{"<Person_1>": "Jane Doe", "<Email Address_1>": "jane@example.com"},
+		{"<Person_1>": "John Roe", "<Person_2>": "Jane Doe"},
+		{"<Person_1>": "Ann Lee", "<Person_2>": "John Roe", "<Email Address_1>": "jane@example.com"},
+	}
+	m := Merge(mappings...)
+
+	want := map[string]string{
+		"<Person_1>":        "Jane Doe",
+		"<Person_3>":        "John Roe",
+		"<Person_4>":        "Ann Lee",
+		"<Email Address_1>": "jane@example.com",
+	}
+	if !reflect.DeepEqual(m.Mapping, want) {
+		t.Errorf("mapping = %v\nwant      %v", m.Mapping, want)
+	}
+	// The same value keeps one token in every chunk; a taken token is
+	// renumbered past the highest number any input used
+	got := m.Apply(texts)
+	wantTexts := []string{
+		"<Person_1> emailed <Email Address_1>.",
+		"<Person_3> replied to <Person_1>.",
+		"<Person_3> copied <Person_4> and <Email Address_1>.",
+	}
+	if !reflect.DeepEqual(got, wantTexts) {
+		t.Errorf("texts = %q\nwant    %q", got, wantTexts)
+	}
+	for i, text := range got {
+		if orig, restored := Detokenize(texts[i], mappings[i]), Detokenize(text, m.Mapping); orig != restored {
+			t.Errorf("chunk %d restores to %q, want %q", i, restored, orig)
+		}
+	}
+
+	wantConflicts := []Conflict{
+		{Kind: SameToken, Source: 1, Token: "<Person_1>", Resolved: "<Person_3>"},
+		{Kind: SameValue, Source: 1, Token: "<Person_2>", Resolved: "<Person_1>"},
+		{Kind: SameToken, Source: 2, Token: "<Person_1>", Resolved: "<Person_4>"},
+		{Kind: SameValue, Source: 2, Token: "<Person_2>", Resolved: "<Person_3>"},
+	}
+	if !reflect.DeepEqual(m.Conflicts, wantConflicts) {
+		t.Errorf("conflicts = %+v\nwant        %+v", m.Conflicts, wantConflicts)
+	}
+	if len(m.Renames[0]) != 0 {
+		t.Errorf("first input renamed: %v", m.Renames[0])
+	}
+}
+
+func TestMergeSwapsInOnePass(t *testing.T) {
+	m := Merge(
+		map[string]string{"<Person_1>": "Jane Doe", "<Person_2>": "John Roe"},
+		map[string]string{"<Person_1>": "John Roe", "<Person_2>": "Jane Doe"},
+	)
+	if got := m.Rewrite(1, "<Person_1> and <Person_2>"); got != "<Person_2> and <Person_1>" {
+		t.Errorf("swapped tokens rewrite to %q", got)
+	}
+}
+
+func TestMergeSameValueOtherType(t *testing.T) {
+	// A value is only the same entity within one type
+	m := Merge(
+		map[string]string{"<Person_1>": "Jordan"},
+		map[string]string{"<Location_1>": "Jordan"},
+	)
+	if len(m.Mapping) != 2 || len(m.Conflicts) != 0 {
+		t.Errorf("mapping = %v, conflicts = %v", m.Mapping, m.Conflicts)
+	}
+}
+
+func TestMergeNonBlindfoldTokens(t *testing.T) {
+	m := Merge(
+		map[string]string{"[name]": "Jane Doe"},
+		map[string]string{"[name]": "John Roe"},
+	)
+	if m.Mapping["[name]"] != "Jane Doe" || len(m.Conflicts) != 1 || m.Conflicts[0].Resolved != "" {
+		t.Errorf("mapping = %v, conflicts = %+v; want the first value kept and an unresolved conflict", m.Mapping, m.Conflicts)
+	}
+}
+
+func TestFold(t *testing.T) {
+	// Local mode tokenizes each occurrence on its own
+	text, m := Fold("<Email Address_1>, again: <Email Address_2>. CC <Email Address_3>", map[string]string{
+		"<Email Address_1>": "jane@example.com",
+		"<Email Address_2>": "jane@example.com",
+		"<Email Address_3>": "john@example.com",
+	})
+	if text != "<Email Address_1>, again: <Email Address_1>. CC <Email Address_3>" {
+		t.Errorf("text = %q", text)
+	}
+	want := map[string]string{"<Email Address_1>": "jane@example.com", "<Email Address_3>": "john@example.com"}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("mapping = %v, want %v", m, want)
+	}
+}
+
+func TestParseFormatToken(t *testing.T) {
+	for _, tok := range []string{"<Person_1>", "<Email Address_12>"} {
+		typ, n, ok := ParseToken(tok)
+		if !ok || FormatToken(typ, n) != tok {
+			t.Errorf("%s: %q %d %v", tok, typ, n, ok)
+		}
+	}
+	for _, tok := range []string{"Person_1", "<Person_1> ", "<Person>", "<Person_x>"} {
+		if _, _, ok := ParseToken(tok); ok {
+			t.Errorf("%q parsed", tok)
+		}
+	}
+}
+
+func TestUnresolved(t *testing.T) {
+	got := Unresolved("Hi <Person_1>, <Person_3> and <Person 1>", map[string]string{"<Person_1>": "Jane"})
+	if !reflect.DeepEqual(got, []string{"<Person_3>"}) {
+		t.Errorf("Unresolved = %v", got)
+	}
+}
@@ -0,0 +1,366 @@
+// Package mapstore defines the mapping file: a token mapping encrypted
+// with AES-256-GCM, with enough metadata in the clear to tell what it is
+// without the key. It is the one format mappings take when they leave the
+// process that made them, so the CLI, the services and an offline review
+// can hand them to each other:
+//
+//	keys, _ := mapstore.ParseKeys(os.Getenv("BLINDFOLD_MAPPING_KEYS"))
+//	err := mapstore.Export(w, res.Mapping, mapstore.Metadata{Session: id, Policy: "strict"}, keys[0])
+//	...
+//	m, meta, err := mapstore.Import(r, keys...)
+//
+// A file is one JSON object:
+//
+//	{
+//	  "format": "blindfold-mapping",
+//	  "version": 1,
+//	  "key_id": "2026-10",
+//	  "metadata": {"session": "case-4411", "policy": "strict",
+//	               "created": "...", "updated": "...",
+//	               "entities": {"Email Address": 2, "Person": 1}},
+//	  "nonce": "base64...",
+//	  "ciphertext": "base64..."
+//	}
+//
+// The ciphertext holds the mapping and a copy of the metadata. Import
+// checks the copy against the clear metadata, so a file whose metadata was
+// edited is refused, while a file that was only reformatted still opens.
+// The header (format, version, key ID) is bound in as associated data.
+//
+// Metadata never holds a value: entity counts come from the token names.
+//
+// Readers take several keys and pick one by the file's key ID, so files
+// under an old key and a new one read side by side while a key is
+// rotated; Rewrap and Store.Rotate move them to the new one.
+package mapstore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+const (
+	// Format names the file format in every file.
+	Format = "blindfold-mapping"
+	// Version is the format version this package writes. Import reads
+	// versions up to it.
+	Version = 1
+)
+
+var (
+	// ErrFormat means the input is not a mapping file this package can
+	// read: another format, or a later version.
+	ErrFormat = errors.New("mapstore: not a supported mapping file")
+	// ErrUnknownKey means none of the keys given has the file's key ID.
+	ErrUnknownKey = errors.New("mapstore: no key with the file's key ID")
+	// ErrUnreadable means the file failed to decrypt under the key with
+	// its ID, or its metadata doesn't match the encrypted copy: the key
+	// is wrong or the file was altered.
+	ErrUnreadable = errors.New("mapstore: mapping file unreadable")
+)
+
+// Metadata describes a mapping without revealing it.
+type Metadata struct {
+	// Session is the conversation, document or case the mapping belongs
+	// to.
+	Session string `json:"session,omitempty"`
+	// Policy names the policy the mapping was tokenized under.
+	Policy string `json:"policy,omitempty"`
+	// Source names what wrote the file, such as "gateway" or
+	// "blindfold mapping export".
+	Source string `json:"source,omitempty"`
+	// Created is when the mapping was first written. Export sets it if
+	// it is zero.
+	Created time.Time `json:"created"`
+	// Updated is when the file was written. Export sets it.
+	Updated time.Time `json:"updated"`
+	// Entities counts the tokens by entity type. Export sets it.
+	Entities map[string]int `json:"entities"`
+	// Labels are free-form annotations, such as a ticket number for a
+	// review. They are readable without the key: keep values out.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Tokens returns the number of tokens in the mapping.
+func (m Metadata) Tokens() int {
+	n := 0
+	for _, c := range m.Entities {
+		n += c
+	}
+	return n
+}
+
+// envelope is the file as stored.
+type envelope struct {
+	Format     string   `json:"format"`
+	Version    int      `json:"version"`
+	KeyID      string   `json:"key_id"`
+	Metadata   Metadata `json:"metadata"`
+	Nonce      []byte   `json:"nonce"`
+	Ciphertext []byte   `json:"ciphertext"`
+}
+
+// payload is what is encrypted.
+type payload struct {
+	Metadata Metadata          `json:"metadata"`
+	Mapping  map[string]string `json:"mapping"`
+}
+
+// Key is an AES-256 key and the ID files record, so a reader holding
+// several keys knows which one opens a file.
+type Key struct {
+	ID     string
+	Secret []byte // 32 bytes
+}
+
+var keyID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// NewKey returns a random key with id.
+func NewKey(id string) (Key, error) {
+	k := Key{ID: id, Secret: make([]byte, 32)}
+	if _, err := rand.Read(k.Secret); err != nil {
+		return Key{}, err
+	}
+	return k, k.check()
+}
+
+func (k Key) check() error {
+	if !keyID.MatchString(k.ID) {
+		return fmt.Errorf("mapstore: invalid key ID %q", k.ID)
+	}
+	if len(k.Secret) != 32 {
+		return fmt.Errorf("mapstore: key %s: want 32 bytes, got %d", k.ID, len(k.Secret))
+	}
+	return nil
+}
+
+// Encode returns the key as "id:base64", the form ParseKeys reads. It is
+// the secret itself: print it only to where it is stored.
+func (k Key) Encode() string {
+	return k.ID + ":" + base64.StdEncoding.EncodeToString(k.Secret)
+}
+
+// ParseKeys reads comma-separated "id:base64" keys, such as the value of
+// BLINDFOLD_MAPPING_KEYS. The first key is the one to write with; the
+// others only read files written before it.
+func ParseKeys(s string) ([]Key, error) {
+	var keys []Key
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, errors.New("mapstore: want keys as id:base64")
+		}
+		raw, err := base64.StdEncoding.DecodeString(secret)
+		if err != nil {
+			return nil, fmt.Errorf("mapstore: key %s: %v", id, err)
+		}
+		k := Key{ID: id, Secret: raw}
+		if err := k.check(); err != nil {
+			return nil, err
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("mapstore: key ID %s listed twice", id)
+		}
+		seen[id] = true
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("mapstore: no keys")
+	}
+	return keys, nil
+}
+
+func (k Key) aead() (cipher.AEAD, error) {
+	if err := k.check(); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(k.Secret)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// associatedData binds the header into the ciphertext, so a file can't be
+// passed off as another version or as written under another key.
+func associatedData(version int, keyID string) []byte {
+	return []byte(fmt.Sprintf("%s\x00%d\x00%s", Format, version, keyID))
+}
+
+// Entities counts the tokens of m by entity type.
+func Entities(m map[string]string) map[string]int {
+	counts := make(map[string]int)
+	for token := range m {
+		if typ, _, ok := mapping.ParseToken(token); ok {
+			counts[typ]++
+		}
+	}
+	return counts
+}
+
+// Marshal encrypts m under key and returns the file. It sets meta's
+// Updated and Entities, and Created if it is zero.
+func Marshal(m map[string]string, meta Metadata, key Key) ([]byte, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	if meta.Created.IsZero() {
+		meta.Created = now
+	}
+	meta.Updated, meta.Entities = now, Entities(m)
+	return seal(m, meta, key)
+}
+
+// seal encrypts m and meta as they are.
+func seal(m map[string]string, meta Metadata, key Key) ([]byte, error) {
+	aead, err := key.aead()
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		m = map[string]string{}
+	}
+	plain, err := json.Marshal(payload{Metadata: meta, Mapping: m})
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	env := envelope{
+		Format: Format, Version: Version, KeyID: key.ID, Metadata: meta,
+		Nonce: nonce, Ciphertext: aead.Seal(nil, nonce, plain, associatedData(Version, key.ID)),
+	}
+	return json.MarshalIndent(env, "", "  ")
+}
+
+// Export writes m to w as a mapping file encrypted under key.
+func Export(w io.Writer, m map[string]string, meta Metadata, key Key) error {
+	data, err := Marshal(m, meta, key)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func decode(data []byte) (*envelope, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	if env.Format != Format {
+		return nil, fmt.Errorf("%w: format %q", ErrFormat, env.Format)
+	}
+	if env.Version < 1 || env.Version > Version {
+		return nil, fmt.Errorf("%w: version %d (this build reads up to %d)", ErrFormat, env.Version, Version)
+	}
+	return &env, nil
+}
+
+// Unmarshal decrypts a mapping file with whichever of keys has its key ID.
+func Unmarshal(data []byte, keys ...Key) (map[string]string, Metadata, error) {
+	env, err := decode(data)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	var key *Key
+	for i := range keys {
+		if keys[i].ID == env.KeyID {
+			key = &keys[i]
+			break
+		}
+	}
+	if key == nil {
+		return nil, Metadata{}, fmt.Errorf("%w %q", ErrUnknownKey, env.KeyID)
+	}
+	aead, err := key.aead()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, Metadata{}, ErrUnreadable
+	}
+	plain, err := aead.Open(nil, env.Nonce, env.Ciphertext, associatedData(env.Version, env.KeyID))
+	if err != nil {
+		return nil, Metadata{}, ErrUnreadable
+	}
+	var p payload
+	if err := json.Unmarshal(plain, &p); err != nil {
+		return nil, Metadata{}, fmt.Errorf("%w: %v", ErrUnreadable, err)
+	}
+	if !sameMetadata(p.Metadata, env.Metadata) {
+		return nil, Metadata{}, fmt.Errorf("%w: metadata was changed after the file was written", ErrUnreadable)
+	}
+	return p.Mapping, p.Metadata, nil
+}
+
+// sameMetadata compares metadata as encoded, with times in UTC, so a tool
+// that rewrote a time in another zone hasn't changed it.
+func sameMetadata(a, b Metadata) bool {
+	a.Created, a.Updated, b.Created, b.Updated = a.Created.UTC(), a.Updated.UTC(), b.Created.UTC(), b.Updated.UTC()
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Equal(ja, jb)
+}
+
+// Import reads a mapping file from r and decrypts it with whichever of
+// keys has its key ID.
+func Import(r io.Reader, keys ...Key) (map[string]string, Metadata, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	return Unmarshal(data, keys...)
+}
+
+// Rewrap re-encrypts a mapping file under keys[0], opening it with
+// whichever of keys has its key ID, and reports whether it had to: a file
+// already under keys[0] is returned as it is. The mapping and metadata,
+// timestamps included, are unchanged; only the key ID, nonce and
+// ciphertext differ.
+func Rewrap(data []byte, keys ...Key) ([]byte, bool, error) {
+	if len(keys) == 0 {
+		return nil, false, errors.New("mapstore: Rewrap needs a key")
+	}
+	env, err := decode(data)
+	if err != nil {
+		return nil, false, err
+	}
+	if env.KeyID == keys[0].ID && env.Version == Version {
+		return data, false, nil
+	}
+	m, meta, err := Unmarshal(data, keys...)
+	if err != nil {
+		return nil, false, err
+	}
+	out, err := seal(m, meta, keys[0])
+	return out, err == nil, err
+}
+
+// Inspect returns a mapping file's metadata and key ID without
+// decrypting it. The metadata is as the file states it; only Import
+// checks it.
+func Inspect(data []byte) (Metadata, string, error) {
+	env, err := decode(data)
+	if err != nil {
+		return Metadata{}, "", err
+	}
+	return env.Metadata, env.KeyID, nil
+}
@@ -0,0 +1,185 @@
+package mapstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/cache"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+)
+
+var local = blindfold.New(blindfold.WithMode("local"))
+
+var sample = map[string]string{
+	"<Email Address_1>": "omar@example.com",
+	"<Email Address_2>": "lina@example.com",
+	"<Phone Number_1>":  "+1 415-555-0134",
+}
+
+func newKey(t *testing.T, id string) Key {
+	t.Helper()
+	k, err := NewKey(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestRoundTrip(t *testing.T) {
+	key := newKey(t, "2026-10")
+	var buf bytes.Buffer
+	if err := Export(&buf, sample, Metadata{Session: "case-4411", Policy: "strict", Labels: map[string]string{"ticket": "REV-12"}}, key); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range sample {
+		if strings.Contains(buf.String(), v) {
+			t.Fatalf("file holds %q in the clear:\n%s", v, buf.String())
+		}
+	}
+
+	meta, keyID, err := Inspect(buf.Bytes())
+	if err != nil || keyID != "2026-10" || meta.Entities["Email Address"] != 2 || meta.Tokens() != 3 {
+		t.Errorf("Inspect = %+v, %q, %v", meta, keyID, err)
+	}
+
+	other := newKey(t, "2026-07")
+	m, meta, err := Import(&buf, other, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 3 || m["<Phone Number_1>"] != "+1 415-555-0134" {
+		t.Errorf("mapping = %v", m)
+	}
+	if meta.Session != "case-4411" || meta.Policy != "strict" || meta.Labels["ticket"] != "REV-12" || meta.Created.IsZero() {
+		t.Errorf("metadata = %+v", meta)
+	}
+}
+
+func TestImportRefuses(t *testing.T) {
+	key := newKey(t, "k1")
+	data, err := Marshal(sample, Metadata{Session: "s1"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit := func(f func(env map[string]any)) []byte {
+		var env map[string]any
+		if err := json.Unmarshal(data, &env); err != nil {
+			t.Fatal(err)
+		}
+		f(env)
+		out, _ := json.Marshal(env)
+		return out
+	}
+	wrong := Key{ID: "k1", Secret: bytes.Repeat([]byte{7}, 32)}
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		keys []Key
+		want error
+	}{
+		{"unknown key", data, []Key{newKey(t, "k2")}, ErrUnknownKey},
+		{"wrong key", data, []Key{wrong}, ErrUnreadable},
+		{"edited metadata", edit(func(env map[string]any) { env["metadata"].(map[string]any)["session"] = "s2" }), []Key{key}, ErrUnreadable},
+		{"other key ID", edit(func(env map[string]any) { env["key_id"] = "k2" }), []Key{key, {ID: "k2", Secret: key.Secret}}, ErrUnreadable},
+		{"later version", edit(func(env map[string]any) { env["version"] = Version + 1 }), []Key{key}, ErrFormat},
+		{"other format", []byte(`{"<Person_1>": "Omar"}`), []Key{key}, ErrFormat},
+	} {
+		if _, _, err := Unmarshal(tc.data, tc.keys...); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+
+	// Reformatting changes no field, so the file still opens
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, edit(func(map[string]any) {}), "", "\t"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Unmarshal(pretty.Bytes(), key); err != nil {
+		t.Errorf("reformatted file: %v", err)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	a, b := newKey(t, "a"), newKey(t, "b")
+	keys, err := ParseKeys(a.Encode() + ", " + b.Encode())
+	if err != nil || len(keys) != 2 || keys[0].ID != "a" || !bytes.Equal(keys[1].Secret, b.Secret) {
+		t.Errorf("ParseKeys = %v, %v", keys, err)
+	}
+	for _, bad := range []string{"", "a", "a:c2hvcnQ=", a.Encode() + "," + a.Encode(), "bad id:" + strings.SplitN(a.Encode(), ":", 2)[1]} {
+		if _, err := ParseKeys(bad); err == nil {
+			t.Errorf("ParseKeys(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestStoreWithProtector(t *testing.T) {
+	kv := cache.NewLRU(100)
+	store, err := NewStore(kv, newKey(t, "k1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Policy = "basic"
+	p := protect.New(local)
+	p.Store = store
+	ctx := protect.Session(context.Background(), "conv-1")
+	var got []string
+	for _, msg := range []string{"I'm omar@example.com", "Reply to omar@example.com and lina@example.com"} {
+		if _, err := p.Do(ctx, msg, func(safe string) (string, error) { got = append(got, safe); return safe, nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got[1] != "Reply to <Email Address_1> and <Email Address_2>" {
+		t.Errorf("second turn sent %q", got[1])
+	}
+
+	data, ok, _ := kv.Get(context.Background(), EntryKey("conv-1"))
+	if !ok || strings.Contains(string(data), "omar@example.com") {
+		t.Fatalf("entry = %s", data)
+	}
+	meta, _, _ := Inspect(data)
+	if meta.Session != "conv-1" || meta.Policy != "basic" || meta.Entities["Email Address"] != 2 {
+		t.Errorf("entry metadata = %+v", meta)
+	}
+
+	// An entry copied under another session's key doesn't restore there
+	kv.Set(context.Background(), EntryKey("conv-2"), data)
+	if _, err := store.Load(context.Background(), "conv-2"); !errors.Is(err, ErrUnreadable) {
+		t.Errorf("copied entry: err = %v", err)
+	}
+}
+
+func TestStoreExportImport(t *testing.T) {
+	ctx := context.Background()
+	service, _ := NewStore(cache.NewLRU(10), newKey(t, "service"))
+	if err := service.Save(ctx, "case-9", sample); err != nil {
+		t.Fatal(err)
+	}
+	review := newKey(t, "review")
+	file, err := service.Export(ctx, "case-9", review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, _, err := Unmarshal(file, review); err != nil || len(m) != 3 {
+		t.Errorf("export opened with the review key: %v, %v", m, err)
+	}
+
+	offline, _ := NewStore(cache.NewLRU(10), newKey(t, "offline"))
+	session, err := offline.Import(ctx, file, review)
+	if err != nil || session != "case-9" {
+		t.Fatalf("Import = %q, %v", session, err)
+	}
+	m, err := offline.Load(ctx, "case-9")
+	if err != nil || m["<Email Address_2>"] != "lina@example.com" {
+		t.Errorf("imported mapping = %v, %v", m, err)
+	}
+	if _, err := service.Export(ctx, "case-404", review); err == nil {
+		t.Error("exported a session with no mapping")
+	}
+}
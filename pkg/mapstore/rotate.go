@@ -0,0 +1,96 @@
+package mapstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/cache"
+)
+
+// maxSwaps bounds the attempts to rewrite one entry that keeps changing
+// under the rotation.
+const maxSwaps = 5
+
+// RotateStats is what a Rotate run did.
+type RotateStats struct {
+	Entries int // entries seen
+	Rotated int // re-encrypted under the current key
+	Current int // already under it, by an earlier run or a Save since
+	// Failed holds the entries that could not be rotated by session: not
+	// readable under any of the Store's keys, or changing on every try.
+	// They are left as they were.
+	Failed map[string]error
+}
+
+// Rotate re-encrypts every entry not under the Store's first key with it,
+// while the Store stays in use. Run it once every process writes with the
+// new key; until then a process still writing with the old one puts
+// entries back under it.
+//
+// Rotating a key without downtime takes four steps:
+//
+//  1. Give every process the new key as a read key: "old,new".
+//  2. Make it the write key everywhere: "new,old". Entries saved from now
+//     on are under it, and every process reads both.
+//  3. Rotate, so the entries nobody has saved since are under it too.
+//  4. Drop the old key: "new".
+//
+// Each entry is replaced with a compare-and-swap, so a Save that lands
+// while its entry is being rotated wins, and no token it added is lost.
+// The Store's cache.Store must be a cache.Rewriter.
+func (s *Store) Rotate(ctx context.Context) (RotateStats, error) {
+	stats := RotateStats{Failed: make(map[string]error)}
+	rw, ok := s.kv.(cache.Rewriter)
+	if !ok {
+		return stats, fmt.Errorf("mapstore: Rotate needs a cache.Rewriter, not %T", s.kv)
+	}
+	keys, err := rw.Keys(ctx, EntryKey(""))
+	if err != nil {
+		return stats, err
+	}
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		stats.Entries++
+		session := key[len(EntryKey("")):]
+		rotated, err := s.rotate(ctx, rw, key)
+		switch {
+		case err != nil:
+			stats.Failed[session] = err
+		case rotated:
+			stats.Rotated++
+		default:
+			stats.Current++
+		}
+	}
+	return stats, nil
+}
+
+var errBusy = errors.New("mapstore: entry changed on every attempt")
+
+func (s *Store) rotate(ctx context.Context, rw cache.Rewriter, key string) (bool, error) {
+	for i := 0; i < maxSwaps; i++ {
+		data, ok, err := rw.Get(ctx, key)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil // removed or expired since it was listed
+		}
+		out, changed, err := Rewrap(data, s.keys...)
+		if err != nil || !changed {
+			return false, err
+		}
+		swapped, err := rw.Swap(ctx, key, data, out)
+		if err != nil {
+			return false, err
+		}
+		if swapped {
+			return true, nil
+		}
+		// Saved in between: read it again, it may be under the key already
+	}
+	return false, errBusy
+}
@@ -0,0 +1,187 @@
+package mapstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/cache"
+)
+
+func TestRewrap(t *testing.T) {
+	old, current := newKey(t, "old"), newKey(t, "new")
+	data, err := Marshal(sample, Metadata{Session: "s1", Created: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, _, _ := Inspect(data)
+
+	out, changed, err := Rewrap(data, current, old)
+	if err != nil || !changed {
+		t.Fatalf("Rewrap = %v, %v", changed, err)
+	}
+	meta, keyID, _ := Inspect(out)
+	if keyID != "new" || !reflect.DeepEqual(meta, before) {
+		t.Errorf("rewrapped: key %q, metadata %+v, want %+v", keyID, meta, before)
+	}
+	if m, _, err := Unmarshal(out, current); err != nil || !reflect.DeepEqual(m, sample) {
+		t.Errorf("rewrapped mapping = %v, %v", m, err)
+	}
+	if again, changed, _ := Rewrap(out, current, old); changed || string(again) != string(out) {
+		t.Error("rewrapped a file already under the current key")
+	}
+}
+
+// TestMixedKeyReadsDuringRotation saves and loads sessions through a
+// service on "new,old" while Rotate runs, then checks that every entry is
+// under the new key with nothing lost.
+func TestMixedKeyReadsDuringRotation(t *testing.T) {
+	ctx := context.Background()
+	old, current := newKey(t, "2026-07"), newKey(t, "2026-10")
+	kv := cache.NewLRU(1000)
+
+	const sessions = 60
+	want := make([]map[string]string, sessions)
+	before, _ := NewStore(kv, old)
+	for i := range want {
+		want[i] = map[string]string{"<Email Address_1>": fmt.Sprintf("user%d@example.com", i)}
+		if err := before.Save(ctx, fmt.Sprint("s", i), want[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Step 1: a process that reads both still writes under the old key
+	step1, _ := NewStore(kv, old, current)
+	if m, err := step1.Load(ctx, "s0"); err != nil || len(m) != 1 {
+		t.Fatalf("step 1 read: %v, %v", m, err)
+	}
+
+	// Step 2 and 3: the service writes under the new key while Rotate runs
+	svc, _ := NewStore(kv, current, old)
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	stop := make(chan struct{})
+	const workers = 4
+	for w := 0; w < workers; w++ {
+		w := w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 2; ; n++ {
+				for i := w; i < sessions; i += workers {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					s := fmt.Sprint("s", i)
+					got, err := svc.Load(ctx, s)
+					if err != nil || !reflect.DeepEqual(got, want[i]) {
+						errs <- fmt.Errorf("%s: load = %v, %v; want %v", s, got, err, want[i])
+						return
+					}
+					// Odd sessions are only read, so Rotate has those to do
+					if i%2 == 0 && n%3 == 0 {
+						next := copyMap(want[i])
+						next[fmt.Sprintf("<Email Address_%d>", n)] = fmt.Sprintf("user%d+%d@example.com", i, n)
+						if err := svc.Save(ctx, s, next); err != nil {
+							errs <- err
+							return
+						}
+						want[i] = next
+					}
+				}
+			}
+		}()
+	}
+	time.Sleep(5 * time.Millisecond)
+	stats, err := svc.Rotate(ctx)
+	close(stop)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if err != nil || stats.Entries != sessions || stats.Rotated+stats.Current != sessions || len(stats.Failed) != 0 {
+		t.Fatalf("Rotate = %+v, %v", stats, err)
+	}
+	if stats.Rotated < sessions/2 {
+		t.Errorf("read-only sessions not rotated: %+v", stats)
+	}
+
+	// Step 4: the old key is gone, and every entry still opens
+	after, _ := NewStore(kv, current)
+	for i := range want {
+		got, err := after.Load(ctx, fmt.Sprint("s", i))
+		if err != nil || !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("s%d after rotation = %v, %v; want %v", i, got, err, want[i])
+		}
+	}
+	oldOnly, _ := NewStore(kv, old)
+	if _, err := oldOnly.Load(ctx, "s0"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("old key alone: err = %v", err)
+	}
+}
+
+func copyMap(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// racy runs before once, just ahead of the first Swap, as a Save landing
+// mid-rotation would.
+type racy struct {
+	*cache.LRU
+	once   sync.Once
+	before func()
+}
+
+func (r *racy) Swap(ctx context.Context, key string, old, new []byte) (bool, error) {
+	r.once.Do(r.before)
+	return r.LRU.Swap(ctx, key, old, new)
+}
+
+func TestRotateKeepsConcurrentSave(t *testing.T) {
+	ctx := context.Background()
+	old, current := newKey(t, "old"), newKey(t, "new")
+	kv := &racy{LRU: cache.NewLRU(10)}
+	before, _ := NewStore(kv, old)
+	before.Save(ctx, "s1", sample)
+
+	svc, _ := NewStore(kv, current, old)
+	saved := copyMap(sample)
+	saved["<Person_1>"] = "Lina Haddad"
+	kv.before = func() {
+		if err := svc.Save(ctx, "s1", saved); err != nil {
+			t.Error(err)
+		}
+	}
+	stats, err := svc.Rotate(ctx)
+	if err != nil || stats.Current != 1 || stats.Rotated != 0 {
+		t.Errorf("Rotate = %+v, %v", stats, err)
+	}
+	if got, err := svc.Load(ctx, "s1"); err != nil || !reflect.DeepEqual(got, saved) {
+		t.Errorf("after rotation = %v, %v; want the concurrent save", got, err)
+	}
+}
+
+func TestRotateReportsUnreadable(t *testing.T) {
+	ctx := context.Background()
+	kv := cache.NewLRU(10)
+	lost, _ := NewStore(kv, newKey(t, "lost"))
+	lost.Save(ctx, "s1", sample)
+	svc, _ := NewStore(kv, newKey(t, "new"))
+	svc.Save(ctx, "s2", sample)
+
+	stats, err := svc.Rotate(ctx)
+	if err != nil || stats.Entries != 2 || stats.Current != 1 || !errors.Is(stats.Failed["s1"], ErrUnknownKey) {
+		t.Errorf("Rotate = %+v, %v", stats, err)
+	}
+}
@@ -0,0 +1,115 @@
+package mapstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/cache"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+)
+
+// Store is a protect.Store that keeps each session's mapping in a
+// cache.Store as a mapping file, so an entry read straight out of Redis
+// is a file Import opens. It writes under the first of its keys and reads
+// under any of them.
+type Store struct {
+	kv   cache.Store
+	keys []Key
+
+	// Policy and Source go into the metadata of every entry.
+	Policy, Source string
+}
+
+var _ protect.Store = (*Store)(nil)
+
+// NewStore returns a Store over kv. keys[0] encrypts; the rest, keys of
+// earlier entries, only decrypt.
+func NewStore(kv cache.Store, keys ...Key) (*Store, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("mapstore: NewStore needs a key")
+	}
+	for _, k := range keys {
+		if err := k.check(); err != nil {
+			return nil, err
+		}
+	}
+	return &Store{kv: kv, keys: keys}, nil
+}
+
+// EntryKey returns where a session's mapping is kept in the cache.Store.
+func EntryKey(session string) string { return "mapping/session/" + session }
+
+// Load implements protect.Store.
+func (s *Store) Load(ctx context.Context, session string) (map[string]string, error) {
+	m, _, err := s.open(ctx, session)
+	return m, err
+}
+
+func (s *Store) open(ctx context.Context, session string) (map[string]string, *Metadata, error) {
+	data, ok, err := s.kv.Get(ctx, EntryKey(session))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return map[string]string{}, nil, nil
+	}
+	m, meta, err := Unmarshal(data, s.keys...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("session %s: %w", session, err)
+	}
+	if meta.Session != session {
+		// Entries name their session, so one copied under another key
+		// doesn't restore that session's tokens
+		return nil, nil, fmt.Errorf("session %s: %w: entry is for session %q", session, ErrUnreadable, meta.Session)
+	}
+	return m, &meta, nil
+}
+
+// Save implements protect.Store. The entry keeps the Created time of the
+// one it replaces.
+func (s *Store) Save(ctx context.Context, session string, m map[string]string) error {
+	meta := Metadata{Session: session, Policy: s.Policy, Source: s.Source}
+	if data, ok, err := s.kv.Get(ctx, EntryKey(session)); err == nil && ok {
+		if prev, _, err := Inspect(data); err == nil {
+			meta.Created, meta.Labels = prev.Created, prev.Labels
+		}
+	}
+	data, err := Marshal(m, meta, s.keys[0])
+	if err != nil {
+		return err
+	}
+	return s.kv.Set(ctx, EntryKey(session), data)
+}
+
+// Export returns the session's mapping as a file encrypted under key,
+// for a reader that doesn't hold the Store's keys: a reviewer, or a
+// service in another environment.
+func (s *Store) Export(ctx context.Context, session string, key Key) ([]byte, error) {
+	m, meta, err := s.open(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, fmt.Errorf("session %s: no mapping", session)
+	}
+	return Marshal(m, *meta, key)
+}
+
+// Import stores a mapping file opened with keys under the session its
+// metadata names, re-encrypted under the Store's key and replacing any
+// entry there, and returns that session.
+func (s *Store) Import(ctx context.Context, data []byte, keys ...Key) (string, error) {
+	m, meta, err := Unmarshal(data, keys...)
+	if err != nil {
+		return "", err
+	}
+	if meta.Session == "" {
+		return "", errors.New("mapstore: file names no session")
+	}
+	out, err := Marshal(m, meta, s.keys[0])
+	if err != nil {
+		return "", err
+	}
+	return meta.Session, s.kv.Set(ctx, EntryKey(meta.Session), out)
+}
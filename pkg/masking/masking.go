@@ -0,0 +1,147 @@
+// Package masking hides detected values partially, for display contexts
+// where a reader needs to recognise a value but not read it: a card as
+// ****-****-****-3456, an email as j***@acme.com.
+//
+// Masking is one-way. Unlike tokenization there is no mapping, so masked
+// text can't be restored; use it for what people see, not for what goes
+// to an LLM and comes back.
+//
+//	res, _ := bf.Detect(ctx, text)
+//	shown := masking.Defaults().Apply(text, res.DetectedEntities)
+package masking
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+// Char replaces hidden characters.
+const Char = '*'
+
+// A Rule masks one detected value.
+type Rule func(value string) string
+
+// KeepLast hides every letter and digit of a value except the last n,
+// leaving separators in place: KeepLast(4) turns 4111-1111-1111-3456 into
+// ****-****-****-3456.
+func KeepLast(n int) Rule {
+	return func(value string) string {
+		return keep(value, func(i, total int) bool { return i >= total-n })
+	}
+}
+
+// KeepFirst is KeepLast from the start of the value.
+func KeepFirst(n int) Rule {
+	return func(value string) string {
+		return keep(value, func(i, _ int) bool { return i < n })
+	}
+}
+
+// Full hides every letter and digit, leaving separators in place.
+func Full() Rule { return KeepLast(0) }
+
+// Email keeps the first character of the local part and the domain:
+// john.smith@acme.com becomes j***@acme.com. Values without an @ are masked
+// in full.
+func Email() Rule {
+	return func(value string) string {
+		local, domain, ok := strings.Cut(value, "@")
+		if !ok || local == "" {
+			return Full()(value)
+		}
+		first := []rune(local)[0]
+		return string(first) + strings.Repeat(string(Char), 3) + "@" + domain
+	}
+}
+
+// keep masks the letters and digits of value for which show(i, total)
+// is false, i counting letters and digits only.
+func keep(value string, show func(i, total int) bool) string {
+	total := 0
+	for _, c := range value {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			total++
+		}
+	}
+	var b strings.Builder
+	i := 0
+	for _, c := range value {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			if !show(i, total) {
+				c = Char
+			}
+			i++
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Rules maps entity types to the rule that masks them. Types without a
+// rule are masked in full.
+type Rules map[string]Rule
+
+// Defaults returns rules for the common built-in types: cards, SSNs, phone
+// numbers and IBANs keep their last four characters, emails their first
+// letter and domain.
+func Defaults() Rules {
+	return Rules{
+		blindfold.EntityCreditCard:   KeepLast(4),
+		blindfold.EntitySSN:          KeepLast(4),
+		blindfold.EntityPhoneNumber:  KeepLast(4),
+		blindfold.EntityIBAN:         KeepLast(4),
+		blindfold.EntityEmailAddress: Email(),
+	}
+}
+
+// Mask masks value with the rule for entityType.
+func (r Rules) Mask(entityType, value string) string {
+	if rule, ok := r[entityType]; ok {
+		return rule(value)
+	}
+	return Full()(value)
+}
+
+// Apply masks every entity in text. Entities are located by their offsets
+// when those match the entity's text, and otherwise by searching for the
+// next occurrence of it; overlapping entities after the first are skipped.
+func (r Rules) Apply(text string, entities []blindfold.DetectedEntity) string {
+	type span struct {
+		start, end int
+		masked     string
+	}
+	var spans []span
+	next := make(map[string]int) // value → where to search for it next
+	for _, e := range entities {
+		if e.Text == "" {
+			continue
+		}
+		start := e.Start
+		if start < 0 || e.End > len(text) || start > e.End || text[start:e.End] != e.Text {
+			i := strings.Index(text[next[e.Text]:], e.Text)
+			if i < 0 {
+				continue
+			}
+			start = next[e.Text] + i
+		}
+		next[e.Text] = start + len(e.Text)
+		spans = append(spans, span{start, start + len(e.Text), r.Mask(e.Type, e.Text)})
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.start < last {
+			continue
+		}
+		b.WriteString(text[last:s.start])
+		b.WriteString(s.masked)
+		last = s.end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
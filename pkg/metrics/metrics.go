@@ -0,0 +1,172 @@
+// Package metrics exposes Prometheus metrics for Blindfold protection
+// pipelines: entities detected by type, detection latency, detokenization
+// failures, fallback events, and failovers between cloud endpoints.
+//
+// Like pkg/otel, it never records values — only entity types, operations
+// and outcomes — so label cardinality stays bounded and no PII ends up in
+// the metrics backend.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// Metrics holds the collectors. Create one per process with New.
+type Metrics struct {
+	// Entities counts detected entities by operation and entity type.
+	Entities *prometheus.CounterVec
+	// Duration observes Detect/Tokenize latency by operation and outcome
+	// ("ok" or "error").
+	Duration *prometheus.HistogramVec
+	// Detokenizations counts detokenize calls.
+	Detokenizations prometheus.Counter
+	// DetokenizeFailures counts detokenize calls whose output still held
+	// unresolved tokens, by reason.
+	DetokenizeFailures *prometheus.CounterVec
+	// Fallbacks counts calls served by a fallback path, by operation.
+	Fallbacks *prometheus.CounterVec
+	// Failovers counts calls moved off a failing cloud endpoint, by
+	// operation and endpoint.
+	Failovers *prometheus.CounterVec
+	// Hedges counts hedged calls, by operation and the endpoint the hedge
+	// went to.
+	Hedges *prometheus.CounterVec
+}
+
+// New creates the collectors and registers them with reg.
+func New(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		Entities: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "blindfold_entities_detected_total",
+			Help: "Entities detected, by operation and entity type.",
+		}, []string{"op", "entity_type"}),
+		Duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "blindfold_detection_duration_seconds",
+			Help:    "Latency of Blindfold Detect/Tokenize calls, by operation and outcome.",
+			Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"op", "outcome"}),
+		Detokenizations: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "blindfold_detokenize_total",
+			Help: "Detokenize calls.",
+		}),
+		DetokenizeFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "blindfold_detokenize_failures_total",
+			Help: "Detokenize calls that left placeholders unresolved, by reason.",
+		}, []string{"reason"}),
+		Fallbacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "blindfold_fallback_events_total",
+			Help: "Calls served by a fallback path (for example local mode), by operation.",
+		}, []string{"op"}),
+		Failovers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "blindfold_failovers_total",
+			Help: "Calls moved to the next cloud endpoint after a failure, by operation and failing endpoint.",
+		}, []string{"op", "endpoint"}),
+		Hedges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "blindfold_hedged_calls_total",
+			Help: "Latency-sensitive calls also sent to a second cloud endpoint, by operation and endpoint.",
+		}, []string{"op", "endpoint"}),
+	}
+	reg.MustRegister(m.Entities, m.Duration, m.Detokenizations, m.DetokenizeFailures, m.Fallbacks, m.Failovers, m.Hedges)
+	return m
+}
+
+// Handler serves the metrics gathered by g in the Prometheus text format.
+// Mount it at /metrics.
+func Handler(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+}
+
+// ObserveEntities counts entities detected by op.
+func (m *Metrics) ObserveEntities(op string, entities []blindfold.DetectedEntity) {
+	for _, e := range entities {
+		m.Entities.WithLabelValues(op, e.Type).Inc()
+	}
+}
+
+// ObserveDetokenize records one detokenization of text against mp. Tokens
+// in text with no mapping entry count as a failure — the restored output
+// would show the user a placeholder.
+func (m *Metrics) ObserveDetokenize(text string, mp map[string]string) {
+	m.Detokenizations.Inc()
+	if len(mapping.Unresolved(text, mp)) > 0 {
+		m.DetokenizeFailures.WithLabelValues("unresolved_token").Inc()
+	}
+}
+
+// ObserveFallback counts one fallback event. Its signature matches
+// resilience.FallbackClient.OnFallback, so it can be assigned directly.
+func (m *Metrics) ObserveFallback(op string, _ error) {
+	m.Fallbacks.WithLabelValues(op).Inc()
+}
+
+// ObserveFailover counts one failover. Its signature matches
+// resilience.Failover.OnFailover.
+func (m *Metrics) ObserveFailover(op, endpoint string, _ error) {
+	m.Failovers.WithLabelValues(op, endpoint).Inc()
+}
+
+// ObserveHedge counts one hedged call. Its signature matches
+// resilience.Failover.OnHedge.
+func (m *Metrics) ObserveHedge(op, endpoint string) {
+	m.Hedges.WithLabelValues(op, endpoint).Inc()
+}
+
+// Client records metrics for every call to a Blindfold client.
+type Client struct {
+	next    bfclient.Client
+	metrics *Metrics
+}
+
+var _ bfclient.Client = (*Client)(nil)
+
+// Wrap returns next instrumented with m.
+func Wrap(next bfclient.Client, m *Metrics) *Client {
+	return &Client{next: next, metrics: m}
+}
+
+// Detect calls the wrapped client and records latency and entities.
+func (c *Client) Detect(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.DetectResponse, error) {
+	start := time.Now()
+	res, err := c.next.Detect(ctx, text, opts...)
+	c.observe("detect", start, err)
+	if err != nil {
+		return nil, err
+	}
+	c.metrics.ObserveEntities("detect", res.DetectedEntities)
+	return res, nil
+}
+
+// Tokenize calls the wrapped client and records latency and entities.
+func (c *Client) Tokenize(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	start := time.Now()
+	res, err := c.next.Tokenize(ctx, text, opts...)
+	c.observe("tokenize", start, err)
+	if err != nil {
+		return nil, err
+	}
+	c.metrics.ObserveEntities("tokenize", res.DetectedEntities)
+	return res, nil
+}
+
+// Detokenize calls the wrapped client and records unresolved tokens.
+func (c *Client) Detokenize(text string, m map[string]string) *blindfold.DetokenizeResponse {
+	c.metrics.ObserveDetokenize(text, m)
+	return c.next.Detokenize(text, m)
+}
+
+func (c *Client) observe(op string, start time.Time, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	c.metrics.Duration.WithLabelValues(op, outcome).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,111 @@
+package otel
+
+import (
+	"context"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// Client records a span for every call to a Blindfold client.
+type Client struct {
+	next bfclient.Client
+}
+
+var _ bfclient.Client = (*Client)(nil)
+
+// Wrap returns next instrumented with spans.
+func Wrap(next bfclient.Client) *Client {
+	return &Client{next: next}
+}
+
+// Detect calls the wrapped client inside a "blindfold.detect" span.
+func (c *Client) Detect(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.DetectResponse, error) {
+	ctx, span := Start(ctx, "blindfold.detect", TextLength.Int(len(text)))
+	defer span.End()
+
+	res, err := c.next.Detect(ctx, text, opts...)
+	if err != nil {
+		RecordError(span, err)
+		return nil, err
+	}
+	RecordEntities(span, res.DetectedEntities)
+	return res, nil
+}
+
+// Tokenize calls the wrapped client inside a "blindfold.tokenize" span.
+func (c *Client) Tokenize(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	ctx, span := Start(ctx, "blindfold.tokenize", TextLength.Int(len(text)))
+	defer span.End()
+
+	res, err := c.next.Tokenize(ctx, text, opts...)
+	if err != nil {
+		RecordError(span, err)
+		return nil, err
+	}
+	RecordEntities(span, res.DetectedEntities)
+	span.SetAttributes(TokensCount.Int(len(res.Mapping)))
+	return res, nil
+}
+
+// Detokenize passes through without a span — it has no context to attach
+// one to. Use DetokenizeContext inside a traced request.
+func (c *Client) Detokenize(text string, m map[string]string) *blindfold.DetokenizeResponse {
+	return c.next.Detokenize(text, m)
+}
+
+// DetokenizeContext detokenizes inside a "blindfold.detokenize" span. Besides
+// the number of replacements it records how many token-shaped strings in
+// text had no mapping entry — a rising count usually means the LLM is
+// mangling placeholders.
+func (c *Client) DetokenizeContext(ctx context.Context, text string, m map[string]string) *blindfold.DetokenizeResponse {
+	_, span := Start(ctx, "blindfold.detokenize", TextLength.Int(len(text)), TokensCount.Int(len(m)))
+	defer span.End()
+
+	res := c.next.Detokenize(text, m)
+	span.SetAttributes(Replacements.Int(res.ReplacementsMade), TokensUnresolved.Int(len(mapping.Unresolved(text, m))))
+	return res
+}
+
+// ChatClient records a span for every chat completion.
+type ChatClient struct {
+	next bfclient.ChatCompleter
+}
+
+var _ bfclient.ChatCompleter = (*ChatClient)(nil)
+
+// WrapChat returns next instrumented with spans.
+func WrapChat(next bfclient.ChatCompleter) *ChatClient {
+	return &ChatClient{next: next}
+}
+
+// CreateChatCompletion calls the wrapped client inside a "chat <model>"
+// span using the GenAI semantic conventions. Token usage is recorded;
+// message contents are not.
+func (c *ChatClient) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	ctx, span := Tracer().Start(ctx, "chat "+req.Model,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(GenAISystem.String("openai"), GenAIRequestModel.String(req.Model)))
+	defer span.End()
+
+	res, err := c.next.CreateChatCompletion(ctx, req)
+	if err != nil {
+		RecordError(span, err)
+		return res, err
+	}
+	reasons := make([]string, len(res.Choices))
+	for i, choice := range res.Choices {
+		reasons[i] = string(choice.FinishReason)
+	}
+	span.SetAttributes(
+		GenAIResponseModel.String(res.Model),
+		GenAIInputTokens.Int(res.Usage.PromptTokens),
+		GenAIOutputTokens.Int(res.Usage.CompletionTokens),
+		GenAIFinishReasons.StringSlice(reasons),
+	)
+	return res, nil
+}
@@ -0,0 +1,142 @@
+// Package otel instruments Blindfold pipelines with OpenTelemetry spans.
+//
+// Wrap a Blindfold client and an LLM client, and every tokenize, chat
+// completion and detokenize step shows up as a span under the request that
+// triggered it. Spans carry entity counts, entity types, text lengths and
+// token usage — never detected values, prompts, completions or mappings —
+// so traces can go to any backend without becoming a PII store themselves.
+//
+// The package name shadows go.opentelemetry.io/otel; import it as
+//
+//	bfotel "github.com/blindfold-dev/blindfold-cookbook/pkg/otel"
+package otel
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	otelapi "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+)
+
+// ScopeName is the instrumentation scope of every span this package creates.
+const ScopeName = "github.com/blindfold-dev/blindfold-cookbook/pkg/otel"
+
+// Span attribute keys. None of them ever holds a detected value.
+const (
+	EntitiesCount    = attribute.Key("blindfold.entities.count")
+	EntityTypes      = attribute.Key("blindfold.entity_types")
+	TextLength       = attribute.Key("blindfold.text.length")
+	TokensCount      = attribute.Key("blindfold.tokens.count")
+	TokensUnresolved = attribute.Key("blindfold.tokens.unresolved")
+	Replacements     = attribute.Key("blindfold.replacements")
+	Policy           = attribute.Key("blindfold.policy")
+
+	// ErrorType is the bferrors kind of a failed call, and
+	// HTTPStatusCode the status the dependency answered it with.
+	ErrorType      = attribute.Key("error.type")
+	HTTPStatusCode = attribute.Key("http.response.status_code")
+
+	// OpenTelemetry GenAI semantic convention keys for the LLM call.
+	GenAISystem        = attribute.Key("gen_ai.system")
+	GenAIRequestModel  = attribute.Key("gen_ai.request.model")
+	GenAIResponseModel = attribute.Key("gen_ai.response.model")
+	GenAIInputTokens   = attribute.Key("gen_ai.usage.input_tokens")
+	GenAIOutputTokens  = attribute.Key("gen_ai.usage.output_tokens")
+	GenAIFinishReasons = attribute.Key("gen_ai.response.finish_reasons")
+)
+
+// Setup installs a global tracer provider for serviceName and returns a
+// function that flushes and stops it; call it before the program exits.
+//
+// The exporter follows the standard OpenTelemetry environment variables:
+// OTEL_TRACES_EXPORTER selects "otlp", "console" or "none", and the OTLP
+// exporter reads OTEL_EXPORTER_OTLP_ENDPOINT (default
+// http://localhost:4318). With neither variable set, tracing stays off so
+// examples that adopt this package run quietly by default.
+func Setup(ctx context.Context, serviceName string) (shutdown func(context.Context) error, err error) {
+	noop := func(context.Context) error { return nil }
+
+	exporter := os.Getenv("OTEL_TRACES_EXPORTER")
+	if exporter == "" && (os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "") {
+		exporter = "otlp"
+	}
+
+	var exp sdktrace.SpanExporter
+	switch exporter {
+	case "", "none":
+		return noop, nil
+	case "otlp":
+		exp, err = otlptracehttp.New(ctx)
+	case "console":
+		exp, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return noop, fmt.Errorf("otel: unsupported OTEL_TRACES_EXPORTER %q", exporter)
+	}
+	if err != nil {
+		return noop, fmt.Errorf("otel: exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return noop, fmt.Errorf("otel: resource: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otelapi.SetTracerProvider(tp)
+	otelapi.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp.Shutdown, nil
+}
+
+// Tracer returns the tracer used by this package, bound to the global
+// provider.
+func Tracer() trace.Tracer {
+	return otelapi.Tracer(ScopeName)
+}
+
+// Start begins a span with the package tracer. Use it for the root span of
+// a pipeline so the wrapped clients' spans nest under it.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// RecordEntities sets the entity count and the sorted, de-duplicated entity
+// types on span. Only types are recorded, never the matched text.
+func RecordEntities(span trace.Span, entities []blindfold.DetectedEntity) {
+	seen := make(map[string]bool)
+	var types []string
+	for _, e := range entities {
+		if !seen[e.Type] {
+			seen[e.Type] = true
+			types = append(types, e.Type)
+		}
+	}
+	sort.Strings(types)
+	span.SetAttributes(EntitiesCount.Int(len(entities)), EntityTypes.StringSlice(types))
+}
+
+// RecordError marks span as failed with the error's kind and, if the
+// dependency answered, its HTTP status. The error message is left out:
+// an API error's message includes the response body, which can echo the
+// request text.
+func RecordError(span trace.Span, err error) {
+	kind := bferrors.KindOf(err)
+	span.SetAttributes(ErrorType.String(kind.String()))
+	desc := kind.String()
+	if code := bferrors.StatusCode(err); code != 0 {
+		span.SetAttributes(HTTPStatusCode.Int(code))
+		desc = fmt.Sprintf("%s (HTTP %d)", kind, code)
+	}
+	span.SetStatus(codes.Error, desc)
+}
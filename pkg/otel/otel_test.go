@@ -0,0 +1,55 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	otelapi "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fake"
+)
+
+func TestRecordErrorKeepsNoMessage(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otelapi.GetTracerProvider()
+	otelapi.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otelapi.SetTracerProvider(prev) })
+
+	// The API echoes the text it refused
+	text := "Refund Jane Doe at jane@example.com"
+	bf := fake.New()
+	bf.FailNext(&blindfold.APIError{BlindfoldError: blindfold.BlindfoldError{
+		Message: "text rejected: " + text, StatusCode: 422,
+	}})
+	if _, err := Wrap(bf).Tokenize(context.Background(), text); err == nil {
+		t.Fatal("want the API error")
+	}
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("%d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Status().Code != codes.Error || span.Status().Description != "detection (HTTP 422)" {
+		t.Errorf("status = %+v", span.Status())
+	}
+	attrs := make(map[string]string)
+	for _, kv := range span.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs[string(ErrorType)] != "detection" || attrs[string(HTTPStatusCode)] != "422" {
+		t.Errorf("attributes = %v", attrs)
+	}
+	dump := fmt.Sprint(span.Status(), span.Attributes(), span.Events())
+	for _, v := range []string{"Jane Doe", "jane@example.com"} {
+		if strings.Contains(dump, v) {
+			t.Errorf("span holds %q: %s", v, dump)
+		}
+	}
+}
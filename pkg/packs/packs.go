@@ -0,0 +1,52 @@
+// Package packs bundles policy files for regional identifiers. Each pack
+// is an ordinary policyconf file — load one by name with Load, or pass its
+// path to any recipe's -policies flag:
+//
+//	go run ./examples/policyconf-go -policies pkg/packs/eu.yaml
+package packs
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+//go:embed *.yaml
+var files embed.FS
+
+// Names returns the bundled packs.
+func Names() []string {
+	entries, _ := fs.ReadDir(files, ".")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".yaml"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load parses the named pack.
+func Load(name string) (*policyconf.Config, error) {
+	data, err := files.ReadFile(name + ".yaml")
+	if err != nil {
+		return nil, fmt.Errorf("packs: unknown pack %q (have %s)", name, strings.Join(Names(), ", "))
+	}
+	cfg, err := policyconf.Parse(data, "yaml")
+	if err != nil {
+		return nil, fmt.Errorf("packs: %s: %w", name, err)
+	}
+	return cfg, nil
+}
+
+// Policy returns the default policy of the named pack.
+func Policy(name string) (*policyconf.Policy, error) {
+	cfg, err := Load(name)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Policy("")
+}
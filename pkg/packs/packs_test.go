@@ -0,0 +1,98 @@
+package packs
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+// fixture is one case of testdata/<pack>.json: the entities a pack must
+// find in text, in order. An empty want asserts that nothing is found.
+type fixture struct {
+	Name string   `json:"name"`
+	Text string   `json:"text"`
+	Want []entity `json:"want"`
+}
+
+type entity struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func loadFixtures(t *testing.T, pack string) []fixture {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", pack+".json"))
+	if err != nil {
+		t.Fatalf("every pack needs fixtures: %v", err)
+	}
+	var fixtures []fixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		t.Fatalf("%s fixtures: %v", pack, err)
+	}
+	return fixtures
+}
+
+func TestPacks(t *testing.T) {
+	for _, name := range Names() {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			pol, err := Policy(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fixtures := loadFixtures(t, name)
+			bf := pol.Wrap(blindfold.New(append(pol.ClientOptions(), blindfold.WithMode("local"))...))
+			for _, f := range fixtures {
+				res, err := bf.Detect(context.Background(), f.Text)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := make([]entity, 0, len(res.DetectedEntities))
+				for _, e := range res.DetectedEntities {
+					got = append(got, entity{e.Type, e.Text})
+				}
+				if !equal(got, f.Want) {
+					t.Errorf("%s: %q\n got %v\nwant %v", f.Name, f.Text, got, f.Want)
+				}
+			}
+		})
+	}
+}
+
+func TestPackTokensRoundTrip(t *testing.T) {
+	for _, name := range Names() {
+		pol, err := Policy(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bf := pol.Wrap(blindfold.New(append(pol.ClientOptions(), blindfold.WithMode("local"))...))
+		for _, f := range loadFixtures(t, name) {
+			res, err := bf.Tokenize(context.Background(), f.Text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Mapping) == 0 && len(f.Want) > 0 {
+				t.Errorf("%s/%s: nothing tokenized", name, f.Name)
+			}
+			if got := bf.Detokenize(res.Text, res.Mapping).Text; got != f.Text {
+				t.Errorf("%s/%s: round trip\n got %q\nwant %q", name, f.Name, got, f.Text)
+			}
+		}
+	}
+}
+
+func equal(a, b []entity) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,249 @@
+package policyconf
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// newDetectors builds a detector per pattern and deny list. They run apart
+// from the SDK's locale registry, so built-in detectors of the same entity
+// type never join in.
+func (p *Policy) newDetectors() []blindfold.Detector {
+	var out []blindfold.Detector
+	for _, pat := range p.rules {
+		cfg := blindfold.RegexDetectorConfig{
+			EntityType:      pat.Entity,
+			Pattern:         pat.re,
+			Score:           pat.Score,
+			ContextRequired: len(pat.Context) > 0,
+			ContextKeywords: pat.Context,
+			Validator:       pat.valid,
+		}
+		if cfg.Score == 0 {
+			cfg.Score = 0.9
+		}
+		out = append(out, blindfold.NewRegexDetector(cfg))
+	}
+	return out
+}
+
+// Client applies a policy to every call of a Blindfold client.
+type Client struct {
+	next   bfclient.Client
+	policy *Policy
+}
+
+var _ bfclient.Client = (*Client)(nil)
+
+// Wrap returns next with p applied: p's call options go first on every
+// call (options passed by the caller still override them), allowlisted
+// values and excluded types are left in the clear, and p's custom
+// patterns and deny lists run locally after the wrapped client, in both
+// local and cloud mode. Build next with p.ClientOptions() so the policy's
+// locales apply.
+func (p *Policy) Wrap(next bfclient.Client) *Client {
+	return &Client{next: next, policy: p}
+}
+
+// Policy returns the applied policy.
+func (c *Client) Policy() *Policy { return c.policy }
+
+func (c *Client) options(opts []blindfold.CallOption) []blindfold.CallOption {
+	return append(c.policy.CallOptions(), opts...)
+}
+
+// Detect detects with the wrapped client, drops allowlisted values and
+// excluded types, then adds custom-pattern and deny-list matches that
+// don't overlap an entity already found.
+func (c *Client) Detect(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.DetectResponse, error) {
+	res, err := c.next.Detect(ctx, text, c.options(opts)...)
+	if err != nil || c.policy.passthrough() {
+		return res, err
+	}
+	var taken []span
+	out := &blindfold.DetectResponse{}
+	for _, e := range res.DetectedEntities {
+		if c.policy.keeps(e.Type, e.Text) {
+			continue
+		}
+		taken = append(taken, span{e.Start, e.End})
+		out.DetectedEntities = append(out.DetectedEntities, e)
+	}
+	for _, m := range c.policy.customMatches(text) {
+		if overlaps(taken, m.Start, m.End) {
+			continue
+		}
+		taken = append(taken, span{m.Start, m.End})
+		out.DetectedEntities = append(out.DetectedEntities, blindfold.DetectedEntity{Type: m.EntityType, Text: m.Text, Start: m.Start, End: m.End, Score: m.Score})
+	}
+	sort.SliceStable(out.DetectedEntities, func(i, j int) bool { return out.DetectedEntities[i].Start < out.DetectedEntities[j].Start })
+	out.EntitiesCount = len(out.DetectedEntities)
+	return out, nil
+}
+
+// Tokenize tokenizes with the wrapped client, puts allowlisted values and
+// excluded types back, then tokenizes custom-pattern and deny-list
+// matches in the result. Custom tokens continue the numbering of the
+// mapping, and a value already in the mapping reuses its token. Start and
+// End of custom entities are offsets into the wrapped client's output,
+// not into the original text.
+func (c *Client) Tokenize(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	res, err := c.next.Tokenize(ctx, text, c.options(opts)...)
+	if err != nil || c.policy.passthrough() {
+		return res, err
+	}
+	res = c.policy.restoreKept(res)
+	if len(c.policy.detectors) == 0 {
+		return res, nil
+	}
+	return c.policy.tokenizeCustom(res), nil
+}
+
+// Detokenize passes through to the wrapped client.
+func (c *Client) Detokenize(text string, m map[string]string) *blindfold.DetokenizeResponse {
+	return c.next.Detokenize(text, m)
+}
+
+// passthrough reports whether the policy adds nothing to the wrapped
+// client's results.
+func (p *Policy) passthrough() bool {
+	return len(p.detectors) == 0 && len(p.allowed) == 0 && len(p.excluded) == 0
+}
+
+// customMatches runs the policy's patterns and deny lists over text and
+// returns non-overlapping matches in order, skipping allowlisted values.
+// Where matches overlap the higher score wins, then the longer match.
+func (p *Policy) customMatches(text string) []blindfold.PIIMatch {
+	if len(p.detectors) == 0 {
+		return nil
+	}
+	lower := strings.ToLower(text)
+	var all []blindfold.PIIMatch
+	for _, d := range p.detectors {
+		for _, m := range d.IterMatches(text, lower) {
+			if !p.allows(m.Text) {
+				all = append(all, m)
+			}
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		a, b := all[i], all[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.End-a.Start > b.End-b.Start
+	})
+	var out []blindfold.PIIMatch
+	last := 0
+	for _, m := range all {
+		if m.Start >= last {
+			out = append(out, m)
+			last = m.End
+		}
+	}
+	return out
+}
+
+// restoreKept detokenizes the allowlisted values and excluded types in res
+// and drops them from its mapping and entities.
+func (p *Policy) restoreKept(res *blindfold.TokenizeResponse) *blindfold.TokenizeResponse {
+	restore := make(map[string]string)
+	for token, value := range res.Mapping {
+		typ, _, _ := mapping.ParseToken(token)
+		if p.keeps(typ, value) {
+			restore[token] = value
+		}
+	}
+	if len(restore) == 0 {
+		return res
+	}
+	out := &blindfold.TokenizeResponse{
+		Text:    mapping.Detokenize(res.Text, restore),
+		Mapping: make(map[string]string, len(res.Mapping)-len(restore)),
+	}
+	for token, value := range res.Mapping {
+		if _, ok := restore[token]; !ok {
+			out.Mapping[token] = value
+		}
+	}
+	for _, e := range res.DetectedEntities {
+		if !p.keeps(e.Type, e.Text) {
+			out.DetectedEntities = append(out.DetectedEntities, e)
+		}
+	}
+	out.EntitiesCount = len(out.DetectedEntities)
+	return out
+}
+
+type span struct{ start, end int }
+
+func overlaps(spans []span, start, end int) bool {
+	for _, s := range spans {
+		if start < s.end && s.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) tokenizeCustom(res *blindfold.TokenizeResponse) *blindfold.TokenizeResponse {
+	text := res.Text
+	matches := p.customMatches(text)
+	if len(matches) == 0 {
+		return res
+	}
+
+	// Existing tokens are off limits, and their numbering continues
+	var taken []span
+	for _, loc := range mapping.TokenPattern.FindAllStringIndex(text, -1) {
+		if _, ok := res.Mapping[text[loc[0]:loc[1]]]; ok {
+			taken = append(taken, span{loc[0], loc[1]})
+		}
+	}
+	out := &blindfold.TokenizeResponse{
+		Mapping:          make(map[string]string, len(res.Mapping)),
+		DetectedEntities: append([]blindfold.DetectedEntity(nil), res.DetectedEntities...),
+	}
+	highest := make(map[string]int)
+	byValue := make(map[string]string)
+	for token, value := range res.Mapping {
+		out.Mapping[token] = value
+		if typ, n, ok := mapping.ParseToken(token); ok {
+			if n > highest[typ] {
+				highest[typ] = n
+			}
+			byValue[typ+"\x00"+value] = token
+		}
+	}
+
+	var b []byte
+	last := 0
+	for _, m := range matches {
+		if m.Start < last || overlaps(taken, m.Start, m.End) {
+			continue
+		}
+		key := m.EntityType + "\x00" + m.Text
+		token, ok := byValue[key]
+		if !ok {
+			highest[m.EntityType]++
+			token = mapping.FormatToken(m.EntityType, highest[m.EntityType])
+			byValue[key] = token
+			out.Mapping[token] = m.Text
+		}
+		b = append(append(b, text[last:m.Start]...), token...)
+		last = m.End
+		out.DetectedEntities = append(out.DetectedEntities, blindfold.DetectedEntity{Type: m.EntityType, Text: m.Text, Start: m.Start, End: m.End, Score: m.Score})
+	}
+	out.Text = string(append(b, text[last:]...))
+	out.EntitiesCount = len(out.DetectedEntities)
+	return out
+}
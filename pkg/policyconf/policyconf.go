@@ -0,0 +1,437 @@
+// Package policyconf loads named protection policies from a YAML or JSON
+// file, so examples select a policy by name at runtime instead of
+// hard-coding "basic".
+//
+// A policy names the entity types to detect (directly, or by extending a
+// built-in Blindfold policy), the locales whose patterns apply, an action
+// per entity type, and custom regex patterns for organization-specific
+// identifiers:
+//
+//	default: support
+//	policies:
+//	  support:
+//	    base: basic
+//	    locales: [us, eu]
+//	    actions:
+//	      Credit Card Number: mask
+//	    patterns:
+//	      - entity: Employee ID
+//	        regex: '\bEMP-\d{6}\b'
+//	    allow: [support@mycompany.com]
+//	    deny:
+//	      Project Codename: [Nightingale]
+//
+// Names not defined in the file resolve to the built-in Blindfold policy of
+// the same name, so "-policy strict" keeps working with any config.
+package policyconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"gopkg.in/yaml.v3"
+)
+
+// Builtins are the policies bundled with the Blindfold SDK.
+var Builtins = []string{"basic", "gdpr_eu", "hipaa_us", "pci_dss", "strict"}
+
+// Action says what to do with an entity type.
+type Action string
+
+// Actions a policy can assign to an entity type.
+const (
+	Tokenize Action = "tokenize" // reversible placeholder (the default)
+	Mask     Action = "mask"     // partially hidden, e.g. ****-****-****-3456
+	Redact   Action = "redact"   // replaced irreversibly
+	Hash     Action = "hash"     // replaced by a stable hash
+	Drop     Action = "drop"     // removed from the text
+	Keep     Action = "keep"     // left as-is
+)
+
+func (a Action) valid() bool {
+	switch a {
+	case Tokenize, Mask, Redact, Hash, Drop, Keep:
+		return true
+	}
+	return false
+}
+
+// Pattern is a custom regex detector for an organization-specific entity.
+type Pattern struct {
+	// Entity is the entity type reported for matches; it also names the
+	// tokens (<Employee ID_1>).
+	Entity string `yaml:"entity" json:"entity"`
+	// Regex is a Go regular expression (RE2 syntax).
+	Regex string `yaml:"regex" json:"regex"`
+	// Score is the confidence reported for matches. Defaults to 0.9.
+	Score float64 `yaml:"score,omitempty" json:"score,omitempty"`
+	// Context, if set, requires one of these keywords within 50
+	// characters of a match.
+	Context []string `yaml:"context,omitempty" json:"context,omitempty"`
+	// Validate names a check run on every match, such as "nhs" for the NHS
+	// number check digit; see RegisterValidator.
+	Validate string `yaml:"validate,omitempty" json:"validate,omitempty"`
+
+	re    *regexp.Regexp
+	valid Validator
+}
+
+// Policy is one named policy.
+type Policy struct {
+	// Name is the policy's key in the config file.
+	Name string `yaml:"-" json:"-"`
+	// Base is a built-in Blindfold policy whose entity types are used when
+	// Entities is empty.
+	Base string `yaml:"base,omitempty" json:"base,omitempty"`
+	// Entities lists the built-in entity types to detect. Takes precedence
+	// over Base.
+	Entities []string `yaml:"entities,omitempty" json:"entities,omitempty"`
+	// Locales selects regional local-mode patterns ("us", "eu", "uk", …).
+	Locales []string `yaml:"locales,omitempty" json:"locales,omitempty"`
+	// Actions assigns an action to entity types; unlisted types are
+	// tokenized.
+	Actions map[string]Action `yaml:"actions,omitempty" json:"actions,omitempty"`
+	// Patterns adds custom detectors.
+	Patterns []Pattern `yaml:"patterns,omitempty" json:"patterns,omitempty"`
+	// Allow lists values that are never tokenized, such as a public support
+	// address, even when a detector finds them. Matching ignores case and
+	// runs of whitespace.
+	Allow []string `yaml:"allow,omitempty" json:"allow,omitempty"`
+	// Deny lists terms that are always tokenized, keyed by the entity type
+	// they are reported as. Terms match whole words, ignoring case.
+	Deny map[string][]string `yaml:"deny,omitempty" json:"deny,omitempty"`
+	// Exclude lists entity types that the wrapped client's detectors never
+	// tokenize. Patterns may still report them, which lets a pack replace a
+	// built-in detector with its own.
+	Exclude []string `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+
+	rules     []Pattern            // Patterns plus one per Deny entry
+	detectors []blindfold.Detector // built from rules
+	allowed   map[string]bool      // normalized Allow values
+	excluded  map[string]bool
+}
+
+// Config is a parsed policy file.
+type Config struct {
+	// Default names the policy used when none is requested.
+	Default  string             `yaml:"default,omitempty" json:"default,omitempty"`
+	Policies map[string]*Policy `yaml:"policies" json:"policies"`
+}
+
+// Load reads a policy file. The format follows the extension: .yaml/.yml
+// or .json.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var format string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		format = "yaml"
+	case ".json":
+		format = "json"
+	default:
+		return nil, fmt.Errorf("policyconf: %s: unknown extension (want .yaml, .yml or .json)", path)
+	}
+	cfg, err := Parse(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse decodes a policy file in format "yaml" or "json" and validates it.
+// Unknown fields are rejected, so typos don't silently weaken a policy.
+func Parse(data []byte, format string) (*Config, error) {
+	var cfg Config
+	switch format {
+	case "yaml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("policyconf: %w", err)
+		}
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("policyconf: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("policyconf: unknown format %q", format)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	if c.Default != "" {
+		if _, ok := c.Policies[c.Default]; !ok && !isBuiltin(c.Default) {
+			return fmt.Errorf("policyconf: default policy %q is not defined", c.Default)
+		}
+	}
+	for name, p := range c.Policies {
+		if p == nil {
+			p = &Policy{}
+			c.Policies[name] = p
+		}
+		p.Name = name
+		if err := p.Compile(); err != nil {
+			return fmt.Errorf("policyconf: policy %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Compile validates a policy built in code and prepares its custom
+// patterns. Load and Parse compile every policy in the file; call Compile
+// yourself before using a Policy literal.
+func (p *Policy) Compile() error {
+	if p.Base != "" && !isBuiltin(p.Base) {
+		return fmt.Errorf("base %q is not a built-in policy (have %s)", p.Base, strings.Join(Builtins, ", "))
+	}
+	for typ, a := range p.Actions {
+		if !a.valid() {
+			return fmt.Errorf("entity %q: unknown action %q (want tokenize, mask, redact, hash, drop or keep)", typ, a)
+		}
+	}
+	p.rules = nil
+	for i := range p.Patterns {
+		pat := &p.Patterns[i]
+		if pat.Entity == "" {
+			return fmt.Errorf("pattern %d: entity is required", i+1)
+		}
+		if err := checkEntity(pat.Entity); err != nil {
+			return fmt.Errorf("pattern %q: %w", pat.Entity, err)
+		}
+		re, err := regexp.Compile(pat.Regex)
+		if err != nil {
+			return fmt.Errorf("pattern %q: %w", pat.Entity, err)
+		}
+		if re.MatchString("") {
+			return fmt.Errorf("pattern %q: regex matches the empty string", pat.Entity)
+		}
+		pat.re = re
+		if pat.Validate != "" {
+			v, err := validator(pat.Validate)
+			if err != nil {
+				return fmt.Errorf("pattern %q: %w", pat.Entity, err)
+			}
+			pat.valid = v
+		}
+		p.rules = append(p.rules, *pat)
+	}
+	deny := make([]string, 0, len(p.Deny))
+	for typ := range p.Deny {
+		deny = append(deny, typ)
+	}
+	sort.Strings(deny)
+	for _, typ := range deny {
+		if err := checkEntity(typ); err != nil {
+			return fmt.Errorf("deny %q: %w", typ, err)
+		}
+		re, err := termsRegexp(p.Deny[typ])
+		if err != nil {
+			return fmt.Errorf("deny %q: %w", typ, err)
+		}
+		p.rules = append(p.rules, Pattern{Entity: typ, Score: 1, re: re})
+	}
+	p.allowed = nil
+	for _, v := range p.Allow {
+		key := normalize(v)
+		if key == "" {
+			return fmt.Errorf("allow: empty value")
+		}
+		if p.allowed == nil {
+			p.allowed = make(map[string]bool, len(p.Allow))
+		}
+		p.allowed[key] = true
+	}
+	p.excluded = nil
+	for _, typ := range p.Exclude {
+		if typ == "" {
+			return fmt.Errorf("exclude: empty entity type")
+		}
+		if p.excluded == nil {
+			p.excluded = make(map[string]bool, len(p.Exclude))
+		}
+		p.excluded[typ] = true
+	}
+	p.detectors = p.newDetectors()
+	return nil
+}
+
+func checkEntity(typ string) error {
+	if strings.ContainsAny(typ, "<>_\n") {
+		return fmt.Errorf("entity may not contain '<', '>', '_' or newlines")
+	}
+	return nil
+}
+
+// termsRegexp matches any of terms as a whole word, ignoring case. Longer
+// terms are tried first so "Project Nightingale" wins over "Nightingale".
+func termsRegexp(terms []string) (*regexp.Regexp, error) {
+	sorted := make([]string, 0, len(terms))
+	for _, t := range terms {
+		if t = strings.TrimSpace(t); t == "" {
+			return nil, fmt.Errorf("empty term")
+		}
+		sorted = append(sorted, t)
+	}
+	if len(sorted) == 0 {
+		return nil, fmt.Errorf("no terms")
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	alts := make([]string, len(sorted))
+	for i, t := range sorted {
+		// \b only applies next to word characters; "C++" ends in a symbol
+		var b strings.Builder
+		if isWord(t[0]) {
+			b.WriteString(`\b`)
+		}
+		b.WriteString(strings.Join(strings.Fields(regexp.QuoteMeta(t)), `\s+`))
+		if isWord(t[len(t)-1]) {
+			b.WriteString(`\b`)
+		}
+		alts[i] = b.String()
+	}
+	return regexp.Compile(`(?i)(?:` + strings.Join(alts, "|") + `)`)
+}
+
+func isWord(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// normalize folds case and collapses whitespace, for allowlist matching.
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// allows reports whether value is on the policy's allowlist.
+func (p *Policy) allows(value string) bool {
+	return len(p.allowed) > 0 && p.allowed[normalize(value)]
+}
+
+// keeps reports whether an entity found by the wrapped client stays in the
+// clear: its value is allowlisted or its type excluded.
+func (p *Policy) keeps(entityType, value string) bool {
+	return p.excluded[entityType] || p.allows(value)
+}
+
+// Policy returns the named policy. An empty name selects the file's
+// default. Names not in the file resolve to built-in Blindfold policies.
+func (c *Config) Policy(name string) (*Policy, error) {
+	if name == "" {
+		name = c.Default
+	}
+	if name == "" {
+		return nil, fmt.Errorf("policyconf: no policy requested and no default set (have %s)", strings.Join(c.Names(), ", "))
+	}
+	if p, ok := c.Policies[name]; ok {
+		return p, nil
+	}
+	if isBuiltin(name) {
+		return Builtin(name), nil
+	}
+	return nil, fmt.Errorf("policyconf: unknown policy %q (have %s)", name, strings.Join(c.Names(), ", "))
+}
+
+// Names returns the policies defined in the file followed by the built-in
+// ones, sorted within each group.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Policies)+len(Builtins))
+	for name := range c.Policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, b := range Builtins {
+		if _, ok := c.Policies[b]; !ok {
+			names = append(names, b)
+		}
+	}
+	return names
+}
+
+// Builtin returns a policy that applies the built-in Blindfold policy name.
+func Builtin(name string) *Policy {
+	return &Policy{Name: name, Base: name}
+}
+
+// Resolve selects a policy by name from the file at path, or from the
+// built-in policies when path is empty — the usual pair of -policies and
+// -policy flags.
+func Resolve(path, name string) (*Policy, error) {
+	if path == "" {
+		cfg := &Config{}
+		return cfg.Policy(name)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Policy(name)
+}
+
+func isBuiltin(name string) bool {
+	for _, b := range Builtins {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Action returns the action for entityType; Tokenize when the policy does
+// not list it.
+func (p *Policy) Action(entityType string) Action {
+	if a, ok := p.Actions[entityType]; ok {
+		return a
+	}
+	return Tokenize
+}
+
+// ClientOptions returns the client options the policy needs (its locales).
+// Pass them to bfclient.FromEnv or blindfold.New.
+func (p *Policy) ClientOptions() []blindfold.Option {
+	if len(p.Locales) == 0 {
+		return nil
+	}
+	return []blindfold.Option{blindfold.WithLocales(p.Locales)}
+}
+
+// CallOptions returns the options selecting the policy's entity types for
+// Detect and Tokenize calls.
+func (p *Policy) CallOptions() []blindfold.CallOption {
+	switch {
+	case len(p.Entities) > 0:
+		return []blindfold.CallOption{blindfold.WithEntities(p.Entities)}
+	case p.Base != "":
+		return []blindfold.CallOption{blindfold.WithCallPolicy(p.Base)}
+	default:
+		return nil
+	}
+}
+
+// CustomTypes returns the entity types of the policy's custom patterns and
+// deny lists.
+func (p *Policy) CustomTypes() []string {
+	var types []string
+	seen := make(map[string]bool)
+	for _, pat := range p.rules {
+		if !seen[pat.Entity] {
+			seen[pat.Entity] = true
+			types = append(types, pat.Entity)
+		}
+	}
+	return types
+}
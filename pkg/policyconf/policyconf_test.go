@@ -0,0 +1,190 @@
+package policyconf
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/fake"
+)
+
+// canned is a wrapped client whose Tokenize returns res whatever the
+// text, standing in for cloud detections a fake can't produce.
+type canned struct {
+	bfclient.Client
+	res blindfold.TokenizeResponse
+}
+
+func (c canned) Tokenize(context.Context, string, ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	res := c.res
+	return &res, nil
+}
+
+func TestWrapTokenize(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		policy  Policy
+		next    bfclient.Client
+		text    string
+		want    string
+		mapping map[string]string
+	}{
+		{
+			name:    "allowlisted value put back",
+			policy:  Policy{Allow: []string{"Support@ACME.com"}},
+			next:    fake.New(fake.WithPatterns("us")),
+			text:    "Write to support@acme.com, not jane@example.com.",
+			want:    "Write to support@acme.com, not <Email Address_2>.",
+			mapping: map[string]string{"<Email Address_2>": "jane@example.com"},
+		},
+		{
+			name:    "allowlisted deny term",
+			policy:  Policy{Allow: []string{"nightingale  ward"}, Deny: map[string][]string{"Project Codename": {"Nightingale", "Nightingale Ward"}}},
+			next:    fake.New(),
+			text:    "Nightingale Ward is public; Nightingale is not.",
+			want:    "Nightingale Ward is public; <Project Codename_1> is not.",
+			mapping: map[string]string{"<Project Codename_1>": "Nightingale"},
+		},
+		{
+			name:    "excluded type",
+			policy:  Policy{Exclude: []string{"Phone Number"}},
+			next:    fake.New(fake.WithPatterns("us")),
+			text:    "Call +1 415-555-0134 or mail jane@example.com.",
+			want:    "Call +1 415-555-0134 or mail <Email Address_1>.",
+			mapping: map[string]string{"<Email Address_1>": "jane@example.com"},
+		},
+		{
+			name:   "deny terms match whole words across whitespace",
+			policy: Policy{Deny: map[string][]string{"Project Codename": {"Project Nightingale", "Nightingale"}}},
+			next:   fake.New(),
+			text:   "Project\n  Nightingale ships; nightingale too, but not Nightingales or SuperNightingale.",
+			want:   "<Project Codename_1> ships; <Project Codename_2> too, but not Nightingales or SuperNightingale.",
+			mapping: map[string]string{
+				"<Project Codename_1>": "Project\n  Nightingale",
+				"<Project Codename_2>": "nightingale",
+			},
+		},
+		{
+			name:   "deny term ending in a symbol",
+			policy: Policy{Deny: map[string][]string{"Tool": {"C++"}}},
+			next:   fake.New(),
+			text:   "Rewrite the C++ service, not the C service.",
+			want:   "Rewrite the <Tool_1> service, not the C service.",
+			mapping: map[string]string{
+				"<Tool_1>": "C++",
+			},
+		},
+		{
+			name:   "numbering continues",
+			policy: Policy{Deny: map[string][]string{"Person": {"Jane Doe"}}},
+			next:   fake.New(fake.WithEntity("Person", "John Smith", "Ann Lee")),
+			text:   "John Smith, Ann Lee and Jane Doe.",
+			want:   "<Person_1>, <Person_2> and <Person_3>.",
+			mapping: map[string]string{
+				"<Person_1>": "John Smith",
+				"<Person_2>": "Ann Lee",
+				"<Person_3>": "Jane Doe",
+			},
+		},
+		{
+			name:   "existing token reused",
+			policy: Policy{Deny: map[string][]string{"Project Codename": {"Nightingale"}}},
+			next: canned{res: blindfold.TokenizeResponse{
+				Text:    "<Project Codename_4> is late; Nightingale slips to May.",
+				Mapping: map[string]string{"<Project Codename_4>": "Nightingale"},
+			}},
+			text:    "Nightingale is late; Nightingale slips to May.",
+			want:    "<Project Codename_4> is late; <Project Codename_4> slips to May.",
+			mapping: map[string]string{"<Project Codename_4>": "Nightingale"},
+		},
+		{
+			name:   "token text is off limits",
+			policy: Policy{Deny: map[string][]string{"Word": {"Person"}}},
+			next:   fake.New(fake.WithEntity("Person", "Jane Doe")),
+			text:   "Person: Jane Doe",
+			want:   "<Word_1>: <Person_1>",
+			mapping: map[string]string{
+				"<Person_1>": "Jane Doe",
+				"<Word_1>":   "Person",
+			},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			p := c.policy
+			if err := p.Compile(); err != nil {
+				t.Fatal(err)
+			}
+			res, err := p.Wrap(c.next).Tokenize(context.Background(), c.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Text != c.want {
+				t.Errorf("text = %q\nwant   %q", res.Text, c.want)
+			}
+			if !reflect.DeepEqual(res.Mapping, c.mapping) {
+				t.Errorf("mapping = %v, want %v", res.Mapping, c.mapping)
+			}
+			if got := mapping.Detokenize(res.Text, res.Mapping); got != c.text {
+				t.Errorf("restored %q, want %q", got, c.text)
+			}
+			if res.EntitiesCount != len(res.DetectedEntities) {
+				t.Errorf("entities count %d for %d entities", res.EntitiesCount, len(res.DetectedEntities))
+			}
+		})
+	}
+}
+
+func TestWrapDetect(t *testing.T) {
+	p := Policy{
+		Allow:   []string{"support@acme.com"},
+		Exclude: []string{"Phone Number"},
+		Deny:    map[string][]string{"Project Codename": {"Nightingale"}},
+	}
+	if err := p.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	text := "Nightingale: call +1 415-555-0134, mail support@acme.com or jane@example.com."
+	res, err := p.Wrap(fake.New(fake.WithPatterns("us"))).Detect(context.Background(), text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range res.DetectedEntities {
+		got = append(got, e.Type+"="+text[e.Start:e.End])
+	}
+	want := []string{"Project Codename=Nightingale", "Email Address=jane@example.com"}
+	if !reflect.DeepEqual(got, want) || res.EntitiesCount != len(want) {
+		t.Errorf("entities = %v (count %d), want %v", got, res.EntitiesCount, want)
+	}
+}
+
+func TestTermsRegexp(t *testing.T) {
+	for _, c := range []struct {
+		terms []string
+		text  string
+		want  []string
+	}{
+		{[]string{"Nightingale"}, "Nightingale, NIGHTINGALE; Nightingales, xNightingale, Nightingale_2", []string{"Nightingale", "NIGHTINGALE"}},
+		{[]string{"Nightingale", "Project Nightingale"}, "Project Nightingale and Nightingale", []string{"Project Nightingale", "Nightingale"}},
+		{[]string{"  Project   Nightingale "}, "project\tnightingale, ProjectNightingale", []string{"project\tnightingale"}},
+		{[]string{"C++", ".NET"}, "C++, C+, .NET and ASP.NET", []string{"C++", ".NET", ".NET"}},
+		{[]string{"a.b"}, "a.b axb", []string{"a.b"}},
+	} {
+		re, err := termsRegexp(c.terms)
+		if err != nil {
+			t.Fatalf("%q: %v", c.terms, err)
+		}
+		if got := re.FindAllString(c.text, -1); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q in %q = %q, want %q", c.terms, c.text, got, c.want)
+		}
+	}
+	for _, terms := range [][]string{nil, {""}, {"ok", "  "}} {
+		if _, err := termsRegexp(terms); err == nil {
+			t.Errorf("%q: want an error", terms)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package policyconf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A Validator checks a pattern match, typically its check digit. Matches it
+// rejects are dropped.
+type Validator func(match string) bool
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]Validator{
+		"aba":      abaChecksum,
+		"cnpj":     cnpjChecksum,
+		"nhs":      nhsChecksum,
+		"ni":       niPrefix,
+		"verhoeff": verhoeffChecksum,
+	}
+)
+
+// RegisterValidator makes v available to patterns as "validate: name". It
+// must be called before the policy files using it are loaded.
+func RegisterValidator(name string, v Validator) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators[name] = v
+}
+
+func validator(name string) (Validator, error) {
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+	if v, ok := validators[name]; ok {
+		return v, nil
+	}
+	names := make([]string, 0, len(validators))
+	for n := range validators {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown validator %q (have %s)", name, strings.Join(names, ", "))
+}
+
+// digits returns the ASCII digits of s, ignoring separators.
+func digits(s string) []int {
+	var out []int
+	for _, c := range s {
+		if '0' <= c && c <= '9' {
+			out = append(out, int(c-'0'))
+		}
+	}
+	return out
+}
+
+// abaChecksum validates a US ABA routing number: the nine digits weighted
+// 3, 7, 1 repeating must sum to a multiple of 10.
+func abaChecksum(s string) bool {
+	d := digits(s)
+	if len(d) != 9 {
+		return false
+	}
+	sum := 0
+	for i, x := range d {
+		sum += x * [3]int{3, 7, 1}[i%3]
+	}
+	return sum%10 == 0
+}
+
+// cnpjChecksum validates a Brazilian CNPJ, numeric or alphanumeric: two
+// modulus 11 check digits over the character values (c - '0') of the
+// first twelve and thirteen characters.
+func cnpjChecksum(s string) bool {
+	var v []int
+	for _, c := range strings.ToUpper(s) {
+		switch {
+		case '0' <= c && c <= '9', 'A' <= c && c <= 'Z':
+			v = append(v, int(c-'0'))
+		}
+	}
+	if len(v) != 14 || v[12] > 9 || v[13] > 9 {
+		return false
+	}
+	same := true
+	for _, x := range v[1:] {
+		same = same && x == v[0]
+	}
+	if same {
+		return false
+	}
+	check := func(n int) int {
+		sum, w := 0, n-7
+		for i := 0; i < n; i++ {
+			sum += v[i] * w
+			if w--; w < 2 {
+				w = 9
+			}
+		}
+		if r := sum % 11; r >= 2 {
+			return 11 - r
+		}
+		return 0
+	}
+	return check(12) == v[12] && check(13) == v[13]
+}
+
+// nhsChecksum validates an NHS number: modulus 11 over the first nine
+// digits with weights 10 down to 2.
+func nhsChecksum(s string) bool {
+	d := digits(s)
+	if len(d) != 10 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += d[i] * (10 - i)
+	}
+	check := 11 - sum%11
+	if check == 11 {
+		check = 0
+	}
+	return check != 10 && check == d[9]
+}
+
+// niPrefix rejects National Insurance numbers with a prefix HMRC never
+// allocates.
+func niPrefix(s string) bool {
+	s = strings.ToUpper(strings.Join(strings.Fields(s), ""))
+	if len(s) < 2 {
+		return false
+	}
+	switch s[:2] {
+	case "BG", "GB", "KN", "NK", "NT", "TN", "ZZ":
+		return false
+	}
+	return true
+}
+
+var (
+	verhoeffD = [10][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 2, 3, 4, 0, 6, 7, 8, 9, 5},
+		{2, 3, 4, 0, 1, 7, 8, 9, 5, 6},
+		{3, 4, 0, 1, 2, 8, 9, 5, 6, 7},
+		{4, 0, 1, 2, 3, 9, 5, 6, 7, 8},
+		{5, 9, 8, 7, 6, 0, 4, 3, 2, 1},
+		{6, 5, 9, 8, 7, 1, 0, 4, 3, 2},
+		{7, 6, 5, 9, 8, 2, 1, 0, 4, 3},
+		{8, 7, 6, 5, 9, 3, 2, 1, 0, 4},
+		{9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+	}
+	verhoeffP = [8][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 5, 7, 6, 2, 8, 3, 0, 9, 4},
+		{5, 8, 0, 3, 7, 9, 6, 1, 4, 2},
+		{8, 9, 1, 6, 0, 4, 3, 5, 2, 7},
+		{9, 4, 5, 3, 1, 2, 6, 8, 7, 0},
+		{4, 2, 8, 6, 5, 7, 3, 9, 0, 1},
+		{2, 7, 9, 3, 8, 0, 6, 4, 1, 5},
+		{7, 0, 4, 6, 9, 1, 3, 2, 5, 8},
+	}
+)
+
+// verhoeffChecksum validates a number whose last digit is a Verhoeff check
+// digit, such as an Aadhaar number.
+func verhoeffChecksum(s string) bool {
+	d := digits(s)
+	if len(d) < 2 {
+		return false
+	}
+	c := 0
+	for i := range d {
+		c = verhoeffD[c][verhoeffP[i%8][d[len(d)-1-i]]]
+	}
+	return c == 0
+}
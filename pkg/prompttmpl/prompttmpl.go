@@ -0,0 +1,184 @@
+// Package prompttmpl renders text/template prompts whose fields are
+// tokenized as they are rendered.
+//
+// A prompt template knows which of its fields come from a customer: the
+// email, the ticket body, the name on the order. Tokenizing the whole
+// rendered prompt afterwards works, but it also runs detection over the
+// instructions and examples, and any field that was meant to stay (an
+// order number that looks like a phone number) can't be told apart. Here
+// the template says what to protect, and only those fields are touched:
+//
+//	t := prompttmpl.Must(prompttmpl.New("reply", bf).Parse(
+//		`Customer {{.Name | protectAs "Person"}} ({{.Email | protect}}) wrote:
+//	{{.Body | protect}}
+//	Order {{.Order}}: write a short reply.`))
+//	p, _ := t.Execute(ctx, ticket)
+//	// p.Text goes to the model; p.Restore(reply) puts the values back
+//
+// protect tokenizes the PII found in a value, like Tokenize on that value
+// alone. protectAs replaces the whole value with a token of the given
+// type without running detection, for fields that are PII by definition
+// (a name detection might miss in local mode). Tokens from all fields
+// share one mapping, so a value that appears in two fields gets one token.
+package prompttmpl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// Tokenizer is the one method a Template uses. *blindfold.Client,
+// bfclient.Client and policy-wrapped clients all satisfy it.
+type Tokenizer interface {
+	Tokenize(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, error)
+}
+
+// Template is a text/template with the protect and protectAs functions.
+// A parsed Template is safe for concurrent use.
+type Template struct {
+	t    *template.Template
+	tk   Tokenizer
+	opts []blindfold.CallOption
+}
+
+// New allocates a template that tokenizes with tk. Call options apply to
+// every Tokenize call the template makes.
+func New(name string, tk Tokenizer, opts ...blindfold.CallOption) *Template {
+	t := &Template{tk: tk, opts: opts}
+	t.t = template.New(name).Funcs(placeholderFuncs)
+	return t
+}
+
+// placeholderFuncs let templates parse; Execute binds the real ones.
+var placeholderFuncs = template.FuncMap{
+	"protect":   func(v any) (string, error) { return "", errUnbound },
+	"protectAs": func(typ string, v any) (string, error) { return "", errUnbound },
+}
+
+var errUnbound = errors.New("prompttmpl: template executed outside Template.Execute")
+
+// Funcs adds functions to the template, as in text/template. It must be
+// called before Parse, and can't replace protect or protectAs.
+func (t *Template) Funcs(funcs template.FuncMap) *Template {
+	t.t.Funcs(funcs)
+	t.t.Funcs(placeholderFuncs)
+	return t
+}
+
+// Parse parses text as the template body.
+func (t *Template) Parse(text string) (*Template, error) {
+	if _, err := t.t.Parse(text); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Must panics if err is non-nil, for use in variable initialization.
+func Must(t *Template, err error) *Template {
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Prompt is a rendered template.
+type Prompt struct {
+	// Text is the rendered prompt, with protected fields tokenized.
+	Text string
+	// Mapping restores every token in Text.
+	Mapping map[string]string
+	// Entities counts the tokenized values by entity type.
+	Entities map[string]int
+}
+
+// Restore puts the values back into text, typically the model's reply.
+func (p *Prompt) Restore(text string) string {
+	return mapping.Detokenize(text, p.Mapping)
+}
+
+// Execute renders the template with data. If a protected field can't be
+// tokenized, Execute fails and no text is returned: the field is never
+// rendered in the clear.
+func (t *Template) Execute(ctx context.Context, data any) (*Prompt, error) {
+	r := &render{ctx: ctx, t: t, mapping: make(map[string]string), byValue: make(map[string]string), highest: make(map[string]int), entities: make(map[string]int)}
+	tmpl, err := t.t.Clone()
+	if err != nil {
+		return nil, err
+	}
+	tmpl.Funcs(template.FuncMap{"protect": r.protect, "protectAs": r.protectAs})
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	return &Prompt{Text: b.String(), Mapping: r.mapping, Entities: r.entities}, nil
+}
+
+// render holds the mapping of one Execute call.
+type render struct {
+	ctx      context.Context
+	t        *Template
+	mapping  map[string]string
+	byValue  map[string]string // entity type + value → token
+	highest  map[string]int
+	entities map[string]int
+}
+
+func (r *render) protect(v any) (string, error) {
+	text := fmt.Sprint(v)
+	if text == "" {
+		return "", nil
+	}
+	res, err := r.t.tk.Tokenize(r.ctx, text, r.t.opts...)
+	if err != nil {
+		return "", fmt.Errorf("protect: %w", err)
+	}
+	if len(res.Mapping) == 0 {
+		return res.Text, nil
+	}
+	m := mapping.Merge(r.mapping, res.Mapping)
+	for token, value := range res.Mapping {
+		typ, _, _ := mapping.ParseToken(token)
+		if _, seen := r.byValue[typ+"\x00"+value]; !seen {
+			r.entities[typ]++
+		}
+	}
+	r.adopt(m.Mapping)
+	return m.Rewrite(1, res.Text), nil
+}
+
+func (r *render) protectAs(typ string, v any) (string, error) {
+	text := fmt.Sprint(v)
+	if text == "" {
+		return "", nil
+	}
+	key := typ + "\x00" + text
+	if token, ok := r.byValue[key]; ok {
+		return token, nil
+	}
+	r.highest[typ]++
+	token := mapping.FormatToken(typ, r.highest[typ])
+	r.mapping[token] = text
+	r.byValue[key] = token
+	r.entities[typ]++
+	return token, nil
+}
+
+// adopt makes m the render's mapping and indexes its tokens.
+func (r *render) adopt(m map[string]string) {
+	r.mapping = m
+	for token, value := range m {
+		if typ, n, ok := mapping.ParseToken(token); ok {
+			r.byValue[typ+"\x00"+value] = token
+			if n > r.highest[typ] {
+				r.highest[typ] = n
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package prompttmpl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+var local = blindfold.New(blindfold.WithMode("local"))
+
+type ticket struct {
+	Name, Email, Body, Order string
+}
+
+var reply = Must(New("reply", local).Parse(`Customer {{.Name | protectAs "Person"}} ({{.Email | protect}}) wrote:
+{{.Body | protect}}
+Order {{.Order}}. Greet {{.Name | protectAs "Person"}} by name.`))
+
+func TestExecute(t *testing.T) {
+	p, err := reply.Execute(context.Background(), ticket{
+		Name:  "Jane Doe",
+		Email: "jane.doe@example.com",
+		Body:  "Please email jane.doe@example.com or bob@example.org, or call 415-555-0134.",
+		Order: "415-555-01",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Customer <Person_1> (<Email Address_1>) wrote:
+Please email <Email Address_1> or <Email Address_2>, or call <Phone Number_1>.
+Order 415-555-01. Greet <Person_1> by name.`
+	if p.Text != want {
+		t.Errorf("text =\n%s\nwant\n%s", p.Text, want)
+	}
+	if len(p.Mapping) != 4 || p.Mapping["<Email Address_2>"] != "bob@example.org" || p.Mapping["<Person_1>"] != "Jane Doe" {
+		t.Errorf("mapping = %v", p.Mapping)
+	}
+	if p.Entities["Email Address"] != 2 || p.Entities["Person"] != 1 {
+		t.Errorf("entities = %v", p.Entities)
+	}
+	if got := p.Restore("Hi <Person_1>, we wrote to <Email Address_2>."); got != "Hi Jane Doe, we wrote to bob@example.org." {
+		t.Errorf("restore = %q", got)
+	}
+}
+
+type failing struct{}
+
+func (failing) Tokenize(context.Context, string, ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestFailClosed(t *testing.T) {
+	tmpl := Must(New("t", failing{}).Parse(`{{.Email | protect}}`))
+	p, err := tmpl.Execute(context.Background(), ticket{Email: "jane.doe@example.com"})
+	if err == nil || p != nil || !strings.Contains(err.Error(), "unavailable") {
+		t.Fatalf("p = %v, err = %v", p, err)
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	tmpl := Must(New("t", local).Funcs(map[string]any{"upper": strings.ToUpper}).Parse(`{{.Body | upper}} {{.Email | protect}}`))
+	p, err := tmpl.Execute(context.Background(), ticket{Body: "hi", Email: "a@example.com"})
+	if err != nil || p.Text != "HI <Email Address_1>" {
+		t.Fatalf("p = %+v, err = %v", p, err)
+	}
+}
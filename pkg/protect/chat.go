@@ -0,0 +1,32 @@
+package protect
+
+import (
+	"context"
+	"errors"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+)
+
+// Chat returns a Func that sends the safe text to llm as the user message
+// after system, and returns the first choice: the one-shot prompt most
+// recipes make. Tell the model in system to keep placeholders as they are.
+func Chat(ctx context.Context, llm bfclient.ChatCompleter, model, system string) Func {
+	return func(safe string) (string, error) {
+		res, err := llm.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+			Model: model,
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: system},
+				{Role: openai.ChatMessageRoleUser, Content: safe},
+			},
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(res.Choices) == 0 {
+			return "", errors.New("protect: empty response")
+		}
+		return res.Choices[0].Message.Content, nil
+	}
+}
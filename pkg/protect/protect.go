@@ -0,0 +1,230 @@
+// Package protect is the tokenize → call → detokenize pattern in one
+// place:
+//
+//	reply, err := protect.Do(ctx, userMessage, func(safe string) (string, error) {
+//		return askModel(ctx, safe) // safe holds <Type_N> tokens, never the values
+//	})
+//
+// A Protector adds the hooks a real deployment needs around that call: a
+// policy to tokenize with, a Store that keeps one mapping per conversation
+// so a customer keeps their token across turns, a guardrail over the text
+// about to leave, and a leak scan over the reply before it is restored.
+//
+//	p := protect.New(bf)
+//	p.Policy = pol
+//	p.Guard = guardrail.New(bf, guardrail.Block)
+//	reply, err := p.Do(protect.Session(ctx, conversationID), userMessage, askModel)
+//
+// fn never sees a real value, and nothing is restored when it fails.
+// Recipes that need the mapping before the call, to look values up or to
+// merge several inputs, use pkg/mapping directly.
+package protect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/guardrail"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/leakscan"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/policyconf"
+)
+
+// ErrUnresolved is returned, wrapped, by a Strict Protector when the
+// output has tokens the mapping doesn't know: the model mangled or
+// invented a placeholder.
+var ErrUnresolved error = &bferrors.Error{Kind: bferrors.MappingMissing, Err: errors.New("protect: output has unknown tokens")}
+
+// Func is the protected call. It gets the input tokenized and returns
+// output that may hold the same tokens.
+type Func func(safe string) (string, error)
+
+// Protector runs Funcs on tokenized input and restores their output. Set
+// its fields before first use.
+type Protector struct {
+	bf bfclient.Client
+
+	// Policy, if set, is applied to every Tokenize call, as Policy.Wrap
+	// would.
+	Policy *policyconf.Policy
+	// Store, if set, keeps the mapping of each Session across calls.
+	// Calls without a Session use a mapping of their own. Either way a
+	// value repeated in the input gets one token.
+	Store Store
+	// Guard, if set, checks the tokenized input before it is passed on,
+	// for PII tokenization missed. In Block mode a finding stops the
+	// call; in Strip mode the stripped text is passed on.
+	Guard *guardrail.Guard
+	// Leaks, if set, scans the output before it is restored, for PII the
+	// call produced itself.
+	Leaks *leakscan.Scanner
+	// Strict fails calls whose output has tokens the mapping doesn't
+	// know, instead of returning them as they are.
+	Strict bool
+	// CallOptions apply to every Tokenize call.
+	CallOptions []blindfold.CallOption
+
+	mu sync.Mutex // serializes load-merge-save of session mappings
+}
+
+// New returns a Protector that tokenizes with bf.
+func New(bf bfclient.Client) *Protector {
+	return &Protector{bf: bf}
+}
+
+var (
+	defaultOnce sync.Once
+	defaultP    *Protector
+)
+
+// Default is the Protector Do uses: no hooks, over a client from the
+// environment. API key is optional — without one it runs in local mode
+// (regex-based, offline).
+func Default() *Protector {
+	defaultOnce.Do(func() { defaultP = New(bfclient.FromEnv()) })
+	return defaultP
+}
+
+// Do runs fn with Default.
+func Do(ctx context.Context, input string, fn Func) (string, error) {
+	return Default().Do(ctx, input, fn)
+}
+
+// Result is everything a protected call produced.
+type Result struct {
+	// Safe is the text fn was given.
+	Safe string
+	// Output is what fn returned, after the leak scan.
+	Output string
+	// Text is Output restored.
+	Text string
+	// Mapping restores Safe and Output. With a Session it is the whole
+	// conversation's.
+	Mapping map[string]string
+	// Entities are the entities tokenized in the input.
+	Entities []blindfold.DetectedEntity
+	// Findings are the guardrail's, Leaks the leak scan's.
+	Findings []guardrail.Finding
+	Leaks    []leakscan.Leak
+	// Unresolved are the tokens in Output the mapping doesn't know.
+	Unresolved []string
+}
+
+// Summary counts the entities tokenized in the input by type, as
+// "Email Address ×2, Phone Number ×1", for logs: it holds no values.
+func (r *Result) Summary() string {
+	counts := make(map[string]int)
+	for _, e := range r.Entities {
+		counts[e.Type]++
+	}
+	types := make([]string, 0, len(counts))
+	for typ, n := range counts {
+		types = append(types, fmt.Sprintf("%s ×%d", typ, n))
+	}
+	sort.Strings(types)
+	return strings.Join(types, ", ")
+}
+
+// Do tokenizes input, runs fn on it and returns fn's output restored.
+func (p *Protector) Do(ctx context.Context, input string, fn Func) (string, error) {
+	res, err := p.Run(ctx, input, fn)
+	if err != nil {
+		return "", err
+	}
+	return res.Text, nil
+}
+
+// Run is Do with the details, for callers that report what was protected
+// or found. On an error the Result, if any, has everything up to the stage
+// that failed, and no restored text.
+func (p *Protector) Run(ctx context.Context, input string, fn Func) (*Result, error) {
+	res, err := p.tokenize(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if p.Guard != nil {
+		safe, findings, err := p.Guard.Check(ctx, res.Safe)
+		res.Findings = findings
+		if err != nil {
+			return res, err
+		}
+		res.Safe = safe
+	}
+
+	out, err := fn(res.Safe)
+	if err != nil {
+		return res, err
+	}
+	if p.Leaks != nil {
+		scanned, leaks, err := p.Leaks.Scan(ctx, out, res.Safe)
+		res.Leaks = leaks
+		if err != nil {
+			return res, fmt.Errorf("protect: leak scan: %w", err)
+		}
+		out = scanned
+	}
+	res.Output = out
+	res.Unresolved = mapping.Unresolved(out, res.Mapping)
+	if p.Strict && len(res.Unresolved) > 0 {
+		return res, fmt.Errorf("%w: %s", ErrUnresolved, strings.Join(res.Unresolved, ", "))
+	}
+	res.Text = mapping.Detokenize(out, res.Mapping)
+	return res, nil
+}
+
+// Restore puts the values of the session's mapping back into text, for
+// output that arrives outside Run, such as a reply fetched later.
+func (p *Protector) Restore(ctx context.Context, text string) (string, error) {
+	key, ok := sessionKey(ctx)
+	if !ok || p.Store == nil {
+		return "", errors.New("protect: Restore needs a Store and a Session")
+	}
+	m, err := p.Store.Load(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("protect: load mapping: %w", err)
+	}
+	return mapping.Detokenize(text, m), nil
+}
+
+// tokenize tokenizes input and folds repeats of a value into one token;
+// in a session, it merges the mapping into the stored one and rewrites the
+// text to the stored tokens.
+func (p *Protector) tokenize(ctx context.Context, input string) (*Result, error) {
+	var bf bfclient.Client = p.bf
+	if p.Policy != nil {
+		bf = p.Policy.Wrap(bf)
+	}
+	tok, err := bf.Tokenize(ctx, input, p.CallOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("protect: tokenize: %w", err)
+	}
+	res := &Result{Entities: tok.DetectedEntities}
+
+	key, ok := sessionKey(ctx)
+	if !ok || p.Store == nil {
+		res.Safe, res.Mapping = mapping.Fold(tok.Text, tok.Mapping)
+		return res, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stored, err := p.Store.Load(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("protect: load mapping: %w", err)
+	}
+	merged := mapping.Merge(stored, tok.Mapping)
+	res.Safe, res.Mapping = merged.Rewrite(1, tok.Text), merged.Mapping
+	if len(merged.Mapping) != len(stored) {
+		if err := p.Store.Save(ctx, key, merged.Mapping); err != nil {
+			return nil, fmt.Errorf("protect: save mapping: %w", err)
+		}
+	}
+	return res, nil
+}
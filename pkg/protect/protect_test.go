@@ -0,0 +1,133 @@
+package protect
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/guardrail"
+)
+
+var local = blindfold.New(blindfold.WithMode("local"))
+
+// echo returns safe as it was given, recording it.
+type echo struct{ got []string }
+
+func (e *echo) fn(safe string) (string, error) {
+	e.got = append(e.got, safe)
+	return "Re: " + safe, nil
+}
+
+func TestDo(t *testing.T) {
+	var e echo
+	res, err := New(local).Run(context.Background(), "Mail omar@example.com", e.fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.got[0] != "Mail <Email Address_1>" {
+		t.Errorf("fn got %q", e.got[0])
+	}
+	if res.Text != "Re: Mail omar@example.com" || res.Summary() != "Email Address ×1" {
+		t.Errorf("text = %q, summary = %q", res.Text, res.Summary())
+	}
+}
+
+func TestRepeatsShareAToken(t *testing.T) {
+	var e echo
+	res, err := New(local).Run(context.Background(), "Mail omar@example.com, cc omar@example.com", e.fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.got[0] != "Mail <Email Address_1>, cc <Email Address_1>" || len(res.Mapping) != 1 {
+		t.Errorf("fn got %q, mapping = %v", e.got[0], res.Mapping)
+	}
+}
+
+func TestFailedCallRestoresNothing(t *testing.T) {
+	boom := errors.New("model down")
+	res, err := New(local).Run(context.Background(), "Mail omar@example.com", func(string) (string, error) { return "", boom })
+	if !errors.Is(err, boom) || res.Text != "" {
+		t.Errorf("res = %+v, err = %v", res, err)
+	}
+}
+
+func TestSessionKeepsTokens(t *testing.T) {
+	p := New(local)
+	p.Store = NewMemoryStore()
+	ctx := Session(context.Background(), "conversation-1")
+	var e echo
+	for _, msg := range []string{"I'm omar@example.com", "Also cc lina@example.com", "Reply to omar@example.com"} {
+		if _, err := p.Do(ctx, msg, e.fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Each message numbers its own tokens from 1; the session keeps one
+	// token per address
+	if !strings.Contains(e.got[2], "<Email Address_1>") || strings.Contains(e.got[1], "<Email Address_1>") {
+		t.Errorf("turns = %q", e.got)
+	}
+	if out, _ := p.Restore(ctx, e.got[1]); out != "Also cc lina@example.com" {
+		t.Errorf("later restore = %q", out)
+	}
+	// Another session starts over
+	res, _ := p.Run(Session(context.Background(), "conversation-2"), "lina@example.com", e.fn)
+	if len(res.Mapping) != 1 {
+		t.Errorf("conversation-2 mapping = %v", res.Mapping)
+	}
+}
+
+// leaky tokenizes nothing, as a detector that misses everything would.
+type leaky struct{ bfclient.Client }
+
+func (leaky) Tokenize(_ context.Context, text string, _ ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	return &blindfold.TokenizeResponse{Text: text, Mapping: map[string]string{}}, nil
+}
+
+func TestGuardBlocks(t *testing.T) {
+	p := New(leaky{})
+	p.Guard = guardrail.New(local, guardrail.Block)
+	called := false
+	_, err := p.Do(context.Background(), "Card 4111 1111 1111 1111", func(string) (string, error) {
+		called = true
+		return "", nil
+	})
+	if !errors.Is(err, guardrail.ErrBlocked) || called {
+		t.Errorf("err = %v, called = %v", err, called)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	p := New(local)
+	p.Strict = true
+	_, err := p.Do(context.Background(), "Mail omar@example.com", func(string) (string, error) {
+		return "Sent to <Email Address_1> and <Person_1>", nil
+	})
+	if !errors.Is(err, ErrUnresolved) || !strings.Contains(err.Error(), "<Person_1>") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+type model struct{ req openai.ChatCompletionRequest }
+
+func (m *model) CreateChatCompletion(_ context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	m.req = req
+	reply := "Replied to " + req.Messages[1].Content
+	return openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: reply}}}}, nil
+}
+
+func TestChat(t *testing.T) {
+	ctx := context.Background()
+	var m model
+	out, err := New(local).Do(ctx, "omar@example.com", Chat(ctx, &m, "gpt-4o-mini", "Keep placeholders."))
+	if err != nil || out != "Replied to omar@example.com" {
+		t.Fatalf("out = %q, err = %v", out, err)
+	}
+	if m.req.Messages[0].Content != "Keep placeholders." || m.req.Messages[1].Content != "<Email Address_1>" {
+		t.Errorf("request = %+v", m.req.Messages)
+	}
+}
@@ -0,0 +1,74 @@
+package protect
+
+import (
+	"context"
+	"sync"
+)
+
+// Store keeps token mappings by session. Load returns an empty mapping,
+// not an error, for a session it has never seen. A Protector serializes
+// its own calls to one Store; a Store shared between processes must make
+// Save safe against concurrent writers itself.
+type Store interface {
+	Load(ctx context.Context, session string) (map[string]string, error)
+	Save(ctx context.Context, session string, m map[string]string) error
+}
+
+type sessionKeyType struct{}
+
+// Session marks ctx as belonging to a session, such as a conversation or
+// a document, whose calls share one mapping in the Protector's Store: a
+// value seen before keeps its token, and the model sees one placeholder
+// per customer however many times they come up.
+func Session(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionKeyType{}, id)
+}
+
+func sessionKey(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(sessionKeyType{}).(string)
+	return id, ok
+}
+
+// MemoryStore is a Store in process memory, for a single service whose
+// sessions don't need to outlive it.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]map[string]string
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]map[string]string)}
+}
+
+// Load returns a copy of the session's mapping.
+func (s *MemoryStore) Load(_ context.Context, session string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyMapping(s.sessions[session]), nil
+}
+
+// Save replaces the session's mapping with a copy of m.
+func (s *MemoryStore) Save(_ context.Context, session string, m map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[session] = copyMapping(m)
+	return nil
+}
+
+// Forget drops the session's mapping, when the conversation ends.
+func (s *MemoryStore) Forget(session string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, session)
+}
+
+func copyMapping(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
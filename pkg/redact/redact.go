@@ -0,0 +1,124 @@
+// Package redact removes detected PII for good, for analytics and export
+// paths where nothing may be restored later.
+//
+// Tokenize returns a mapping next to the tokenized text, and whoever holds
+// the two can restore the original. A team that has promised not to store
+// anything reversible has to make sure that mapping is never written
+// anywhere. This package only ever calls Detect, so no mapping exists to
+// keep, and its result is its own type: code that accepts a Text can't be
+// handed tokenized text by mistake.
+//
+//	r := redact.New(bf)
+//	t, _ := r.Redact(ctx, "Mail jane@example.com")
+//	t.String() // "Mail [Email Address]"
+//
+// RedactEach does the same for many short texts in one call.
+package redact
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/masking"
+)
+
+// Detector is the one method a Redactor uses. *blindfold.Client,
+// bfclient.Client and policy-wrapped clients all satisfy it.
+type Detector interface {
+	Detect(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.DetectResponse, error)
+}
+
+// Text is redacted text. Its only constructor is Redactor.Redact, so a
+// value of this type never carries a mapping and can't hold tokens.
+type Text struct {
+	s      string
+	counts map[string]int
+}
+
+// String returns the redacted text.
+func (t Text) String() string { return t.s }
+
+// Counts returns how many entities of each type were removed. It holds
+// types and numbers only, never values.
+func (t Text) Counts() map[string]int {
+	out := make(map[string]int, len(t.counts))
+	for typ, n := range t.counts {
+		out[typ] = n
+	}
+	return out
+}
+
+// MarshalJSON encodes t as a JSON string.
+func (t Text) MarshalJSON() ([]byte, error) { return json.Marshal(t.s) }
+
+// Label returns what replaces an entity of the given type:
+// "[Email Address]".
+func Label(entityType string) string { return "[" + entityType + "]" }
+
+// Redactor replaces every detected entity with its Label.
+type Redactor struct {
+	d Detector
+}
+
+// New returns a Redactor that finds entities with d.
+func New(d Detector) *Redactor {
+	return &Redactor{d: d}
+}
+
+// Redact detects PII in text and replaces each entity with its label. An
+// error means nothing was redacted; there is no partial result to use.
+func (r *Redactor) Redact(ctx context.Context, text string, opts ...blindfold.CallOption) (Text, error) {
+	res, err := r.d.Detect(ctx, text, opts...)
+	if err != nil {
+		return Text{}, fmt.Errorf("redact: %w", err)
+	}
+	rules := make(masking.Rules)
+	counts := make(map[string]int)
+	for _, e := range res.DetectedEntities {
+		label := Label(e.Type)
+		rules[e.Type] = func(string) string { return label }
+		counts[e.Type]++
+	}
+	return Text{s: rules.Apply(text, res.DetectedEntities), counts: counts}, nil
+}
+
+// RedactEach redacts several texts, such as log lines, with one Detect
+// call over all of them joined by newlines. An entity that runs from one
+// text into the next is redacted in both. An error means none were
+// redacted.
+func (r *Redactor) RedactEach(ctx context.Context, texts []string, opts ...blindfold.CallOption) ([]Text, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	joined := strings.Join(texts, "\n")
+	res, err := r.d.Detect(ctx, joined, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("redact: %w", err)
+	}
+	out := make([]Text, len(texts))
+	start := 0 // offset of texts[i] in joined
+	ents := res.DetectedEntities
+	for i, t := range texts {
+		end := start + len(t)
+		rules := make(masking.Rules)
+		counts := make(map[string]int)
+		var mine []blindfold.DetectedEntity
+		for _, e := range ents {
+			if e.End <= start || e.Start >= end {
+				continue
+			}
+			s, en := max(e.Start, start)-start, min(e.End, end)-start
+			label := Label(e.Type)
+			rules[e.Type] = func(string) string { return label }
+			counts[e.Type]++
+			mine = append(mine, blindfold.DetectedEntity{Type: e.Type, Text: t[s:en], Start: s, End: en, Score: e.Score})
+		}
+		out[i] = Text{s: rules.Apply(t, mine), counts: counts}
+		start = end + 1
+	}
+	return out, nil
+}
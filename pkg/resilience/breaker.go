@@ -0,0 +1,206 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+)
+
+// ErrOpen is returned by Breaker.Allow while the circuit is open.
+var ErrOpen = errors.New("resilience: circuit open")
+
+// State is the state of a Breaker.
+type State int
+
+const (
+	Closed   State = iota // calls flow normally
+	Open                  // calls are rejected until the cooldown elapses
+	HalfOpen              // one probe call is let through
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Breaker is a consecutive-failure circuit breaker. After Threshold
+// failures in a row it opens for Cooldown, then lets a single probe through:
+// success closes it, failure opens it again.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+	// OnStateChange, if set, is called (outside the lock) on every
+	// transition.
+	OnStateChange func(from, to State)
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewBreaker returns a closed breaker.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{Threshold: threshold, Cooldown: cooldown}
+}
+
+// State returns the current state.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Allow reports whether a call may proceed, returning ErrOpen if not.
+// Every allowed call must be followed by exactly one Record.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	from := b.state
+	if b.state == Open && time.Since(b.openedAt) >= b.Cooldown {
+		b.state = HalfOpen
+	}
+	var err error
+	switch {
+	case b.state == Open:
+		err = ErrOpen
+	case b.state == HalfOpen && b.probing:
+		err = ErrOpen
+	case b.state == HalfOpen:
+		b.probing = true
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return err
+}
+
+// Record reports the outcome of an allowed call.
+func (b *Breaker) Record(err error) {
+	b.mu.Lock()
+	from := b.state
+	b.probing = false
+	if err == nil {
+		b.failures = 0
+		b.state = Closed
+	} else {
+		b.failures++
+		if b.state == HalfOpen || b.failures >= b.Threshold {
+			b.state = Open
+			b.openedAt = time.Now()
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// release ends an allowed call without recording an outcome.
+func (b *Breaker) release() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+func (b *Breaker) notify(from, to State) {
+	if from != to && b.OnStateChange != nil {
+		b.OnStateChange(from, to)
+	}
+}
+
+// FallbackClient sends calls to Primary while its breaker is closed and to
+// Secondary otherwise — typically a cloud-mode client backed by a
+// local-mode one. A primary call that fails with an outage — a network
+// failure or a server error — is retried on the secondary right away, so
+// callers never see cloud outages; they only get the reduced detection
+// coverage of the fallback. Any other error, such as a rejected API key or
+// a bad request, is returned as is and doesn't count against the breaker:
+// the backend is up, and serving the call locally would only hide the
+// mistake behind weaker protection.
+type FallbackClient struct {
+	Primary   bfclient.Client
+	Secondary bfclient.Client
+	Breaker   *Breaker
+	// OnFallback, if set, is called for every call served by Secondary,
+	// with the primary error (ErrOpen while the circuit is open).
+	OnFallback func(op string, err error)
+}
+
+var _ bfclient.Client = (*FallbackClient)(nil)
+
+// NewLocalFallback pairs primary with a local-mode client for the same
+// locales and opens after threshold consecutive failures for cooldown.
+func NewLocalFallback(primary bfclient.Client, threshold int, cooldown time.Duration, opts ...blindfold.Option) *FallbackClient {
+	return &FallbackClient{
+		Primary:   primary,
+		Secondary: blindfold.New(append(opts, blindfold.WithMode("local"))...),
+		Breaker:   NewBreaker(threshold, cooldown),
+	}
+}
+
+// Detect runs Detect on the primary, falling back to the secondary.
+func (c *FallbackClient) Detect(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.DetectResponse, error) {
+	return fallback(c, ctx, "detect", func(bf bfclient.Client) (*blindfold.DetectResponse, error) {
+		return bf.Detect(ctx, text, opts...)
+	})
+}
+
+// Tokenize runs Tokenize on the primary, falling back to the secondary.
+func (c *FallbackClient) Tokenize(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	return fallback(c, ctx, "tokenize", func(bf bfclient.Client) (*blindfold.TokenizeResponse, error) {
+		return bf.Tokenize(ctx, text, opts...)
+	})
+}
+
+// Detokenize is local in every mode, so it always uses the primary.
+func (c *FallbackClient) Detokenize(text string, mapping map[string]string) *blindfold.DetokenizeResponse {
+	return c.Primary.Detokenize(text, mapping)
+}
+
+func fallback[T any](c *FallbackClient, ctx context.Context, op string, call func(bfclient.Client) (T, error)) (T, error) {
+	err := c.Breaker.Allow()
+	if err == nil {
+		var v T
+		v, err = call(c.Primary)
+		if ctx.Err() != nil {
+			// The caller gave up; that says nothing about the backend.
+			c.Breaker.release()
+			return v, err
+		}
+		if err != nil && !outage(err) {
+			c.Breaker.release()
+			return v, err
+		}
+		c.Breaker.Record(err)
+		if err == nil {
+			return v, nil
+		}
+	}
+	if c.OnFallback != nil {
+		c.OnFallback(op, err)
+	}
+	return call(c.Secondary)
+}
+
+// outage reports whether err means the backend is unavailable rather
+// than refusing the call. A request that timed out is an outage: fallback
+// has already told the caller's own deadline apart by its context.
+func outage(err error) bool {
+	return bferrors.KindOf(err) == bferrors.Transient
+}
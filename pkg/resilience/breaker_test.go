@@ -0,0 +1,64 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+func TestFallbackOnOutageOnly(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		err      error
+		fallback bool
+	}{
+		{"network", errUnavailable, true},
+		{"server error", &blindfold.APIError{BlindfoldError: blindfold.BlindfoldError{Message: "bad gateway", StatusCode: http.StatusBadGateway}}, true},
+		{"revoked key", &blindfold.AuthenticationError{BlindfoldError: blindfold.BlindfoldError{Message: "invalid API key"}}, false},
+		{"bad request", &blindfold.APIError{BlindfoldError: blindfold.BlindfoldError{Message: "text too long", StatusCode: http.StatusBadRequest}}, false},
+	} {
+		cloud, local := &region{name: "cloud", err: c.err}, &region{name: "local"}
+		f := &FallbackClient{Primary: cloud, Secondary: local, Breaker: NewBreaker(1, time.Minute)}
+		res, err := f.Tokenize(context.Background(), "text")
+		if c.fallback {
+			if err != nil || res.Text != "local" || f.Breaker.State() != Open {
+				t.Errorf("%s: res = %+v, err = %v, breaker %s; want local mode and an open breaker", c.name, res, err, f.Breaker.State())
+			}
+			continue
+		}
+		if !errors.Is(err, c.err) || local.calls.Load() != 0 || f.Breaker.State() != Closed {
+			t.Errorf("%s: err = %v, %d local calls, breaker %s; want the error and a closed breaker", c.name, err, local.calls.Load(), f.Breaker.State())
+		}
+	}
+}
+
+// A cloud that hangs until the client's timeout is an outage, unless it
+// was the caller who ran out of time.
+func TestFallbackOnTimeout(t *testing.T) {
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { <-release }))
+	defer hung.Close()
+	defer close(release)
+	cloud := blindfold.New(blindfold.WithAPIKey("test"), blindfold.WithBaseURL(hung.URL),
+		blindfold.WithMaxRetries(0), blindfold.WithTimeout(50*time.Millisecond))
+
+	local := &region{name: "local"}
+	f := &FallbackClient{Primary: cloud, Secondary: local, Breaker: NewBreaker(1, time.Minute)}
+	res, err := f.Tokenize(context.Background(), "text")
+	if err != nil || res.Text != "local" || f.Breaker.State() != Open {
+		t.Errorf("client timeout: res = %+v, err = %v, breaker %s; want local mode and an open breaker", res, err, f.Breaker.State())
+	}
+
+	local = &region{name: "local"}
+	f = &FallbackClient{Primary: cloud, Secondary: local, Breaker: NewBreaker(1, time.Minute)}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := f.Tokenize(ctx, "text"); err == nil || local.calls.Load() != 0 || f.Breaker.State() != Closed {
+		t.Errorf("caller deadline: err = %v, %d local calls, breaker %s; want the error and a closed breaker", err, local.calls.Load(), f.Breaker.State())
+	}
+}
@@ -0,0 +1,41 @@
+package resilience
+
+import "sync"
+
+// Budget limits retries to a fraction of overall traffic. Every call
+// deposits ratio tokens and every retry withdraws a whole one, so with a
+// ratio of 0.1 at most one call in ten can be retried once the reserve is
+// spent. The balance is capped at reserve+1, which keeps a long quiet period
+// from banking unlimited retries for the next outage.
+type Budget struct {
+	mu      sync.Mutex
+	ratio   float64
+	max     float64
+	balance float64
+}
+
+// NewBudget returns a budget that starts with reserve retries available.
+func NewBudget(ratio float64, reserve int) *Budget {
+	return &Budget{ratio: ratio, max: float64(reserve) + 1, balance: float64(reserve)}
+}
+
+// Deposit records one call.
+func (b *Budget) Deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.balance += b.ratio
+	if b.balance > b.max {
+		b.balance = b.max
+	}
+}
+
+// Withdraw takes one retry from the budget, reporting false if none is left.
+func (b *Budget) Withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.balance < 1 {
+		return false
+	}
+	b.balance--
+	return true
+}
@@ -0,0 +1,64 @@
+package resilience
+
+import (
+	"context"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+)
+
+// Client applies a Policy to every network call of a Blindfold client.
+// Detokenize is local and passes straight through.
+type Client struct {
+	next   bfclient.Client
+	policy *Policy
+}
+
+var _ bfclient.Client = (*Client)(nil)
+
+// Wrap returns next guarded by p.
+func Wrap(next bfclient.Client, p *Policy) *Client {
+	return &Client{next: next, policy: p}
+}
+
+// Detect calls the wrapped client's Detect under the policy.
+func (c *Client) Detect(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.DetectResponse, error) {
+	return Call(ctx, c.policy, func(ctx context.Context) (*blindfold.DetectResponse, error) {
+		return c.next.Detect(ctx, text, opts...)
+	})
+}
+
+// Tokenize calls the wrapped client's Tokenize under the policy.
+func (c *Client) Tokenize(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	return Call(ctx, c.policy, func(ctx context.Context) (*blindfold.TokenizeResponse, error) {
+		return c.next.Tokenize(ctx, text, opts...)
+	})
+}
+
+// Detokenize passes through to the wrapped client.
+func (c *Client) Detokenize(text string, mapping map[string]string) *blindfold.DetokenizeResponse {
+	return c.next.Detokenize(text, mapping)
+}
+
+// ChatClient applies a Policy to chat completion calls.
+type ChatClient struct {
+	next   bfclient.ChatCompleter
+	policy *Policy
+}
+
+var _ bfclient.ChatCompleter = (*ChatClient)(nil)
+
+// WrapChat returns next guarded by p. Give LLM clients their own policy —
+// provider rate limits are unrelated to Blindfold's.
+func WrapChat(next bfclient.ChatCompleter, p *Policy) *ChatClient {
+	return &ChatClient{next: next, policy: p}
+}
+
+// CreateChatCompletion calls the wrapped client under the policy.
+func (c *ChatClient) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	return Call(ctx, c.policy, func(ctx context.Context) (openai.ChatCompletionResponse, error) {
+		return c.next.CreateChatCompletion(ctx, req)
+	})
+}
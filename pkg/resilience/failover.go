@@ -0,0 +1,256 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+)
+
+// ErrNoHealthyEndpoint is returned by Failover when every endpoint is
+// failing its health checks or has an open circuit.
+var ErrNoHealthyEndpoint = errors.New("resilience: no healthy endpoint")
+
+// Endpoint is one cloud deployment of the Blindfold API, typically a
+// region, with the client that calls it.
+type Endpoint struct {
+	Name   string
+	Client bfclient.Client
+}
+
+// EndpointStatus is a snapshot of one endpoint's health.
+type EndpointStatus struct {
+	Name      string    `json:"name"`
+	Healthy   bool      `json:"healthy"` // passing health checks with a closed circuit
+	Circuit   string    `json:"circuit"`
+	LastCheck time.Time `json:"last_check,omitempty"`
+	LastError string    `json:"last_error,omitempty"` // of the last health check
+}
+
+type member struct {
+	Endpoint
+	breaker *Breaker
+	down    atomic.Bool // failed the last health check
+
+	mu        sync.Mutex
+	lastCheck time.Time
+	lastErr   error
+}
+
+// Failover spreads calls over several endpoints in order of preference.
+// Each call goes to the first endpoint that is passing its health checks
+// and whose circuit breaker is closed; when it fails with a retryable
+// error the call moves on to the next endpoint at once, so a regional
+// outage costs callers one failed attempt at most, until the breaker
+// opens and the endpoint is skipped outright.
+//
+// Calls whose context is marked with LatencySensitive are also hedged:
+// if the endpoint hasn't answered after HedgeAfter, the same call is sent
+// to the next endpoint and the first answer wins. That trims tail latency
+// for callers waiting on it, at the cost of extra load on the hedged
+// fraction of calls.
+//
+// Detokenize is local and goes to the first endpoint's client.
+type Failover struct {
+	// HedgeAfter is how long a latency-sensitive call waits before it is
+	// hedged; zero disables hedging.
+	HedgeAfter time.Duration
+	// Retryable decides whether an error moves a call to the next
+	// endpoint. Nil means the package-level Retryable.
+	Retryable func(error) bool
+	// OnFailover, if set, is called when a call fails on endpoint and is
+	// sent to the next one, with the error.
+	OnFailover func(op, endpoint string, err error)
+	// OnHedge, if set, is called when a hedged call is sent to endpoint.
+	OnHedge func(op, endpoint string)
+
+	members []*member
+}
+
+var _ bfclient.Client = (*Failover)(nil)
+
+// NewFailover returns a Failover over endpoints, most preferred first;
+// there must be at least one. Each endpoint's breaker opens after
+// threshold consecutive failures for cooldown. Build the endpoint clients
+// with blindfold.WithMaxRetries(0), so a failing endpoint hands over
+// without retrying first.
+func NewFailover(threshold int, cooldown time.Duration, endpoints ...Endpoint) *Failover {
+	f := &Failover{}
+	for _, e := range endpoints {
+		f.members = append(f.members, &member{Endpoint: e, breaker: NewBreaker(threshold, cooldown)})
+	}
+	return f
+}
+
+type latencySensitiveKey struct{}
+
+// LatencySensitive marks ctx as belonging to a call someone is waiting on,
+// such as a chat request, which a Failover may hedge.
+func LatencySensitive(ctx context.Context) context.Context {
+	return context.WithValue(ctx, latencySensitiveKey{}, true)
+}
+
+// CheckHealth probes every endpoint once, concurrently, with a small
+// Detect call. An endpoint that fails its probe is skipped until a probe
+// succeeds again.
+func (f *Failover) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, m := range f.members {
+		wg.Add(1)
+		go func(m *member) {
+			defer wg.Done()
+			_, err := m.Client.Detect(ctx, "health check")
+			if ctx.Err() != nil {
+				return
+			}
+			m.down.Store(err != nil)
+			m.mu.Lock()
+			m.lastCheck, m.lastErr = time.Now(), err
+			m.mu.Unlock()
+		}(m)
+	}
+	wg.Wait()
+}
+
+// Run checks health every interval until ctx is done. Start it in its own
+// goroutine.
+func (f *Failover) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		check, cancel := context.WithTimeout(ctx, interval)
+		f.CheckHealth(check)
+		cancel()
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Status reports the health of every endpoint, in order of preference.
+func (f *Failover) Status() []EndpointStatus {
+	out := make([]EndpointStatus, len(f.members))
+	for i, m := range f.members {
+		state := m.breaker.State()
+		m.mu.Lock()
+		out[i] = EndpointStatus{
+			Name:      m.Name,
+			Healthy:   !m.down.Load() && state == Closed,
+			Circuit:   state.String(),
+			LastCheck: m.lastCheck,
+		}
+		if m.lastErr != nil {
+			out[i].LastError = m.lastErr.Error()
+		}
+		m.mu.Unlock()
+	}
+	return out
+}
+
+// Detect runs Detect on the preferred healthy endpoint.
+func (f *Failover) Detect(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.DetectResponse, error) {
+	return failover(f, ctx, "detect", func(ctx context.Context, bf bfclient.Client) (*blindfold.DetectResponse, error) {
+		return bf.Detect(ctx, text, opts...)
+	})
+}
+
+// Tokenize runs Tokenize on the preferred healthy endpoint.
+func (f *Failover) Tokenize(ctx context.Context, text string, opts ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	return failover(f, ctx, "tokenize", func(ctx context.Context, bf bfclient.Client) (*blindfold.TokenizeResponse, error) {
+		return bf.Tokenize(ctx, text, opts...)
+	})
+}
+
+// Detokenize is local in every mode, so it uses the first endpoint.
+func (f *Failover) Detokenize(text string, mapping map[string]string) *blindfold.DetokenizeResponse {
+	return f.members[0].Client.Detokenize(text, mapping)
+}
+
+type attempt[T any] struct {
+	v   T
+	err error
+	m   *member
+}
+
+func failover[T any](f *Failover, parent context.Context, op string, call func(context.Context, bfclient.Client) (T, error)) (T, error) {
+	var zero T
+	retryable := f.Retryable
+	if retryable == nil {
+		retryable = Retryable
+	}
+	// Cancelled on return, which stops the loser of a hedge
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	results := make(chan attempt[T], len(f.members))
+	next, inflight := 0, 0
+
+	// start sends the call to the next available endpoint, reporting
+	// whether there was one. The attempt records its own outcome on the
+	// endpoint's breaker, even after failover has returned.
+	start := func() (*member, bool) {
+		for ; next < len(f.members); next++ {
+			m := f.members[next]
+			if m.down.Load() || m.breaker.Allow() != nil {
+				continue
+			}
+			next++
+			inflight++
+			go func() {
+				v, err := call(ctx, m.Client)
+				switch {
+				case err != nil && ctx.Err() != nil:
+					// Cancelled: says nothing about the endpoint
+					m.breaker.release()
+				case err != nil && retryable(err):
+					m.breaker.Record(err)
+				default:
+					m.breaker.Record(nil)
+				}
+				results <- attempt[T]{v, err, m}
+			}()
+			return m, true
+		}
+		return nil, false
+	}
+
+	if _, ok := start(); !ok {
+		return zero, ErrNoHealthyEndpoint
+	}
+	var hedge <-chan time.Time
+	if f.HedgeAfter > 0 && parent.Value(latencySensitiveKey{}) != nil {
+		t := time.NewTimer(f.HedgeAfter)
+		defer t.Stop()
+		hedge = t.C
+	}
+	var lastErr error
+	for inflight > 0 {
+		select {
+		case <-hedge:
+			hedge = nil // at most one hedge per call
+			if m, ok := start(); ok && f.OnHedge != nil {
+				f.OnHedge(op, m.Name)
+			}
+		case a := <-results:
+			inflight--
+			if a.err == nil || parent.Err() != nil || !retryable(a.err) {
+				return a.v, a.err
+			}
+			lastErr = a.err
+			if inflight > 0 {
+				continue // a hedge is still running
+			}
+			if _, ok := start(); ok && f.OnFailover != nil {
+				f.OnFailover(op, a.m.Name, a.err)
+			}
+		}
+	}
+	return zero, fmt.Errorf("resilience: every endpoint failed, last: %w", lastErr)
+}
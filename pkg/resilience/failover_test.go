@@ -0,0 +1,152 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+)
+
+var errUnavailable = &blindfold.NetworkError{BlindfoldError: blindfold.BlindfoldError{Message: "connection refused"}}
+
+// region answers with its own name after delay, or fails with err.
+type region struct {
+	bfclient.Client
+	name  string
+	delay time.Duration
+	err   error
+	calls atomic.Int32
+}
+
+func (r *region) Tokenize(ctx context.Context, _ string, _ ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	r.calls.Add(1)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(r.delay):
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &blindfold.TokenizeResponse{Text: r.name}, nil
+}
+
+func (r *region) Detect(ctx context.Context, _ string, _ ...blindfold.CallOption) (*blindfold.DetectResponse, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &blindfold.DetectResponse{}, nil
+}
+
+func newRegions(threshold int, regions ...*region) *Failover {
+	var eps []Endpoint
+	for _, r := range regions {
+		eps = append(eps, Endpoint{Name: r.name, Client: r})
+	}
+	return NewFailover(threshold, time.Minute, eps...)
+}
+
+func TestFailoverMovesToNextEndpoint(t *testing.T) {
+	eu, us := &region{name: "eu", err: errUnavailable}, &region{name: "us"}
+	f := newRegions(2, eu, us)
+	var moved []string
+	f.OnFailover = func(op, endpoint string, err error) { moved = append(moved, op+" "+endpoint) }
+
+	for i := 0; i < 3; i++ {
+		res, err := f.Tokenize(context.Background(), "x")
+		if err != nil || res.Text != "us" {
+			t.Fatalf("call %d: %v, %v", i, res, err)
+		}
+	}
+	// The breaker opened after two failures, so the third call skipped eu
+	if n := eu.calls.Load(); n != 2 {
+		t.Errorf("eu called %d times, want 2", n)
+	}
+	if len(moved) != 2 || moved[0] != "tokenize eu" {
+		t.Errorf("failovers = %v", moved)
+	}
+	if s := f.Status(); s[0].Healthy || s[0].Circuit != "open" || !s[1].Healthy {
+		t.Errorf("status = %+v", s)
+	}
+}
+
+func TestFailoverReturnsNonRetryableErrors(t *testing.T) {
+	bad := &blindfold.APIError{BlindfoldError: blindfold.BlindfoldError{Message: "text too long", StatusCode: 400}}
+	eu, us := &region{name: "eu", err: bad}, &region{name: "us"}
+	f := newRegions(2, eu, us)
+	if _, err := f.Tokenize(context.Background(), "x"); !errors.Is(err, bad) {
+		t.Errorf("err = %v, want the 400", err)
+	}
+	if us.calls.Load() != 0 {
+		t.Error("a 400 was retried on the next endpoint")
+	}
+	if s := f.Status(); !s[0].Healthy {
+		t.Errorf("a 400 counted against eu: %+v", s[0])
+	}
+}
+
+func TestFailoverAllFailing(t *testing.T) {
+	f := newRegions(1, &region{name: "eu", err: errUnavailable}, &region{name: "us", err: errUnavailable})
+	if _, err := f.Tokenize(context.Background(), "x"); !errors.Is(err, errUnavailable) {
+		t.Errorf("first call: %v", err)
+	}
+	if _, err := f.Tokenize(context.Background(), "x"); !errors.Is(err, ErrNoHealthyEndpoint) {
+		t.Errorf("with both circuits open: %v, want ErrNoHealthyEndpoint", err)
+	}
+}
+
+func TestFailoverSkipsEndpointsFailingHealthChecks(t *testing.T) {
+	eu, us := &region{name: "eu", err: errUnavailable}, &region{name: "us"}
+	f := newRegions(5, eu, us)
+	f.CheckHealth(context.Background())
+	if res, err := f.Tokenize(context.Background(), "x"); err != nil || res.Text != "us" {
+		t.Fatalf("%v, %v", res, err)
+	}
+	if eu.calls.Load() != 0 {
+		t.Error("a call went to the endpoint failing its health check")
+	}
+	s := f.Status()
+	if s[0].Healthy || s[0].LastError != "connection refused" || s[0].LastCheck.IsZero() {
+		t.Errorf("status = %+v", s[0])
+	}
+
+	eu.err = nil
+	f.CheckHealth(context.Background())
+	if res, _ := f.Tokenize(context.Background(), "x"); res.Text != "eu" {
+		t.Errorf("served by %s after eu recovered", res.Text)
+	}
+}
+
+func TestFailoverHedgesLatencySensitiveCalls(t *testing.T) {
+	eu, us := &region{name: "eu", delay: 200 * time.Millisecond}, &region{name: "us"}
+	f := newRegions(2, eu, us)
+	f.HedgeAfter = 10 * time.Millisecond
+	var hedged atomic.Int32
+	f.OnHedge = func(string, string) { hedged.Add(1) }
+
+	start := time.Now()
+	res, err := f.Tokenize(LatencySensitive(context.Background()), "x")
+	if err != nil || res.Text != "us" {
+		t.Fatalf("hedged call: %v, %v", res, err)
+	}
+	if d := time.Since(start); d > 150*time.Millisecond {
+		t.Errorf("hedged call took %s", d)
+	}
+	if hedged.Load() != 1 {
+		t.Errorf("%d hedges, want 1", hedged.Load())
+	}
+
+	// Unmarked calls wait for the preferred endpoint
+	if res, err := f.Tokenize(context.Background(), "x"); err != nil || res.Text != "eu" {
+		t.Errorf("unmarked call: %v, %v", res, err)
+	}
+	// The cancelled loser didn't count against eu
+	if s := f.Status(); !s[0].Healthy {
+		t.Errorf("status = %+v", s[0])
+	}
+}
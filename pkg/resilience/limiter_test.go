@@ -0,0 +1,59 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterBurstThenRate(t *testing.T) {
+	l := NewLimiter(50, 2)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Two from the burst, then two at 20ms apart
+	if d := time.Since(start); d < 30*time.Millisecond || d > time.Second {
+		t.Errorf("4 calls at 50/s with a burst of 2 took %s", d)
+	}
+}
+
+func TestLimiterWaitHonorsContext(t *testing.T) {
+	l := NewLimiter(0.001, 1)
+	_ = l.Wait(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait on an empty bucket = %v, want the deadline", err)
+	}
+}
+
+func TestLimiterWithoutRateIsUnlimited(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		if l := NewLimiter(rate, 1); l != nil {
+			t.Errorf("NewLimiter(%v) = %+v, want nil", rate, l)
+		}
+		p := DefaultPolicy(rate)
+		done := make(chan error, 1)
+		go func() {
+			for i := 0; i < 100; i++ {
+				if err := Do(context.Background(), p, func(context.Context) error { return nil }); err != nil {
+					done <- err
+					return
+				}
+			}
+			done <- nil
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("rps %v: %v", rate, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("rps %v: calls blocked", rate)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Package resilience adds client-side rate limiting and retries to the
+// Blindfold client and to LLM clients.
+//
+// A Policy combines three mechanisms:
+//
+//   - a token-bucket Limiter that paces outgoing calls,
+//   - exponential Backoff with full jitter between attempts on 429 and 5xx,
+//   - a retry Budget that caps retries to a fraction of overall traffic, so
+//     an outage does not multiply load by the attempt count.
+//
+// The Blindfold SDK retries on its own (two retries by default). When you
+// wrap it, build the client with blindfold.WithMaxRetries(0) so retries are
+// only counted once, against the shared budget.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bferrors"
+)
+
+// ErrBudgetExhausted is returned (wrapping the last attempt's error) when a
+// retry was needed but the retry budget had no tokens left.
+var ErrBudgetExhausted = errors.New("resilience: retry budget exhausted")
+
+// Backoff computes exponential delays with full jitter.
+type Backoff struct {
+	Base time.Duration // delay before the first retry, before jitter
+	Max  time.Duration // upper bound for any single delay
+}
+
+// Delay returns the wait before retry number attempt (0-based): a random
+// duration in [0, min(Max, Base*2^attempt)].
+func (b Backoff) Delay(attempt int) time.Duration {
+	d := b.Base
+	for i := 0; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	if d > b.Max {
+		d = b.Max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// Policy configures Do and Call. Limiter and Budget are optional and are
+// meant to be shared by every caller that talks to the same backend.
+type Policy struct {
+	MaxAttempts int // total attempts including the first; values < 1 mean 1
+	Backoff     Backoff
+	Limiter     *Limiter
+	Budget      *Budget
+	// Retryable decides whether an error is worth another attempt. Nil
+	// means the package-level Retryable.
+	Retryable func(error) bool
+}
+
+// DefaultPolicy returns a policy with 4 attempts, 200ms–5s backoff, a
+// limiter at rps requests per second (burst of the same size), and a budget
+// allowing retries for 10% of requests plus a reserve of 10. An rps of 0
+// or less leaves calls unlimited.
+func DefaultPolicy(rps float64) *Policy {
+	burst := int(rps)
+	if burst < 1 {
+		burst = 1
+	}
+	return &Policy{
+		MaxAttempts: 4,
+		Backoff:     Backoff{Base: 200 * time.Millisecond, Max: 5 * time.Second},
+		Limiter:     NewLimiter(rps, burst),
+		Budget:      NewBudget(0.1, 10),
+	}
+}
+
+// Do runs fn until it succeeds, returns a non-retryable error, runs out of
+// attempts, or the retry budget is exhausted. Every attempt waits on the
+// limiter first.
+func Do(ctx context.Context, p *Policy, fn func(ctx context.Context) error) error {
+	_, err := Call(ctx, p, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// Call is Do for functions that return a value.
+func Call[T any](ctx context.Context, p *Policy, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = Retryable
+	}
+	if p.Budget != nil {
+		p.Budget.Deposit()
+	}
+
+	for attempt := 0; ; attempt++ {
+		if p.Limiter != nil {
+			if err := p.Limiter.Wait(ctx); err != nil {
+				return zero, err
+			}
+		}
+		v, err := fn(ctx)
+		if err == nil {
+			return v, nil
+		}
+		if attempt+1 >= attempts || !retryable(err) || ctx.Err() != nil {
+			return zero, err
+		}
+		if p.Budget != nil && !p.Budget.Withdraw() {
+			return zero, errors.Join(ErrBudgetExhausted, err)
+		}
+		if err := sleep(ctx, p.Backoff.Delay(attempt)); err != nil {
+			return zero, err
+		}
+	}
+}
+
+// Retryable reports whether err is a rate-limit (429), a server error
+// (5xx), or a network failure from the Blindfold SDK or go-openai, as
+// classified by bferrors. Context cancellation, authentication errors and
+// a used-up quota are never retryable.
+func Retryable(err error) bool {
+	return bferrors.IsRetryable(err)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
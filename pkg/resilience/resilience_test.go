@@ -0,0 +1,80 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Base: 10 * time.Millisecond, Max: 50 * time.Millisecond}
+	for attempt, ceiling := range []time.Duration{10, 20, 40, 50, 50, 50} {
+		ceiling *= time.Millisecond
+		for i := 0; i < 100; i++ {
+			if d := b.Delay(attempt); d < 0 || d > ceiling {
+				t.Fatalf("Delay(%d) = %s, want within [0, %s]", attempt, d, ceiling)
+			}
+		}
+	}
+	if d := (Backoff{}).Delay(3); d != 0 {
+		t.Errorf("zero Backoff: Delay = %s", d)
+	}
+}
+
+var (
+	errServer = &blindfold.APIError{BlindfoldError: blindfold.BlindfoldError{Message: "unavailable", StatusCode: 503}}
+	errAuth   = &blindfold.AuthenticationError{BlindfoldError: blindfold.BlindfoldError{Message: "bad key", StatusCode: 401}}
+)
+
+func TestCallRetries(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		errs     []error // returned by successive attempts; then success
+		attempts int
+		budget   *Budget
+		wantErr  error
+		calls    int
+	}{
+		{"succeeds after retries", []error{errServer, errServer}, 4, nil, nil, 3},
+		{"out of attempts", []error{errServer, errServer, errServer}, 2, nil, errServer, 2},
+		{"not retryable", []error{errAuth}, 4, nil, errAuth, 1},
+		{"budget exhausted", []error{errServer, errServer}, 4, NewBudget(0, 1), ErrBudgetExhausted, 2},
+	} {
+		calls := 0
+		p := &Policy{MaxAttempts: c.attempts, Backoff: Backoff{Base: time.Millisecond, Max: time.Millisecond}, Budget: c.budget}
+		v, err := Call(context.Background(), p, func(context.Context) (int, error) {
+			calls++
+			if calls <= len(c.errs) {
+				return 0, c.errs[calls-1]
+			}
+			return calls, nil
+		})
+		if calls != c.calls {
+			t.Errorf("%s: %d calls, want %d", c.name, calls, c.calls)
+		}
+		switch {
+		case c.wantErr != nil:
+			if !errors.Is(err, c.wantErr) {
+				t.Errorf("%s: err = %v, want %v", c.name, err, c.wantErr)
+			}
+		case err != nil || v != c.calls:
+			t.Errorf("%s: v = %d, err = %v", c.name, v, err)
+		}
+	}
+}
+
+func TestCallStopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := Do(ctx, &Policy{MaxAttempts: 5, Backoff: Backoff{Base: time.Hour, Max: time.Hour}}, func(context.Context) error {
+		calls++
+		cancel()
+		return errServer
+	})
+	if calls != 1 || !errors.Is(err, errServer) {
+		t.Errorf("%d calls, err = %v; want one call and its error", calls, err)
+	}
+}
@@ -0,0 +1,293 @@
+// Package semcache caches model answers by the meaning of tokenized
+// prompts, so the same question about different customers is answered
+// once.
+//
+// "Can you resend the invoice to jane@example.com?" and "Can you resend
+// the invoice to bob@example.org?" are one question. Tokenized, they are
+// "... to <Email Address_1>?" and, once token numbers are made canonical
+// (numbered by order of appearance), identical as text. The cache stores
+// the answer as a template in canonical tokens, and on a hit rewrites it
+// into the tokens of the new request, so the caller detokenizes with its
+// own mapping and gets its own customer's values back. Prompts that are
+// worded differently but mean the same match by embedding similarity.
+//
+//	c := semcache.New(semcache.OpenAI(oa, openai.SmallEmbedding3), 0.92)
+//	if answer, ok, _ := c.Lookup(ctx, tokenized.Text); ok {
+//		return bf.Detokenize(answer, tokenized.Mapping).Text
+//	}
+//
+// Nothing in the cache identifies anyone: prompts, templates and vectors
+// are built from tokenized text, and mappings are never stored.
+package semcache
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// Embedder turns text into a vector.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+type openAIEmbedder struct {
+	c     *openai.Client
+	model openai.EmbeddingModel
+}
+
+// OpenAI returns an Embedder backed by the OpenAI embeddings API. It is
+// only ever given canonical tokenized text.
+func OpenAI(c *openai.Client, model openai.EmbeddingModel) Embedder {
+	return openAIEmbedder{c: c, model: model}
+}
+
+func (e openAIEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	res, err := e.c.CreateEmbeddings(ctx, openai.EmbeddingRequest{Input: []string{text}, Model: e.model})
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Data) == 0 {
+		return nil, fmt.Errorf("semcache: empty embedding response")
+	}
+	return res.Data[0].Embedding, nil
+}
+
+// Words is an offline Embedder that hashes words into dim buckets. It
+// matches rewordings that share most of their words, not paraphrases,
+// which makes it useful for tests and demos and not much else.
+type Words int
+
+func (dim Words) Embed(_ context.Context, text string) ([]float32, error) {
+	v := make([]float32, int(dim))
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '<' && r != '>' && r != '_'
+	}) {
+		h := fnv.New32a()
+		h.Write([]byte(w))
+		v[h.Sum32()%uint32(dim)]++
+	}
+	return v, nil
+}
+
+// Canonical renumbers the tokens in text by order of first appearance
+// per type, so prompts that differ only in which customer they are about
+// read the same. It returns the canonical text and the renames from
+// canonical token to the token in text.
+func Canonical(text string) (string, map[string]string) {
+	toCanon := make(map[string]string)
+	fromCanon := make(map[string]string)
+	counts := make(map[string]int)
+	out := mapping.ReplaceTokens(text, func(token string) string {
+		if c, ok := toCanon[token]; ok {
+			return c
+		}
+		typ, _, ok := mapping.ParseToken(token)
+		if !ok {
+			return token
+		}
+		counts[typ]++
+		c := mapping.FormatToken(typ, counts[typ])
+		toCanon[token], fromCanon[c] = c, token
+		return c
+	})
+	return out, fromCanon
+}
+
+// specific matches numbers in a prompt that an answer may depend on:
+// order numbers, amounts, dates. Tokens are taken out first.
+var specific = regexp.MustCompile(`\d[\d.,:/-]*\d|\d`)
+
+type entry struct {
+	prompt    string // canonical
+	vector    []float32
+	answer    string   // canonical template
+	specifics []string // numbers from the prompt that the answer repeats
+}
+
+// Stats is a snapshot of cache counters.
+type Stats struct {
+	Hits    int64
+	Exact   int64 // hits answered without an embedding call
+	Misses  int64
+	Entries int
+}
+
+// Cache holds answer templates. Set its fields before first use; it is
+// safe for concurrent use after that.
+type Cache struct {
+	e         Embedder
+	threshold float64
+	// MaxEntries caps the cache; the oldest entry goes first. Zero means
+	// 10000.
+	MaxEntries int
+
+	mu      sync.RWMutex
+	entries []*entry
+	exact   map[string]*entry
+
+	hits, exactHits, misses atomic.Int64
+}
+
+// New returns a cache that matches prompts with cosine similarity of at
+// least threshold between embeddings from e.
+func New(e Embedder, threshold float64) *Cache {
+	return &Cache{e: e, threshold: threshold, exact: make(map[string]*entry)}
+}
+
+// Stats returns the current counters.
+func (c *Cache) Stats() Stats {
+	c.mu.RLock()
+	n := len(c.entries)
+	c.mu.RUnlock()
+	return Stats{Hits: c.hits.Load(), Exact: c.exactHits.Load(), Misses: c.misses.Load(), Entries: n}
+}
+
+// Lookup returns the cached answer for a tokenized prompt, rewritten into
+// the prompt's own tokens. An answer is only returned if every token it
+// uses exists in the prompt and every number it took from its original
+// prompt appears in this one too, so "order 5531" is never answered with
+// the template for order 6602.
+func (c *Cache) Lookup(ctx context.Context, tokenized string) (string, bool, error) {
+	canon, renames := Canonical(tokenized)
+	c.mu.RLock()
+	e, ok := c.exact[canon]
+	c.mu.RUnlock()
+	if ok {
+		if answer, ok := e.render(canon, renames); ok {
+			c.hits.Add(1)
+			c.exactHits.Add(1)
+			return answer, true, nil
+		}
+	}
+
+	v, err := c.e.Embed(ctx, canon)
+	if err != nil {
+		return "", false, fmt.Errorf("semcache: %w", err)
+	}
+	var best *entry
+	bestSim := c.threshold
+	c.mu.RLock()
+	for _, e := range c.entries {
+		if sim := cosine(v, e.vector); sim >= bestSim {
+			if _, ok := e.render(canon, renames); ok {
+				best, bestSim = e, sim
+			}
+		}
+	}
+	c.mu.RUnlock()
+	if best == nil {
+		c.misses.Add(1)
+		return "", false, nil
+	}
+	answer, _ := best.render(canon, renames)
+	c.hits.Add(1)
+	return answer, true, nil
+}
+
+// render rewrites the answer template into the request's tokens, or
+// reports that it doesn't fit the request.
+func (e *entry) render(canon string, renames map[string]string) (string, bool) {
+	bare := mapping.TokenPattern.ReplaceAllString(canon, " ")
+	for _, s := range e.specifics {
+		if !strings.Contains(bare, s) {
+			return "", false
+		}
+	}
+	fits := true
+	out := mapping.ReplaceTokens(e.answer, func(token string) string {
+		t, ok := renames[token]
+		if !ok {
+			fits = false
+		}
+		return t
+	})
+	return out, fits
+}
+
+// Store caches answer, the model's tokenized reply to a tokenized prompt.
+// Answers that use a token the prompt doesn't hold can't be rewritten for
+// another request and are not stored; Store reports whether it stored
+// the answer.
+func (c *Cache) Store(ctx context.Context, tokenized, answer string) (bool, error) {
+	canon, renames := Canonical(tokenized)
+	toCanon := make(map[string]string, len(renames))
+	for c, t := range renames {
+		toCanon[t] = c
+	}
+	fits := true
+	template := mapping.ReplaceTokens(answer, func(token string) string {
+		c, ok := toCanon[token]
+		if !ok {
+			fits = false
+		}
+		return c
+	})
+	if !fits {
+		return false, nil
+	}
+
+	bare := mapping.TokenPattern.ReplaceAllString(canon, " ")
+	var specifics []string
+	for _, s := range specific.FindAllString(mapping.TokenPattern.ReplaceAllString(template, " "), -1) {
+		if strings.Contains(bare, s) {
+			specifics = append(specifics, s)
+		}
+	}
+	v, err := c.e.Embed(ctx, canon)
+	if err != nil {
+		return false, fmt.Errorf("semcache: %w", err)
+	}
+
+	e := &entry{prompt: canon, vector: v, answer: template, specifics: specifics}
+	limit := c.MaxEntries
+	if limit <= 0 {
+		limit = 10000
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Entries are never changed once stored, so a lookup can keep using
+	// one after the lock is released; a newer answer replaces the old one
+	if old, ok := c.exact[canon]; ok {
+		for i := range c.entries {
+			if c.entries[i] == old {
+				c.entries[i] = e
+			}
+		}
+		c.exact[canon] = e
+		return true, nil
+	}
+	if len(c.entries) >= limit {
+		delete(c.exact, c.entries[0].prompt)
+		c.entries = c.entries[1:]
+	}
+	c.entries = append(c.entries, e)
+	c.exact[canon] = e
+	return true, nil
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
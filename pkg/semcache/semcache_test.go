@@ -0,0 +1,54 @@
+package semcache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	got, renames := Canonical("Send <Email Address_4> and <Person_2> to <Email Address_3>, cc <Email Address_4>")
+	if want := "Send <Email Address_1> and <Person_1> to <Email Address_2>, cc <Email Address_1>"; got != want {
+		t.Errorf("canonical = %q, want %q", got, want)
+	}
+	if renames["<Email Address_2>"] != "<Email Address_3>" || renames["<Person_1>"] != "<Person_2>" {
+		t.Errorf("renames = %v", renames)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	ctx := context.Background()
+	c := New(Words(256), 0.8)
+	stored, err := c.Store(ctx, "Can you resend the invoice for order 5531 to <Email Address_1>?",
+		"Sure — the invoice for order 5531 is on its way to <Email Address_1>.")
+	if err != nil || !stored {
+		t.Fatalf("stored = %v, err = %v", stored, err)
+	}
+
+	// Same question, another customer, different token numbers
+	got, ok, err := c.Lookup(ctx, "Can you resend the invoice for order 5531 to <Email Address_3>?")
+	if err != nil || !ok || got != "Sure — the invoice for order 5531 is on its way to <Email Address_3>." {
+		t.Errorf("exact: %q %v %v", got, ok, err)
+	}
+	// Reworded
+	if got, ok, _ := c.Lookup(ctx, "Please can you resend the invoice for order 5531 to <Email Address_2>"); !ok || got != "Sure — the invoice for order 5531 is on its way to <Email Address_2>." {
+		t.Errorf("similar: %q %v", got, ok)
+	}
+	// Another order: the answer repeats 5531, so it doesn't fit
+	if _, ok, _ := c.Lookup(ctx, "Can you resend the invoice for order 6602 to <Email Address_1>?"); ok {
+		t.Error("answered with another order's template")
+	}
+	// No email in the request: the template's token has nothing to map to
+	if _, ok, _ := c.Lookup(ctx, "Can you resend the invoice for order 5531?"); ok {
+		t.Error("answered with a token the request doesn't have")
+	}
+	if s := c.Stats(); s.Hits != 2 || s.Exact != 1 || s.Misses != 2 || s.Entries != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestStoreRefusesForeignTokens(t *testing.T) {
+	c := New(Words(64), 0.9)
+	if ok, _ := c.Store(context.Background(), "Who is <Person_1>?", "<Person_1> works with <Person_2>."); ok {
+		t.Error("stored an answer with a token the prompt doesn't hold")
+	}
+}
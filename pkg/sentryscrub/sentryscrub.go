@@ -0,0 +1,290 @@
+// Package sentryscrub redacts PII from Sentry events and breadcrumbs
+// before the sentry-go SDK sends them.
+//
+// Error reports pick up whatever was in flight: an email address in an
+// error message, a request body with a card number, a user object
+// attached as extra data. sentry-go's SendDefaultPII only covers what the
+// SDK collects itself. A Scrubber runs detection over the text an
+// application puts into an event and replaces each entity with its label
+// ("[Email Address]"), as pkg/redact does:
+//
+//	s := sentryscrub.NewLocal()
+//	sentry.Init(s.Options(sentry.ClientOptions{Dsn: dsn}))
+//
+// It scrubs the message, exception values, stack frame variables and
+// source lines, extra data, contexts, tags, the user, the request (URL,
+// query string, body, cookies and headers), and breadcrumb messages and
+// data. Maps and slices are walked, and other values in extra data and
+// contexts are turned into their JSON form first, as Sentry would send
+// them; so are JSON request bodies. A whole event is redacted with one
+// Detect call.
+//
+// Local mode is the default, since the hooks run on the path of every
+// error report and a report shouldn't wait on the network. If detection
+// fails, the event or breadcrumb is dropped rather than sent as it is.
+package sentryscrub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/getsentry/sentry-go"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/redact"
+)
+
+// Scrubber redacts Sentry events and breadcrumbs.
+type Scrubber struct {
+	r *redact.Redactor
+
+	// OnError, if set, is called when an event or breadcrumb is dropped
+	// because detection failed.
+	OnError func(error)
+}
+
+// New returns a Scrubber that detects with d, for example a
+// policy-wrapped local client so a policy's patterns and allowlist apply.
+func New(d redact.Detector) *Scrubber {
+	return &Scrubber{r: redact.New(d)}
+}
+
+// NewLocal returns a Scrubber with a local-mode client built from opts.
+func NewLocal(opts ...blindfold.Option) *Scrubber {
+	return New(blindfold.New(append(opts, blindfold.WithMode("local"))...))
+}
+
+// Options returns o with the Scrubber's hooks installed. Hooks already
+// in o run first, and the Scrubber sees what they return.
+func (s *Scrubber) Options(o sentry.ClientOptions) sentry.ClientOptions {
+	send, crumb := o.BeforeSend, o.BeforeBreadcrumb
+	o.BeforeSend = func(e *sentry.Event, h *sentry.EventHint) *sentry.Event {
+		if send != nil {
+			if e = send(e, h); e == nil {
+				return nil
+			}
+		}
+		return s.BeforeSend(e, h)
+	}
+	o.BeforeBreadcrumb = func(b *sentry.Breadcrumb, h *sentry.BreadcrumbHint) *sentry.Breadcrumb {
+		if crumb != nil {
+			if b = crumb(b, h); b == nil {
+				return nil
+			}
+		}
+		return s.BeforeBreadcrumb(b, h)
+	}
+	return o
+}
+
+// BeforeSend is a sentry.ClientOptions.BeforeSend hook. It returns nil,
+// dropping the event, if detection fails.
+func (s *Scrubber) BeforeSend(e *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+	if err := s.Event(context.Background(), e); err != nil {
+		s.failed(err)
+		return nil
+	}
+	return e
+}
+
+// BeforeBreadcrumb is a sentry.ClientOptions.BeforeBreadcrumb hook. It
+// returns nil, dropping the breadcrumb, if detection fails.
+func (s *Scrubber) BeforeBreadcrumb(b *sentry.Breadcrumb, _ *sentry.BreadcrumbHint) *sentry.Breadcrumb {
+	var t targets
+	t.breadcrumb(b)
+	if err := t.redact(context.Background(), s.r); err != nil {
+		s.failed(err)
+		return nil
+	}
+	return b
+}
+
+// Event redacts e in place. Breadcrumbs already on the event are scrubbed
+// too, for programs that install BeforeSend alone.
+func (s *Scrubber) Event(ctx context.Context, e *sentry.Event) error {
+	var t targets
+	t.str(&e.Message)
+	for i := range e.Exception {
+		x := &e.Exception[i]
+		t.str(&x.Value)
+		t.frames(x.Stacktrace)
+	}
+	for i := range e.Threads {
+		t.frames(e.Threads[i].Stacktrace)
+	}
+	t.anyMap(e.Extra)
+	for _, c := range e.Contexts {
+		t.anyMap(c)
+	}
+	t.strMap(e.Tags)
+	t.str(&e.User.Email)
+	t.str(&e.User.IPAddress)
+	t.str(&e.User.Username)
+	t.str(&e.User.Name)
+	t.strMap(e.User.Data)
+	if r := e.Request; r != nil {
+		t.str(&r.URL)
+		t.str(&r.QueryString)
+		t.body(&r.Data)
+		t.str(&r.Cookies)
+		t.strMap(r.Headers)
+	}
+	for _, b := range e.Breadcrumbs {
+		t.breadcrumb(b)
+	}
+	return t.redact(ctx, s.r)
+}
+
+func (s *Scrubber) failed(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+// targets collects the strings of an event along with how to write each
+// one back, so they can be redacted with a single call.
+type targets struct {
+	texts []string
+	set   []func(string)
+	after []func() // run once every string is written back
+}
+
+func (t *targets) add(v string, set func(string)) {
+	if v == "" {
+		return
+	}
+	t.texts = append(t.texts, v)
+	t.set = append(t.set, set)
+}
+
+func (t *targets) str(p *string) {
+	t.add(*p, func(v string) { *p = v })
+}
+
+func (t *targets) strMap(m map[string]string) {
+	for k, v := range m {
+		k := k
+		t.add(v, func(v string) { m[k] = v })
+	}
+}
+
+func (t *targets) anyMap(m map[string]any) {
+	for k, v := range m {
+		k := k
+		t.value(v, func(v any) { m[k] = v })
+	}
+}
+
+// value collects the strings in v, a value from extra data or a context.
+// set replaces v when it must change shape: a struct becomes its JSON
+// form. Numbers and booleans are left as they are.
+func (t *targets) value(v any, set func(any)) {
+	switch v := v.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+	case string:
+		t.add(v, func(s string) { set(s) })
+	case []byte:
+		t.add(string(v), func(s string) { set(s) })
+	case error:
+		t.add(v.Error(), func(s string) { set(s) })
+	case fmt.Stringer:
+		t.add(v.String(), func(s string) { set(s) })
+	case map[string]any:
+		t.anyMap(v)
+	case map[string]string:
+		t.strMap(v)
+	case []any:
+		for i := range v {
+			i := i
+			t.value(v[i], func(x any) { v[i] = x })
+		}
+	case []string:
+		for i := range v {
+			t.str(&v[i])
+		}
+	default:
+		// Sentry sends it as JSON; scrub that form
+		data, err := json.Marshal(v)
+		if err != nil {
+			set(fmt.Sprintf("[unserializable %T]", v))
+			return
+		}
+		var decoded any
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			set(fmt.Sprintf("[unserializable %T]", v))
+			return
+		}
+		set(decoded)
+		t.value(decoded, set)
+	}
+}
+
+// body collects a request body. A JSON body is walked as values, so
+// escapes like \n don't run into the text around them; it is sent back
+// compacted.
+func (t *targets) body(p *string) {
+	var v any
+	if d := json.NewDecoder(strings.NewReader(*p)); d.Decode(&v) != nil || d.More() {
+		t.str(p)
+		return
+	}
+	switch v.(type) {
+	case map[string]any, []any:
+	default:
+		t.str(p)
+		return
+	}
+	t.value(v, func(x any) { v = x })
+	t.after = append(t.after, func() {
+		var b strings.Builder
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		if enc.Encode(v) == nil {
+			*p = strings.TrimSuffix(b.String(), "\n")
+		}
+	})
+}
+
+func (t *targets) frames(st *sentry.Stacktrace) {
+	if st == nil {
+		return
+	}
+	for i := range st.Frames {
+		f := &st.Frames[i]
+		t.anyMap(f.Vars)
+		// Source lines added by the ContextifyFrames integration
+		t.str(&f.ContextLine)
+		for j := range f.PreContext {
+			t.str(&f.PreContext[j])
+		}
+		for j := range f.PostContext {
+			t.str(&f.PostContext[j])
+		}
+	}
+}
+
+func (t *targets) breadcrumb(b *sentry.Breadcrumb) {
+	t.str(&b.Message)
+	t.anyMap(b.Data)
+}
+
+// redact redacts every collected string and writes the results back.
+// Nothing is written if detection fails.
+func (t *targets) redact(ctx context.Context, r *redact.Redactor) error {
+	if len(t.texts) == 0 {
+		return nil
+	}
+	out, err := r.RedactEach(ctx, t.texts)
+	if err != nil {
+		return fmt.Errorf("sentryscrub: %w", err)
+	}
+	for i, text := range out {
+		t.set[i](text.String())
+	}
+	for _, fn := range t.after {
+		fn()
+	}
+	return nil
+}
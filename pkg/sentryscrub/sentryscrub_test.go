@@ -0,0 +1,159 @@
+package sentryscrub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	"github.com/getsentry/sentry-go"
+)
+
+type customer struct {
+	ID    int    `json:"id"`
+	Email string `json:"email"`
+}
+
+func sampleEvent() *sentry.Event {
+	e := sentry.NewEvent()
+	e.Message = "checkout failed for jane.doe@example.com"
+	e.Exception = []sentry.Exception{{
+		Type:  "*errors.errorString",
+		Value: "charge 4111 1111 1111 1111: declined",
+		Stacktrace: &sentry.Stacktrace{Frames: []sentry.Frame{{
+			Function: "charge",
+			Vars:     map[string]any{"phone": "415-555-0134", "attempt": 2},
+		}}},
+	}}
+	e.Extra = map[string]any{
+		"customer": customer{ID: 8812, Email: "jane.doe@example.com"},
+		"notes":    []any{"call 415-555-0134", 3.5},
+		"cause":    errors.New("no account for bob@example.org"),
+	}
+	e.Contexts = map[string]sentry.Context{"billing": {"ssn": "123-45-6789"}}
+	e.Tags = map[string]string{"customer_email": "jane.doe@example.com", "region": "eu"}
+	e.User = sentry.User{ID: "u-1", Email: "jane.doe@example.com", IPAddress: "203.0.113.42"}
+	e.Request = &sentry.Request{
+		URL:         "https://shop.example.com/orders?email=jane.doe@example.com",
+		QueryString: "email=jane.doe@example.com",
+		Data:        `{"card":"4111 1111 1111 1111","note":"ship to\nbob@example.org"}`,
+		Headers:     map[string]string{"X-Customer": "bob@example.org"},
+	}
+	e.Breadcrumbs = []*sentry.Breadcrumb{{Message: "login jane.doe@example.com", Data: map[string]any{"ip": "203.0.113.42"}}}
+	return e
+}
+
+func TestEvent(t *testing.T) {
+	e := sampleEvent()
+	if err := NewLocal().Event(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, v := range []string{"jane.doe@example.com", "bob@example.org", "4111 1111 1111 1111", "415-555-0134", "123-45-6789", "203.0.113.42"} {
+		if strings.Contains(out, v) {
+			t.Errorf("%q left in the event: %s", v, out)
+		}
+	}
+	for _, want := range []string{"[Email Address]", "[Credit Card Number]", `"attempt":2`, `"id":8812`, `"region":"eu"`, `"id":"u-1"`, "3.5"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("event lacks %s: %s", want, out)
+		}
+	}
+	if e.Message != "checkout failed for [Email Address]" {
+		t.Errorf("Message = %q", e.Message)
+	}
+	if got := e.Request.Data; got != `{"card":"[Credit Card Number]","note":"ship to\n[Email Address]"}` {
+		t.Errorf("Request.Data = %q", got)
+	}
+}
+
+type failing struct{}
+
+func (failing) Detect(context.Context, string, ...blindfold.CallOption) (*blindfold.DetectResponse, error) {
+	return nil, errors.New("detector down")
+}
+
+func TestFailClosed(t *testing.T) {
+	var got []error
+	s := New(failing{})
+	s.OnError = func(err error) { got = append(got, err) }
+	if e := s.BeforeSend(sampleEvent(), nil); e != nil {
+		t.Error("BeforeSend sent an event it couldn't scrub")
+	}
+	if b := s.BeforeBreadcrumb(&sentry.Breadcrumb{Message: "login jane.doe@example.com"}, nil); b != nil {
+		t.Error("BeforeBreadcrumb kept a breadcrumb it couldn't scrub")
+	}
+	if len(got) != 2 {
+		t.Errorf("OnError called %d times, want 2", len(got))
+	}
+	// Nothing to scrub needs no detection
+	if b := s.BeforeBreadcrumb(&sentry.Breadcrumb{Category: "http"}, nil); b == nil {
+		t.Error("empty breadcrumb dropped")
+	}
+}
+
+func TestOptionsChains(t *testing.T) {
+	var order []string
+	o := NewLocal().Options(sentry.ClientOptions{
+		BeforeSend: func(e *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+			order = append(order, "app")
+			e.Message += " (user jane.doe@example.com)"
+			return e
+		},
+		BeforeBreadcrumb: func(b *sentry.Breadcrumb, _ *sentry.BreadcrumbHint) *sentry.Breadcrumb {
+			if b.Category == "noise" {
+				return nil
+			}
+			return b
+		},
+	})
+	e := o.BeforeSend(&sentry.Event{Message: "boom"}, nil)
+	if len(order) != 1 || e.Message != "boom (user [Email Address])" {
+		t.Errorf("BeforeSend: order %v, message %q", order, e.Message)
+	}
+	if b := o.BeforeBreadcrumb(&sentry.Breadcrumb{Category: "noise", Message: "x"}, nil); b != nil {
+		t.Error("app hook's drop was overridden")
+	}
+	b := o.BeforeBreadcrumb(&sentry.Breadcrumb{Message: "GET /users?email=bob@example.org"}, nil)
+	if b.Message != "GET /users?email=[Email Address]" {
+		t.Errorf("breadcrumb = %q", b.Message)
+	}
+}
+
+// TestClient runs the hooks inside a real sentry client with a transport
+// that records what would be sent.
+func TestClient(t *testing.T) {
+	// Source lines are sent too (ContextifyFrames), so the values are
+	// spelled out only where the test expects them redacted
+	card := "4111" + strings.Repeat(" 1111", 3)
+	tr := &recorder{}
+	client, err := sentry.NewClient(NewLocal().Options(sentry.ClientOptions{Transport: tr}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := sentry.NewHub(client, sentry.NewScope())
+	hub.AddBreadcrumb(&sentry.Breadcrumb{Message: "clicked pay, card 4111 1111 1111 1111"}, nil)
+	hub.CaptureException(errors.New("refund to jane.doe@example.com failed"))
+	if len(tr.events) != 1 {
+		t.Fatalf("%d events sent, want 1", len(tr.events))
+	}
+	data, _ := json.Marshal(tr.events[0])
+	if s := string(data); strings.Contains(s, "jane.doe@example.com") || strings.Contains(s, card) {
+		t.Errorf("PII sent: %s", s)
+	}
+}
+
+type recorder struct{ events []*sentry.Event }
+
+func (r *recorder) Configure(sentry.ClientOptions)        {}
+func (r *recorder) SendEvent(e *sentry.Event)             { r.events = append(r.events, e) }
+func (r *recorder) Flush(time.Duration) bool              { return true }
+func (r *recorder) FlushWithContext(context.Context) bool { return true }
+func (r *recorder) Close()                                {}
@@ -0,0 +1,187 @@
+// Package sqlfn implements the Blindfold SQL functions that warehouse
+// services expose (Snowflake external functions, BigQuery remote
+// functions): tokenize, tokenize_with_mapping and detokenize, and a
+// batch runner that calls one over many rows in parallel.
+//
+// Warehouses send rows in batches and expect one result per row, in
+// order. Run keeps that order, and fails the whole batch if any row
+// fails, so a warehouse never gets a row back untokenized:
+//
+//	fn := sqlfn.Funcs["tokenize"]
+//	results, err := sqlfn.Run(ctx, bf, fn, rows, 8)
+//
+// Each service only translates its warehouse's wire format to and from
+// rows of arguments.
+package sqlfn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// ErrBadArgs is returned, wrapped, for a row whose arguments the function
+// can't take. It is the caller's fault, not a reason to retry.
+var ErrBadArgs = errors.New("sqlfn: bad arguments")
+
+// Func is a SQL function over one row. A nil argument is SQL NULL, and so
+// is a nil result.
+type Func struct {
+	Name string
+	Args int
+	// Tokenizes is set for functions whose results hold tokens.
+	Tokenizes bool
+	Call      func(ctx context.Context, bf bfclient.Client, args []*string) (any, error)
+}
+
+// Funcs are the functions by name.
+var Funcs = map[string]Func{
+	// tokenize(text) → tokenized text. The mapping is dropped, so the
+	// values can't be restored: use it to scrub.
+	"tokenize": {"tokenize", 1, true, func(ctx context.Context, bf bfclient.Client, args []*string) (any, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		res, err := bf.Tokenize(ctx, *args[0])
+		if err != nil {
+			return nil, err
+		}
+		return res.Text, nil
+	}},
+	// tokenize_with_mapping(text) → {"text": ..., "mapping": {...}}, for
+	// tables whose values must come back later. Store the mapping apart
+	// from the text, with tighter grants.
+	"tokenize_with_mapping": {"tokenize_with_mapping", 1, true, func(ctx context.Context, bf bfclient.Client, args []*string) (any, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		res, err := bf.Tokenize(ctx, *args[0])
+		if err != nil {
+			return nil, err
+		}
+		m := res.Mapping
+		if m == nil {
+			m = map[string]string{}
+		}
+		return map[string]any{"text": res.Text, "mapping": m}, nil
+	}},
+	// detokenize(text, mapping) → text with the values restored. mapping
+	// is the JSON object tokenize_with_mapping returned.
+	"detokenize": {"detokenize", 2, false, func(_ context.Context, _ bfclient.Client, args []*string) (any, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		var m map[string]string
+		if args[1] != nil {
+			if err := json.Unmarshal([]byte(*args[1]), &m); err != nil {
+				return nil, fmt.Errorf("%w: mapping is not a JSON object of strings", ErrBadArgs)
+			}
+		}
+		return mapping.Detokenize(*args[0], m), nil
+	}},
+}
+
+// Args converts a row of JSON values to arguments. Strings are taken as
+// they are, null is NULL, and anything else (a VARIANT, JSON or OBJECT
+// column) is passed on as its JSON text.
+func Args(row []json.RawMessage) []*string {
+	args := make([]*string, len(row))
+	for i, raw := range row {
+		if string(raw) == "null" {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			s = string(raw)
+		}
+		args[i] = &s
+	}
+	return args
+}
+
+// Run calls fn on every row, on up to workers goroutines, and returns the
+// results in row order. The first failing row cancels the rest, and Run
+// returns its error, wrapped with the row's index, and no results.
+func Run(ctx context.Context, bf bfclient.Client, fn Func, rows [][]*string, workers int) ([]any, error) {
+	for i, args := range rows {
+		if len(args) != fn.Args {
+			return nil, fmt.Errorf("row %d: %w: %s takes %d argument(s), got %d", i, ErrBadArgs, fn.Name, fn.Args, len(args))
+		}
+	}
+	out := make([]any, len(rows))
+	errs := make([]error, len(rows))
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if out[i], errs[i] = fn.Call(runCtx, bf, rows[i]); errs[i] != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+	for i := range rows {
+		if runCtx.Err() != nil {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// The row that failed, not the ones its cancellation stopped
+	for i, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+	}
+	return out, nil
+}
+
+// Entities summarizes the tokens in results by type, "Email Address:3
+// Person:2", for logs that must not hold values. A token repeated within
+// one result counts once.
+func Entities(results []any) string {
+	counts := make(map[string]int)
+	for _, r := range results {
+		text, _ := r.(string)
+		if m, ok := r.(map[string]any); ok {
+			text, _ = m["text"].(string)
+		}
+		seen := make(map[string]bool)
+		for _, token := range mapping.TokenPattern.FindAllString(text, -1) {
+			if !seen[token] {
+				seen[token] = true
+				typ, _, _ := mapping.ParseToken(token)
+				counts[typ]++
+			}
+		}
+	}
+	if len(counts) == 0 {
+		return "none"
+	}
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	parts := make([]string, len(types))
+	for i, t := range types {
+		parts[i] = fmt.Sprintf("%s:%d", t, counts[t])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,88 @@
+package sqlfn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+)
+
+var local = blindfold.New(blindfold.WithMode("local"))
+
+func str(s string) *string { return &s }
+
+func TestRunKeepsOrder(t *testing.T) {
+	rows := [][]*string{{str("mail jane.doe@example.com")}, {nil}, {str("no PII here")}, {str("call 415-555-0134")}}
+	got, err := Run(context.Background(), local, Funcs["tokenize"], rows, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []any{"mail <Email Address_1>", nil, "no PII here", "call <Phone Number_1>"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if e := Entities(got); e != "Email Address:1 Phone Number:1" {
+		t.Errorf("entities = %q", e)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	text := "Ticket from omar.haddad@example.com about 203.0.113.42"
+	res, err := Run(ctx, local, Funcs["tokenize_with_mapping"], [][]*string{{str(text)}}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := res[0].(map[string]any)
+	m, _ := json.Marshal(v["mapping"])
+	tokenized := v["text"].(string)
+	if strings.Contains(tokenized, "omar") {
+		t.Fatalf("not tokenized: %q", tokenized)
+	}
+	// The mapping comes back as a JSON column, as a warehouse sends it
+	row := Args([]json.RawMessage{json.RawMessage(`"` + tokenized + `"`), m})
+	back, err := Run(ctx, local, Funcs["detokenize"], [][]*string{row}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back[0] != text {
+		t.Errorf("detokenized = %q, want %q", back[0], text)
+	}
+}
+
+func TestBadArgs(t *testing.T) {
+	_, err := Run(context.Background(), local, Funcs["detokenize"], [][]*string{{str("x"), str("[1, 2]")}}, 1)
+	if !errors.Is(err, ErrBadArgs) || !strings.HasPrefix(err.Error(), "row 0:") {
+		t.Errorf("err = %v", err)
+	}
+	_, err = Run(context.Background(), local, Funcs["tokenize"], [][]*string{{str("a"), str("b")}}, 1)
+	if !errors.Is(err, ErrBadArgs) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// failing fails "bad" and holds every other row until it is cancelled.
+type failing struct{ bfclient.Client }
+
+func (failing) Tokenize(ctx context.Context, text string, _ ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	if text == "bad" {
+		return nil, errors.New("unavailable")
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestFailClosed(t *testing.T) {
+	rows := [][]*string{{str("slow")}, {str("slow")}, {str("bad")}, {str("slow")}}
+	got, err := Run(context.Background(), failing{}, Funcs["tokenize"], rows, 4)
+	if got != nil || err == nil || err.Error() != "row 2: unavailable" {
+		t.Fatalf("got %v, err = %v", got, err)
+	}
+}
@@ -0,0 +1,217 @@
+// Package stream protects data that is too large, or arrives too slowly,
+// to hold in memory at once: files of any size, pipes, network streams.
+//
+// A Reader wraps an io.Reader and yields its content tokenized, one chunk
+// at a time. Chunks end at line breaks where possible, so an entity is
+// only split if it spans lines (or a single line is longer than the chunk
+// size). Tokens are numbered across the whole stream: a value keeps its
+// token from the first chunk it appears in to the last.
+//
+//	r := stream.NewReader(ctx, bf, file)
+//	if _, err := io.Copy(out, r); err != nil {
+//		return err
+//	}
+//	save(r.Mapping())
+//
+// NewWriter goes the other way, restoring tokens in whatever is written to
+// it.
+package stream
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/streamdetok"
+)
+
+// DefaultChunkSize is the chunk size of NewReader: large enough to keep
+// Tokenize calls few, small enough to stay well under API request limits.
+const DefaultChunkSize = 64 << 10
+
+// Reader yields the tokenized content of an underlying reader.
+type Reader struct {
+	ctx  context.Context
+	bf   bfclient.Client
+	src  io.Reader
+	size int
+
+	raw    []byte // read from src, not yet tokenized
+	srcErr error  // sticky error from src; io.EOF at the end
+	out    []byte // tokenized, not yet returned
+	err    error  // sticky error returned once out is drained
+
+	tokens *accumulator
+	counts map[string]int
+}
+
+// NewReader returns a Reader tokenizing r in chunks of DefaultChunkSize.
+func NewReader(ctx context.Context, bf bfclient.Client, r io.Reader) *Reader {
+	return NewReaderSize(ctx, bf, r, DefaultChunkSize)
+}
+
+// NewReaderSize returns a Reader tokenizing r in chunks of at most size
+// bytes.
+func NewReaderSize(ctx context.Context, bf bfclient.Client, r io.Reader, size int) *Reader {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	return &Reader{ctx: ctx, bf: bf, src: r, size: size, tokens: newAccumulator(), counts: make(map[string]int)}
+}
+
+// Read implements io.Reader. A Tokenize failure is returned as the error,
+// with nothing of the failed chunk read; the Reader is unusable after it.
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.next()
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// Mapping returns the mapping for everything read so far. It is complete
+// once Read has returned io.EOF. The map is shared; don't modify it.
+func (r *Reader) Mapping() map[string]string { return r.tokens.mapping }
+
+// Counts returns the number of entities detected so far, by entity type.
+func (r *Reader) Counts() map[string]int { return r.counts }
+
+// next tokenizes the next chunk into out. It returns io.EOF after the last
+// chunk, or the error that stopped it.
+func (r *Reader) next() error {
+	for len(r.raw) < r.size && r.srcErr == nil {
+		buf := make([]byte, r.size-len(r.raw))
+		n, err := r.src.Read(buf)
+		r.raw = append(r.raw, buf[:n]...)
+		r.srcErr = err
+	}
+	if len(r.raw) == 0 {
+		return r.srcErr
+	}
+	cut := len(r.raw)
+	if r.srcErr == nil {
+		cut = safeCut(r.raw)
+	}
+	chunk := string(r.raw[:cut])
+	res, err := r.bf.Tokenize(r.ctx, chunk)
+	if err != nil {
+		return err
+	}
+	r.raw = append(r.raw[:0], r.raw[cut:]...)
+	r.out = []byte(r.tokens.add(res.Text, res.Mapping))
+	for _, e := range res.DetectedEntities {
+		r.counts[e.Type]++
+	}
+	if r.srcErr != nil && r.srcErr != io.EOF && len(r.raw) == 0 {
+		return r.srcErr
+	}
+	return nil
+}
+
+// safeCut returns where to end a full chunk: after its last line break,
+// else after its last space or tab, else at the last rune boundary.
+func safeCut(b []byte) int {
+	if i := bytes.LastIndexByte(b, '\n'); i >= 0 {
+		return i + 1
+	}
+	if i := bytes.LastIndexAny(b, " \t"); i >= 0 {
+		return i + 1
+	}
+	// Back up over a rune cut short: at most UTFMax-1 bytes
+	for cut := len(b); cut > 0 && cut > len(b)-utf8.UTFMax; cut-- {
+		if utf8.RuneStart(b[cut-1]) {
+			if utf8.FullRune(b[cut-1:]) {
+				return len(b)
+			}
+			if cut > 1 {
+				return cut - 1
+			}
+			break
+		}
+	}
+	return len(b)
+}
+
+// NewWriter returns a writer that restores m's tokens in everything
+// written to it before passing it on to w, however the writes split the
+// tokens. Call Close at the end to flush what it holds back.
+func NewWriter(w io.Writer, m map[string]string) *streamdetok.Writer {
+	return streamdetok.NewWriter(w, m)
+}
+
+// accumulator merges the per-chunk mappings of one stream as they come,
+// with the same rules as mapping.Merge: a known value reuses its token,
+// and a token already bound to another value is renumbered.
+type accumulator struct {
+	mapping map[string]string
+	byValue map[string]string // entity type + value → token
+	highest map[string]int    // entity type → highest number in use
+}
+
+func newAccumulator() *accumulator {
+	return &accumulator{mapping: map[string]string{}, byValue: map[string]string{}, highest: map[string]int{}}
+}
+
+// add merges m, the mapping of one tokenized chunk, and returns text with
+// the chunk's tokens renamed to the stream's. Unlike calling
+// mapping.Merge per chunk, the cost depends only on the chunk.
+func (a *accumulator) add(text string, m map[string]string) string {
+	tokens := make([]string, 0, len(m))
+	for t := range m {
+		tokens = append(tokens, t)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		ti, ni, _ := mapping.ParseToken(tokens[i])
+		tj, nj, _ := mapping.ParseToken(tokens[j])
+		if ti != tj {
+			return ti < tj
+		}
+		if ni != nj {
+			return ni < nj
+		}
+		return tokens[i] < tokens[j]
+	})
+
+	renames := make(map[string]string)
+	for _, token := range tokens {
+		value := m[token]
+		typ, n, parsed := mapping.ParseToken(token)
+		key := typ + "\x00" + value
+		if existing, ok := a.byValue[key]; ok {
+			if existing != token {
+				renames[token] = existing
+			}
+			continue
+		}
+		target := token
+		if _, taken := a.mapping[token]; taken {
+			if !parsed {
+				continue // no numbering to move it to; the first value stands, as in mapping.Merge
+			}
+			a.highest[typ]++
+			target = mapping.FormatToken(typ, a.highest[typ])
+			renames[token] = target
+		} else if parsed && n > a.highest[typ] {
+			a.highest[typ] = n
+		}
+		a.mapping[target] = value
+		a.byValue[key] = target
+	}
+	if len(renames) == 0 {
+		return text
+	}
+	return mapping.ReplaceTokens(text, func(token string) string {
+		if r, ok := renames[token]; ok {
+			return r
+		}
+		return token
+	})
+}
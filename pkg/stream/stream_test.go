@@ -0,0 +1,118 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+func sample(lines int) string {
+	var b strings.Builder
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&b, "line %d: mail user%d@example.com, cc ops@example.com, call 415-555-%04d\n", i, i%7, i%3)
+	}
+	return b.String()
+}
+
+func tokenizeAll(t *testing.T, r io.Reader, size int) (string, *Reader) {
+	t.Helper()
+	bf := blindfold.New(blindfold.WithMode("local"))
+	sr := NewReaderSize(context.Background(), bf, r, size)
+	out, err := io.ReadAll(sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), sr
+}
+
+func TestReaderRoundTrip(t *testing.T) {
+	text := sample(40)
+	for _, size := range []int{100, 257, 4096} {
+		got, r := tokenizeAll(t, iotest.OneByteReader(strings.NewReader(text)), size)
+		if back := mapping.Detokenize(got, r.Mapping()); back != text {
+			t.Errorf("size %d: round trip differs:\n got %.200q\nwant %.200q", size, back, text)
+		}
+		// 7 distinct users, ops@ and 3 phone numbers
+		if n := len(r.Mapping()); n != 11 {
+			t.Errorf("size %d: %d tokens, want 11", size, n)
+		}
+	}
+}
+
+func TestReaderOneTokenPerValue(t *testing.T) {
+	got, r := tokenizeAll(t, strings.NewReader(sample(30)), 90)
+	if strings.Contains(got, "@example.com") {
+		t.Errorf("output still holds an address: %.200q", got)
+	}
+	seen := make(map[string]string)
+	for token, value := range r.Mapping() {
+		if prev, ok := seen[value]; ok {
+			t.Errorf("%q has two tokens: %s and %s", value, prev, token)
+		}
+		seen[value] = token
+	}
+	if n := r.Counts()["Email Address"]; n != 60 {
+		t.Errorf("Counts()[Email Address] = %d, want 60", n)
+	}
+}
+
+func TestReaderLongLines(t *testing.T) {
+	// No line breaks at all: chunks end at spaces instead
+	text := strings.Repeat("reach jane.doe@example.com anytime ", 50)
+	got, r := tokenizeAll(t, strings.NewReader(text), 64)
+	if want := strings.Repeat("reach <Email Address_1> anytime ", 50); got != want {
+		t.Errorf("got %.120q, want %.120q", got, want)
+	}
+	if len(r.Mapping()) != 1 {
+		t.Errorf("mapping = %v, want one token", r.Mapping())
+	}
+}
+
+func TestReaderTokenizeError(t *testing.T) {
+	bf := blindfold.New(blindfold.WithAPIKey("x"), blindfold.WithBaseURL("http://127.0.0.1:1"), blindfold.WithMaxRetries(0))
+	r := NewReader(context.Background(), bf, strings.NewReader("mail jane.doe@example.com\n"))
+	out, err := io.ReadAll(r)
+	if err == nil || len(out) != 0 {
+		t.Errorf("ReadAll = %q, %v; want no output and an error", out, err)
+	}
+}
+
+func TestSafeCut(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want int
+	}{
+		{"ab\ncd ef", 3},
+		{"ab cd", 3},
+		{"abcd", 4},
+		{"abc\xe2\x98", 3}, // a cut-short ☃
+		{"abc☃", 6},
+	} {
+		if got := safeCut([]byte(c.in)); got != c.want {
+			t.Errorf("safeCut(%q) = %d, want %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestWriterRestores(t *testing.T) {
+	var out strings.Builder
+	w := NewWriter(&out, map[string]string{"<Email Address_1>": "jane.doe@example.com"})
+	for _, part := range []string{"write to <Email", " Address_1", "> today"} {
+		if _, err := io.WriteString(w, part); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "write to jane.doe@example.com today"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
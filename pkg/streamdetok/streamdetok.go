@@ -0,0 +1,157 @@
+// Package streamdetok restores Blindfold tokens in text that arrives in
+// chunks: SSE deltas, WebSocket frames, a CLI reading a streamed
+// response. A token can be split across any chunk boundary
+// ("<Email Addr" + "ess_1>"), so a Detokenizer holds back the end of the
+// input only while it could still grow into a mapping key, and emits
+// everything before it.
+//
+// The output is the same as mapping.Detokenize over the whole text, for
+// any way of cutting it into chunks:
+//
+//	d := streamdetok.New(res.Mapping)
+//	for chunk := range chunks {
+//		fmt.Print(d.Push(chunk))
+//	}
+//	fmt.Print(d.Flush())
+//
+// Writer adapts a Detokenizer to an io.Writer or a callback.
+package streamdetok
+
+import (
+	"io"
+	"strings"
+)
+
+// Detokenizer restores mapping keys in chunked text. It is not safe for
+// concurrent use; use one per stream.
+type Detokenizer struct {
+	mapping  map[string]string
+	prefixes map[string]bool // proper prefixes of keys
+	first    [256]bool       // first bytes of keys
+	maxLen   int
+	pending  string
+}
+
+// New returns a Detokenizer for mapping (token → original value). Keys
+// need not be Blindfold tokens; empty keys are ignored.
+func New(mapping map[string]string) *Detokenizer {
+	d := &Detokenizer{mapping: mapping, prefixes: make(map[string]bool)}
+	for k := range mapping {
+		if k == "" {
+			continue
+		}
+		d.first[k[0]] = true
+		if len(k) > d.maxLen {
+			d.maxLen = len(k)
+		}
+		for i := 1; i < len(k); i++ {
+			d.prefixes[k[:i]] = true
+		}
+	}
+	return d
+}
+
+// Push adds chunk and returns the restored text that is final. Text is
+// held back from the first position where the rest of the input is a
+// proper prefix of a key, so output lags input by less than the longest
+// key.
+func (d *Detokenizer) Push(chunk string) string {
+	out, rest := d.restore(d.pending+chunk, false)
+	d.pending = rest
+	return out
+}
+
+// Flush returns whatever is still held back, restored, and resets the
+// Detokenizer for reuse with the same mapping.
+func (d *Detokenizer) Flush() string {
+	out, _ := d.restore(d.pending, true)
+	d.pending = ""
+	return out
+}
+
+// Pending returns the number of bytes held back.
+func (d *Detokenizer) Pending() int { return len(d.pending) }
+
+// restore replaces keys in buf left to right, the longest key winning at
+// each position. Unless final, it stops where the rest of buf could still
+// become a longer match and returns that part unprocessed.
+func (d *Detokenizer) restore(buf string, final bool) (string, string) {
+	if d.maxLen == 0 {
+		return buf, ""
+	}
+	var b strings.Builder
+	b.Grow(len(buf))
+	last := 0 // start of the literal run not yet written
+	for i := 0; i < len(buf); {
+		if !d.first[buf[i]] {
+			i++
+			continue
+		}
+		if !final && len(buf)-i < d.maxLen && d.prefixes[buf[i:]] {
+			b.WriteString(buf[last:i])
+			return b.String(), buf[i:]
+		}
+		n := len(buf) - i
+		if n > d.maxLen {
+			n = d.maxLen
+		}
+		matched := false
+		for l := n; l > 0; l-- {
+			if v, ok := d.mapping[buf[i:i+l]]; ok {
+				b.WriteString(buf[last:i])
+				b.WriteString(v)
+				i += l
+				last = i
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			i++
+		}
+	}
+	b.WriteString(buf[last:])
+	return b.String(), ""
+}
+
+// Writer restores tokens in bytes written to it and passes the result on.
+type Writer struct {
+	d    *Detokenizer
+	emit func(string) error
+}
+
+// NewWriter returns a Writer that writes restored text to w.
+func NewWriter(w io.Writer, mapping map[string]string) *Writer {
+	return NewFunc(mapping, func(s string) error {
+		_, err := io.WriteString(w, s)
+		return err
+	})
+}
+
+// NewFunc returns a Writer that calls fn with each piece of restored
+// text. fn is not called with empty text.
+func NewFunc(mapping map[string]string, fn func(string) error) *Writer {
+	return &Writer{d: New(mapping), emit: fn}
+}
+
+// Write restores what it can of p and passes it on. It reports len(p)
+// written unless passing on fails; held-back bytes count as written.
+func (w *Writer) Write(p []byte) (int, error) {
+	if out := w.d.Push(string(p)); out != "" {
+		if err := w.emit(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush passes on whatever is held back. Call it at the end of the stream.
+func (w *Writer) Flush() error {
+	if out := w.d.Flush(); out != "" {
+		return w.emit(out)
+	}
+	return nil
+}
+
+// Close flushes the Writer. It does not close the underlying writer.
+func (w *Writer) Close() error { return w.Flush() }
@@ -0,0 +1,96 @@
+package streamdetok
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+var testMapping = map[string]string{
+	"<Email Address_1>": "jane@example.com",
+	"<Person_1>":        "Jane Doe",
+	"<Person_12>":       "John Roe",
+	"Marcus Webb":       "John Smith", // a pseudonym, not token-shaped
+	"Marcus":            "John",
+}
+
+var testTexts = []string{
+	"",
+	"no tokens here",
+	"Hi <Person_1>, we emailed <Email Address_1>.",
+	"<Person_12> and <Person_1> and <Person_1",
+	"Marcus Webb met Marcus. Marcus W",
+	"<<Person_1>> <Email Address_> <Email Address_1>>",
+	"ünï <Person_1> ☃ <Email Address_1>",
+}
+
+// TestEverySplit checks every way of cutting each text in two, and in
+// single bytes, against detokenizing the whole text at once.
+func TestEverySplit(t *testing.T) {
+	for _, text := range testTexts {
+		want := mapping.Detokenize(text, testMapping)
+		for cut := 0; cut <= len(text); cut++ {
+			d := New(testMapping)
+			got := d.Push(text[:cut]) + d.Push(text[cut:]) + d.Flush()
+			if got != want {
+				t.Errorf("split %q|%q:\n got %q\nwant %q", text[:cut], text[cut:], got, want)
+			}
+		}
+		d := New(testMapping)
+		var b strings.Builder
+		for i := 0; i < len(text); i++ {
+			b.WriteString(d.Push(text[i : i+1]))
+		}
+		if got := b.String() + d.Flush(); got != want {
+			t.Errorf("byte by byte %q:\n got %q\nwant %q", text, got, want)
+		}
+	}
+}
+
+func TestRandomChunks(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	parts := []string{"<Person_1>", "<Person_12>", "<Email Address_1>", "Marcus", " Webb", "<", "_1", ">", " ", "x"}
+	for n := 0; n < 500; n++ {
+		var b strings.Builder
+		for i := rng.Intn(20); i > 0; i-- {
+			b.WriteString(parts[rng.Intn(len(parts))])
+		}
+		text := b.String()
+		want := mapping.Detokenize(text, testMapping)
+
+		var out strings.Builder
+		w := NewWriter(&out, testMapping)
+		for rest := text; rest != ""; {
+			k := 1 + rng.Intn(len(rest))
+			if _, err := w.Write([]byte(rest[:k])); err != nil {
+				t.Fatal(err)
+			}
+			rest = rest[k:]
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != want {
+			t.Fatalf("%q:\n got %q\nwant %q", text, out.String(), want)
+		}
+	}
+}
+
+func TestHoldsBackOnlyKeyPrefixes(t *testing.T) {
+	d := New(testMapping)
+	if got := d.Push("Hello <Ema"); got != "Hello " || d.Pending() != 4 {
+		t.Errorf("Push = %q with %d pending, want %q with 4", got, d.Pending(), "Hello ")
+	}
+	if got := d.Push("il Address_1> and <b>"); got != "jane@example.com and <b>" || d.Pending() != 0 {
+		t.Errorf("Push = %q with %d pending", got, d.Pending())
+	}
+	// A complete key that could still grow into a longer one waits
+	if got := d.Push("Marcus"); got != "" {
+		t.Errorf("Push = %q, want it held for Marcus Webb", got)
+	}
+	if got := d.Push("!"); got != "John!" {
+		t.Errorf("Push = %q, want %q", got, "John!")
+	}
+}
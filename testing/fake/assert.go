@@ -0,0 +1,48 @@
+package fake
+
+import (
+	"strings"
+	"testing"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+var leakScanner = blindfold.NewPIIScanner([]string{"us", "eu", "uk"})
+
+// AssertNoPIILeaked fails the test if prompt — typically what was sent to
+// the LLM — contains pattern-detectable PII or any of the known values.
+// Blindfold tokens in the prompt are ignored. Pass the names and other
+// NLP-only values your test uses as known, since patterns cannot find them.
+func AssertNoPIILeaked(t testing.TB, prompt string, known ...string) {
+	t.Helper()
+	stripped := mapping.ReplaceTokens(prompt, func(string) string { return " " })
+	for _, m := range leakScanner.Detect(stripped, nil) {
+		t.Errorf("PII leaked: %s %q in prompt %q", m.EntityType, m.Text, prompt)
+	}
+	for _, v := range known {
+		if v != "" && strings.Contains(stripped, v) {
+			t.Errorf("PII leaked: %q in prompt %q", v, prompt)
+		}
+	}
+}
+
+// AssertNoMappingLeaked is AssertNoPIILeaked with every value of m as known.
+func AssertNoMappingLeaked(t testing.TB, prompt string, m map[string]string) {
+	t.Helper()
+	known := make([]string, 0, len(m))
+	for _, v := range m {
+		known = append(known, v)
+	}
+	AssertNoPIILeaked(t, prompt, known...)
+}
+
+// AssertRestored fails the test if text still contains Blindfold tokens,
+// i.e. detokenization missed one (usually because the LLM altered it).
+func AssertRestored(t testing.TB, text string) {
+	t.Helper()
+	if tokens := mapping.TokenPattern.FindAllString(text, -1); len(tokens) > 0 {
+		t.Errorf("unrestored tokens %v in %q", tokens, text)
+	}
+}
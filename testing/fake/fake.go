@@ -0,0 +1,191 @@
+// Package fake provides an in-process bfclient.Client and assertion helpers
+// for hermetic unit tests of PII-protected pipelines.
+//
+// Code under test should accept a bfclient.Client rather than a concrete
+// *blindfold.Client; tests then hand it a fake:
+//
+//	bf := fake.New(fake.WithEntity("Person", "John Smith"))
+//	reply, err := protectedChat(ctx, bf, llm, "Email John Smith at john@acme.com")
+//	fake.AssertNoPIILeaked(t, llm.LastPrompt(), "John Smith")
+//
+// Unlike local mode, the fake can "detect" names and other NLP-only
+// entities: register the literal values a test uses with WithEntity.
+package fake
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/bfclient"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+)
+
+// ErrUnavailable is a convenient error for FailNext when the test only
+// cares that detection failed.
+var ErrUnavailable = errors.New("fake: blindfold unavailable")
+
+// Call records one method call on the fake.
+type Call struct {
+	Method string // "Detect", "Tokenize" or "Detokenize"
+	Text   string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithEntity makes the fake detect each of values as entityType.
+func WithEntity(entityType string, values ...string) Option {
+	return func(c *Client) {
+		for _, v := range values {
+			c.literals[v] = entityType
+		}
+	}
+}
+
+// WithPatterns also runs the SDK's local regex detectors for the given
+// locales (emails, phones, cards, ...), as local mode would.
+func WithPatterns(locales ...string) Option {
+	return func(c *Client) { c.scanner = blindfold.NewPIIScanner(locales) }
+}
+
+// Client is a deterministic in-process implementation of bfclient.Client.
+// Identical values always get the same token within one call. Call options
+// are accepted but ignored.
+type Client struct {
+	mu       sync.Mutex
+	literals map[string]string // value → entity type
+	scanner  *blindfold.PIIScanner
+	calls    []Call
+	failures []error
+}
+
+var _ bfclient.Client = (*Client)(nil)
+
+// New returns a fake that detects the registered literals and, with
+// WithPatterns, pattern-based entities.
+func New(opts ...Option) *Client {
+	c := &Client{literals: make(map[string]string)}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// FailNext makes the next Detect or Tokenize call return err. Calls queue
+// up: FailNext(a); FailNext(b) fails the next two calls in order.
+func (c *Client) FailNext(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures = append(c.failures, err)
+}
+
+// Calls returns every call made so far.
+func (c *Client) Calls() []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Call(nil), c.calls...)
+}
+
+func (c *Client) record(method, text string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, Call{Method: method, Text: text})
+	if method == "Detokenize" || len(c.failures) == 0 {
+		return nil
+	}
+	err := c.failures[0]
+	c.failures = c.failures[1:]
+	return err
+}
+
+// Detect returns the registered literals and pattern matches in text.
+func (c *Client) Detect(_ context.Context, text string, _ ...blindfold.CallOption) (*blindfold.DetectResponse, error) {
+	if err := c.record("Detect", text); err != nil {
+		return nil, err
+	}
+	found := c.find(text)
+	return &blindfold.DetectResponse{DetectedEntities: found, EntitiesCount: len(found)}, nil
+}
+
+// Tokenize replaces detected values with <Type_N> tokens.
+func (c *Client) Tokenize(_ context.Context, text string, _ ...blindfold.CallOption) (*blindfold.TokenizeResponse, error) {
+	if err := c.record("Tokenize", text); err != nil {
+		return nil, err
+	}
+	found := c.find(text)
+	m := make(map[string]string)
+	byValue := make(map[string]string)
+	counters := make(map[string]int)
+
+	var b strings.Builder
+	last := 0
+	for _, e := range found {
+		token, ok := byValue[e.Type+"\x00"+e.Text]
+		if !ok {
+			counters[e.Type]++
+			token = mapping.FormatToken(e.Type, counters[e.Type])
+			byValue[e.Type+"\x00"+e.Text] = token
+			m[token] = e.Text
+		}
+		b.WriteString(text[last:e.Start])
+		b.WriteString(token)
+		last = e.End
+	}
+	b.WriteString(text[last:])
+	return &blindfold.TokenizeResponse{Text: b.String(), Mapping: m, DetectedEntities: found, EntitiesCount: len(found)}, nil
+}
+
+// Detokenize restores tokens from mapping, like the SDK.
+func (c *Client) Detokenize(text string, m map[string]string) *blindfold.DetokenizeResponse {
+	_ = c.record("Detokenize", text)
+	return &blindfold.DetokenizeResponse{Text: mapping.Detokenize(text, m), ReplacementsMade: len(m)}
+}
+
+// find returns non-overlapping entities in reading order. Where candidates
+// overlap, the earliest wins, then the longest.
+func (c *Client) find(text string) []blindfold.DetectedEntity {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var cands []blindfold.DetectedEntity
+	for value, typ := range c.literals {
+		if value == "" {
+			continue
+		}
+		for off := 0; ; {
+			i := strings.Index(text[off:], value)
+			if i < 0 {
+				break
+			}
+			start := off + i
+			cands = append(cands, blindfold.DetectedEntity{Type: typ, Text: value, Start: start, End: start + len(value), Score: 1})
+			off = start + len(value)
+		}
+	}
+	if c.scanner != nil {
+		for _, m := range c.scanner.Detect(text, nil) {
+			cands = append(cands, blindfold.DetectedEntity{Type: m.EntityType, Text: m.Text, Start: m.Start, End: m.End, Score: m.Score})
+		}
+	}
+	sort.Slice(cands, func(i, j int) bool {
+		if cands[i].Start != cands[j].Start {
+			return cands[i].Start < cands[j].Start
+		}
+		return cands[i].End > cands[j].End
+	})
+
+	var out []blindfold.DetectedEntity
+	end := -1
+	for _, e := range cands {
+		if e.Start >= end {
+			out = append(out, e)
+			end = e.End
+		}
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// recorder catches the failures an assertion reports instead of failing
+// the test running it.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertNoPIILeaked(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		prompt string
+		known  []string
+		fails  bool
+	}{
+		{"clean", "Refund <Person_1> at <Email Address_1>.", []string{"Jane Doe"}, false},
+		{"pattern PII", "Refund <Person_1> at jane@example.com.", nil, true},
+		{"known value", "Refund Jane Doe at <Email Address_1>.", []string{"Jane Doe"}, true},
+		{"card number", "Card 4111 1111 1111 1111 was charged twice.", nil, true},
+		{"token text is not a value", "Ask <Person_1> about it.", []string{"Person"}, false},
+		{"empty known value", "Nothing to see.", []string{""}, false},
+	} {
+		r := &recorder{TB: t}
+		AssertNoPIILeaked(r, c.prompt, c.known...)
+		if failed := len(r.errors) > 0; failed != c.fails {
+			t.Errorf("%s: failed = %v, want %v (%v)", c.name, failed, c.fails, r.errors)
+		}
+	}
+}
+
+func TestAssertNoMappingLeaked(t *testing.T) {
+	m := map[string]string{"<Person_1>": "Jane Doe"}
+	r := &recorder{TB: t}
+	AssertNoMappingLeaked(r, "Hello <Person_1>", m)
+	if len(r.errors) != 0 {
+		t.Errorf("clean prompt failed: %v", r.errors)
+	}
+	AssertNoMappingLeaked(r, "Hello Jane Doe", m)
+	if len(r.errors) != 1 {
+		t.Errorf("leaked mapping value: %d failures, want 1", len(r.errors))
+	}
+}
+
+func TestAssertRestored(t *testing.T) {
+	r := &recorder{TB: t}
+	AssertRestored(r, "Hello Jane Doe")
+	if len(r.errors) != 0 {
+		t.Errorf("restored text failed: %v", r.errors)
+	}
+	AssertRestored(r, "Hello <Person_1>")
+	if len(r.errors) != 1 {
+		t.Errorf("unrestored token: %d failures, want 1", len(r.errors))
+	}
+}
+
+func TestFakeTokenize(t *testing.T) {
+	bf := New(WithEntity("Person", "John Smith"), WithPatterns("us"))
+	res, err := bf.Tokenize(context.Background(), "John Smith <john@acme.com>, again John Smith")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<Person_1> <<Email Address_1>>, again <Person_1>"; res.Text != want {
+		t.Errorf("text = %q, want %q", res.Text, want)
+	}
+	if res.Mapping["<Person_1>"] != "John Smith" || res.Mapping["<Email Address_1>"] != "john@acme.com" || len(res.Mapping) != 2 {
+		t.Errorf("mapping = %v", res.Mapping)
+	}
+	if got := bf.Detokenize(res.Text, res.Mapping).Text; got != "John Smith <john@acme.com>, again John Smith" {
+		t.Errorf("detokenized %q", got)
+	}
+
+	bf.FailNext(ErrUnavailable)
+	if _, err := bf.Detect(context.Background(), "x"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("FailNext: err = %v", err)
+	}
+	if _, err := bf.Detect(context.Background(), "x"); err != nil {
+		t.Errorf("after the failure: %v", err)
+	}
+	if n := len(bf.Calls()); n != 4 {
+		t.Errorf("%d calls recorded, want 4", n)
+	}
+}
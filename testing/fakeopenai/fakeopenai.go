@@ -0,0 +1,94 @@
+// Package fakeopenai is a scripted stand-in for the OpenAI chat
+// completions endpoint, for the -demo mode of the recipes and for tests:
+// the script gets each request and returns the reply, and the package
+// does the HTTP.
+//
+//	llm := fakeopenai.Server(func(req openai.ChatCompletionRequest) string {
+//		return "Noted, " + fakeopenai.Prompt(req)
+//	})
+//	defer llm.Close()
+//	oa := openai.NewClientWithConfig(fakeopenai.Config(llm))
+//
+// Like a real model, a script only knows what is in the request: a reply
+// can only use the tokens the recipe sent, which is what a demo of
+// tokenization should show.
+package fakeopenai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Script returns the content of the assistant's reply to req.
+type Script func(req openai.ChatCompletionRequest) string
+
+// MessageScript returns the assistant's whole reply to req, for scripts
+// that call tools.
+type MessageScript func(req openai.ChatCompletionRequest) openai.ChatCompletionMessage
+
+// Server starts a server answering chat completions with script. Close
+// it when done.
+func Server(script Script) *httptest.Server {
+	return httptest.NewServer(Handler(script))
+}
+
+// MessageServer is Server for a MessageScript.
+func MessageServer(script MessageScript) *httptest.Server {
+	return httptest.NewServer(MessageHandler(script))
+}
+
+// Handler answers chat completion requests with script, for a fake that
+// serves other endpoints as well: mount it on /v1/chat/completions.
+func Handler(script Script) http.Handler {
+	return MessageHandler(func(req openai.ChatCompletionRequest) openai.ChatCompletionMessage {
+		return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: script(req)}
+	})
+}
+
+// MessageHandler is Handler for a MessageScript. A reply with tool calls
+// finishes with reason tool_calls, any other with stop.
+func MessageHandler(script MessageScript) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) == 0 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		msg := script(req.ChatCompletionRequest)
+		finish := openai.FinishReasonStop
+		if len(msg.ToolCalls) > 0 {
+			finish = openai.FinishReasonToolCalls
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{ID: "chatcmpl-demo", Object: "chat.completion", Model: req.Model,
+			Choices: []openai.ChatCompletionChoice{{Message: msg, FinishReason: finish}}})
+	})
+}
+
+// request is a chat completion request as the fake decodes it. The SDK
+// can't decode its own JSON schema response format, which no script
+// needs, so it is skipped.
+type request struct {
+	openai.ChatCompletionRequest
+	ResponseFormat json.RawMessage `json:"response_format,omitempty"`
+}
+
+// Config returns a go-openai config for the API at srv.
+func Config(srv *httptest.Server) openai.ClientConfig {
+	cfg := openai.DefaultConfig("demo")
+	cfg.BaseURL = srv.URL + "/v1"
+	return cfg
+}
+
+// Prompt returns the content of the last user message in req.
+func Prompt(req openai.ChatCompletionRequest) string {
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == openai.ChatMessageRoleUser {
+			return req.Messages[i].Content
+		}
+	}
+	return ""
+}
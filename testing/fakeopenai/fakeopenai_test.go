@@ -0,0 +1,70 @@
+package fakeopenai
+
+import (
+	"context"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestServer(t *testing.T) {
+	var got openai.ChatCompletionRequest
+	srv := Server(func(req openai.ChatCompletionRequest) string {
+		got = req
+		return "Re: " + Prompt(req)
+	})
+	defer srv.Close()
+
+	res, err := openai.NewClientWithConfig(Config(srv)).CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model: "demo",
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "Keep placeholders."},
+			{Role: openai.ChatMessageRoleUser, Content: "Mail <Email Address_1>"},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Choices) != 1 || res.Choices[0].Message.Content != "Re: Mail <Email Address_1>" || res.Choices[0].FinishReason != openai.FinishReasonStop {
+		t.Errorf("response = %+v", res)
+	}
+	if got.Model != "demo" || len(got.Messages) != 2 {
+		t.Errorf("script got %+v", got)
+	}
+}
+
+func TestMessageServerToolCalls(t *testing.T) {
+	srv := MessageServer(func(openai.ChatCompletionRequest) openai.ChatCompletionMessage {
+		return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{{
+			ID: "call_1", Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "lookup", Arguments: `{"email":"<Email Address_1>"}`},
+		}}}
+	})
+	defer srv.Close()
+
+	res, err := openai.NewClientWithConfig(Config(srv)).CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model:    "demo",
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "Find <Email Address_1>"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := res.Choices[0]; c.FinishReason != openai.FinishReasonToolCalls || c.Message.ToolCalls[0].Function.Name != "lookup" {
+		t.Errorf("choice = %+v", c)
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	req := openai.ChatCompletionRequest{Messages: []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "first"},
+		{Role: openai.ChatMessageRoleAssistant, Content: "reply"},
+		{Role: openai.ChatMessageRoleUser, Content: "second"},
+		{Role: openai.ChatMessageRoleTool, Content: "result"},
+	}}
+	if got := Prompt(req); got != "second" {
+		t.Errorf("Prompt = %q", got)
+	}
+	if got := Prompt(openai.ChatCompletionRequest{}); got != "" {
+		t.Errorf("no messages: %q", got)
+	}
+}
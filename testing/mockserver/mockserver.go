@@ -0,0 +1,297 @@
+// Package mockserver emulates the Blindfold cloud API for integration tests
+// and CI pipelines that must not depend on real API keys or the network.
+//
+// Detection is deterministic: the server runs the SDK's local regex scanner,
+// so a given input always produces the same entities and tokens. Latency
+// and failures are configurable, including switching an outage on and off
+// while a test runs.
+//
+//	srv := mockserver.New(mockserver.WithLatency(20 * time.Millisecond))
+//	defer srv.Close()
+//	bf := srv.Client() // *blindfold.Client in cloud mode, pointed at srv
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+)
+
+// APIKey is the key the server accepts unless WithAPIKey sets another.
+const APIKey = "mock-api-key"
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithAPIKey sets the accepted X-API-Key. Requests with any other key get 401.
+func WithAPIKey(key string) Option {
+	return func(s *Server) { s.apiKey = key }
+}
+
+// WithLatency delays every response by d.
+func WithLatency(d time.Duration) Option {
+	return func(s *Server) { s.latency = d }
+}
+
+// WithLocales sets the locales of the scanner used for detection.
+func WithLocales(locales ...string) Option {
+	return func(s *Server) { s.scanner = blindfold.NewPIIScanner(locales) }
+}
+
+// WithFailFirst makes the first n requests fail with status.
+func WithFailFirst(n int, status int) Option {
+	return func(s *Server) { s.failFirst, s.failStatus = int64(n), status }
+}
+
+// WithFailEvery makes every nth request (n, 2n, ...) fail with status.
+func WithFailEvery(n int, status int) Option {
+	return func(s *Server) { s.failEvery, s.failStatus = int64(n), status }
+}
+
+// Server is a running mock of the Blindfold API.
+type Server struct {
+	*httptest.Server
+
+	apiKey     string
+	latency    time.Duration
+	scanner    *blindfold.PIIScanner
+	failFirst  int64
+	failEvery  int64
+	failStatus int
+
+	seen   atomic.Int64
+	outage atomic.Int64 // status code while an outage is on, else 0
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+// New starts a server on a random local port.
+func New(opts ...Option) *Server {
+	s := newServer(opts...)
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// NewUnstarted returns a server that is not listening yet, for callers
+// that want to choose the listener (see cmd/mockserver).
+func NewUnstarted(opts ...Option) *Server {
+	s := newServer(opts...)
+	s.Server = httptest.NewUnstartedServer(s)
+	return s
+}
+
+func newServer(opts ...Option) *Server {
+	s := &Server{
+		apiKey:     APIKey,
+		scanner:    blindfold.NewPIIScanner(nil),
+		failStatus: http.StatusServiceUnavailable,
+		requests:   make(map[string]int),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// Client returns a cloud-mode SDK client pointed at the server. SDK
+// retries are disabled so tests see failures as they are injected.
+func (s *Server) Client(opts ...blindfold.Option) *blindfold.Client {
+	base := []blindfold.Option{
+		blindfold.WithAPIKey(s.apiKey),
+		blindfold.WithBaseURL(s.URL),
+		blindfold.WithMaxRetries(0),
+	}
+	return blindfold.New(append(base, opts...)...)
+}
+
+// SetOutage makes every request fail with status until ClearOutage.
+func (s *Server) SetOutage(status int) { s.outage.Store(int64(status)) }
+
+// ClearOutage ends an outage started with SetOutage.
+func (s *Server) ClearOutage() { s.outage.Store(0) }
+
+// Requests returns how many requests each endpoint path has received,
+// including failed ones.
+func (s *Server) Requests() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]int, len(s.requests))
+	for k, v := range s.requests {
+		out[k] = v
+	}
+	return out
+}
+
+type request struct {
+	Text           string   `json:"text"`
+	Texts          []string `json:"texts"`
+	Entities       []string `json:"entities"`
+	ScoreThreshold *float64 `json:"score_threshold"`
+	CharsToShow    *int     `json:"chars_to_show"`
+	FromEnd        bool     `json:"from_end"`
+	MaskingChar    string   `json:"masking_char"`
+	HashType       string   `json:"hash_type"`
+	HashPrefix     string   `json:"hash_prefix"`
+	HashLength     int      `json:"hash_length"`
+}
+
+// ServeHTTP implements the API endpoints.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests[r.URL.Path]++
+	s.mu.Unlock()
+	n := s.seen.Add(1)
+
+	if s.latency > 0 {
+		select {
+		case <-time.After(s.latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if status := s.injectedFailure(n); status != 0 {
+		writeError(w, status, fmt.Sprintf("injected failure on request %d", n))
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "POST required")
+		return
+	}
+	if r.Header.Get("X-API-Key") != s.apiKey {
+		writeError(w, http.StatusUnauthorized, "Invalid API key")
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+
+	op, ok := s.operation(r.URL.Path, req)
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown endpoint "+r.URL.Path)
+		return
+	}
+	if req.Texts != nil {
+		results := make([]any, len(req.Texts))
+		for i, t := range req.Texts {
+			results[i] = op(t)
+		}
+		writeJSON(w, map[string]any{"results": results, "total": len(results), "succeeded": len(results), "failed": 0})
+		return
+	}
+	writeJSON(w, op(req.Text))
+}
+
+func (s *Server) injectedFailure(n int64) int {
+	if status := s.outage.Load(); status != 0 {
+		return int(status)
+	}
+	if n <= s.failFirst || (s.failEvery > 0 && n%s.failEvery == 0) {
+		return s.failStatus
+	}
+	return 0
+}
+
+// operation returns the handler for one text on the given endpoint.
+func (s *Server) operation(path string, req request) (func(text string) any, bool) {
+	detect := func(text string) []blindfold.PIIMatch {
+		matches := s.scanner.Detect(text, req.Entities)
+		if req.ScoreThreshold == nil {
+			return matches
+		}
+		var kept []blindfold.PIIMatch
+		for _, m := range matches {
+			if m.Score >= *req.ScoreThreshold {
+				kept = append(kept, m)
+			}
+		}
+		return kept
+	}
+	types := func(matches []blindfold.PIIMatch) []string {
+		seen := map[string]bool{}
+		out := []string{}
+		for _, m := range matches {
+			if !seen[m.EntityType] {
+				seen[m.EntityType] = true
+				out = append(out, m.EntityType)
+			}
+		}
+		if len(out) == 0 {
+			return []string{"__none__"}
+		}
+		return out
+	}
+
+	switch path {
+	case "/detect":
+		return func(text string) any {
+			m := detect(text)
+			return blindfold.DetectResponse{DetectedEntities: entities(m), EntitiesCount: len(m)}
+		}, true
+	case "/tokenize":
+		return func(text string) any {
+			res := s.scanner.Tokenize(text, types(detect(text)))
+			return blindfold.TokenizeResponse{Text: res.Text, Mapping: res.Mapping, DetectedEntities: entities(res.Matches), EntitiesCount: len(res.Matches)}
+		}, true
+	case "/redact":
+		return func(text string) any {
+			res := s.scanner.Redact(text, types(detect(text)))
+			return blindfold.RedactResponse{Text: res.Text, DetectedEntities: entities(res.Matches), EntitiesCount: len(res.Matches)}
+		}, true
+	case "/mask":
+		return func(text string) any {
+			show, char := 3, "*"
+			if req.CharsToShow != nil {
+				show = *req.CharsToShow
+			}
+			if req.MaskingChar != "" {
+				char = req.MaskingChar
+			}
+			res := s.scanner.Mask(text, show, req.FromEnd, char, types(detect(text)))
+			return blindfold.MaskResponse{Text: res.Text, DetectedEntities: entities(res.Matches), EntitiesCount: len(res.Matches)}
+		}, true
+	case "/hash":
+		return func(text string) any {
+			prefix, length := req.HashPrefix, req.HashLength
+			if length == 0 {
+				prefix, length = "HASH_", 16
+			}
+			res := s.scanner.Hash(text, req.HashType, prefix, length, types(detect(text)))
+			return blindfold.HashResponse{Text: res.Text, DetectedEntities: entities(res.Matches), EntitiesCount: len(res.Matches)}
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+func entities(matches []blindfold.PIIMatch) []blindfold.DetectedEntity {
+	out := make([]blindfold.DetectedEntity, len(matches))
+	for i, m := range matches {
+		out[i] = blindfold.DetectedEntity{Type: m.EntityType, Text: m.Text, Start: m.Start, End: m.End, Score: m.Score}
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	body := map[string]any{"detail": msg}
+	if status == http.StatusTooManyRequests {
+		body["retry_after"] = 0.05
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
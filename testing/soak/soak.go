@@ -0,0 +1,427 @@
+// Package soak is a fault-injection harness for long-running tests of the
+// protection gateway and of pkg/protect's session path: a fake OpenAI API
+// that fails in the ways real providers do, a mapping store whose outages
+// a test switches on and off, and a Checker that watches every text on its
+// way to the model and back for values that don't belong there.
+//
+//	up := &soak.Upstream{Faults: soak.AllFaults, OnRequest: func(b []byte) { chk.Sent(string(b)) }}
+//	srv := httptest.NewServer(up)
+//	gw := gateway.New(gateway.Config{Blindfold: bf, Upstream: srv.URL + "/v1"})
+//	// ... send conversations through gw, chk.Received(conv.ID, reply) ...
+//	for _, p := range chk.Problems() { t.Error(p) }
+//
+// The tests in this package are the soak runs themselves; they run for a
+// few seconds under go test and for as long as -soak says otherwise:
+//
+//	go test ./testing/soak -soak 30m -timeout 0 -v
+package soak
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/genpii"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/mapping"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+)
+
+// Fault is one way an upstream reply goes wrong.
+type Fault int
+
+const (
+	// None is a well-formed reply.
+	None Fault = iota
+	// Status500 is an OpenAI-style 500 error.
+	Status500
+	// Slow is a well-formed reply after up to SlowDelay, often slower
+	// than the caller waits.
+	Slow
+	// MalformedSSE is a stream with a truncated chunk and a stray line in
+	// the middle; a non-streaming reply is cut off mid-JSON.
+	MalformedSSE
+	// CutStream is a stream whose connection drops in the middle of a
+	// placeholder.
+	CutStream
+)
+
+// AllFaults is every fault, with well-formed replies as common as all the
+// others together.
+var AllFaults = []Fault{None, None, None, None, Status500, Slow, MalformedSSE, CutStream}
+
+func (f Fault) String() string {
+	switch f {
+	case None:
+		return "none"
+	case Status500:
+		return "500"
+	case Slow:
+		return "slow"
+	case MalformedSSE:
+		return "malformed"
+	case CutStream:
+		return "cut"
+	}
+	return fmt.Sprintf("Fault(%d)", int(f))
+}
+
+// Upstream is a fake OpenAI chat completions API that answers with the
+// placeholders of the last message it was sent, streamed or not, and
+// injects a fault picked at random from Faults into each reply. Set its
+// fields before serving it.
+type Upstream struct {
+	// Faults are picked from uniformly for each request. Empty means
+	// None.
+	Faults []Fault
+	// SlowDelay is the longest a Slow reply waits. Defaults to 500ms.
+	SlowDelay time.Duration
+	// Seed seeds the fault choices.
+	Seed int64
+	// OnRequest, if set, is called with every request body, from the
+	// handler's goroutine.
+	OnRequest func(body []byte)
+
+	once   sync.Once
+	mu     sync.Mutex
+	rnd    *rand.Rand
+	counts [CutStream + 1]atomic.Int64
+}
+
+// Counts returns how many replies had each fault.
+func (u *Upstream) Counts() map[Fault]int64 {
+	out := make(map[Fault]int64)
+	for f := range u.counts {
+		if n := u.counts[f].Load(); n > 0 {
+			out[Fault(f)] = n
+		}
+	}
+	return out
+}
+
+func (u *Upstream) pick() (Fault, time.Duration) {
+	u.once.Do(func() { u.rnd = rand.New(rand.NewSource(u.Seed)) })
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	f := None
+	if len(u.Faults) > 0 {
+		f = u.Faults[u.rnd.Intn(len(u.Faults))]
+	}
+	delay := u.SlowDelay
+	if delay <= 0 {
+		delay = 500 * time.Millisecond
+	}
+	return f, time.Duration(u.rnd.Int63n(int64(delay)))
+}
+
+type chatRequest struct {
+	Stream   bool `json:"stream"`
+	Messages []struct {
+		Content string `json:"content"`
+	} `json:"messages"`
+}
+
+// ServeHTTP implements http.Handler.
+func (u *Upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+	if u.OnRequest != nil {
+		u.OnRequest(body)
+	}
+	var req chatRequest
+	if err := json.Unmarshal(body, &req); err != nil || len(req.Messages) == 0 {
+		http.Error(w, `{"error":{"message":"invalid request","type":"invalid_request_error"}}`, http.StatusBadRequest)
+		return
+	}
+	fault, delay := u.pick()
+	u.counts[fault].Add(1)
+
+	reply := echo(req.Messages[len(req.Messages)-1].Content)
+	switch fault {
+	case Status500:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `{"error":{"message":"The server had an error while processing your request. Sorry about that!","type":"server_error"}}`)
+		return
+	case Slow:
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if !req.Stream {
+		w.Header().Set("Content-Type", "application/json")
+		out := fmt.Sprintf(`{"id":"soak","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":%q},"finish_reason":"stop"}]}`, reply)
+		if fault == MalformedSSE || fault == CutStream {
+			out = out[:len(out)/2]
+		}
+		io.WriteString(w, out)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+	chunk := func(content string) string {
+		return fmt.Sprintf("data: {\"id\":\"soak\",\"object\":\"chat.completion.chunk\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%q},\"finish_reason\":null}]}\n\n", content)
+	}
+	// Deltas of 5 bytes split most placeholders across chunks
+	deltas := split(reply, 5)
+	cut := len(deltas) / 2
+	if tok := mapping.TokenPattern.FindStringIndex(reply); tok != nil {
+		cut = (tok[0] + 2) / 5 // the delta holding the token's third byte
+	}
+	for i, d := range deltas {
+		switch {
+		case fault == CutStream && i == cut:
+			io.WriteString(w, chunk(d))
+			if flusher != nil {
+				flusher.Flush()
+			}
+			// The connection drops without a final chunk or [DONE]
+			panic(http.ErrAbortHandler)
+		case fault == MalformedSSE && i == cut:
+			line := chunk(d + reply)
+			io.WriteString(w, line[:len(line)-12]+"\n\n")
+			io.WriteString(w, "data: <html>502 Bad Gateway</html>\n\n: keep-alive\n\n")
+			continue
+		}
+		io.WriteString(w, chunk(d))
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	io.WriteString(w, "data: {\"id\":\"soak\",\"object\":\"chat.completion.chunk\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+}
+
+// echo is the model's reply to content: its placeholders, each once.
+func echo(content string) string {
+	var tokens []string
+	seen := make(map[string]bool)
+	for _, tok := range mapping.TokenPattern.FindAllString(content, -1) {
+		if !seen[tok] {
+			seen[tok] = true
+			tokens = append(tokens, tok)
+		}
+	}
+	if len(tokens) == 0 {
+		return "Thanks, noted."
+	}
+	return "Noted: " + strings.Join(tokens, ", ") + ". Anything else?"
+}
+
+func split(s string, n int) []string {
+	var out []string
+	for i := 0; i < len(s); i += n {
+		out = append(out, s[i:min(i+n, len(s))])
+	}
+	return out
+}
+
+// ErrStoreDown is returned by a Store during an outage.
+var ErrStoreDown = errors.New("soak: mapping store unavailable")
+
+// Store is a protect.Store whose loads and saves fail while a test says
+// so.
+type Store struct {
+	protect.Store
+	loads, saves atomic.Bool
+}
+
+// NewStore wraps s.
+func NewStore(s protect.Store) *Store { return &Store{Store: s} }
+
+// SetOutage makes loads, saves or both fail with ErrStoreDown until
+// ClearOutage.
+func (s *Store) SetOutage(loads, saves bool) { s.loads.Store(loads); s.saves.Store(saves) }
+
+// ClearOutage ends an outage started with SetOutage.
+func (s *Store) ClearOutage() { s.SetOutage(false, false) }
+
+// Load implements protect.Store.
+func (s *Store) Load(ctx context.Context, session string) (map[string]string, error) {
+	if s.loads.Load() {
+		return nil, ErrStoreDown
+	}
+	return s.Store.Load(ctx, session)
+}
+
+// Save implements protect.Store.
+func (s *Store) Save(ctx context.Context, session string, m map[string]string) error {
+	if s.saves.Load() {
+		return ErrStoreDown
+	}
+	return s.Store.Save(ctx, session, m)
+}
+
+// Flap calls outage, waits up to down, calls restore and waits up to up,
+// with the waits picked at random, until ctx is done. It returns after a
+// final restore.
+func Flap(ctx context.Context, seed int64, up, down time.Duration, outage, restore func()) {
+	rnd := rand.New(rand.NewSource(seed))
+	defer restore()
+	for {
+		for _, step := range []struct {
+			f func()
+			d time.Duration
+		}{{outage, down}, {restore, up}} {
+			step.f()
+			select {
+			case <-time.After(time.Duration(rnd.Int63n(int64(step.d)) + 1)):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// detectable are the generated entity types the local regex detectors
+// find, so a soak run in local mode or against the mock server can tell a
+// leak from a value that was never meant to be tokenized.
+var detectable = map[string]bool{genpii.Email: true, genpii.Phone: true, genpii.SSN: true, genpii.CreditCard: true, genpii.IPAddress: true}
+
+// Conversation is a generated conversation and the values in it that must
+// be tokenized.
+type Conversation struct {
+	ID     string
+	Turns  []string
+	Values []string
+}
+
+// Conversations generates n conversations of turns user messages each.
+func Conversations(n, turns int, seed int64) []Conversation {
+	g := genpii.New(seed)
+	convs := make([]Conversation, n)
+	for i := range convs {
+		c := Conversation{ID: fmt.Sprintf("conv-%04d", i+1)}
+		for t := 0; t < turns; t++ {
+			doc := g.Document("", "")
+			c.Turns = append(c.Turns, doc.Text)
+			for _, e := range doc.Entities {
+				if detectable[e.Type] {
+					c.Values = append(c.Values, e.Text)
+				}
+			}
+		}
+		convs[i] = c
+	}
+	return convs
+}
+
+// maxProblems caps the problems a Checker keeps; it still counts the rest.
+const maxProblems = 20
+
+// Checker checks the texts of a soak run against the values of its
+// conversations. It is safe for concurrent use.
+type Checker struct {
+	owners map[string]map[string]bool // value → conversations it is in
+
+	mu       sync.Mutex
+	problems []string
+	dropped  int
+}
+
+// NewChecker returns a Checker for convs.
+func NewChecker(convs []Conversation) *Checker {
+	c := &Checker{owners: make(map[string]map[string]bool)}
+	for _, conv := range convs {
+		for _, v := range conv.Values {
+			if c.owners[v] == nil {
+				c.owners[v] = make(map[string]bool)
+			}
+			c.owners[v][conv.ID] = true
+		}
+	}
+	return c
+}
+
+// Sent checks text on its way to the model: it must hold no value of any
+// conversation.
+func (c *Checker) Sent(text string) {
+	stripped := mapping.ReplaceTokens(text, func(string) string { return " " })
+	for v := range c.owners {
+		if strings.Contains(stripped, v) {
+			c.problem("raw value %q sent upstream in %s", v, clip(text))
+		}
+	}
+}
+
+// Received checks a reply to conversation conv, successful or cut short:
+// it must hold no placeholder, since a caller has no use for one, and no
+// value of another conversation.
+func (c *Checker) Received(conv, text string) {
+	if tokens := mapping.TokenPattern.FindAllString(text, -1); len(tokens) > 0 {
+		c.problem("%s: unrestored tokens %v in reply %s", conv, tokens, clip(text))
+	}
+	for v, in := range c.owners {
+		if !in[conv] && strings.Contains(text, v) {
+			c.problem("%s: value %q of another conversation in reply %s", conv, v, clip(text))
+		}
+	}
+}
+
+// Failed checks an error reply or message: it must hold no value and no
+// placeholder at all, since errors end up in logs.
+func (c *Checker) Failed(conv, text string) {
+	if tokens := mapping.TokenPattern.FindAllString(text, -1); len(tokens) > 0 {
+		c.problem("%s: tokens %v in error %s", conv, tokens, clip(text))
+	}
+	for v := range c.owners {
+		if strings.Contains(text, v) {
+			c.problem("%s: value %q in error %s", conv, v, clip(text))
+		}
+	}
+}
+
+// Mapping checks the stored mapping of conversation conv: one token per
+// value, and only values of conv.
+func (c *Checker) Mapping(conv string, m map[string]string) {
+	seen := make(map[string]string, len(m))
+	for tok, v := range m {
+		if other, ok := seen[v]; ok {
+			c.problem("%s: %q stored under both %s and %s", conv, v, other, tok)
+		}
+		seen[v] = tok
+		if in, known := c.owners[v]; known && !in[conv] {
+			c.problem("%s: value %q of another conversation stored as %s", conv, v, tok)
+		}
+	}
+}
+
+// Problems returns what the checks found, at most 20 of them followed by
+// a count of the rest.
+func (c *Checker) Problems() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := append([]string(nil), c.problems...)
+	if c.dropped > 0 {
+		out = append(out, fmt.Sprintf("... and %d more", c.dropped))
+	}
+	return out
+}
+
+func (c *Checker) problem(format string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.problems) == maxProblems {
+		c.dropped++
+		return
+	}
+	c.problems = append(c.problems, fmt.Sprintf(format, args...))
+}
+
+func clip(s string) string {
+	if len(s) > 300 {
+		s = s[:300] + "..."
+	}
+	return fmt.Sprintf("%q", s)
+}
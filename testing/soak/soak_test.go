@@ -0,0 +1,214 @@
+package soak
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	blindfold "github.com/blindfold-dev/Blindfold/packages/go-sdk"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/gateway"
+	"github.com/blindfold-dev/blindfold-cookbook/pkg/protect"
+	"github.com/blindfold-dev/blindfold-cookbook/testing/mockserver"
+)
+
+var soakDuration = flag.Duration("soak", 2*time.Second, "how long each soak test sends traffic")
+
+const (
+	workers       = 8
+	callerTimeout = 250 * time.Millisecond // shorter than the slowest Slow reply
+)
+
+var local = blindfold.New(blindfold.WithMode("local"))
+
+// TestGateway sends conversations through the gateway, streamed and not,
+// while the upstream injects every Fault and the Blindfold API (the mock
+// server) flaps between up and 503.
+func TestGateway(t *testing.T) {
+	convs := Conversations(40, 4, 1)
+	chk := NewChecker(convs)
+
+	bf := mockserver.New(mockserver.WithLatency(time.Millisecond))
+	defer bf.Close()
+	up := &Upstream{Faults: AllFaults, SlowDelay: 2 * callerTimeout, Seed: 1, OnRequest: func(b []byte) { chk.Sent(string(b)) }}
+	upstream := httptest.NewServer(up)
+	defer upstream.Close()
+	stats := gateway.NewStats()
+	gw := httptest.NewServer(gateway.New(gateway.Config{Blindfold: bf.Client(), Upstream: upstream.URL + "/v1", Stats: stats}))
+	defer gw.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *soakDuration)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		Flap(ctx, 1, 500*time.Millisecond, 50*time.Millisecond,
+			func() { bf.SetOutage(http.StatusServiceUnavailable) }, bf.ClearOutage)
+	}()
+
+	var statuses sync.Map // status → *atomic.Int64
+	count := func(status int) {
+		n, _ := statuses.LoadOrStore(status, new(atomic.Int64))
+		n.(*atomic.Int64).Add(1)
+	}
+	client := &http.Client{Timeout: callerTimeout}
+	for w := 0; w < workers; w++ {
+		w := w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(int64(w)))
+			for ctx.Err() == nil {
+				conv := convs[rnd.Intn(len(convs))]
+				status, body, err := chat(client, gw.URL, conv.Turns[:1+rnd.Intn(len(conv.Turns))], rnd.Intn(2) == 0)
+				switch {
+				case status == 0:
+					count(0) // timed out waiting for headers
+				case status == http.StatusOK:
+					// A stream cut short keeps what it had relayed
+					count(status)
+					chk.Received(conv.ID, body)
+				default:
+					count(status)
+					chk.Failed(conv.ID, body)
+				}
+				if err != nil && status != 0 && !errors.Is(err, io.ErrUnexpectedEOF) && !isTimeout(err) {
+					t.Errorf("reading reply: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	statuses.Range(func(k, v any) bool {
+		t.Logf("status %d: %d", k, v.(*atomic.Int64).Load())
+		return true
+	})
+	t.Logf("upstream faults: %v, Blindfold requests: %v", up.Counts(), bf.Requests())
+	for _, p := range chk.Problems() {
+		t.Error(p)
+	}
+	// Abandoned requests must not leave handlers behind
+	deadline := time.Now().Add(5 * time.Second)
+	for stats.InFlight() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := stats.InFlight(); n != 0 {
+		t.Errorf("%d requests still in flight", n)
+	}
+	if len(up.Counts()) != int(CutStream)+1 {
+		t.Errorf("not every fault was injected: %v", up.Counts())
+	}
+}
+
+// chat sends one chat completion of turns, with a canned assistant reply
+// between them, and reads the reply to the end. status is 0 if no reply
+// arrived.
+func chat(client *http.Client, base string, turns []string, stream bool) (status int, body string, err error) {
+	messages := []map[string]string{{"role": "system", "content": "Keep placeholders like <Person_1> as they are."}}
+	for i, turn := range turns {
+		if i > 0 {
+			messages = append(messages, map[string]string{"role": "assistant", "content": "Thanks, noted."})
+		}
+		messages = append(messages, map[string]string{"role": "user", "content": turn})
+	}
+	payload, _ := json.Marshal(map[string]any{"model": "soak", "messages": messages, "stream": stream})
+	resp, err := client.Post(base+"/v1/chat/completions", "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(b), err
+}
+
+func isTimeout(err error) bool {
+	var te interface{ Timeout() bool }
+	return errors.As(err, &te) && te.Timeout()
+}
+
+// TestSessions runs conversations through a Protector whose mapping store
+// flaps between up, loads failing and saves failing, while the model
+// injects every Fault. Each conversation is one session, its turns sent
+// in order, as a chat service would.
+func TestSessions(t *testing.T) {
+	convs := Conversations(40, 6, 2)
+	chk := NewChecker(convs)
+
+	up := &Upstream{Faults: AllFaults, SlowDelay: 2 * callerTimeout, Seed: 2, OnRequest: func(b []byte) { chk.Sent(string(b)) }}
+	upstream := httptest.NewServer(up)
+	defer upstream.Close()
+	cfg := openai.DefaultConfig("soak")
+	cfg.BaseURL = upstream.URL + "/v1"
+	llm := openai.NewClientWithConfig(cfg)
+
+	mem := protect.NewMemoryStore()
+	store := NewStore(mem)
+	p := protect.New(local)
+	p.Store = store
+
+	ctx, cancel := context.WithTimeout(context.Background(), *soakDuration)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		rnd := rand.New(rand.NewSource(2))
+		Flap(ctx, 2, 500*time.Millisecond, 50*time.Millisecond,
+			func() { store.SetOutage(rnd.Intn(2) == 0, rnd.Intn(2) == 0) }, store.ClearOutage)
+	}()
+
+	var ok, failed atomic.Int64
+	for w := 0; w < workers; w++ {
+		w := w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Worker w owns conversations w, w+workers, ...
+			for round := 0; ctx.Err() == nil; round++ {
+				for i := w; i < len(convs) && ctx.Err() == nil; i += workers {
+					conv := convs[i]
+					turn := conv.Turns[round%len(conv.Turns)]
+					callCtx, cancel := context.WithTimeout(protect.Session(context.Background(), conv.ID), callerTimeout)
+					reply, err := p.Do(callCtx, turn, protect.Chat(callCtx, llm, "soak", "Keep placeholders like <Person_1> as they are."))
+					cancel()
+					if err != nil {
+						failed.Add(1)
+						chk.Failed(conv.ID, err.Error())
+						continue
+					}
+					ok.Add(1)
+					chk.Received(conv.ID, reply)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, conv := range convs {
+		m, err := mem.Load(context.Background(), conv.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chk.Mapping(conv.ID, m)
+	}
+	t.Logf("%d turns restored, %d failed; upstream faults: %v", ok.Load(), failed.Load(), up.Counts())
+	for _, p := range chk.Problems() {
+		t.Error(p)
+	}
+	if ok.Load() == 0 || failed.Load() == 0 {
+		t.Errorf("want both restored and failed turns, got %d and %d", ok.Load(), failed.Load())
+	}
+}